@@ -36,6 +36,22 @@ func init() {
 
 	viper.SetDefault("kubevim.url", "kube-vim:50051")
 	viper.SetDefault("kubevim.tls.insecureSkipVerify", true)
+
+	viper.SetDefault("imageUpload.readyTimeout", "5m")
+	viper.SetDefault("imageUpload.tls.insecureSkipVerify", false)
+
+	podNamespace := os.Getenv("POD_NAMESPACE")
+	if podNamespace == "" {
+		podNamespace = common.KubeNfvDefaultNamespace
+	}
+	viper.SetDefault("subscriptions.enabled", false)
+	viper.SetDefault("subscriptions.namespace", podNamespace)
+	viper.SetDefault("subscriptions.configMap", "kube-vim-gateway-subscriptions")
+	viper.SetDefault("subscriptions.reconnectInterval", "5s")
+	viper.SetDefault("subscriptions.delivery.maxAttempts", 5)
+	viper.SetDefault("subscriptions.delivery.initialBackoff", "1s")
+	viper.SetDefault("subscriptions.delivery.maxBackoff", "30s")
+	viper.SetDefault("subscriptions.delivery.timeout", "10s")
 }
 
 func main() {
@@ -13,13 +13,16 @@ import (
 	"github.com/kube-nfv/kube-vim/internal/config"
 	"github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	"github.com/kube-nfv/kube-vim/internal/kubevim"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
 	"github.com/kube-nfv/kube-vim/internal/misc"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 )
 
 type CmdLineOpts struct {
-	configPath string
+	configPath     string
+	verifyChecksum bool
 }
 
 var (
@@ -29,6 +32,7 @@ var (
 func init() {
 	// Parse CmdLine flags
 	flag.StringVar(&opts.configPath, "config", "/etc/kube-vim/config.yaml", "kube-vim configuration file path")
+	flag.BoolVar(&opts.verifyChecksum, cdi.VerifyChecksumFlag, false, "verify the checksum of an imported image disk and exit (run by the image checksum Job)")
 
 	podNamespace := os.Getenv("POD_NAMESPACE")
 	if podNamespace == "" {
@@ -37,21 +41,21 @@ func init() {
 	config.SetViperDefaults(podNamespace)
 }
 
-func SetConfigDefaultAfterInit() {
-	// Temporary hack to initialize http node in configuration if it is empty like
-	// http: {}
-	if viper.IsSet("image.http") {
-		viper.SetDefault("image.http.initEmpty", true)
-	}
-}
-
 func main() {
 	flag.Parse()
+	if opts.verifyChecksum {
+		if err := cdi.VerifyDiskChecksum(); err != nil {
+			// The termination message is the failure reason of the image.
+			_ = os.WriteFile(corev1.TerminationMessagePathDefault, []byte(err.Error()), 0o644)
+			log.Fatalf("Verify image checksum: %v", err)
+		}
+		log.Print("Image checksum verified")
+		return
+	}
 	viper.SetConfigFile(opts.configPath)
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Can't read kube-vim configuration from path '%s': %v", opts.configPath, err)
 	}
-	SetConfigDefaultAfterInit()
 	var cfg config.Config
 	if err := viper.Unmarshal(&cfg); err != nil {
 		log.Fatalf("Parse kube-vim configuration from path '%s': %v", opts.configPath, err)
@@ -1,6 +1,11 @@
 package common
 
-import "fmt"
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
 
 var (
 	K8sManagedByLabel        = "app.kubernetes.io/managed-by"
@@ -17,3 +22,32 @@ func IsServerTlsConfigured(cfg *TlsServerConfig) bool {
 		cfg.Cert != nil && *cfg.Cert != "" &&
 		cfg.Key != nil && *cfg.Key != ""
 }
+
+// NewClientTlsConfig builds the crypto/tls client configuration. A nil cfg
+// yields the defaults: verification against the host's root CA set.
+func NewClientTlsConfig(cfg *TlsClientConfig) (*tls.Config, error) {
+	res := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg == nil {
+		return res, nil
+	}
+	if cfg.InsecureSkipVerify != nil {
+		res.InsecureSkipVerify = *cfg.InsecureSkipVerify
+	}
+	if cfg.ServerName != nil {
+		res.ServerName = *cfg.ServerName
+	}
+	if cfg.RootCAs != nil && len(*cfg.RootCAs) > 0 {
+		pool := x509.NewCertPool()
+		for _, path := range *cfg.RootCAs {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read root CA '%s': %w", path, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no PEM certificate found in root CA '%s'", path)
+			}
+		}
+		res.RootCAs = pool
+	}
+	return res, nil
+}
@@ -9,11 +9,35 @@ import (
 
 // Config Top-level configuration node for kube-vim gateway.
 type Config struct {
+	// ImageUpload Streaming image upload served by the gateway on POST /admin/v1/images/upload.
+	// The gateway registers the image with kube-vim, waits for its upload-target
+	// DataVolume and forwards the request body to the CDI upload proxy.
+	ImageUpload *ImageUploadConfig `json:"imageUpload,omitempty"`
+
 	// Kubevim Kube-vim connection configuration.
 	Kubevim *KubeVimConfig `json:"kubevim,omitempty"`
 
 	// Service Configuration related to the kube-vim Gateway service.
 	Service *ServiceConfig `json:"service,omitempty"`
+
+	// Subscriptions ETSI GS NFV-SOL 013 subscriptions served by the gateway. REST consumers
+	// register a callback URI and receive virtualised resource change
+	// notifications as HTTP POSTs. Subscriptions are persisted in a ConfigMap
+	// (credentials in a Secret of the same name) so they survive gateway restarts.
+	Subscriptions *SubscriptionsConfig `json:"subscriptions,omitempty"`
+}
+
+// ImageUploadConfig Streaming image upload served by the gateway on POST /admin/v1/images/upload.
+// The gateway registers the image with kube-vim, waits for its upload-target
+// DataVolume and forwards the request body to the CDI upload proxy.
+type ImageUploadConfig struct {
+	// ReadyTimeout How long to wait for the upload-target DataVolume to become UploadReady (Go duration).
+	ReadyTimeout *string `json:"readyTimeout,omitempty"`
+
+	// Tls "TLS client configuration defines the settings required to establish a secure
+	// connection to a server using TLS. It controls aspects such as certificate validation,
+	// server verification, and root certificate authorities used in the validation process."
+	Tls *externalRef0.TlsClientConfig `json:"tls,omitempty"`
 }
 
 // KubeVimConfig Kube-vim connection configuration.
@@ -27,6 +51,21 @@ type KubeVimConfig struct {
 	Url *string `json:"url,omitempty"`
 }
 
+// NotificationDeliveryConfig Notification delivery retry policy. Failed POSTs are retried with exponential backoff.
+type NotificationDeliveryConfig struct {
+	// InitialBackoff Delay before the first retry (Go duration). Doubled on every further retry.
+	InitialBackoff *string `json:"initialBackoff,omitempty"`
+
+	// MaxAttempts Maximum delivery attempts per notification, including the first one.
+	MaxAttempts *int `json:"maxAttempts,omitempty"`
+
+	// MaxBackoff Upper bound of the retry delay (Go duration).
+	MaxBackoff *string `json:"maxBackoff,omitempty"`
+
+	// Timeout Timeout of a single notification or callback test request (Go duration).
+	Timeout *string `json:"timeout,omitempty"`
+}
+
 // ServerConfig Kube-vim Gateway Server configuration.
 type ServerConfig struct {
 	// Ip "IP address in either IPv4 or IPv6 format. An IP address is used to uniquely identify
@@ -70,3 +109,24 @@ type ServiceConfig struct {
 	// Server Kube-vim Gateway Server configuration.
 	Server *ServerConfig `json:"server,omitempty"`
 }
+
+// SubscriptionsConfig ETSI GS NFV-SOL 013 subscriptions served by the gateway. REST consumers
+// register a callback URI and receive virtualised resource change
+// notifications as HTTP POSTs. Subscriptions are persisted in a ConfigMap
+// (credentials in a Secret of the same name) so they survive gateway restarts.
+type SubscriptionsConfig struct {
+	// ConfigMap Name of the ConfigMap (and Secret) holding the subscriptions.
+	ConfigMap *string `json:"configMap,omitempty"`
+
+	// Delivery Notification delivery retry policy. Failed POSTs are retried with exponential backoff.
+	Delivery *NotificationDeliveryConfig `json:"delivery,omitempty"`
+
+	// Enabled Whether the gateway serves the subscriptions API and delivers notifications. Default off; opt-in.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Namespace Namespace of the subscriptions ConfigMap and Secret.
+	Namespace *string `json:"namespace,omitempty"`
+
+	// ReconnectInterval Delay before the gateway reopens the kube-vim notification stream after it ended (Go duration).
+	ReconnectInterval *string `json:"reconnectInterval,omitempty"`
+}
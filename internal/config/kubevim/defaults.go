@@ -19,6 +19,8 @@ func SetViperDefaults(podNamespace string) {
 	viper.SetDefault("service.server.port", 50051)
 
 	viper.SetDefault("image.storageClass", "default")
+	viper.SetDefault("image.uploadProxyUrl", "https://cdi-uploadproxy.cdi.svc")
+	viper.SetDefault("image.sizeDiscoveryTimeout", "10s")
 
 	viper.SetDefault("k8s.namespace", podNamespace)
 
@@ -30,6 +32,9 @@ func SetViperDefaults(podNamespace string) {
 
 	viper.SetDefault("monitoring.enabled", false)
 	viper.SetDefault("monitoring.metricsPort", 9095)
+	viper.SetDefault("monitoring.performance.configMap", "kube-vim-pm")
+	viper.SetDefault("monitoring.performance.queryTimeout", "30s")
+	viper.SetDefault("monitoring.performance.thresholdInterval", "60s")
 }
 
 // Normalize fills in defaults that depend on other already-loaded values. It
@@ -46,6 +51,9 @@ func (c *Config) Normalize(k8sNamespace string) error {
 	if c.Network == nil {
 		return nil
 	}
+	if err := c.Network.Nat.validate(); err != nil {
+		return err
+	}
 	mgmt := c.Network.ManagementNetwork
 	if mgmt == nil || mgmt.Enabled == nil || !*mgmt.Enabled {
 		return nil
@@ -72,3 +80,18 @@ func (c *Config) Normalize(k8sNamespace string) error {
 	}
 	return nil
 }
+
+// validate checks that an enabled NAT backend is a known one and names the
+// external subnet.
+func (n *NatConfig) validate() error {
+	if n == nil || n.Backend == nil || *n.Backend == "" {
+		return nil
+	}
+	if *n.Backend != Iptables && *n.Backend != Ovn {
+		return fmt.Errorf("network.nat.backend must be one of %s, %s", Iptables, Ovn)
+	}
+	if n.ExternalSubnet == nil || *n.ExternalSubnet == "" {
+		return fmt.Errorf("network.nat.externalSubnet is required when network.nat.backend is set")
+	}
+	return nil
+}
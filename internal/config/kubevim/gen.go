@@ -7,6 +7,32 @@ import (
 	externalRef0 "github.com/kube-nfv/kube-vim/internal/config"
 )
 
+// Defines values for ImageStorageProfileConfigCloneStrategy.
+const (
+	ImageStorageProfileConfigCloneStrategyCopy     ImageStorageProfileConfigCloneStrategy = "copy"
+	ImageStorageProfileConfigCloneStrategyCsiClone ImageStorageProfileConfigCloneStrategy = "csi-clone"
+	ImageStorageProfileConfigCloneStrategySnapshot ImageStorageProfileConfigCloneStrategy = "snapshot"
+)
+
+// Defines values for ImageStorageProfileConfigGoldenAccessMode.
+const (
+	ImageStorageProfileConfigGoldenAccessModeReadOnlyMany  ImageStorageProfileConfigGoldenAccessMode = "ReadOnlyMany"
+	ImageStorageProfileConfigGoldenAccessModeReadWriteMany ImageStorageProfileConfigGoldenAccessMode = "ReadWriteMany"
+	ImageStorageProfileConfigGoldenAccessModeReadWriteOnce ImageStorageProfileConfigGoldenAccessMode = "ReadWriteOnce"
+)
+
+// Defines values for ImageStorageProfileConfigVolumeMode.
+const (
+	ImageStorageProfileConfigVolumeModeBlock      ImageStorageProfileConfigVolumeMode = "Block"
+	ImageStorageProfileConfigVolumeModeFilesystem ImageStorageProfileConfigVolumeMode = "Filesystem"
+)
+
+// Defines values for NatConfigBackend.
+const (
+	Iptables NatConfigBackend = "iptables"
+	Ovn      NatConfigBackend = "ovn"
+)
+
 // Defines values for TolerationEffect.
 const (
 	NoExecute        TolerationEffect = "NoExecute"
@@ -55,27 +81,84 @@ type Config struct {
 	Service *ServiceConfig `json:"service,omitempty"`
 }
 
-// GlanceImageConfig Configuration for Glance image provider.
-type GlanceImageConfig = map[string]interface{}
+// GlanceImageConfig Configuration for Glance image provider: a catalog of the active images of an OpenStack Glance service.
+type GlanceImageConfig struct {
+	// IdentityEndpoint Keystone v3 endpoint URL.
+	IdentityEndpoint *string `json:"identityEndpoint,omitempty"`
+
+	// Password Keystone user password.
+	Password *string `json:"password,omitempty"`
 
-// HttpImageConfig Configuration for HTTP image provider.
-type HttpImageConfig = map[string]interface{}
+	// ProjectDomainName Keystone domain of the project.
+	ProjectDomainName *string `json:"projectDomainName,omitempty"`
+
+	// ProjectName Keystone project the images are listed in.
+	ProjectName *string `json:"projectName,omitempty"`
+
+	// Region Region of the Glance endpoint in the Keystone catalog.
+	Region *string `json:"region,omitempty"`
+
+	// UserDomainName Keystone domain of the user.
+	UserDomainName *string `json:"userDomainName,omitempty"`
+
+	// Username Keystone user name.
+	Username *string `json:"username,omitempty"`
+}
+
+// HttpImageConfig Configuration for HTTP image provider: a catalog of images listed in a JSON index served over HTTP(S).
+type HttpImageConfig struct {
+	// CatalogUrl URL of the JSON image index. Relative image URLs in the index are resolved against it.
+	CatalogUrl *string `json:"catalogUrl,omitempty"`
+}
 
 // ImageConfig Configuration for kube-vim image providers.
 type ImageConfig struct {
-	// Glance Configuration for Glance image provider.
+	// ChecksumVerifierImage Container image of the Job verifying the checksum of imported images. The Job runs the kube-vim binary with -verify-checksum, so this is the kube-vim image; the Helm chart defaults it to the deployed one. Checksum verification is refused when unset.
+	ChecksumVerifierImage *string `json:"checksumVerifierImage,omitempty"`
+
+	// Glance Configuration for Glance image provider: a catalog of the active images of an OpenStack Glance service.
 	Glance *GlanceImageConfig `json:"glance,omitempty"`
 
-	// Http Configuration for HTTP image provider.
+	// Http Configuration for HTTP image provider: a catalog of images listed in a JSON index served over HTTP(S).
 	Http *HttpImageConfig `json:"http,omitempty"`
 
-	// Local Configuration for Local image provider.
+	// Local Configuration for Local image provider: a catalog of the image files of a directory, which kube-vim serves over HTTP to the CDI importer.
 	Local *LocalImageConfig `json:"local,omitempty"`
 
+	// SizeDiscoveryTimeout Timeout of reading the size of an image from its source before the import (Go duration). 0 disables size discovery.
+	SizeDiscoveryTimeout *string `json:"sizeDiscoveryTimeout,omitempty"`
+
 	// StorageClass Kubernetes StorageClass for software image PVCs. By default 'default' storageClass will be used
 	StorageClass *string `json:"storageClass,omitempty"`
+
+	// StorageProfiles Overrides of the CDI StorageProfile settings used for software images, keyed by StorageClass name.
+	StorageProfiles *map[string]ImageStorageProfileConfig `json:"storageProfiles,omitempty"`
+
+	// UploadProxyUrl Base URL of the CDI upload proxy returned to image upload clients. The kube-vim gateway streams uploads to it, so it must be reachable from the gateway pod.
+	UploadProxyUrl *string `json:"uploadProxyUrl,omitempty"`
+}
+
+// ImageStorageProfileConfig Software image settings of a StorageClass. Unset fields are detected from its CDI StorageProfile.
+type ImageStorageProfileConfig struct {
+	// CloneStrategy CDI clone strategy of boot volumes. kube-vim sets it on the StorageProfile, which is where CDI reads it from.
+	CloneStrategy *ImageStorageProfileConfigCloneStrategy `json:"cloneStrategy,omitempty"`
+
+	// GoldenAccessMode Access mode of the image volumes. Detected as the first of ReadOnlyMany, ReadWriteMany and ReadWriteOnce the StorageProfile offers.
+	GoldenAccessMode *ImageStorageProfileConfigGoldenAccessMode `json:"goldenAccessMode,omitempty"`
+
+	// VolumeMode Volume mode of the image and boot volumes. Detected as the volume mode of the StorageProfile claim property set holding the golden access mode.
+	VolumeMode *ImageStorageProfileConfigVolumeMode `json:"volumeMode,omitempty"`
 }
 
+// ImageStorageProfileConfigCloneStrategy CDI clone strategy of boot volumes. kube-vim sets it on the StorageProfile, which is where CDI reads it from.
+type ImageStorageProfileConfigCloneStrategy string
+
+// ImageStorageProfileConfigGoldenAccessMode Access mode of the image volumes. Detected as the first of ReadOnlyMany, ReadWriteMany and ReadWriteOnce the StorageProfile offers.
+type ImageStorageProfileConfigGoldenAccessMode string
+
+// ImageStorageProfileConfigVolumeMode Volume mode of the image and boot volumes. Detected as the volume mode of the StorageProfile claim property set holding the golden access mode.
+type ImageStorageProfileConfigVolumeMode string
+
 // K8sConfig Configuration related to Kubernetes operations.
 type K8sConfig struct {
 	// Config Specific path to find k8s config file. By default k8s ServiceAccount place is used
@@ -85,10 +168,21 @@ type K8sConfig struct {
 	Namespace *string `json:"namespace,omitempty"`
 }
 
-// LocalImageConfig Configuration for Local image provider.
+// LocalImageConfig Configuration for Local image provider: a catalog of the image files of a directory, which kube-vim serves over HTTP to the CDI importer.
 type LocalImageConfig struct {
 	// Location Location where to locate images.
 	Location *string `json:"location,omitempty"`
+
+	// Port "A TCP port number specifies the endpoint for network communication on the service.
+	// Port numbers range from 1 to 65535, with the lower range (1-1023) typically reserved for well-known services and system processes.
+	// It is important to choose a port within the allowed range that does not conflict with other services running on the host.
+	//
+	// Ensure that the selected port is open and accessible for communication while respecting the security policies of your network.
+	// Avoid using ports that are commonly blocked by firewalls or reserved for specific applications."
+	Port *externalRef0.Port `json:"port,omitempty"`
+
+	// Url Base URL the CDI importer reaches the served image files at, e.g. the URL of a Service targeting port.
+	Url *string `json:"url,omitempty"`
 }
 
 // ManagementNetworkConfig Optional kube-vim-managed shared management network used by an NFVO
@@ -137,8 +231,33 @@ type MonitoringConfig struct {
 	// Ensure that the selected port is open and accessible for communication while respecting the security policies of your network.
 	// Avoid using ports that are commonly blocked by firewalls or reserved for specific applications."
 	MetricsPort *externalRef0.Port `json:"metricsPort,omitempty"`
+
+	// Performance Virtualised resource performance management (PM jobs and thresholds).
+	// kube-vim queries a Prometheus-compatible endpoint that scrapes both the
+	// backend exporters and the kube-vim /metrics endpoint, and joins the
+	// backend series to ETSI ids through the `kubevim_*_info` metrics. PM is
+	// disabled when prometheusUrl is empty.
+	Performance *PerformanceManagementConfig `json:"performance,omitempty"`
 }
 
+// NatConfig External connectivity of overlay networks through NAT gateways and
+// floating IPs. The backend selects the kube-OVN resources kube-vim
+// renders them as: `iptables` runs a VpcNatGateway pod per gateway with
+// IptablesEIP, IptablesFIPRule and IptablesSnatRule objects, `ovn`
+// programs the NAT into the VPC logical router with OvnEip, OvnFip and
+// OvnSnatRule objects. NAT gateways and floating IPs are disabled when
+// backend is unset.
+type NatConfig struct {
+	// Backend kube-OVN NAT implementation backing NAT gateways and floating IPs.
+	Backend *NatConfigBackend `json:"backend,omitempty"`
+
+	// ExternalSubnet kube-OVN subnet of the external network the gateway and floating IP addresses are allocated from. Required when backend is set.
+	ExternalSubnet *string `json:"externalSubnet,omitempty"`
+}
+
+// NatConfigBackend kube-OVN NAT implementation backing NAT gateways and floating IPs.
+type NatConfigBackend string
+
 // NetworkConfig Configuration for kube-vim cluster static network.
 type NetworkConfig struct {
 	// ManagementNetwork Optional kube-vim-managed shared management network used by an NFVO
@@ -149,6 +268,15 @@ type NetworkConfig struct {
 	// deleted by NS termination.
 	ManagementNetwork *ManagementNetworkConfig `json:"managementNetwork,omitempty"`
 
+	// Nat External connectivity of overlay networks through NAT gateways and
+	// floating IPs. The backend selects the kube-OVN resources kube-vim
+	// renders them as: `iptables` runs a VpcNatGateway pod per gateway with
+	// IptablesEIP, IptablesFIPRule and IptablesSnatRule objects, `ovn`
+	// programs the NAT into the VPC logical router with OvnEip, OvnFip and
+	// OvnSnatRule objects. NAT gateways and floating IPs are disabled when
+	// backend is unset.
+	Nat *NatConfig `json:"nat,omitempty"`
+
 	// Sriov Settings applied to every SR-IOV network kube-vim creates. SR-IOV
 	// networks are rendered as ovs-cni NetworkAttachmentDefinitions so the
 	// VF representor is attached to an OVS bridge, enabling OVS hardware
@@ -159,6 +287,25 @@ type NetworkConfig struct {
 	Sriov *SriovNetworkConfig `json:"sriov,omitempty"`
 }
 
+// PerformanceManagementConfig Virtualised resource performance management (PM jobs and thresholds).
+// kube-vim queries a Prometheus-compatible endpoint that scrapes both the
+// backend exporters and the kube-vim /metrics endpoint, and joins the
+// backend series to ETSI ids through the `kubevim_*_info` metrics. PM is
+// disabled when prometheusUrl is empty.
+type PerformanceManagementConfig struct {
+	// ConfigMap Name of the ConfigMap in the kube-vim namespace PM jobs and thresholds are persisted in.
+	ConfigMap *string `json:"configMap,omitempty"`
+
+	// PrometheusUrl Base URL of the Prometheus-compatible HTTP API, e.g. http://prometheus.monitoring:9090.
+	PrometheusUrl *string `json:"prometheusUrl,omitempty"`
+
+	// QueryTimeout Timeout of a single Prometheus query (Go duration).
+	QueryTimeout *string `json:"queryTimeout,omitempty"`
+
+	// ThresholdInterval How often thresholds are evaluated, and the window the metric is computed over (Go duration).
+	ThresholdInterval *string `json:"thresholdInterval,omitempty"`
+}
+
 // ServerConfig Kube-vim Server configuration.
 type ServerConfig struct {
 	// Port "A TCP port number specifies the endpoint for network communication on the service.
@@ -37,6 +37,11 @@ func (c *CommonErrorConverter) ConvertToGrpcError(err error) error {
 		return status.Error(codes.PermissionDenied, err.Error())
 	}
 
+	var failedPreconditionErr *ErrFailedPrecondition
+	if errors.As(err, &failedPreconditionErr) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
 	// Check for common untyped errors anywhere in the chain
 	if errors.Is(err, ErrNotImplemented) {
 		return status.Error(codes.Unimplemented, err.Error())
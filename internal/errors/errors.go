@@ -66,6 +66,20 @@ func (e *ErrPermissionDenied) Error() string {
 	return fmt.Sprintf("access denied to %s", e.Resource)
 }
 
+// ErrFailedPrecondition for operations refused in the current state of the
+// resource, e.g. deleting an object that is still in use
+type ErrFailedPrecondition struct {
+	Resource string
+	Reason   string
+}
+
+func (e *ErrFailedPrecondition) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%s: %s", e.Resource, e.Reason)
+	}
+	return fmt.Sprintf("%s: precondition failed", e.Resource)
+}
+
 // ErrK8sObjectNotInstantiated indicates that the object is not from Kubernetes
 type ErrK8sObjectNotInstantiated struct {
 	ObjectType string
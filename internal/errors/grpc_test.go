@@ -48,6 +48,12 @@ func TestToGRPCError_StructuredErrors(t *testing.T) {
 			expectedCode: codes.AlreadyExists,
 			expectedMsg:  "flavour 'xyz789' already exists",
 		},
+		{
+			name:         "FailedPreconditionError with resource and reason",
+			inputError:   &ErrFailedPrecondition{Resource: "image 'ubuntu'", Reason: "in use by 2 virtual machines"},
+			expectedCode: codes.FailedPrecondition,
+			expectedMsg:  "image 'ubuntu': in use by 2 virtual machines",
+		},
 		{
 			name:         "PermissionDeniedError with resource and reason",
 			inputError:   &ErrPermissionDenied{Resource: "flavour", Reason: "not managed by kube-nfv"},
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/alarm"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ViVnfmAlarmsPath is the REST root of the active alarms.
+const ViVnfmAlarmsPath = "/vivnfm/v5/alarms"
+
+// alarmModifications is the SOL 005 AlarmModifications PATCH body. Only
+// acknowledging is supported.
+type alarmModifications struct {
+	AckState alarm.AckState `json:"ackState"`
+}
+
+// registerAlarmHandlers binds the alarm REST resources to the interim Alarms
+// service. Alarm notifications are only streamed over gRPC.
+func registerAlarmHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	handlers := []restHandler{
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmAlarmsPath,
+			rpc:     vivnfmserver.Alarms_QueryAlarms_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryAlarms(ctx, conn, filterRequest(r))
+			},
+		},
+		{
+			method:  http.MethodPatch,
+			pattern: ViVnfmAlarmsPath + "/{alarmId}",
+			rpc:     vivnfmserver.Alarms_AcknowledgeAlarm_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, params map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				mods := &alarmModifications{}
+				if err := inbound.NewDecoder(r.Body).Decode(mods); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				if mods.AckState != alarm.AckStateAcknowledged {
+					return nil, status.Errorf(codes.InvalidArgument, "ackState must be %s", alarm.AckStateAcknowledged)
+				}
+				return vivnfmserver.AcknowledgeAlarm(ctx, conn, &nfvcommon.Identifier{Value: params["alarmId"]})
+			},
+		},
+	}
+	return registerRestHandlers(mux, handlers)
+}
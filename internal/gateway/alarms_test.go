@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/alarm"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAlarmManager keeps alarms in memory. Alarm subscriptions are not served
+// over REST.
+type fakeAlarmManager struct {
+	alarms []alarm.Alarm
+	filter string
+}
+
+func (m *fakeAlarmManager) QueryAlarms(filter string) ([]alarm.Alarm, error) {
+	m.filter = filter
+	return m.alarms, nil
+}
+
+func (m *fakeAlarmManager) Acknowledge(alarmId string) (*alarm.Alarm, error) {
+	for i := range m.alarms {
+		if m.alarms[i].AlarmId == alarmId {
+			m.alarms[i].AckState = alarm.AckStateAcknowledged
+			res := m.alarms[i]
+			return &res, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "alarm not found")
+}
+
+func (m *fakeAlarmManager) Subscribe(context.Context, string) (vivnfmserver.AlarmStream, error) {
+	return nil, apperrors.ErrUnsupported
+}
+
+func TestAlarmHandlers(t *testing.T) {
+	alarmm := &fakeAlarmManager{alarms: []alarm.Alarm{{AlarmId: "a1", AckState: alarm.AckStateUnacknowledged}}}
+	mux := newTestMux()
+	srv := &fakeInterimServer{ViVnfmServer: &vivnfmserver.ViVnfmServer{AlarmMgr: alarmm}}
+	require.NoError(t, registerAlarmHandlers(mux, newTestConn(t, srv)))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(http.MethodGet, ViVnfmAlarmsPath+"?filter=(eq,ackState,UNACKNOWLEDGED)", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(rec)["alarms"], 1)
+	assert.Equal(t, "filter=(eq,ackState,UNACKNOWLEDGED)", alarmm.filter)
+
+	rec = serve(http.MethodPatch, ViVnfmAlarmsPath+"/a1", `{"ackState":"ACKNOWLEDGED"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "ACKNOWLEDGED", decode(rec)["ackState"])
+
+	rec = serve(http.MethodPatch, ViVnfmAlarmsPath+"/a1", `{"ackState":"UNACKNOWLEDGED"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodPatch, ViVnfmAlarmsPath+"/missing", `{"ackState":"ACKNOWLEDGED"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+}
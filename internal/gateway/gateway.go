@@ -11,6 +11,7 @@ import (
 	common "github.com/kube-nfv/kube-vim/internal/config"
 	config "github.com/kube-nfv/kube-vim/internal/config/gateway"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/gateway/subscription"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
@@ -74,18 +75,63 @@ func (g *kubeVimGateway) Start(ctx context.Context) error {
 	if err = vivnfm.RegisterViVnfmHandler(ctx, gwmux, conn); err != nil {
 		return fmt.Errorf("register viVnfm gateway handler: %w", err)
 	}
+	if err = registerNetworkUpdateHandler(gwmux, conn); err != nil {
+		return fmt.Errorf("register network update handler: %w", err)
+	}
+	if err = registerNetworkTerminateHandler(gwmux, conn); err != nil {
+		return fmt.Errorf("register network terminate handler: %w", err)
+	}
+	if err = registerSecurityGroupHandlers(gwmux, conn); err != nil {
+		return fmt.Errorf("register security group handlers: %w", err)
+	}
+	if err = registerVipHandlers(gwmux, conn); err != nil {
+		return fmt.Errorf("register vip handlers: %w", err)
+	}
+	if err = registerNatHandlers(gwmux, conn); err != nil {
+		return fmt.Errorf("register nat handlers: %w", err)
+	}
+	if err = registerAlarmHandlers(gwmux, conn); err != nil {
+		return fmt.Errorf("register alarm handlers: %w", err)
+	}
+	if err = registerPerformanceHandlers(gwmux, conn); err != nil {
+		return fmt.Errorf("register performance handlers: %w", err)
+	}
+	if err = registerOrViHandlers(gwmux, conn); err != nil {
+		return fmt.Errorf("register orVi gateway handler: %w", err)
+	}
+	uploader, err := newImageUploader(g.cfg.ImageUpload, conn, g.logger.Named("ImageUpload"))
+	if err != nil {
+		return fmt.Errorf("initialize image uploader: %w", err)
+	}
+	if err = uploader.register(gwmux); err != nil {
+		return fmt.Errorf("register image upload handler: %w", err)
+	}
+	serverCtx, serverCancel := context.WithCancel(ctx)
+	defer serverCancel()
+
+	var handler http.Handler = gwmux
+	if g.cfg.Subscriptions != nil && *g.cfg.Subscriptions.Enabled {
+		subHandler, err := g.startSubscriptions(serverCtx, conn)
+		if err != nil {
+			return fmt.Errorf("start subscriptions: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle(subscription.SubscriptionsPath, subHandler)
+		mux.Handle(subscription.SubscriptionsPath+"/", subHandler)
+		mux.Handle("/", gwmux)
+		handler = mux
+	}
+
 	servAddr := fmt.Sprintf(":%d", *g.cfg.Service.Server.Port)
 	server := &http.Server{
 		Addr:         servAddr,
-		Handler:      LogMiddlewareHandler(gwmux, g.logger),
+		Handler:      LogMiddlewareHandler(handler, g.logger),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
 	errCh := make(chan error, 1) // buffered channel to avoid goroutine leak
-	serverCtx, serverCancel := context.WithCancel(ctx)
-	defer serverCancel()
 
 	useTLS := common.IsServerTlsConfigured(g.cfg.Service.Server.Tls)
 	go func() {
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ViVnfmNatGatewaysPath is the REST root of the NAT gateways.
+	ViVnfmNatGatewaysPath = "/vivnfm/v5/nat-gateways"
+	// ViVnfmFloatingIpsPath is the REST root of the floating IPs.
+	ViVnfmFloatingIpsPath = "/vivnfm/v5/floating-ips"
+)
+
+// registerNatHandlers binds the NAT gateway and floating IP REST resources to
+// the interim Nat service. PATCH on a floating IP binds it to the portIpAddress
+// of the body, or unbinds it when that is empty.
+func registerNatHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	handlers := []restHandler{
+		{
+			method:  http.MethodPost,
+			pattern: ViVnfmNatGatewaysPath,
+			rpc:     vivnfmserver.Nat_CreateNatGateway_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return vivnfmserver.CreateNatGateway(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmNatGatewaysPath,
+			rpc:     vivnfmserver.Nat_QueryNatGateways_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryNatGateways(ctx, conn)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmNatGatewaysPath + "/{natGatewayId}",
+			rpc:     vivnfmserver.Nat_QueryNatGateway_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryNatGateway(ctx, conn, &nfvcommon.Identifier{Value: params["natGatewayId"]})
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   ViVnfmNatGatewaysPath + "/{natGatewayId}",
+			rpc:       vivnfmserver.Nat_DeleteNatGateway_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				if err := vivnfmserver.DeleteNatGateway(ctx, conn, &nfvcommon.Identifier{Value: params["natGatewayId"]}); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+		{
+			method:  http.MethodPost,
+			pattern: ViVnfmFloatingIpsPath,
+			rpc:     vivnfmserver.Nat_CreateFloatingIp_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return vivnfmserver.CreateFloatingIp(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmFloatingIpsPath,
+			rpc:     vivnfmserver.Nat_QueryFloatingIps_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryFloatingIps(ctx, conn)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmFloatingIpsPath + "/{floatingIpId}",
+			rpc:     vivnfmserver.Nat_QueryFloatingIp_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryFloatingIp(ctx, conn, &nfvcommon.Identifier{Value: params["floatingIpId"]})
+			},
+		},
+		{
+			method:  http.MethodPatch,
+			pattern: ViVnfmFloatingIpsPath + "/{floatingIpId}",
+			rpc:     vivnfmserver.Nat_UpdateFloatingIp_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, params map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				if req.Fields == nil {
+					req.Fields = map[string]*structpb.Value{}
+				}
+				// The path addresses the floating IP, whatever the body says.
+				req.Fields["floatingIpId"] = structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+					"value": structpb.NewStringValue(params["floatingIpId"]),
+				}})
+				return vivnfmserver.UpdateFloatingIp(ctx, conn, req)
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   ViVnfmFloatingIpsPath + "/{floatingIpId}",
+			rpc:       vivnfmserver.Nat_DeleteFloatingIp_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				if err := vivnfmserver.DeleteFloatingIp(ctx, conn, &nfvcommon.Identifier{Value: params["floatingIpId"]}); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+	}
+	return registerRestHandlers(mux, handlers)
+}
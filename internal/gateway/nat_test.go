@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeNatManager keeps NAT gateways and floating IPs in memory. Its other
+// network manager methods are not used by the NAT handlers.
+type fakeNatManager struct {
+	network.Manager
+
+	gateways map[string]*network.NatGateway
+	fips     map[string]*network.FloatingIp
+}
+
+func (m *fakeNatManager) CreateNatGateway(_ context.Context, gw *network.NatGateway) (*network.NatGateway, error) {
+	gw.NatGatewayId = &nfvcommon.Identifier{Value: "gw-" + gw.Name}
+	gw.ExternalIpAddress = "172.18.0.10"
+	m.gateways[gw.NatGatewayId.Value] = gw
+	return gw, nil
+}
+
+func (m *fakeNatManager) GetNatGateway(_ context.Context, opts ...network.GetNatOpt) (*network.NatGateway, error) {
+	gw, ok := m.gateways[network.ApplyGetNatOpts(opts...).Uid.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "nat gateway not found")
+	}
+	return gw, nil
+}
+
+func (m *fakeNatManager) ListNatGateways(context.Context) ([]*network.NatGateway, error) {
+	res := make([]*network.NatGateway, 0, len(m.gateways))
+	for _, gw := range m.gateways {
+		res = append(res, gw)
+	}
+	return res, nil
+}
+
+func (m *fakeNatManager) DeleteNatGateway(_ context.Context, opts ...network.GetNatOpt) error {
+	if len(m.fips) != 0 {
+		return status.Error(codes.FailedPrecondition, "floating ips allocated")
+	}
+	delete(m.gateways, network.ApplyGetNatOpts(opts...).Uid.GetValue())
+	return nil
+}
+
+func (m *fakeNatManager) CreateFloatingIp(_ context.Context, fip *network.FloatingIp) (*network.FloatingIp, error) {
+	fip.FloatingIpId = &nfvcommon.Identifier{Value: "fip-" + fip.Name}
+	fip.IpAddress = "172.18.0.11"
+	m.fips[fip.FloatingIpId.Value] = fip
+	return fip, nil
+}
+
+func (m *fakeNatManager) GetFloatingIp(_ context.Context, opts ...network.GetNatOpt) (*network.FloatingIp, error) {
+	fip, ok := m.fips[network.ApplyGetNatOpts(opts...).Uid.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "floating ip not found")
+	}
+	return fip, nil
+}
+
+func (m *fakeNatManager) ListFloatingIps(context.Context) ([]*network.FloatingIp, error) {
+	res := make([]*network.FloatingIp, 0, len(m.fips))
+	for _, fip := range m.fips {
+		res = append(res, fip)
+	}
+	return res, nil
+}
+
+func (m *fakeNatManager) UpdateFloatingIp(_ context.Context, upd *network.FloatingIp) (*network.FloatingIp, error) {
+	fip, ok := m.fips[upd.FloatingIpId.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "floating ip not found")
+	}
+	fip.PortIpAddress = upd.PortIpAddress
+	return fip, nil
+}
+
+func (m *fakeNatManager) DeleteFloatingIp(_ context.Context, opts ...network.GetNatOpt) error {
+	delete(m.fips, network.ApplyGetNatOpts(opts...).Uid.GetValue())
+	return nil
+}
+
+func TestNatHandlers(t *testing.T) {
+	natm := &fakeNatManager{gateways: map[string]*network.NatGateway{}, fips: map[string]*network.FloatingIp{}}
+	mux := newTestMux()
+	srv := &fakeInterimServer{ViVnfmServer: &vivnfmserver.ViVnfmServer{NetworkMgr: natm}}
+	require.NoError(t, registerNatHandlers(mux, newTestConn(t, srv)))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(http.MethodPost, ViVnfmNatGatewaysPath, `{"name":"gw","networkId":{"value":"net-1"},"snatSubnetIds":[{"value":"sub-1"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	gw := decode(rec)
+	assert.Equal(t, map[string]any{"value": "gw-gw"}, gw["natGatewayId"])
+	assert.Equal(t, "172.18.0.10", gw["externalIpAddress"])
+
+	rec = serve(http.MethodGet, ViVnfmNatGatewaysPath+"/gw-gw", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "gw", decode(rec)["name"])
+
+	rec = serve(http.MethodPost, ViVnfmFloatingIpsPath, `{"name":"web","natGatewayId":{"value":"gw-gw"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "172.18.0.11", decode(rec)["ipAddress"])
+
+	rec = serve(http.MethodPatch, ViVnfmFloatingIpsPath+"/fip-web", `{"portIpAddress":"10.0.0.5"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "10.0.0.5", decode(rec)["portIpAddress"])
+	assert.Equal(t, "10.0.0.5", natm.fips["fip-web"].PortIpAddress)
+
+	rec = serve(http.MethodGet, ViVnfmFloatingIpsPath, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(rec)["floatingIps"], 1)
+
+	rec = serve(http.MethodDelete, ViVnfmNatGatewaysPath+"/gw-gw", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodDelete, ViVnfmFloatingIpsPath+"/fip-web", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = serve(http.MethodDelete, ViVnfmNatGatewaysPath+"/gw-gw", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, natm.gateways)
+
+	rec = serve(http.MethodGet, ViVnfmNatGatewaysPath, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Empty(t, decode(rec)["natGateways"])
+}
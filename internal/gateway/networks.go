@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ViVnfmNetworkPath is the REST resource of a virtualised network or subnet.
+const ViVnfmNetworkPath = "/vivnfm/v5/networks/{networkResourceId}"
+
+// registerNetworkUpdateHandler binds PATCH on a network resource to the interim
+// Networks UpdateVirtualisedNetworkResource RPC. The body is an
+// AllocateNetworkRequest carrying the updated network or subnet data.
+func registerNetworkUpdateHandler(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	err := mux.HandlePath(http.MethodPatch, ViVnfmNetworkPath, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		inbound, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx, err := runtime.AnnotateContext(ctx, mux, r, vivnfmserver.Networks_UpdateVirtualisedNetworkResource_FullMethodName, runtime.WithHTTPPathPattern(ViVnfmNetworkPath))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		req := &vivnfm.AllocateNetworkRequest{}
+		if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "decode request body: %v", err))
+			return
+		}
+		id := &nfvcommon.Identifier{Value: params["networkResourceId"]}
+		res, err := vivnfmserver.UpdateVirtualisedNetworkResource(ctx, conn, id, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, res)
+	})
+	if err != nil {
+		return fmt.Errorf("register %s %s: %w", http.MethodPatch, ViVnfmNetworkPath, err)
+	}
+	return nil
+}
+
+// registerNetworkTerminateHandler binds DELETE on a network resource in place of
+// the generated ViVnfm handler, so that the cascade=true query parameter reaches
+// TerminateVirtualisedNetworkResource as request metadata. It must be registered
+// after the generated handlers: the mux tries the last registered one first.
+func registerNetworkTerminateHandler(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	err := mux.HandlePath(http.MethodDelete, ViVnfmNetworkPath, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx, err := runtime.AnnotateContext(ctx, mux, r, vivnfm.ViVnfm_TerminateVirtualisedNetworkResource_FullMethodName, runtime.WithHTTPPathPattern(ViVnfmNetworkPath))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		cascade := r.URL.Query().Get("cascade") == "true"
+		id := &nfvcommon.Identifier{Value: params["networkResourceId"]}
+		res, err := vivnfmserver.TerminateVirtualisedNetworkResource(ctx, conn, id, cascade)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, res)
+	})
+	if err != nil {
+		return fmt.Errorf("register %s %s: %w", http.MethodDelete, ViVnfmNetworkPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeNetworkServer struct {
+	vivnfm.UnimplementedViVnfmServer
+	admin.UnimplementedAdminServer
+
+	lastId  string
+	lastReq *vivnfm.AllocateNetworkRequest
+	inUse   map[string]bool
+}
+
+func (s *fakeNetworkServer) TerminateVirtualisedNetworkResource(ctx context.Context, req *vivnfm.TerminateNetworkRequest) (*vivnfm.TerminateNetworkResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	cascade := len(md.Get(network.DeleteCascadeMetadataKey)) > 0
+	if s.inUse[req.GetNetworkResourceId().GetValue()] && !cascade {
+		return nil, status.Error(codes.FailedPrecondition, "in use by 1 compute(s): vm-1")
+	}
+	return &vivnfm.TerminateNetworkResponse{NetworkResourceId: req.NetworkResourceId}, nil
+}
+
+func (s *fakeNetworkServer) UpdateVirtualisedNetworkResource(ctx context.Context, req *vivnfm.AllocateNetworkRequest) (*vivnfm.AllocateNetworkResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get(network.UpdateResourceIdMetadataKey); len(ids) > 0 {
+		s.lastId = ids[0]
+	}
+	s.lastReq = req
+	if req.GetTypeSubnetData().GetCidr() != nil {
+		return nil, status.Error(codes.InvalidArgument, "cidr is immutable")
+	}
+	return &vivnfm.AllocateNetworkResponse{SubnetData: &vivnfm.NetworkSubnet{
+		ResourceId: &nfvcommon.Identifier{Value: s.lastId},
+		GatewayIp:  req.GetTypeSubnetData().GetGatewayIp(),
+	}}, nil
+}
+
+func TestNetworkUpdateHandler(t *testing.T) {
+	srv := &fakeNetworkServer{}
+	mux := newTestMux()
+	require.NoError(t, registerNetworkUpdateHandler(mux, newTestConn(t, srv)))
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/vivnfm/v5/networks/sub-1", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(`{"networkResourceType":"SUBNET","typeSubnetData":{"gatewayIp":{"ip":"10.0.0.254"}}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	out := map[string]any{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	assert.Equal(t, "sub-1", srv.lastId)
+	assert.Equal(t, nfvcommon.NetworkResourceType_SUBNET, srv.lastReq.GetNetworkResourceType())
+	assert.Equal(t, map[string]any{"ip": "10.0.0.254"}, out["subnetData"].(map[string]any)["gatewayIp"])
+
+	rec = serve(`{"networkResourceType":"SUBNET","typeSubnetData":{"cidr":{"cidr":"10.1.0.0/24"}}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(`not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestNetworkTerminateHandler(t *testing.T) {
+	srv := &fakeNetworkServer{inUse: map[string]bool{"net-2": true}}
+	conn := newTestConn(t, srv)
+	mux := newTestMux()
+	require.NoError(t, vivnfm.RegisterViVnfmHandler(context.Background(), mux, conn))
+	require.NoError(t, registerNetworkTerminateHandler(mux, conn))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		return rec
+	}
+
+	rec := serve("/vivnfm/v5/networks/net-1")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	out := map[string]any{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	assert.Equal(t, map[string]any{"value": "net-1"}, out["networkResourceId"])
+
+	assert.Equal(t, http.StatusBadRequest, serve("/vivnfm/v5/networks/net-2").Code, "FailedPrecondition maps to 400")
+	assert.Equal(t, http.StatusOK, serve("/vivnfm/v5/networks/net-2?cascade=true").Code)
+}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	orviserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/orvi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// OrViImagesPath is the REST root of the Or-Vi software image operations.
+	OrViImagesPath = "/orvi/v5/images"
+	// OrViResourceZonesPath is the REST root of the resource zones.
+	OrViResourceZonesPath = "/orvi/v5/resource_zones"
+	// OrViComputeCapacityPath is the REST resource of the compute capacity.
+	OrViComputeCapacityPath = "/orvi/v5/compute_capacity"
+	// OrViQuotasPath is the REST root of the compute and network quotas.
+	OrViQuotasPath = "/orvi/v5/quotas"
+)
+
+// registerOrViHandlers binds the Or-Vi REST resources. Until the Or-Vi service
+// is published in kube-vim-api they are served over the interim OrVi service.
+func registerOrViHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	handlers := []restHandler{
+		{
+			method:  http.MethodPost,
+			pattern: OrViImagesPath,
+			rpc:     orviserver.OrVi_AddImage_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &admin.DownloadImageRequest{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return orviserver.AddImage(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: OrViImagesPath,
+			rpc:     orviserver.OrVi_QueryImages_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return orviserver.QueryImages(ctx, conn, filterRequest(r))
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: OrViImagesPath + "/{softwareImageId}",
+			rpc:     orviserver.OrVi_QueryImage_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return orviserver.QueryImage(ctx, conn, &nfvcommon.Identifier{Value: params["softwareImageId"]})
+			},
+		},
+		{
+			method:  http.MethodPatch,
+			pattern: OrViImagesPath + "/{softwareImageId}",
+			rpc:     orviserver.OrVi_UpdateImage_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, params map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				md := &admin.ImageMetadata{}
+				if err := inbound.NewDecoder(r.Body).Decode(md); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return orviserver.UpdateImage(ctx, conn, &nfvcommon.Identifier{Value: params["softwareImageId"]}, md)
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   OrViImagesPath + "/{softwareImageId}",
+			rpc:       orviserver.OrVi_DeleteImage_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, r *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				force := r.URL.Query().Get("force") == "true"
+				id := &nfvcommon.Identifier{Value: params["softwareImageId"]}
+				if err := orviserver.DeleteImage(ctx, conn, id, force); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: OrViResourceZonesPath,
+			rpc:     orviserver.OrVi_QueryResourceZones_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return orviserver.QueryResourceZones(ctx, conn, filterRequest(r))
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: OrViComputeCapacityPath,
+			rpc:     orviserver.OrVi_QueryComputeCapacity_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+				for _, key := range []string{"zoneId", "computeFlavourId"} {
+					if v := r.URL.Query().Get(key); v != "" {
+						req.Fields[key] = structpb.NewStringValue(v)
+					}
+				}
+				return orviserver.QueryComputeCapacity(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: OrViQuotasPath + "/compute",
+			rpc:     orviserver.OrVi_QueryComputeQuota_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return orviserver.QueryComputeQuota(ctx, conn)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: OrViQuotasPath + "/network",
+			rpc:     orviserver.OrVi_QueryNetworkQuota_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return orviserver.QueryNetworkQuota(ctx, conn)
+			},
+		},
+	}
+
+	return registerRestHandlers(mux, handlers)
+}
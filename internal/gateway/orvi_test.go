@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/orvi"
+	adminserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/admin"
+	orviserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/orvi"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestConn serves the ViVnfm and Admin services of srv, and the interim
+// Images, Networks, SecurityGroups, Vips and Nat services it implements, over an
+// in-memory listener and returns a client connection to them.
+func newTestConn(t *testing.T, srv interface {
+	vivnfm.ViVnfmServer
+	admin.AdminServer
+}) *grpc.ClientConn {
+	t.Helper()
+	return serveTestConn(t, func(gs *grpc.Server) {
+		registerTestServers(gs, srv)
+	})
+}
+
+// registerTestServers registers on gs the services newTestConn serves.
+func registerTestServers(gs *grpc.Server, srv interface {
+	vivnfm.ViVnfmServer
+	admin.AdminServer
+}) {
+	vivnfm.RegisterViVnfmServer(gs, srv)
+	admin.RegisterAdminServer(gs, srv)
+	if images, ok := srv.(adminserver.ImagesServer); ok {
+		adminserver.RegisterImagesServer(gs, images)
+	}
+	if networks, ok := srv.(vivnfmserver.NetworksServer); ok {
+		vivnfmserver.RegisterNetworksServer(gs, networks)
+	}
+	if secgroups, ok := srv.(vivnfmserver.SecurityGroupsServer); ok {
+		vivnfmserver.RegisterSecurityGroupsServer(gs, secgroups)
+	}
+	if vips, ok := srv.(vivnfmserver.VipsServer); ok {
+		vivnfmserver.RegisterVipsServer(gs, vips)
+	}
+	if nat, ok := srv.(vivnfmserver.NatServer); ok {
+		vivnfmserver.RegisterNatServer(gs, nat)
+	}
+	if alarms, ok := srv.(vivnfmserver.AlarmsServer); ok {
+		vivnfmserver.RegisterAlarmsServer(gs, alarms)
+	}
+	if performance, ok := srv.(vivnfmserver.PerformanceServer); ok {
+		vivnfmserver.RegisterPerformanceServer(gs, performance)
+	}
+}
+
+// serveTestConn serves the services registered by register over an in-memory
+// listener and returns a client connection to them.
+func serveTestConn(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func newTestMux() *runtime.ServeMux {
+	return runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, newQuantityMarshaler(zap.NewNop())))
+}
+
+func TestOrViImageHandlers(t *testing.T) {
+	svc := &fakeOrViService{images: map[string]string{"img-1": "ubuntu", "img-2": "busy"}, inUse: map[string]bool{"img-2": true}}
+	mux := newTestMux()
+	require.NoError(t, registerOrViHandlers(mux, newOrViTestConn(t, svc)))
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(http.MethodPost, OrViImagesPath, `{"metadata":{"name":"debian"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "debian", decode(t, rec)["name"])
+	assert.Equal(t, "debian", svc.lastDownload.GetMetadata().GetName())
+
+	rec = serve(http.MethodGet, OrViImagesPath+"/img-1", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "ubuntu", decode(t, rec)["name"])
+
+	rec = serve(http.MethodGet, OrViImagesPath+"?filter=(eq,name,ubuntu)", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(t, rec)["softwareImagesInformation"], 3)
+	assert.Equal(t, "filter=(eq,name,ubuntu)", svc.lastFilter)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, OrViImagesPath+"/missing", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, OrViImagesPath, `{"metadata":`).Code)
+
+	rec = serve(http.MethodPatch, OrViImagesPath+"/img-1", `{"version":"24.04","metadata":{"fields":{"image.kubevim.kubenfv.io/min-disk":"20Gi"}}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "24.04", decode(t, rec)["version"])
+	assert.Equal(t, "20Gi", svc.lastUpdate.GetMetadata().GetFields()["image.kubevim.kubenfv.io/min-disk"])
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPatch, OrViImagesPath+"/missing", `{"version":"1"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPatch, OrViImagesPath+"/img-1", `{"version":`).Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, OrViImagesPath+"/img-1", "").Code)
+	assert.NotContains(t, svc.images, "img-1")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, OrViImagesPath+"/img-1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodDelete, OrViImagesPath+"/img-2", "").Code)
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, OrViImagesPath+"/img-2?force=true", "").Code)
+}
+
+// fakeOrViService serves the images it holds, answers fixed zones, capacity
+// and quotas and records the arguments it got.
+type fakeOrViService struct {
+	images       map[string]string
+	inUse        map[string]bool
+	lastFilter   string
+	lastDownload *admin.DownloadImageRequest
+	lastUpdate   *admin.ImageMetadata
+
+	zoneId    string
+	flavourId *nfvcommon.Identifier
+}
+
+func (f *fakeOrViService) AddImage(_ context.Context, req *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error) {
+	f.lastDownload = req
+	f.images["img-new"] = req.GetMetadata().GetName()
+	return &vivnfm.SoftwareImageInformation{SoftwareImageId: &nfvcommon.Identifier{Value: "img-new"}, Name: req.GetMetadata().GetName()}, nil
+}
+
+func (f *fakeOrViService) QueryImages(_ context.Context, filter string) ([]*vivnfm.SoftwareImageInformation, error) {
+	f.lastFilter = filter
+	res := make([]*vivnfm.SoftwareImageInformation, 0, len(f.images))
+	for id, name := range f.images {
+		res = append(res, &vivnfm.SoftwareImageInformation{SoftwareImageId: &nfvcommon.Identifier{Value: id}, Name: name})
+	}
+	return res, nil
+}
+
+func (f *fakeOrViService) QueryImage(_ context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	name, ok := f.images[id.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "image not found")
+	}
+	return &vivnfm.SoftwareImageInformation{SoftwareImageId: id, Name: name}, nil
+}
+
+func (f *fakeOrViService) UpdateImage(ctx context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	img, err := f.QueryImage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	f.lastUpdate = md
+	img.Version = md.Version
+	return img, nil
+}
+
+func (f *fakeOrViService) DeleteImage(_ context.Context, id *nfvcommon.Identifier, force bool) error {
+	if _, ok := f.images[id.GetValue()]; !ok {
+		return status.Error(codes.NotFound, "image not found")
+	}
+	if f.inUse[id.GetValue()] && !force {
+		return status.Error(codes.FailedPrecondition, "image in use")
+	}
+	delete(f.images, id.GetValue())
+	return nil
+}
+
+func (f *fakeOrViService) QueryResourceZones(_ context.Context, filter string) ([]orvi.ResourceZone, error) {
+	if filter == "filter=(eq,zoneState,UNAVAILABLE)" {
+		return []orvi.ResourceZone{}, nil
+	}
+	return []orvi.ResourceZone{{ZoneId: orvi.DefaultZoneId, ZoneState: orvi.ZoneStateAvailable, Hosts: []string{"node-1"}}}, nil
+}
+
+func (f *fakeOrViService) QueryComputeCapacity(_ context.Context, zoneId string, flavourId *nfvcommon.Identifier) (*orvi.ComputeCapacity, error) {
+	if zoneId == "missing" {
+		return nil, status.Error(codes.NotFound, "resource zone not found")
+	}
+	f.zoneId, f.flavourId = zoneId, flavourId
+	return &orvi.ComputeCapacity{ZoneId: zoneId, VCpu: orvi.CapacityInformation{TotalCapacity: 8, AvailableCapacity: 8}}, nil
+}
+
+func (f *fakeOrViService) QueryComputeQuota(context.Context) (*orvi.VirtualComputeQuota, error) {
+	return &orvi.VirtualComputeQuota{ResourceGroupId: "kube-nfv", NumVcInstances: orvi.QuotaValue{Used: 1}}, nil
+}
+
+func (f *fakeOrViService) QueryNetworkQuota(context.Context) (*orvi.VirtualNetworkQuota, error) {
+	return &orvi.VirtualNetworkQuota{ResourceGroupId: "kube-nfv", NumSubnets: orvi.QuotaValue{Used: 2}}, nil
+}
+
+// newOrViTestConn serves the interim OrVi service over svc. It is served
+// alone: its image RPCs share their names with the ViVnfm ones.
+func newOrViTestConn(t *testing.T, svc orviserver.Service) *grpc.ClientConn {
+	t.Helper()
+	return serveTestConn(t, func(gs *grpc.Server) {
+		orviserver.RegisterOrViServer(gs, &orviserver.Server{Svc: svc})
+	})
+}
+
+func TestOrViResourceHandlers(t *testing.T) {
+	svc := &fakeOrViService{}
+	mux := newTestMux()
+	require.NoError(t, registerOrViHandlers(mux, newOrViTestConn(t, svc)))
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(OrViResourceZonesPath)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(t, rec)["zones"], 1)
+	rec = serve(OrViResourceZonesPath + "?filter=(eq,zoneState,UNAVAILABLE)")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Empty(t, decode(t, rec)["zones"])
+
+	rec = serve(OrViComputeCapacityPath + "?zoneId=default&computeFlavourId=small")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "default", decode(t, rec)["zoneId"])
+	assert.Equal(t, "default", svc.zoneId)
+	assert.Equal(t, "small", svc.flavourId.GetValue())
+	assert.Equal(t, http.StatusNotFound, serve(OrViComputeCapacityPath+"?zoneId=missing").Code)
+
+	rec = serve(OrViQuotasPath + "/compute")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "kube-nfv", decode(t, rec)["resourceGroupId"])
+	rec = serve(OrViQuotasPath + "/network")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, decode(t, rec), "numSubnets")
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ViVnfmPmJobsPath is the REST root of the PM jobs.
+	ViVnfmPmJobsPath = "/vivnfm/v5/pm_jobs"
+	// ViVnfmThresholdsPath is the REST root of the thresholds.
+	ViVnfmThresholdsPath = "/vivnfm/v5/thresholds"
+)
+
+// registerPerformanceHandlers binds the PM job, performance report and
+// threshold REST resources to the interim Performance service. PM
+// notifications are only streamed over gRPC.
+func registerPerformanceHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	handlers := []restHandler{
+		{
+			method:  http.MethodPost,
+			pattern: ViVnfmPmJobsPath,
+			rpc:     vivnfmserver.Performance_CreatePmJob_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return vivnfmserver.CreatePmJob(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmPmJobsPath,
+			rpc:     vivnfmserver.Performance_QueryPmJobs_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryPmJobs(ctx, conn, filterRequest(r))
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   ViVnfmPmJobsPath + "/{pmJobId}",
+			rpc:       vivnfmserver.Performance_DeletePmJob_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				if err := vivnfmserver.DeletePmJob(ctx, conn, &nfvcommon.Identifier{Value: params["pmJobId"]}); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmPmJobsPath + "/{pmJobId}/reports/{reportId}",
+			rpc:     vivnfmserver.Performance_QueryPerformanceReport_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryPerformanceReport(ctx, conn, &structpb.Struct{Fields: map[string]*structpb.Value{
+					"pmJobId":  structpb.NewStringValue(params["pmJobId"]),
+					"reportId": structpb.NewStringValue(params["reportId"]),
+				}})
+			},
+		},
+		{
+			method:  http.MethodPost,
+			pattern: ViVnfmThresholdsPath,
+			rpc:     vivnfmserver.Performance_CreateThreshold_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return vivnfmserver.CreateThreshold(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmThresholdsPath,
+			rpc:     vivnfmserver.Performance_QueryThresholds_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryThresholds(ctx, conn, filterRequest(r))
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   ViVnfmThresholdsPath + "/{thresholdId}",
+			rpc:       vivnfmserver.Performance_DeleteThreshold_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				if err := vivnfmserver.DeleteThreshold(ctx, conn, &nfvcommon.Identifier{Value: params["thresholdId"]}); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+	}
+	return registerRestHandlers(mux, handlers)
+}
+
+// filterRequest is the interim query request carrying the SOL 013 filter of r,
+// if any.
+func filterRequest(r *http.Request) *structpb.Struct {
+	req := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	if f := r.URL.Query().Get("filter"); f != "" {
+		// Note: according to the ETSI GS NFA-SOL 013 5.2 specification filter should begin with "filter="
+		req.Fields["filter"] = structpb.NewStringValue(fmt.Sprintf("filter=%s", f))
+	}
+	return req
+}
@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/pm"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// inMemoryPmManager serves REST from a pm.Manager that is never started, so no
+// Prometheus query is made.
+type inMemoryPmManager struct {
+	*pm.Manager
+}
+
+func (m inMemoryPmManager) Subscribe(ctx context.Context, filter string) (vivnfmserver.PmStream, error) {
+	sub, err := m.Manager.Subscribe(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func TestPerformanceHandlers(t *testing.T) {
+	pmm := inMemoryPmManager{Manager: pm.NewManager(zap.NewNop(), nil, nil, time.Second, time.Minute)}
+	mux := newTestMux()
+	srv := &fakeInterimServer{ViVnfmServer: &vivnfmserver.ViVnfmServer{PmMgr: pmm}}
+	require.NoError(t, registerPerformanceHandlers(mux, newTestConn(t, srv)))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(http.MethodPost, ViVnfmPmJobsPath, `{"objectType":"COMPUTE","objectInstanceIds":["vm-a"],"performanceMetrics":["VCpuUsageMean"],"collectionPeriod":60,"reportingPeriod":300}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	jobId, _ := decode(rec)["pmJobId"].(string)
+	require.NotEmpty(t, jobId)
+
+	rec = serve(http.MethodGet, ViVnfmPmJobsPath+"?filter=(eq,pmJobId,"+jobId+")", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(rec)["pmJobs"], 1)
+
+	rec = serve(http.MethodDelete, ViVnfmPmJobsPath+"/"+jobId, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodPost, ViVnfmThresholdsPath, `{"objectType":"COMPUTE","objectInstanceIds":["vm-a"],"performanceMetric":"VMemoryUsageMean","thresholdValue":90}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	thresholdId, _ := decode(rec)["thresholdId"].(string)
+	require.NotEmpty(t, thresholdId)
+
+	rec = serve(http.MethodGet, ViVnfmThresholdsPath, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(rec)["thresholds"], 1)
+
+	rec = serve(http.MethodDelete, ViVnfmThresholdsPath+"/"+thresholdId, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodPost, ViVnfmThresholdsPath, `{"thresholdValue":90,"unknown":true}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+}
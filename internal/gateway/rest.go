@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+)
+
+// restCall serves a REST request over gRPC and returns the response message.
+type restCall func(ctx context.Context, r *http.Request, params map[string]string, inbound runtime.Marshaler) (proto.Message, error)
+
+// restHandler binds a REST method and path pattern to a restCall.
+type restHandler struct {
+	method  string
+	pattern string
+	// rpc annotates the request context with the gRPC method it is served by.
+	rpc string
+	// noContent answers 204 No Content on success instead of the message.
+	noContent bool
+	call      restCall
+}
+
+// registerRestHandlers binds each of the handlers on mux.
+func registerRestHandlers(mux *runtime.ServeMux, handlers []restHandler) error {
+	for _, h := range handlers {
+		err := mux.HandlePath(h.method, h.pattern, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			inbound, outbound := runtime.MarshalerForRequest(mux, r)
+			if h.rpc != "" {
+				annotated, err := runtime.AnnotateContext(ctx, mux, r, h.rpc, runtime.WithHTTPPathPattern(h.pattern))
+				if err != nil {
+					runtime.HTTPError(ctx, mux, outbound, w, r, err)
+					return
+				}
+				ctx = annotated
+			}
+			res, err := h.call(ctx, r, params, inbound)
+			if err != nil {
+				runtime.HTTPError(ctx, mux, outbound, w, r, err)
+				return
+			}
+			if h.noContent {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, res)
+		})
+		if err != nil {
+			return fmt.Errorf("register %s %s: %w", h.method, h.pattern, err)
+		}
+	}
+	return nil
+}
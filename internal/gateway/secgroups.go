@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ViVnfmSecurityGroupsPath is the REST root of the security groups.
+const ViVnfmSecurityGroupsPath = "/vivnfm/v5/security-groups"
+
+// registerSecurityGroupHandlers binds the security group REST resources to the
+// interim SecurityGroups service. An update replaces the rules of the group in
+// the path.
+func registerSecurityGroupHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	handlers := []restHandler{
+		{
+			method:  http.MethodPost,
+			pattern: ViVnfmSecurityGroupsPath,
+			rpc:     vivnfmserver.SecurityGroups_CreateSecurityGroup_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return vivnfmserver.CreateSecurityGroup(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmSecurityGroupsPath,
+			rpc:     vivnfmserver.SecurityGroups_QuerySecurityGroups_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QuerySecurityGroups(ctx, conn)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmSecurityGroupsPath + "/{securityGroupId}",
+			rpc:     vivnfmserver.SecurityGroups_QuerySecurityGroup_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QuerySecurityGroup(ctx, conn, &nfvcommon.Identifier{Value: params["securityGroupId"]})
+			},
+		},
+		{
+			method:  http.MethodPatch,
+			pattern: ViVnfmSecurityGroupsPath + "/{securityGroupId}",
+			rpc:     vivnfmserver.SecurityGroups_UpdateSecurityGroup_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, params map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				if req.Fields == nil {
+					req.Fields = map[string]*structpb.Value{}
+				}
+				// The path addresses the group, whatever the body says.
+				req.Fields["securityGroupId"] = structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+					"value": structpb.NewStringValue(params["securityGroupId"]),
+				}})
+				return vivnfmserver.UpdateSecurityGroup(ctx, conn, req)
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   ViVnfmSecurityGroupsPath + "/{securityGroupId}",
+			rpc:       vivnfmserver.SecurityGroups_DeleteSecurityGroup_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				if err := vivnfmserver.DeleteSecurityGroup(ctx, conn, &nfvcommon.Identifier{Value: params["securityGroupId"]}); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+	}
+	return registerRestHandlers(mux, handlers)
+}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSecurityGroupManager keeps security groups in memory. Its other network
+// manager methods are not used by the security group handlers.
+type fakeSecurityGroupManager struct {
+	network.Manager
+
+	groups map[string]*network.SecurityGroup
+}
+
+func (m *fakeSecurityGroupManager) CreateSecurityGroup(_ context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+	sg.SecurityGroupId = &nfvcommon.Identifier{Value: "sg-" + sg.Name}
+	m.groups[sg.SecurityGroupId.Value] = sg
+	return sg, nil
+}
+
+func (m *fakeSecurityGroupManager) GetSecurityGroup(_ context.Context, opts ...network.GetSecurityGroupOpt) (*network.SecurityGroup, error) {
+	sg, ok := m.groups[network.ApplyGetSecurityGroupOpts(opts...).Uid.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "security group not found")
+	}
+	return sg, nil
+}
+
+func (m *fakeSecurityGroupManager) ListSecurityGroups(context.Context) ([]*network.SecurityGroup, error) {
+	res := make([]*network.SecurityGroup, 0, len(m.groups))
+	for _, sg := range m.groups {
+		res = append(res, sg)
+	}
+	return res, nil
+}
+
+func (m *fakeSecurityGroupManager) UpdateSecurityGroup(_ context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+	cur, ok := m.groups[sg.SecurityGroupId.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "security group not found")
+	}
+	if sg.Rules != nil {
+		cur.Rules = sg.Rules
+	}
+	return cur, nil
+}
+
+func (m *fakeSecurityGroupManager) DeleteSecurityGroup(_ context.Context, opts ...network.GetSecurityGroupOpt) error {
+	id := network.ApplyGetSecurityGroupOpts(opts...).Uid.GetValue()
+	if id == "sg-in-use" {
+		return status.Error(codes.FailedPrecondition, "in use by 1 compute(s): vm-1")
+	}
+	delete(m.groups, id)
+	return nil
+}
+
+// fakeInterimServer serves the interim services of a ViVnfmServer over the
+// network manager under test.
+type fakeInterimServer struct {
+	*vivnfmserver.ViVnfmServer
+	admin.UnimplementedAdminServer
+}
+
+func TestSecurityGroupHandlers(t *testing.T) {
+	sgm := &fakeSecurityGroupManager{groups: map[string]*network.SecurityGroup{}}
+	mux := newTestMux()
+	srv := &fakeInterimServer{ViVnfmServer: &vivnfmserver.ViVnfmServer{NetworkMgr: sgm}}
+	require.NoError(t, registerSecurityGroupHandlers(mux, newTestConn(t, srv)))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(http.MethodPost, ViVnfmSecurityGroupsPath, `{"name":"web","rules":[{"direction":"ingress","protocol":"tcp","portRangeMin":443,"remoteCidr":"0.0.0.0/0"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	out := decode(rec)
+	assert.Equal(t, map[string]any{"value": "sg-web"}, out["securityGroupId"])
+	assert.Equal(t, "tcp", out["rules"].([]any)[0].(map[string]any)["protocol"])
+	require.Contains(t, sgm.groups, "sg-web")
+	assert.Equal(t, int32(443), sgm.groups["sg-web"].Rules[0].PortRangeMin)
+
+	rec = serve(http.MethodPost, ViVnfmSecurityGroupsPath, `{"name":"web","unknown":true}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodGet, ViVnfmSecurityGroupsPath+"/sg-web", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "web", decode(rec)["name"])
+
+	rec = serve(http.MethodGet, ViVnfmSecurityGroupsPath+"/sg-missing", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodGet, ViVnfmSecurityGroupsPath, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(rec)["securityGroups"], 1)
+
+	rec = serve(http.MethodPatch, ViVnfmSecurityGroupsPath+"/sg-web", `{"rules":[]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Empty(t, sgm.groups["sg-web"].Rules)
+
+	rec = serve(http.MethodDelete, ViVnfmSecurityGroupsPath+"/sg-in-use", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodDelete, ViVnfmSecurityGroupsPath+"/sg-web", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, sgm.groups)
+}
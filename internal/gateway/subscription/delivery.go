@@ -0,0 +1,143 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// DeliveryPolicy controls how notifications are POSTed to callback URIs.
+type DeliveryPolicy struct {
+	// MaxAttempts is the number of POSTs per notification, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles on every
+	// further retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds a single request to the callback URI.
+	Timeout time.Duration
+}
+
+// callbackClient sends requests to one subscriber's callback URI with the
+// credentials of its subscription.
+type callbackClient struct {
+	httpClient *http.Client
+	basic      *ParamsBasic
+	policy     DeliveryPolicy
+}
+
+// newCallbackClient builds the client for a subscription. With OAuth2 client
+// credentials the returned client fetches, caches and refreshes the access token
+// itself; base carries the transport for both the token and the callback calls.
+func newCallbackClient(ctx context.Context, base *http.Client, auth *SubscriptionAuthentication, policy DeliveryPolicy) *callbackClient {
+	c := &callbackClient{
+		httpClient: base,
+		policy:     policy,
+	}
+	switch authType(auth) {
+	case AuthTypeBasic:
+		c.basic = auth.ParamsBasic
+	case AuthTypeOauth2ClientCredentials:
+		params := auth.ParamsOauth2ClientCredentials
+		cc := &clientcredentials.Config{
+			ClientID:     params.ClientId,
+			ClientSecret: params.ClientPassword,
+			TokenURL:     params.TokenEndpoint,
+		}
+		c.httpClient = cc.Client(context.WithValue(ctx, oauth2.HTTPClient, base))
+	}
+	return c
+}
+
+// authType returns the authentication type of a validated subscription, or ""
+// when the callback is called without credentials.
+func authType(auth *SubscriptionAuthentication) AuthType {
+	if auth == nil || len(auth.AuthType) == 0 {
+		return ""
+	}
+	return auth.AuthType[0]
+}
+
+// test performs the SOL 013 callback connectivity test: a GET on the callback
+// URI that must be answered with 204 No Content.
+func (c *callbackClient) test(ctx context.Context, callbackUri string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.policy.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, callbackUri, nil)
+	if err != nil {
+		return &ErrCallbackTestFailed{CallbackUri: callbackUri, Reason: err.Error()}
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return &ErrCallbackTestFailed{CallbackUri: callbackUri, Reason: err.Error()}
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return &ErrCallbackTestFailed{CallbackUri: callbackUri, Reason: fmt.Sprintf("expected status 204, got %d", resp.StatusCode)}
+	}
+	return nil
+}
+
+// deliver POSTs the notification, retrying failed attempts with exponential
+// backoff. Any 2xx response is a successful delivery.
+func (c *callbackClient) deliver(ctx context.Context, callbackUri string, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("encode notification '%s': %w", n.Id, err)
+	}
+	backoff := c.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err = c.post(ctx, callbackUri, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.policy.MaxAttempts {
+			return fmt.Errorf("deliver notification '%s' after %d attempts: %w", n.Id, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("deliver notification '%s': %w", n.Id, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, c.policy.MaxBackoff)
+	}
+}
+
+func (c *callbackClient) post(ctx context.Context, callbackUri string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.policy.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackUri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// do sends the request and drains the response body so the connection can be
+// reused. Only the status code of a callback response is of interest.
+func (c *callbackClient) do(req *http.Request) (*http.Response, error) {
+	if c.basic != nil {
+		req.SetBasicAuth(c.basic.UserName, c.basic.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp, nil
+}
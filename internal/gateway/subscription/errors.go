@@ -0,0 +1,14 @@
+package subscription
+
+import "fmt"
+
+// ErrCallbackTestFailed is returned on create when the callback URI does not
+// pass the SOL 013 §5.9.9 connectivity test (GET answered with 204 No Content).
+type ErrCallbackTestFailed struct {
+	CallbackUri string
+	Reason      string
+}
+
+func (e *ErrCallbackTestFailed) Error() string {
+	return fmt.Sprintf("callback uri '%s' test failed: %s", e.CallbackUri, e.Reason)
+}
@@ -0,0 +1,126 @@
+package subscription
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"go.uber.org/zap"
+)
+
+// maxRequestBodySize bounds a create subscription request body.
+const maxRequestBodySize = 64 << 10
+
+// Handler serves the SOL 013 subscriptions resources:
+//
+//	POST   /vivnfm/v5/subscriptions       create (201 + Location)
+//	GET    /vivnfm/v5/subscriptions       query, optional ?filter=
+//	GET    /vivnfm/v5/subscriptions/{id}  read
+//	DELETE /vivnfm/v5/subscriptions/{id}  terminate (204)
+func NewHandler(m *Manager, logger *zap.Logger) http.Handler {
+	h := &handler{manager: m, logger: logger}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+SubscriptionsPath, h.create)
+	mux.HandleFunc("GET "+SubscriptionsPath, h.list)
+	mux.HandleFunc("GET "+SubscriptionsPath+"/{id}", h.get)
+	mux.HandleFunc("DELETE "+SubscriptionsPath+"/{id}", h.delete)
+	return mux
+}
+
+type handler struct {
+	manager *Manager
+	logger  *zap.Logger
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	req := &SubscriptionRequest{}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		h.writeError(w, &apperrors.ErrInvalidArgument{Field: "body", Reason: err.Error()})
+		return
+	}
+	sub, err := h.manager.Create(r.Context(), req, baseUri(r))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", sub.Links.Self.Href)
+	h.writeJSON(w, http.StatusCreated, sub)
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.manager.List(r.URL.Query().Get("filter"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	sub, err := h.manager.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sub)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.manager.Delete(r.Context(), r.PathValue("id")); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Warn("write subscriptions response", zap.Error(err))
+	}
+}
+
+// writeError maps err to a SOL 013 ProblemDetails response.
+func (h *handler) writeError(w http.ResponseWriter, err error) {
+	var (
+		invalidArg   *apperrors.ErrInvalidArgument
+		notFound     *apperrors.ErrNotFound
+		callbackTest *ErrCallbackTestFailed
+	)
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &invalidArg):
+		status = http.StatusBadRequest
+	case errors.As(err, &notFound):
+		status = http.StatusNotFound
+	case errors.As(err, &callbackTest):
+		status = http.StatusUnprocessableEntity
+	default:
+		h.logger.Error("subscriptions request failed", zap.Error(err))
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(&ProblemDetails{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	}); err != nil {
+		h.logger.Warn("write subscriptions error response", zap.Error(err))
+	}
+}
+
+// baseUri reconstructs the API root the client used, for resource links.
+func baseUri(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
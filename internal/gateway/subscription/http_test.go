@@ -0,0 +1,60 @@
+package subscription
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHandler(t *testing.T) {
+	cs := newCallbackServer(t)
+	m, _ := newTestManager(t)
+	h := NewHandler(m, zap.NewNop())
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	problem := func(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+		t.Helper()
+		assert.Equal(t, status, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		pd := ProblemDetails{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&pd))
+		assert.Equal(t, status, pd.Status)
+		assert.NotEmpty(t, pd.Detail)
+	}
+
+	rec := serve(http.MethodPost, SubscriptionsPath, `{"callbackUri":"`+cs.URL+`","filter":"(eq,resourceType,IMAGE)"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	sub := Subscription{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&sub))
+	assert.Equal(t, "http://example.com"+SubscriptionsPath+"/"+sub.Id, rec.Header().Get("Location"))
+	assert.Equal(t, "(eq,resourceType,IMAGE)", sub.Filter)
+
+	rec = serve(http.MethodGet, SubscriptionsPath+"/"+sub.Id, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodGet, SubscriptionsPath+"?filter=(eq,id,"+sub.Id+")", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var subs []Subscription
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&subs))
+	assert.Equal(t, []Subscription{sub}, subs)
+
+	problem(t, serve(http.MethodPost, SubscriptionsPath, `{"callbackUri":`), http.StatusBadRequest)
+	problem(t, serve(http.MethodPost, SubscriptionsPath, `{"callbackUri":"`+cs.URL+`","unknown":1}`), http.StatusBadRequest)
+	problem(t, serve(http.MethodPost, SubscriptionsPath, `{"callbackUri":"`+cs.URL+`/missing","filter":"(eq,x"}`), http.StatusBadRequest)
+	problem(t, serve(http.MethodGet, SubscriptionsPath+"/missing", ""), http.StatusNotFound)
+
+	rec = serve(http.MethodDelete, SubscriptionsPath+"/"+sub.Id, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	problem(t, serve(http.MethodDelete, SubscriptionsPath+"/"+sub.Id, ""), http.StatusNotFound)
+}
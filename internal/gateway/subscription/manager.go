@@ -0,0 +1,299 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	filter "github.com/kube-nfv/query-filter"
+	"go.uber.org/zap"
+)
+
+// subscriberQueueSize bounds the notifications waiting for delivery to a single
+// subscriber. While a callback is retried the queue fills up; once full, new
+// notifications for that subscriber are dropped so a dead callback cannot stall
+// delivery to the others.
+const subscriberQueueSize = 256
+
+// Manager owns the subscriptions: it validates and persists them, and runs one
+// delivery worker per subscription that POSTs matching events to its callback.
+type Manager struct {
+	logger     *zap.Logger
+	store      Store
+	httpClient *http.Client
+	policy     DeliveryPolicy
+
+	mu          sync.RWMutex
+	ctx         context.Context
+	subscribers map[string]*subscriber
+}
+
+type subscriber struct {
+	rec    *record
+	client *callbackClient
+	queue  chan *Notification
+	cancel context.CancelFunc
+}
+
+func NewManager(logger *zap.Logger, store Store, httpClient *http.Client, policy DeliveryPolicy) *Manager {
+	return &Manager{
+		logger:      logger,
+		store:       store,
+		httpClient:  httpClient,
+		policy:      policy,
+		subscribers: make(map[string]*subscriber),
+	}
+}
+
+// Start loads the persisted subscriptions and starts their delivery workers.
+// Workers stop when ctx is done. Start must be called before serving requests.
+func (m *Manager) Start(ctx context.Context) error {
+	recs, err := m.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("load subscriptions: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ctx = ctx
+	for _, rec := range recs {
+		m.startLocked(rec)
+	}
+	m.logger.Info("subscriptions loaded", zap.Int("count", len(recs)))
+	return nil
+}
+
+// Create validates the request, tests the callback URI and persists the new
+// subscription. baseUri is the gateway API root used to build the resource
+// links (e.g. "https://gw.example:51155").
+func (m *Manager) Create(ctx context.Context, req *SubscriptionRequest, baseUri string) (*Subscription, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	client := newCallbackClient(m.runContext(), m.httpClient, req.Authentication, m.policy)
+	if err := client.test(ctx, req.CallbackUri); err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	rec := &record{
+		Subscription: Subscription{
+			Id:          id,
+			Filter:      req.Filter,
+			CallbackUri: req.CallbackUri,
+			Links: SubscriptionLinks{
+				Self: Link{Href: baseUri + SubscriptionsPath + "/" + id},
+			},
+		},
+		Authentication: req.Authentication,
+	}
+	if err := m.store.Put(ctx, rec); err != nil {
+		return nil, fmt.Errorf("persist subscription '%s': %w", id, err)
+	}
+	m.mu.Lock()
+	m.startLocked(rec)
+	m.mu.Unlock()
+	m.logger.Info("subscription created", zap.String("id", id), zap.String("callbackUri", req.CallbackUri))
+	sub := rec.Subscription
+	return &sub, nil
+}
+
+// Get returns a subscription by id.
+func (m *Manager) Get(id string) (*Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.subscribers[id]
+	if !ok {
+		return nil, &apperrors.ErrNotFound{Entity: "subscription", Identifier: id}
+	}
+	sub := s.rec.Subscription
+	return &sub, nil
+}
+
+// List returns the subscriptions matching the SOL 013 filter expression
+// (without the "filter=" prefix). An empty filter returns all of them.
+func (m *Manager) List(filterStr string) ([]Subscription, error) {
+	m.mu.RLock()
+	subs := make([]Subscription, 0, len(m.subscribers))
+	for _, s := range m.subscribers {
+		subs = append(subs, s.rec.Subscription)
+	}
+	m.mu.RUnlock()
+	slices.SortFunc(subs, func(a, b Subscription) int { return strings.Compare(a.Id, b.Id) })
+	if filterStr == "" {
+		return subs, nil
+	}
+	res, err := filter.FilterList(subs, "filter="+filterStr)
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	return res, nil
+}
+
+// Delete removes a subscription and stops its delivery worker. Notifications
+// still queued for it are discarded.
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	m.mu.RLock()
+	_, ok := m.subscribers[id]
+	m.mu.RUnlock()
+	if !ok {
+		return &apperrors.ErrNotFound{Entity: "subscription", Identifier: id}
+	}
+	if err := m.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete subscription '%s': %w", id, err)
+	}
+	m.mu.Lock()
+	if s, ok := m.subscribers[id]; ok {
+		s.cancel()
+		delete(m.subscribers, id)
+	}
+	m.mu.Unlock()
+	m.logger.Info("subscription deleted", zap.String("id", id))
+	return nil
+}
+
+// Dispatch queues the event for every subscription whose filter matches it. It
+// never blocks.
+func (m *Manager) Dispatch(ev event.Event) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for id, s := range m.subscribers {
+		if !matches(s.rec.Subscription.Filter, ev) {
+			continue
+		}
+		n := newNotification(s.rec, ev)
+		select {
+		case s.queue <- n:
+		default:
+			m.logger.Warn("subscriber queue full, drop notification",
+				zap.String("subscriptionId", id),
+				zap.String("resourceId", ev.ResourceId),
+				zap.String("changeType", string(ev.ChangeType)))
+		}
+	}
+}
+
+// runContext is the context delivery workers and token sources live in.
+func (m *Manager) runContext() context.Context {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// startLocked registers the subscriber and starts its delivery worker. m.mu must
+// be held and Start must have set m.ctx.
+func (m *Manager) startLocked(rec *record) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	s := &subscriber{
+		rec:    rec,
+		client: newCallbackClient(ctx, m.httpClient, rec.Authentication, m.policy),
+		queue:  make(chan *Notification, subscriberQueueSize),
+		cancel: cancel,
+	}
+	m.subscribers[rec.Subscription.Id] = s
+	go m.deliverLoop(ctx, s)
+}
+
+func (m *Manager) deliverLoop(ctx context.Context, s *subscriber) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-s.queue:
+			if err := s.client.deliver(ctx, s.rec.Subscription.CallbackUri, n); err != nil && ctx.Err() == nil {
+				m.logger.Error("notification delivery failed",
+					zap.String("subscriptionId", s.rec.Subscription.Id),
+					zap.String("notificationId", n.Id),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+func newNotification(rec *record, ev event.Event) *Notification {
+	ts := ev.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return &Notification{
+		Id:               uuid.NewString(),
+		NotificationType: VirtualisedResourceChangeNotificationType,
+		SubscriptionId:   rec.Subscription.Id,
+		TimeStamp:        ts,
+		ChangeType:       ev.ChangeType,
+		ResourceType:     ev.ResourceType,
+		ResourceId:       ev.ResourceId,
+		ResourceName:     ev.ResourceName,
+		State:            ev.State,
+		PreviousState:    ev.PreviousState,
+		Links: NotificationLinks{
+			Subscription: Link{Href: rec.Subscription.Links.Self.Href},
+		},
+	}
+}
+
+// matches evaluates a subscription filter against an event. Filters are
+// validated on create, so an evaluation error is treated as no match.
+func matches(filterStr string, ev event.Event) bool {
+	if filterStr == "" {
+		return true
+	}
+	res, err := filter.FilterList([]event.Event{ev}, "filter="+filterStr)
+	return err == nil && len(res) == 1
+}
+
+func validateRequest(req *SubscriptionRequest) error {
+	if err := validateUri(req.CallbackUri); err != nil {
+		return &apperrors.ErrInvalidArgument{Field: "callbackUri", Reason: err.Error()}
+	}
+	if req.Filter != "" {
+		if _, err := filter.FilterList([]event.Event{{}}, "filter="+req.Filter); err != nil {
+			return &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+		}
+	}
+	auth := req.Authentication
+	if auth == nil {
+		return nil
+	}
+	if len(auth.AuthType) != 1 {
+		return &apperrors.ErrInvalidArgument{Field: "authentication.authType", Reason: "exactly one authentication type must be set"}
+	}
+	switch auth.AuthType[0] {
+	case AuthTypeBasic:
+		if auth.ParamsBasic == nil || auth.ParamsBasic.UserName == "" {
+			return &apperrors.ErrInvalidArgument{Field: "authentication.paramsBasic", Reason: "userName is required for BASIC authentication"}
+		}
+	case AuthTypeOauth2ClientCredentials:
+		params := auth.ParamsOauth2ClientCredentials
+		if params == nil || params.ClientId == "" {
+			return &apperrors.ErrInvalidArgument{Field: "authentication.paramsOauth2ClientCredentials", Reason: "clientId is required for OAUTH2_CLIENT_CREDENTIALS authentication"}
+		}
+		if err := validateUri(params.TokenEndpoint); err != nil {
+			return &apperrors.ErrInvalidArgument{Field: "authentication.paramsOauth2ClientCredentials.tokenEndpoint", Reason: err.Error()}
+		}
+	default:
+		return &apperrors.ErrInvalidArgument{Field: "authentication.authType", Reason: fmt.Sprintf("unsupported authentication type '%s'", auth.AuthType[0])}
+	}
+	return nil
+}
+
+func validateUri(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("'%s' is not an absolute http(s) uri", uri)
+	}
+	return nil
+}
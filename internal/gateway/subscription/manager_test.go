@@ -0,0 +1,204 @@
+package subscription
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testPolicy = DeliveryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     2 * time.Millisecond,
+	Timeout:        time.Second,
+}
+
+// callbackServer is a subscriber endpoint. It answers the callback test with 204
+// and records POSTed notifications, failing the first failFirst POSTs.
+type callbackServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	failFirst     int
+	posts         int
+	notifications []Notification
+	authHeaders   []string
+	received      chan struct{}
+}
+
+func newCallbackServer(t *testing.T) *callbackServer {
+	cs := &callbackServer{received: make(chan struct{}, 16)}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		cs.authHeaders = append(cs.authHeaders, r.Header.Get("Authorization"))
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		cs.posts++
+		if cs.posts <= cs.failFirst {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		n := Notification{}
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cs.notifications = append(cs.notifications, n)
+		w.WriteHeader(http.StatusNoContent)
+		cs.received <- struct{}{}
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+func (cs *callbackServer) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-cs.received:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "timed out waiting for notification")
+	}
+}
+
+func newTestManager(t *testing.T) (*Manager, Store) {
+	store := NewConfigMapStore(k8stest.NewClient(t), k8stest.TestNamespace, testStoreName)
+	m := NewManager(zap.NewNop(), store, &http.Client{}, testPolicy)
+	require.NoError(t, m.Start(t.Context()))
+	return m, store
+}
+
+func TestManagerCreateAndDeliver(t *testing.T) {
+	cs := newCallbackServer(t)
+	cs.failFirst = 2
+	m, store := newTestManager(t)
+
+	sub, err := m.Create(t.Context(), &SubscriptionRequest{
+		CallbackUri: cs.URL,
+		Filter:      "(eq,resourceType,COMPUTE)",
+		Authentication: &SubscriptionAuthentication{
+			AuthType:    []AuthType{AuthTypeBasic},
+			ParamsBasic: &ParamsBasic{UserName: "user", Password: "secret"},
+		},
+	}, "http://gw")
+	require.NoError(t, err)
+	assert.Equal(t, "http://gw/vivnfm/v5/subscriptions/"+sub.Id, sub.Links.Self.Href)
+
+	recs, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, *sub, recs[0].Subscription)
+
+	m.Dispatch(event.Event{ChangeType: event.ChangeTypeCreated, ResourceType: event.ResourceTypeImage, ResourceId: "img"})
+	m.Dispatch(event.Event{ChangeType: event.ChangeTypeStateChanged, ResourceType: event.ResourceTypeCompute, ResourceId: "vm", State: "RUNNING", PreviousState: "STARTING"})
+	cs.wait(t)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	assert.Equal(t, 3, cs.posts, "delivered on the third attempt")
+	require.Len(t, cs.notifications, 1, "filtered out events are not delivered")
+	n := cs.notifications[0]
+	assert.Equal(t, VirtualisedResourceChangeNotificationType, n.NotificationType)
+	assert.Equal(t, sub.Id, n.SubscriptionId)
+	assert.Equal(t, "vm", n.ResourceId)
+	assert.Equal(t, "RUNNING", n.State)
+	assert.Equal(t, "STARTING", n.PreviousState)
+	assert.Equal(t, sub.Links.Self.Href, n.Links.Subscription.Href)
+	for _, h := range cs.authHeaders {
+		assert.NotEmpty(t, h, "every callback request carries the credentials")
+	}
+}
+
+func TestManagerRestoresSubscriptions(t *testing.T) {
+	cs := newCallbackServer(t)
+	m, store := newTestManager(t)
+	sub, err := m.Create(t.Context(), &SubscriptionRequest{CallbackUri: cs.URL}, "http://gw")
+	require.NoError(t, err)
+
+	restarted := NewManager(zap.NewNop(), store, &http.Client{}, testPolicy)
+	require.NoError(t, restarted.Start(t.Context()))
+	got, err := restarted.Get(sub.Id)
+	require.NoError(t, err)
+	assert.Equal(t, sub, got)
+
+	restarted.Dispatch(event.Event{ChangeType: event.ChangeTypeDeleted, ResourceType: event.ResourceTypeNetwork, ResourceId: "net"})
+	cs.wait(t)
+}
+
+func TestManagerCreateValidation(t *testing.T) {
+	cs := newCallbackServer(t)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(failing.Close)
+	m, _ := newTestManager(t)
+
+	tests := []struct {
+		name    string
+		req     *SubscriptionRequest
+		wantErr any
+	}{
+		{"relative callback", &SubscriptionRequest{CallbackUri: "/cb"}, &apperrors.ErrInvalidArgument{}},
+		{"non http callback", &SubscriptionRequest{CallbackUri: "ftp://host/cb"}, &apperrors.ErrInvalidArgument{}},
+		{"invalid filter", &SubscriptionRequest{CallbackUri: cs.URL, Filter: "(eq,noSuchField,x)"}, &apperrors.ErrInvalidArgument{}},
+		{"no auth type", &SubscriptionRequest{CallbackUri: cs.URL, Authentication: &SubscriptionAuthentication{}}, &apperrors.ErrInvalidArgument{}},
+		{"unsupported auth type", &SubscriptionRequest{CallbackUri: cs.URL, Authentication: &SubscriptionAuthentication{AuthType: []AuthType{"TLS_CERT"}}}, &apperrors.ErrInvalidArgument{}},
+		{"basic without params", &SubscriptionRequest{CallbackUri: cs.URL, Authentication: &SubscriptionAuthentication{AuthType: []AuthType{AuthTypeBasic}}}, &apperrors.ErrInvalidArgument{}},
+		{"oauth2 without token endpoint", &SubscriptionRequest{CallbackUri: cs.URL, Authentication: &SubscriptionAuthentication{
+			AuthType:                      []AuthType{AuthTypeOauth2ClientCredentials},
+			ParamsOauth2ClientCredentials: &ParamsOauth2ClientCredentials{ClientId: "id"},
+		}}, &apperrors.ErrInvalidArgument{}},
+		{"callback test fails", &SubscriptionRequest{CallbackUri: failing.URL}, &ErrCallbackTestFailed{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Create(t.Context(), tt.req, "http://gw")
+			require.Error(t, err)
+			assert.IsType(t, tt.wantErr, err)
+		})
+	}
+	subs, err := m.List("")
+	require.NoError(t, err)
+	assert.Empty(t, subs, "rejected requests are not persisted")
+}
+
+func TestManagerListAndDelete(t *testing.T) {
+	cs := newCallbackServer(t)
+	m, store := newTestManager(t)
+	a, err := m.Create(t.Context(), &SubscriptionRequest{CallbackUri: cs.URL + "/a"}, "http://gw")
+	require.NoError(t, err)
+	b, err := m.Create(t.Context(), &SubscriptionRequest{CallbackUri: cs.URL + "/b"}, "http://gw")
+	require.NoError(t, err)
+
+	subs, err := m.List("")
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+	subs, err = m.List("(eq,id," + b.Id + ")")
+	require.NoError(t, err)
+	assert.Equal(t, []Subscription{*b}, subs)
+	_, err = m.List("(eq,noSuchField,x)")
+	var invalid *apperrors.ErrInvalidArgument
+	assert.ErrorAs(t, err, &invalid)
+
+	require.NoError(t, m.Delete(t.Context(), a.Id))
+	var notFound *apperrors.ErrNotFound
+	_, err = m.Get(a.Id)
+	assert.ErrorAs(t, err, &notFound)
+	assert.ErrorAs(t, m.Delete(t.Context(), a.Id), &notFound)
+	recs, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, b.Id, recs[0].Subscription.Id)
+}
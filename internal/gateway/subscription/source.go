@@ -0,0 +1,111 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// EventSource produces kube-vim change events for the gateway to deliver.
+type EventSource interface {
+	// Run emits events until ctx is done.
+	Run(ctx context.Context, emit func(event.Event)) error
+}
+
+// streamSource follows the kube-vim Notifications stream (see
+// docs/notifications.md). When the stream ends it reopens it after
+// reconnectInterval, resuming after the last event it emitted so a dropped
+// connection neither loses nor repeats events.
+type streamSource struct {
+	logger            *zap.Logger
+	conn              grpc.ClientConnInterface
+	reconnectInterval time.Duration
+
+	// lastVersion is the version of the last emitted event. Empty until the
+	// first event, or after kube-vim reported it expired.
+	lastVersion string
+}
+
+func NewStreamSource(logger *zap.Logger, conn grpc.ClientConnInterface, reconnectInterval time.Duration) *streamSource {
+	return &streamSource{
+		logger:            logger,
+		conn:              conn,
+		reconnectInterval: reconnectInterval,
+	}
+}
+
+func (s *streamSource) Run(ctx context.Context, emit func(event.Event)) error {
+	for {
+		err := s.follow(ctx, emit)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if status.Code(err) == codes.OutOfRange {
+			// The marker left the kube-vim backlog, or kube-vim restarted. The
+			// changes in between cannot be replayed; follow from now on.
+			s.logger.Warn("notification version expired, changes since it are not reported",
+				zap.String("version", s.lastVersion), zap.Error(err))
+			s.lastVersion = ""
+			continue
+		}
+		s.logger.Warn("kube-vim notification stream ended, reconnecting",
+			zap.String("version", s.lastVersion), zap.Duration("after", s.reconnectInterval), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.reconnectInterval):
+		}
+	}
+}
+
+// follow emits the events of one stream until it ends. It returns nil when
+// kube-vim closed the stream.
+func (s *streamSource) follow(ctx context.Context, emit func(event.Event)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := vivnfmserver.SubscribeNotifications(ctx, s.conn, &structpb.Struct{}, s.lastVersion)
+	if err != nil {
+		return fmt.Errorf("subscribe to kube-vim notifications: %w", err)
+	}
+	for {
+		st, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ev, err := decodeEvent(st)
+		if err != nil {
+			return err
+		}
+		emit(ev)
+		s.lastVersion = ev.Version
+	}
+}
+
+// decodeEvent decodes a streamed event. Unlike interim.DecodeStruct it ignores
+// unknown fields, so a newer kube-vim can add attributes.
+func decodeEvent(st *structpb.Struct) (event.Event, error) {
+	ev := event.Event{}
+	raw, err := protojson.Marshal(st)
+	if err != nil {
+		return ev, fmt.Errorf("encode kube-vim notification: %w", err)
+	}
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		return ev, fmt.Errorf("decode kube-vim notification: %w", err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,133 @@
+package subscription
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeNotifications serves one scripted stream per Subscribe call and records
+// the resume version each call asked for.
+type fakeNotifications struct {
+	mu       sync.Mutex
+	versions []string
+	streams  []func(grpc.ServerStreamingServer[structpb.Struct]) error
+}
+
+func (f *fakeNotifications) Subscribe(_ *structpb.Struct, stream grpc.ServerStreamingServer[structpb.Struct]) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	version := ""
+	if v := md.Get(vivnfmserver.NotificationVersionMetadataKey); len(v) > 0 {
+		version = v[0]
+	}
+	f.mu.Lock()
+	call := len(f.versions)
+	f.versions = append(f.versions, version)
+	f.mu.Unlock()
+	if call >= len(f.streams) {
+		<-stream.Context().Done()
+		return nil
+	}
+	return f.streams[call](stream)
+}
+
+func (f *fakeNotifications) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.versions...)
+}
+
+func sendEvents(versions ...string) func(grpc.ServerStreamingServer[structpb.Struct]) error {
+	return func(stream grpc.ServerStreamingServer[structpb.Struct]) error {
+		for _, v := range versions {
+			st, err := interim.EncodeStruct(event.Event{Version: v, ChangeType: event.ChangeTypeCreated, ResourceType: event.ResourceTypeCompute, ResourceId: "uid-" + v})
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(st); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func newNotificationsConn(t *testing.T, srv vivnfmserver.NotificationsServer) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	vivnfmserver.RegisterNotificationsServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestStreamSource(t *testing.T) {
+	fake := &fakeNotifications{streams: []func(grpc.ServerStreamingServer[structpb.Struct]) error{
+		// Delivers two events, then drops the connection.
+		func(stream grpc.ServerStreamingServer[structpb.Struct]) error {
+			if err := sendEvents("e1-1", "e1-2")(stream); err != nil {
+				return err
+			}
+			return status.Error(codes.Unavailable, "shutting down")
+		},
+		// The resume marker is gone, e.g. kube-vim restarted.
+		func(grpc.ServerStreamingServer[structpb.Struct]) error {
+			return status.Error(codes.OutOfRange, "version expired")
+		},
+		sendEvents("e2-1"),
+	}}
+	src := NewStreamSource(zap.NewNop(), newNotificationsConn(t, fake), time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	events := make(chan event.Event, 8)
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, func(ev event.Event) { events <- ev }) }()
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case ev := <-events:
+			assert.Equal(t, "uid-"+ev.Version, ev.ResourceId)
+			got = append(got, ev.Version)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for notifications", "got %v", got)
+		}
+	}
+	assert.Equal(t, []string{"e1-1", "e1-2", "e2-1"}, got)
+
+	// A clean end of the third stream resumes after its last event.
+	require.Eventually(t, func() bool { return len(fake.calls()) == 4 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, []string{"", "e1-2", "", "e2-1"}, fake.calls())
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "source did not stop on context cancellation")
+	}
+}
@@ -0,0 +1,166 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// record is a persisted subscription together with its callback credentials.
+type record struct {
+	Subscription   Subscription
+	Authentication *SubscriptionAuthentication
+}
+
+// Store persists subscriptions across gateway restarts.
+type Store interface {
+	List(ctx context.Context) ([]*record, error)
+	Put(ctx context.Context, rec *record) error
+	Delete(ctx context.Context, id string) error
+}
+
+// configMapStore keeps every subscription as a JSON entry keyed by its id in a
+// single ConfigMap. Callback credentials are kept under the same key in a Secret
+// of the same name, so the ConfigMap never holds passwords.
+type configMapStore struct {
+	client    client.Client
+	namespace string
+	name      string
+}
+
+func NewConfigMapStore(c client.Client, namespace, name string) *configMapStore {
+	return &configMapStore{
+		client:    c,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s *configMapStore) List(ctx context.Context) ([]*record, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get subscriptions configmap '%s/%s': %w", s.namespace, s.name, err)
+	}
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, secret); err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get subscriptions secret '%s/%s': %w", s.namespace, s.name, err)
+	}
+
+	ids := make([]string, 0, len(cm.Data))
+	for id := range cm.Data {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	res := make([]*record, 0, len(ids))
+	for _, id := range ids {
+		rec := &record{}
+		if err := json.Unmarshal([]byte(cm.Data[id]), &rec.Subscription); err != nil {
+			return nil, fmt.Errorf("decode subscription '%s': %w", id, err)
+		}
+		if raw, ok := secret.Data[id]; ok {
+			rec.Authentication = &SubscriptionAuthentication{}
+			if err := json.Unmarshal(raw, rec.Authentication); err != nil {
+				return nil, fmt.Errorf("decode authentication of subscription '%s': %w", id, err)
+			}
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+func (s *configMapStore) Put(ctx context.Context, rec *record) error {
+	id := rec.Subscription.Id
+	sub, err := json.Marshal(rec.Subscription)
+	if err != nil {
+		return fmt.Errorf("encode subscription '%s': %w", id, err)
+	}
+	// Credentials are written first: a subscription visible in the ConfigMap must
+	// always be deliverable.
+	if rec.Authentication != nil {
+		auth, err := json.Marshal(rec.Authentication)
+		if err != nil {
+			return fmt.Errorf("encode authentication of subscription '%s': %w", id, err)
+		}
+		if err := s.updateSecret(ctx, func(data map[string][]byte) { data[id] = auth }); err != nil {
+			return err
+		}
+	}
+	return s.updateConfigMap(ctx, func(data map[string]string) { data[id] = string(sub) })
+}
+
+func (s *configMapStore) Delete(ctx context.Context, id string) error {
+	if err := s.updateConfigMap(ctx, func(data map[string]string) { delete(data, id) }); err != nil {
+		return err
+	}
+	return s.updateSecret(ctx, func(data map[string][]byte) { delete(data, id) })
+}
+
+func (s *configMapStore) objectMeta() metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      s.name,
+		Namespace: s.namespace,
+		Labels: map[string]string{
+			common.K8sManagedByLabel: common.KubeNfvName,
+		},
+	}
+}
+
+func (s *configMapStore) updateConfigMap(ctx context.Context, mutate func(map[string]string)) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, cm); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return err
+			}
+			cm = &corev1.ConfigMap{ObjectMeta: s.objectMeta(), Data: map[string]string{}}
+			mutate(cm.Data)
+			return s.client.Create(ctx, cm)
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		mutate(cm.Data)
+		return s.client.Update(ctx, cm)
+	})
+	if err != nil {
+		return fmt.Errorf("update subscriptions configmap '%s/%s': %w", s.namespace, s.name, err)
+	}
+	return nil
+}
+
+func (s *configMapStore) updateSecret(ctx context.Context, mutate func(map[string][]byte)) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &corev1.Secret{}
+		if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, secret); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return err
+			}
+			secret = &corev1.Secret{ObjectMeta: s.objectMeta(), Data: map[string][]byte{}}
+			mutate(secret.Data)
+			if len(secret.Data) == 0 {
+				return nil
+			}
+			return s.client.Create(ctx, secret)
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		mutate(secret.Data)
+		return s.client.Update(ctx, secret)
+	})
+	if err != nil {
+		return fmt.Errorf("update subscriptions secret '%s/%s': %w", s.namespace, s.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package subscription
+
+import (
+	"testing"
+
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testStoreName = "kube-vim-gateway-subscriptions"
+
+func TestConfigMapStore(t *testing.T) {
+	c := k8stest.NewClient(t)
+	store := NewConfigMapStore(c, k8stest.TestNamespace, testStoreName)
+	ctx := t.Context()
+
+	recs, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, recs, "missing configmap is an empty store")
+
+	plain := &record{Subscription: Subscription{Id: "a", CallbackUri: "http://a.example/cb", Filter: "(eq,resourceType,COMPUTE)"}}
+	withAuth := &record{
+		Subscription: Subscription{Id: "b", CallbackUri: "http://b.example/cb"},
+		Authentication: &SubscriptionAuthentication{
+			AuthType:    []AuthType{AuthTypeBasic},
+			ParamsBasic: &ParamsBasic{UserName: "user", Password: "secret"},
+		},
+	}
+	require.NoError(t, store.Put(ctx, plain))
+	require.NoError(t, store.Put(ctx, withAuth))
+
+	recs, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*record{plain, withAuth}, recs)
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: k8stest.TestNamespace, Name: testStoreName}, cm))
+	assert.NotContains(t, cm.Data["b"], "secret", "credentials must not be stored in the configmap")
+
+	require.NoError(t, store.Delete(ctx, "b"))
+	recs, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*record{plain}, recs)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: k8stest.TestNamespace, Name: testStoreName}, secret))
+	assert.NotContains(t, secret.Data, "b")
+}
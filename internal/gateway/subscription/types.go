@@ -0,0 +1,101 @@
+// Package subscription implements ETSI GS NFV-SOL 013 subscriptions in the
+// kube-vim gateway: REST consumers register a callback URI and receive
+// virtualised resource change notifications as HTTP POSTs, without holding a
+// gRPC stream.
+package subscription
+
+import (
+	"time"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+)
+
+const (
+	// VirtualisedResourceChangeNotificationType is the notificationType of every
+	// notification delivered to subscribers.
+	VirtualisedResourceChangeNotificationType = "VirtualisedResourceChangeNotification"
+
+	// SubscriptionsPath is the subscriptions collection resource served by Handler.
+	SubscriptionsPath = "/vivnfm/v5/subscriptions"
+)
+
+// AuthType is a SOL 013 §8.3.4 notification authentication type.
+type AuthType string
+
+const (
+	AuthTypeBasic                   AuthType = "BASIC"
+	AuthTypeOauth2ClientCredentials AuthType = "OAUTH2_CLIENT_CREDENTIALS"
+)
+
+// SubscriptionAuthentication holds the credentials the gateway uses when calling
+// the subscriber's callback URI. It is never returned by the API.
+type SubscriptionAuthentication struct {
+	AuthType                      []AuthType                     `json:"authType"`
+	ParamsBasic                   *ParamsBasic                   `json:"paramsBasic,omitempty"`
+	ParamsOauth2ClientCredentials *ParamsOauth2ClientCredentials `json:"paramsOauth2ClientCredentials,omitempty"`
+}
+
+type ParamsBasic struct {
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+}
+
+type ParamsOauth2ClientCredentials struct {
+	ClientId       string `json:"clientId"`
+	ClientPassword string `json:"clientPassword"`
+	TokenEndpoint  string `json:"tokenEndpoint"`
+}
+
+// SubscriptionRequest is the body of a create subscription request.
+type SubscriptionRequest struct {
+	// Filter is a SOL 013 attribute filter expression (without the "filter="
+	// prefix) over the notification attributes, e.g.
+	// "(eq,resourceType,COMPUTE);(eq,changeType,STATE_CHANGED)". Empty matches
+	// every notification.
+	Filter         string                      `json:"filter,omitempty"`
+	CallbackUri    string                      `json:"callbackUri"`
+	Authentication *SubscriptionAuthentication `json:"authentication,omitempty"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+}
+
+type SubscriptionLinks struct {
+	Self Link `json:"self"`
+}
+
+// Subscription is the subscription resource representation.
+type Subscription struct {
+	Id          string            `json:"id"`
+	Filter      string            `json:"filter,omitempty"`
+	CallbackUri string            `json:"callbackUri"`
+	Links       SubscriptionLinks `json:"_links"`
+}
+
+type NotificationLinks struct {
+	Subscription Link `json:"subscription"`
+}
+
+// Notification is the body POSTed to the callback URI.
+type Notification struct {
+	Id               string             `json:"id"`
+	NotificationType string             `json:"notificationType"`
+	SubscriptionId   string             `json:"subscriptionId"`
+	TimeStamp        time.Time          `json:"timeStamp"`
+	ChangeType       event.ChangeType   `json:"changeType"`
+	ResourceType     event.ResourceType `json:"resourceType"`
+	ResourceId       string             `json:"resourceId"`
+	ResourceName     string             `json:"resourceName,omitempty"`
+	State            string             `json:"state,omitempty"`
+	PreviousState    string             `json:"previousState,omitempty"`
+	Links            NotificationLinks  `json:"_links"`
+}
+
+// ProblemDetails is the SOL 013 §6.3 error response body.
+type ProblemDetails struct {
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	config "github.com/kube-nfv/kube-vim/internal/config/gateway"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/gateway/subscription"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// startSubscriptions loads the persisted SOL 013 subscriptions, starts the
+// kube-vim change detection and returns the handler serving the subscriptions
+// API. Everything it starts stops when ctx is done.
+func (g *kubeVimGateway) startSubscriptions(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	cfg := g.cfg.Subscriptions
+	reconnectInterval, err := parseDuration("subscriptions.reconnectInterval", cfg.ReconnectInterval)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := deliveryPolicy(cfg.Delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("get k8s inClusterConfig: %w", err)
+	}
+	k8sClient, err := client.New(k8sConfig, client.Options{Scheme: clientgoscheme.Scheme})
+	if err != nil {
+		return nil, fmt.Errorf("create k8s client: %w", err)
+	}
+
+	logger := g.logger.Named("Subscriptions")
+	store := subscription.NewConfigMapStore(k8sClient, *cfg.Namespace, *cfg.ConfigMap)
+	mgr := subscription.NewManager(logger, store, &http.Client{}, policy)
+	if err := mgr.Start(ctx); err != nil {
+		return nil, err
+	}
+	source := subscription.NewStreamSource(logger, conn, reconnectInterval)
+	go func() {
+		if err := source.Run(ctx, mgr.Dispatch); err != nil {
+			logger.Error("kube-vim change detection stopped", zap.Error(err))
+		}
+	}()
+	return subscription.NewHandler(mgr, logger), nil
+}
+
+func deliveryPolicy(cfg *config.NotificationDeliveryConfig) (subscription.DeliveryPolicy, error) {
+	policy := subscription.DeliveryPolicy{MaxAttempts: *cfg.MaxAttempts}
+	if policy.MaxAttempts < 1 {
+		return policy, &apperrors.ErrInvalidArgument{Field: "subscriptions.delivery.maxAttempts", Reason: "must be at least 1"}
+	}
+	var err error
+	if policy.InitialBackoff, err = parseDuration("subscriptions.delivery.initialBackoff", cfg.InitialBackoff); err != nil {
+		return policy, err
+	}
+	if policy.MaxBackoff, err = parseDuration("subscriptions.delivery.maxBackoff", cfg.MaxBackoff); err != nil {
+		return policy, err
+	}
+	if policy.Timeout, err = parseDuration("subscriptions.delivery.timeout", cfg.Timeout); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+func parseDuration(field string, value *string) (time.Duration, error) {
+	d, err := time.ParseDuration(*value)
+	if err != nil {
+		return 0, &apperrors.ErrInvalidArgument{Field: field, Reason: err.Error()}
+	}
+	if d <= 0 {
+		return 0, &apperrors.ErrInvalidArgument{Field: field, Reason: "must be positive"}
+	}
+	return d, nil
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	config "github.com/kube-nfv/kube-vim/internal/config/gateway"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
+	adminserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/admin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	// ImageUploadPath streams the request body into a new software image. The
+	// image name is the "name" query parameter; "storageSize" and
+	// "storageClass" are optional.
+	ImageUploadPath = "/admin/v1/images/upload"
+
+	defaultUploadReadyTimeout = 5 * time.Minute
+	uploadReadyPollInterval   = time.Second
+	// uploadErrorBodyLimit bounds how much of an upload proxy error response
+	// ends up in the returned status.
+	uploadErrorBodyLimit = 4096
+	// uploadCleanupTimeout bounds the deletion of an image whose upload
+	// failed. It runs after the request context may already be canceled.
+	uploadCleanupTimeout = 30 * time.Second
+)
+
+// imageUploader serves ImageUploadPath. It registers the image with
+// SetupImageUploadProxy, waits until CDI is ready to receive it and forwards
+// the request body to the CDI upload proxy, so that the client needs no access
+// to the cluster.
+type imageUploader struct {
+	logger *zap.Logger
+
+	conn         grpc.ClientConnInterface
+	adminClient  admin.AdminClient
+	vivnfmClient vivnfm.ViVnfmClient
+	httpClient   *http.Client
+
+	readyTimeout time.Duration
+	pollInterval time.Duration
+}
+
+func newImageUploader(cfg *config.ImageUploadConfig, conn grpc.ClientConnInterface, logger *zap.Logger) (*imageUploader, error) {
+	u := &imageUploader{
+		logger:       logger,
+		conn:         conn,
+		adminClient:  admin.NewAdminClient(conn),
+		vivnfmClient: vivnfm.NewViVnfmClient(conn),
+		readyTimeout: defaultUploadReadyTimeout,
+		pollInterval: uploadReadyPollInterval,
+	}
+	var tlsCfg *common.TlsClientConfig
+	if cfg != nil {
+		tlsCfg = cfg.Tls
+		if cfg.ReadyTimeout != nil {
+			d, err := time.ParseDuration(*cfg.ReadyTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("parse imageUpload.readyTimeout '%s': %w", *cfg.ReadyTimeout, err)
+			}
+			u.readyTimeout = d
+		}
+	}
+	tlsClientCfg, err := common.NewClientTlsConfig(tlsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize upload proxy TLS configuration: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsClientCfg
+	u.httpClient = &http.Client{Transport: transport}
+	return u, nil
+}
+
+func (u *imageUploader) register(mux *runtime.ServeMux) error {
+	return mux.HandlePath(http.MethodPost, ImageUploadPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		res, err := u.upload(ctx, w, r)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, res)
+	})
+}
+
+func (u *imageUploader) upload(ctx context.Context, w http.ResponseWriter, r *http.Request) (*vivnfm.SoftwareImageInformation, error) {
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "query parameter 'name' is required")
+	}
+	// An image takes far longer to stream than the server read and write
+	// timeouts allow.
+	rc := http.NewResponseController(w)
+	if err := errors.Join(rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{})); err != nil {
+		u.logger.Warn("Failed to lift the server timeouts; the upload is cut off by them", zap.String("image", name), zap.Error(err))
+	}
+
+	var header metadata.MD
+	setupCtx := metadata.AppendToOutgoingContext(ctx,
+		image.UploadImageNameMetadataKey, name,
+		image.UploadStorageSizeMetadataKey, query.Get("storageSize"),
+		image.UploadStorageClassMetadataKey, query.Get("storageClass"),
+	)
+	if _, err := u.adminClient.SetupImageUploadProxy(setupCtx, &admin.SetupImageUploadProxyRequest{}, grpc.Header(&header)); err != nil {
+		return nil, err
+	}
+	first := func(key string) string {
+		if v := header.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	imageId, uploadUrl, token := first(image.UploadImageIdMetadataKey), first(image.UploadUrlMetadataKey), first(image.UploadTokenMetadataKey)
+	if imageId == "" || uploadUrl == "" || token == "" {
+		return nil, status.Error(codes.Internal, "kube-vim returned no upload target")
+	}
+	log := u.logger.With(zap.String("image", name), zap.String("imageId", imageId))
+
+	if err := u.waitUploadReady(ctx, imageId); err != nil {
+		u.discard(ctx, log, imageId)
+		return nil, err
+	}
+	log.Info("streaming image to the CDI upload proxy")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadUrl, r.Body)
+	if err != nil {
+		u.discard(ctx, log, imageId)
+		return nil, status.Errorf(codes.Internal, "build upload proxy request: %v", err)
+	}
+	req.ContentLength = r.ContentLength
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		u.discard(ctx, log, imageId)
+		return nil, status.Errorf(codes.Unavailable, "upload image '%s' to the CDI upload proxy: %v", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, uploadErrorBodyLimit))
+		u.discard(ctx, log, imageId)
+		return nil, status.Errorf(codes.Internal, "CDI upload proxy rejected image '%s': %s: %s", name, resp.Status, body)
+	}
+	log.Info("image uploaded")
+
+	img, err := u.vivnfmClient.QueryImage(ctx, &vivnfm.QueryImageRequest{SoftwareImageId: &nfvcommon.Identifier{Value: imageId}})
+	if err != nil {
+		return nil, err
+	}
+	return img.GetSoftwareImageInformation(), nil
+}
+
+// discard deletes an image whose upload failed, so that no empty upload
+// DataVolume is left behind. A failed deletion is only logged; the upload error
+// is what the client gets.
+func (u *imageUploader) discard(ctx context.Context, log *zap.Logger, imageId string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), uploadCleanupTimeout)
+	defer cancel()
+	if err := adminserver.DeleteImage(ctx, u.conn, &nfvcommon.Identifier{Value: imageId}, true); err != nil {
+		log.Warn("failed to delete the image of a failed upload", zap.Error(err))
+		return
+	}
+	log.Info("deleted the image of a failed upload")
+}
+
+// waitUploadReady polls the image download status until its DataVolume is
+// UploadReady, i.e. the CDI upload server accepts the data.
+func (u *imageUploader) waitUploadReady(ctx context.Context, imageId string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.readyTimeout)
+	defer cancel()
+	ticker := time.NewTicker(u.pollInterval)
+	defer ticker.Stop()
+	for {
+		res, err := u.adminClient.GetImageDownloadStatus(ctx, &admin.GetImageDownloadStatusRequest{
+			ImageId: &nfvcommon.Identifier{Value: imageId},
+		})
+		if err != nil && status.Code(err) != codes.DeadlineExceeded {
+			return err
+		}
+		st := res.GetStatus()
+		switch {
+		case st.GetMetadata().GetFields()[cdi.K8sDataVolumePhase] == string(v1beta1.UploadReady):
+			return nil
+		case st.GetState() == admin.DownloadState_FAILED:
+			return status.Errorf(codes.FailedPrecondition, "image '%s' upload target failed: %s", imageId, st.GetErrorMessage())
+		}
+		select {
+		case <-ctx.Done():
+			return status.Errorf(codes.DeadlineExceeded, "image '%s' not ready for upload after %s", imageId, u.readyTimeout)
+		case <-ticker.C:
+		}
+	}
+}
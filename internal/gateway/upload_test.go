@@ -0,0 +1,205 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+// fakeImageServer serves the images the upload handler queries and deletes.
+type fakeImageServer struct {
+	vivnfm.UnimplementedViVnfmServer
+	admin.UnimplementedAdminServer
+
+	images map[string]string
+	inUse  map[string]bool
+}
+
+func (s *fakeImageServer) DeleteImage(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	force := len(md.Get(image.DeleteForceMetadataKey)) > 0
+	if _, ok := s.images[id.GetValue()]; !ok {
+		return nil, status.Error(codes.NotFound, "image not found")
+	}
+	if s.inUse[id.GetValue()] && !force {
+		return nil, status.Error(codes.FailedPrecondition, "image in use")
+	}
+	delete(s.images, id.GetValue())
+	return &emptypb.Empty{}, nil
+}
+
+func (s *fakeImageServer) QueryImage(_ context.Context, req *vivnfm.QueryImageRequest) (*vivnfm.QueryImageResponse, error) {
+	name, ok := s.images[req.GetSoftwareImageId().GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "image not found")
+	}
+	return &vivnfm.QueryImageResponse{SoftwareImageInformation: &vivnfm.SoftwareImageInformation{
+		SoftwareImageId: req.GetSoftwareImageId(),
+		Name:            name,
+	}}, nil
+}
+
+type fakeUploadServer struct {
+	fakeImageServer
+
+	uploadUrl string
+	setupMD   metadata.MD
+	// phases is returned by successive GetImageDownloadStatus calls; the last
+	// one repeats.
+	phases []v1beta1.DataVolumePhase
+	polls  int
+}
+
+func (s *fakeUploadServer) SetupImageUploadProxy(ctx context.Context, _ *admin.SetupImageUploadProxyRequest) (*admin.SetupImageUploadProxyResponse, error) {
+	s.setupMD, _ = metadata.FromIncomingContext(ctx)
+	s.images["img-up"] = s.setupMD.Get(image.UploadImageNameMetadataKey)[0]
+	if err := grpc.SetHeader(ctx, metadata.Pairs(
+		image.UploadImageIdMetadataKey, "img-up",
+		image.UploadUrlMetadataKey, s.uploadUrl,
+		image.UploadTokenMetadataKey, "secret-token",
+	)); err != nil {
+		return nil, err
+	}
+	return &admin.SetupImageUploadProxyResponse{}, nil
+}
+
+func (s *fakeUploadServer) GetImageDownloadStatus(_ context.Context, req *admin.GetImageDownloadStatusRequest) (*admin.GetImageDownloadStatusResponse, error) {
+	phase := s.phases[min(s.polls, len(s.phases)-1)]
+	s.polls++
+	st := &admin.ImageDownloadStatus{
+		ImageId:  req.GetImageId(),
+		State:    admin.DownloadState_PENDING,
+		Metadata: &nfvcommon.Metadata{Fields: map[string]string{cdi.K8sDataVolumePhase: string(phase)}},
+	}
+	if phase == v1beta1.Failed {
+		st.State = admin.DownloadState_FAILED
+	}
+	return &admin.GetImageDownloadStatusResponse{Status: st}, nil
+}
+
+func TestImageUpload(t *testing.T) {
+	var received, auth string
+	proxy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, auth = string(body), r.Header.Get("Authorization")
+	}))
+	t.Cleanup(proxy.Close)
+
+	newUploadMux := func(t *testing.T, srv *fakeUploadServer) http.Handler {
+		t.Helper()
+		u, err := newImageUploader(nil, newTestConn(t, srv), zap.NewNop())
+		require.NoError(t, err)
+		u.httpClient = proxy.Client()
+		u.pollInterval = time.Millisecond
+		u.readyTimeout = time.Second
+		mux := newTestMux()
+		require.NoError(t, u.register(mux))
+		return mux
+	}
+	serve := func(mux http.Handler, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("streams the body once the target is ready", func(t *testing.T) {
+		srv := &fakeUploadServer{
+			fakeImageServer: fakeImageServer{images: map[string]string{}},
+			uploadUrl:       proxy.URL + "/v1beta1/upload",
+			phases:          []v1beta1.DataVolumePhase{v1beta1.UploadScheduled, v1beta1.UploadReady},
+		}
+		rec := serve(newUploadMux(t, srv), ImageUploadPath+"?name=cirros&storageSize=1Gi", "qcow2-bytes")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		assert.Equal(t, "cirros", out["name"])
+		assert.Equal(t, "qcow2-bytes", received)
+		assert.Equal(t, "Bearer secret-token", auth)
+		assert.Equal(t, []string{"1Gi"}, srv.setupMD.Get(image.UploadStorageSizeMetadataKey))
+		assert.Equal(t, 2, srv.polls)
+	})
+
+	t.Run("upload outlasts the server timeouts", func(t *testing.T) {
+		received = ""
+		srv := &fakeUploadServer{
+			fakeImageServer: fakeImageServer{images: map[string]string{}},
+			uploadUrl:       proxy.URL + "/v1beta1/upload",
+			phases:          []v1beta1.DataVolumePhase{v1beta1.UploadReady},
+		}
+		gw := httptest.NewUnstartedServer(LogMiddlewareHandler(newUploadMux(t, srv), zap.NewNop()))
+		gw.Config.ReadTimeout = 100 * time.Millisecond
+		gw.Config.WriteTimeout = 100 * time.Millisecond
+		gw.Start()
+		t.Cleanup(gw.Close)
+
+		body, writer := io.Pipe()
+		go func() {
+			_, _ = writer.Write([]byte("qcow2-"))
+			time.Sleep(300 * time.Millisecond)
+			_, _ = writer.Write([]byte("bytes"))
+			_ = writer.Close()
+		}()
+		resp, err := gw.Client().Post(gw.URL+ImageUploadPath+"?name=cirros", "application/octet-stream", body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(out))
+		assert.Equal(t, "qcow2-bytes", received)
+	})
+
+	t.Run("failed target is not uploaded", func(t *testing.T) {
+		received = ""
+		srv := &fakeUploadServer{
+			fakeImageServer: fakeImageServer{images: map[string]string{}},
+			uploadUrl:       proxy.URL + "/v1beta1/upload",
+			phases:          []v1beta1.DataVolumePhase{v1beta1.Failed},
+		}
+		rec := serve(newUploadMux(t, srv), ImageUploadPath+"?name=cirros", "qcow2-bytes")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, received)
+		assert.NotContains(t, srv.images, "img-up")
+	})
+
+	t.Run("rejected upload deletes the image", func(t *testing.T) {
+		rejecting := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "bad image", http.StatusBadRequest)
+		}))
+		t.Cleanup(rejecting.Close)
+		srv := &fakeUploadServer{
+			fakeImageServer: fakeImageServer{images: map[string]string{}},
+			uploadUrl:       rejecting.URL + "/v1beta1/upload",
+			phases:          []v1beta1.DataVolumePhase{v1beta1.UploadReady},
+		}
+		rec := serve(newUploadMux(t, srv), ImageUploadPath+"?name=cirros", "qcow2-bytes")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "bad image")
+		assert.NotContains(t, srv.images, "img-up")
+	})
+
+	t.Run("name is required", func(t *testing.T) {
+		srv := &fakeUploadServer{fakeImageServer: fakeImageServer{images: map[string]string{}}}
+		rec := serve(newUploadMux(t, srv), ImageUploadPath, "qcow2-bytes")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, srv.setupMD)
+	})
+}
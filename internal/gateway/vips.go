@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ViVnfmVipsPath is the REST root of the virtual IPs.
+const ViVnfmVipsPath = "/vivnfm/v5/vips"
+
+// registerVipHandlers binds the virtual IP REST resources to the interim Vips
+// service. Deleting a virtual IP detaches it from every vNIC.
+func registerVipHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	handlers := []restHandler{
+		{
+			method:  http.MethodPost,
+			pattern: ViVnfmVipsPath,
+			rpc:     vivnfmserver.Vips_CreateVip_FullMethodName,
+			call: func(ctx context.Context, r *http.Request, _ map[string]string, inbound runtime.Marshaler) (proto.Message, error) {
+				req := &structpb.Struct{}
+				if err := inbound.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "decode request body: %v", err)
+				}
+				return vivnfmserver.CreateVip(ctx, conn, req)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmVipsPath,
+			rpc:     vivnfmserver.Vips_QueryVips_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, _ map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryVips(ctx, conn)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			pattern: ViVnfmVipsPath + "/{vipId}",
+			rpc:     vivnfmserver.Vips_QueryVip_FullMethodName,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				return vivnfmserver.QueryVip(ctx, conn, &nfvcommon.Identifier{Value: params["vipId"]})
+			},
+		},
+		{
+			method:    http.MethodDelete,
+			pattern:   ViVnfmVipsPath + "/{vipId}",
+			rpc:       vivnfmserver.Vips_DeleteVip_FullMethodName,
+			noContent: true,
+			call: func(ctx context.Context, _ *http.Request, params map[string]string, _ runtime.Marshaler) (proto.Message, error) {
+				if err := vivnfmserver.DeleteVip(ctx, conn, &nfvcommon.Identifier{Value: params["vipId"]}); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			},
+		},
+	}
+	return registerRestHandlers(mux, handlers)
+}
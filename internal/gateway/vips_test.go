@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeVipManager keeps virtual IPs in memory. Its other network manager
+// methods are not used by the virtual IP handlers.
+type fakeVipManager struct {
+	network.Manager
+
+	vips map[string]*network.Vip
+}
+
+func (m *fakeVipManager) CreateVip(_ context.Context, vip *network.Vip) (*network.Vip, error) {
+	vip.VipId = &nfvcommon.Identifier{Value: "vip-" + vip.Name}
+	m.vips[vip.VipId.Value] = vip
+	return vip, nil
+}
+
+func (m *fakeVipManager) GetVip(_ context.Context, opts ...network.GetVipOpt) (*network.Vip, error) {
+	vip, ok := m.vips[network.ApplyGetVipOpts(opts...).Uid.GetValue()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "vip not found")
+	}
+	return vip, nil
+}
+
+func (m *fakeVipManager) ListVips(context.Context) ([]*network.Vip, error) {
+	res := make([]*network.Vip, 0, len(m.vips))
+	for _, vip := range m.vips {
+		res = append(res, vip)
+	}
+	return res, nil
+}
+
+func (m *fakeVipManager) DeleteVip(_ context.Context, opts ...network.GetVipOpt) error {
+	delete(m.vips, network.ApplyGetVipOpts(opts...).Uid.GetValue())
+	return nil
+}
+
+func TestVipHandlers(t *testing.T) {
+	vipm := &fakeVipManager{vips: map[string]*network.Vip{}}
+	mux := newTestMux()
+	srv := &fakeInterimServer{ViVnfmServer: &vivnfmserver.ViVnfmServer{NetworkMgr: vipm}}
+	require.NoError(t, registerVipHandlers(mux, newTestConn(t, srv)))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		out := map[string]any{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		return out
+	}
+
+	rec := serve(http.MethodPost, ViVnfmVipsPath, `{"name":"vrrp","subnetId":{"value":"sub-1"},"ipAddresses":["10.0.0.100"]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, map[string]any{"value": "vip-vrrp"}, decode(rec)["vipId"])
+	require.Contains(t, vipm.vips, "vip-vrrp")
+	assert.Equal(t, []string{"10.0.0.100"}, vipm.vips["vip-vrrp"].IpAddresses)
+
+	rec = serve(http.MethodGet, ViVnfmVipsPath+"/vip-vrrp", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "vrrp", decode(rec)["name"])
+
+	rec = serve(http.MethodGet, ViVnfmVipsPath, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Len(t, decode(rec)["vips"], 1)
+
+	rec = serve(http.MethodDelete, ViVnfmVipsPath+"/vip-vrrp", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, vipm.vips)
+}
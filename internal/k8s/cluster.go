@@ -4,6 +4,7 @@ import (
 	common "github.com/kube-nfv/kube-vim/internal/config"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
@@ -17,9 +18,12 @@ import (
 // APIReader (GetAPIReader) for read-after-write paths.
 //
 // The cache is scoped to `namespace` and restricted to kube-nfv-owned objects
-// (managed-by label), with two exceptions:
+// (managed-by label), with these exceptions:
 //   - pods: virt-launcher pods carry kubevirt labels, not managed-by, so they are
 //     cached namespace-wide with no label filter (hot path: launcher info + scrapes).
+//   - nodes: cluster-scoped and unlabelled; cached with no label filter so the
+//     alarm manager follows node readiness. Every node of the cluster is cached,
+//     so trimNode strips them to the fields kube-vim reads.
 //   - storageclasses: cluster-scoped and unlabelled; never cached — read via APIReader.
 func NewCluster(cfg *rest.Config, namespace string, scheme *runtime.Scheme) (cluster.Cluster, error) {
 	managedSel := labels.SelectorFromSet(labels.Set{common.K8sManagedByLabel: common.KubeNfvName})
@@ -30,7 +34,8 @@ func NewCluster(cfg *rest.Config, namespace string, scheme *runtime.Scheme) (clu
 			DefaultNamespaces:    map[string]cache.Config{namespace: {}},
 			DefaultLabelSelector: managedSel,
 			ByObject: map[client.Object]cache.ByObject{
-				&corev1.Pod{}: {Label: labels.Everything()},
+				&corev1.Pod{}:  {Label: labels.Everything()},
+				&corev1.Node{}: {Label: labels.Everything(), Transform: trimNode},
 			},
 		}
 		o.Client.Cache = &client.CacheOptions{
@@ -38,3 +43,33 @@ func NewCluster(cfg *rest.Config, namespace string, scheme *runtime.Scheme) (clu
 		}
 	})
 }
+
+// trimNode keeps the node fields kube-vim reads: labels (zone, compute node
+// selector), unschedulable, conditions (readiness), capacity and allocatable.
+// The image list, annotations and managed fields, which make up most of a node
+// object, are dropped so the cache costs a few hundred bytes per node.
+func trimNode(obj any) (any, error) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return obj, nil
+	}
+	return &corev1.Node{
+		TypeMeta: node.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              node.Name,
+			UID:               node.UID,
+			ResourceVersion:   node.ResourceVersion,
+			CreationTimestamp: node.CreationTimestamp,
+			DeletionTimestamp: node.DeletionTimestamp,
+			Labels:            node.Labels,
+		},
+		Spec: corev1.NodeSpec{
+			Unschedulable: node.Spec.Unschedulable,
+		},
+		Status: corev1.NodeStatus{
+			Capacity:    node.Status.Capacity,
+			Allocatable: node.Status.Allocatable,
+			Conditions:  node.Status.Conditions,
+		},
+	}, nil
+}
@@ -10,6 +10,7 @@ import (
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	instancetypev1beta1 "kubevirt.io/api/instancetype/v1beta1"
 	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	cdiuploadv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
 )
 
 // BuildScheme returns a runtime.Scheme with every API group kube-vim reads or
@@ -24,6 +25,7 @@ func BuildScheme() (*runtime.Scheme, error) {
 		{"kubevirt core/v1", kubevirtv1.AddToScheme},
 		{"kubevirt instancetype/v1beta1", instancetypev1beta1.AddToScheme},
 		{"cdi core/v1beta1", cdiv1beta1.AddToScheme},
+		{"cdi upload/v1beta1", cdiuploadv1beta1.AddToScheme}, // uploadtokenrequests (create only)
 		{"kube-ovn v1", kubeovnv1.AddToScheme},
 		{"net-attach v1", netattv1.AddToScheme},
 	}
@@ -0,0 +1,114 @@
+// Package alarm implements virtualised resource fault management (IFA 005/006
+// §7.6 / §8.6): it raises and clears alarms from the state of the KubeVirt,
+// CDI and kube-OVN objects backing ETSI resources and from node health, keeps
+// the active alarm list, and lets consumers query, acknowledge and subscribe to
+// alarm notifications.
+package alarm
+
+import (
+	"time"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+)
+
+// PerceivedSeverity is the IFA 005 §8.6.2 alarm severity.
+type PerceivedSeverity string
+
+const (
+	SeverityCritical      PerceivedSeverity = "CRITICAL"
+	SeverityMajor         PerceivedSeverity = "MAJOR"
+	SeverityMinor         PerceivedSeverity = "MINOR"
+	SeverityWarning       PerceivedSeverity = "WARNING"
+	SeverityIndeterminate PerceivedSeverity = "INDETERMINATE"
+)
+
+// EventType is the ITU-T X.733 event type of an alarm.
+type EventType string
+
+const (
+	EventTypeCommunications  EventType = "COMMUNICATIONS_ALARM"
+	EventTypeProcessingError EventType = "PROCESSING_ERROR_ALARM"
+	EventTypeEnvironmental   EventType = "ENVIRONMENTAL_ALARM"
+	EventTypeQoS             EventType = "QOS_ALARM"
+	EventTypeEquipment       EventType = "EQUIPMENT_ALARM"
+)
+
+// AckState tells whether an alarm has been acknowledged by a consumer.
+type AckState string
+
+const (
+	AckStateUnacknowledged AckState = "UNACKNOWLEDGED"
+	AckStateAcknowledged   AckState = "ACKNOWLEDGED"
+)
+
+// ProbableCause identifies the fault kube-vim detected. Together with the
+// managed object id it identifies an alarm: a fault raised again while its alarm
+// is active updates that alarm instead of raising a new one.
+type ProbableCause string
+
+const (
+	// ProbableCauseComputeFailed: the VMI is in phase Failed.
+	ProbableCauseComputeFailed ProbableCause = "COMPUTE_FAILED"
+	// ProbableCauseComputeUnreachable: the VMI phase is Unknown, its node lost
+	// contact with the control plane.
+	ProbableCauseComputeUnreachable ProbableCause = "COMPUTE_UNREACHABLE"
+	// ProbableCauseComputeNotReady: the VMI is running but not Ready.
+	ProbableCauseComputeNotReady ProbableCause = "COMPUTE_NOT_READY"
+	// ProbableCauseComputeUnschedulable: the virt-launcher pod cannot be scheduled.
+	ProbableCauseComputeUnschedulable ProbableCause = "COMPUTE_UNSCHEDULABLE"
+	// ProbableCauseHostNotReady: the node hosting the compute is NotReady.
+	ProbableCauseHostNotReady ProbableCause = "HOST_NOT_READY"
+	// ProbableCauseImageImportFailed: the image DataVolume import failed or is
+	// being retried.
+	ProbableCauseImageImportFailed ProbableCause = "IMAGE_IMPORT_FAILED"
+	// ProbableCauseSubnetAddressExhaustion: the subnet is running out of (or out
+	// of) free addresses.
+	ProbableCauseSubnetAddressExhaustion ProbableCause = "SUBNET_ADDRESS_EXHAUSTION"
+)
+
+// Alarm is the IFA 005 §8.6.2 alarm information element. Field names follow the
+// spec so SOL 013 filters address them directly, e.g.
+// "filter=(eq,perceivedSeverity,CRITICAL)".
+type Alarm struct {
+	AlarmId string `json:"alarmId"`
+	// ManagedObjectId is the ETSI id of the affected resource, the same value the
+	// Query* RPCs return for it.
+	ManagedObjectId string             `json:"managedObjectId"`
+	ResourceType    event.ResourceType `json:"resourceType"`
+	// ResourceName is the name of the affected resource, for operators.
+	ResourceName          string            `json:"resourceName,omitempty"`
+	AlarmRaisedTime       time.Time         `json:"alarmRaisedTime"`
+	AlarmChangedTime      *time.Time        `json:"alarmChangedTime,omitempty"`
+	AlarmClearedTime      *time.Time        `json:"alarmClearedTime,omitempty"`
+	AlarmAcknowledgedTime *time.Time        `json:"alarmAcknowledgedTime,omitempty"`
+	AckState              AckState          `json:"ackState"`
+	PerceivedSeverity     PerceivedSeverity `json:"perceivedSeverity"`
+	EventTime             time.Time         `json:"eventTime"`
+	EventType             EventType         `json:"eventType"`
+	// FaultType is a short human readable description of the fault.
+	FaultType     string        `json:"faultType"`
+	ProbableCause ProbableCause `json:"probableCause"`
+	IsRootCause   bool          `json:"isRootCause"`
+	// FaultDetails carries the backend evidence, e.g. the VMI condition reason or
+	// the CDI error message.
+	FaultDetails []string `json:"faultDetails,omitempty"`
+}
+
+// NotificationType distinguishes raise/change from clear notifications.
+type NotificationType string
+
+const (
+	// AlarmNotificationType is sent when an alarm is raised or its severity or
+	// details change.
+	AlarmNotificationType NotificationType = "AlarmNotification"
+	// AlarmClearedNotificationType is sent when the fault is gone. The alarm
+	// carries AlarmClearedTime and keeps its last severity, so a severity filter
+	// also receives the clear of the alarms it received.
+	AlarmClearedNotificationType NotificationType = "AlarmClearedNotification"
+)
+
+// Notification is a single alarm notification.
+type Notification struct {
+	NotificationType NotificationType `json:"notificationType"`
+	Alarm            Alarm            `json:"alarm"`
+}
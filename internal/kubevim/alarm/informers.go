@@ -0,0 +1,309 @@
+package alarm
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	toolscache "k8s.io/client-go/tools/cache"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// subnetUsageWarningRatio is the address usage from which a subnet raises a
+	// WARNING exhaustion alarm. With no free address left it becomes MAJOR.
+	subnetUsageWarningRatio = 0.9
+)
+
+// RegisterInformers attaches the alarm manager to the shared cache informers.
+// It must be called before the cache is started. Unlike notifications, the
+// initial list is evaluated too: faults that exist when kube-vim starts are
+// raised right away.
+func (m *Manager) RegisterInformers(ctx context.Context, informers cache.Informers) error {
+	watches := []struct {
+		obj    client.Object
+		handle func(ctx context.Context, obj client.Object, deleted bool)
+	}{
+		{&kubevirtv1.VirtualMachineInstance{}, m.handleVirtualMachineInstance},
+		{&cdiv1beta1.DataVolume{}, m.handleDataVolume},
+		{&kubeovnv1.Subnet{}, m.handleSubnet},
+		{&corev1.Node{}, m.handleNode},
+	}
+	for _, w := range watches {
+		inf, err := informers.GetInformer(ctx, w.obj)
+		if err != nil {
+			return fmt.Errorf("get informer for %T: %w", w.obj, err)
+		}
+		if _, err := inf.AddEventHandler(eventHandler(ctx, w.handle)); err != nil {
+			return fmt.Errorf("add event handler for %T: %w", w.obj, err)
+		}
+	}
+	return nil
+}
+
+func eventHandler(ctx context.Context, handle func(ctx context.Context, obj client.Object, deleted bool)) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if o, ok := obj.(client.Object); ok {
+				handle(ctx, o, false)
+			}
+		},
+		UpdateFunc: func(_, newObj any) {
+			if o, ok := newObj.(client.Object); ok {
+				handle(ctx, o, false)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if o, ok := obj.(client.Object); ok {
+				handle(ctx, o, true)
+			}
+		},
+	}
+}
+
+func sourceKey(kind string, obj client.Object) string {
+	return kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func (m *Manager) handleVirtualMachineInstance(ctx context.Context, obj client.Object, deleted bool) {
+	vmi := obj.(*kubevirtv1.VirtualMachineInstance)
+	var faults []fault
+	if !deleted && misc.IsObjectManagedByKubeNfv(vmi) {
+		faults = computeFaults(vmi, m.getNode(ctx, vmi.Status.NodeName))
+	}
+	m.reconcile(sourceKey("vmi", vmi), faults)
+}
+
+// handleNode re-evaluates the computes hosted on the node, whose HOST_NOT_READY
+// alarm follows the node Ready condition.
+func (m *Manager) handleNode(ctx context.Context, obj client.Object, _ bool) {
+	vmis := &kubevirtv1.VirtualMachineInstanceList{}
+	if err := m.reader.List(ctx, vmis, client.InNamespace(m.namespace)); err != nil {
+		m.logger.Warn("list virtual machine instances for node alarm", zap.String("node", obj.GetName()), zap.Error(err))
+		return
+	}
+	for i := range vmis.Items {
+		if vmis.Items[i].Status.NodeName == obj.GetName() {
+			m.handleVirtualMachineInstance(ctx, &vmis.Items[i], false)
+		}
+	}
+}
+
+// getNode returns the node from the cache, or nil when the VMI is not scheduled
+// or the node is gone (the VMI phase reports that case).
+func (m *Manager) getNode(ctx context.Context, name string) *corev1.Node {
+	if name == "" {
+		return nil
+	}
+	node := &corev1.Node{}
+	if err := m.reader.Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			m.logger.Warn("get node for compute alarm", zap.String("node", name), zap.Error(err))
+		}
+		return nil
+	}
+	return node
+}
+
+// computeFaults derives the compute faults from the VMI phase and conditions and
+// from its node. The faults are attributed to the owning VirtualMachine, whose
+// UID is the ETSI compute id.
+func computeFaults(vmi *kubevirtv1.VirtualMachineInstance, node *corev1.Node) []fault {
+	if vmi.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	computeId, computeName := "", ""
+	for _, ref := range vmi.GetOwnerReferences() {
+		if ref.Kind == "VirtualMachine" {
+			computeId, computeName = string(ref.UID), ref.Name
+			break
+		}
+	}
+	if computeId == "" {
+		return nil
+	}
+	newFault := func(cause ProbableCause, severity PerceivedSeverity, et EventType, faultType string, details ...string) fault {
+		return fault{
+			managedObjectId: computeId,
+			resourceType:    event.ResourceTypeCompute,
+			resourceName:    computeName,
+			probableCause:   cause,
+			severity:        severity,
+			eventType:       et,
+			faultType:       faultType,
+			details:         details,
+		}
+	}
+
+	var faults []fault
+	switch vmi.Status.Phase {
+	case kubevirtv1.Failed:
+		faults = append(faults, newFault(ProbableCauseComputeFailed, SeverityCritical, EventTypeProcessingError,
+			"Virtual machine instance failed", conditionDetails(vmi, kubevirtv1.VirtualMachineInstanceReady)...))
+	case kubevirtv1.Unknown:
+		faults = append(faults, newFault(ProbableCauseComputeUnreachable, SeverityMajor, EventTypeCommunications,
+			"Virtual machine instance state unknown", "node: "+vmi.Status.NodeName))
+	case kubevirtv1.Running:
+		if cond := vmiCondition(vmi, kubevirtv1.VirtualMachineInstanceReady); cond != nil && cond.Status == corev1.ConditionFalse && !isPaused(vmi) {
+			faults = append(faults, newFault(ProbableCauseComputeNotReady, SeverityMajor, EventTypeProcessingError,
+				"Virtual machine instance not ready", conditionDetails(vmi, kubevirtv1.VirtualMachineInstanceReady)...))
+		}
+	case kubevirtv1.Pending, kubevirtv1.Scheduling:
+		podScheduled := kubevirtv1.VirtualMachineInstanceConditionType(corev1.PodScheduled)
+		if cond := vmiCondition(vmi, podScheduled); cond != nil && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			faults = append(faults, newFault(ProbableCauseComputeUnschedulable, SeverityMajor, EventTypeProcessingError,
+				"Virtual machine instance cannot be scheduled", conditionDetails(vmi, podScheduled)...))
+		}
+	}
+
+	if node != nil && vmi.Status.Phase != kubevirtv1.Failed && vmi.Status.Phase != kubevirtv1.Succeeded {
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady && cond.Status != corev1.ConditionTrue {
+				faults = append(faults, newFault(ProbableCauseHostNotReady, SeverityCritical, EventTypeEquipment,
+					"Host not ready", "node: "+node.Name, "reason: "+cond.Reason, "message: "+cond.Message))
+			}
+		}
+	}
+	return faults
+}
+
+func vmiCondition(vmi *kubevirtv1.VirtualMachineInstance, t kubevirtv1.VirtualMachineInstanceConditionType) *kubevirtv1.VirtualMachineInstanceCondition {
+	for i := range vmi.Status.Conditions {
+		if vmi.Status.Conditions[i].Type == t {
+			return &vmi.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func isPaused(vmi *kubevirtv1.VirtualMachineInstance) bool {
+	cond := vmiCondition(vmi, kubevirtv1.VirtualMachineInstancePaused)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+func conditionDetails(vmi *kubevirtv1.VirtualMachineInstance, t kubevirtv1.VirtualMachineInstanceConditionType) []string {
+	cond := vmiCondition(vmi, t)
+	if cond == nil {
+		return nil
+	}
+	return []string{"reason: " + cond.Reason, "message: " + cond.Message}
+}
+
+// handleDataVolume raises an image alarm for a failed or retrying import. Only
+// image DataVolumes (populated from the image VolumeImportSource) are
+// considered; compute boot volumes are cloned from the image PVC.
+func (m *Manager) handleDataVolume(_ context.Context, obj client.Object, deleted bool) {
+	dv := obj.(*cdiv1beta1.DataVolume)
+	var faults []fault
+	if !deleted && misc.IsObjectManagedByKubeNfv(dv) {
+		faults = imageImportFaults(dv)
+	}
+	m.reconcile(sourceKey("dv", dv), faults)
+}
+
+func imageImportFaults(dv *cdiv1beta1.DataVolume) []fault {
+	imageId := dv.GetLabels()[image.K8sImageIdLabel]
+	if imageId == "" || dv.Spec.Storage == nil || dv.Spec.Storage.DataSourceRef == nil || dv.Spec.Storage.DataSourceRef.Kind != cdi.CDIVolumeImportSourceKind {
+		return nil
+	}
+	if dv.GetDeletionTimestamp() != nil || dv.Status.Phase == cdiv1beta1.Succeeded {
+		return nil
+	}
+	var details []string
+	for _, cond := range dv.Status.Conditions {
+		if cond.Type == cdiv1beta1.DataVolumeRunning && cond.Message != "" {
+			details = append(details, "reason: "+cond.Reason, "message: "+cond.Message)
+		}
+	}
+	f := fault{
+		managedObjectId: imageId,
+		resourceType:    event.ResourceTypeImage,
+		resourceName:    dv.GetName(),
+		probableCause:   ProbableCauseImageImportFailed,
+		eventType:       EventTypeProcessingError,
+		details:         details,
+	}
+	switch {
+	case dv.Status.Phase == cdiv1beta1.Failed:
+		f.severity = SeverityCritical
+		f.faultType = "Image import failed"
+	case dv.Status.RestartCount > 0:
+		// CDI restarts a failing importer pod indefinitely; report it while the
+		// import keeps failing so the operator can fix the source.
+		f.severity = SeverityMajor
+		f.faultType = fmt.Sprintf("Image import failing, retried %d times", dv.Status.RestartCount)
+	default:
+		return nil
+	}
+	return []fault{f}
+}
+
+func (m *Manager) handleSubnet(_ context.Context, obj client.Object, deleted bool) {
+	subnet := obj.(*kubeovnv1.Subnet)
+	var faults []fault
+	if !deleted && misc.IsObjectManagedByKubeNfv(subnet) {
+		faults = subnetFaults(subnet)
+	}
+	m.reconcile(sourceKey("subnet", subnet), faults)
+}
+
+// subnetFaults raises SUBNET_ADDRESS_EXHAUSTION from the kube-OVN subnet address
+// statistics: WARNING from subnetUsageWarningRatio, MAJOR when no address is
+// left. A dual-stack subnet is as exhausted as its fuller family.
+func subnetFaults(subnet *kubeovnv1.Subnet) []fault {
+	if subnet.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	st := subnet.Status
+	var families [][2]float64
+	switch subnet.Spec.Protocol {
+	case kubeovnv1.ProtocolIPv6:
+		families = [][2]float64{{st.V6AvailableIPs, st.V6UsingIPs}}
+	case kubeovnv1.ProtocolDual:
+		families = [][2]float64{{st.V4AvailableIPs, st.V4UsingIPs}, {st.V6AvailableIPs, st.V6UsingIPs}}
+	default:
+		families = [][2]float64{{st.V4AvailableIPs, st.V4UsingIPs}}
+	}
+	available, usage := math.Inf(1), 0.0
+	for _, f := range families {
+		avail, using := f[0], f[1]
+		if avail+using == 0 {
+			// Status not populated yet.
+			continue
+		}
+		available = min(available, avail)
+		usage = max(usage, using/(avail+using))
+	}
+	if usage < subnetUsageWarningRatio && available > 0 {
+		return nil
+	}
+	f := fault{
+		managedObjectId: misc.UIDToIdentifier(subnet.GetUID()).GetValue(),
+		resourceType:    event.ResourceTypeSubnet,
+		resourceName:    subnet.GetName(),
+		probableCause:   ProbableCauseSubnetAddressExhaustion,
+		eventType:       EventTypeQoS,
+		severity:        SeverityWarning,
+		faultType:       "Subnet address pool nearly exhausted",
+		details:         []string{fmt.Sprintf("usage: %.0f%%", usage*100), fmt.Sprintf("available: %.0f", available)},
+	}
+	if available <= 0 {
+		f.severity = SeverityMajor
+		f.faultType = "Subnet address pool exhausted"
+	}
+	return []fault{f}
+}
@@ -0,0 +1,227 @@
+package alarm
+
+import (
+	"testing"
+	"time"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	"github.com/kube-nfv/kube-vim/internal/k8s"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+)
+
+func vmi(phase kubevirtv1.VirtualMachineInstancePhase, conds ...kubevirtv1.VirtualMachineInstanceCondition) *kubevirtv1.VirtualMachineInstance {
+	meta := k8stest.ManagedMeta("vm1")
+	meta.Namespace = k8stest.TestNamespace
+	meta.OwnerReferences = []metav1.OwnerReference{{Kind: "VirtualMachine", Name: "vm1", UID: "vm-uid"}}
+	return &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: meta,
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase:      phase,
+			NodeName:   "node1",
+			Conditions: conds,
+		},
+	}
+}
+
+func node(ready corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: ready, Reason: "KubeletNotReady"},
+		}},
+	}
+}
+
+func causes(faults []fault) []ProbableCause {
+	res := make([]ProbableCause, 0, len(faults))
+	for _, f := range faults {
+		res = append(res, f.probableCause)
+	}
+	return res
+}
+
+func TestComputeFaults(t *testing.T) {
+	notReady := kubevirtv1.VirtualMachineInstanceCondition{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionFalse, Reason: "GuestNotRunning"}
+	paused := kubevirtv1.VirtualMachineInstanceCondition{Type: kubevirtv1.VirtualMachineInstancePaused, Status: corev1.ConditionTrue}
+	unschedulable := kubevirtv1.VirtualMachineInstanceCondition{
+		Type:   kubevirtv1.VirtualMachineInstanceConditionType(corev1.PodScheduled),
+		Status: corev1.ConditionFalse,
+		Reason: corev1.PodReasonUnschedulable,
+	}
+	orphan := vmi(kubevirtv1.Failed)
+	orphan.OwnerReferences = nil
+
+	tests := []struct {
+		name string
+		vmi  *kubevirtv1.VirtualMachineInstance
+		node *corev1.Node
+		want []ProbableCause
+	}{
+		{"healthy", vmi(kubevirtv1.Running), node(corev1.ConditionTrue), nil},
+		{"failed", vmi(kubevirtv1.Failed), nil, []ProbableCause{ProbableCauseComputeFailed}},
+		{"unknown", vmi(kubevirtv1.Unknown), nil, []ProbableCause{ProbableCauseComputeUnreachable}},
+		{"not ready", vmi(kubevirtv1.Running, notReady), nil, []ProbableCause{ProbableCauseComputeNotReady}},
+		{"paused is not a fault", vmi(kubevirtv1.Running, notReady, paused), nil, nil},
+		{"unschedulable", vmi(kubevirtv1.Scheduling, unschedulable), nil, []ProbableCause{ProbableCauseComputeUnschedulable}},
+		{"host not ready", vmi(kubevirtv1.Running), node(corev1.ConditionUnknown), []ProbableCause{ProbableCauseHostNotReady}},
+		{"failed on lost host", vmi(kubevirtv1.Failed), node(corev1.ConditionFalse), []ProbableCause{ProbableCauseComputeFailed}},
+		{"no owning vm", orphan, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faults := computeFaults(tt.vmi, tt.node)
+			assert.ElementsMatch(t, tt.want, causes(faults))
+			for _, f := range faults {
+				assert.Equal(t, "vm-uid", f.managedObjectId)
+			}
+		})
+	}
+}
+
+func imageDv(phase cdiv1beta1.DataVolumePhase, restarts int32) *cdiv1beta1.DataVolume {
+	meta := k8stest.ManagedMeta("img")
+	meta.Labels[image.K8sImageIdLabel] = "img-uid"
+	return &cdiv1beta1.DataVolume{
+		ObjectMeta: meta,
+		Spec: cdiv1beta1.DataVolumeSpec{Storage: &cdiv1beta1.StorageSpec{
+			DataSourceRef: &corev1.TypedObjectReference{Kind: cdi.CDIVolumeImportSourceKind, Name: "img"},
+		}},
+		Status: cdiv1beta1.DataVolumeStatus{
+			Phase:        phase,
+			RestartCount: restarts,
+			Conditions: []cdiv1beta1.DataVolumeCondition{
+				{Type: cdiv1beta1.DataVolumeRunning, Status: corev1.ConditionFalse, Reason: "Error", Message: "Unable to connect to http data source"},
+			},
+		},
+	}
+}
+
+func TestImageImportFaults(t *testing.T) {
+	bootDv := imageDv(cdiv1beta1.Failed, 0)
+	bootDv.Spec.Storage.DataSourceRef = nil
+
+	tests := []struct {
+		name     string
+		dv       *cdiv1beta1.DataVolume
+		severity PerceivedSeverity
+	}{
+		{"failed", imageDv(cdiv1beta1.Failed, 0), SeverityCritical},
+		{"retrying", imageDv(cdiv1beta1.ImportInProgress, 3), SeverityMajor},
+		{"in progress", imageDv(cdiv1beta1.ImportInProgress, 0), ""},
+		{"succeeded after retries", imageDv(cdiv1beta1.Succeeded, 3), ""},
+		{"compute boot volume", bootDv, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faults := imageImportFaults(tt.dv)
+			if tt.severity == "" {
+				assert.Empty(t, faults)
+				return
+			}
+			require.Len(t, faults, 1)
+			assert.Equal(t, "img-uid", faults[0].managedObjectId)
+			assert.Equal(t, tt.severity, faults[0].severity)
+			assert.Contains(t, faults[0].details, "message: Unable to connect to http data source")
+		})
+	}
+}
+
+func subnet(protocol string, v4avail, v4using, v6avail, v6using float64) *kubeovnv1.Subnet {
+	return &kubeovnv1.Subnet{
+		ObjectMeta: k8stest.ManagedMeta("sub"),
+		Spec:       kubeovnv1.SubnetSpec{Protocol: protocol},
+		Status: kubeovnv1.SubnetStatus{
+			V4AvailableIPs: v4avail, V4UsingIPs: v4using,
+			V6AvailableIPs: v6avail, V6UsingIPs: v6using,
+		},
+	}
+}
+
+func TestSubnetFaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		subnet   *kubeovnv1.Subnet
+		severity PerceivedSeverity
+	}{
+		{"plenty left", subnet(kubeovnv1.ProtocolIPv4, 200, 50, 0, 0), ""},
+		{"status not populated", subnet(kubeovnv1.ProtocolIPv4, 0, 0, 0, 0), ""},
+		{"nearly exhausted", subnet(kubeovnv1.ProtocolIPv4, 5, 245, 0, 0), SeverityWarning},
+		{"exhausted", subnet(kubeovnv1.ProtocolIPv4, 0, 254, 0, 0), SeverityMajor},
+		{"ipv6 exhausted", subnet(kubeovnv1.ProtocolIPv6, 200, 0, 0, 100), SeverityMajor},
+		{"dual fuller family", subnet(kubeovnv1.ProtocolDual, 200, 50, 1, 99), SeverityWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faults := subnetFaults(tt.subnet)
+			if tt.severity == "" {
+				assert.Empty(t, faults)
+				return
+			}
+			require.Len(t, faults, 1)
+			assert.Equal(t, "uid-sub", faults[0].managedObjectId)
+			assert.Equal(t, tt.severity, faults[0].severity)
+		})
+	}
+}
+
+func TestRegisterInformers(t *testing.T) {
+	scheme, err := k8s.BuildScheme()
+	require.NoError(t, err)
+	informers := &informertest.FakeInformers{Scheme: scheme}
+	running := vmi(kubevirtv1.Running)
+	c := k8stest.NewClient(t, running, node(corev1.ConditionTrue))
+	m := NewManager(zap.NewNop(), c, k8stest.TestNamespace)
+	require.NoError(t, m.RegisterInformers(t.Context(), informers))
+	sub, err := m.Subscribe(t.Context(), "")
+	require.NoError(t, err)
+
+	vmiInf, err := informers.FakeInformerFor(t.Context(), &kubevirtv1.VirtualMachineInstance{})
+	require.NoError(t, err)
+	nodeInf, err := informers.FakeInformerFor(t.Context(), &corev1.Node{})
+	require.NoError(t, err)
+
+	vmiInf.Add(running)
+	assert.Empty(t, sub.Events(), "healthy compute raises nothing")
+
+	// The node goes NotReady: its computes get a HOST_NOT_READY alarm.
+	lost := node(corev1.ConditionUnknown)
+	require.NoError(t, c.Status().Update(t.Context(), lost))
+	nodeInf.Update(node(corev1.ConditionTrue), lost)
+	raised := receiveOne(t, sub)
+	assert.Equal(t, AlarmNotificationType, raised.NotificationType)
+	assert.Equal(t, ProbableCauseHostNotReady, raised.Alarm.ProbableCause)
+	assert.Equal(t, "vm-uid", raised.Alarm.ManagedObjectId)
+
+	// The VMI goes away: every alarm it raised is cleared.
+	vmiInf.Delete(running)
+	cleared := receiveOne(t, sub)
+	assert.Equal(t, AlarmClearedNotificationType, cleared.NotificationType)
+	assert.Equal(t, raised.Alarm.AlarmId, cleared.Alarm.AlarmId)
+
+	subnetInf, err := informers.FakeInformerFor(t.Context(), &kubeovnv1.Subnet{})
+	require.NoError(t, err)
+	subnetInf.Add(subnet(kubeovnv1.ProtocolIPv4, 0, 254, 0, 0))
+	exhausted := receiveOne(t, sub)
+	assert.Equal(t, ProbableCauseSubnetAddressExhaustion, exhausted.Alarm.ProbableCause)
+}
+
+func receiveOne(t *testing.T, sub *Subscription) Notification {
+	t.Helper()
+	select {
+	case n := <-sub.Events():
+		return n
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for alarm notification")
+	}
+	return Notification{}
+}
@@ -0,0 +1,278 @@
+package alarm
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	filter "github.com/kube-nfv/query-filter"
+	"go.uber.org/zap"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// subscriberBufferSize is the per-subscriber channel depth. A subscriber that
+// falls further behind is dropped with event.ErrSlowSubscriber and
+// re-reads the active alarms with QueryAlarms after resubscribing.
+const subscriberBufferSize = 256
+
+// Manager keeps the active alarms. Alarms are derived state: they are rebuilt
+// from the cache on start (the informers replay the initial list), so nothing is
+// persisted and acknowledgements do not survive a kube-vim restart.
+type Manager struct {
+	logger *zap.Logger
+	// reader is the cache-backed client used to look up the objects a fault
+	// depends on (the node of a VMI, the VMIs on a node).
+	reader    client.Reader
+	namespace string
+
+	mu     sync.Mutex
+	alarms map[alarmKey]*Alarm
+	// sources records which alarms each backend object raised, so a fault that is
+	// no longer reported by its source is cleared.
+	sources     map[string]map[alarmKey]struct{}
+	subscribers map[*Subscription]struct{}
+}
+
+type alarmKey struct {
+	managedObjectId string
+	probableCause   ProbableCause
+}
+
+// fault is a fault currently reported by a backend object. The manager raises an
+// alarm for it, or updates the active one.
+type fault struct {
+	managedObjectId string
+	resourceType    event.ResourceType
+	resourceName    string
+	probableCause   ProbableCause
+	severity        PerceivedSeverity
+	eventType       EventType
+	faultType       string
+	details         []string
+}
+
+// Subscription is a live alarm notification stream. Events is closed when the
+// subscription ends; Err then reports why (nil for a regular Close or context
+// cancellation).
+type Subscription struct {
+	manager *Manager
+	filter  string
+	events  chan Notification
+	// done is closed together with events so the context watcher exits when
+	// the subscription ends on its own (Close, slow subscriber).
+	done chan struct{}
+	err  error
+}
+
+func NewManager(logger *zap.Logger, reader client.Reader, namespace string) *Manager {
+	return &Manager{
+		logger:      logger,
+		reader:      reader,
+		namespace:   namespace,
+		alarms:      make(map[alarmKey]*Alarm),
+		sources:     make(map[string]map[alarmKey]struct{}),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// QueryAlarms returns the active alarms matching the SOL 013 filter
+// ("filter=..."), oldest first. An empty filter returns all of them.
+func (m *Manager) QueryAlarms(filterStr string) ([]Alarm, error) {
+	m.mu.Lock()
+	res := make([]Alarm, 0, len(m.alarms))
+	for _, a := range m.alarms {
+		res = append(res, *a)
+	}
+	m.mu.Unlock()
+	slices.SortFunc(res, func(a, b Alarm) int {
+		if c := a.AlarmRaisedTime.Compare(b.AlarmRaisedTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a.AlarmId, b.AlarmId)
+	})
+	if filterStr == "" {
+		return res, nil
+	}
+	filtered, err := filter.FilterList(res, filterStr)
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	return filtered, nil
+}
+
+// Acknowledge marks an active alarm as acknowledged. Acknowledging an already
+// acknowledged alarm returns it unchanged.
+func (m *Manager) Acknowledge(alarmId string) (*Alarm, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.alarms {
+		if a.AlarmId != alarmId {
+			continue
+		}
+		if a.AckState != AckStateAcknowledged {
+			now := time.Now()
+			a.AckState = AckStateAcknowledged
+			a.AlarmAcknowledgedTime = &now
+		}
+		res := *a
+		return &res, nil
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "alarm", Identifier: alarmId}
+}
+
+// Subscribe registers an alarm notification stream. The filter is evaluated
+// against the notified Alarm. The subscription is closed when ctx is done.
+func (m *Manager) Subscribe(ctx context.Context, filterStr string) (*Subscription, error) {
+	if _, err := filter.FilterList([]Alarm{{}}, filterStr); err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	sub := &Subscription{
+		manager: m,
+		filter:  filterStr,
+		events:  make(chan Notification, subscriberBufferSize),
+		done:    make(chan struct{}),
+	}
+	m.mu.Lock()
+	m.subscribers[sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// Events returns the notification stream. It is closed when the subscription ends.
+func (s *Subscription) Events() <-chan Notification { return s.events }
+
+// Err reports why the subscription ended. Only valid once Events is closed.
+func (s *Subscription) Err() error {
+	s.manager.mu.Lock()
+	defer s.manager.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.manager.mu.Lock()
+	defer s.manager.mu.Unlock()
+	s.manager.closeLocked(s, nil)
+}
+
+func (m *Manager) closeLocked(sub *Subscription, err error) {
+	if _, ok := m.subscribers[sub]; !ok {
+		return
+	}
+	delete(m.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+	close(sub.done)
+}
+
+// reconcile sets the faults currently reported by source. New faults raise an
+// alarm, changed ones update it and faults source no longer reports are cleared.
+func (m *Manager) reconcile(source string, faults []fault) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+
+	current := make(map[alarmKey]struct{}, len(faults))
+	for _, f := range faults {
+		key := alarmKey{managedObjectId: f.managedObjectId, probableCause: f.probableCause}
+		current[key] = struct{}{}
+		a, ok := m.alarms[key]
+		if !ok {
+			a = &Alarm{
+				AlarmId:           uuid.NewString(),
+				ManagedObjectId:   f.managedObjectId,
+				ResourceType:      f.resourceType,
+				ResourceName:      f.resourceName,
+				AlarmRaisedTime:   now,
+				AckState:          AckStateUnacknowledged,
+				PerceivedSeverity: f.severity,
+				EventTime:         now,
+				EventType:         f.eventType,
+				FaultType:         f.faultType,
+				ProbableCause:     f.probableCause,
+				IsRootCause:       true,
+				FaultDetails:      f.details,
+			}
+			m.alarms[key] = a
+			m.logger.Info("alarm raised",
+				zap.String("alarmId", a.AlarmId),
+				zap.String("managedObjectId", a.ManagedObjectId),
+				zap.String("probableCause", string(a.ProbableCause)),
+				zap.String("severity", string(a.PerceivedSeverity)))
+			m.notifyLocked(AlarmNotificationType, a)
+			continue
+		}
+		if a.PerceivedSeverity == f.severity && a.FaultType == f.faultType && slices.Equal(a.FaultDetails, f.details) {
+			continue
+		}
+		a.PerceivedSeverity = f.severity
+		a.FaultType = f.faultType
+		a.FaultDetails = f.details
+		a.EventTime = now
+		a.AlarmChangedTime = &now
+		m.notifyLocked(AlarmNotificationType, a)
+	}
+
+	for key := range m.sources[source] {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		a, ok := m.alarms[key]
+		if !ok {
+			continue
+		}
+		delete(m.alarms, key)
+		a.AlarmClearedTime = &now
+		a.EventTime = now
+		m.logger.Info("alarm cleared",
+			zap.String("alarmId", a.AlarmId),
+			zap.String("managedObjectId", a.ManagedObjectId),
+			zap.String("probableCause", string(a.ProbableCause)))
+		m.notifyLocked(AlarmClearedNotificationType, a)
+	}
+	if len(current) == 0 {
+		delete(m.sources, source)
+	} else {
+		m.sources[source] = current
+	}
+}
+
+// notifyLocked fans a snapshot of the alarm out to matching subscribers without
+// blocking. m.mu must be held.
+func (m *Manager) notifyLocked(nt NotificationType, a *Alarm) {
+	n := Notification{NotificationType: nt, Alarm: *a}
+	for sub := range m.subscribers {
+		if !matches(sub.filter, n.Alarm) {
+			continue
+		}
+		select {
+		case sub.events <- n:
+		default:
+			m.logger.Warn("drop slow alarm subscriber", zap.String("alarmId", a.AlarmId))
+			m.closeLocked(sub, event.ErrSlowSubscriber)
+		}
+	}
+}
+
+// matches evaluates a subscription filter against an alarm. The filter was
+// validated on subscribe, so an evaluation error is treated as no match.
+func matches(filterStr string, a Alarm) bool {
+	if filterStr == "" {
+		return true
+	}
+	res, err := filter.FilterList([]Alarm{a}, filterStr)
+	return err == nil && len(res) == 1
+}
@@ -0,0 +1,198 @@
+package alarm
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	return NewManager(zap.NewNop(), k8stest.NewClient(t), k8stest.TestNamespace)
+}
+
+func computeFault(id string, cause ProbableCause, severity PerceivedSeverity) fault {
+	return fault{
+		managedObjectId: id,
+		resourceType:    event.ResourceTypeCompute,
+		resourceName:    "vm-" + id,
+		probableCause:   cause,
+		severity:        severity,
+		eventType:       EventTypeProcessingError,
+		faultType:       string(cause),
+	}
+}
+
+// receive drains n notifications or fails the test after a short timeout.
+func receive(t *testing.T, sub *Subscription, n int) []Notification {
+	t.Helper()
+	res := make([]Notification, 0, n)
+	for len(res) < n {
+		select {
+		case ev, ok := <-sub.Events():
+			require.True(t, ok, "subscription closed after %d of %d notifications", len(res), n)
+			res = append(res, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for notifications", "got %d of %d", len(res), n)
+		}
+	}
+	return res
+}
+
+func TestManagerReconcileLifecycle(t *testing.T) {
+	m := newTestManager(t)
+	sub, err := m.Subscribe(t.Context(), "")
+	require.NoError(t, err)
+
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeNotReady, SeverityMajor)})
+	raised := receive(t, sub, 1)[0]
+	assert.Equal(t, AlarmNotificationType, raised.NotificationType)
+	assert.Equal(t, "a", raised.Alarm.ManagedObjectId)
+	assert.Equal(t, AckStateUnacknowledged, raised.Alarm.AckState)
+	assert.Nil(t, raised.Alarm.AlarmChangedTime)
+
+	// Reported again unchanged: no new alarm and no notification.
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeNotReady, SeverityMajor)})
+	assert.Empty(t, sub.Events())
+
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeNotReady, SeverityCritical)})
+	changed := receive(t, sub, 1)[0]
+	assert.Equal(t, raised.Alarm.AlarmId, changed.Alarm.AlarmId, "a changed fault updates its alarm")
+	assert.Equal(t, SeverityCritical, changed.Alarm.PerceivedSeverity)
+	assert.NotNil(t, changed.Alarm.AlarmChangedTime)
+
+	m.reconcile("vmi/ns/a", nil)
+	cleared := receive(t, sub, 1)[0]
+	assert.Equal(t, AlarmClearedNotificationType, cleared.NotificationType)
+	assert.Equal(t, raised.Alarm.AlarmId, cleared.Alarm.AlarmId)
+	assert.NotNil(t, cleared.Alarm.AlarmClearedTime)
+	assert.Equal(t, SeverityCritical, cleared.Alarm.PerceivedSeverity, "a clear keeps the last severity")
+
+	alarms, err := m.QueryAlarms("")
+	require.NoError(t, err)
+	assert.Empty(t, alarms)
+	assert.Empty(t, m.sources)
+}
+
+func TestManagerReconcileClearsOnlyMissingFaults(t *testing.T) {
+	m := newTestManager(t)
+	m.reconcile("vmi/ns/a", []fault{
+		computeFault("a", ProbableCauseComputeNotReady, SeverityMajor),
+		computeFault("a", ProbableCauseHostNotReady, SeverityCritical),
+	})
+	m.reconcile("vmi/ns/b", []fault{computeFault("b", ProbableCauseComputeFailed, SeverityCritical)})
+
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeNotReady, SeverityMajor)})
+	alarms, err := m.QueryAlarms("")
+	require.NoError(t, err)
+	causes := make([]ProbableCause, 0, len(alarms))
+	for _, a := range alarms {
+		causes = append(causes, a.ProbableCause)
+	}
+	assert.ElementsMatch(t, []ProbableCause{ProbableCauseComputeNotReady, ProbableCauseComputeFailed}, causes)
+}
+
+func TestManagerQueryAlarms(t *testing.T) {
+	m := newTestManager(t)
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeFailed, SeverityCritical)})
+	m.reconcile("vmi/ns/b", []fault{computeFault("b", ProbableCauseComputeNotReady, SeverityMajor)})
+
+	alarms, err := m.QueryAlarms("filter=(eq,perceivedSeverity,CRITICAL)")
+	require.NoError(t, err)
+	require.Len(t, alarms, 1)
+	assert.Equal(t, "a", alarms[0].ManagedObjectId)
+
+	_, err = m.QueryAlarms("filter=(eq,noSuchField,x)")
+	var invalid *apperrors.ErrInvalidArgument
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestManagerAcknowledge(t *testing.T) {
+	m := newTestManager(t)
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeFailed, SeverityCritical)})
+	alarms, err := m.QueryAlarms("")
+	require.NoError(t, err)
+	require.Len(t, alarms, 1)
+
+	acked, err := m.Acknowledge(alarms[0].AlarmId)
+	require.NoError(t, err)
+	assert.Equal(t, AckStateAcknowledged, acked.AckState)
+	require.NotNil(t, acked.AlarmAcknowledgedTime)
+
+	again, err := m.Acknowledge(alarms[0].AlarmId)
+	require.NoError(t, err)
+	assert.Equal(t, acked.AlarmAcknowledgedTime, again.AlarmAcknowledgedTime, "acknowledge is idempotent")
+
+	alarms, err = m.QueryAlarms("filter=(eq,ackState,ACKNOWLEDGED)")
+	require.NoError(t, err)
+	assert.Len(t, alarms, 1)
+
+	_, err = m.Acknowledge("missing")
+	var notFound *apperrors.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestManagerSubscribeFilter(t *testing.T) {
+	m := newTestManager(t)
+	_, err := m.Subscribe(t.Context(), "filter=(eq,resourceType")
+	var invalid *apperrors.ErrInvalidArgument
+	assert.ErrorAs(t, err, &invalid)
+
+	sub, err := m.Subscribe(t.Context(), "filter=(eq,perceivedSeverity,CRITICAL)")
+	require.NoError(t, err)
+	m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeNotReady, SeverityMajor)})
+	m.reconcile("vmi/ns/b", []fault{computeFault("b", ProbableCauseComputeFailed, SeverityCritical)})
+	m.reconcile("vmi/ns/b", nil)
+
+	got := receive(t, sub, 2)
+	assert.Equal(t, AlarmNotificationType, got[0].NotificationType)
+	assert.Equal(t, AlarmClearedNotificationType, got[1].NotificationType)
+	assert.Equal(t, "b", got[1].Alarm.ManagedObjectId)
+	assert.Empty(t, sub.Events())
+}
+
+func TestManagerDropsSlowSubscriber(t *testing.T) {
+	m := newTestManager(t)
+	sub, err := m.Subscribe(t.Context(), "")
+	require.NoError(t, err)
+
+	for i := 0; i <= subscriberBufferSize; i++ {
+		sev := SeverityMajor
+		if i%2 == 1 {
+			sev = SeverityCritical
+		}
+		m.reconcile("vmi/ns/a", []fault{computeFault("a", ProbableCauseComputeNotReady, sev)})
+	}
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, subscriberBufferSize, received)
+	assert.ErrorIs(t, sub.Err(), event.ErrSlowSubscriber)
+}
+
+func TestManagerCloseReleasesContextWatcher(t *testing.T) {
+	m := newTestManager(t)
+	before := runtime.NumGoroutine()
+	subs := make([]*Subscription, 0, 10)
+	for range 10 {
+		sub, err := m.Subscribe(t.Context(), "")
+		require.NoError(t, err)
+		subs = append(subs, sub)
+	}
+	for _, sub := range subs {
+		sub.Close()
+	}
+	// Polled by hand: assert.Eventually runs the condition on goroutines of its own.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -3,6 +3,11 @@ package kubevirt
 import (
 	"context"
 	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
@@ -92,7 +97,7 @@ func (r *ipamResolver) resolveInterfaces(ctx context.Context, networksData []*vi
 			if err != nil {
 				return nil, nil, nil, fmt.Errorf("get IPAM for subnet '%s': %w", subnetIdVal, err)
 			}
-			net, iface, ann, err = r.initNetwork(ctx, ipam)
+			net, iface, ann, err = r.initNetwork(ctx, ipam, netData)
 			if err != nil {
 				return nil, nil, nil, fmt.Errorf("initialize kubevirt network and interface from IPAM for subnet '%s': %w", subnetIdVal, err)
 			}
@@ -104,6 +109,19 @@ func (r *ipamResolver) resolveInterfaces(ctx context.Context, networksData []*vi
 			}
 
 			if netInst.NetworkType == nfvcommon.NetworkType_NETWORK_TYPE_SRIOV {
+				fields := netData.Metadata.GetFields()
+				if fields[compute.VnicSecurityGroupsMetadataKey] != "" || fields[compute.VnicPortSecurityMetadataKey] != "" || fields[compute.VnicVipsMetadataKey] != "" {
+					return nil, nil, nil, &apperrors.ErrInvalidArgument{
+						Field:  fmt.Sprintf("VM interface index %d metadata", netIdx),
+						Reason: "SR-IOV vNICs bypass OVN and support neither security groups, port security nor virtual IPs",
+					}
+				}
+				if netData.GetBandwidth() != 0 {
+					return nil, nil, nil, &apperrors.ErrInvalidArgument{
+						Field:  fmt.Sprintf("VM interface index %d bandwidth", netIdx),
+						Reason: "SR-IOV vNICs bypass OVN and cannot be rate limited",
+					}
+				}
 				net, iface, ann, err = initSriovNetwork(netInst, networkIpam)
 				if err != nil {
 					return nil, nil, nil, fmt.Errorf("initialize SR-IOV interface for network '%s': %w", netData.NetworkId.Value, err)
@@ -128,7 +146,7 @@ func (r *ipamResolver) resolveInterfaces(ctx context.Context, networksData []*vi
 						err,
 					)
 				}
-				net, iface, ann, err = r.initNetwork(ctx, ipam)
+				net, iface, ann, err = r.initNetwork(ctx, ipam, netData)
 				if err != nil {
 					return nil, nil, nil, fmt.Errorf("initialize kubevirt network and interface from IPAM for network '%s': %w", netData.NetworkId.Value, err)
 				}
@@ -145,7 +163,9 @@ func (r *ipamResolver) resolveInterfaces(ctx context.Context, networksData []*vi
 
 // subnetIpam returns the IP/MAC allocation for a subnet.
 // If IPAM not configured: returns random allocatable IP/MAC.
-// If IPAM is configured with a static IP, checks the address belongs to the subnet.
+// If IPAM is configured with a static IP, checks the address belongs to the subnet
+// and, when the subnet has address pools of its family, to one of them. The
+// static IP of a dual-stack subnet may be a comma-joined IPv4 and IPv6 pair.
 func (r *ipamResolver) subnetIpam(ctx context.Context, subnetId *nfvcommon.Identifier, netIPAMs []*vivnfm.VirtualNetworkInterfaceIPAM) (*vivnfm.VirtualNetworkInterfaceIPAM, error) {
 	var netIpam *vivnfm.VirtualNetworkInterfaceIPAM = nil
 	for _, ipam := range netIPAMs {
@@ -166,6 +186,10 @@ func (r *ipamResolver) subnetIpam(ctx context.Context, subnetId *nfvcommon.Ident
 	if netIpam.IpAddress == nil {
 		return netIpam, nil
 	}
+	ips, err := staticIps(netIpam.IpAddress)
+	if err != nil {
+		return nil, err
+	}
 	// Check if the static IP Address belongs to the subnet referenced by the subnetId.
 	sub, err := r.netManager.GetSubnet(ctx, network.GetSubnetByUid(subnetId))
 	if err != nil {
@@ -177,9 +201,51 @@ func (r *ipamResolver) subnetIpam(ctx context.Context, subnetId *nfvcommon.Ident
 	if !network.IpBelongsToCidr(netIpam.IpAddress, sub.Cidr) {
 		return nil, fmt.Errorf("IP address '%s' not in subnet CIDR '%s'", netIpam.IpAddress.Ip, sub.Cidr.Cidr)
 	}
+	for _, ip := range ips {
+		pools := addressPoolsOfFamily(sub.AddressPool, ip)
+		if len(pools) > 0 && !network.IpBelongsToAddressPools(&nfvcommon.IPAddress{Ip: ip.String()}, pools) {
+			return nil, &apperrors.ErrInvalidArgument{
+				Field:  "IP address",
+				Reason: fmt.Sprintf("'%s' is not in the address pools of subnet '%s'", ip, sub.ResourceId.GetValue()),
+			}
+		}
+	}
 	return netIpam, nil
 }
 
+// staticIps parses the static IP of an IPAM: a single address or a comma-joined
+// IPv4 and IPv6 pair.
+func staticIps(ip *nfvcommon.IPAddress) ([]netip.Addr, error) {
+	parts := network.SplitDualStack(ip.GetIp())
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "IP address", Reason: fmt.Sprintf("must be an address or an IPv4,IPv6 address pair: '%s'", ip.GetIp())}
+	}
+	ips := make([]netip.Addr, 0, len(parts))
+	for _, part := range parts {
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, &apperrors.ErrInvalidArgument{Field: "IP address", Reason: fmt.Sprintf("invalid format: '%s'", part)}
+		}
+		ips = append(ips, addr.Unmap())
+	}
+	if len(ips) == 2 && ips[0].Is4() == ips[1].Is4() {
+		return nil, &apperrors.ErrInvalidArgument{Field: "IP address", Reason: fmt.Sprintf("address pair must hold one IPv4 and one IPv6 address: '%s'", ip.GetIp())}
+	}
+	return ips, nil
+}
+
+// addressPoolsOfFamily returns the pools of the address family of ip.
+func addressPoolsOfFamily(pools []*nfvcommon.IPAddressPool, ip netip.Addr) []*nfvcommon.IPAddressPool {
+	res := make([]*nfvcommon.IPAddressPool, 0, len(pools))
+	for _, pool := range pools {
+		start, err := netip.ParseAddr(pool.GetStartIP().GetIp())
+		if err == nil && start.Unmap().Is4() == ip.Is4() {
+			res = append(res, pool)
+		}
+	}
+	return res
+}
+
 // networkIpam finds the IPAM for a network identified by networkId (no subnetId).
 //  1. If IPAM exists for the network:
 //     a. if subnetId exists in IPAM: resolve from the subnetId.
@@ -202,7 +268,7 @@ func (r *ipamResolver) networkIpam(ctx context.Context, networkId *nfvcommon.Ide
 		if err != nil {
 			return nil, fmt.Errorf("get subnet with networkId '%s' and IP '%s': %w", networkId.Value, netIpam.IpAddress.Ip, err)
 		}
-		netIpam.SubnetId.Value = sub.ResourceId.Value
+		netIpam.SubnetId = sub.ResourceId
 		return r.subnetIpam(ctx, sub.ResourceId, netIPAMs)
 	}
 	if returnIfNoIpam {
@@ -270,8 +336,9 @@ func initSriovNetwork(netInst *vivnfm.VirtualNetwork, networkIpams []*vivnfm.Vir
 }
 
 // initNetwork returns the kubevirt network and interface from the IPAM. The IPAM
-// must reference a subnet.
-func (r *ipamResolver) initNetwork(ctx context.Context, networkIpam *vivnfm.VirtualNetworkInterfaceIPAM) (*kubevirtv1.Network, *kubevirtv1.Interface, map[string]string, error) {
+// must reference a subnet. The vNIC metadata selects its port security, and the
+// vNIC or subnet bandwidth its rate limit.
+func (r *ipamResolver) initNetwork(ctx context.Context, networkIpam *vivnfm.VirtualNetworkInterfaceIPAM, netData *vivnfm.VirtualNetworkInterfaceData) (*kubevirtv1.Network, *kubevirtv1.Interface, map[string]string, error) {
 	if networkIpam.SubnetId == nil || networkIpam.SubnetId.Value == "" {
 		return nil, nil, nil, &apperrors.ErrInvalidArgument{Field: "network IPAM", Reason: "must have a subnetId reference"}
 	}
@@ -301,13 +368,20 @@ func (r *ipamResolver) initNetwork(ctx context.Context, networkIpam *vivnfm.Virt
 	}
 	ann := make(map[string]string)
 	if networkIpam.IpAddress != nil && networkIpam.IpAddress.Ip != "" {
-		ann[fmt.Sprintf("%s.%s.ovn.kubernetes.io/ip_address", netAttachName, r.namespace)] = networkIpam.IpAddress.Ip
+		// kube-ovn takes the addresses of both families of a dual-stack subnet comma-joined.
+		ann[fmt.Sprintf("%s.%s.ovn.kubernetes.io/ip_address", netAttachName, r.namespace)] = strings.Join(network.SplitDualStack(networkIpam.IpAddress.Ip), ",")
 	}
 	if networkIpam.MacAddress != nil && networkIpam.MacAddress.Mac != "" {
 		ann[fmt.Sprintf("%s.%s.ovn.kubernetes.io/mac_address", netAttachName, r.namespace)] = networkIpam.MacAddress.Mac
 	}
 
 	ann[fmt.Sprintf("%s.%s.ovn.kubernetes.io/logical_switch", netAttachName, r.namespace)] = subnetName
+	if err := r.portSecurityAnnotations(ctx, ann, netAttachName, subnet.ResourceId, netData.GetMetadata()); err != nil {
+		return nil, nil, nil, fmt.Errorf("port security of the interface in subnet '%s': %w", networkIpam.GetSubnetId().Value, err)
+	}
+	if err := r.bandwidthAnnotations(ann, netAttachName, subnet, netData.GetBandwidth()); err != nil {
+		return nil, nil, nil, fmt.Errorf("bandwidth of the interface in subnet '%s': %w", networkIpam.GetSubnetId().Value, err)
+	}
 
 	ifaceName := fmt.Sprintf("%s-%s", subnetName, ifaceUid)
 	return &kubevirtv1.Network{
@@ -325,3 +399,141 @@ func (r *ipamResolver) initNetwork(ctx context.Context, networkIpam *vivnfm.Virt
 			},
 		}, ann, nil
 }
+
+// portSecurityAnnotations adds to ann the kube-ovn port_security,
+// security_groups and aaps annotations of a vNIC of netAttachName in the subnet
+// selected by its metadata. Without any of the keys the vNIC stays open and no
+// annotation is added.
+func (r *ipamResolver) portSecurityAnnotations(ctx context.Context, ann map[string]string, netAttachName string, subnetId *nfvcommon.Identifier, vnicMetadata *nfvcommon.Metadata) error {
+	fields := vnicMetadata.GetFields()
+	groups := splitMetadataList(fields[compute.VnicSecurityGroupsMetadataKey])
+	vips := splitMetadataList(fields[compute.VnicVipsMetadataKey])
+	portSecurityStr, hasPortSecurity := fields[compute.VnicPortSecurityMetadataKey]
+	portSecurity := len(groups) > 0 || len(vips) > 0
+	if hasPortSecurity {
+		var err error
+		if portSecurity, err = strconv.ParseBool(portSecurityStr); err != nil {
+			return &apperrors.ErrInvalidArgument{Field: compute.VnicPortSecurityMetadataKey, Reason: fmt.Sprintf("must be true or false: '%s'", portSecurityStr)}
+		}
+		if !portSecurity && len(groups) > 0 {
+			return &apperrors.ErrInvalidArgument{Field: compute.VnicSecurityGroupsMetadataKey, Reason: "security groups need port security"}
+		}
+		if !portSecurity && len(vips) > 0 {
+			return &apperrors.ErrInvalidArgument{Field: compute.VnicVipsMetadataKey, Reason: "virtual IPs need port security"}
+		}
+	}
+	if !hasPortSecurity && !portSecurity {
+		return nil
+	}
+	provider := fmt.Sprintf("%s.%s", netAttachName, r.namespace)
+	prefix := provider + ".ovn.kubernetes.io/"
+	ann[prefix+"port_security"] = strconv.FormatBool(portSecurity)
+	if len(groups) > 0 {
+		names, err := r.securityGroupNames(ctx, groups)
+		if err != nil {
+			return err
+		}
+		ann[prefix+"security_groups"] = strings.Join(names, ",")
+	}
+	if len(vips) > 0 {
+		names, err := r.vipNames(ctx, vips, subnetId)
+		if err != nil {
+			return err
+		}
+		ann[provider+network.KubeovnAapsAnnotationSuffix] = strings.Join(names, ",")
+	}
+	return nil
+}
+
+// bandwidthAnnotations adds to ann the kube-ovn ingress_rate and egress_rate
+// annotations of a vNIC of netAttachName, in Mbps. The bandwidth of the vNIC,
+// when not 0, must not exceed the bandwidth of the subnet; otherwise the subnet
+// bandwidth applies. Without either the vNIC is not rate limited and no
+// annotation is added.
+func (r *ipamResolver) bandwidthAnnotations(ann map[string]string, netAttachName string, subnet *vivnfm.NetworkSubnet, bandwidth float32) error {
+	var limit uint64
+	if value, ok := subnet.GetMetadata().GetFields()[network.SubnetBandwidthMetadataKey]; ok {
+		var err error
+		if limit, err = strconv.ParseUint(value, 10, 32); err != nil {
+			return &apperrors.ErrInvalidArgument{Field: network.SubnetBandwidthMetadataKey, Reason: fmt.Sprintf("must be a whole number of Mbps: %s", value)}
+		}
+	}
+	if bandwidth != 0 {
+		mbps := float64(bandwidth)
+		if mbps < 1 || mbps > math.MaxUint32 || mbps != math.Trunc(mbps) {
+			return &apperrors.ErrInvalidArgument{Field: "bandwidth", Reason: fmt.Sprintf("must be a positive whole number of Mbps: %v", bandwidth)}
+		}
+		if limit != 0 && uint64(mbps) > limit {
+			return &apperrors.ErrInvalidArgument{Field: "bandwidth", Reason: fmt.Sprintf("%v Mbps exceeds the %d Mbps of the subnet", bandwidth, limit)}
+		}
+		limit = uint64(mbps)
+	}
+	if limit == 0 {
+		return nil
+	}
+	prefix := fmt.Sprintf("%s.%s.ovn.kubernetes.io/", netAttachName, r.namespace)
+	ann[prefix+"ingress_rate"] = strconv.FormatUint(limit, 10)
+	ann[prefix+"egress_rate"] = strconv.FormatUint(limit, 10)
+	return nil
+}
+
+// securityGroupNames resolves the security group names or ids to unique names.
+func (r *ipamResolver) securityGroupNames(ctx context.Context, groups []string) ([]string, error) {
+	sgMgr, ok := r.netManager.(network.SecurityGroupManager)
+	if !ok {
+		return nil, fmt.Errorf("security groups with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		opt := network.GetSecurityGroupByName(group)
+		if misc.IsUUID(group) {
+			opt = network.GetSecurityGroupByUid(&nfvcommon.Identifier{Value: group})
+		}
+		sg, err := sgMgr.GetSecurityGroup(ctx, opt)
+		if err != nil {
+			return nil, fmt.Errorf("get security group '%s': %w", group, err)
+		}
+		if !slices.Contains(names, sg.Name) {
+			names = append(names, sg.Name)
+		}
+	}
+	return names, nil
+}
+
+// vipNames resolves the virtual IP names or ids to unique names, checking that
+// each is in the subnet.
+func (r *ipamResolver) vipNames(ctx context.Context, vips []string, subnetId *nfvcommon.Identifier) ([]string, error) {
+	vipMgr, ok := r.netManager.(network.VipManager)
+	if !ok {
+		return nil, fmt.Errorf("virtual IPs with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	names := make([]string, 0, len(vips))
+	for _, vipRef := range vips {
+		opt := network.GetVipByName(vipRef)
+		if misc.IsUUID(vipRef) {
+			opt = network.GetVipByUid(&nfvcommon.Identifier{Value: vipRef})
+		}
+		vip, err := vipMgr.GetVip(ctx, opt)
+		if err != nil {
+			return nil, fmt.Errorf("get virtual IP '%s': %w", vipRef, err)
+		}
+		if vip.SubnetId.GetValue() != subnetId.GetValue() {
+			return nil, &apperrors.ErrInvalidArgument{Field: compute.VnicVipsMetadataKey, Reason: fmt.Sprintf("virtual IP '%s' is in subnet '%s', not in the subnet '%s' of the vNIC", vipRef, vip.SubnetId.GetValue(), subnetId.GetValue())}
+		}
+		if !slices.Contains(names, vip.Name) {
+			names = append(names, vip.Name)
+		}
+	}
+	return names, nil
+}
+
+// splitMetadataList splits a comma-separated metadata value, dropping blanks.
+func splitMetadataList(value string) []string {
+	var res []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -8,6 +8,7 @@ import (
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/compute"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
 	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
 	"github.com/stretchr/testify/assert"
@@ -60,6 +61,55 @@ func TestSubnetIpam(t *testing.T) {
 		_, err := r.subnetIpam(context.Background(), subnetId, []*vivnfm.VirtualNetworkInterfaceIPAM{ipam})
 		require.Error(t, err)
 	})
+
+	t.Run("static ip must be in the subnet address pools", func(t *testing.T) {
+		pooledSubnet := &vivnfm.NetworkSubnet{
+			ResourceId: subnetId,
+			Cidr:       &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24"},
+			AddressPool: []*nfvcommon.IPAddressPool{{
+				StartIP: &nfvcommon.IPAddress{Ip: "10.0.0.21"},
+				EndIP:   &nfvcommon.IPAddress{Ip: "10.0.0.254"},
+			}},
+		}
+		r, nm := newResolver(t)
+		nm.EXPECT().GetSubnet(gomock.Any(), gomock.Any()).Return(pooledSubnet, nil).Times(2)
+
+		ipam := &vivnfm.VirtualNetworkInterfaceIPAM{SubnetId: subnetId, IpAddress: &nfvcommon.IPAddress{Ip: "10.0.0.21"}}
+		got, err := r.subnetIpam(context.Background(), subnetId, []*vivnfm.VirtualNetworkInterfaceIPAM{ipam})
+		require.NoError(t, err)
+		assert.Same(t, ipam, got)
+
+		ipam = &vivnfm.VirtualNetworkInterfaceIPAM{SubnetId: subnetId, IpAddress: &nfvcommon.IPAddress{Ip: "10.0.0.20"}}
+		_, err = r.subnetIpam(context.Background(), subnetId, []*vivnfm.VirtualNetworkInterfaceIPAM{ipam})
+		var target *apperrors.ErrInvalidArgument
+		require.ErrorAs(t, err, &target)
+		assert.Contains(t, err.Error(), "address pools")
+	})
+
+	t.Run("static ipv4 and ipv6 pair on a dual-stack subnet", func(t *testing.T) {
+		dualSubnet := &vivnfm.NetworkSubnet{
+			ResourceId: subnetId,
+			Cidr:       &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"},
+			AddressPool: []*nfvcommon.IPAddressPool{{
+				StartIP: &nfvcommon.IPAddress{Ip: "10.0.0.21"},
+				EndIP:   &nfvcommon.IPAddress{Ip: "10.0.0.254"},
+			}},
+		}
+		r, nm := newResolver(t)
+		nm.EXPECT().GetSubnet(gomock.Any(), gomock.Any()).Return(dualSubnet, nil).AnyTimes()
+
+		ipam := &vivnfm.VirtualNetworkInterfaceIPAM{SubnetId: subnetId, IpAddress: &nfvcommon.IPAddress{Ip: "10.0.0.21,fd00::5"}}
+		got, err := r.subnetIpam(context.Background(), subnetId, []*vivnfm.VirtualNetworkInterfaceIPAM{ipam})
+		require.NoError(t, err, "the IPv4 pool does not restrict the IPv6 address")
+		assert.Same(t, ipam, got)
+
+		for _, ip := range []string{"10.0.0.5,fd00::5", "10.0.0.21,10.0.0.22", "10.0.0.21,fd00::5,fd00::6", "10.0.0.21,bad"} {
+			ipam := &vivnfm.VirtualNetworkInterfaceIPAM{SubnetId: subnetId, IpAddress: &nfvcommon.IPAddress{Ip: ip}}
+			_, err := r.subnetIpam(context.Background(), subnetId, []*vivnfm.VirtualNetworkInterfaceIPAM{ipam})
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, ip)
+		}
+	})
 }
 
 func TestNetworkIpam(t *testing.T) {
@@ -146,7 +196,7 @@ func TestInitNetwork(t *testing.T) {
 			IpAddress:  &nfvcommon.IPAddress{Ip: "10.0.0.5"},
 			MacAddress: &nfvcommon.MacAddress{Mac: "aa:bb:cc:dd:ee:ff"},
 		}
-		net, iface, ann, err := r.initNetwork(context.Background(), ipam)
+		net, iface, ann, err := r.initNetwork(context.Background(), ipam, nil)
 		require.NoError(t, err)
 		require.NotNil(t, net.Multus)
 		assert.Equal(t, "sub1-netattach", net.Multus.NetworkName)
@@ -157,14 +207,176 @@ func TestInitNetwork(t *testing.T) {
 		assert.Equal(t, "aa:bb:cc:dd:ee:ff", ann["sub1-netattach."+ns+".ovn.kubernetes.io/mac_address"])
 	})
 
+	t.Run("dual-stack address pair is one comma-joined annotation", func(t *testing.T) {
+		r, nm := newResolver(t)
+		nm.EXPECT().GetSubnet(gomock.Any(), gomock.Any()).Return(subnet, nil)
+		ipam := &vivnfm.VirtualNetworkInterfaceIPAM{
+			SubnetId:  k8stest.ID("sub1"),
+			IpAddress: &nfvcommon.IPAddress{Ip: "10.0.0.5, fd00::5"},
+		}
+		_, _, ann, err := r.initNetwork(context.Background(), ipam, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.5,fd00::5", ann["sub1-netattach."+k8stest.TestNamespace+".ovn.kubernetes.io/ip_address"])
+	})
+
 	t.Run("ipam without a subnet id is rejected", func(t *testing.T) {
 		r, _ := newResolver(t) // rejected before any lookup
-		_, _, _, err := r.initNetwork(context.Background(), &vivnfm.VirtualNetworkInterfaceIPAM{})
+		_, _, _, err := r.initNetwork(context.Background(), &vivnfm.VirtualNetworkInterfaceIPAM{}, nil)
 		var target *apperrors.ErrInvalidArgument
 		assert.ErrorAs(t, err, &target)
 	})
 }
 
+// sgNetworkManager is a network manager that also manages security groups.
+type sgNetworkManager struct {
+	*networkmock.MockManager
+	*networkmock.MockSecurityGroupManager
+}
+
+// vipNetworkManager is a network manager that also manages virtual IPs.
+type vipNetworkManager struct {
+	*networkmock.MockManager
+	*networkmock.MockVipManager
+}
+
+func TestPortSecurityAnnotations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	prefix := "sub1-netattach." + k8stest.TestNamespace + ".ovn.kubernetes.io/"
+	vnicMetadata := func(fields map[string]string) *nfvcommon.Metadata { return &nfvcommon.Metadata{Fields: fields} }
+
+	t.Run("no selection keeps the vNIC open", func(t *testing.T) {
+		r, _ := newResolver(t)
+		ann := map[string]string{}
+		require.NoError(t, r.portSecurityAnnotations(ctx, ann, "sub1-netattach", k8stest.ID("uid-sub1"), nil))
+		assert.Empty(t, ann)
+	})
+
+	t.Run("groups by name or id enable port security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		sgm := networkmock.NewMockSecurityGroupManager(ctrl)
+		r := newIpamResolver(sgNetworkManager{networkmock.NewMockManager(ctrl), sgm}, k8stest.TestNamespace)
+		sgId := "0b5d7a2e-3c1f-4e8a-9d6b-2f4c8e1a7b90"
+		sgm.EXPECT().GetSecurityGroup(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, opts ...network.GetSecurityGroupOpt) (*network.SecurityGroup, error) {
+				cfg := network.ApplyGetSecurityGroupOpts(opts...)
+				if cfg.Uid != nil {
+					assert.Equal(t, sgId, cfg.Uid.GetValue())
+					return &network.SecurityGroup{Name: "ssh"}, nil
+				}
+				return &network.SecurityGroup{Name: cfg.Name}, nil
+			}).Times(2)
+		ann := map[string]string{}
+		require.NoError(t, r.portSecurityAnnotations(ctx, ann, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(map[string]string{
+			compute.VnicSecurityGroupsMetadataKey: "web, " + sgId,
+		})))
+		assert.Equal(t, map[string]string{prefix + "port_security": "true", prefix + "security_groups": "web,ssh"}, ann)
+	})
+
+	t.Run("port security can be disabled", func(t *testing.T) {
+		r, _ := newResolver(t)
+		ann := map[string]string{}
+		require.NoError(t, r.portSecurityAnnotations(ctx, ann, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(map[string]string{
+			compute.VnicPortSecurityMetadataKey: "false",
+		})))
+		assert.Equal(t, map[string]string{prefix + "port_security": "false"}, ann)
+	})
+
+	t.Run("invalid selections are rejected", func(t *testing.T) {
+		r, _ := newResolver(t) // rejected before any lookup
+		for name, fields := range map[string]map[string]string{
+			"groups without port security": {compute.VnicSecurityGroupsMetadataKey: "web", compute.VnicPortSecurityMetadataKey: "false"},
+			"port security not a bool":     {compute.VnicPortSecurityMetadataKey: "maybe"},
+		} {
+			err := r.portSecurityAnnotations(ctx, map[string]string{}, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(fields))
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+
+	t.Run("virtual IPs of the subnet are allowed address pairs", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		vipm := networkmock.NewMockVipManager(ctrl)
+		r := newIpamResolver(vipNetworkManager{networkmock.NewMockManager(ctrl), vipm}, k8stest.TestNamespace)
+		vipm.EXPECT().GetVip(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, opts ...network.GetVipOpt) (*network.Vip, error) {
+				name := network.ApplyGetVipOpts(opts...).Name
+				subnetId := "uid-sub1"
+				if name == "elsewhere" {
+					subnetId = "uid-sub2"
+				}
+				return &network.Vip{Name: name, SubnetId: k8stest.ID(subnetId)}, nil
+			}).AnyTimes()
+		ann := map[string]string{}
+		require.NoError(t, r.portSecurityAnnotations(ctx, ann, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(map[string]string{
+			compute.VnicVipsMetadataKey: "vrrp,vrrp",
+		})))
+		assert.Equal(t, map[string]string{prefix + "port_security": "true", prefix + "aaps": "vrrp"}, ann)
+
+		err := r.portSecurityAnnotations(ctx, map[string]string{}, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(map[string]string{
+			compute.VnicVipsMetadataKey: "elsewhere",
+		}))
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target, "virtual IP of another subnet")
+
+		err = r.portSecurityAnnotations(ctx, map[string]string{}, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(map[string]string{
+			compute.VnicVipsMetadataKey: "vrrp", compute.VnicPortSecurityMetadataKey: "false",
+		}))
+		assert.ErrorAs(t, err, &target, "virtual IPs without port security")
+	})
+
+	t.Run("groups need a security group manager", func(t *testing.T) {
+		r, _ := newResolver(t)
+		err := r.portSecurityAnnotations(ctx, map[string]string{}, "sub1-netattach", k8stest.ID("uid-sub1"), vnicMetadata(map[string]string{
+			compute.VnicSecurityGroupsMetadataKey: "web",
+		}))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
+
+func TestBandwidthAnnotations(t *testing.T) {
+	t.Parallel()
+	prefix := "sub1-netattach." + k8stest.TestNamespace + ".ovn.kubernetes.io/"
+	subnet := func(fields map[string]string) *vivnfm.NetworkSubnet {
+		return &vivnfm.NetworkSubnet{ResourceId: k8stest.ID("sub1"), Metadata: &nfvcommon.Metadata{Fields: fields}}
+	}
+	limited := subnet(map[string]string{network.SubnetBandwidthMetadataKey: "100"})
+
+	t.Run("no bandwidth leaves the vNIC unlimited", func(t *testing.T) {
+		r, _ := newResolver(t)
+		ann := map[string]string{}
+		require.NoError(t, r.bandwidthAnnotations(ann, "sub1-netattach", subnet(nil), 0))
+		assert.Empty(t, ann)
+	})
+
+	t.Run("vNIC bandwidth limits both directions", func(t *testing.T) {
+		r, _ := newResolver(t)
+		ann := map[string]string{}
+		require.NoError(t, r.bandwidthAnnotations(ann, "sub1-netattach", limited, 50))
+		assert.Equal(t, map[string]string{prefix + "ingress_rate": "50", prefix + "egress_rate": "50"}, ann)
+	})
+
+	t.Run("subnet bandwidth is the default", func(t *testing.T) {
+		r, _ := newResolver(t)
+		ann := map[string]string{}
+		require.NoError(t, r.bandwidthAnnotations(ann, "sub1-netattach", limited, 0))
+		assert.Equal(t, map[string]string{prefix + "ingress_rate": "100", prefix + "egress_rate": "100"}, ann)
+	})
+
+	t.Run("invalid bandwidths are rejected", func(t *testing.T) {
+		r, _ := newResolver(t)
+		for name, bandwidth := range map[string]float32{
+			"negative":         -10,
+			"fractional":       2.5,
+			"above the subnet": 200,
+		} {
+			err := r.bandwidthAnnotations(map[string]string{}, "sub1-netattach", limited, bandwidth)
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+}
+
 func TestResolveInterfaces(t *testing.T) {
 	t.Parallel()
 	t.Run("always prepends the pod network", func(t *testing.T) {
@@ -199,4 +411,16 @@ func TestResolveInterfaces(t *testing.T) {
 		assert.NotNil(t, ifaces[1].SRIOV)
 		assert.Equal(t, "sriov-nad", nets[1].Multus.NetworkName)
 	})
+
+	t.Run("sriov interface with a bandwidth is rejected", func(t *testing.T) {
+		r, nm := newResolver(t)
+		nm.EXPECT().GetNetwork(gomock.Any(), gomock.Any()).Return(&vivnfm.VirtualNetwork{
+			NetworkResourceId: k8stest.ID("net1"),
+			NetworkType:       nfvcommon.NetworkType_NETWORK_TYPE_SRIOV,
+		}, nil)
+		data := []*vivnfm.VirtualNetworkInterfaceData{{NetworkId: k8stest.ID("net1"), Bandwidth: k8stest.Ptr(float32(100))}}
+		_, _, _, err := r.resolveInterfaces(context.Background(), data, nil)
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
 }
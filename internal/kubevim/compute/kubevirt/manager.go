@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	netattv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
@@ -143,6 +144,16 @@ func (m *manager) AllocateComputeResource(ctx context.Context, req *vivnfm.Alloc
 	if err != nil {
 		return nil, fmt.Errorf("get image '%s': %w", req.GetVcImageId(), err)
 	}
+	// A catalog image is imported on its first use.
+	if imgInfo.Status == image.StatusAvailable {
+		importer, ok := m.imageManager.(image.Importer)
+		if !ok {
+			return nil, fmt.Errorf("import image '%s' with the configured image manager: %w", req.GetVcImageId().GetValue(), apperrors.ErrUnsupported)
+		}
+		if imgInfo, err = importer.ImportImage(ctx, req.GetVcImageId()); err != nil {
+			return nil, fmt.Errorf("import image '%s': %w", req.GetVcImageId().GetValue(), err)
+		}
+	}
 	dvs, err := initImageDataVolumes(imgInfo, flav.StorageAttributes, req.GetComputeName(), namespace)
 	if err != nil {
 		return nil, fmt.Errorf("initialize kubevirt data volume: %w", err)
@@ -331,9 +342,30 @@ type kubevirtNetworkInfo struct {
 	} `json:"interfaces,omitempty"`
 }
 
+// kubeovnIpAddressAnnotationSuffix ends the kube-ovn annotation holding the
+// addresses, comma-joined for a dual-stack subnet, allocated to the pod in the
+// subnet of a provider ("<netattach>.<namespace>.ovn").
+const kubeovnIpAddressAnnotationSuffix = ".ovn.kubernetes.io/ip_address"
+
+// kube-ovn annotations of the VirtualMachine template selecting the port
+// security and the security groups of the vNIC of a provider. Its virtual IPs
+// are under network.KubeovnAapsAnnotationSuffix.
+const (
+	kubeovnPortSecurityAnnotationSuffix   = ".ovn.kubernetes.io/port_security"
+	kubeovnSecurityGroupsAnnotationSuffix = ".ovn.kubernetes.io/security_groups"
+)
+
+// kube-ovn annotations of the VirtualMachine template limiting the rate, in
+// Mbps, of the traffic to and from the vNIC of a provider.
+const (
+	kubeovnIngressRateAnnotationSuffix = ".ovn.kubernetes.io/ingress_rate"
+	kubeovnEgressRateAnnotationSuffix  = ".ovn.kubernetes.io/egress_rate"
+)
+
 type launcherInfo struct {
 	podName       string
-	hostPciByVnic map[string]string // vNIC name -> host PCI address (SR-IOV / pass-through)
+	hostPciByVnic map[string]string   // vNIC name -> host PCI address (SR-IOV / pass-through)
+	ipsByProvider map[string][]string // kube-ovn provider "<netattach>.<namespace>" -> allocated IPs
 }
 
 // getLauncherInfo reads the VMI's virt-launcher pod name and per-vNIC host PCI
@@ -370,14 +402,23 @@ func selectLauncherPod(pods []*corev1.Pod, nodeName string) *corev1.Pod {
 	return pods[0]
 }
 
-// launcherInfoFromPod parses the virt-launcher pod name and per-vNIC host PCI
-// addresses from the kubevirt network-info annotation.
+// launcherInfoFromPod parses the virt-launcher pod name, the per-vNIC host PCI
+// addresses from the kubevirt network-info annotation, and the IPs kube-ovn
+// allocated in each attached subnet.
 func launcherInfoFromPod(pod *corev1.Pod) launcherInfo {
 	info := launcherInfo{hostPciByVnic: map[string]string{}}
 	if pod == nil {
 		return info
 	}
 	info.podName = pod.Name
+	for key, value := range pod.Annotations {
+		if provider, ok := strings.CutSuffix(key, kubeovnIpAddressAnnotationSuffix); ok && provider != "" {
+			if info.ipsByProvider == nil {
+				info.ipsByProvider = map[string][]string{}
+			}
+			info.ipsByProvider[provider] = network.SplitDualStack(value)
+		}
+	}
 	infoJSON, ok := pod.Annotations[kubevirtNetworkInfoAnnotation]
 	if !ok {
 		return info
@@ -525,7 +566,7 @@ func initImageBootableDataVolume(imageInfo *vivnfm.SoftwareImageInformation, boo
 	if imageInfo.Name == "" {
 		return nil, &apperrors.ErrInvalidArgument{Field: "software image name", Reason: "cannot be empty"}
 	}
-	if imageInfo.Status != "ready" {
+	if imageInfo.Status != image.StatusReady {
 		return nil, fmt.Errorf("image %s (id: %s) not ready (actual state: %s): %w", imageInfo.Name, imageInfo.SoftwareImageId.Value, imageInfo.Status, apperrors.ErrInternal)
 	}
 	if bootableAttributes == nil {
@@ -542,6 +583,34 @@ func initImageBootableDataVolume(imageInfo *vivnfm.SoftwareImageInformation, boo
 	if imageInfo.GetSize().Cmp(*bootableAttributes.SizeOfStorage) == 1 {
 		return nil, &apperrors.ErrInvalidArgument{Field: "size of bootable disk", Reason: "can't be less that image size"}
 	}
+	if imageInfo.MinDisk != nil && imageInfo.GetMinDisk().Cmp(*bootableAttributes.SizeOfStorage) == 1 {
+		return nil, &apperrors.ErrInvalidArgument{
+			Field:  "size of bootable disk",
+			Reason: fmt.Sprintf("can't be less than the image min disk %s", imageInfo.GetMinDisk().String()),
+		}
+	}
+
+	// Cloning in the storage class and volume mode of the image lets CDI use a
+	// snapshot or CSI clone instead of copying the whole disk.
+	imageMd := imageInfo.GetMetadata().GetFields()
+	storage := &v1beta1.StorageSpec{
+		Resources: corev1.VolumeResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: *bootableAttributes.SizeOfStorage,
+			},
+		},
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+	}
+	if accessMode := imageMd[image.K8sImageCloneAccessModeMetadataKey]; accessMode != "" {
+		storage.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.PersistentVolumeAccessMode(accessMode)}
+	}
+	if sc := imageMd[image.K8sImageStorageClassMetadataKey]; sc != "" {
+		storage.StorageClassName = &sc
+	}
+	if volumeMode := imageMd[image.K8sImageVolumeModeMetadataKey]; volumeMode != "" {
+		mode := corev1.PersistentVolumeMode(volumeMode)
+		storage.VolumeMode = &mode
+	}
 
 	dvName := fmt.Sprintf("%s-boot-dv", vmName)
 	return &kubevirtv1.DataVolumeTemplateSpec{
@@ -559,17 +628,7 @@ func initImageBootableDataVolume(imageInfo *vivnfm.SoftwareImageInformation, boo
 					Name:      imageInfo.Name,
 				},
 			},
-			Storage: &v1beta1.StorageSpec{
-				Resources: corev1.VolumeResourceRequirements{
-					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: *bootableAttributes.SizeOfStorage,
-					},
-				},
-				AccessModes: []corev1.PersistentVolumeAccessMode{
-					// TODO: Temporary solution to make it works with ReadWriteOnce sc.
-					corev1.ReadWriteOnce,
-				},
-			},
+			Storage: storage,
 		},
 	}, nil
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -156,6 +157,27 @@ func TestAllocateComputeResource(t *testing.T) {
 		assert.Equal(t, "myvm-boot-dv", vm.Spec.DataVolumeTemplates[0].Name)
 	})
 
+	t.Run("boot disk is cloned in the image storage class and volume mode", func(t *testing.T) {
+		m, mocks := newComputeManager(t, podOnlyVMI("myvm"))
+		img := readyImage()
+		img.Metadata = &nfvcommon.Metadata{Fields: map[string]string{
+			image.K8sImageStorageClassMetadataKey:    "ceph-rbd",
+			image.K8sImageVolumeModeMetadataKey:      "Block",
+			image.K8sImageCloneAccessModeMetadataKey: "ReadWriteMany",
+		}}
+		mocks.flavour.EXPECT().GetFlavour(gomock.Any(), gomock.Any()).Return(kubevirtFlavour(), nil)
+		mocks.image.EXPECT().GetImage(gomock.Any(), gomock.Any()).Return(img, nil)
+
+		_, err := m.AllocateComputeResource(context.Background(), allocateReq())
+		require.NoError(t, err)
+		vm := &kubevirtv1.VirtualMachine{}
+		require.NoError(t, m.client.Get(context.Background(), client.ObjectKey{Namespace: k8stest.TestNamespace, Name: "myvm"}, vm))
+		storage := vm.Spec.DataVolumeTemplates[0].Spec.Storage
+		assert.Equal(t, "ceph-rbd", *storage.StorageClassName)
+		assert.Equal(t, corev1.PersistentVolumeBlock, *storage.VolumeMode)
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, storage.AccessModes)
+	})
+
 	t.Run("nil request is rejected", func(t *testing.T) {
 		m, _ := newComputeManager(t)
 		_, err := m.AllocateComputeResource(context.Background(), nil)
@@ -195,4 +217,67 @@ func TestAllocateComputeResource(t *testing.T) {
 		var target *apperrors.ErrNotFound
 		assert.ErrorAs(t, err, &target)
 	})
+
+	t.Run("boot disk smaller than the image min disk is rejected", func(t *testing.T) {
+		m, mocks := newComputeManager(t)
+		img := readyImage()
+		img.MinDisk = k8stest.Ptr(resource.MustParse("100Gi"))
+		mocks.flavour.EXPECT().GetFlavour(gomock.Any(), gomock.Any()).Return(kubevirtFlavour(), nil)
+		mocks.image.EXPECT().GetImage(gomock.Any(), gomock.Any()).Return(img, nil)
+		_, err := m.AllocateComputeResource(context.Background(), allocateReq())
+		var target *apperrors.ErrInvalidArgument
+		require.ErrorAs(t, err, &target)
+		assert.Contains(t, err.Error(), "min disk 100Gi")
+	})
+
+	t.Run("failed image is refused", func(t *testing.T) {
+		m, mocks := newComputeManager(t)
+		img := readyImage()
+		img.Status = image.StatusFailed
+		mocks.flavour.EXPECT().GetFlavour(gomock.Any(), gomock.Any()).Return(kubevirtFlavour(), nil)
+		mocks.image.EXPECT().GetImage(gomock.Any(), gomock.Any()).Return(img, nil)
+		_, err := m.AllocateComputeResource(context.Background(), allocateReq())
+		assert.ErrorContains(t, err, "not ready")
+	})
+
+	t.Run("catalog image is imported on first use", func(t *testing.T) {
+		m, mocks := newComputeManager(t, podOnlyVMI("myvm"))
+		img := readyImage()
+		img.Status = image.StatusAvailable
+		imported := false
+		m.imageManager = &importingImageManagerMock{
+			imageManagerMock: imageManagerMock{MockNfvImageManager: mocks.image},
+			importImage: func(id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+				assert.Equal(t, "img1", id.GetValue())
+				imported = true
+				return readyImage(), nil
+			},
+		}
+		mocks.flavour.EXPECT().GetFlavour(gomock.Any(), gomock.Any()).Return(kubevirtFlavour(), nil)
+		mocks.image.EXPECT().GetImage(gomock.Any(), gomock.Any()).Return(img, nil)
+		_, err := m.AllocateComputeResource(context.Background(), allocateReq())
+		require.NoError(t, err)
+		assert.True(t, imported)
+	})
+
+	t.Run("catalog image without an importer is unsupported", func(t *testing.T) {
+		m, mocks := newComputeManager(t)
+		img := readyImage()
+		img.Status = image.StatusAvailable
+		mocks.flavour.EXPECT().GetFlavour(gomock.Any(), gomock.Any()).Return(kubevirtFlavour(), nil)
+		mocks.image.EXPECT().GetImage(gomock.Any(), gomock.Any()).Return(img, nil)
+		_, err := m.AllocateComputeResource(context.Background(), allocateReq())
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
+
+// importingImageManagerMock is an imageManagerMock that also imports catalog
+// images.
+type importingImageManagerMock struct {
+	imageManagerMock
+	importImage func(*nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error)
+}
+
+func (m *importingImageManagerMock) ImportImage(_ context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	return m.importImage(id)
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
@@ -74,11 +76,25 @@ func nfvVirtualComputeFromKubevirtVm(ctx context.Context, netMgr network.Manager
 			netMdFields[KubevirtVmNetworkManagement] = "true"
 		} else if netSpec.NetworkSource.Multus != nil {
 			multusNet := netSpec.NetworkSource.Multus
+			var bandwidth float32
 			if multusNet.Default {
 				netMdFields[KubevirtVmNetworkManagement] = "true"
 			} else {
 				netMdFields[KubevirtVmNetworkManagement] = "false"
 			}
+			if vm.Spec.Template != nil {
+				provider := kubeovnProvider(multusNet.NetworkName, vmi.Namespace)
+				if v, ok := vm.Spec.Template.ObjectMeta.Annotations[provider+kubeovnPortSecurityAnnotationSuffix]; ok {
+					netMdFields[compute.VnicPortSecurityMetadataKey] = v
+				}
+				if v, ok := vm.Spec.Template.ObjectMeta.Annotations[provider+kubeovnSecurityGroupsAnnotationSuffix]; ok {
+					netMdFields[compute.VnicSecurityGroupsMetadataKey] = v
+				}
+				if v, ok := vm.Spec.Template.ObjectMeta.Annotations[provider+network.KubeovnAapsAnnotationSuffix]; ok {
+					netMdFields[compute.VnicVipsMetadataKey] = v
+				}
+				bandwidth = kubeovnVnicBandwidth(vm.Spec.Template.ObjectMeta.Annotations, provider)
+			}
 			// TODO: Add logic to split the NetworkAttachmentDefinition from namespace (if it exists).
 			// SR-IOV networks map directly to a NAD with no subnet — check for that first.
 			if sriovNet, err := netMgr.GetNetwork(ctx, network.GetNetworkByName(multusNet.NetworkName)); err == nil && sriovNet.NetworkType == nfvcommon.NetworkType_NETWORK_TYPE_SRIOV {
@@ -104,7 +120,7 @@ func nfvVirtualComputeFromKubevirtVm(ctx context.Context, netMgr network.Manager
 				} else {
 					netIfRes.SubnetId = subnet.ResourceId
 					netIfRes.NetworkId = subnet.NetworkId
-					netIfRes.Bandwidth = 0
+					netIfRes.Bandwidth = bandwidth
 				}
 			}
 		} else {
@@ -112,8 +128,17 @@ func nfvVirtualComputeFromKubevirtVm(ctx context.Context, netMgr network.Manager
 		}
 		ifaceStatus, err := getInterfaceStatusFromVmi(name, vmi)
 		if err == nil && ifaceStatus != nil {
-			ips := make([]*nfvcommon.IPAddress, 0, len(ifaceStatus.IPs))
-			for _, ip := range ifaceStatus.IPs {
+			// The guest reports the addresses it configured, which may miss a family
+			// of a dual-stack subnet; kube-ovn knows the addresses it allocated.
+			var allocatedIps []string
+			if multusNet := netSpec.NetworkSource.Multus; multusNet != nil {
+				allocatedIps = launcher.ipsByProvider[kubeovnProvider(multusNet.NetworkName, vmi.Namespace)]
+			}
+			ips := make([]*nfvcommon.IPAddress, 0, len(ifaceStatus.IPs)+len(allocatedIps))
+			for _, ip := range append(slices.Clone(ifaceStatus.IPs), allocatedIps...) {
+				if slices.ContainsFunc(ips, func(known *nfvcommon.IPAddress) bool { return known.Ip == ip }) {
+					continue
+				}
 				ips = append(ips, &nfvcommon.IPAddress{
 					Ip: ip,
 				})
@@ -300,3 +325,29 @@ func ifaceBindingMethodToNfv(method kubevirtv1.InterfaceBindingMethod) (nfvcommo
 		return nfvcommon.TypeVirtualNic_TYPE_VIRTUAL_NIC_BRIDGE, fmt.Errorf("unknown interface binding method: %w", apperrors.ErrUnsupported)
 	}
 }
+
+// kubeovnProvider returns the kube-ovn provider "<netattach>.<namespace>" of a
+// multus network name, which may be namespaced as "<namespace>/<netattach>".
+func kubeovnProvider(networkName, namespace string) string {
+	if ns, name, ok := strings.Cut(networkName, "/"); ok {
+		return name + "." + ns
+	}
+	return networkName + "." + namespace
+}
+
+// kubeovnVnicBandwidth returns the bandwidth, in Mbps, of the vNIC of the
+// provider: the lower of its kube-ovn ingress and egress rates, 0 when neither
+// limits it.
+func kubeovnVnicBandwidth(annotations map[string]string, provider string) float32 {
+	var res float32
+	for _, suffix := range []string{kubeovnIngressRateAnnotationSuffix, kubeovnEgressRateAnnotationSuffix} {
+		rate, err := strconv.ParseUint(annotations[provider+suffix], 10, 32)
+		if err != nil || rate == 0 {
+			continue
+		}
+		if res == 0 || float32(rate) < res {
+			res = float32(rate)
+		}
+	}
+	return res
+}
@@ -1,13 +1,22 @@
 package kubevirt
 
 import (
+	"context"
 	"testing"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/compute"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/flavour"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 )
@@ -169,3 +178,73 @@ func TestGetInterfaceFromVm(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestNfvVirtualComputeInterfaceIps(t *testing.T) {
+	t.Parallel()
+	nm := networkmock.NewMockManager(gomock.NewController(t))
+	nm.EXPECT().GetNetwork(gomock.Any(), gomock.Any()).Return(nil, &apperrors.ErrNotFound{Entity: "network"}).AnyTimes()
+	nm.EXPECT().GetSubnet(gomock.Any(), gomock.Any()).Return(&vivnfm.NetworkSubnet{
+		ResourceId: k8stest.ID("sub1"),
+		NetworkId:  k8stest.ID("net1"),
+	}, nil).AnyTimes()
+
+	meta := k8stest.ManagedMeta("vm1")
+	meta.Namespace = k8stest.TestNamespace
+	meta.Labels[flavour.K8sFlavourIdLabel] = "f1"
+	meta.Labels[image.K8sImageIdLabel] = "img1"
+	provider := "sub1-netattach." + k8stest.TestNamespace
+	vm := &kubevirtv1.VirtualMachine{ObjectMeta: meta, Spec: kubevirtv1.VirtualMachineSpec{
+		Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			provider + kubeovnPortSecurityAnnotationSuffix:   "true",
+			provider + kubeovnSecurityGroupsAnnotationSuffix: "web,ssh",
+			provider + network.KubeovnAapsAnnotationSuffix:   "vrrp",
+			provider + kubeovnIngressRateAnnotationSuffix:    "100",
+			provider + kubeovnEgressRateAnnotationSuffix:     "50",
+		}}},
+	}}
+	vmi := &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: meta,
+		Spec: kubevirtv1.VirtualMachineInstanceSpec{
+			Networks: []kubevirtv1.Network{{
+				Name:          "sub1-vnic",
+				NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: "sub1-netattach"}},
+			}},
+			Domain: kubevirtv1.DomainSpec{Devices: kubevirtv1.Devices{Interfaces: []kubevirtv1.Interface{{
+				Name:                   "sub1-vnic",
+				InterfaceBindingMethod: kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}},
+			}}}},
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{{
+			Name: "sub1-vnic",
+			MAC:  "02:00:00:00:00:01",
+			IPs:  []string{"10.0.0.5"},
+		}}},
+	}
+	// The guest configured IPv4 only; kube-ovn allocated both families.
+	launcher := launcherInfoFromPod(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "virt-launcher-vm1",
+		Annotations: map[string]string{
+			"ovn.kubernetes.io/ip_address": "10.244.0.7",
+			"sub1-netattach." + k8stest.TestNamespace + kubeovnIpAddressAnnotationSuffix: "10.0.0.5,fd00::5",
+		},
+	}})
+
+	got, err := nfvVirtualComputeFromKubevirtVm(context.Background(), nm, vm, vmi, launcher)
+	require.NoError(t, err)
+	require.Len(t, got.GetVirtualNetworkInterface(), 1)
+	ips := got.GetVirtualNetworkInterface()[0].GetIpAddress()
+	require.Len(t, ips, 2)
+	assert.Equal(t, "10.0.0.5", ips[0].GetIp())
+	assert.Equal(t, "fd00::5", ips[1].GetIp())
+
+	fields := got.GetVirtualNetworkInterface()[0].GetMetadata().GetFields()
+	assert.Equal(t, "true", fields[compute.VnicPortSecurityMetadataKey])
+	assert.Equal(t, "web,ssh", fields[compute.VnicSecurityGroupsMetadataKey])
+	assert.Equal(t, "vrrp", fields[compute.VnicVipsMetadataKey])
+	assert.Equal(t, float32(50), got.GetVirtualNetworkInterface()[0].GetBandwidth(), "the lower rate is the effective limit")
+}
+
+func TestKubeovnProvider(t *testing.T) {
+	assert.Equal(t, "sub1-netattach.ns1", kubeovnProvider("sub1-netattach", "ns1"))
+	assert.Equal(t, "sub1-netattach.other", kubeovnProvider("other/sub1-netattach", "ns1"))
+}
@@ -17,6 +17,19 @@ const (
 	KubenfvVmNetworkSubnetAssignmentAnnotation = "compute.kubevim.kubenfv.io/network.subnet.assignment"
 	UnknownNetworkSubnetAssigmentAnnotationMsg = "unknown network subnet assignment annotation, should be one of: random, manual"
 
+	// VnicSecurityGroupsMetadataKey selects, in VirtualNetworkInterfaceData
+	// metadata, the comma-separated names or ids of the security groups of the
+	// vNIC. A queried vNIC reports the group names under the same key.
+	VnicSecurityGroupsMetadataKey = "compute.kubevim.kubenfv.io/security-groups"
+	// VnicPortSecurityMetadataKey enables ("true") or disables ("false") port
+	// security on a vNIC. It defaults to enabled when security groups or virtual
+	// IPs are selected; disabling it, e.g. for router-type VNFs, excludes both.
+	VnicPortSecurityMetadataKey = "compute.kubevim.kubenfv.io/port-security"
+	// VnicVipsMetadataKey selects the comma-separated names or ids of the virtual
+	// IPs the vNIC may use as allowed address pairs. The virtual IPs must be in
+	// the subnet of the vNIC. A queried vNIC reports their names under the same key.
+	VnicVipsMetadataKey = "compute.kubevim.kubenfv.io/vips"
+
 	// VnicHostPciAddressMetadataKey holds the host PCI address backing a vNIC, set
 	// only when it maps to a host PCI device (SR-IOV VF or PCI pass-through). Absent
 	// for virtio/bridge vNICs, which are tap devices with no host PCI device.
@@ -0,0 +1,55 @@
+// Package event holds the virtualised resource change event shared by the
+// kube-vim notification hub, its fault and performance management consumers and
+// the gateway. It has no kube-vim dependencies so the gateway can decode the
+// notification stream without pulling in the resource managers.
+package event
+
+import (
+	"errors"
+	"time"
+)
+
+// ChangeType is the kind of change a notification reports.
+type ChangeType string
+
+const (
+	ChangeTypeCreated      ChangeType = "CREATED"
+	ChangeTypeModified     ChangeType = "MODIFIED"
+	ChangeTypeDeleted      ChangeType = "DELETED"
+	ChangeTypeStateChanged ChangeType = "STATE_CHANGED"
+)
+
+// ResourceType is the ETSI virtualised resource a notification is about.
+type ResourceType string
+
+const (
+	ResourceTypeCompute ResourceType = "COMPUTE"
+	ResourceTypeNetwork ResourceType = "NETWORK"
+	ResourceTypeSubnet  ResourceType = "SUBNET"
+	ResourceTypeImage   ResourceType = "IMAGE"
+)
+
+// Event is a single change notification. Fields are flat scalars with json tags
+// so SOL 013 filter expressions (query-filter) address them directly, e.g.
+// "filter=(eq,resourceType,COMPUTE);(eq,changeType,STATE_CHANGED)".
+type Event struct {
+	// Version is the resume marker assigned by the hub on publish.
+	Version      string       `json:"version"`
+	ChangeType   ChangeType   `json:"changeType"`
+	ResourceType ResourceType `json:"resourceType"`
+	// ResourceId is the ETSI identifier (the backing k8s object UID), the same
+	// value Query* RPCs return for the resource.
+	ResourceId   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	// State and PreviousState are set on STATE_CHANGED: the ComputeRunningState
+	// name for compute, the CDI DataVolume phase for images.
+	State         string    `json:"state,omitempty"`
+	PreviousState string    `json:"previousState,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+var (
+	// ErrSlowSubscriber is set on a subscription dropped because it stopped
+	// draining events. The client should resubscribe from its last version.
+	ErrSlowSubscriber = errors.New("subscriber too slow, events dropped")
+)
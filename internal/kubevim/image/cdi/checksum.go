@@ -0,0 +1,315 @@
+package cdi
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	checksumJobBackoffLimit = 2
+
+	// VerifyChecksumFlag makes the kube-vim binary run as the checksum
+	// verifier of a checksum Job (see VerifyDiskChecksum).
+	VerifyChecksumFlag = "verify-checksum"
+
+	checksumVolumeName     = "disk"
+	checksumDiskMountPath  = "/disk"
+	checksumDiskDevicePath = "/dev/image"
+	// cdiDiskImageFile is where CDI writes the disk image on a Filesystem
+	// volume.
+	cdiDiskImageFile = "disk.img"
+	// cdiImporterUID is the user the CDI importer writes the disk image as. The
+	// verifier runs as the same user to read it.
+	cdiImporterUID = 107
+
+	checksumDiskPathEnv  = "DISK_PATH"
+	checksumImageSizeEnv = "IMAGE_SIZE"
+	checksumAlgorithmEnv = "CHECKSUM_ALGORITHM"
+	checksumExpectedEnv  = "EXPECTED_CHECKSUM"
+)
+
+// checksumHashes maps the supported checksum algorithms to their hash.
+var checksumHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// normalizeChecksum validates the expected checksum of an image source and
+// returns its lower-case algorithm and value.
+func normalizeChecksum(checksum *admin.Checksum) (string, string, error) {
+	algorithm := strings.ToLower(checksum.GetAlgorithm())
+	if _, ok := checksumHashes[algorithm]; !ok {
+		return "", "", &apperrors.ErrInvalidArgument{Field: "checksum.algorithm", Reason: fmt.Sprintf("'%s' is not one of md5, sha1, sha256, sha512", checksum.GetAlgorithm())}
+	}
+	value := strings.ToLower(checksum.GetValue())
+	if _, err := hex.DecodeString(value); err != nil || value == "" {
+		return "", "", &apperrors.ErrInvalidArgument{Field: "checksum.value", Reason: "must be a hex digest"}
+	}
+	return algorithm, value, nil
+}
+
+func checksumJobName(imgName string) string {
+	return imgName + "-checksum"
+}
+
+// checksumJob builds the Job verifying the checksum annotated on vis against
+// the disk image imported into pvc, which it mounts read-only. CDI converts the
+// image to raw and grows it to the volume, so the Job hashes the first
+// virtual-size bytes: the raw disk as the source holds it. The Job is owned by
+// vis, so it is garbage-collected with the image, and its outcome is the
+// checksum verification state of the image.
+func checksumJob(vis *v1beta1.VolumeImportSource, pvc *corev1.PersistentVolumeClaim, verifierImage string) *batchv1.Job {
+	backoffLimit := int32(checksumJobBackoffLimit)
+	runAsNonRoot := true
+	runAsUser := int64(cdiImporterUID)
+	allowPrivilegeEscalation := false
+	readOnlyRootFilesystem := true
+
+	container := corev1.Container{
+		Name:  "verify",
+		Image: verifierImage,
+		Args:  []string{"-" + VerifyChecksumFlag},
+		Env: []corev1.EnvVar{
+			{Name: checksumImageSizeEnv, Value: vis.Annotations[image.K8sImageVirtualSizeAnnotation]},
+			{Name: checksumAlgorithmEnv, Value: vis.Annotations[image.K8sImageChecksumAlgorithmAnnotation]},
+			{Name: checksumExpectedEnv, Value: vis.Annotations[image.K8sImageChecksumAnnotation]},
+		},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		SecurityContext: &corev1.SecurityContext{
+			RunAsNonRoot:             &runAsNonRoot,
+			RunAsUser:                &runAsUser,
+			RunAsGroup:               &runAsUser,
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+	}
+	if pvc.Spec.VolumeMode != nil && *pvc.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		container.VolumeDevices = []corev1.VolumeDevice{{Name: checksumVolumeName, DevicePath: checksumDiskDevicePath}}
+		container.Env = append(container.Env, corev1.EnvVar{Name: checksumDiskPathEnv, Value: checksumDiskDevicePath})
+	} else {
+		container.VolumeMounts = []corev1.VolumeMount{{Name: checksumVolumeName, MountPath: checksumDiskMountPath, ReadOnly: true}}
+		container.Env = append(container.Env, corev1.EnvVar{Name: checksumDiskPathEnv, Value: checksumDiskMountPath + "/" + cdiDiskImageFile})
+	}
+
+	labels := map[string]string{
+		common.K8sManagedByLabel: common.KubeNfvName,
+		image.K8sImageIdLabel:    imageIdentifier(vis).GetValue(),
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      checksumJobName(vis.Name),
+			Namespace: vis.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1beta1.SchemeGroupVersion.String(),
+				Kind:       CDIVolumeImportSourceKind,
+				Name:       vis.Name,
+				UID:        vis.UID,
+			}},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Volumes: []corev1.Volume{{
+						Name: checksumVolumeName,
+						VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: pvc.Name,
+							ReadOnly:  true,
+						}},
+					}},
+					Containers: []corev1.Container{container},
+				},
+			},
+		},
+	}
+}
+
+// checksumUnverifiable returns why the checksum of an image cannot be verified,
+// or "" if it can. The checksum Job reads the raw disk CDI imported, which holds
+// the bytes of the source only when the source is an uncompressed raw image:
+// one whose size as transferred is its virtual size.
+func (m *cdiManager) checksumUnverifiable(lazy bool, size imageSize) string {
+	switch {
+	case m.cfg.ChecksumVerifierImage == nil || *m.cfg.ChecksumVerifierImage == "":
+		return "image.checksumVerifierImage is not configured"
+	case lazy:
+		return "a lazy image is not imported"
+	case size.virtual == 0:
+		return "the virtual size of the image is unknown"
+	case size.compressed != size.virtual:
+		return "the source is not an uncompressed raw image, so the imported disk differs from it"
+	}
+	return ""
+}
+
+// RegisterInformers starts the checksum verification of an image once its
+// DataVolume has succeeded. It must be called before the cache is started; the
+// initial list starts the verification of images imported while kube-vim was
+// down.
+func (m *cdiManager) RegisterInformers(ctx context.Context, informers cache.Informers) error {
+	inf, err := informers.GetInformer(ctx, &v1beta1.DataVolume{})
+	if err != nil {
+		return fmt.Errorf("get informer for %T: %w", &v1beta1.DataVolume{}, err)
+	}
+	handle := func(obj any) {
+		if dv, ok := obj.(*v1beta1.DataVolume); ok {
+			m.handleDataVolume(ctx, dv)
+		}
+	}
+	if _, err := inf.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    handle,
+		UpdateFunc: func(_, newObj any) { handle(newObj) },
+	}); err != nil {
+		return fmt.Errorf("add event handler for %T: %w", &v1beta1.DataVolume{}, err)
+	}
+	return nil
+}
+
+// handleDataVolume creates the checksum Job of the image imported by dv once dv
+// has succeeded, if the image checksum is to be verified.
+func (m *cdiManager) handleDataVolume(ctx context.Context, dv *v1beta1.DataVolume) {
+	if dv.Status.Phase != v1beta1.Succeeded || !misc.IsObjectManagedByKubeNfv(dv) || dv.Namespace != *m.k8sCfg.Namespace {
+		return
+	}
+	imageId, ok := dv.Labels[image.K8sImageIdLabel]
+	if !ok {
+		return
+	}
+	log := m.logger.With(zap.String("image", dv.Name))
+	vis := &v1beta1.VolumeImportSource{}
+	if err := m.client.Get(ctx, client.ObjectKey{Namespace: dv.Namespace, Name: dv.Name}, vis); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Warn("get image VolumeImportSource for checksum verification", zap.Error(err))
+		}
+		return
+	}
+	if imageIdentifier(vis).GetValue() != imageId || vis.Annotations[image.K8sImageChecksumVerifyAnnotation] != "true" {
+		return
+	}
+	if m.cfg.ChecksumVerifierImage == nil || *m.cfg.ChecksumVerifierImage == "" {
+		log.Warn("checksum not verified: image.checksumVerifierImage is not configured")
+		return
+	}
+	job := &batchv1.Job{}
+	if err := m.client.Get(ctx, client.ObjectKey{Namespace: dv.Namespace, Name: checksumJobName(vis.Name)}, job); err == nil {
+		return
+	} else if !k8serrors.IsNotFound(err) {
+		log.Warn("get checksum Job", zap.Error(err))
+		return
+	}
+	// The PVC is not kube-vim owned: CDI creates it for the DataVolume.
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := m.apiReader.Get(ctx, client.ObjectKey{Namespace: dv.Namespace, Name: dv.Name}, pvc); err != nil {
+		log.Warn("get image PVC for checksum verification", zap.Error(err))
+		return
+	}
+	if err := m.client.Create(ctx, checksumJob(vis, pvc, *m.cfg.ChecksumVerifierImage)); err != nil && !k8serrors.IsAlreadyExists(err) {
+		log.Warn("create checksum Job", zap.Error(err))
+		return
+	}
+	log.Info("verifying image checksum")
+}
+
+// VerifyDiskChecksum is the checksum verifier run by the checksum Job: it
+// hashes the first IMAGE_SIZE bytes of the disk at DISK_PATH with
+// CHECKSUM_ALGORITHM and compares the digest to EXPECTED_CHECKSUM.
+func VerifyDiskChecksum() error {
+	size, err := strconv.ParseInt(os.Getenv(checksumImageSizeEnv), 10, 64)
+	if err != nil || size <= 0 {
+		return fmt.Errorf("invalid %s '%s'", checksumImageSizeEnv, os.Getenv(checksumImageSizeEnv))
+	}
+	return verifyDiskChecksum(os.Getenv(checksumDiskPathEnv), size, os.Getenv(checksumAlgorithmEnv), os.Getenv(checksumExpectedEnv))
+}
+
+func verifyDiskChecksum(path string, size int64, algorithm, expected string) error {
+	newHash, ok := checksumHashes[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported checksum algorithm '%s'", algorithm)
+	}
+	disk, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open disk image: %w", err)
+	}
+	defer disk.Close()
+	h := newHash()
+	n, err := io.Copy(h, io.LimitReader(disk, size))
+	if err != nil {
+		return fmt.Errorf("read disk image: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("disk image holds %d bytes, expected at least %d", n, size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, sum)
+	}
+	return nil
+}
+
+type checksumState int
+
+const (
+	// checksumUnverified: no checksum, or validateChecksum disabled.
+	checksumUnverified checksumState = iota
+	checksumVerifying
+	checksumVerified
+	checksumFailed
+)
+
+// imageChecksumState returns the checksum verification state of the image vis
+// from its verification Job (nil if there is none yet) and, on failure, the
+// reason given by the Job condition. An image whose checksum is to be verified
+// is verifying until its Job completes.
+func imageChecksumState(vis *v1beta1.VolumeImportSource, job *batchv1.Job) (checksumState, string) {
+	if vis.Annotations[image.K8sImageChecksumVerifyAnnotation] != "true" {
+		return checksumUnverified, ""
+	}
+	if job == nil {
+		return checksumVerifying, ""
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return checksumVerified, ""
+		case batchv1.JobFailed:
+			if cond.Message != "" {
+				return checksumFailed, cond.Message
+			}
+			return checksumFailed, cond.Reason
+		}
+	}
+	return checksumVerifying, ""
+}
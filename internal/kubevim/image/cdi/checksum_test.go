@@ -0,0 +1,192 @@
+package cdi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func seedImagePvc(name string, volumeMode corev1.PersistentVolumeMode) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{VolumeMode: &volumeMode},
+	}
+}
+
+// seedImageDv is seedDv labelled with the image id, as DownloadImage creates it.
+func seedImageDv(name string, phase v1beta1.DataVolumePhase) *v1beta1.DataVolume {
+	dv := seedDv(name)
+	dv.Labels[image.K8sImageIdLabel] = "uid-" + name
+	dv.Status.Phase = phase
+	return dv
+}
+
+func TestChecksumJob(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	getJob := func(t *testing.T, cl client.Client) (*batchv1.Job, error) {
+		t.Helper()
+		job := &batchv1.Job{}
+		return job, cl.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "img1-checksum"}, job)
+	}
+
+	t.Run("starts once the data volume succeeded and reads the pvc", func(t *testing.T) {
+		dv := seedImageDv("img1", v1beta1.ImportInProgress)
+		m, cl := newManager(t, seedChecksumVis("img1"), dv, seedImagePvc("img1", corev1.PersistentVolumeFilesystem))
+		m.handleDataVolume(ctx, dv)
+		_, err := getJob(t, cl)
+		require.True(t, apierrors.IsNotFound(err))
+
+		dv.Status.Phase = v1beta1.Succeeded
+		m.handleDataVolume(ctx, dv)
+		job, err := getJob(t, cl)
+		require.NoError(t, err)
+		assert.Equal(t, "uid-img1", string(job.OwnerReferences[0].UID))
+		volume := job.Spec.Template.Spec.Volumes[0]
+		assert.Equal(t, &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "img1", ReadOnly: true}, volume.PersistentVolumeClaim)
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, "kube-vim:test", container.Image)
+		assert.Equal(t, []string{"-" + VerifyChecksumFlag}, container.Args)
+		assert.Equal(t, []corev1.VolumeMount{{Name: volume.Name, MountPath: "/disk", ReadOnly: true}}, container.VolumeMounts)
+		assert.Contains(t, container.Env, corev1.EnvVar{Name: "DISK_PATH", Value: "/disk/disk.img"})
+		assert.Contains(t, container.Env, corev1.EnvVar{Name: "IMAGE_SIZE", Value: "1048576"})
+		assert.Contains(t, container.Env, corev1.EnvVar{Name: "CHECKSUM_ALGORITHM", Value: "sha256"})
+		assert.Contains(t, container.Env, corev1.EnvVar{Name: "EXPECTED_CHECKSUM", Value: "abc123"})
+
+		// A later update leaves the Job alone.
+		m.handleDataVolume(ctx, dv)
+	})
+
+	t.Run("block volume is read as a device", func(t *testing.T) {
+		dv := seedImageDv("img1", v1beta1.Succeeded)
+		m, cl := newManager(t, seedChecksumVis("img1"), dv, seedImagePvc("img1", corev1.PersistentVolumeBlock))
+		m.handleDataVolume(ctx, dv)
+		job, err := getJob(t, cl)
+		require.NoError(t, err)
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Empty(t, container.VolumeMounts)
+		assert.Equal(t, "/dev/image", container.VolumeDevices[0].DevicePath)
+		assert.Contains(t, container.Env, corev1.EnvVar{Name: "DISK_PATH", Value: "/dev/image"})
+	})
+
+	t.Run("unverified checksum starts no job", func(t *testing.T) {
+		vis := seedChecksumVis("img1")
+		delete(vis.Annotations, image.K8sImageChecksumVerifyAnnotation)
+		dv := seedImageDv("img1", v1beta1.Succeeded)
+		m, cl := newManager(t, vis, dv, seedImagePvc("img1", corev1.PersistentVolumeFilesystem))
+		m.handleDataVolume(ctx, dv)
+		_, err := getJob(t, cl)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+}
+
+func TestDownloadImageChecksumVerification(t *testing.T) {
+	t.Parallel()
+	raw := make([]byte, 4096)
+	qcow2 := qcow2Header(1 << 30)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/raw.img":
+			http.ServeContent(w, r, "raw.img", time.Time{}, bytes.NewReader(raw))
+		case "/disk.qcow2":
+			http.ServeContent(w, r, "disk.qcow2", time.Time{}, bytes.NewReader(qcow2))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	download := func(t *testing.T, path string, validate *bool) (client.Client, error) {
+		t.Helper()
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		m.sizeDiscoveryTimeout = 5 * time.Second
+		req := httpDownloadReq("img1")
+		req.Source.Http.Url = srv.URL + path
+		req.Source.Http.Checksum = &admin.Checksum{Algorithm: "sha256", Value: "abc123"}
+		req.Options = &admin.DownloadOptions{ValidateChecksum: validate}
+		_, err := m.DownloadImage(context.Background(), req)
+		return cl, err
+	}
+	verifyAnnotation := func(t *testing.T, cl client.Client) string {
+		t.Helper()
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		return vis.Annotations[image.K8sImageChecksumVerifyAnnotation]
+	}
+
+	t.Run("raw source is verified", func(t *testing.T) {
+		cl, err := download(t, "/raw.img", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "true", verifyAnnotation(t, cl))
+	})
+
+	t.Run("qcow2 source is only recorded", func(t *testing.T) {
+		cl, err := download(t, "/disk.qcow2", nil)
+		require.NoError(t, err)
+		assert.Empty(t, verifyAnnotation(t, cl))
+	})
+
+	t.Run("required verification of a qcow2 source is rejected", func(t *testing.T) {
+		_, err := download(t, "/disk.qcow2", k8stest.Ptr(true))
+		var target *apperrors.ErrInvalidArgument
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "options.validateChecksum", target.Field)
+	})
+
+	t.Run("disabled verification is only recorded", func(t *testing.T) {
+		cl, err := download(t, "/raw.img", k8stest.Ptr(false))
+		require.NoError(t, err)
+		assert.Empty(t, verifyAnnotation(t, cl))
+	})
+
+	t.Run("checksum in the metadata is rejected", func(t *testing.T) {
+		m, _ := newManager(t)
+		req := &admin.DownloadImageRequest{
+			Metadata: &admin.ImageMetadata{Name: "img1", Metadata: &nfvcommon.Metadata{Fields: map[string]string{
+				image.K8sImageChecksumAnnotation: "abc123",
+			}}},
+			Source: &admin.ImageSource{Type: admin.ImageSourceType_REGISTRY, Registry: &admin.RegistrySource{Image: "quay.io/vnf/disk:1.0"}},
+		}
+		_, err := m.DownloadImage(context.Background(), req)
+		var target *apperrors.ErrInvalidArgument
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, image.K8sImageChecksumAnnotation, target.Field)
+	})
+}
+
+func TestVerifyDiskChecksum(t *testing.T) {
+	t.Parallel()
+	content := []byte("raw disk content")
+	sum := sha256.Sum256(content)
+	expected := hex.EncodeToString(sum[:])
+	// CDI grows the imported disk to the volume.
+	disk := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, os.WriteFile(disk, append(content, make([]byte, 1024)...), 0o600))
+	size := int64(len(content))
+
+	assert.NoError(t, verifyDiskChecksum(disk, size, "sha256", expected))
+	assert.ErrorContains(t, verifyDiskChecksum(disk, size, "sha256", "abc123"), "checksum mismatch: expected abc123, got "+expected)
+	assert.ErrorContains(t, verifyDiskChecksum(disk, size+2048, "sha256", expected), "expected at least")
+	assert.Error(t, verifyDiskChecksum(disk, size, "crc32", expected))
+}
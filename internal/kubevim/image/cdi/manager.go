@@ -3,6 +3,11 @@ package cdi
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
@@ -12,19 +17,32 @@ import (
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
 	"github.com/kube-nfv/kube-vim/internal/misc"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	uploadv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	CDIVolumeImportSourceKind = "VolumeImportSource"
+	// cdiUploadPath is the synchronous upload endpoint of the CDI upload proxy.
+	cdiUploadPath = "/v1beta1/upload"
+	// Transports of the CDI registry source URL.
+	registryDockerScheme     = "docker://"
+	registryOciArchiveScheme = "oci-archive://"
 
 	K8sDataVolumeIdLabel = "cdi.image.kubevim.kubenfv.io/data-volume-id"
 	K8sDataVolumePhase   = "cdi.image.kubevim.kubenfv.io/data-volume-phase"
+	// K8sDataVolumeRestarts is the download status metadata key holding how many
+	// times CDI restarted the importer pod.
+	K8sDataVolumeRestarts = "cdi.image.kubevim.kubenfv.io/data-volume-restarts"
 )
 
 var (
@@ -40,20 +58,36 @@ type cdiManager struct {
 	apiReader client.Reader
 	cfg       *config.ImageConfig
 	k8sCfg    *config.K8sConfig
+	logger    *zap.Logger
+	// sizeDiscoveryTimeout bounds reading the image size from its source; 0
+	// disables size discovery.
+	sizeDiscoveryTimeout time.Duration
 }
 
-func NewCDIImageManager(cl client.Client, apiReader client.Reader, cfg *config.ImageConfig, k8sCfg *config.K8sConfig) (*cdiManager, error) {
+func NewCDIImageManager(cl client.Client, apiReader client.Reader, cfg *config.ImageConfig, k8sCfg *config.K8sConfig, logger *zap.Logger) (*cdiManager, error) {
 	if cfg.StorageClass == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "config image.StorageClass", Reason: "can't be empty"}
 	}
 	if k8sCfg.Namespace == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "config k8s.Namespace", Reason: "can't be nil"}
 	}
+	var sizeDiscoveryTimeout time.Duration
+	if cfg.SizeDiscoveryTimeout != nil && *cfg.SizeDiscoveryTimeout != "" {
+		var err error
+		if sizeDiscoveryTimeout, err = time.ParseDuration(*cfg.SizeDiscoveryTimeout); err != nil || sizeDiscoveryTimeout < 0 {
+			return nil, &apperrors.ErrInvalidArgument{Field: "config image.sizeDiscoveryTimeout", Reason: fmt.Sprintf("'%s' is not a valid duration", *cfg.SizeDiscoveryTimeout)}
+		}
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 	return &cdiManager{
-		client:    cl,
-		apiReader: apiReader,
-		cfg:       cfg,
-		k8sCfg:    k8sCfg,
+		client:               cl,
+		apiReader:            apiReader,
+		cfg:                  cfg,
+		k8sCfg:               k8sCfg,
+		logger:               logger,
+		sizeDiscoveryTimeout: sizeDiscoveryTimeout,
 	}, nil
 }
 
@@ -61,29 +95,26 @@ func (m *cdiManager) GetImage(ctx context.Context, id *nfvcommon.Identifier) (*v
 	if id == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "id", Reason: "can't be nil"}
 	}
-	ns := client.InNamespace(*m.k8sCfg.Namespace)
-	managed := client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}
-	visList := &v1beta1.VolumeImportSourceList{}
-	if err := m.client.List(ctx, visList, ns, managed); err != nil {
-		return nil, fmt.Errorf("list CDI VolumeImportSources: %w", err)
-	}
-	var imageVis *v1beta1.VolumeImportSource
-	for idx := range visList.Items {
-		if misc.IdentifierToUID(id) == visList.Items[idx].GetUID() {
-			imageVis = &visList.Items[idx]
-			break
-		}
-	}
-	if imageVis == nil {
-		return nil, &apperrors.ErrNotFound{Entity: "software image", Identifier: id.Value}
+	imageVis, err := m.getVolumeImportSource(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	return m.softwareImage(ctx, id, imageVis)
+}
+
+// softwareImage returns the information of the image id held by imageVis.
+func (m *cdiManager) softwareImage(ctx context.Context, id *nfvcommon.Identifier, imageVis *v1beta1.VolumeImportSource) (*vivnfm.SoftwareImageInformation, error) {
 	imgName := imageVis.Name
 	dv := &v1beta1.DataVolume{}
 	if err := m.client.Get(ctx, client.ObjectKey{Namespace: *m.k8sCfg.Namespace, Name: imgName}, dv); err != nil {
 		return nil, fmt.Errorf("get CDI DataVolume from image '%s' (id: %s): %w", imgName, id.Value, err)
 	}
 
-	nfvImg, err := nfvImageFromCdiDataVolumeVis(dv, imageVis)
+	checksumJob, err := m.getChecksumJob(ctx, imageVis)
+	if err != nil {
+		return nil, err
+	}
+	nfvImg, err := nfvImageFromCdiDataVolumeVis(dv, imageVis, checksumJob)
 	if err != nil {
 		return nil, fmt.Errorf("convert CDI DataVolume and VolumeImportSource to NFV SoftwareImageInformation from image '%s' (id: %s): %w", imgName, id.Value, err)
 	}
@@ -106,6 +137,14 @@ func (m *cdiManager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInf
 		dvRef := &dvList.Items[idx]
 		dataVolumesIdx[dvRef.Name] = dvRef
 	}
+	jobList := &batchv1.JobList{}
+	if err := m.client.List(ctx, jobList, ns, managed); err != nil {
+		return nil, fmt.Errorf("list checksum Jobs: %w", err)
+	}
+	jobsIdx := make(map[string]*batchv1.Job)
+	for idx := range jobList.Items {
+		jobsIdx[jobList.Items[idx].Name] = &jobList.Items[idx]
+	}
 	res := make([]*vivnfm.SoftwareImageInformation, 0, len(visList.Items))
 	for idx := range visList.Items {
 		img := &visList.Items[idx]
@@ -113,7 +152,7 @@ func (m *cdiManager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInf
 		if !ok {
 			continue
 		}
-		nfvImg, err := nfvImageFromCdiDataVolumeVis(imgDv, img)
+		nfvImg, err := nfvImageFromCdiDataVolumeVis(imgDv, img, jobsIdx[checksumJobName(img.Name)])
 		if err != nil {
 			continue
 		}
@@ -127,10 +166,54 @@ func (m *cdiManager) DownloadImage(ctx context.Context, req *admin.DownloadImage
 		return nil, &apperrors.ErrInvalidArgument{Field: "request", Reason: "can't be nil"}
 	}
 	imgName := req.Metadata.GetName()
-	importSourceType, err := importSourceTypeFromImageSource(req.Source)
+	importSourceType, err := importSourceTypeFromImageSource(imgName, req.Source, req.Metadata.GetMetadata())
 	if err != nil {
 		return nil, fmt.Errorf("get CDI ImportSourceType from imageSource: %w", err)
 	}
+	attributes, err := imageAttributeAnnotations(req)
+	if err != nil {
+		return nil, fmt.Errorf("get attributes of image '%s': %w", imgName, err)
+	}
+	// The checksum is verified when validateChecksum is true, and when it is
+	// unset and the image can be verified (see checksumUnverifiable).
+	_, hasChecksum := attributes[image.K8sImageChecksumAnnotation]
+	var validateChecksum *bool
+	if req.Options != nil {
+		validateChecksum = req.Options.ValidateChecksum
+	}
+	requireChecksum := hasChecksum && validateChecksum != nil && *validateChecksum
+	if requireChecksum && (m.cfg.ChecksumVerifierImage == nil || *m.cfg.ChecksumVerifierImage == "") {
+		return nil, fmt.Errorf("checksum verification without image.checksumVerifierImage configured: %w", apperrors.ErrUnsupported)
+	}
+	lazy := req.Options != nil && (req.Options.LazyDownload != nil && *req.Options.LazyDownload == true)
+	// A lazy image is not read before its first use.
+	var discovered imageSize
+	if !lazy && m.sizeDiscoveryTimeout > 0 {
+		discoverCtx, cancel := context.WithTimeout(ctx, m.sizeDiscoveryTimeout)
+		discovered, err = m.discoverImageSize(discoverCtx, req, importSourceType)
+		cancel()
+		if err != nil {
+			// The import may still succeed, e.g. when only CDI can reach the
+			// source; it then gets the requested or default size.
+			m.logger.Warn("Image size discovery failed", zap.String("image", imgName), zap.Error(err))
+		}
+		if discovered.compressed > 0 {
+			attributes[image.K8sImageCompressedSizeAnnotation] = strconv.FormatInt(discovered.compressed, 10)
+		}
+		if discovered.virtual > 0 {
+			attributes[image.K8sImageVirtualSizeAnnotation] = strconv.FormatInt(discovered.virtual, 10)
+		}
+	}
+	if hasChecksum && (validateChecksum == nil || *validateChecksum) {
+		switch reason := m.checksumUnverifiable(lazy, discovered); {
+		case reason == "":
+			attributes[image.K8sImageChecksumVerifyAnnotation] = "true"
+		case requireChecksum:
+			return nil, &apperrors.ErrInvalidArgument{Field: "options.validateChecksum", Reason: fmt.Sprintf("the checksum cannot be verified: %s", reason)}
+		default:
+			m.logger.Info("Image checksum recorded without verification", zap.String("image", imgName), zap.String("reason", reason))
+		}
+	}
 	volumeImportSource := &v1beta1.VolumeImportSource{
 		ObjectMeta: v1.ObjectMeta{
 			Name: imgName,
@@ -138,11 +221,19 @@ func (m *cdiManager) DownloadImage(ctx context.Context, req *admin.DownloadImage
 				common.K8sManagedByLabel: common.KubeNfvName,
 				image.K8sIsUploadLabel:   "false",
 			},
+			Annotations: attributes,
 		},
 		Spec: v1beta1.VolumeImportSourceSpec{
 			Source: importSourceType,
 		},
 	}
+	// An image imported from a catalog keeps the stable id of the catalog image.
+	if catalogId, ok := req.Metadata.GetMetadata().GetFields()[image.K8sCatalogImageIdLabel]; ok {
+		if !misc.IsUUID(catalogId) {
+			return nil, &apperrors.ErrInvalidArgument{Field: image.K8sCatalogImageIdLabel, Reason: fmt.Sprintf("'%s' is not a UUID", catalogId)}
+		}
+		volumeImportSource.Labels[image.K8sCatalogImageIdLabel] = catalogId
+	}
 	volumeImportSource.Namespace = *m.k8sCfg.Namespace
 	if err := m.client.Create(ctx, volumeImportSource); err != nil {
 		return nil, fmt.Errorf("create CDI VolumeImportSource: %w", err)
@@ -155,10 +246,17 @@ func (m *cdiManager) DownloadImage(ctx context.Context, req *admin.DownloadImage
 			ObjectMeta: v1.ObjectMeta{Name: imgName, Namespace: *m.k8sCfg.Namespace},
 		})
 	}
-	imageId := misc.UIDToIdentifier(volumeImportSource.GetUID())
+	imageId := imageIdentifier(volumeImportSource)
+	for _, obj := range httpSourceObjects(volumeImportSource, req.Source.GetHttp(), req.Metadata.GetMetadata()) {
+		if err := m.client.Create(ctx, obj); err != nil {
+			// Objects created so far are owned by the VolumeImportSource and go with it.
+			cleanupVolumeImportSource()
+			return nil, fmt.Errorf("create HTTP source object '%s' of image '%s': %w", obj.GetName(), imgName, err)
+		}
+	}
 
 	// Return non-instantiated image if LazyDownload option presents
-	if req.Options != nil && (req.Options.LazyDownload != nil && *req.Options.LazyDownload == true) {
+	if lazy {
 		return &admin.DownloadImageResponse{
 			ImageId: imageId,
 		}, nil
@@ -168,89 +266,353 @@ func (m *cdiManager) DownloadImage(ctx context.Context, req *admin.DownloadImage
 	if req.Options != nil && (req.Options.StorageClass != nil && *req.Options.StorageClass != "") {
 		storageClassName = *req.Options.StorageClass
 	}
+	storageSize := ""
+	if req.Options != nil && req.Options.StorageSize != nil {
+		storageSize = *req.Options.StorageSize
+	}
+	if err := m.createImageDataVolume(ctx, volumeImportSource, storageClassName, storageSize, discovered); err != nil {
+		cleanupVolumeImportSource()
+		return nil, err
+	}
+	return &admin.DownloadImageResponse{
+		ImageId: imageId,
+	}, nil
+}
+
+// GetImageDownloadStatus reports the import progress of an image from its
+// DataVolume. An image registered with LazyDownload has no DataVolume until it
+// is first used and is reported as LAZY_REGISTERED.
+func (m *cdiManager) GetImageDownloadStatus(ctx context.Context, req *admin.GetImageDownloadStatusRequest) (*admin.GetImageDownloadStatusResponse, error) {
+	if req == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "request", Reason: "can't be nil"}
+	}
+	if req.GetImageId() == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "imageId", Reason: "can't be nil"}
+	}
+	imageVis, err := m.getVolumeImportSource(ctx, req.GetImageId())
+	if err != nil {
+		return nil, err
+	}
+	dv := &v1beta1.DataVolume{}
+	if err := m.client.Get(ctx, client.ObjectKey{Namespace: *m.k8sCfg.Namespace, Name: imageVis.Name}, dv); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get CDI DataVolume from image '%s' (id: %s): %w", imageVis.Name, req.GetImageId().GetValue(), err)
+		}
+		if dvId, ok := imageVis.Labels[K8sDataVolumeIdLabel]; ok {
+			return nil, &image.ErrDataVolumeNotFound{Name: imageVis.Name, UID: dvId}
+		}
+		return &admin.GetImageDownloadStatusResponse{
+			Status: lazyDownloadStatus(imageVis),
+		}, nil
+	}
+	checksumJob, err := m.getChecksumJob(ctx, imageVis)
+	if err != nil {
+		return nil, err
+	}
+	status := downloadStatusFromDataVolume(dv, imageVis, checksumJob)
+	if st, _ := imageChecksumState(imageVis, checksumJob); st == checksumFailed {
+		if msg := m.checksumFailureMessage(ctx, checksumJob); msg != "" {
+			errMsg := fmt.Sprintf("checksum verification failed: %s", msg)
+			status.ErrorMessage = &errMsg
+		}
+	}
+	return &admin.GetImageDownloadStatusResponse{
+		Status: status,
+	}, nil
+}
+
+// getChecksumJob returns the checksum verification Job of the image, or nil if
+// its checksum is not verified or the Job has not started yet.
+func (m *cdiManager) getChecksumJob(ctx context.Context, vis *v1beta1.VolumeImportSource) (*batchv1.Job, error) {
+	if vis.Annotations[image.K8sImageChecksumVerifyAnnotation] != "true" {
+		return nil, nil
+	}
+	job := &batchv1.Job{}
+	if err := m.client.Get(ctx, client.ObjectKey{Namespace: vis.Namespace, Name: checksumJobName(vis.Name)}, job); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get checksum Job of image '%s': %w", vis.Name, err)
+	}
+	return job, nil
+}
+
+// checksumFailureMessage returns the termination message of the failed checksum
+// verification pod of job, which names the actual checksum, if there is one.
+func (m *cdiManager) checksumFailureMessage(ctx context.Context, job *batchv1.Job) string {
+	pods := &corev1.PodList{}
+	if err := m.client.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+		return ""
+	}
+	for _, pod := range pods.Items {
+		for _, st := range pod.Status.ContainerStatuses {
+			if st.State.Terminated != nil && st.State.Terminated.ExitCode != 0 && st.State.Terminated.Message != "" {
+				return strings.TrimSpace(st.State.Terminated.Message)
+			}
+		}
+	}
+	return ""
+}
+
+// DeleteImage removes the image DataVolume, checksum Job, the Secret and
+// ConfigMap of its HTTP source and finally its VolumeImportSource. Unless force is set, an image
+// still referenced by a VirtualMachine is not deleted. Computes booted from the
+// image keep working either way, as their disk is a clone.
+func (m *cdiManager) DeleteImage(ctx context.Context, id *nfvcommon.Identifier, force bool) error {
+	if id == nil {
+		return &apperrors.ErrInvalidArgument{Field: "id", Reason: "can't be nil"}
+	}
+	imageVis, err := m.getVolumeImportSource(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !force {
+		vmList := &kubevirtv1.VirtualMachineList{}
+		if err := m.client.List(ctx, vmList, client.InNamespace(*m.k8sCfg.Namespace), client.MatchingLabels{image.K8sImageIdLabel: imageIdentifier(imageVis).GetValue()}); err != nil {
+			return fmt.Errorf("list VirtualMachines of image '%s': %w", imageVis.Name, err)
+		}
+		if len(vmList.Items) > 0 {
+			return &apperrors.ErrFailedPrecondition{
+				Resource: fmt.Sprintf("image '%s' (id: %s)", imageVis.Name, id.GetValue()),
+				Reason:   fmt.Sprintf("in use by %d virtual machine(s), e.g. '%s'", len(vmList.Items), vmList.Items[0].Name),
+			}
+		}
+	}
+	ns := *m.k8sCfg.Namespace
+	for _, obj := range []client.Object{
+		&v1beta1.DataVolume{ObjectMeta: v1.ObjectMeta{Name: imageVis.Name, Namespace: ns}},
+		&batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: checksumJobName(imageVis.Name), Namespace: ns}},
+		&corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: httpSourceHeadersName(imageVis.Name), Namespace: ns}},
+		&corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: httpSourceCaName(imageVis.Name), Namespace: ns}},
+	} {
+		if err := m.client.Delete(ctx, obj, client.PropagationPolicy(v1.DeletePropagationBackground)); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("delete %s of image '%s': %w", obj.GetName(), imageVis.Name, err)
+		}
+	}
+	if err := m.client.Delete(ctx, imageVis); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("delete CDI VolumeImportSource of image '%s': %w", imageVis.Name, err)
+	}
+	return nil
+}
+
+// UpdateImage changes the image attributes persisted as annotations of the
+// image VolumeImportSource (see image.Updater).
+func (m *cdiManager) UpdateImage(ctx context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	if id == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "id", Reason: "can't be nil"}
+	}
+	attributes, err := updatedImageAttributes(md)
+	if err != nil {
+		return nil, err
+	}
+	imageVis, err := m.getVolumeImportSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name := md.GetName(); name != "" && name != imageVis.Name {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: fmt.Sprintf("image '%s' cannot be renamed", imageVis.Name)}
+	}
+	visBase := imageVis.DeepCopy()
+	if imageVis.Annotations == nil {
+		imageVis.Annotations = map[string]string{}
+	}
+	maps.Copy(imageVis.Annotations, attributes)
+	if err := m.client.Patch(ctx, imageVis, client.MergeFrom(visBase)); err != nil {
+		return nil, fmt.Errorf("update CDI VolumeImportSource attributes of image '%s': %w", imageVis.Name, err)
+	}
+	return m.softwareImage(ctx, id, imageVis)
+}
+
+// SetupImageUpload registers an image whose content is uploaded through the CDI
+// upload proxy. The image is a VolumeImportSource labelled as uploaded, with a
+// blank source, and an upload-target DataVolume; the returned token authorizes
+// one upload to that DataVolume.
+func (m *cdiManager) SetupImageUpload(ctx context.Context, req *image.UploadRequest) (*image.UploadTarget, error) {
+	if req == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "request", Reason: "can't be nil"}
+	}
+	if req.Name == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "can't be empty"}
+	}
+	if m.cfg.UploadProxyUrl == nil || *m.cfg.UploadProxyUrl == "" {
+		return nil, fmt.Errorf("image upload without image.uploadProxyUrl configured: %w", apperrors.ErrUnsupported)
+	}
+	uploadUrl, err := url.JoinPath(*m.cfg.UploadProxyUrl, cdiUploadPath)
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "config image.uploadProxyUrl", Reason: err.Error()}
+	}
+	volumeImportSource := &v1beta1.VolumeImportSource{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: *m.k8sCfg.Namespace,
+			Labels: map[string]string{
+				common.K8sManagedByLabel: common.KubeNfvName,
+				image.K8sIsUploadLabel:   "true",
+			},
+		},
+		Spec: v1beta1.VolumeImportSourceSpec{
+			Source: &v1beta1.ImportSourceType{
+				Blank: &v1beta1.DataVolumeBlankImage{},
+			},
+		},
+	}
+	if err := m.client.Create(ctx, volumeImportSource); err != nil {
+		return nil, fmt.Errorf("create CDI VolumeImportSource: %w", err)
+	}
+	cleanupCtx := context.WithoutCancel(ctx)
+	cleanup := func() {
+		m.client.Delete(cleanupCtx, &v1beta1.DataVolume{
+			ObjectMeta: v1.ObjectMeta{Name: req.Name, Namespace: *m.k8sCfg.Namespace},
+		})
+		m.client.Delete(cleanupCtx, &v1beta1.VolumeImportSource{
+			ObjectMeta: v1.ObjectMeta{Name: req.Name, Namespace: *m.k8sCfg.Namespace},
+		})
+	}
+	storageClassName := *m.cfg.StorageClass
+	if req.StorageClass != "" {
+		storageClassName = req.StorageClass
+	}
+	if err := m.createImageDataVolume(ctx, volumeImportSource, storageClassName, req.StorageSize, imageSize{}); err != nil {
+		cleanup()
+		return nil, err
+	}
+	tokenReq := &uploadv1beta1.UploadTokenRequest{
+		ObjectMeta: v1.ObjectMeta{Name: req.Name, Namespace: *m.k8sCfg.Namespace},
+		Spec:       uploadv1beta1.UploadTokenRequestSpec{PvcName: req.Name},
+	}
+	if err := m.client.Create(ctx, tokenReq); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("request CDI upload token for image '%s': %w", req.Name, err)
+	}
+	if tokenReq.Status.Token == "" {
+		cleanup()
+		return nil, fmt.Errorf("CDI returned an empty upload token for image '%s'", req.Name)
+	}
+	return &image.UploadTarget{
+		ImageId: imageIdentifier(volumeImportSource),
+		Url:     uploadUrl,
+		Token:   tokenReq.Status.Token,
+	}, nil
+}
+
+// createImageDataVolume creates the DataVolume backing the image of vis and
+// labels vis with its id. The DataVolume is populated from vis, or is an
+// upload target when vis is labelled as uploaded. It is sized storageSize if
+// given, else from the discovered image size, else defaultImageSize. The
+// DataVolume is removed again on failure; vis is left to the caller.
+func (m *cdiManager) createImageDataVolume(ctx context.Context, vis *v1beta1.VolumeImportSource, storageClassName, storageSize string, discovered imageSize) error {
+	imgName := vis.Name
+	upload := vis.Labels[image.K8sIsUploadLabel] == "true"
 	storageClass, err := getStorageClass(ctx, storageClassName, m.apiReader)
 	if err != nil {
-		cleanupVolumeImportSource()
-		return nil, fmt.Errorf("get storageClass: %w", err)
+		return fmt.Errorf("get storageClass: %w", err)
+	}
+	// The DataVolume names the resolved class: boot volumes are cloned in it.
+	storageClassName = storageClass.Name
+	settings, err := m.storageSettings(ctx, storageClassName)
+	if err != nil {
+		return err
+	}
+	// The clone settings are kube-vim bookkeeping, which CDI does not read: they
+	// are returned as image metadata, and computes clone their boot volume with
+	// the clone access mode.
+	dvAnnotations := map[string]string{
+		image.K8sImageCloneAccessModeMetadataKey: string(settings.cloneAccessMode),
+	}
+	if settings.cloneStrategy != "" {
+		dvAnnotations[image.K8sImageCloneStrategyMetadataKey] = string(settings.cloneStrategy)
 	}
-	dvAnnotations := make(map[string]string)
 	if storageClass.VolumeBindingMode != nil && *storageClass.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
 		dvAnnotations["cdi.kubevirt.io/storage.bind.immediate.requested"] = "true"
 	}
 
-	imageSize := defaultImageSize
-	// TODO: Add ImageSize pre-population.
-	if req.Options != nil && req.Options.StorageSize != nil {
-		reqSize, err := resource.ParseQuantity(*req.Options.StorageSize)
+	dvSize := defaultImageSize
+	if storageSize != "" {
+		reqSize, err := resource.ParseQuantity(storageSize)
 		if err == nil {
-			imageSize = reqSize
+			dvSize = reqSize
 		}
+		if discovered.virtual > 0 && dvSize.Value() < discovered.virtual {
+			return &apperrors.ErrInvalidArgument{
+				Field:  "storageSize",
+				Reason: fmt.Sprintf("can't be less than the image virtual size %s", resource.NewQuantity(discovered.virtual, resource.BinarySI)),
+			}
+		}
+	} else if discovered.virtual > 0 || discovered.compressed > 0 {
+		// Block volumes have no filesystem to reserve space for.
+		overhead := 0.0
+		if settings.volumeMode == nil || *settings.volumeMode != corev1.PersistentVolumeBlock {
+			if overhead, err = m.filesystemOverhead(ctx, storageClassName); err != nil {
+				return fmt.Errorf("get filesystem overhead of storageClass '%s': %w", storageClassName, err)
+			}
+		}
+		dvSize = requiredStorage(discovered, overhead)
 	}
-	imageSize.Format = resource.BinarySI
+	dvSize.Format = resource.BinarySI
 
 	dataVolume := v1beta1.DataVolume{
 		ObjectMeta: v1.ObjectMeta{
 			Name: imgName,
 			Labels: map[string]string{
 				common.K8sManagedByLabel: common.KubeNfvName,
-				image.K8sImageIdLabel:    string(volumeImportSource.GetUID()),
+				image.K8sImageIdLabel:    imageIdentifier(vis).GetValue(),
 			},
 			Annotations: dvAnnotations,
 		},
 		Spec: v1beta1.DataVolumeSpec{
 			Storage: &v1beta1.StorageSpec{
-				DataSourceRef: &corev1.TypedObjectReference{
-					APIGroup: &v1beta1.CDIGroupVersionKind.Group,
-					Kind:     CDIVolumeImportSourceKind,
-					Name:     imgName,
-				},
-				AccessModes: []corev1.PersistentVolumeAccessMode{
-					// TODO: Temporary solution to make it works with ReadWriteOnce sc.
-					// Need to make it ReadOnlyMany since it is golden volume.
-					corev1.ReadWriteOnce,
-				},
+				AccessModes: []corev1.PersistentVolumeAccessMode{settings.goldenAccessMode},
+				VolumeMode:  settings.volumeMode,
 				Resources: corev1.VolumeResourceRequirements{
 					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: imageSize,
+						corev1.ResourceStorage: dvSize,
 					},
 				},
 				StorageClassName: &storageClassName,
 			},
 		},
 	}
+	if upload {
+		dataVolume.Labels[image.K8sIsUploadLabel] = "true"
+		dataVolume.Spec.Source = &v1beta1.DataVolumeSource{Upload: &v1beta1.DataVolumeSourceUpload{}}
+	} else {
+		dataVolume.Spec.Storage.DataSourceRef = &corev1.TypedObjectReference{
+			APIGroup: &v1beta1.CDIGroupVersionKind.Group,
+			Kind:     CDIVolumeImportSourceKind,
+			Name:     imgName,
+		}
+	}
 	dataVolume.Namespace = *m.k8sCfg.Namespace
 	if err := m.client.Create(ctx, &dataVolume); err != nil {
-		cleanupVolumeImportSource()
-		return nil, fmt.Errorf("create CDI DataVolume for image '%s': %w", imgName, err)
-	}
-	cleanupDataVolume := func() error {
-		cleanupVolumeImportSource()
-		return m.client.Delete(cleanupCtx, &v1beta1.DataVolume{
-			ObjectMeta: v1.ObjectMeta{Name: imgName, Namespace: *m.k8sCfg.Namespace},
-		})
+		return fmt.Errorf("create CDI DataVolume for image '%s': %w", imgName, err)
 	}
 
 	// Label the VolumeImportSource with the DataVolume id. Patch (not Update) to
 	// avoid a resourceVersion conflict on the object we just created.
-	visBase := volumeImportSource.DeepCopy()
-	volumeImportSource.Labels[K8sDataVolumeIdLabel] = string(dataVolume.GetUID())
-	if err := m.client.Patch(ctx, volumeImportSource, client.MergeFrom(visBase)); err != nil {
-		cleanupDataVolume()
-		return nil, fmt.Errorf("update CDI VolumeImportSource label for image '%s': %w", imgName, err)
+	visBase := vis.DeepCopy()
+	vis.Labels[K8sDataVolumeIdLabel] = string(dataVolume.GetUID())
+	if err := m.client.Patch(ctx, vis, client.MergeFrom(visBase)); err != nil {
+		m.client.Delete(context.WithoutCancel(ctx), &v1beta1.DataVolume{
+			ObjectMeta: v1.ObjectMeta{Name: imgName, Namespace: *m.k8sCfg.Namespace},
+		})
+		return fmt.Errorf("update CDI VolumeImportSource label for image '%s': %w", imgName, err)
 	}
-
-	return &admin.DownloadImageResponse{
-		ImageId: imageId,
-	}, nil
+	return nil
 }
 
-func (m *cdiManager) GetImageDownloadStatus(ctx context.Context, req *admin.GetImageDownloadStatusRequest) (*admin.GetImageDownloadStatusResponse, error) {
-	if req == nil {
-		return nil, &apperrors.ErrInvalidArgument{Field: "request", Reason: "can't be nil"}
+// getVolumeImportSource returns the VolumeImportSource representing the image,
+// found by its image id or its UID.
+func (m *cdiManager) getVolumeImportSource(ctx context.Context, id *nfvcommon.Identifier) (*v1beta1.VolumeImportSource, error) {
+	ns := client.InNamespace(*m.k8sCfg.Namespace)
+	managed := client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}
+	visList := &v1beta1.VolumeImportSourceList{}
+	if err := m.client.List(ctx, visList, ns, managed); err != nil {
+		return nil, fmt.Errorf("list CDI VolumeImportSources: %w", err)
 	}
-	return nil, nil
-}
-
-func (m *cdiManager) SetupImageUploadProxy(ctx context.Context, req *admin.SetupImageUploadProxyRequest) (*admin.SetupImageUploadProxyResponse, error) {
-	return nil, nil
+	for idx := range visList.Items {
+		if imageIdentifier(&visList.Items[idx]).GetValue() == id.GetValue() || misc.IdentifierToUID(id) == visList.Items[idx].GetUID() {
+			return &visList.Items[idx], nil
+		}
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "software image", Identifier: id.Value}
 }
@@ -8,16 +8,24 @@ import (
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
 	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s"
 	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/misc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	uploadv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 const testNamespace = k8stest.TestNamespace
@@ -27,7 +35,8 @@ func newManagerWithSC(t *testing.T, storageClass string, objs ...client.Object)
 	cl := k8stest.NewClient(t, objs...)
 	ns := testNamespace
 	sc := storageClass
-	m, err := NewCDIImageManager(cl, cl, &config.ImageConfig{StorageClass: &sc}, &config.K8sConfig{Namespace: &ns})
+	verifier := "kube-vim:test"
+	m, err := NewCDIImageManager(cl, cl, &config.ImageConfig{StorageClass: &sc, ChecksumVerifierImage: &verifier}, &config.K8sConfig{Namespace: &ns}, nil)
 	require.NoError(t, err)
 	return m, cl
 }
@@ -160,6 +169,145 @@ func TestDownloadImage(t *testing.T) {
 		assert.NotEmpty(t, vis.Labels[K8sDataVolumeIdLabel], "vis should be labelled with the data volume id")
 	})
 
+	t.Run("http credentials and CA are kept off the import source", func(t *testing.T) {
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		req := httpDownloadReq("img1")
+		req.Source.Http.Headers = map[string]string{"X-Api-Key": "k3y", "Authorization": "Bearer t0ken"}
+		req.Metadata.Metadata = &nfvcommon.Metadata{Fields: map[string]string{
+			image.SourceSecretRefMetadataKey:       "basic-auth",
+			image.SourceHeaderSecretRefMetadataKey: "vendor-token",
+			image.SourceCaBundleMetadataKey:        "-----BEGIN CERTIFICATE-----\n...",
+		}}
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		src := vis.Spec.Source.HTTP
+		require.NotNil(t, src)
+		assert.Empty(t, src.ExtraHeaders)
+		assert.Equal(t, "basic-auth", src.SecretRef)
+		assert.Equal(t, []string{"vendor-token", "img1-http-headers"}, src.SecretExtraHeaders)
+		assert.Equal(t, "img1-http-ca", src.CertConfigMap)
+
+		owner := []metav1.OwnerReference{{
+			APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: CDIVolumeImportSourceKind, Name: "img1", UID: vis.UID,
+		}}
+		secret := &corev1.Secret{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1-http-headers"}, secret))
+		assert.Equal(t, owner, secret.OwnerReferences)
+		assert.Equal(t, map[string]string{"header-0": "Authorization: Bearer t0ken", "header-1": "X-Api-Key: k3y"}, secret.StringData)
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1-http-ca"}, cm))
+		assert.Equal(t, owner, cm.OwnerReferences)
+		assert.Contains(t, cm.Data["ca.crt"], "BEGIN CERTIFICATE")
+	})
+
+	t.Run("http CA bundle and CA ConfigMap are exclusive", func(t *testing.T) {
+		m, cl := newManager(t)
+		req := httpDownloadReq("img1")
+		req.Metadata.Metadata = &nfvcommon.Metadata{Fields: map[string]string{
+			image.SourceCertConfigMapMetadataKey: "vendor-ca",
+			image.SourceCaBundleMetadataKey:      "-----BEGIN CERTIFICATE-----",
+		}}
+		_, err := m.DownloadImage(context.Background(), req)
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+		err = cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, &v1beta1.VolumeImportSource{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("registry source imports the OCI image with its credentials", func(t *testing.T) {
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		pullPolicy := admin.PullPolicy_IF_NOT_PRESENT
+		platform := "linux/arm64"
+		req := &admin.DownloadImageRequest{
+			Metadata: &admin.ImageMetadata{
+				Name: "img1",
+				Metadata: &nfvcommon.Metadata{Fields: map[string]string{
+					image.SourceSecretRefMetadataKey:     "vendor-pull",
+					image.SourceCertConfigMapMetadataKey: "vendor-ca",
+				}},
+			},
+			Source: &admin.ImageSource{
+				Type:     admin.ImageSourceType_REGISTRY,
+				Registry: &admin.RegistrySource{Image: "quay.io/vendor/vnf:1.0", PullPolicy: &pullPolicy, Platform: &platform},
+			},
+		}
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		reg := vis.Spec.Source.Registry
+		require.NotNil(t, reg)
+		assert.Equal(t, "docker://quay.io/vendor/vnf:1.0", *reg.URL)
+		assert.Equal(t, v1beta1.RegistryPullNode, *reg.PullMethod)
+		assert.Equal(t, "arm64", reg.Platform.Architecture)
+		assert.Equal(t, "vendor-pull", *reg.SecretRef)
+		assert.Equal(t, "vendor-ca", *reg.CertConfigMap)
+
+		got, err := m.GetImage(context.Background(), misc.UIDToIdentifier(vis.GetUID()))
+		require.NoError(t, err)
+		assert.Equal(t, string(image.Registry), got.GetMetadata().GetFields()[image.K8sSourceLabel])
+	})
+
+	t.Run("s3 source imports the object like an http image", func(t *testing.T) {
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		region := "eu-west-1"
+		req := &admin.DownloadImageRequest{
+			Metadata: &admin.ImageMetadata{
+				Name: "img1",
+				Metadata: &nfvcommon.Metadata{Fields: map[string]string{
+					image.SourceSecretRefMetadataKey:     "minio-creds",
+					image.SourceCertConfigMapMetadataKey: "minio-ca",
+				}},
+			},
+			Source: &admin.ImageSource{
+				Type: admin.ImageSourceType_S3,
+				S3:   &admin.S3Source{Endpoint: "http://minio.local:9000", Bucket: "images", Key: "vendor/vnf 1.0.qcow2", Region: &region},
+			},
+		}
+		resp, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		require.NotNil(t, vis.Spec.Source.S3)
+		assert.Equal(t, "http://minio.local:9000/images/vendor/vnf%201.0.qcow2", vis.Spec.Source.S3.URL)
+		assert.Equal(t, "minio-creds", vis.Spec.Source.S3.SecretRef)
+		assert.Equal(t, "minio-ca", vis.Spec.Source.S3.CertConfigMap)
+		assert.NotEmpty(t, vis.Labels[K8sDataVolumeIdLabel])
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, &v1beta1.DataVolume{}))
+
+		got, err := m.GetImage(context.Background(), resp.ImageId)
+		require.NoError(t, err)
+		assert.Equal(t, string(image.S3), got.GetMetadata().GetFields()[image.K8sSourceLabel])
+	})
+
+	t.Run("s3 source defaults to https and rejects virtual-hosted style", func(t *testing.T) {
+		src := &admin.S3Source{Endpoint: "minio.local", Bucket: "images", Key: "vnf.qcow2"}
+		res, err := convertToS3DataVolumeSource(src, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://minio.local/images/vnf.qcow2", res.URL)
+
+		pathStyle := false
+		src.PathStyle = &pathStyle
+		_, err = convertToS3DataVolumeSource(src, nil)
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+
+	t.Run("registry source without image is rejected", func(t *testing.T) {
+		m, _ := newManager(t)
+		req := &admin.DownloadImageRequest{
+			Metadata: &admin.ImageMetadata{Name: "img1"},
+			Source:   &admin.ImageSource{Type: admin.ImageSourceType_REGISTRY, Registry: &admin.RegistrySource{}},
+		}
+		_, err := m.DownloadImage(context.Background(), req)
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+
 	t.Run("unsupported source type is rejected and rolls back", func(t *testing.T) {
 		m, cl := newManager(t)
 		req := httpDownloadReq("img1")
@@ -172,6 +320,37 @@ func TestDownloadImage(t *testing.T) {
 		assert.True(t, apierrors.IsNotFound(err))
 	})
 
+	t.Run("catalog image is identified by its catalog id", func(t *testing.T) {
+		ctx := context.Background()
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		catalogId := "7d1e5c9a-3f4b-5a6c-8d7e-9f0a1b2c3d4e"
+		req := httpDownloadReq(catalogId)
+		req.Metadata.Metadata = &nfvcommon.Metadata{Fields: map[string]string{
+			image.K8sCatalogImageIdLabel:       catalogId,
+			image.K8sImageCatalogMetadataKey:   "glance",
+			image.K8sImageCatalogIdMetadataKey: "ubuntu",
+		}}
+		resp, err := m.DownloadImage(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, catalogId, resp.ImageId.GetValue())
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: catalogId}, vis))
+		assert.Equal(t, catalogId, vis.Labels[image.K8sCatalogImageIdLabel])
+
+		got, err := m.GetImage(ctx, &nfvcommon.Identifier{Value: catalogId})
+		require.NoError(t, err)
+		assert.Equal(t, catalogId, got.SoftwareImageId.GetValue())
+		assert.Equal(t, "glance", got.Metadata.GetFields()[image.K8sImageCatalogMetadataKey])
+		assert.Equal(t, "ubuntu", got.Metadata.GetFields()[image.K8sImageCatalogIdMetadataKey])
+
+		req = httpDownloadReq("img2")
+		req.Metadata.Metadata = &nfvcommon.Metadata{Fields: map[string]string{image.K8sCatalogImageIdLabel: "ubuntu"}}
+		_, err = m.DownloadImage(ctx, req)
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target, "the catalog id must be a UUID")
+	})
+
 	t.Run("missing storage class rolls back the import source", func(t *testing.T) {
 		m, cl := newManagerWithSC(t, "ghost")
 		_, err := m.DownloadImage(context.Background(), httpDownloadReq("img1"))
@@ -180,3 +359,571 @@ func TestDownloadImage(t *testing.T) {
 		assert.True(t, apierrors.IsNotFound(err), "import source must be cleaned up when the data volume cannot be created")
 	})
 }
+
+// seedChecksumVis is seedVis with an expected sha256 checksum to verify.
+func seedChecksumVis(name string) *v1beta1.VolumeImportSource {
+	vis := seedVis(name)
+	vis.Annotations = map[string]string{
+		image.K8sImageChecksumAnnotation:          "abc123",
+		image.K8sImageChecksumAlgorithmAnnotation: "sha256",
+		image.K8sImageChecksumVerifyAnnotation:    "true",
+		image.K8sImageVirtualSizeAnnotation:       "1048576",
+	}
+	return vis
+}
+
+func seedChecksumJob(name string, cond batchv1.JobConditionType, message string) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: k8stest.ManagedMeta(checksumJobName(name))}
+	job.Namespace = testNamespace
+	if cond != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: cond, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: message}}
+	}
+	return job
+}
+
+func TestImageChecksumAndAttributes(t *testing.T) {
+	t.Parallel()
+	t.Run("download persists the attributes and records an unverifiable checksum", func(t *testing.T) {
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		req := httpDownloadReq("img1")
+		req.Source.Http.Url = "https://example.com/vnf-1.0.qcow2.xz?sig=1"
+		req.Source.Http.Checksum = &admin.Checksum{Algorithm: "SHA256", Value: "ABC123"}
+		req.Metadata.Provider = k8stest.Ptr("vendor")
+		req.Metadata.Version = k8stest.Ptr("1.0")
+		req.Metadata.Metadata = &nfvcommon.Metadata{Fields: map[string]string{
+			image.K8sImageMinDiskAnnotation: "20Gi",
+			image.K8sImageMinRamAnnotation:  "2Gi",
+		}}
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		assert.Equal(t, map[string]string{
+			image.K8sImageChecksumAnnotation:          "abc123",
+			image.K8sImageChecksumAlgorithmAnnotation: "sha256",
+			image.K8sImageProviderAnnotation:          "vendor",
+			image.K8sImageVersionAnnotation:           "1.0",
+			image.K8sImageMinDiskAnnotation:           "20Gi",
+			image.K8sImageMinRamAnnotation:            "2Gi",
+			image.K8sImageDiskFormatAnnotation:        "qcow2",
+			image.K8sImageContainerFormatAnnotation:   "bare",
+		}, vis.Annotations)
+
+		// The xz-compressed qcow2 source differs from the imported raw disk.
+		err = cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1-checksum"}, &batchv1.Job{})
+		assert.True(t, apierrors.IsNotFound(err))
+
+		got, err := m.GetImage(context.Background(), misc.UIDToIdentifier(vis.UID))
+		require.NoError(t, err)
+		assert.Equal(t, image.StatusImporting, got.GetStatus())
+		assert.Equal(t, "abc123", got.GetChecksum())
+		assert.Equal(t, "sha256", got.GetMetadata().GetFields()[image.K8sImageChecksumAlgorithmAnnotation])
+		assert.Equal(t, "vendor", got.GetProvider())
+		assert.Equal(t, "qcow2", got.GetDiskFormat())
+		assert.Equal(t, "20Gi", got.GetMinDisk().String())
+		assert.Equal(t, "2Gi", got.GetMinRam().String())
+	})
+
+	t.Run("validateChecksum false keeps the checksum but skips the job", func(t *testing.T) {
+		m, cl := newManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		req := httpDownloadReq("img1")
+		req.Source.Http.Checksum = &admin.Checksum{Algorithm: "md5", Value: "abc123"}
+		req.Options = &admin.DownloadOptions{ValidateChecksum: k8stest.Ptr(false)}
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+		err = cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1-checksum"}, &batchv1.Job{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("invalid checksum and min disk are rejected", func(t *testing.T) {
+		m, _ := newManager(t)
+		req := httpDownloadReq("img1")
+		req.Source.Http.Checksum = &admin.Checksum{Algorithm: "crc32", Value: "abc123"}
+		_, err := m.DownloadImage(context.Background(), req)
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+
+		req = httpDownloadReq("img1")
+		req.Metadata.Metadata = &nfvcommon.Metadata{Fields: map[string]string{image.K8sImageMinDiskAnnotation: "lots"}}
+		_, err = m.DownloadImage(context.Background(), req)
+		assert.ErrorAs(t, err, &target)
+	})
+
+	t.Run("image is verifying until its checksum job starts", func(t *testing.T) {
+		m, _ := newManager(t, seedChecksumVis("img1"), seedDv("img1"))
+		got, err := m.GetImage(context.Background(), k8stest.ID("uid-img1"))
+		require.NoError(t, err)
+		assert.Equal(t, image.StatusVerifying, got.GetStatus())
+	})
+
+	t.Run("image status follows the checksum job", func(t *testing.T) {
+		for cond, want := range map[batchv1.JobConditionType]string{
+			"":                  image.StatusVerifying,
+			batchv1.JobComplete: image.StatusReady,
+			batchv1.JobFailed:   image.StatusFailed,
+		} {
+			m, _ := newManager(t, seedChecksumVis("img1"), seedDv("img1"), seedChecksumJob("img1", cond, ""))
+			got, err := m.GetImage(context.Background(), k8stest.ID("uid-img1"))
+			require.NoError(t, err)
+			assert.Equal(t, want, got.GetStatus(), cond)
+
+			list, err := m.ListImages(context.Background())
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			assert.Equal(t, want, list[0].GetStatus(), cond)
+		}
+	})
+
+	t.Run("image is ready only once its data volume succeeded", func(t *testing.T) {
+		for phase, want := range map[v1beta1.DataVolumePhase]string{
+			v1beta1.PhaseUnset:       image.StatusImporting,
+			v1beta1.Pending:          image.StatusImporting,
+			v1beta1.ImportInProgress: image.StatusImporting,
+			v1beta1.UploadReady:      image.StatusImporting,
+			v1beta1.Failed:           image.StatusFailed,
+			v1beta1.Succeeded:        image.StatusReady,
+		} {
+			dv := seedDv("img1")
+			dv.Status.Phase = phase
+			m, _ := newManager(t, seedVis("img1"), dv)
+			got, err := m.GetImage(context.Background(), k8stest.ID("uid-img1"))
+			require.NoError(t, err)
+			assert.Equal(t, want, got.GetStatus(), phase)
+		}
+	})
+
+	t.Run("download status reports verification and mismatch", func(t *testing.T) {
+		m, _ := newManager(t, seedChecksumVis("img1"), seedDv("img1"), seedChecksumJob("img1", "", ""))
+		res, err := m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{ImageId: k8stest.ID("uid-img1")})
+		require.NoError(t, err)
+		assert.Equal(t, admin.DownloadState_DOWNLOADING, res.GetStatus().GetState())
+		assert.Contains(t, res.GetStatus().GetMessage(), "verifying checksum")
+
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "img1-checksum-x", Namespace: testNamespace, Labels: map[string]string{batchv1.JobNameLabel: "img1-checksum"},
+		}}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			ExitCode: 1, Message: "checksum mismatch: expected abc123, got def456\n",
+		}}}}
+		m, _ = newManager(t, seedChecksumVis("img1"), seedDv("img1"), seedChecksumJob("img1", batchv1.JobFailed, "Job has reached the specified backoff limit"), pod)
+		res, err = m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{ImageId: k8stest.ID("uid-img1")})
+		require.NoError(t, err)
+		assert.Equal(t, admin.DownloadState_FAILED, res.GetStatus().GetState())
+		assert.Equal(t, "checksum verification failed: checksum mismatch: expected abc123, got def456", res.GetStatus().GetErrorMessage())
+	})
+}
+
+func TestGetImageDownloadStatus(t *testing.T) {
+	t.Parallel()
+	statusOf := func(t *testing.T, objs ...client.Object) *admin.ImageDownloadStatus {
+		t.Helper()
+		m, _ := newManager(t, objs...)
+		res, err := m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{
+			ImageId: &nfvcommon.Identifier{Value: "uid-img1"},
+		})
+		require.NoError(t, err)
+		return res.GetStatus()
+	}
+	withStatus := func(status v1beta1.DataVolumeStatus) *v1beta1.DataVolume {
+		dv := seedDv("img1")
+		dv.Status = status
+		return dv
+	}
+
+	t.Run("import in progress reports the percentage", func(t *testing.T) {
+		got := statusOf(t, seedVis("img1"), withStatus(v1beta1.DataVolumeStatus{
+			Phase:    v1beta1.ImportInProgress,
+			Progress: "45.70%",
+		}))
+		assert.Equal(t, admin.DownloadState_DOWNLOADING, got.GetState())
+		assert.Equal(t, int32(45), got.GetProgressPercentage())
+		assert.Equal(t, "uid-img1", got.GetImageId().GetValue())
+		assert.Nil(t, got.ErrorMessage)
+	})
+
+	t.Run("importer restarts surface the running error", func(t *testing.T) {
+		got := statusOf(t, seedVis("img1"), withStatus(v1beta1.DataVolumeStatus{
+			Phase:        v1beta1.ImportInProgress,
+			Progress:     "N/A",
+			RestartCount: 3,
+			Conditions: []v1beta1.DataVolumeCondition{{
+				Type:    v1beta1.DataVolumeRunning,
+				Status:  corev1.ConditionFalse,
+				Reason:  "Error",
+				Message: "Unable to connect to http data source",
+			}},
+		}))
+		assert.Equal(t, admin.DownloadState_DOWNLOADING, got.GetState())
+		assert.Nil(t, got.ProgressPercentage)
+		assert.Equal(t, "Unable to connect to http data source", got.GetErrorMessage())
+		assert.Equal(t, "3", got.GetMetadata().GetFields()[K8sDataVolumeRestarts])
+		assert.Contains(t, got.GetMessage(), "restarted 3 times")
+	})
+
+	t.Run("pending phases are pending", func(t *testing.T) {
+		for _, phase := range []v1beta1.DataVolumePhase{v1beta1.PhaseUnset, v1beta1.Pending, v1beta1.ImportScheduled, v1beta1.WaitForFirstConsumer} {
+			got := statusOf(t, seedVis("img1"), withStatus(v1beta1.DataVolumeStatus{Phase: phase}))
+			assert.Equal(t, admin.DownloadState_PENDING, got.GetState(), phase)
+		}
+	})
+
+	t.Run("succeeded is completed", func(t *testing.T) {
+		done := metav1.Now()
+		got := statusOf(t, seedVis("img1"), withStatus(v1beta1.DataVolumeStatus{
+			Phase:    v1beta1.Succeeded,
+			Progress: "100.0%",
+			Conditions: []v1beta1.DataVolumeCondition{{
+				Type:               v1beta1.DataVolumeReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: done,
+			}},
+		}))
+		assert.Equal(t, admin.DownloadState_COMPLETED, got.GetState())
+		assert.Equal(t, int32(100), got.GetProgressPercentage())
+		assert.Equal(t, done.Unix(), got.GetCompletedAt().GetSeconds())
+	})
+
+	t.Run("failed reports the reason", func(t *testing.T) {
+		got := statusOf(t, seedVis("img1"), withStatus(v1beta1.DataVolumeStatus{
+			Phase: v1beta1.Failed,
+			Conditions: []v1beta1.DataVolumeCondition{
+				{Type: v1beta1.DataVolumeRunning, Status: corev1.ConditionFalse, Reason: "ImportFailed"},
+				{Type: v1beta1.DataVolumeReady, Status: corev1.ConditionFalse, Message: "invalid image format"},
+			},
+		}))
+		assert.Equal(t, admin.DownloadState_FAILED, got.GetState())
+		assert.Equal(t, "invalid image format", got.GetErrorMessage())
+	})
+
+	t.Run("lazy image without data volume is lazy registered", func(t *testing.T) {
+		got := statusOf(t, seedVis("img1"))
+		assert.Equal(t, admin.DownloadState_LAZY_REGISTERED, got.GetState())
+		assert.Equal(t, "uid-img1", got.GetImageId().GetValue())
+	})
+
+	t.Run("deleted data volume is ErrDataVolumeNotFound", func(t *testing.T) {
+		vis := seedVis("img1")
+		vis.Labels[K8sDataVolumeIdLabel] = "uid-dv"
+		m, _ := newManager(t, vis)
+		_, err := m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{
+			ImageId: &nfvcommon.Identifier{Value: "uid-img1"},
+		})
+		var target *image.ErrDataVolumeNotFound
+		assert.ErrorAs(t, err, &target)
+	})
+
+	t.Run("missing image is ErrNotFound", func(t *testing.T) {
+		m, _ := newManager(t)
+		_, err := m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{
+			ImageId: &nfvcommon.Identifier{Value: "uid-nope"},
+		})
+		var target *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &target)
+	})
+
+	t.Run("nil image id is rejected", func(t *testing.T) {
+		m, _ := newManager(t)
+		_, err := m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{})
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+}
+
+// newUploadManager is newManager with an upload proxy configured and a client
+// that answers UploadTokenRequests like the CDI apiserver: the token is set on
+// the returned object and nothing is stored.
+func storageProfile(name string, strategy v1beta1.CDICloneStrategy, sets ...v1beta1.ClaimPropertySet) *v1beta1.StorageProfile {
+	profile := &v1beta1.StorageProfile{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	profile.Status.ClaimPropertySets = sets
+	if strategy != "" {
+		profile.Status.CloneStrategy = &strategy
+	}
+	return profile
+}
+
+func claimSet(volumeMode corev1.PersistentVolumeMode, modes ...corev1.PersistentVolumeAccessMode) v1beta1.ClaimPropertySet {
+	return v1beta1.ClaimPropertySet{AccessModes: modes, VolumeMode: &volumeMode}
+}
+
+func TestImageStorageSettings(t *testing.T) {
+	t.Parallel()
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}}
+	getDv := func(t *testing.T, cl client.Client) *v1beta1.DataVolume {
+		dv := &v1beta1.DataVolume{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, dv))
+		return dv
+	}
+
+	t.Run("golden image is ReadOnlyMany where the storage profile offers it", func(t *testing.T) {
+		profile := storageProfile("fast", v1beta1.CloneStrategyCsiClone,
+			claimSet(corev1.PersistentVolumeFilesystem, corev1.ReadWriteOnce),
+			claimSet(corev1.PersistentVolumeBlock, corev1.ReadWriteMany, corev1.ReadOnlyMany),
+		)
+		m, cl := newManager(t, sc, profile)
+		_, err := m.DownloadImage(context.Background(), httpDownloadReq("img1"))
+		require.NoError(t, err)
+
+		dv := getDv(t, cl)
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany}, dv.Spec.Storage.AccessModes)
+		assert.Equal(t, corev1.PersistentVolumeBlock, *dv.Spec.Storage.VolumeMode)
+		assert.Equal(t, "ReadWriteMany", dv.Annotations[image.K8sImageCloneAccessModeMetadataKey])
+		assert.Equal(t, "csi-clone", dv.Annotations[image.K8sImageCloneStrategyMetadataKey])
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		got, err := m.GetImage(context.Background(), misc.UIDToIdentifier(vis.UID))
+		require.NoError(t, err)
+		fields := got.GetMetadata().GetFields()
+		assert.Equal(t, "fast", fields[image.K8sImageStorageClassMetadataKey])
+		assert.Equal(t, "Block", fields[image.K8sImageVolumeModeMetadataKey])
+		assert.Equal(t, "ReadWriteMany", fields[image.K8sImageCloneAccessModeMetadataKey])
+	})
+
+	t.Run("without a storage profile the image is ReadWriteOnce", func(t *testing.T) {
+		m, cl := newManager(t, sc)
+		_, err := m.DownloadImage(context.Background(), httpDownloadReq("img1"))
+		require.NoError(t, err)
+		dv := getDv(t, cl)
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, dv.Spec.Storage.AccessModes)
+		assert.Nil(t, dv.Spec.Storage.VolumeMode)
+		assert.Equal(t, "ReadWriteOnce", dv.Annotations[image.K8sImageCloneAccessModeMetadataKey])
+		assert.NotContains(t, dv.Annotations, image.K8sImageCloneStrategyMetadataKey)
+	})
+
+	t.Run("configured settings override the profile", func(t *testing.T) {
+		profile := storageProfile("fast", v1beta1.CloneStrategyHostAssisted,
+			claimSet(corev1.PersistentVolumeFilesystem, corev1.ReadWriteMany),
+		)
+		m, cl := newManager(t, sc, profile)
+		accessMode := config.ImageStorageProfileConfigGoldenAccessModeReadWriteOnce
+		strategy := config.ImageStorageProfileConfigCloneStrategySnapshot
+		m.cfg.StorageProfiles = &map[string]config.ImageStorageProfileConfig{
+			"fast": {GoldenAccessMode: &accessMode, CloneStrategy: &strategy},
+		}
+		_, err := m.DownloadImage(context.Background(), httpDownloadReq("img1"))
+		require.NoError(t, err)
+
+		dv := getDv(t, cl)
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, dv.Spec.Storage.AccessModes)
+		assert.Equal(t, "snapshot", dv.Annotations[image.K8sImageCloneStrategyMetadataKey])
+		// The profile is only written at startup.
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "fast"}, profile))
+		assert.Nil(t, profile.Spec.CloneStrategy)
+	})
+}
+
+func TestEnsureStorageProfiles(t *testing.T) {
+	t.Parallel()
+	snapshot := config.ImageStorageProfileConfigCloneStrategySnapshot
+	newProfilesManager := func(t *testing.T, objs ...client.Object) (*cdiManager, client.Client) {
+		t.Helper()
+		m, cl := newManager(t, objs...)
+		accessMode := config.ImageStorageProfileConfigGoldenAccessModeReadWriteOnce
+		m.cfg.StorageProfiles = &map[string]config.ImageStorageProfileConfig{
+			"fast": {CloneStrategy: &snapshot},
+			"slow": {GoldenAccessMode: &accessMode},
+		}
+		return m, cl
+	}
+
+	t.Run("configured clone strategy is set on the profile", func(t *testing.T) {
+		m, cl := newProfilesManager(t, storageProfile("fast", v1beta1.CloneStrategyHostAssisted))
+		require.NoError(t, m.EnsureStorageProfiles(context.Background()))
+
+		profile := &v1beta1.StorageProfile{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "fast"}, profile))
+		assert.Equal(t, v1beta1.CloneStrategySnapshot, *profile.Spec.CloneStrategy)
+	})
+
+	t.Run("profile already set is left alone", func(t *testing.T) {
+		profile := storageProfile("fast", v1beta1.CloneStrategySnapshot)
+		strategy := v1beta1.CloneStrategySnapshot
+		profile.Spec.CloneStrategy = &strategy
+		m, cl := newProfilesManager(t, profile)
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "fast"}, profile))
+		resourceVersion := profile.ResourceVersion
+		require.NoError(t, m.EnsureStorageProfiles(context.Background()))
+
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "fast"}, profile))
+		assert.Equal(t, resourceVersion, profile.ResourceVersion)
+	})
+
+	t.Run("missing profile fails", func(t *testing.T) {
+		m, _ := newProfilesManager(t)
+		assert.ErrorContains(t, m.EnsureStorageProfiles(context.Background()), "get CDI StorageProfile 'fast'")
+	})
+}
+
+func TestDeleteImage(t *testing.T) {
+	t.Parallel()
+	headers := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "img1-http-headers", Namespace: testNamespace}}
+	vm := func(imageId string) *kubevirtv1.VirtualMachine {
+		return &kubevirtv1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{
+			Name: "vm1", Namespace: testNamespace, Labels: map[string]string{image.K8sImageIdLabel: imageId},
+		}}
+	}
+	assertGone := func(t *testing.T, cl client.Client, obj client.Object) {
+		t.Helper()
+		err := cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: obj.GetName()}, obj)
+		assert.True(t, apierrors.IsNotFound(err), "%s should be deleted", obj.GetName())
+	}
+
+	t.Run("removes the import source, data volume and source objects", func(t *testing.T) {
+		m, cl := newManager(t, seedVis("img1"), seedDv("img1"), headers.DeepCopy(), vm("uid-other"))
+		require.NoError(t, m.DeleteImage(context.Background(), k8stest.ID("uid-img1"), false))
+		assertGone(t, cl, &v1beta1.VolumeImportSource{ObjectMeta: metav1.ObjectMeta{Name: "img1"}})
+		assertGone(t, cl, &v1beta1.DataVolume{ObjectMeta: metav1.ObjectMeta{Name: "img1"}})
+		assertGone(t, cl, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "img1-http-headers"}})
+	})
+
+	t.Run("lazy image without data volume", func(t *testing.T) {
+		m, cl := newManager(t, seedVis("img1"))
+		require.NoError(t, m.DeleteImage(context.Background(), k8stest.ID("uid-img1"), false))
+		assertGone(t, cl, &v1beta1.VolumeImportSource{ObjectMeta: metav1.ObjectMeta{Name: "img1"}})
+	})
+
+	t.Run("image in use is refused", func(t *testing.T) {
+		m, cl := newManager(t, seedVis("img1"), seedDv("img1"), vm("uid-img1"))
+		err := m.DeleteImage(context.Background(), k8stest.ID("uid-img1"), false)
+		var target *apperrors.ErrFailedPrecondition
+		require.ErrorAs(t, err, &target)
+		assert.Contains(t, err.Error(), "vm1")
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, &v1beta1.VolumeImportSource{}))
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, &v1beta1.DataVolume{}))
+	})
+
+	t.Run("force deletes an image in use", func(t *testing.T) {
+		m, cl := newManager(t, seedVis("img1"), seedDv("img1"), vm("uid-img1"))
+		require.NoError(t, m.DeleteImage(context.Background(), k8stest.ID("uid-img1"), true))
+		assertGone(t, cl, &v1beta1.VolumeImportSource{ObjectMeta: metav1.ObjectMeta{Name: "img1"}})
+	})
+
+	t.Run("unknown image is ErrNotFound", func(t *testing.T) {
+		m, _ := newManager(t)
+		var target *apperrors.ErrNotFound
+		assert.ErrorAs(t, m.DeleteImage(context.Background(), k8stest.ID("uid-nope"), false), &target)
+	})
+}
+
+func TestUpdateImage(t *testing.T) {
+	t.Parallel()
+	ptr := func(s string) *string { return &s }
+	seedAttributed := func() *v1beta1.VolumeImportSource {
+		vis := seedVis("img1")
+		vis.Annotations = map[string]string{image.K8sImageProviderAnnotation: "acme", image.K8sImageVersionAnnotation: "1.0"}
+		return vis
+	}
+
+	t.Run("patches the given attributes", func(t *testing.T) {
+		m, cl := newManager(t, seedAttributed(), seedDv("img1"))
+		img, err := m.UpdateImage(context.Background(), k8stest.ID("uid-img1"), &admin.ImageMetadata{
+			Version:  ptr("2.0"),
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{image.K8sImageMinDiskAnnotation: "20Gi"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "acme", img.GetProvider())
+		assert.Equal(t, "2.0", img.GetVersion())
+		assert.Equal(t, "20Gi", img.GetMinDisk().String())
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		assert.Equal(t, "2.0", vis.Annotations[image.K8sImageVersionAnnotation])
+		assert.Equal(t, "20Gi", vis.Annotations[image.K8sImageMinDiskAnnotation])
+	})
+
+	for name, md := range map[string]*admin.ImageMetadata{
+		"rename":            {Name: "img2"},
+		"invalid min-ram":   {Metadata: &nfvcommon.Metadata{Fields: map[string]string{image.K8sImageMinRamAnnotation: "lots"}}},
+		"checksum":          {Metadata: &nfvcommon.Metadata{Fields: map[string]string{image.K8sImageChecksumAnnotation: "abc"}}},
+		"description":       {Description: ptr("boot image")},
+		"empty disk-format": {Metadata: &nfvcommon.Metadata{Fields: map[string]string{image.K8sImageDiskFormatAnnotation: ""}}},
+	} {
+		t.Run(name+" is rejected", func(t *testing.T) {
+			m, cl := newManager(t, seedAttributed(), seedDv("img1"))
+			_, err := m.UpdateImage(context.Background(), k8stest.ID("uid-img1"), md)
+			var target *apperrors.ErrInvalidArgument
+			require.ErrorAs(t, err, &target)
+
+			vis := &v1beta1.VolumeImportSource{}
+			require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+			assert.Equal(t, seedAttributed().Annotations, vis.Annotations)
+		})
+	}
+
+	t.Run("unknown image is ErrNotFound", func(t *testing.T) {
+		m, _ := newManager(t)
+		_, err := m.UpdateImage(context.Background(), k8stest.ID("uid-nope"), &admin.ImageMetadata{Version: ptr("2.0")})
+		var target *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &target)
+	})
+}
+
+func newUploadManager(t *testing.T, objs ...client.Object) (*cdiManager, client.Client) {
+	t.Helper()
+	scheme, err := k8s.BuildScheme()
+	require.NoError(t, err)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if tr, ok := obj.(*uploadv1beta1.UploadTokenRequest); ok {
+				tr.Status.Token = "token-" + tr.Spec.PvcName
+				return nil
+			}
+			return k8stest.AssignMetaOnCreate.Create(ctx, c, obj, opts...)
+		},
+	}).Build()
+	ns, sc, proxy := testNamespace, "fast", "https://cdi-uploadproxy.cdi.svc"
+	m, err := NewCDIImageManager(cl, cl, &config.ImageConfig{StorageClass: &sc, UploadProxyUrl: &proxy}, &config.K8sConfig{Namespace: &ns}, nil)
+	require.NoError(t, err)
+	return m, cl
+}
+
+func TestSetupImageUpload(t *testing.T) {
+	t.Parallel()
+	t.Run("creates an upload target and returns the token", func(t *testing.T) {
+		m, cl := newUploadManager(t, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+		target, err := m.SetupImageUpload(context.Background(), &image.UploadRequest{Name: "img1", StorageSize: "2Gi"})
+		require.NoError(t, err)
+		assert.Equal(t, "uid-img1", target.ImageId.GetValue())
+		assert.Equal(t, "https://cdi-uploadproxy.cdi.svc/v1beta1/upload", target.Url)
+		assert.Equal(t, "token-img1", target.Token)
+
+		dv := &v1beta1.DataVolume{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, dv))
+		require.NotNil(t, dv.Spec.Source)
+		assert.NotNil(t, dv.Spec.Source.Upload)
+		assert.Nil(t, dv.Spec.Storage.DataSourceRef)
+		assert.Equal(t, "true", dv.Labels[image.K8sIsUploadLabel])
+		assert.Equal(t, "2Gi", dv.Spec.Storage.Resources.Requests.Storage().String())
+
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		assert.Equal(t, "true", vis.Labels[image.K8sIsUploadLabel])
+		assert.Equal(t, string(dv.GetUID()), vis.Labels[K8sDataVolumeIdLabel])
+
+		got, err := m.GetImage(context.Background(), target.ImageId)
+		require.NoError(t, err)
+		assert.Equal(t, string(image.Upload), got.GetMetadata().GetFields()[image.K8sSourceLabel])
+	})
+
+	t.Run("missing storage class rolls back", func(t *testing.T) {
+		m, cl := newUploadManager(t)
+		_, err := m.SetupImageUpload(context.Background(), &image.UploadRequest{Name: "img1"})
+		require.Error(t, err)
+		err = cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, &v1beta1.VolumeImportSource{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("empty name is rejected", func(t *testing.T) {
+		m, _ := newUploadManager(t)
+		_, err := m.SetupImageUpload(context.Background(), &image.UploadRequest{})
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+
+	t.Run("unconfigured upload proxy is unsupported", func(t *testing.T) {
+		m, _ := newManager(t)
+		_, err := m.SetupImageUpload(context.Background(), &image.UploadRequest{Name: "img1"})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
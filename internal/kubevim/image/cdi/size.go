@@ -0,0 +1,484 @@
+package cdi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// sizeProbeBytes is how much of the source is read to find the image header.
+	sizeProbeBytes = 64 << 10
+	// cdiConfigName is the name of the cluster-wide CDIConfig.
+	cdiConfigName = "config"
+	// defaultFilesystemOverhead is the CDI default when CDIConfig sets none.
+	defaultFilesystemOverhead = 0.06
+	// storageAlignment is the granularity of the computed DataVolume size.
+	storageAlignment = 1 << 20
+)
+
+var (
+	qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+	vmdkMagic  = []byte("KDMV")
+	gzipMagic  = []byte{0x1f, 0x8b}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// imageSize is what size discovery learned about an image before its import.
+type imageSize struct {
+	// compressed is the size of the source as transferred, 0 if unknown.
+	compressed int64
+	// virtual is the size of the disk the image holds, 0 if unknown.
+	virtual int64
+}
+
+// sourceAccess is how kube-vim reaches an image source the way the CDI
+// importer does.
+type sourceAccess struct {
+	header   http.Header
+	user     string
+	password string
+	client   *http.Client
+}
+
+// sourceRefs names the credentials and CA of an image source. headers and
+// caBundle are the inline parts of an HTTP source: their Secret and ConfigMap
+// are created after the VolumeImportSource, so they are used as given.
+type sourceRefs struct {
+	secretRef     string
+	headerSecret  string
+	certConfigMap string
+	headers       map[string]string
+	caBundle      string
+}
+
+// discoverImageSize learns the size of the image behind source before CDI
+// imports it. HTTP and anonymous S3 sources are probed with a single ranged
+// GET, registry images by their manifest.
+func (m *cdiManager) discoverImageSize(ctx context.Context, req *admin.DownloadImageRequest, source *v1beta1.ImportSourceType) (imageSize, error) {
+	fields := req.GetMetadata().GetMetadata().GetFields()
+	refs := sourceRefs{
+		secretRef:     fields[image.SourceSecretRefMetadataKey],
+		certConfigMap: fields[image.SourceCertConfigMapMetadataKey],
+	}
+	switch {
+	case source.HTTP != nil:
+		refs.headerSecret = fields[image.SourceHeaderSecretRefMetadataKey]
+		refs.headers = req.GetSource().GetHttp().GetHeaders()
+		refs.caBundle = fields[image.SourceCaBundleMetadataKey]
+		access, err := m.sourceAccess(ctx, refs)
+		if err != nil {
+			return imageSize{}, err
+		}
+		return probeHTTPImageSize(ctx, access, source.HTTP.URL)
+	case source.S3 != nil:
+		if refs.secretRef != "" {
+			return imageSize{}, fmt.Errorf("size discovery of signed s3 requests: %w", apperrors.ErrUnsupported)
+		}
+		access, err := m.sourceAccess(ctx, refs)
+		if err != nil {
+			return imageSize{}, err
+		}
+		return probeHTTPImageSize(ctx, access, source.S3.URL)
+	case source.Registry != nil:
+		access, err := m.sourceAccess(ctx, refs)
+		if err != nil {
+			return imageSize{}, err
+		}
+		arch := runtime.GOARCH
+		if source.Registry.Platform != nil && source.Registry.Platform.Architecture != "" {
+			arch = source.Registry.Platform.Architecture
+		}
+		return registryImageSize(ctx, access, *source.Registry.URL, arch)
+	}
+	return imageSize{}, fmt.Errorf("size discovery of the image source: %w", apperrors.ErrUnsupported)
+}
+
+// sourceAccess reads the credentials and CA named by refs. The referenced
+// Secrets and ConfigMap are not kube-vim owned, so they are read through the
+// uncached reader.
+func (m *cdiManager) sourceAccess(ctx context.Context, refs sourceRefs) (*sourceAccess, error) {
+	ns := *m.k8sCfg.Namespace
+	res := &sourceAccess{header: http.Header{}}
+	if refs.secretRef != "" {
+		secret := &corev1.Secret{}
+		if err := m.apiReader.Get(ctx, client.ObjectKey{Namespace: ns, Name: refs.secretRef}, secret); err != nil {
+			return nil, fmt.Errorf("get source secret '%s': %w", refs.secretRef, err)
+		}
+		res.user, res.password = string(secret.Data["accessKeyId"]), string(secret.Data["secretKey"])
+	}
+	if refs.headerSecret != "" {
+		secret := &corev1.Secret{}
+		if err := m.apiReader.Get(ctx, client.ObjectKey{Namespace: ns, Name: refs.headerSecret}, secret); err != nil {
+			return nil, fmt.Errorf("get source header secret '%s': %w", refs.headerSecret, err)
+		}
+		for _, line := range secret.Data {
+			if hdr, value, ok := strings.Cut(string(line), ":"); ok {
+				res.header.Add(strings.TrimSpace(hdr), strings.TrimSpace(value))
+			}
+		}
+	}
+	for hdr, value := range refs.headers {
+		res.header.Set(hdr, value)
+	}
+
+	caPEM := []byte(refs.caBundle)
+	if refs.certConfigMap != "" {
+		cm := &corev1.ConfigMap{}
+		if err := m.apiReader.Get(ctx, client.ObjectKey{Namespace: ns, Name: refs.certConfigMap}, cm); err != nil {
+			return nil, fmt.Errorf("get source CA configmap '%s': %w", refs.certConfigMap, err)
+		}
+		for key, value := range cm.Data {
+			if strings.HasSuffix(key, ".crt") {
+				caPEM = append(caPEM, []byte(value+"\n")...)
+			}
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(caPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, &apperrors.ErrInvalidArgument{Field: "source CA", Reason: "holds no PEM certificate"}
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	res.client = &http.Client{Transport: transport}
+	return res, nil
+}
+
+func (a *sourceAccess) newRequest(ctx context.Context, method, rawUrl string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	for hdr, values := range a.header {
+		req.Header[hdr] = values
+	}
+	if a.user != "" {
+		req.SetBasicAuth(a.user, a.password)
+	}
+	return req, nil
+}
+
+// probeHTTPImageSize reads the first bytes of an HTTP image. The total size
+// comes from Content-Range, or from Content-Length if the server ignores the
+// range, and the virtual size from the image header.
+func probeHTTPImageSize(ctx context.Context, access *sourceAccess, rawUrl string) (imageSize, error) {
+	req, err := access.newRequest(ctx, http.MethodGet, rawUrl)
+	if err != nil {
+		return imageSize{}, fmt.Errorf("build request to '%s': %w", rawUrl, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", sizeProbeBytes-1))
+	resp, err := access.client.Do(req)
+	if err != nil {
+		return imageSize{}, fmt.Errorf("request '%s': %w", rawUrl, err)
+	}
+	defer resp.Body.Close()
+
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Content-Range: bytes 0-65535/<total>, the total may be "*".
+		if _, after, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			total, _ = strconv.ParseInt(after, 10, 64)
+		}
+	case http.StatusOK:
+		total = resp.ContentLength
+	default:
+		return imageSize{}, fmt.Errorf("request '%s' failed with status: %s", rawUrl, resp.Status)
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, sizeProbeBytes))
+	if err != nil {
+		return imageSize{}, fmt.Errorf("read '%s': %w", rawUrl, err)
+	}
+	res := imageSize{virtual: virtualSizeFromHeader(head, total)}
+	if total > 0 {
+		res.compressed = total
+	}
+	return res, nil
+}
+
+// virtualSizeFromHeader returns the virtual size of the disk image starting
+// with head, or 0 if it can't be told from the header. total is the size of the
+// whole file, which is the virtual size of a raw image.
+func virtualSizeFromHeader(head []byte, total int64) int64 {
+	switch {
+	case bytes.HasPrefix(head, qcow2Magic) && len(head) >= 32:
+		return int64(binary.BigEndian.Uint64(head[24:32]))
+	case bytes.HasPrefix(head, vmdkMagic) && len(head) >= 20:
+		// Capacity in 512-byte sectors.
+		return int64(binary.LittleEndian.Uint64(head[12:20])) * 512
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(head))
+		if err != nil {
+			return 0
+		}
+		inner, _ := io.ReadAll(io.LimitReader(zr, sizeProbeBytes))
+		// The decompressed size of a gzip raw image is not in its header.
+		return virtualSizeFromHeader(inner, 0)
+	case bytes.HasPrefix(head, xzMagic):
+		return 0
+	}
+	if total < 0 {
+		return 0
+	}
+	return total
+}
+
+// registryImageSize sums the layer sizes of the manifest of an image reference
+// (docker://[host/]repository[:tag|@digest]). The disk inside the layers is
+// compressed, so only the compressed size is known.
+func registryImageSize(ctx context.Context, access *sourceAccess, ref, arch string) (imageSize, error) {
+	if !strings.HasPrefix(ref, registryDockerScheme) {
+		return imageSize{}, fmt.Errorf("size discovery of '%s': %w", ref, apperrors.ErrUnsupported)
+	}
+	host, repository, reference := parseRegistryReference(strings.TrimPrefix(ref, registryDockerScheme))
+	reg := &registryClient{access: access, host: host, repository: repository}
+	manifest, err := reg.manifest(ctx, reference)
+	if err != nil {
+		return imageSize{}, err
+	}
+	if len(manifest.Manifests) > 0 {
+		digest := ""
+		for _, desc := range manifest.Manifests {
+			if desc.Platform.Architecture == arch && (desc.Platform.OS == "" || desc.Platform.OS == "linux") {
+				digest = desc.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return imageSize{}, &apperrors.ErrNotFound{Entity: "image manifest for architecture", Identifier: arch}
+		}
+		if manifest, err = reg.manifest(ctx, digest); err != nil {
+			return imageSize{}, err
+		}
+	}
+	var res imageSize
+	for _, layer := range manifest.Layers {
+		res.compressed += layer.Size
+	}
+	return res, nil
+}
+
+// parseRegistryReference splits an image reference the way container runtimes
+// do: the first path component is the registry host if it looks like one, and
+// Docker Hub otherwise.
+func parseRegistryReference(ref string) (host, repository, reference string) {
+	host = "registry-1.docker.io"
+	repository = ref
+	if first, rest, ok := strings.Cut(ref, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, repository = first, rest
+	}
+	if host == "docker.io" || host == "index.docker.io" {
+		host = "registry-1.docker.io"
+	}
+	reference = "latest"
+	if repo, digest, ok := strings.Cut(repository, "@"); ok {
+		repository, reference = repo, digest
+	} else if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+		repository, reference = repository[:idx], repository[idx+1:]
+	}
+	if host == "registry-1.docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return host, repository, reference
+}
+
+type registryManifest struct {
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+var manifestMediaTypes = strings.Join([]string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}, ", ")
+
+// registryClient reads manifests from the OCI distribution API. It follows a
+// bearer token challenge once, with the source credentials if any.
+type registryClient struct {
+	access     *sourceAccess
+	host       string
+	repository string
+	token      string
+}
+
+func (r *registryClient) manifest(ctx context.Context, reference string) (*registryManifest, error) {
+	manifestUrl := fmt.Sprintf("https://%s/v2/%s/manifests/%s", r.host, r.repository, reference)
+	resp, err := r.get(ctx, manifestUrl)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authorize(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = r.get(ctx, manifestUrl); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get manifest '%s' failed with status: %s", manifestUrl, resp.Status)
+	}
+	res := &registryManifest{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(res); err != nil {
+		return nil, fmt.Errorf("decode manifest '%s': %w", manifestUrl, err)
+	}
+	return res, nil
+}
+
+func (r *registryClient) get(ctx context.Context, rawUrl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request to '%s': %w", rawUrl, err)
+	}
+	req.Header.Set("Accept", manifestMediaTypes)
+	switch {
+	case r.token != "":
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	case r.access.user != "":
+		req.SetBasicAuth(r.access.user, r.access.password)
+	}
+	resp, err := r.access.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request '%s': %w", rawUrl, err)
+	}
+	return resp, nil
+}
+
+// authorize gets a pull token from the realm of a Bearer challenge.
+func (r *registryClient) authorize(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("registry '%s' authentication '%s': %w", r.host, scheme, apperrors.ErrUnsupported)
+	}
+	attrs := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok {
+			attrs[key] = strings.Trim(value, `"`)
+		}
+	}
+	tokenUrl, err := url.Parse(attrs["realm"])
+	if err != nil || tokenUrl.Host == "" {
+		return fmt.Errorf("registry '%s' token realm '%s' is invalid", r.host, attrs["realm"])
+	}
+	query := tokenUrl.Query()
+	if attrs["service"] != "" {
+		query.Set("service", attrs["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", r.repository))
+	tokenUrl.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenUrl.String(), nil)
+	if err != nil {
+		return fmt.Errorf("build request to '%s': %w", tokenUrl, err)
+	}
+	if r.access.user != "" {
+		req.SetBasicAuth(r.access.user, r.access.password)
+	}
+	resp, err := r.access.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry token request failed with status: %s", resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return fmt.Errorf("decode registry token: %w", err)
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	if r.token == "" {
+		return errors.New("registry returned an empty token")
+	}
+	return nil
+}
+
+// filesystemOverhead returns the share of a Filesystem volume of storageClass
+// that CDI reserves, from the CDIConfig status.
+func (m *cdiManager) filesystemOverhead(ctx context.Context, storageClass string) (float64, error) {
+	cdiConfig := &v1beta1.CDIConfig{}
+	if err := m.apiReader.Get(ctx, client.ObjectKey{Name: cdiConfigName}, cdiConfig); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return defaultFilesystemOverhead, nil
+		}
+		return 0, fmt.Errorf("get CDIConfig: %w", err)
+	}
+	overhead := cdiConfig.Status.FilesystemOverhead
+	if overhead == nil {
+		return defaultFilesystemOverhead, nil
+	}
+	percent := overhead.Global
+	if scPercent, ok := overhead.StorageClass[storageClass]; ok {
+		percent = scPercent
+	}
+	if percent == "" {
+		return defaultFilesystemOverhead, nil
+	}
+	res, err := strconv.ParseFloat(string(percent), 64)
+	if err != nil || res < 0 || res >= 1 {
+		return 0, fmt.Errorf("CDIConfig filesystem overhead '%s' is invalid", percent)
+	}
+	return res, nil
+}
+
+// requiredStorage returns the DataVolume size an image of size needs: the
+// virtual size grown by the filesystem overhead, the way CDI computes the space
+// it requires, rounded up to MiB. With only the compressed size known, it is a
+// lower bound and the default size is used unless the image is larger.
+func requiredStorage(size imageSize, overhead float64) resource.Quantity {
+	need := size.virtual
+	if need == 0 {
+		need = size.compressed
+	}
+	required := int64(math.Ceil(float64(need) / (1 - overhead)))
+	required = (required + storageAlignment - 1) / storageAlignment * storageAlignment
+	if size.virtual == 0 && required < defaultImageSize.Value() {
+		return defaultImageSize.DeepCopy()
+	}
+	return *resource.NewQuantity(required, resource.BinarySI)
+}
@@ -0,0 +1,250 @@
+package cdi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func qcow2Header(virtual uint64) []byte {
+	head := make([]byte, 512)
+	copy(head, qcow2Magic)
+	binary.BigEndian.PutUint32(head[4:8], 3)
+	binary.BigEndian.PutUint64(head[24:32], virtual)
+	return head
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func newSizingManager(t *testing.T, objs ...client.Object) (*cdiManager, client.Client) {
+	t.Helper()
+	objs = append(objs, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+	cl := k8stest.NewClient(t, objs...)
+	ns, sc, timeout := testNamespace, "fast", "5s"
+	m, err := NewCDIImageManager(cl, cl, &config.ImageConfig{StorageClass: &sc, SizeDiscoveryTimeout: &timeout}, &config.K8sConfig{Namespace: &ns}, nil)
+	require.NoError(t, err)
+	return m, cl
+}
+
+func dataVolumeSize(t *testing.T, cl client.Client, name string) *resource.Quantity {
+	t.Helper()
+	dv := &v1beta1.DataVolume{}
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: name}, dv))
+	return dv.Spec.Storage.Resources.Requests.Storage()
+}
+
+func TestVirtualSizeFromHeader(t *testing.T) {
+	t.Parallel()
+	vmdk := make([]byte, 512)
+	copy(vmdk, vmdkMagic)
+	binary.LittleEndian.PutUint64(vmdk[12:20], 4<<21)
+
+	tests := []struct {
+		name  string
+		head  []byte
+		total int64
+		want  int64
+	}{
+		{name: "qcow2", head: qcow2Header(20 << 30), total: 1 << 30, want: 20 << 30},
+		{name: "gzip qcow2", head: gzipped(t, qcow2Header(8<<30)), total: 1 << 20, want: 8 << 30},
+		{name: "vmdk", head: vmdk, total: 1 << 20, want: 4 << 30},
+		{name: "raw", head: []byte("\x00\x00\x00\x00"), total: 2 << 30, want: 2 << 30},
+		{name: "gzip raw", head: gzipped(t, []byte("\x00\x00\x00\x00")), total: 1 << 20, want: 0},
+		{name: "xz", head: append([]byte{}, xzMagic...), total: 1 << 20, want: 0},
+		{name: "raw of unknown length", head: []byte("\x00"), total: -1, want: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, virtualSizeFromHeader(tc.head, tc.total))
+		})
+	}
+}
+
+func TestParseRegistryReference(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ref, host, repository, reference string
+	}{
+		{"cirros", "registry-1.docker.io", "library/cirros", "latest"},
+		{"docker.io/kubevirt/cirros-container-disk-demo:v1.4", "registry-1.docker.io", "kubevirt/cirros-container-disk-demo", "v1.4"},
+		{"quay.io/vendor/vnf:1.0", "quay.io", "vendor/vnf", "1.0"},
+		{"registry.local:5000/vnf@sha256:abc", "registry.local:5000", "vnf", "sha256:abc"},
+		{"localhost/vnf", "localhost", "vnf", "latest"},
+	}
+	for _, tc := range tests {
+		host, repository, reference := parseRegistryReference(tc.ref)
+		assert.Equal(t, []string{tc.host, tc.repository, tc.reference}, []string{host, repository, reference}, tc.ref)
+	}
+}
+
+func TestRequiredStorage(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		size     imageSize
+		overhead float64
+		want     string
+	}{
+		// 1Gi / (1 - 0.06) rounded up to MiB.
+		{name: "virtual size with overhead", size: imageSize{virtual: 1 << 30}, overhead: 0.06, want: "1090Mi"},
+		{name: "virtual size without overhead", size: imageSize{virtual: 1 << 30, compressed: 300 << 20}, want: "1Gi"},
+		// A compressed size alone is a lower bound.
+		{name: "small compressed size", size: imageSize{compressed: 1 << 30}, overhead: 0.06, want: "10Gi"},
+		{name: "large compressed size", size: imageSize{compressed: 20 << 30}, want: "20Gi"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := requiredStorage(tc.size, tc.overhead)
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+}
+
+func TestDownloadImageDiscoversSize(t *testing.T) {
+	t.Parallel()
+	content := append(qcow2Header(20<<30), make([]byte, 1<<20)...)
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("X-Token")
+		http.ServeContent(w, r, "vnf.qcow2", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Run("sizes the data volume from the qcow2 virtual size", func(t *testing.T) {
+		cdiConfig := &v1beta1.CDIConfig{ObjectMeta: metav1.ObjectMeta{Name: cdiConfigName}}
+		cdiConfig.Status.FilesystemOverhead = &v1beta1.FilesystemOverhead{Global: "0.055", StorageClass: map[string]v1beta1.Percent{"fast": "0"}}
+		m, cl := newSizingManager(t, cdiConfig)
+		req := httpDownloadReq("img1")
+		req.Source.Http.Url = srv.URL + "/vnf.qcow2"
+		req.Source.Http.Headers = map[string]string{"X-Token": "secret"}
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+
+		assert.Equal(t, "secret", gotAuth)
+		assert.Equal(t, "20Gi", dataVolumeSize(t, cl, "img1").String())
+		vis := &v1beta1.VolumeImportSource{}
+		require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "img1"}, vis))
+		assert.Equal(t, fmt.Sprint(len(content)), vis.Annotations[image.K8sImageCompressedSizeAnnotation])
+		assert.Equal(t, fmt.Sprint(int64(20<<30)), vis.Annotations[image.K8sImageVirtualSizeAnnotation])
+	})
+
+	t.Run("adds the default filesystem overhead", func(t *testing.T) {
+		m, cl := newSizingManager(t)
+		req := httpDownloadReq("img1")
+		req.Source.Http.Url = srv.URL + "/vnf.qcow2"
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "21788Mi", dataVolumeSize(t, cl, "img1").String())
+	})
+
+	t.Run("storage size below the virtual size is rejected", func(t *testing.T) {
+		m, _ := newSizingManager(t)
+		req := httpDownloadReq("img1")
+		req.Source.Http.Url = srv.URL + "/vnf.qcow2"
+		req.Options = &admin.DownloadOptions{StorageSize: k8stest.Ptr("10Gi")}
+		_, err := m.DownloadImage(context.Background(), req)
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+
+	t.Run("unreachable source keeps the default size", func(t *testing.T) {
+		m, cl := newSizingManager(t)
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+		req := httpDownloadReq("img1")
+		req.Source.Http.Url = down.URL + "/vnf.qcow2"
+		_, err := m.DownloadImage(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "10Gi", dataVolumeSize(t, cl, "img1").String())
+	})
+}
+
+func TestRegistryImageSize(t *testing.T) {
+	t.Parallel()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			user, password, _ := r.BasicAuth()
+			if user != "robot" || password != "pw" || r.URL.Query().Get("scope") != "repository:vendor/vnf:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token":"t0k"}`)
+		case r.Header.Get("Authorization") != "Bearer t0k":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/vendor/vnf/manifests/1.0":
+			fmt.Fprint(w, `{"manifests":[
+				{"digest":"sha256:arm","platform":{"architecture":"arm64","os":"linux"}},
+				{"digest":"sha256:amd","platform":{"architecture":"amd64","os":"linux"}}]}`)
+		case r.URL.Path == "/v2/vendor/vnf/manifests/sha256:amd":
+			fmt.Fprint(w, `{"layers":[{"size":1000},{"size":2000}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vendor-pull", Namespace: testNamespace},
+		Data:       map[string][]byte{"accessKeyId": []byte("robot"), "secretKey": []byte("pw")},
+	}
+	ca := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vendor-ca", Namespace: testNamespace},
+		Data:       map[string]string{"ca.crt": string(caPEM)},
+	}
+	m, _ := newSizingManager(t, secret, ca)
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	source := &v1beta1.ImportSourceType{Registry: &v1beta1.DataVolumeSourceRegistry{
+		URL:      k8stest.Ptr("docker://" + host + "/vendor/vnf:1.0"),
+		Platform: &v1beta1.PlatformOptions{Architecture: "amd64"},
+	}}
+	req := &admin.DownloadImageRequest{Metadata: &admin.ImageMetadata{
+		Name: "img1",
+		Metadata: &nfvcommon.Metadata{Fields: map[string]string{
+			image.SourceSecretRefMetadataKey:     "vendor-pull",
+			image.SourceCertConfigMapMetadataKey: "vendor-ca",
+		}},
+	}}
+	got, err := m.discoverImageSize(context.Background(), req, source)
+	require.NoError(t, err)
+	assert.Equal(t, imageSize{compressed: 3000}, got)
+
+	source.Registry.Platform.Architecture = "s390x"
+	_, err = m.discoverImageSize(context.Background(), req, source)
+	var notFound *apperrors.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
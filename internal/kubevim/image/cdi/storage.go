@@ -0,0 +1,134 @@
+package cdi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// goldenAccessModes are the access modes of an image volume in order of
+// preference: a golden image is only read once imported.
+var goldenAccessModes = []corev1.PersistentVolumeAccessMode{
+	corev1.ReadOnlyMany,
+	corev1.ReadWriteMany,
+	corev1.ReadWriteOnce,
+}
+
+// storageSettings are the software image settings of a StorageClass.
+type storageSettings struct {
+	goldenAccessMode corev1.PersistentVolumeAccessMode
+	// cloneAccessMode is the access mode of the boot volumes cloned from the
+	// image: ReadWriteMany if the storage offers it, which allows live migration.
+	cloneAccessMode corev1.PersistentVolumeAccessMode
+	// volumeMode is nil if unknown, which leaves it to CDI.
+	volumeMode *corev1.PersistentVolumeMode
+	// cloneStrategy is empty if CDI picks it: snapshot when a VolumeSnapshotClass
+	// of the provisioner exists, copy otherwise.
+	cloneStrategy v1beta1.CDICloneStrategy
+}
+
+// storageSettings detects the software image settings of storageClass from its
+// CDI StorageProfile, overridden by image.storageProfiles.
+func (m *cdiManager) storageSettings(ctx context.Context, storageClass string) (*storageSettings, error) {
+	var override config.ImageStorageProfileConfig
+	if m.cfg.StorageProfiles != nil {
+		override = (*m.cfg.StorageProfiles)[storageClass]
+	}
+	profile := &v1beta1.StorageProfile{}
+	if err := m.apiReader.Get(ctx, client.ObjectKey{Name: storageClass}, profile); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get CDI StorageProfile '%s': %w", storageClass, err)
+		}
+		profile = nil
+	}
+	var claimSets []v1beta1.ClaimPropertySet
+	if profile != nil {
+		claimSets = profile.Status.ClaimPropertySets
+	}
+
+	res := &storageSettings{goldenAccessMode: corev1.ReadWriteOnce, cloneAccessMode: corev1.ReadWriteOnce}
+	if override.GoldenAccessMode != nil {
+		res.goldenAccessMode = corev1.PersistentVolumeAccessMode(*override.GoldenAccessMode)
+		if set := claimSetWithAccessMode(claimSets, res.goldenAccessMode, nil); set != nil {
+			res.volumeMode = set.VolumeMode
+		}
+	} else {
+		for _, mode := range goldenAccessModes {
+			if set := claimSetWithAccessMode(claimSets, mode, nil); set != nil {
+				res.goldenAccessMode, res.volumeMode = mode, set.VolumeMode
+				break
+			}
+		}
+	}
+	if override.VolumeMode != nil {
+		volumeMode := corev1.PersistentVolumeMode(*override.VolumeMode)
+		res.volumeMode = &volumeMode
+	}
+	// A smart clone keeps the volume mode of its source.
+	if claimSetWithAccessMode(claimSets, corev1.ReadWriteMany, res.volumeMode) != nil {
+		res.cloneAccessMode = corev1.ReadWriteMany
+	}
+
+	// A configured clone strategy was set on the StorageProfile at startup, by
+	// EnsureStorageProfiles.
+	if override.CloneStrategy != nil {
+		res.cloneStrategy = v1beta1.CDICloneStrategy(*override.CloneStrategy)
+	} else if profile != nil && profile.Status.CloneStrategy != nil {
+		res.cloneStrategy = *profile.Status.CloneStrategy
+	}
+	return res, nil
+}
+
+// EnsureStorageProfiles sets the clone strategies of image.storageProfiles on
+// the CDI StorageProfiles, the only place CDI reads them from. It runs once when
+// kube-vim starts, so creating an image never writes cluster-scoped objects.
+func (m *cdiManager) EnsureStorageProfiles(ctx context.Context) error {
+	if m.cfg.StorageProfiles == nil {
+		return nil
+	}
+	for _, storageClass := range slices.Sorted(maps.Keys(*m.cfg.StorageProfiles)) {
+		override := (*m.cfg.StorageProfiles)[storageClass]
+		if override.CloneStrategy == nil {
+			continue
+		}
+		strategy := v1beta1.CDICloneStrategy(*override.CloneStrategy)
+		profile := &v1beta1.StorageProfile{}
+		if err := m.apiReader.Get(ctx, client.ObjectKey{Name: storageClass}, profile); err != nil {
+			return fmt.Errorf("get CDI StorageProfile '%s': %w", storageClass, err)
+		}
+		if profile.Spec.CloneStrategy != nil && *profile.Spec.CloneStrategy == strategy {
+			continue
+		}
+		base := profile.DeepCopy()
+		profile.Spec.CloneStrategy = &strategy
+		if err := m.client.Patch(ctx, profile, client.MergeFrom(base)); err != nil {
+			return fmt.Errorf("set clone strategy of CDI StorageProfile '%s': %w", storageClass, err)
+		}
+		m.logger.Info("Set clone strategy of CDI StorageProfile", zap.String("storageClass", storageClass), zap.String("cloneStrategy", string(strategy)))
+	}
+	return nil
+}
+
+// claimSetWithAccessMode returns the first claim property set offering mode,
+// with volumeMode unless it is nil.
+func claimSetWithAccessMode(sets []v1beta1.ClaimPropertySet, mode corev1.PersistentVolumeAccessMode, volumeMode *corev1.PersistentVolumeMode) *v1beta1.ClaimPropertySet {
+	for idx := range sets {
+		set := &sets[idx]
+		if !slices.Contains(set.AccessModes, mode) {
+			continue
+		}
+		if volumeMode != nil && (set.VolumeMode == nil || *set.VolumeMode != *volumeMode) {
+			continue
+		}
+		return set
+	}
+	return nil
+}
@@ -3,22 +3,35 @@ package cdi
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/kube-nfv/kube-vim-api/pkg/apis"
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	common "github.com/kube-nfv/kube-vim/internal/config"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
 	"github.com/kube-nfv/kube-vim/internal/misc"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// importSourceTypeFromImageSource converts the admin image source of image
+// imgName to the CDI import source. md is the DownloadImage metadata, which
+// names the source credentials (see image.SourceSecretRefMetadataKey).
 // TODO: Add additional image import sources
-func importSourceTypeFromImageSource(imgSource *admin.ImageSource) (*v1beta1.ImportSourceType, error) {
+func importSourceTypeFromImageSource(imgName string, imgSource *admin.ImageSource, md *apis.Metadata) (*v1beta1.ImportSourceType, error) {
 	if imgSource == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "imageSource", Reason: "can't be nil"}
 	}
@@ -27,28 +40,282 @@ func importSourceTypeFromImageSource(imgSource *admin.ImageSource) (*v1beta1.Imp
 
 	switch imgSource.Type {
 	case admin.ImageSourceType_HTTP:
-		if res.HTTP, err = convertToHttpDataVolumeSource(imgSource.Http); err != nil {
+		if res.HTTP, err = convertToHttpDataVolumeSource(imgName, imgSource.Http, md); err != nil {
 			return nil, fmt.Errorf("convert to http data volume source: %w", err)
 		}
+	case admin.ImageSourceType_REGISTRY:
+		if res.Registry, err = convertToRegistryDataVolumeSource(imgSource.Registry, md); err != nil {
+			return nil, fmt.Errorf("convert to registry data volume source: %w", err)
+		}
+	case admin.ImageSourceType_S3:
+		if res.S3, err = convertToS3DataVolumeSource(imgSource.S3, md); err != nil {
+			return nil, fmt.Errorf("convert to s3 data volume source: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("unknown imageSource type '%s': %w", imgSource.Type, apperrors.ErrUnsupported)
 	}
 	return res, nil
 }
 
-// TODO: Also add secrets from HTTP population
-func convertToHttpDataVolumeSource(http *admin.HttpSource) (*v1beta1.DataVolumeSourceHTTP, error) {
+// convertToHttpDataVolumeSource maps an HTTP source of image imgName to the CDI
+// HTTP source. Credentials never land on the VolumeImportSource: basic auth and
+// header Secrets are referenced by name, and the inline headers and CA bundle
+// point to the Secret and ConfigMap created by httpSourceObjects.
+func convertToHttpDataVolumeSource(imgName string, http *admin.HttpSource, md *apis.Metadata) (*v1beta1.DataVolumeSourceHTTP, error) {
 	if http == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "httpSource", Reason: "can't be nil"}
 	}
 	res := &v1beta1.DataVolumeSourceHTTP{}
 	res.URL = http.GetUrl()
+	res.SecretRef = md.GetFields()[image.SourceSecretRefMetadataKey]
+
+	if headerSecret := md.GetFields()[image.SourceHeaderSecretRefMetadataKey]; headerSecret != "" {
+		res.SecretExtraHeaders = append(res.SecretExtraHeaders, headerSecret)
+	}
+	if len(http.Headers) > 0 {
+		res.SecretExtraHeaders = append(res.SecretExtraHeaders, httpSourceHeadersName(imgName))
+	}
+	certConfigMap, caBundle := md.GetFields()[image.SourceCertConfigMapMetadataKey], md.GetFields()[image.SourceCaBundleMetadataKey]
+	switch {
+	case certConfigMap != "" && caBundle != "":
+		return nil, &apperrors.ErrInvalidArgument{
+			Field:  image.SourceCaBundleMetadataKey,
+			Reason: fmt.Sprintf("can't be combined with %s", image.SourceCertConfigMapMetadataKey),
+		}
+	case caBundle != "":
+		res.CertConfigMap = httpSourceCaName(imgName)
+	default:
+		res.CertConfigMap = certConfigMap
+	}
+	return res, nil
+}
+
+// imageAttributeAnnotations returns the image attributes to persist on the
+// image record: provider and version, the expected checksum of an HTTP source
+// (a checksum given for another source in the metadata is rejected),
+// and the catalog of the image, the disk and container formats and the minimum
+// disk and RAM given in the request metadata. The disk format defaults to the extension of the source
+// file, the container format to "docker" for registry sources and "bare"
+// otherwise.
+func imageAttributeAnnotations(req *admin.DownloadImageRequest) (map[string]string, error) {
+	fields := req.GetMetadata().GetMetadata().GetFields()
+	res := map[string]string{}
+	if req.GetMetadata().Provider != nil {
+		res[image.K8sImageProviderAnnotation] = req.GetMetadata().GetProvider()
+	}
+	if req.GetMetadata().Version != nil {
+		res[image.K8sImageVersionAnnotation] = req.GetMetadata().GetVersion()
+	}
+	// Only an HTTP source carries an expected checksum.
+	for _, key := range []string{image.K8sImageChecksumAnnotation, image.K8sImageChecksumAlgorithmAnnotation} {
+		if _, ok := fields[key]; ok {
+			return nil, &apperrors.ErrInvalidArgument{Field: key, Reason: "the checksum is only supported as source.http.checksum of an HTTP source"}
+		}
+	}
+	if checksum := req.GetSource().GetHttp().GetChecksum(); checksum != nil {
+		algorithm, value, err := normalizeChecksum(checksum)
+		if err != nil {
+			return nil, err
+		}
+		res[image.K8sImageChecksumAlgorithmAnnotation] = algorithm
+		res[image.K8sImageChecksumAnnotation] = value
+	}
+	for _, key := range []string{image.K8sImageCatalogMetadataKey, image.K8sImageCatalogIdMetadataKey} {
+		if val, ok := fields[key]; ok {
+			res[key] = val
+		}
+	}
+	for _, key := range []string{image.K8sImageMinDiskAnnotation, image.K8sImageMinRamAnnotation} {
+		val, ok := fields[key]
+		if !ok {
+			continue
+		}
+		q, err := resource.ParseQuantity(val)
+		if err != nil {
+			return nil, &apperrors.ErrInvalidArgument{Field: key, Reason: err.Error()}
+		}
+		res[key] = q.String()
+	}
+
+	diskFormat := fields[image.K8sImageDiskFormatAnnotation]
+	if diskFormat == "" {
+		switch req.GetSource().GetType() {
+		case admin.ImageSourceType_HTTP:
+			diskFormat = diskFormatFromPath(req.GetSource().GetHttp().GetUrl())
+		case admin.ImageSourceType_S3:
+			diskFormat = diskFormatFromPath(req.GetSource().GetS3().GetKey())
+		}
+	}
+	if diskFormat != "" {
+		res[image.K8sImageDiskFormatAnnotation] = diskFormat
+	}
+	containerFormat := fields[image.K8sImageContainerFormatAnnotation]
+	if containerFormat == "" {
+		containerFormat = "bare"
+		if req.GetSource().GetType() == admin.ImageSourceType_REGISTRY {
+			containerFormat = "docker"
+		}
+	}
+	res[image.K8sImageContainerFormatAnnotation] = containerFormat
+	return res, nil
+}
+
+// diskFormatFromPath infers the disk format from the file extension of a URL or
+// object key, looking through the gz and xz compression CDI handles. ".img" is
+// used for both raw and qcow2 images and is left undetermined.
+func diskFormatFromPath(p string) string {
+	if u, err := url.Parse(p); err == nil {
+		p = u.Path
+	}
+	p = strings.ToLower(p)
+	p = strings.TrimSuffix(strings.TrimSuffix(p, ".gz"), ".xz")
+	switch ext := path.Ext(p); ext {
+	case ".qcow2", ".raw", ".iso", ".vmdk", ".vhd", ".vhdx":
+		return strings.TrimPrefix(ext, ".")
+	}
+	return ""
+}
 
-	extraHeaders := make([]string, 0, len(http.Headers))
-	for hdr, val := range http.Headers {
-		extraHeaders = append(extraHeaders, fmt.Sprintf("%s: %s", hdr, val))
+func httpSourceHeadersName(imgName string) string {
+	return imgName + "-http-headers"
+}
+
+func httpSourceCaName(imgName string) string {
+	return imgName + "-http-ca"
+}
+
+// httpSourceObjects builds the Secret holding the inline headers and the
+// ConfigMap holding the inline CA bundle of the HTTP source of vis, if any.
+// Both are owned by vis, so they are garbage-collected with the image.
+func httpSourceObjects(vis *v1beta1.VolumeImportSource, http *admin.HttpSource, md *apis.Metadata) []client.Object {
+	if http == nil {
+		return nil
+	}
+	objMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: vis.Namespace,
+			Labels: map[string]string{
+				common.K8sManagedByLabel: common.KubeNfvName,
+				image.K8sImageIdLabel:    imageIdentifier(vis).GetValue(),
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1beta1.SchemeGroupVersion.String(),
+				Kind:       CDIVolumeImportSourceKind,
+				Name:       vis.Name,
+				UID:        vis.UID,
+			}},
+		}
+	}
+	var res []client.Object
+	if len(http.GetHeaders()) > 0 {
+		names := make([]string, 0, len(http.Headers))
+		for hdr := range http.Headers {
+			names = append(names, hdr)
+		}
+		sort.Strings(names)
+		// Each value is one "Name: value" header line, as CDI expects from
+		// secretExtraHeaders. Header names are not valid Secret keys in general.
+		data := make(map[string]string, len(names))
+		for idx, hdr := range names {
+			data[fmt.Sprintf("header-%d", idx)] = fmt.Sprintf("%s: %s", hdr, http.Headers[hdr])
+		}
+		res = append(res, &v1.Secret{
+			ObjectMeta: objMeta(httpSourceHeadersName(vis.Name)),
+			Type:       v1.SecretTypeOpaque,
+			StringData: data,
+		})
+	}
+	if caBundle := md.GetFields()[image.SourceCaBundleMetadataKey]; caBundle != "" {
+		res = append(res, &v1.ConfigMap{
+			ObjectMeta: objMeta(httpSourceCaName(vis.Name)),
+			Data:       map[string]string{"ca.crt": caBundle},
+		})
+	}
+	return res
+}
+
+// convertToRegistryDataVolumeSource maps an OCI image reference to the CDI
+// registry source. A reference without a transport is pulled with docker://.
+// The IF_NOT_PRESENT pull policy uses the node pull method, which reuses the
+// image from the container runtime cache of the node.
+func convertToRegistryDataVolumeSource(registry *admin.RegistrySource, md *apis.Metadata) (*v1beta1.DataVolumeSourceRegistry, error) {
+	if registry == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "registrySource", Reason: "can't be nil"}
+	}
+	ref := registry.GetImage()
+	if ref == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "registrySource.image", Reason: "can't be empty"}
+	}
+	if !strings.HasPrefix(ref, registryDockerScheme) && !strings.HasPrefix(ref, registryOciArchiveScheme) {
+		ref = registryDockerScheme + ref
+	}
+	res := &v1beta1.DataVolumeSourceRegistry{URL: &ref}
+
+	if registry.PullPolicy != nil {
+		var pullMethod v1beta1.RegistryPullMethod
+		switch registry.GetPullPolicy() {
+		case admin.PullPolicy_ALWAYS:
+			pullMethod = v1beta1.RegistryPullPod
+		case admin.PullPolicy_IF_NOT_PRESENT:
+			pullMethod = v1beta1.RegistryPullNode
+		default:
+			return nil, fmt.Errorf("registry pull policy '%s': %w", registry.GetPullPolicy(), apperrors.ErrUnsupported)
+		}
+		res.PullMethod = &pullMethod
+	}
+	if registry.Platform != nil {
+		// Platform is "os/arch[/variant]"; CDI selects by architecture only.
+		parts := strings.Split(registry.GetPlatform(), "/")
+		if len(parts) < 2 || parts[1] == "" {
+			return nil, &apperrors.ErrInvalidArgument{Field: "registrySource.platform", Reason: fmt.Sprintf("'%s' is not in os/arch form", registry.GetPlatform())}
+		}
+		res.Platform = &v1beta1.PlatformOptions{Architecture: parts[1]}
+	}
+	if secret := md.GetFields()[image.SourceSecretRefMetadataKey]; secret != "" {
+		res.SecretRef = &secret
+	}
+	if cm := md.GetFields()[image.SourceCertConfigMapMetadataKey]; cm != "" {
+		res.CertConfigMap = &cm
+	}
+	return res, nil
+}
+
+// convertToS3DataVolumeSource maps an S3 object to the CDI S3 source URL
+// <endpoint>/<bucket>/<key>. CDI always addresses the bucket path-style and
+// derives the region from the endpoint host, so virtual-hosted style is
+// rejected and the region is not passed on. An endpoint without a scheme is
+// reached over https.
+func convertToS3DataVolumeSource(s3 *admin.S3Source, md *apis.Metadata) (*v1beta1.DataVolumeSourceS3, error) {
+	if s3 == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "s3Source", Reason: "can't be nil"}
+	}
+	if s3.GetEndpoint() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "s3Source.endpoint", Reason: "can't be empty"}
+	}
+	if s3.GetBucket() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "s3Source.bucket", Reason: "can't be empty"}
+	}
+	if s3.GetKey() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "s3Source.key", Reason: "can't be empty"}
+	}
+	if s3.PathStyle != nil && !s3.GetPathStyle() {
+		return nil, fmt.Errorf("virtual-hosted style s3 addressing: %w", apperrors.ErrUnsupported)
+	}
+	endpoint := s3.GetEndpoint()
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	ep, err := url.Parse(endpoint)
+	if err != nil || ep.Host == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "s3Source.endpoint", Reason: fmt.Sprintf("'%s' is not a valid endpoint", s3.GetEndpoint())}
+	}
+	objectUrl := ep.JoinPath(s3.GetBucket(), s3.GetKey())
+	res := &v1beta1.DataVolumeSourceS3{
+		URL:           objectUrl.String(),
+		SecretRef:     md.GetFields()[image.SourceSecretRefMetadataKey],
+		CertConfigMap: md.GetFields()[image.SourceCertConfigMapMetadataKey],
 	}
-	res.ExtraHeaders = extraHeaders
 	return res, nil
 }
 
@@ -86,7 +353,18 @@ func getStorageClass(ctx context.Context, name string, reader client.Reader) (*s
 	return nil, &apperrors.ErrNotFound{Entity: "storageClass", Identifier: "default"}
 }
 
-func nfvImageFromCdiDataVolumeVis(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImportSource) (*vivnfm.SoftwareImageInformation, error) {
+// imageIdentifier returns the id of the image of vis: the stable id of the
+// catalog image it was imported from, else the VolumeImportSource UID.
+func imageIdentifier(vis *v1beta1.VolumeImportSource) *apis.Identifier {
+	if id := vis.Labels[image.K8sCatalogImageIdLabel]; id != "" {
+		return &apis.Identifier{Value: id}
+	}
+	return misc.UIDToIdentifier(vis.GetUID())
+}
+
+// nfvImageFromCdiDataVolumeVis converts an image record, its DataVolume and
+// its checksum verification Job (nil if there is none) to the ETSI image.
+func nfvImageFromCdiDataVolumeVis(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImportSource, checksumJob *batchv1.Job) (*vivnfm.SoftwareImageInformation, error) {
 	if dv == nil || vis == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "dataVolume or volumeImportSource", Reason: "can't be nil"}
 	}
@@ -103,11 +381,14 @@ func nfvImageFromCdiDataVolumeVis(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImp
 		return nil, &apperrors.ErrK8sObjectNotManagedByKubeNfv{ObjectType: vis.Kind, ObjectName: vis.Name, ObjectId: string(vis.GetUID())}
 	}
 
-	imgId := misc.UIDToIdentifier(vis.GetUID())
+	imgId := imageIdentifier(vis)
 	imgName := vis.GetName()
-	srcTypeName, err := sourceNameFromImportSourceType(vis.Spec.Source)
-	if err != nil {
-		return nil, fmt.Errorf("get sourceName from ImportSourceType: %w", err)
+	var srcTypeName image.SourceType = image.Upload
+	if vis.Labels[image.K8sIsUploadLabel] != "true" {
+		var err error
+		if srcTypeName, err = sourceNameFromImportSourceType(vis.Spec.Source); err != nil {
+			return nil, fmt.Errorf("get sourceName from ImportSourceType: %w", err)
+		}
 	}
 
 	metadata := map[string]string{
@@ -117,6 +398,18 @@ func nfvImageFromCdiDataVolumeVis(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImp
 		K8sDataVolumePhase:    string(dv.Status.Phase),
 	}
 
+	if sc := dv.Spec.Storage.StorageClassName; sc != nil {
+		metadata[image.K8sImageStorageClassMetadataKey] = *sc
+	}
+	if volumeMode := dv.Spec.Storage.VolumeMode; volumeMode != nil {
+		metadata[image.K8sImageVolumeModeMetadataKey] = string(*volumeMode)
+	}
+	for _, key := range []string{image.K8sImageCloneAccessModeMetadataKey, image.K8sImageCloneStrategyMetadataKey} {
+		if value, ok := dv.Annotations[key]; ok {
+			metadata[key] = value
+		}
+	}
+
 	for _, dvCond := range dv.Status.Conditions {
 		switch dvCond.Type {
 		case v1beta1.DataVolumeBound:
@@ -136,7 +429,16 @@ func nfvImageFromCdiDataVolumeVis(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImp
 
 	// TODO: For now each image is uploaded
 	isUploaded := true
-	status := "ready"
+	status := image.StatusReady
+	checksumSt, _ := imageChecksumState(vis, checksumJob)
+	switch {
+	case dv.Status.Phase == v1beta1.Failed, checksumSt == checksumFailed:
+		status = image.StatusFailed
+	case dv.Status.Phase != v1beta1.Succeeded:
+		status = image.StatusImporting
+	case checksumSt == checksumVerifying:
+		status = image.StatusVerifying
+	}
 
 	metadata[image.K8sIsUploadLabel] = strconv.FormatBool(isUploaded)
 
@@ -151,18 +453,230 @@ func nfvImageFromCdiDataVolumeVis(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImp
 			Fields: metadata,
 		},
 	}
+	applyImageAttributes(res, vis)
+	return res, nil
+}
+
+// updatedImageAttributes returns the image attribute annotations set by the
+// UpdateImage metadata md. Only provider, version and the format and minimum
+// size metadata keys can be updated.
+func updatedImageAttributes(md *admin.ImageMetadata) (map[string]string, error) {
+	if md == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "metadata", Reason: "can't be nil"}
+	}
+	if md.Description != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "description", Reason: "is not kept by the image and cannot be updated"}
+	}
+	res := map[string]string{}
+	if md.Provider != nil {
+		res[image.K8sImageProviderAnnotation] = md.GetProvider()
+	}
+	if md.Version != nil {
+		res[image.K8sImageVersionAnnotation] = md.GetVersion()
+	}
+	fields := md.GetMetadata().GetFields()
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		val := fields[key]
+		switch key {
+		case image.K8sImageDiskFormatAnnotation, image.K8sImageContainerFormatAnnotation:
+			if val == "" {
+				return nil, &apperrors.ErrInvalidArgument{Field: key, Reason: "can't be empty"}
+			}
+			res[key] = val
+		case image.K8sImageMinDiskAnnotation, image.K8sImageMinRamAnnotation:
+			q, err := resource.ParseQuantity(val)
+			if err != nil {
+				return nil, &apperrors.ErrInvalidArgument{Field: key, Reason: err.Error()}
+			}
+			res[key] = q.String()
+		default:
+			return nil, &apperrors.ErrInvalidArgument{Field: key, Reason: "cannot be updated"}
+		}
+	}
 	return res, nil
 }
 
+// applyImageAttributes fills the image attributes persisted on vis by
+// DownloadImage. The checksum algorithm, the discovered sizes and the catalog
+// an image was imported from have no field of their own and go to the metadata.
+func applyImageAttributes(img *vivnfm.SoftwareImageInformation, vis *v1beta1.VolumeImportSource) {
+	annotation := func(key string) *string {
+		if v, ok := vis.Annotations[key]; ok {
+			return &v
+		}
+		return nil
+	}
+	quantity := func(key string) *resource.Quantity {
+		if v, ok := vis.Annotations[key]; ok {
+			if q, err := resource.ParseQuantity(v); err == nil {
+				return &q
+			}
+		}
+		return nil
+	}
+	img.Provider = annotation(image.K8sImageProviderAnnotation)
+	img.Version = annotation(image.K8sImageVersionAnnotation)
+	img.Checksum = annotation(image.K8sImageChecksumAnnotation)
+	img.DiskFormat = annotation(image.K8sImageDiskFormatAnnotation)
+	img.ContainerFormat = annotation(image.K8sImageContainerFormatAnnotation)
+	img.MinDisk = quantity(image.K8sImageMinDiskAnnotation)
+	img.MinRam = quantity(image.K8sImageMinRamAnnotation)
+	for _, key := range []string{image.K8sImageChecksumAlgorithmAnnotation, image.K8sImageCompressedSizeAnnotation, image.K8sImageVirtualSizeAnnotation,
+		image.K8sImageCatalogMetadataKey, image.K8sImageCatalogIdMetadataKey} {
+		if value, ok := vis.Annotations[key]; ok {
+			img.Metadata.Fields[key] = value
+		}
+	}
+}
+
 func sourceNameFromImportSourceType(source *v1beta1.ImportSourceType) (image.SourceType, error) {
 	if source == nil {
 		return "", &apperrors.ErrInvalidArgument{Field: "source", Reason: "can't be nil"}
 	}
 	if source.HTTP != nil {
-		if source.HTTP.CertConfigMap != "" || source.HTTP.SecretRef != "" {
+		if source.HTTP.CertConfigMap != "" || source.HTTP.SecretRef != "" || len(source.HTTP.SecretExtraHeaders) > 0 {
 			return image.HTTPS, nil
 		}
 		return image.HTTP, nil
 	}
+	if source.Registry != nil {
+		return image.Registry, nil
+	}
+	if source.S3 != nil {
+		return image.S3, nil
+	}
 	return "", fmt.Errorf("unsupported source: %w", apperrors.ErrUnsupported)
 }
+
+// downloadStatusFromDataVolume maps the DataVolume phase, progress, restart count
+// and conditions to the image download status. CDI retries a failing import
+// indefinitely, so a DataVolume in progress with restarts is still DOWNLOADING;
+// the error reported by its Running condition is surfaced as ErrorMessage.
+func downloadStatusFromDataVolume(dv *v1beta1.DataVolume, vis *v1beta1.VolumeImportSource, checksumJob *batchv1.Job) *admin.ImageDownloadStatus {
+	res := &admin.ImageDownloadStatus{
+		ImageId:   imageIdentifier(vis),
+		StartedAt: misc.ConvertToProtoTimestamp(misc.GetCreationTimestamp(dv)),
+		Metadata: &apis.Metadata{
+			Fields: map[string]string{
+				K8sDataVolumeIdLabel:  string(dv.GetUID()),
+				K8sDataVolumePhase:    string(dv.Status.Phase),
+				K8sDataVolumeRestarts: strconv.Itoa(int(dv.Status.RestartCount)),
+			},
+		},
+	}
+	running := findDataVolumeCondition(dv, v1beta1.DataVolumeRunning)
+	ready := findDataVolumeCondition(dv, v1beta1.DataVolumeReady)
+
+	switch dv.Status.Phase {
+	case v1beta1.Succeeded:
+		res.State = admin.DownloadState_COMPLETED
+		progress := int32(100)
+		res.ProgressPercentage = &progress
+		if ready != nil && ready.Status == v1.ConditionTrue {
+			res.CompletedAt = misc.ConvertToProtoTimestamp(ready.LastTransitionTime.Time)
+		}
+	case v1beta1.Failed:
+		res.State = admin.DownloadState_FAILED
+		reason := conditionReason("import failed", running, ready)
+		res.ErrorMessage = &reason
+	case v1beta1.PhaseUnset, v1beta1.Pending, v1beta1.PVCBound, v1beta1.ImportScheduled,
+		v1beta1.WaitForFirstConsumer, v1beta1.PendingPopulation, v1beta1.Unknown,
+		v1beta1.UploadScheduled, v1beta1.UploadReady:
+		// An upload target stays pending until its bytes arrive.
+		res.State = admin.DownloadState_PENDING
+	default:
+		// ImportInProgress and the other in-progress phases CDI may report.
+		res.State = admin.DownloadState_DOWNLOADING
+		if p, ok := parseDataVolumeProgress(dv.Status.Progress); ok {
+			res.ProgressPercentage = &p
+		}
+		if running != nil && running.Status == v1.ConditionFalse && running.Message != "" {
+			res.ErrorMessage = &running.Message
+		}
+	}
+	msg := fmt.Sprintf("DataVolume phase %s", phaseOrUnset(dv.Status.Phase))
+	if dv.Status.Progress != "" && dv.Status.Progress != "N/A" {
+		msg += fmt.Sprintf(", progress %s", dv.Status.Progress)
+	}
+	if dv.Status.RestartCount > 0 {
+		msg += fmt.Sprintf(", importer restarted %d times", dv.Status.RestartCount)
+	}
+	// An imported image is only complete once its checksum is verified, which
+	// starts when its DataVolume has succeeded.
+	switch checksumSt, reason := imageChecksumState(vis, checksumJob); {
+	case dv.Status.Phase != v1beta1.Succeeded:
+	case checksumSt == checksumFailed:
+		res.State = admin.DownloadState_FAILED
+		res.CompletedAt = nil
+		errMsg := fmt.Sprintf("checksum verification failed: %s", reason)
+		res.ErrorMessage = &errMsg
+		msg += ", checksum verification failed"
+	case checksumSt == checksumVerifying:
+		res.State = admin.DownloadState_DOWNLOADING
+		res.CompletedAt = nil
+		msg += ", verifying checksum"
+	case checksumSt == checksumVerified:
+		msg += ", checksum verified"
+	}
+	res.Message = &msg
+	return res
+}
+
+// lazyDownloadStatus is the download status of an image registered with
+// LazyDownload, whose DataVolume has not been created yet.
+func lazyDownloadStatus(vis *v1beta1.VolumeImportSource) *admin.ImageDownloadStatus {
+	msg := "image registered for lazy download, no DataVolume created yet"
+	return &admin.ImageDownloadStatus{
+		ImageId:   imageIdentifier(vis),
+		State:     admin.DownloadState_LAZY_REGISTERED,
+		Message:   &msg,
+		StartedAt: misc.ConvertToProtoTimestamp(misc.GetCreationTimestamp(vis)),
+	}
+}
+
+func findDataVolumeCondition(dv *v1beta1.DataVolume, condType v1beta1.DataVolumeConditionType) *v1beta1.DataVolumeCondition {
+	for idx := range dv.Status.Conditions {
+		if dv.Status.Conditions[idx].Type == condType {
+			return &dv.Status.Conditions[idx]
+		}
+	}
+	return nil
+}
+
+// conditionReason returns the first non-empty message of the given conditions,
+// else their first non-empty reason, else fallback.
+func conditionReason(fallback string, conds ...*v1beta1.DataVolumeCondition) string {
+	reason := ""
+	for _, c := range conds {
+		if c == nil {
+			continue
+		}
+		if c.Message != "" {
+			return c.Message
+		}
+		if reason == "" {
+			reason = c.Reason
+		}
+	}
+	if reason != "" {
+		return reason
+	}
+	return fallback
+}
+
+// parseDataVolumeProgress parses the CDI progress ("45.20%", "N/A") to a whole
+// percentage.
+func parseDataVolumeProgress(progress v1beta1.DataVolumeProgress) (int32, bool) {
+	p, err := strconv.ParseFloat(strings.TrimSuffix(string(progress), "%"), 64)
+	if err != nil || p < 0 {
+		return 0, false
+	}
+	return int32(min(p, 100)), true
+}
+
+func phaseOrUnset(phase v1beta1.DataVolumePhase) string {
+	if phase == v1beta1.PhaseUnset {
+		return "unset"
+	}
+	return string(phase)
+}
@@ -0,0 +1,269 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// catalogNamespace is the UUID namespace of the catalog image ids.
+var catalogNamespace = uuid.MustParse("2d6c3653-07dd-422d-8a9a-fc25711f0795")
+
+// Catalog is an image catalog backend and the name it is known by, e.g. "glance".
+type Catalog struct {
+	Name    string
+	Catalog image.Catalog
+}
+
+// catalogRef locates a catalog image.
+type catalogRef struct {
+	catalog image.Catalog
+	name    string
+	id      string
+}
+
+// manager merges the images of the importer (the CDI image manager) with the
+// images of the catalogs. A catalog image has a stable id derived from its
+// catalog name and its id there, and is imported by the importer, under that
+// id, when a compute first uses it (ImportImage).
+type manager struct {
+	admin.UnimplementedAdminServer
+
+	importer image.Manager
+	catalogs []Catalog
+
+	// refs indexes the catalog images seen by their stable id. It is filled
+	// by listing the catalogs.
+	lock sync.RWMutex
+	refs map[string]catalogRef
+}
+
+func NewManager(importer image.Manager, catalogs ...Catalog) image.Manager {
+	return &manager{
+		importer: importer,
+		catalogs: catalogs,
+		refs:     make(map[string]catalogRef),
+	}
+}
+
+// catalogImageId returns the stable id of the image id of catalog name.
+func catalogImageId(name, id string) *nfvcommon.Identifier {
+	return &nfvcommon.Identifier{Value: uuid.NewSHA1(catalogNamespace, []byte(name+"/"+id)).String()}
+}
+
+func (m *manager) DownloadImage(ctx context.Context, req *admin.DownloadImageRequest) (*admin.DownloadImageResponse, error) {
+	return m.importer.DownloadImage(ctx, req)
+}
+
+// GetImageDownloadStatus reports a catalog image not imported yet as PENDING.
+func (m *manager) GetImageDownloadStatus(ctx context.Context, req *admin.GetImageDownloadStatusRequest) (*admin.GetImageDownloadStatusResponse, error) {
+	res, err := m.importer.GetImageDownloadStatus(ctx, req)
+	if err == nil || !isNotFound(err) {
+		return res, err
+	}
+	if _, resolveErr := m.resolve(ctx, req.GetImageId()); resolveErr != nil {
+		return nil, err
+	}
+	msg := "catalog image not imported yet, it is imported on first use"
+	return &admin.GetImageDownloadStatusResponse{
+		Status: &admin.ImageDownloadStatus{
+			ImageId: req.GetImageId(),
+			State:   admin.DownloadState_PENDING,
+			Message: &msg,
+		},
+	}, nil
+}
+
+func (m *manager) SetupImageUpload(ctx context.Context, req *image.UploadRequest) (*image.UploadTarget, error) {
+	uploader, ok := m.importer.(image.Uploader)
+	if !ok {
+		return nil, fmt.Errorf("image upload with the configured image manager: %w", apperrors.ErrUnsupported)
+	}
+	return uploader.SetupImageUpload(ctx, req)
+}
+
+func (m *manager) GetImage(ctx context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	img, err := m.importer.GetImage(ctx, id)
+	if err == nil || !isNotFound(err) {
+		return img, err
+	}
+	ref, resolveErr := m.resolve(ctx, id)
+	if resolveErr != nil {
+		if isNotFound(resolveErr) {
+			return nil, err
+		}
+		return nil, resolveErr
+	}
+	img, err = ref.catalog.GetImage(ctx, &nfvcommon.Identifier{Value: ref.id})
+	if err != nil {
+		return nil, fmt.Errorf("get image '%s' of catalog '%s': %w", ref.id, ref.name, err)
+	}
+	return catalogImage(img, id, ref.name, ref.id), nil
+}
+
+// ListImages returns the imported images and the catalog images not imported
+// yet.
+func (m *manager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInformation, error) {
+	res, err := m.importer.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string]struct{}, len(res))
+	for _, img := range res {
+		imported[img.GetSoftwareImageId().GetValue()] = struct{}{}
+	}
+	for _, cat := range m.catalogs {
+		imgs, err := m.listCatalog(ctx, cat)
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range imgs {
+			if _, ok := imported[img.GetSoftwareImageId().GetValue()]; !ok {
+				res = append(res, img)
+			}
+		}
+	}
+	return res, nil
+}
+
+// ImportImage imports a catalog image through the importer under its stable
+// id. Concurrent first uses of an image import it once.
+func (m *manager) ImportImage(ctx context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	img, err := m.importer.GetImage(ctx, id)
+	if err == nil || !isNotFound(err) {
+		return img, err
+	}
+	ref, err := m.resolve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	req, err := ref.catalog.ImageSource(ctx, &nfvcommon.Identifier{Value: ref.id})
+	if err != nil {
+		return nil, fmt.Errorf("get source of image '%s' of catalog '%s': %w", ref.id, ref.name, err)
+	}
+	if req.Metadata == nil {
+		req.Metadata = &admin.ImageMetadata{}
+	}
+	if req.Metadata.Metadata == nil {
+		req.Metadata.Metadata = &nfvcommon.Metadata{}
+	}
+	if req.Metadata.Metadata.Fields == nil {
+		req.Metadata.Metadata.Fields = map[string]string{}
+	}
+	req.Metadata.Name = id.GetValue()
+	req.Metadata.Metadata.Fields[image.K8sCatalogImageIdLabel] = id.GetValue()
+	req.Metadata.Metadata.Fields[image.K8sImageCatalogMetadataKey] = ref.name
+	req.Metadata.Metadata.Fields[image.K8sImageCatalogIdMetadataKey] = ref.id
+	if _, err := m.importer.DownloadImage(ctx, req); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("import image '%s' of catalog '%s': %w", ref.id, ref.name, err)
+	}
+	return m.importer.GetImage(ctx, id)
+}
+
+// DeleteImage deletes an imported image. A catalog image that was imported is
+// available again afterwards; the catalog itself is never changed.
+func (m *manager) DeleteImage(ctx context.Context, id *nfvcommon.Identifier, force bool) error {
+	deleter, ok := m.importer.(image.Deleter)
+	if !ok {
+		return fmt.Errorf("image deletion with the configured image manager: %w", apperrors.ErrUnsupported)
+	}
+	err := deleter.DeleteImage(ctx, id, force)
+	if err == nil || !isNotFound(err) {
+		return err
+	}
+	if ref, resolveErr := m.resolve(ctx, id); resolveErr == nil {
+		return fmt.Errorf("delete image '%s' of catalog '%s' not imported: %w", ref.id, ref.name, apperrors.ErrUnsupported)
+	}
+	return err
+}
+
+// UpdateImage updates the attributes of an imported image. A catalog image
+// not imported yet has its attributes in the catalog and cannot be updated.
+func (m *manager) UpdateImage(ctx context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	updater, ok := m.importer.(image.Updater)
+	if !ok {
+		return nil, fmt.Errorf("image update with the configured image manager: %w", apperrors.ErrUnsupported)
+	}
+	img, err := updater.UpdateImage(ctx, id, md)
+	if err == nil || !isNotFound(err) {
+		return img, err
+	}
+	if ref, resolveErr := m.resolve(ctx, id); resolveErr == nil {
+		return nil, fmt.Errorf("update image '%s' of catalog '%s' not imported: %w", ref.id, ref.name, apperrors.ErrUnsupported)
+	}
+	return nil, err
+}
+
+// listCatalog lists the images of cat under their stable ids and indexes them.
+func (m *manager) listCatalog(ctx context.Context, cat Catalog) ([]*vivnfm.SoftwareImageInformation, error) {
+	imgs, err := cat.Catalog.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list images of catalog '%s': %w", cat.Name, err)
+	}
+	res := make([]*vivnfm.SoftwareImageInformation, 0, len(imgs))
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, img := range imgs {
+		catalogId := img.GetSoftwareImageId().GetValue()
+		id := catalogImageId(cat.Name, catalogId)
+		m.refs[id.GetValue()] = catalogRef{catalog: cat.Catalog, name: cat.Name, id: catalogId}
+		res = append(res, catalogImage(img, id, cat.Name, catalogId))
+	}
+	return res, nil
+}
+
+// resolve returns the catalog image id, listing the catalogs if it was not
+// seen yet.
+func (m *manager) resolve(ctx context.Context, id *nfvcommon.Identifier) (catalogRef, error) {
+	notFound := &apperrors.ErrNotFound{Entity: "software image", Identifier: id.GetValue()}
+	if !misc.IsUUID(id.GetValue()) {
+		return catalogRef{}, notFound
+	}
+	m.lock.RLock()
+	ref, ok := m.refs[id.GetValue()]
+	m.lock.RUnlock()
+	if ok {
+		return ref, nil
+	}
+	for _, cat := range m.catalogs {
+		if _, err := m.listCatalog(ctx, cat); err != nil {
+			return catalogRef{}, err
+		}
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if ref, ok := m.refs[id.GetValue()]; ok {
+		return ref, nil
+	}
+	return catalogRef{}, notFound
+}
+
+// catalogImage returns img of catalog name under its stable id.
+func catalogImage(img *vivnfm.SoftwareImageInformation, id *nfvcommon.Identifier, name, catalogId string) *vivnfm.SoftwareImageInformation {
+	img.SoftwareImageId = id
+	img.Status = image.StatusAvailable
+	if img.Metadata == nil {
+		img.Metadata = &nfvcommon.Metadata{}
+	}
+	if img.Metadata.Fields == nil {
+		img.Metadata.Fields = map[string]string{}
+	}
+	img.Metadata.Fields[image.K8sImageCatalogMetadataKey] = name
+	img.Metadata.Fields[image.K8sImageCatalogIdMetadataKey] = catalogId
+	return img
+}
+
+func isNotFound(err error) bool {
+	var notFound *apperrors.ErrNotFound
+	return errors.As(err, &notFound)
+}
@@ -0,0 +1,365 @@
+package composite
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeImporter stands in for the CDI image manager: DownloadImage registers a
+// ready image under the catalog id of the request, like CDI does.
+type fakeImporter struct {
+	admin.UnimplementedAdminServer
+
+	mu        sync.Mutex
+	images    map[string]*vivnfm.SoftwareImageInformation
+	downloads []*admin.DownloadImageRequest
+	deleted   []string
+}
+
+func newFakeImporter(imgs ...*vivnfm.SoftwareImageInformation) *fakeImporter {
+	f := &fakeImporter{images: map[string]*vivnfm.SoftwareImageInformation{}}
+	for _, img := range imgs {
+		f.images[img.GetSoftwareImageId().GetValue()] = img
+	}
+	return f
+}
+
+func (f *fakeImporter) GetImage(_ context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	img, ok := f.images[id.GetValue()]
+	if !ok {
+		return nil, &apperrors.ErrNotFound{Entity: "software image", Identifier: id.GetValue()}
+	}
+	return proto.Clone(img).(*vivnfm.SoftwareImageInformation), nil
+}
+
+func (f *fakeImporter) ListImages(context.Context) ([]*vivnfm.SoftwareImageInformation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := make([]*vivnfm.SoftwareImageInformation, 0, len(f.images))
+	for _, img := range f.images {
+		res = append(res, proto.Clone(img).(*vivnfm.SoftwareImageInformation))
+	}
+	return res, nil
+}
+
+func (f *fakeImporter) DownloadImage(_ context.Context, req *admin.DownloadImageRequest) (*admin.DownloadImageResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downloads = append(f.downloads, req)
+	id := req.GetMetadata().GetMetadata().GetFields()[image.K8sCatalogImageIdLabel]
+	if _, ok := f.images[id]; ok {
+		return nil, k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "volumeimportsources"}, req.GetMetadata().GetName())
+	}
+	f.images[id] = &vivnfm.SoftwareImageInformation{
+		SoftwareImageId: &nfvcommon.Identifier{Value: id},
+		Name:            req.GetMetadata().GetName(),
+		Status:          image.StatusReady,
+		Metadata:        &nfvcommon.Metadata{Fields: req.GetMetadata().GetMetadata().GetFields()},
+	}
+	return &admin.DownloadImageResponse{ImageId: &nfvcommon.Identifier{Value: id}}, nil
+}
+
+func (f *fakeImporter) DeleteImage(_ context.Context, id *nfvcommon.Identifier, _ bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.images[id.GetValue()]; !ok {
+		return &apperrors.ErrNotFound{Entity: "software image", Identifier: id.GetValue()}
+	}
+	delete(f.images, id.GetValue())
+	f.deleted = append(f.deleted, id.GetValue())
+	return nil
+}
+
+func (f *fakeImporter) UpdateImage(_ context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	img, ok := f.images[id.GetValue()]
+	if !ok {
+		return nil, &apperrors.ErrNotFound{Entity: "software image", Identifier: id.GetValue()}
+	}
+	if md.Version != nil {
+		img.Version = md.Version
+	}
+	return proto.Clone(img).(*vivnfm.SoftwareImageInformation), nil
+}
+
+// fakeCatalog lists fixed images whose source is "http://catalog/<id>".
+type fakeCatalog struct {
+	admin.UnimplementedAdminServer
+
+	images []string
+	lists  int
+}
+
+func (f *fakeCatalog) catalogImage(id string) *vivnfm.SoftwareImageInformation {
+	return &vivnfm.SoftwareImageInformation{
+		SoftwareImageId: &nfvcommon.Identifier{Value: id},
+		Name:            id,
+		Status:          image.StatusAvailable,
+	}
+}
+
+func (f *fakeCatalog) GetImage(_ context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	for _, img := range f.images {
+		if img == id.GetValue() {
+			return f.catalogImage(img), nil
+		}
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "image", Identifier: id.GetValue()}
+}
+
+func (f *fakeCatalog) ListImages(context.Context) ([]*vivnfm.SoftwareImageInformation, error) {
+	f.lists++
+	res := make([]*vivnfm.SoftwareImageInformation, 0, len(f.images))
+	for _, img := range f.images {
+		res = append(res, f.catalogImage(img))
+	}
+	return res, nil
+}
+
+func (f *fakeCatalog) ImageSource(_ context.Context, id *nfvcommon.Identifier) (*admin.DownloadImageRequest, error) {
+	return &admin.DownloadImageRequest{
+		Source: &admin.ImageSource{
+			Type: admin.ImageSourceType_HTTP,
+			Http: &admin.HttpSource{Url: "http://catalog/" + id.GetValue()},
+		},
+	}, nil
+}
+
+func setup(imgs ...*vivnfm.SoftwareImageInformation) (*fakeImporter, *fakeCatalog, *fakeCatalog, *manager) {
+	importer := newFakeImporter(imgs...)
+	glance := &fakeCatalog{images: []string{"ubuntu", "cirros"}}
+	local := &fakeCatalog{images: []string{"ubuntu"}}
+	m := NewManager(importer, Catalog{Name: "glance", Catalog: glance}, Catalog{Name: "local", Catalog: local}).(*manager)
+	return importer, glance, local, m
+}
+
+func imageIds(imgs []*vivnfm.SoftwareImageInformation) []string {
+	res := make([]string, 0, len(imgs))
+	for _, img := range imgs {
+		res = append(res, img.GetSoftwareImageId().GetValue())
+	}
+	return res
+}
+
+func TestCatalogImageId(t *testing.T) {
+	t.Parallel()
+	id := catalogImageId("glance", "ubuntu")
+	assert.Equal(t, id.GetValue(), catalogImageId("glance", "ubuntu").GetValue(), "ids are stable")
+	assert.NotEqual(t, id.GetValue(), catalogImageId("local", "ubuntu").GetValue(), "ids differ between catalogs")
+	assert.NotEqual(t, id.GetValue(), catalogImageId("glance", "cirros").GetValue())
+}
+
+func TestListImages(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("merges the catalogs under stable ids", func(t *testing.T) {
+		uploaded := &vivnfm.SoftwareImageInformation{SoftwareImageId: &nfvcommon.Identifier{Value: "uploaded"}, Status: image.StatusReady}
+		_, _, _, m := setup(uploaded)
+		imgs, err := m.ListImages(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			"uploaded",
+			catalogImageId("glance", "ubuntu").GetValue(),
+			catalogImageId("glance", "cirros").GetValue(),
+			catalogImageId("local", "ubuntu").GetValue(),
+		}, imageIds(imgs))
+		for _, img := range imgs[1:] {
+			assert.Equal(t, image.StatusAvailable, img.GetStatus())
+			assert.Contains(t, []string{"glance", "local"}, img.GetMetadata().GetFields()[image.K8sImageCatalogMetadataKey])
+		}
+	})
+
+	t.Run("an imported catalog image is listed once, as imported", func(t *testing.T) {
+		id := catalogImageId("glance", "cirros")
+		_, _, _, m := setup(&vivnfm.SoftwareImageInformation{SoftwareImageId: id, Status: image.StatusReady})
+		imgs, err := m.ListImages(ctx)
+		require.NoError(t, err)
+		require.Len(t, imgs, 3)
+		for _, img := range imgs {
+			if img.GetSoftwareImageId().GetValue() == id.GetValue() {
+				assert.Equal(t, image.StatusReady, img.GetStatus())
+			}
+		}
+	})
+}
+
+func TestGetImage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("catalog image not imported is available", func(t *testing.T) {
+		_, glance, _, m := setup()
+		id := catalogImageId("glance", "ubuntu")
+		img, err := m.GetImage(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, id.GetValue(), img.GetSoftwareImageId().GetValue())
+		assert.Equal(t, "ubuntu", img.GetName())
+		assert.Equal(t, image.StatusAvailable, img.GetStatus())
+		assert.Equal(t, "ubuntu", img.GetMetadata().GetFields()[image.K8sImageCatalogIdMetadataKey])
+		assert.Equal(t, 1, glance.lists, "unknown ids are resolved by listing the catalogs")
+
+		_, err = m.GetImage(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, 1, glance.lists, "resolved ids are remembered")
+	})
+
+	t.Run("unknown image is not found", func(t *testing.T) {
+		_, _, _, m := setup()
+		for _, id := range []string{"not-a-uuid", catalogImageId("glance", "missing").GetValue()} {
+			_, err := m.GetImage(ctx, &nfvcommon.Identifier{Value: id})
+			var notFound *apperrors.ErrNotFound
+			assert.ErrorAs(t, err, &notFound, id)
+		}
+	})
+}
+
+func TestImportImage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("imports the catalog image under its stable id", func(t *testing.T) {
+		importer, _, _, m := setup()
+		id := catalogImageId("local", "ubuntu")
+		img, err := m.ImportImage(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, image.StatusReady, img.GetStatus())
+		assert.Equal(t, id.GetValue(), img.GetSoftwareImageId().GetValue())
+
+		require.Len(t, importer.downloads, 1)
+		req := importer.downloads[0]
+		assert.Equal(t, id.GetValue(), req.GetMetadata().GetName())
+		assert.Equal(t, "http://catalog/ubuntu", req.GetSource().GetHttp().GetUrl())
+		fields := req.GetMetadata().GetMetadata().GetFields()
+		assert.Equal(t, id.GetValue(), fields[image.K8sCatalogImageIdLabel])
+		assert.Equal(t, "local", fields[image.K8sImageCatalogMetadataKey])
+		assert.Equal(t, "ubuntu", fields[image.K8sImageCatalogIdMetadataKey])
+
+		_, err = m.ImportImage(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, importer.downloads, 1, "an imported image is not imported again")
+	})
+
+	t.Run("concurrent import of the same image succeeds", func(t *testing.T) {
+		_, _, _, m := setup()
+		id := catalogImageId("glance", "cirros")
+		racing := &racingImporter{fakeImporter: newFakeImporter()}
+		m.importer = racing
+		img, err := m.ImportImage(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, image.StatusReady, img.GetStatus())
+		assert.Len(t, racing.downloads, 2, "the import of this request found the one of the other request")
+	})
+}
+
+func TestDeleteImage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("deleting an imported catalog image makes it available again", func(t *testing.T) {
+		importer, _, _, m := setup()
+		id := catalogImageId("glance", "ubuntu")
+		_, err := m.ImportImage(ctx, id)
+		require.NoError(t, err)
+		require.NoError(t, m.DeleteImage(ctx, id, false))
+		assert.Equal(t, []string{id.GetValue()}, importer.deleted)
+
+		img, err := m.GetImage(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, image.StatusAvailable, img.GetStatus())
+	})
+
+	t.Run("catalog image not imported can't be deleted", func(t *testing.T) {
+		_, _, _, m := setup()
+		err := m.DeleteImage(ctx, catalogImageId("glance", "ubuntu"), false)
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+
+	t.Run("unknown image is not found", func(t *testing.T) {
+		_, _, _, m := setup()
+		err := m.DeleteImage(ctx, catalogImageId("glance", "missing"), false)
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestUpdateImage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	version := "24.04"
+
+	t.Run("imported catalog image is updated by the importer", func(t *testing.T) {
+		_, _, _, m := setup()
+		id := catalogImageId("glance", "ubuntu")
+		_, err := m.ImportImage(ctx, id)
+		require.NoError(t, err)
+		img, err := m.UpdateImage(ctx, id, &admin.ImageMetadata{Version: &version})
+		require.NoError(t, err)
+		assert.Equal(t, version, img.GetVersion())
+	})
+
+	t.Run("catalog image not imported can't be updated", func(t *testing.T) {
+		_, _, _, m := setup()
+		_, err := m.UpdateImage(ctx, catalogImageId("glance", "ubuntu"), &admin.ImageMetadata{Version: &version})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+
+	t.Run("unknown image is not found", func(t *testing.T) {
+		_, _, _, m := setup()
+		_, err := m.UpdateImage(ctx, catalogImageId("glance", "missing"), &admin.ImageMetadata{Version: &version})
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestGetImageDownloadStatus(t *testing.T) {
+	t.Parallel()
+	_, _, _, m := setup()
+	m.importer = &statusImporter{fakeImporter: newFakeImporter()}
+	id := catalogImageId("glance", "ubuntu")
+	res, err := m.GetImageDownloadStatus(context.Background(), &admin.GetImageDownloadStatusRequest{ImageId: id})
+	require.NoError(t, err)
+	assert.Equal(t, admin.DownloadState_PENDING, res.GetStatus().GetState())
+	assert.Equal(t, id.GetValue(), res.GetStatus().GetImageId().GetValue())
+}
+
+// statusImporter reports every image as not found by GetImageDownloadStatus.
+type statusImporter struct {
+	*fakeImporter
+}
+
+func (s *statusImporter) GetImageDownloadStatus(_ context.Context, req *admin.GetImageDownloadStatusRequest) (*admin.GetImageDownloadStatusResponse, error) {
+	return nil, &apperrors.ErrNotFound{Entity: "software image", Identifier: req.GetImageId().GetValue()}
+}
+
+// racingImporter imports every image once more right before the first
+// DownloadImage, as a concurrent request would.
+type racingImporter struct {
+	*fakeImporter
+	raced bool
+}
+
+func (r *racingImporter) DownloadImage(ctx context.Context, req *admin.DownloadImageRequest) (*admin.DownloadImageResponse, error) {
+	if !r.raced {
+		r.raced = true
+		if _, err := r.fakeImporter.DownloadImage(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return r.fakeImporter.DownloadImage(ctx, req)
+}
@@ -2,14 +2,12 @@ package glance
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"os"
 	"sync"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
-	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/imagedata"
 	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
 	"github.com/gophercloud/gophercloud/pagination"
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
@@ -17,61 +15,115 @@ import (
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
 	"github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-// Image manager for glance image storage
+const (
+	// defaultDomainName is the Keystone domain of the user and the project
+	// unless configured.
+	defaultDomainName = "Default"
+	// authTokenHeader authorizes the image data download of the importer.
+	authTokenHeader = "X-Auth-Token"
+
+	// Glance image properties holding the multihash of the image data.
+	glanceHashAlgoProperty  = "os_hash_algo"
+	glanceHashValueProperty = "os_hash_value"
+)
+
+// Image manager for glance image storage: a catalog of the active images of
+// the project. The images are imported from the Glance image data endpoint
+// with the token of kube-vim. The Keystone authentication happens on first use
+// and is renewed when the token expires.
 // Glance image manager uses global lock to protect shared resources (TODO: Rewrite with lock-free API)
 type manager struct {
 	admin.UnimplementedAdminServer
-	glanceServiceClient *gophercloud.ServiceClient
 
-	lock sync.Mutex
+	authOpts gophercloud.AuthOptions
+	region   string
+
+	glanceServiceClient *gophercloud.ServiceClient
+	lock                sync.Mutex
 }
 
 func NewGlanceImageManager(cfg *config.GlanceImageConfig) (*manager, error) {
-	client, err := openstack.AuthenticatedClient(gophercloud.AuthOptions{
-		// IdentityEndpoint: cfg.Identity.Endpoint,
-		// Username:         cfg.Identity.Username,
-		// Password:         cfg.Identity.Password,
-	})
+	value := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	withDefault := func(v *string) string {
+		if v == nil || *v == "" {
+			return defaultDomainName
+		}
+		return *v
+	}
+	for field, v := range map[string]*string{
+		"identityEndpoint": cfg.IdentityEndpoint,
+		"username":         cfg.Username,
+		"password":         cfg.Password,
+		"projectName":      cfg.ProjectName,
+	} {
+		if value(v) == "" {
+			return nil, &apperrors.ErrInvalidArgument{Field: "config image.glance." + field, Reason: "can't be empty"}
+		}
+	}
+	return &manager{
+		authOpts: gophercloud.AuthOptions{
+			IdentityEndpoint: *cfg.IdentityEndpoint,
+			Username:         *cfg.Username,
+			Password:         *cfg.Password,
+			DomainName:       withDefault(cfg.UserDomainName),
+			Scope: &gophercloud.AuthScope{
+				ProjectName: *cfg.ProjectName,
+				DomainName:  withDefault(cfg.ProjectDomainName),
+			},
+			AllowReauth: true,
+		},
+		region: value(cfg.Region),
+	}, nil
+}
+
+// serviceClient returns the Glance client, authenticating with Keystone first
+// if needed. The caller holds the lock.
+func (m *manager) serviceClient() (*gophercloud.ServiceClient, error) {
+	if m.glanceServiceClient != nil {
+		return m.glanceServiceClient, nil
+	}
+	client, err := openstack.AuthenticatedClient(m.authOpts)
 	if err != nil {
 		return nil, fmt.Errorf("create openstack identity client: %w", err)
 	}
-
 	glanceClient, err := openstack.NewImageServiceV2(client, gophercloud.EndpointOpts{
-		// Region: cfg.Region,
+		Region: m.region,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create glance image service client: %w", err)
 	}
-	return &manager{
-		glanceServiceClient: glanceClient,
-		lock:                sync.Mutex{},
-	}, nil
+	m.glanceServiceClient = glanceClient
+	return glanceClient, nil
 }
 
 func (m *manager) GetImage(ctx context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	if id == nil || id.Value == "" {
-		return nil, &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
-	}
-	getRes := images.Get(m.glanceServiceClient, id.Value)
-	img, err := getRes.Extract()
+	img, err := m.getImage(id)
 	if err != nil {
-		return nil, fmt.Errorf("get image '%s' from glance service: %w", id.Value, err)
+		return nil, err
 	}
-	imgNfv, err := convertImage(img)
-	if err != nil {
-		return nil, fmt.Errorf("convert glance image '%s' to internal struct: %w", id.Value, err)
-	}
-	return imgNfv, nil
+	return convertImage(img), nil
 }
 
 func (m *manager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInformation, error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	pager := images.List(m.glanceServiceClient, images.ListOpts{})
+	glanceClient, err := m.serviceClient()
+	if err != nil {
+		return nil, err
+	}
+	pager := images.List(glanceClient, images.ListOpts{Status: images.ImageStatusActive})
 	if pager.Err != nil {
 		return nil, fmt.Errorf("list images from glance server: %w", pager.Err)
 	}
@@ -81,12 +133,8 @@ func (m *manager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInform
 		if err != nil {
 			return false, fmt.Errorf("extract images from glance list response: %w", err)
 		}
-		for _, img := range imgs {
-			nfvImg, err := convertImage(&img)
-			if err != nil {
-				return false, fmt.Errorf("convert glance image to internal structure: %w", err)
-			}
-			imagesRes = append(imagesRes, nfvImg)
+		for idx := range imgs {
+			imagesRes = append(imagesRes, convertImage(&imgs[idx]))
 		}
 		return true, nil
 	}); err != nil {
@@ -95,31 +143,114 @@ func (m *manager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInform
 	return imagesRes, nil
 }
 
-func (m *manager) UploadImage(ctx context.Context, id *nfvcommon.Identifier, location string) error {
+// ImageSource returns the Glance image data endpoint of an active image,
+// authorized by the current token, with the checksum Glance recorded.
+func (m *manager) ImageSource(ctx context.Context, id *nfvcommon.Identifier) (*admin.DownloadImageRequest, error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	if id == nil || id.Value == "" {
-		return &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
-	}
-	img, err := imagedata.Download(m.glanceServiceClient, id.Value).Extract()
+	img, err := m.getImage(id)
 	if err != nil {
-		return fmt.Errorf("download image '%s' from glance service: %w", id.Value, err)
+		return nil, err
+	}
+	if img.Status != images.ImageStatusActive {
+		return nil, &apperrors.ErrFailedPrecondition{Resource: fmt.Sprintf("glance image '%s'", img.ID), Reason: fmt.Sprintf("status is '%s', not active", img.Status)}
+	}
+	httpSource := &admin.HttpSource{
+		Url:     m.glanceServiceClient.ServiceURL("images", img.ID, "file"),
+		Headers: map[string]string{authTokenHeader: m.glanceServiceClient.Token()},
+	}
+	if algorithm, value := imageChecksum(img); value != "" {
+		httpSource.Checksum = &admin.Checksum{Algorithm: algorithm, Value: value}
+	}
+	nfvImg := convertImage(img)
+	fields := map[string]string{
+		image.K8sImageContainerFormatAnnotation: img.ContainerFormat,
+		image.K8sImageDiskFormatAnnotation:      img.DiskFormat,
 	}
-	defer img.Close()
+	if nfvImg.MinDisk != nil {
+		fields[image.K8sImageMinDiskAnnotation] = nfvImg.MinDisk.String()
+	}
+	if nfvImg.MinRam != nil {
+		fields[image.K8sImageMinRamAnnotation] = nfvImg.MinRam.String()
+	}
+	return &admin.DownloadImageRequest{
+		Metadata: &admin.ImageMetadata{
+			Metadata: &nfvcommon.Metadata{Fields: fields},
+		},
+		Source: &admin.ImageSource{
+			Type: admin.ImageSourceType_HTTP,
+			Http: httpSource,
+		},
+	}, nil
+}
 
-	file, err := os.OpenFile(location, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+// getImage returns the Glance image id. The caller holds the lock.
+func (m *manager) getImage(id *nfvcommon.Identifier) (*images.Image, error) {
+	if id == nil || id.Value == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
+	}
+	glanceClient, err := m.serviceClient()
 	if err != nil {
-		return fmt.Errorf("open/create file '%s' for image '%s': %w", location, id.Value, err)
+		return nil, err
 	}
-	defer file.Close()
-	_, err = io.Copy(file, img)
+	img, err := images.Get(glanceClient, id.Value).Extract()
 	if err != nil {
-		return fmt.Errorf("copy image data for image '%s' to file '%s': %w", id.Value, location, err)
+		var notFound gophercloud.ErrDefault404
+		if errors.As(err, &notFound) {
+			return nil, &apperrors.ErrNotFound{Entity: "glance image", Identifier: id.Value}
+		}
+		return nil, fmt.Errorf("get image '%s' from glance service: %w", id.Value, err)
 	}
-	return nil
+	return img, nil
 }
 
-func convertImage(img *images.Image) (*vivnfm.SoftwareImageInformation, error) {
-	// TODO:
-	return nil, nil
+// imageChecksum returns the multihash of the image if Glance computed one, else
+// its md5 checksum.
+func imageChecksum(img *images.Image) (string, string) {
+	algorithm, _ := img.Properties[glanceHashAlgoProperty].(string)
+	value, _ := img.Properties[glanceHashValueProperty].(string)
+	if algorithm != "" && value != "" {
+		return algorithm, value
+	}
+	if img.Checksum != "" {
+		return "md5", img.Checksum
+	}
+	return "", ""
+}
+
+// convertImage converts a Glance image to the catalog image. Glance gives the
+// minimum disk in GB and the minimum RAM in MB.
+func convertImage(img *images.Image) *vivnfm.SoftwareImageInformation {
+	res := &vivnfm.SoftwareImageInformation{
+		SoftwareImageId: &nfvcommon.Identifier{Value: img.ID},
+		Name:            img.Name,
+		CreatedAt:       misc.ConvertToProtoTimestamp(img.CreatedAt),
+		UpdatedAt:       misc.ConvertToProtoTimestamp(img.UpdatedAt),
+		Size:            resource.NewQuantity(img.SizeBytes, resource.BinarySI),
+		Status:          image.StatusAvailable,
+		Metadata:        &nfvcommon.Metadata{Fields: map[string]string{}},
+	}
+	if res.Name == "" {
+		res.Name = img.ID
+	}
+	if img.ContainerFormat != "" {
+		res.ContainerFormat = &img.ContainerFormat
+	}
+	if img.DiskFormat != "" {
+		res.DiskFormat = &img.DiskFormat
+	}
+	if algorithm, value := imageChecksum(img); value != "" {
+		res.Checksum = &value
+		res.Metadata.Fields[image.K8sImageChecksumAlgorithmAnnotation] = algorithm
+	}
+	if img.MinDiskGigabytes > 0 {
+		res.MinDisk = resource.NewQuantity(int64(img.MinDiskGigabytes)<<30, resource.BinarySI)
+	}
+	if img.MinRAMMegabytes > 0 {
+		res.MinRam = resource.NewQuantity(int64(img.MinRAMMegabytes)<<20, resource.BinarySI)
+	}
+	if img.VirtualSize > 0 {
+		res.Metadata.Fields[image.K8sImageVirtualSizeAnnotation] = fmt.Sprint(img.VirtualSize)
+	}
+	return res
 }
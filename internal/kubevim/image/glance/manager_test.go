@@ -0,0 +1,216 @@
+package glance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testToken   = "gAAAAAtesttoken"
+	testImageId = "4c1d8e33-1d0a-4a4b-9c4f-0f2b8d3e6a10"
+)
+
+var testImage = map[string]any{
+	"id":               testImageId,
+	"name":             "ubuntu-22.04",
+	"status":           "active",
+	"container_format": "bare",
+	"disk_format":      "qcow2",
+	"min_disk":         10,
+	"min_ram":          512,
+	"size":             629145600,
+	"virtual_size":     2361393152,
+	"checksum":         "0123456789abcdef0123456789abcdef",
+	"os_hash_algo":     "sha512",
+	"os_hash_value":    "ffee",
+	"created_at":       "2026-01-02T03:04:05Z",
+	"updated_at":       "2026-01-02T03:04:05Z",
+}
+
+// glanceStandIn serves the Keystone v3 password authentication and the Glance
+// v2 image API. It counts the authentications.
+type glanceStandIn struct {
+	*httptest.Server
+	auths atomic.Int32
+}
+
+func newGlanceStandIn(t *testing.T) *glanceStandIn {
+	t.Helper()
+	s := &glanceStandIn{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /identity/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Auth struct {
+				Identity struct {
+					Password struct {
+						User struct {
+							Name     string `json:"name"`
+							Password string `json:"password"`
+							Domain   struct {
+								Name string `json:"name"`
+							} `json:"domain"`
+						} `json:"user"`
+					} `json:"password"`
+				} `json:"identity"`
+				Scope struct {
+					Project struct {
+						Name string `json:"name"`
+					} `json:"project"`
+				} `json:"scope"`
+			} `json:"auth"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		user := body.Auth.Identity.Password.User
+		if user.Name != "kubevim" || user.Password != "secret" || user.Domain.Name != "Default" || body.Auth.Scope.Project.Name != "nfv" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.auths.Add(1)
+		w.Header().Set("X-Subject-Token", testToken)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"token": map[string]any{
+			"expires_at": "2099-01-01T00:00:00Z",
+			"catalog": []any{map[string]any{
+				"type": "image",
+				"name": "glance",
+				"endpoints": []any{map[string]any{
+					"interface": "public",
+					"region":    "RegionOne",
+					"region_id": "RegionOne",
+					"url":       s.URL + "/image",
+				}},
+			}},
+		}})
+	})
+	mux.HandleFunc("GET /image/v2/images", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "active", r.URL.Query().Get("status"))
+		json.NewEncoder(w).Encode(map[string]any{"images": []any{testImage}})
+	})
+	mux.HandleFunc("GET /image/v2/images/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PathValue("id") != testImageId {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(testImage)
+	})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *glanceStandIn) config() *config.GlanceImageConfig {
+	return &config.GlanceImageConfig{
+		IdentityEndpoint: k8stest.Ptr(s.URL + "/identity/v3"),
+		Username:         k8stest.Ptr("kubevim"),
+		Password:         k8stest.Ptr("secret"),
+		ProjectName:      k8stest.Ptr("nfv"),
+		Region:           k8stest.Ptr("RegionOne"),
+	}
+}
+
+func TestNewGlanceImageManager(t *testing.T) {
+	t.Parallel()
+	srv := newGlanceStandIn(t)
+	cfg := srv.config()
+	cfg.Password = nil
+	_, err := NewGlanceImageManager(cfg)
+	var invalid *apperrors.ErrInvalidArgument
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Field, "password")
+
+	_, err = NewGlanceImageManager(srv.config())
+	require.NoError(t, err)
+	assert.Zero(t, srv.auths.Load(), "keystone is not contacted before first use")
+}
+
+func TestGlanceCatalog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("lists the active images", func(t *testing.T) {
+		srv := newGlanceStandIn(t)
+		m, err := NewGlanceImageManager(srv.config())
+		require.NoError(t, err)
+		imgs, err := m.ListImages(ctx)
+		require.NoError(t, err)
+		require.Len(t, imgs, 1)
+
+		img := imgs[0]
+		assert.Equal(t, testImageId, img.GetSoftwareImageId().GetValue())
+		assert.Equal(t, "ubuntu-22.04", img.GetName())
+		assert.Equal(t, image.StatusAvailable, img.GetStatus())
+		assert.Equal(t, "qcow2", img.GetDiskFormat())
+		assert.Equal(t, "bare", img.GetContainerFormat())
+		assert.Equal(t, "10Gi", img.GetMinDisk().String())
+		assert.Equal(t, "512Mi", img.GetMinRam().String())
+		assert.Equal(t, int64(629145600), img.GetSize().Value())
+		assert.Equal(t, "ffee", img.GetChecksum(), "the multihash is preferred over md5")
+		assert.Equal(t, "sha512", img.GetMetadata().GetFields()[image.K8sImageChecksumAlgorithmAnnotation])
+		assert.Equal(t, "2361393152", img.GetMetadata().GetFields()[image.K8sImageVirtualSizeAnnotation])
+	})
+
+	t.Run("get maps a missing image to not found", func(t *testing.T) {
+		srv := newGlanceStandIn(t)
+		m, err := NewGlanceImageManager(srv.config())
+		require.NoError(t, err)
+		_, err = m.GetImage(ctx, &nfvcommon.Identifier{Value: "00000000-0000-0000-0000-000000000000"})
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+
+		_, err = m.GetImage(ctx, &nfvcommon.Identifier{Value: testImageId})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), srv.auths.Load(), "the token is reused")
+	})
+
+	t.Run("image source is the image data endpoint with the token", func(t *testing.T) {
+		srv := newGlanceStandIn(t)
+		m, err := NewGlanceImageManager(srv.config())
+		require.NoError(t, err)
+		req, err := m.ImageSource(ctx, &nfvcommon.Identifier{Value: testImageId})
+		require.NoError(t, err)
+		assert.Equal(t, admin.ImageSourceType_HTTP, req.GetSource().GetType())
+		httpSource := req.GetSource().GetHttp()
+		assert.Equal(t, srv.URL+"/image/v2/images/"+testImageId+"/file", httpSource.GetUrl())
+		assert.Equal(t, map[string]string{authTokenHeader: testToken}, httpSource.GetHeaders())
+		assert.Equal(t, "sha512", httpSource.GetChecksum().GetAlgorithm())
+		fields := req.GetMetadata().GetMetadata().GetFields()
+		assert.Equal(t, "qcow2", fields[image.K8sImageDiskFormatAnnotation])
+		assert.Equal(t, "10Gi", fields[image.K8sImageMinDiskAnnotation])
+		assert.Equal(t, "512Mi", fields[image.K8sImageMinRamAnnotation])
+	})
+
+	t.Run("bad credentials fail on use", func(t *testing.T) {
+		srv := newGlanceStandIn(t)
+		cfg := srv.config()
+		cfg.Password = k8stest.Ptr("wrong")
+		m, err := NewGlanceImageManager(cfg)
+		require.NoError(t, err)
+		_, err = m.ListImages(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "identity")
+	})
+}
@@ -2,11 +2,11 @@ package http
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
+	"net/url"
+	"time"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
@@ -14,156 +14,225 @@ import (
 	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
-	"github.com/kube-nfv/kube-vim/internal/misc"
-	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
-	"kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 )
 
-var (
-	// Untyped errors for simple cases
-	ErrContentLengthMissing = errors.New("Content-Length header is missing")
+const (
+	indexRequestTimeout = 30 * time.Second
+	// maxIndexSize bounds the JSON image index read from the catalog.
+	maxIndexSize = 16 << 20
 )
 
-// http image manager provides the ability to download software image from the
-// http(s) endpoints. Uploaded image should be able to stored either in pvc or in the
-// kubevirt datavolume.
+// index is the JSON image index of an HTTP catalog:
+//
+//	{"images": [{"id": "ubuntu-22.04", "name": "Ubuntu 22.04", "url": "ubuntu-22.04.qcow2",
+//	  "diskFormat": "qcow2", "checksum": {"algorithm": "sha256", "value": "..."}}]}
+type index struct {
+	Images []indexImage `json:"images"`
+}
+
+type indexImage struct {
+	Id              string `json:"id"`
+	Name            string `json:"name"`
+	Url             string `json:"url"`
+	Provider        string `json:"provider,omitempty"`
+	Version         string `json:"version,omitempty"`
+	DiskFormat      string `json:"diskFormat,omitempty"`
+	ContainerFormat string `json:"containerFormat,omitempty"`
+	Checksum        *struct {
+		Algorithm string `json:"algorithm"`
+		Value     string `json:"value"`
+	} `json:"checksum,omitempty"`
+	// MinDisk, MinRam and Size are resource quantities, e.g. "10Gi".
+	MinDisk  string            `json:"minDisk,omitempty"`
+	MinRam   string            `json:"minRam,omitempty"`
+	Size     string            `json:"size,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// http image manager is a catalog of the images listed in a JSON index served
+// over HTTP(S). The index is read on every request, so it can be updated in
+// place. The images are imported from their URL.
 type manager struct {
 	admin.UnimplementedAdminServer
 
-	cdiCtrl *image.CdiController
+	catalogUrl *url.URL
+	client     *http.Client
 }
 
 // initialize new http image manager from the specified configuration
-func NewHttpImageManager(cdiCtrl *image.CdiController, cfg *config.HttpImageConfig) (*manager, error) {
+func NewHttpImageManager(cfg *config.HttpImageConfig) (*manager, error) {
+	if cfg.CatalogUrl == nil || *cfg.CatalogUrl == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "config image.http.catalogUrl", Reason: "can't be empty"}
+	}
+	catalogUrl, err := url.Parse(*cfg.CatalogUrl)
+	if err != nil || (catalogUrl.Scheme != "http" && catalogUrl.Scheme != "https") {
+		return nil, &apperrors.ErrInvalidArgument{Field: "config image.http.catalogUrl", Reason: fmt.Sprintf("'%s' is not an http(s) URL", *cfg.CatalogUrl)}
+	}
 	return &manager{
-		cdiCtrl: cdiCtrl,
+		catalogUrl: catalogUrl,
+		client:     &http.Client{Timeout: indexRequestTimeout},
 	}, nil
 }
 
-// get http image and store it in the kubevirt DV (Data Volume) or in the PV claimed by PVC.
-// Note: For http image manager image Identifier should be full url path if image not exists yet.
-// If image already created it might be identified by either DV name, DV UID or source url.
-// TODO(dmaloval)
-//
-//	Add ability to works with different storage clases (as well as WaitForFirstConsumer mode)
-func (m *manager) GetImage(ctx context.Context, imageId *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
-	if imageId == nil || imageId.GetValue() == "" {
-		return nil, &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
-	}
-	isSource := false
-	getDvOpts := []image.GetDvOrVisOpt{}
-	if strings.HasPrefix(imageId.GetValue(), "http") || strings.HasPrefix(imageId.GetValue(), "https") {
-		getDvOpts = append(getDvOpts, image.FindBySourceUrl(imageId.GetValue()))
-		isSource = true
-	} else if misc.IsUUID(imageId.GetValue()) {
-		getDvOpts = append(getDvOpts, image.FindByUID(imageId.GetValue()))
-	} else {
-		getDvOpts = append(getDvOpts, image.FindByName(imageId.GetValue()))
-	}
-	vis, err := m.cdiCtrl.GetVolumeImportSource(ctx, getDvOpts...)
-	if err == nil {
-		return softwareImageInfoFromVolumeImportSource(vis)
-	}
-	var notFoundErr *apperrors.ErrNotFound
-	if !k8s_errors.IsNotFound(err) && !errors.As(err, &notFoundErr) {
-		return nil, fmt.Errorf("get k8s data volume for image '%s': %w", imageId.GetValue(), err)
-	}
-	// Data volume not found and need to be created.
-	if !isSource {
-		return nil, fmt.Errorf("initial image placement requires source URL as imageId: %w", apperrors.ErrUnsupported)
-	}
-	vis, err = m.cdiCtrl.CreateVolumeImportSource(ctx, &v1beta1.ImportSourceType{
-		HTTP: &v1beta1.DataVolumeSourceHTTP{
-			URL: imageId.GetValue(),
-		},
-	})
+func (m *manager) GetImage(ctx context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	img, err := m.getIndexImage(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("create k8s VolumeImportSource resource: %w", err)
+		return nil, err
 	}
-	return softwareImageInfoFromVolumeImportSource(vis)
+	return m.convertImage(img)
 }
 
 func (m *manager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInformation, error) {
-	images, err := m.cdiCtrl.ListVolumeImportSources(ctx)
+	idx, err := m.readIndex(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("list volume import sources: %w", err)
+		return nil, err
 	}
-	res := make([]*vivnfm.SoftwareImageInformation, 0, len(images))
-	for idx := range images {
-		imgRef := &images[idx]
-		imgInfo, err := softwareImageInfoFromVolumeImportSource(imgRef)
+	res := make([]*vivnfm.SoftwareImageInformation, 0, len(idx.Images))
+	for i := range idx.Images {
+		img, err := m.convertImage(&idx.Images[i])
 		if err != nil {
-			return nil, fmt.Errorf("convert volume import source to image info: %w", err)
+			return nil, fmt.Errorf("convert catalog image '%s': %w", idx.Images[i].Id, err)
 		}
-		res = append(res, imgInfo)
+		res = append(res, img)
 	}
 	return res, nil
 }
 
-func (m *manager) UploadImage(context.Context, *nfvcommon.Identifier, string /*location*/) error {
+// ImageSource returns the HTTP source of the image, with its checksum if the
+// index has one.
+func (m *manager) ImageSource(ctx context.Context, id *nfvcommon.Identifier) (*admin.DownloadImageRequest, error) {
+	img, err := m.getIndexImage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	imgUrl, err := m.imageUrl(img)
+	if err != nil {
+		return nil, err
+	}
+	httpSource := &admin.HttpSource{Url: imgUrl}
+	if img.Checksum != nil {
+		httpSource.Checksum = &admin.Checksum{Algorithm: img.Checksum.Algorithm, Value: img.Checksum.Value}
+	}
+	md := map[string]string{}
+	for key, value := range map[string]string{
+		image.K8sImageDiskFormatAnnotation:      img.DiskFormat,
+		image.K8sImageContainerFormatAnnotation: img.ContainerFormat,
+		image.K8sImageMinDiskAnnotation:         img.MinDisk,
+		image.K8sImageMinRamAnnotation:          img.MinRam,
+	} {
+		if value != "" {
+			md[key] = value
+		}
+	}
+	return &admin.DownloadImageRequest{
+		Metadata: &admin.ImageMetadata{
+			Provider: optional(img.Provider),
+			Version:  optional(img.Version),
+			Metadata: &nfvcommon.Metadata{Fields: md},
+		},
+		Source: &admin.ImageSource{
+			Type: admin.ImageSourceType_HTTP,
+			Http: httpSource,
+		},
+	}, nil
+}
 
-	return apperrors.ErrNotImplemented
+func (m *manager) getIndexImage(ctx context.Context, id *nfvcommon.Identifier) (*indexImage, error) {
+	if id == nil || id.Value == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
+	}
+	idx, err := m.readIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range idx.Images {
+		if idx.Images[i].Id == id.Value {
+			return &idx.Images[i], nil
+		}
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "image", Identifier: id.Value}
 }
 
-// TODO: HTTP HEAD returns actual image size, while PVC need to be created with virtual.
-// Add qemu-img size check
-// Also some http enpoints not supports http HEAD (ex. S3)
-func tryCalculeteContentLength(url string) (int64, error) {
-	resp, err := http.Head(url)
+func (m *manager) readIndex(ctx context.Context) (*index, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.catalogUrl.String(), nil)
 	if err != nil {
-		return 0, fmt.Errorf("make HEAD request to '%s': %w", url, err)
+		return nil, fmt.Errorf("build image index request: %w", err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get image index '%s': %w", m.catalogUrl, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("http request to '%s' failed with status: %s", url, resp.Status)
+		return nil, fmt.Errorf("get image index '%s': unexpected status %s", m.catalogUrl, resp.Status)
 	}
-	contentLength := resp.Header.Get("Content-Length")
-	if contentLength == "" {
-		return 0, ErrContentLengthMissing
+	idx := &index{}
+	if err := json.NewDecoder(http.MaxBytesReader(nil, resp.Body, maxIndexSize)).Decode(idx); err != nil {
+		return nil, fmt.Errorf("decode image index '%s': %w", m.catalogUrl, err)
 	}
-	size, err := strconv.ParseInt(contentLength, 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("parse Content-Length header for '%s': %w", url, err)
-	}
-	return size, nil
+	return idx, nil
 }
 
-func softwareImageInfoFromDv(dv *v1beta1.DataVolume) *vivnfm.SoftwareImageInformation {
-	return &vivnfm.SoftwareImageInformation{
-		SoftwareImageId: &nfvcommon.Identifier{
-			Value: string(dv.GetUID()),
-		},
-		Name: dv.Name,
-		Size: dv.Spec.Storage.Resources.Requests.Storage(),
+// imageUrl resolves the URL of img against the catalog URL.
+func (m *manager) imageUrl(img *indexImage) (string, error) {
+	ref, err := url.Parse(img.Url)
+	if err != nil || img.Url == "" {
+		return "", &apperrors.ErrInvalidArgument{Field: fmt.Sprintf("url of catalog image '%s'", img.Id), Reason: fmt.Sprintf("'%s' is not a URL", img.Url)}
 	}
+	return m.catalogUrl.ResolveReference(ref).String(), nil
 }
 
-func softwareImageInfoFromVolumeImportSource(vis *v1beta1.VolumeImportSource) (*vivnfm.SoftwareImageInformation, error) {
-	if !misc.IsObjectInstantiated(vis) {
-		return nil, &apperrors.ErrK8sObjectNotInstantiated{ObjectType: "VolumeImportSource"}
+func (m *manager) convertImage(img *indexImage) (*vivnfm.SoftwareImageInformation, error) {
+	imgUrl, err := m.imageUrl(img)
+	if err != nil {
+		return nil, err
+	}
+	quantity := func(field, value string) (*resource.Quantity, error) {
+		if value == "" {
+			return nil, nil
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, &apperrors.ErrInvalidArgument{Field: fmt.Sprintf("%s of catalog image '%s'", field, img.Id), Reason: err.Error()}
+		}
+		return &q, nil
 	}
-	if !misc.IsObjectManagedByKubeNfv(vis) {
-		return nil, &apperrors.ErrK8sObjectNotManagedByKubeNfv{ObjectType: "VolumeImportSource", ObjectName: vis.Name, ObjectId: string(vis.GetUID())}
+	res := &vivnfm.SoftwareImageInformation{
+		SoftwareImageId: &nfvcommon.Identifier{Value: img.Id},
+		Name:            img.Name,
+		Provider:        optional(img.Provider),
+		Version:         optional(img.Version),
+		DiskFormat:      optional(img.DiskFormat),
+		ContainerFormat: optional(img.ContainerFormat),
+		Status:          image.StatusAvailable,
+		Metadata:        &nfvcommon.Metadata{Fields: map[string]string{image.K8sSourceUrlAnnotation: imgUrl}},
 	}
-	if source, ok := vis.Labels[image.K8sSourceLabel]; !ok || (source != string(image.HTTP) && source != string(image.HTTPS)) {
-		return nil, fmt.Errorf("http image manager cannot convert image with '%s' source: %w", source, apperrors.ErrUnsupported)
+	if res.Name == "" {
+		res.Name = img.Id
 	}
-	metadata := &nfvcommon.Metadata{
-		Fields: vis.Labels,
+	if img.Checksum != nil {
+		res.Checksum = &img.Checksum.Value
+		res.Metadata.Fields[image.K8sImageChecksumAlgorithmAnnotation] = img.Checksum.Algorithm
 	}
-	for k, v := range vis.Annotations {
-		metadata.Fields[k] = v
+	if res.MinDisk, err = quantity("minDisk", img.MinDisk); err != nil {
+		return nil, err
 	}
-	return &vivnfm.SoftwareImageInformation{
-		SoftwareImageId: &nfvcommon.Identifier{
-			Value: string(vis.GetUID()),
-		},
-		Name:      vis.Name,
-		CreatedAt: misc.ConvertK8sTimeToProtoTimestamp(vis.CreationTimestamp),
-		UpdatedAt: misc.ConvertK8sTimeToProtoTimestamp(vis.CreationTimestamp),
-		// Temportary solution to allocated 5 Gi to the image
-		Size:     resource.NewQuantity(5*1024*1024*1024, resource.BinarySI),
-		Metadata: metadata,
-	}, nil
+	if res.MinRam, err = quantity("minRam", img.MinRam); err != nil {
+		return nil, err
+	}
+	if res.Size, err = quantity("size", img.Size); err != nil {
+		return nil, err
+	}
+	for key, value := range img.Metadata {
+		res.Metadata.Fields[key] = value
+	}
+	return res, nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
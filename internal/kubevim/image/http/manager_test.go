@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIndex = `{"images": [
+  {"id": "ubuntu-22.04", "name": "Ubuntu 22.04", "url": "images/ubuntu-22.04.qcow2", "provider": "canonical",
+   "diskFormat": "qcow2", "checksum": {"algorithm": "sha256", "value": "abc123"}, "minDisk": "10Gi", "size": "600Mi"},
+  {"id": "cirros", "url": "https://mirror.example.com/cirros.img"}
+]}`
+
+func newTestManager(t *testing.T) *manager {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog/index.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testIndex))
+	}))
+	t.Cleanup(srv.Close)
+	m, err := NewHttpImageManager(&config.HttpImageConfig{CatalogUrl: k8stest.Ptr(srv.URL + "/catalog/index.json")})
+	require.NoError(t, err)
+	return m
+}
+
+func TestNewHttpImageManager(t *testing.T) {
+	t.Parallel()
+	for _, catalogUrl := range []*string{nil, k8stest.Ptr(""), k8stest.Ptr("ftp://example.com/index.json")} {
+		_, err := NewHttpImageManager(&config.HttpImageConfig{CatalogUrl: catalogUrl})
+		var invalid *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &invalid)
+	}
+}
+
+func TestHttpCatalog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("lists the index images", func(t *testing.T) {
+		m := newTestManager(t)
+		imgs, err := m.ListImages(ctx)
+		require.NoError(t, err)
+		require.Len(t, imgs, 2)
+
+		ubuntu := imgs[0]
+		assert.Equal(t, "ubuntu-22.04", ubuntu.GetSoftwareImageId().GetValue())
+		assert.Equal(t, "Ubuntu 22.04", ubuntu.GetName())
+		assert.Equal(t, image.StatusAvailable, ubuntu.GetStatus())
+		assert.Equal(t, "canonical", ubuntu.GetProvider())
+		assert.Equal(t, "qcow2", ubuntu.GetDiskFormat())
+		assert.Equal(t, "abc123", ubuntu.GetChecksum())
+		assert.Equal(t, "10Gi", ubuntu.GetMinDisk().String())
+		assert.Equal(t, "600Mi", ubuntu.GetSize().String())
+		assert.Equal(t, "sha256", ubuntu.GetMetadata().GetFields()[image.K8sImageChecksumAlgorithmAnnotation])
+		assert.Equal(t, m.catalogUrl.JoinPath("..", "images/ubuntu-22.04.qcow2").String(), ubuntu.GetMetadata().GetFields()[image.K8sSourceUrlAnnotation])
+
+		// The id stands in for a missing name.
+		assert.Equal(t, "cirros", imgs[1].GetName())
+	})
+
+	t.Run("gets an image by its index id", func(t *testing.T) {
+		m := newTestManager(t)
+		img, err := m.GetImage(ctx, &nfvcommon.Identifier{Value: "cirros"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://mirror.example.com/cirros.img", img.GetMetadata().GetFields()[image.K8sSourceUrlAnnotation])
+
+		_, err = m.GetImage(ctx, &nfvcommon.Identifier{Value: "missing"})
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("image source is the resolved URL with the checksum and attributes", func(t *testing.T) {
+		m := newTestManager(t)
+		req, err := m.ImageSource(ctx, &nfvcommon.Identifier{Value: "ubuntu-22.04"})
+		require.NoError(t, err)
+		assert.Equal(t, admin.ImageSourceType_HTTP, req.GetSource().GetType())
+		assert.Equal(t, m.catalogUrl.JoinPath("..", "images/ubuntu-22.04.qcow2").String(), req.GetSource().GetHttp().GetUrl())
+		assert.Equal(t, "sha256", req.GetSource().GetHttp().GetChecksum().GetAlgorithm())
+		assert.Equal(t, "abc123", req.GetSource().GetHttp().GetChecksum().GetValue())
+		assert.Equal(t, "canonical", req.GetMetadata().GetProvider())
+		fields := req.GetMetadata().GetMetadata().GetFields()
+		assert.Equal(t, "qcow2", fields[image.K8sImageDiskFormatAnnotation])
+		assert.Equal(t, "10Gi", fields[image.K8sImageMinDiskAnnotation])
+		assert.NotContains(t, fields, image.K8sImageMinRamAnnotation)
+	})
+
+	t.Run("unreachable index fails", func(t *testing.T) {
+		m, err := NewHttpImageManager(&config.HttpImageConfig{CatalogUrl: k8stest.Ptr("http://127.0.0.1:1/index.json")})
+		require.NoError(t, err)
+		_, err = m.ListImages(ctx)
+		assert.Error(t, err)
+	})
+}
@@ -2,27 +2,55 @@ package local
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
 	"github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+const (
+	// defaultPort mirrors the default in the config schema. It is not a Viper
+	// default, which would configure the local catalog everywhere.
+	defaultPort = 8090
+
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// local image manager is a catalog of the image files of a directory. The CDI
+// importer can't read the directory, so the files are served over HTTP (Start)
+// and imported from the configured URL. Only the regular, non-hidden files at
+// the top of the directory are images.
 type manager struct {
 	admin.UnimplementedAdminServer
+
 	location string
-	lock     sync.Mutex
+	baseUrl  *url.URL
+	server   *http.Server
+	port     int
+	logger   *zap.Logger
 }
 
-func NewLocalImageManager(cfg *config.LocalImageConfig) (*manager, error) {
+func NewLocalImageManager(cfg *config.LocalImageConfig, logger *zap.Logger) (*manager, error) {
+	if cfg.Location == nil || *cfg.Location == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "config image.local.location", Reason: "can't be empty"}
+	}
 	stat, err := os.Stat(*cfg.Location)
 	if err != nil {
 		return nil, fmt.Errorf("get file stats for location '%s': %w", *cfg.Location, err)
@@ -33,112 +61,188 @@ func NewLocalImageManager(cfg *config.LocalImageConfig) (*manager, error) {
 	if stat.Mode()&0400 == 0 {
 		return nil, &apperrors.ErrPermissionDenied{Resource: fmt.Sprintf("location '%s'", *cfg.Location), Reason: "no read permissions"}
 	}
-	if stat.Mode()&0200 == 0 {
-		return nil, &apperrors.ErrPermissionDenied{Resource: fmt.Sprintf("location '%s'", *cfg.Location), Reason: "no write permissions"}
+	if cfg.Url == nil || *cfg.Url == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "config image.local.url", Reason: "can't be empty"}
+	}
+	baseUrl, err := url.Parse(*cfg.Url)
+	if err != nil || (baseUrl.Scheme != "http" && baseUrl.Scheme != "https") {
+		return nil, &apperrors.ErrInvalidArgument{Field: "config image.local.url", Reason: fmt.Sprintf("'%s' is not an http(s) URL", *cfg.Url)}
+	}
+	port := defaultPort
+	if cfg.Port != nil {
+		port = *cfg.Port
 	}
-	return &manager{
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	m := &manager{
 		location: *cfg.Location,
-		lock:     sync.Mutex{},
-	}, nil
+		baseUrl:  baseUrl,
+		port:     port,
+		logger:   logger,
+	}
+	m.server = &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(port)),
+		Handler:           m,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	return m, nil
+}
+
+// Start serves the image files until ctx is cancelled.
+func (m *manager) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		m.logger.Info("local image server started", zap.Int("port", m.port), zap.String("location", m.location))
+		if err := m.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := m.server.Shutdown(shutdownCtx); err != nil {
+			m.logger.Warn("local image server shutdown", zap.Error(err))
+		}
+		return nil
+	case err := <-errCh:
+		return fmt.Errorf("serve local images: %w", err)
+	}
+}
+
+// ServeHTTP serves GET and HEAD of "/<image file>", with range requests, which
+// the size discovery of the importer relies on.
+func (m *manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	info, err := m.imageFile(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(filepath.Join(m.location, info.Name()))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
 
 func (m *manager) GetImage(ctx context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
-	m.lock.Lock()
-	defer m.lock.Unlock()
 	if id == nil || id.Value == "" {
 		return nil, &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
 	}
-	files, err := os.ReadDir(m.location)
+	files, err := m.imageFiles()
 	if err != nil {
-		return nil, fmt.Errorf("read image directory '%s': %w", m.location, err)
+		return nil, err
 	}
 	for _, file := range files {
 		if nameMatchRule(id, file) {
-			return convertImage(file)
+			return convertImage(file), nil
 		}
 	}
 	return nil, &apperrors.ErrNotFound{Entity: "image", Identifier: id.Value}
 }
 
 func (m *manager) ListImages(ctx context.Context) ([]*vivnfm.SoftwareImageInformation, error) {
-	m.lock.Lock()
-	defer m.lock.Unlock()
-	files, err := os.ReadDir(m.location)
+	files, err := m.imageFiles()
 	if err != nil {
-		return nil, fmt.Errorf("read image directory '%s': %w", m.location, err)
+		return nil, err
 	}
 	res := make([]*vivnfm.SoftwareImageInformation, 0, len(files))
 	for _, file := range files {
-		imageInfo, err := convertImage(file)
-		if err != nil {
-			continue
-		}
-		res = append(res, imageInfo)
+		res = append(res, convertImage(file))
 	}
 	return res, nil
 }
 
-func (m *manager) UploadImage(ctx context.Context, id *nfvcommon.Identifier, location string) error {
-	if id == nil || id.Value == "" {
-		return &apperrors.ErrInvalidArgument{Field: "image id", Reason: "cannot be empty"}
-	}
-	m.lock.Lock()
-	defer m.lock.Unlock()
+// ImageSource returns the URL the image file is served at.
+func (m *manager) ImageSource(ctx context.Context, id *nfvcommon.Identifier) (*admin.DownloadImageRequest, error) {
+	img, err := m.GetImage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	if img.DiskFormat != nil {
+		fields[image.K8sImageDiskFormatAnnotation] = img.GetDiskFormat()
+	}
+	return &admin.DownloadImageRequest{
+		Metadata: &admin.ImageMetadata{
+			Metadata: &nfvcommon.Metadata{Fields: fields},
+		},
+		Source: &admin.ImageSource{
+			Type: admin.ImageSourceType_HTTP,
+			Http: &admin.HttpSource{Url: m.baseUrl.JoinPath(img.SoftwareImageId.GetValue()).String()},
+		},
+	}, nil
+}
 
-	files, err := os.ReadDir(m.location)
+// imageFiles returns the image files of the directory.
+func (m *manager) imageFiles() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(m.location)
 	if err != nil {
-		return fmt.Errorf("read source directory '%s': %w", m.location, err)
+		return nil, fmt.Errorf("read image directory '%s': %w", m.location, err)
 	}
-	var sourceFilePath string
-	for _, file := range files {
-		if nameMatchRule(id, file) {
-			sourceFilePath = filepath.Join(m.location, file.Name())
-			break
+	res := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
 		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read.
+			continue
+		}
+		res = append(res, info)
 	}
-	if sourceFilePath == "" {
-		return &apperrors.ErrNotFound{Entity: "image file", Identifier: id.Value}
-	}
-
-	destDir := filepath.Dir(location)
-	info, err := os.Stat(destDir)
-	if os.IsNotExist(err) {
-		return &apperrors.ErrNotFound{Entity: "destination directory", Identifier: destDir}
-	}
-	if err != nil || !info.IsDir() {
-		return fmt.Errorf("access destination directory '%s': %w", destDir, err)
-	}
-
-	sourceFile, err := os.Open(sourceFilePath)
-	if err != nil {
-		return fmt.Errorf("open source file '%s' for image '%s': %w", sourceFilePath, id.Value, err)
-	}
-	defer sourceFile.Close()
+	return res, nil
+}
 
-	destinationFilePath := filepath.Join(location, id.Value)
-	destFile, err := os.Create(destinationFilePath)
+// imageFile returns the image file name of the directory.
+func (m *manager) imageFile(name string) (os.FileInfo, error) {
+	files, err := m.imageFiles()
 	if err != nil {
-		return fmt.Errorf("create destination file '%s' for image '%s': %w", destinationFilePath, id.Value, err)
+		return nil, err
 	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		return fmt.Errorf("copy image '%s' from '%s' to '%s': %w", id.Value, sourceFilePath, destinationFilePath, err)
+	for _, file := range files {
+		if file.Name() == name {
+			return file, nil
+		}
 	}
-	return nil
+	return nil, &apperrors.ErrNotFound{Entity: "image file", Identifier: name}
 }
 
-func convertImage(file os.DirEntry) (*vivnfm.SoftwareImageInformation, error) {
-	// TODO:
-	return &vivnfm.SoftwareImageInformation{}, nil
+// convertImage converts an image file to the catalog image. Its id is the file
+// name and its name the file name without extension.
+func convertImage(file os.FileInfo) *vivnfm.SoftwareImageInformation {
+	fileName := file.Name()
+	res := &vivnfm.SoftwareImageInformation{
+		SoftwareImageId: &nfvcommon.Identifier{Value: fileName},
+		Name:            strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		CreatedAt:       misc.ConvertToProtoTimestamp(file.ModTime()),
+		UpdatedAt:       misc.ConvertToProtoTimestamp(file.ModTime()),
+		Size:            resource.NewQuantity(file.Size(), resource.BinarySI),
+		Status:          image.StatusAvailable,
+		Metadata:        &nfvcommon.Metadata{Fields: map[string]string{}},
+	}
+	// The importer decompresses gz and xz files.
+	switch ext := strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(strings.TrimSuffix(fileName, ".gz"), ".xz")), "."); ext {
+	case "qcow2", "raw", "iso", "vmdk", "vhd", "vhdx":
+		res.DiskFormat = &ext
+	}
+	return res
 }
 
 // nameMatchRule checks if the file name matches the identifier, ignoring the extension.
 // Rule is based on the name equality without extension eg.
 // nameMatchRule("ubuntu-22.0.4", file{name: ubuntu-22.0.4}) -> true (equal match)
 // nameMatchRule("ubuntu-22.0.4", file{name: ubuntu-22.0.4.iso}) -> true (match without extension)
-func nameMatchRule(id *nfvcommon.Identifier, file os.DirEntry) bool {
+func nameMatchRule(id *nfvcommon.Identifier, file os.FileInfo) bool {
 	if id == nil {
 		return false
 	}
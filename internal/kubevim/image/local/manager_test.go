@@ -0,0 +1,121 @@
+package local
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestManager builds the local catalog over a directory holding two images,
+// a hidden file and a sub-directory.
+func newTestManager(t *testing.T) *manager {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"cirros.qcow2":      "cirros image",
+		"ubuntu.raw.xz":     "ubuntu",
+		".ubuntu.raw.xz.sw": "editor swap",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+	m, err := NewLocalImageManager(&config.LocalImageConfig{
+		Location: &dir,
+		Url:      k8stest.Ptr("http://kube-vim-images.kube-nfv.svc:8090/"),
+	}, nil)
+	require.NoError(t, err)
+	return m
+}
+
+func TestNewLocalImageManager(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	var invalid *apperrors.ErrInvalidArgument
+	_, err := NewLocalImageManager(&config.LocalImageConfig{Location: &file, Url: k8stest.Ptr("http://images")}, nil)
+	assert.ErrorAs(t, err, &invalid, "location is not a directory")
+	_, err = NewLocalImageManager(&config.LocalImageConfig{Location: &dir}, nil)
+	assert.ErrorAs(t, err, &invalid, "url is required")
+	_, err = NewLocalImageManager(&config.LocalImageConfig{Location: &dir, Url: k8stest.Ptr("images")}, nil)
+	assert.ErrorAs(t, err, &invalid, "url must be absolute")
+
+	m, err := NewLocalImageManager(&config.LocalImageConfig{Location: &dir, Url: k8stest.Ptr("http://images")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultPort, m.port)
+}
+
+func TestLocalCatalog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("lists the image files", func(t *testing.T) {
+		m := newTestManager(t)
+		imgs, err := m.ListImages(ctx)
+		require.NoError(t, err)
+		require.Len(t, imgs, 2)
+		assert.Equal(t, "cirros.qcow2", imgs[0].GetSoftwareImageId().GetValue())
+		assert.Equal(t, "cirros", imgs[0].GetName())
+		assert.Equal(t, "qcow2", imgs[0].GetDiskFormat())
+		assert.Equal(t, int64(len("cirros image")), imgs[0].GetSize().Value())
+		assert.Equal(t, image.StatusAvailable, imgs[0].GetStatus())
+		assert.Equal(t, "ubuntu.raw.xz", imgs[1].GetSoftwareImageId().GetValue())
+		assert.Equal(t, "raw", imgs[1].GetDiskFormat(), "the format is looked up through the compression")
+	})
+
+	t.Run("gets an image by file name with or without extension", func(t *testing.T) {
+		m := newTestManager(t)
+		for _, id := range []string{"cirros.qcow2", "cirros"} {
+			img, err := m.GetImage(ctx, &nfvcommon.Identifier{Value: id})
+			require.NoError(t, err)
+			assert.Equal(t, "cirros.qcow2", img.GetSoftwareImageId().GetValue())
+		}
+		_, err := m.GetImage(ctx, &nfvcommon.Identifier{Value: "nested"})
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("image source is the served file", func(t *testing.T) {
+		m := newTestManager(t)
+		req, err := m.ImageSource(ctx, &nfvcommon.Identifier{Value: "cirros"})
+		require.NoError(t, err)
+		assert.Equal(t, "http://kube-vim-images.kube-nfv.svc:8090/cirros.qcow2", req.GetSource().GetHttp().GetUrl())
+		assert.Equal(t, "qcow2", req.GetMetadata().GetMetadata().GetFields()[image.K8sImageDiskFormatAnnotation])
+	})
+
+	t.Run("serves ranges of image files only", func(t *testing.T) {
+		srv := httptest.NewServer(newTestManager(t))
+		defer srv.Close()
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/cirros.qcow2", nil)
+		require.NoError(t, err)
+		req.Header.Set("Range", "bytes=0-5")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "cirros", string(body))
+		assert.Equal(t, "bytes 0-5/12", resp.Header.Get("Content-Range"))
+
+		for _, path := range []string{"/.ubuntu.raw.xz.sw", "/nested", "/", "/../manager.go"} {
+			resp, err := http.Get(srv.URL + path)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+		}
+	})
+}
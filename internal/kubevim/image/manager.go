@@ -18,6 +18,70 @@ const (
 	K8sSourceUrlAnnotation  = "image.kubevim.kubenfv.io/source-url"
 	K8sIsImageBoundToPvc    = "image.kubevim.kubenfv.io/is-pvc-bound"
 	K8sImagePvcStorageClass = "image.kubevim.kubenfv.io/storage-class"
+
+	// DownloadImage metadata (ImageMetadata.metadata) keys naming the Secret and
+	// the CA ConfigMap of the image source, in the kube-vim namespace. The admin
+	// ImageSource messages have no field for them yet.
+	SourceSecretRefMetadataKey     = "image.kubevim.kubenfv.io/source-secret"
+	SourceCertConfigMapMetadataKey = "image.kubevim.kubenfv.io/source-cert-configmap"
+	// SourceHeaderSecretRefMetadataKey names a Secret whose values are extra
+	// HTTP header lines, e.g. "Authorization: Bearer <token>".
+	SourceHeaderSecretRefMetadataKey = "image.kubevim.kubenfv.io/source-header-secret"
+	// SourceCaBundleMetadataKey carries a PEM CA bundle of the HTTP source,
+	// which kube-vim stores in a ConfigMap owned by the image.
+	SourceCaBundleMetadataKey = "image.kubevim.kubenfv.io/source-ca-bundle"
+
+	// Image attribute annotations of the image record. The disk and container
+	// formats and the minimum disk and RAM are taken from the DownloadImage
+	// metadata keys of the same name.
+	K8sImageChecksumAnnotation          = "image.kubevim.kubenfv.io/checksum"
+	K8sImageChecksumAlgorithmAnnotation = "image.kubevim.kubenfv.io/checksum-algorithm"
+	K8sImageProviderAnnotation          = "image.kubevim.kubenfv.io/provider"
+	K8sImageVersionAnnotation           = "image.kubevim.kubenfv.io/version"
+	K8sImageDiskFormatAnnotation        = "image.kubevim.kubenfv.io/disk-format"
+	K8sImageContainerFormatAnnotation   = "image.kubevim.kubenfv.io/container-format"
+	K8sImageMinDiskAnnotation           = "image.kubevim.kubenfv.io/min-disk"
+	K8sImageMinRamAnnotation            = "image.kubevim.kubenfv.io/min-ram"
+	// Sizes of the image learned before its import: the source as transferred
+	// and the disk it holds, in bytes.
+	K8sImageCompressedSizeAnnotation = "image.kubevim.kubenfv.io/compressed-size"
+	K8sImageVirtualSizeAnnotation    = "image.kubevim.kubenfv.io/virtual-size"
+	// K8sImageChecksumVerifyAnnotation marks an image whose checksum is
+	// verified once its DataVolume has succeeded.
+	K8sImageChecksumVerifyAnnotation = "image.kubevim.kubenfv.io/checksum-verify"
+
+	// Storage of the image volume, returned as image metadata. A boot volume is
+	// cloned in the storage class and volume mode of its image, so CDI can use a
+	// snapshot or CSI clone, and with the clone access mode. The clone keys are
+	// kube-vim annotations of the image DataVolume, not read by CDI; the clone
+	// strategy CDI uses is the one of the StorageProfile.
+	K8sImageStorageClassMetadataKey    = "image.kubevim.kubenfv.io/storage-class"
+	K8sImageVolumeModeMetadataKey      = "image.kubevim.kubenfv.io/volume-mode"
+	K8sImageCloneAccessModeMetadataKey = "image.kubevim.kubenfv.io/clone-access-mode"
+	K8sImageCloneStrategyMetadataKey   = "image.kubevim.kubenfv.io/clone-strategy"
+
+	// K8sCatalogImageIdLabel is the stable id of a catalog image, kept by the
+	// image once it is imported. It is the DownloadImage metadata key passing
+	// the id to the importer and the label of the imported image.
+	K8sCatalogImageIdLabel = "image.kubevim.kubenfv.io/catalog-id"
+	// Catalog of an image and its id in that catalog, returned as image metadata.
+	K8sImageCatalogMetadataKey   = "image.kubevim.kubenfv.io/catalog"
+	K8sImageCatalogIdMetadataKey = "image.kubevim.kubenfv.io/catalog-image-id"
+)
+
+// SoftwareImageInformation statuses. Only ready images can back a compute.
+const (
+	StatusReady = "ready"
+	// StatusImporting is an image whose DataVolume has not succeeded yet: it
+	// is pending, importing, cloning or waiting for an upload.
+	StatusImporting = "importing"
+	// StatusVerifying is an imported image whose checksum is being verified.
+	StatusVerifying = "verifying"
+	// StatusFailed is an image whose import or checksum verification failed.
+	StatusFailed = "failed"
+	// StatusAvailable is a catalog image not imported yet. It is imported when
+	// a compute first uses it.
+	StatusAvailable = "available"
 )
 
 // NfvImageManager is the ETSI Vi-Vnfm image query surface. It is split out of
@@ -41,12 +105,87 @@ type Manager interface {
 	NfvImageManager
 }
 
+// Catalog is an image backend listing images held outside the cluster, e.g. in
+// Glance. Its images are imported into a volume by the CDI image manager from
+// the source returned by ImageSource.
+type Catalog interface {
+	Manager
+
+	// ImageSource returns the request importing the catalog image id. The
+	// image name is left to the caller.
+	ImageSource(context.Context, *nfvcommon.Identifier) (*admin.DownloadImageRequest, error)
+}
+
+// Importer is implemented by the image managers listing images that are not
+// imported yet (StatusAvailable). ImportImage imports such an image and
+// returns it as it is then; an image already imported is returned as is.
+type Importer interface {
+	ImportImage(context.Context, *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error)
+}
+
+// UploadRequest describes an image to be uploaded through the CDI upload proxy.
+type UploadRequest struct {
+	Name string
+	// StorageSize is the size of the image volume (resource.Quantity). It must
+	// fit the virtual size of the uploaded image. Optional.
+	StorageSize string
+	// StorageClass overrides image.storageClass. Optional.
+	StorageClass string
+}
+
+// UploadTarget is where the image bytes are to be sent: a POST of the raw
+// image to Url with "Authorization: Bearer <Token>". The upload proxy accepts
+// it once the image DataVolume reaches the UploadReady phase.
+type UploadTarget struct {
+	ImageId *nfvcommon.Identifier
+	Url     string
+	Token   string
+}
+
+// Uploader is implemented by the image managers that accept image uploads.
+type Uploader interface {
+	SetupImageUpload(context.Context, *UploadRequest) (*UploadTarget, error)
+}
+
+// Deleter is implemented by image managers that can delete images. An image
+// in use by a compute is only deleted with force; otherwise DeleteImage returns
+// apperrors.ErrFailedPrecondition.
+type Deleter interface {
+	DeleteImage(ctx context.Context, id *nfvcommon.Identifier, force bool) error
+}
+
+// Updater is implemented by image managers that can change the attributes of
+// an image: provider, version and the disk-format, container-format, min-disk
+// and min-ram metadata keys. An attribute absent from the metadata is left as
+// is. The name and the image content cannot be changed.
+type Updater interface {
+	UpdateImage(ctx context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error)
+}
+
+// gRPC metadata carrying the SetupImageUploadProxy parameters and result and
+// the DeleteImage force flag, which have no message fields yet in kube-vim-api.
+const (
+	UploadImageNameMetadataKey    = "kubevim-upload-image-name"
+	UploadStorageSizeMetadataKey  = "kubevim-upload-storage-size"
+	UploadStorageClassMetadataKey = "kubevim-upload-storage-class"
+
+	UploadImageIdMetadataKey = "kubevim-upload-image-id"
+	UploadUrlMetadataKey     = "kubevim-upload-url"
+	UploadTokenMetadataKey   = "kubevim-upload-token"
+
+	// DeleteForceMetadataKey ("true") deletes an image even while it is in use.
+	DeleteForceMetadataKey = "kubevim-delete-force"
+)
+
 type SourceType string
 
 const (
-	HTTP    SourceType = "http"
-	HTTPS              = "https"
-	Unknown            = ""
+	HTTP     SourceType = "http"
+	HTTPS               = "https"
+	Registry            = "registry"
+	S3                  = "s3"
+	Upload              = "upload"
+	Unknown             = ""
 )
 
 func SourceTypeFromString(sourceTypeStr string) (SourceType, error) {
@@ -55,6 +194,12 @@ func SourceTypeFromString(sourceTypeStr string) (SourceType, error) {
 		return HTTPS, nil
 	case string(HTTP):
 		return HTTP, nil
+	case string(Registry):
+		return Registry, nil
+	case string(S3):
+		return S3, nil
+	case string(Upload):
+		return Upload, nil
 	default:
 		return Unknown, fmt.Errorf("unknown source type '%s': %w", sourceTypeStr, apperrors.ErrUnsupported)
 	}
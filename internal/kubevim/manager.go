@@ -3,28 +3,37 @@ package kubevim
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/k8s"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/alarm"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/compute"
 	kubevirt_compute "github.com/kube-nfv/kube-vim/internal/kubevim/compute/kubevirt"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/flavour"
 	kubevirt_flavour "github.com/kube-nfv/kube-vim/internal/kubevim/flavour/kubevirt"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
 	cdiimmage "github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
-	//"github.com/kube-nfv/kube-vim/internal/kubevim/image/glance"
-	//http_im "github.com/kube-nfv/kube-vim/internal/kubevim/image/http"
-	//"github.com/kube-nfv/kube-vim/internal/kubevim/image/local"
+	imagecomposite "github.com/kube-nfv/kube-vim/internal/kubevim/image/composite"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/glance"
+	http_im "github.com/kube-nfv/kube-vim/internal/kubevim/image/http"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/local"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network/composite"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network/kubeovn"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network/sriov"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/notification"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/orvi"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/pm"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/server"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/telemetry"
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"go.uber.org/zap"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
 )
 
@@ -48,6 +57,28 @@ type kubevimManager struct {
 	flavourMgr   flavour.Manager
 	computeMgr   compute.Manager
 	telemetryMgr *telemetry.Manager
+	// notificationHub publishes resource change notifications derived from the
+	// shared cache informers.
+	notificationHub *notification.Hub
+	// alarmMgr raises and clears virtualised resource alarms from the state of
+	// the cached backend objects and nodes.
+	alarmMgr *alarm.Manager
+	// orviSvc serves the Or-Vi operations (image management, capacity, zones
+	// and quotas) on top of the domain managers.
+	orviSvc *orvi.Service
+	// pmMgr runs PM jobs and thresholds over Prometheus. nil when performance
+	// management is not configured.
+	pmMgr *pm.Manager
+	// localImageServer serves the files of the local image catalog to the CDI
+	// importer. nil when the local catalog is not configured.
+	localImageServer interface{ Start(context.Context) error }
+	// cdiImageMgr is the CDI image manager behind imageMgr. It starts the
+	// checksum verification of imported images from the shared cache
+	// informers, and sets the configured StorageProfile clone strategies.
+	cdiImageMgr interface {
+		RegisterInformers(context.Context, cache.Informers) error
+		EnsureStorageProfiles(context.Context) error
+	}
 
 	// cluster hosts the shared scheme, cache-backed client and APIReader used by
 	// all managers. Its cache must be started (Start) and synced before serving.
@@ -109,9 +140,17 @@ func NewKubeVimManager(cfg *config.Config, logger *zap.Logger) (*kubevimManager,
 	if err := mgr.initComputeManager(cfg.K8s, cfg.Compute); err != nil {
 		return nil, fmt.Errorf("initialize compute manager: %w", err)
 	}
+	if mgr.orviSvc, err = orvi.NewService(mgr.imageMgr, mgr.flavourMgr, mgr.networkMgr, cl.GetClient(), cl.GetAPIReader(), cfg.K8s, cfg.Compute); err != nil {
+		return nil, fmt.Errorf("initialize or-vi service: %w", err)
+	}
+	mgr.notificationHub = notification.NewHub(logger.Named("Notification"), notification.DefaultBacklogSize)
+	mgr.alarmMgr = alarm.NewManager(logger.Named("Alarm"), cl.GetClient(), *cfg.K8s.Namespace)
 	if err := mgr.initTelemetryManager(cfg.Monitoring); err != nil {
 		return nil, fmt.Errorf("initialize telemetry manager: %w", err)
 	}
+	if err := mgr.initPerformanceManager(cfg.Monitoring, cfg.K8s); err != nil {
+		return nil, fmt.Errorf("initialize performance manager: %w", err)
+	}
 	if err := mgr.initNorthboundServer(cfg.Service.Server); err != nil {
 		return nil, fmt.Errorf("configure northbound server: %w", err)
 	}
@@ -123,6 +162,21 @@ func (m *kubevimManager) Start(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// Notification handlers must be attached before the cache starts; the
+	// initial list replay is skipped by the hub itself.
+	if err := m.notificationHub.RegisterInformers(ctx, m.cluster.GetCache()); err != nil {
+		m.logger.Error("Failed to register resource change notification informers", zap.Error(err))
+		return
+	}
+	if err := m.alarmMgr.RegisterInformers(ctx, m.cluster.GetCache()); err != nil {
+		m.logger.Error("Failed to register alarm informers", zap.Error(err))
+		return
+	}
+	if err := m.cdiImageMgr.RegisterInformers(ctx, m.cluster.GetCache()); err != nil {
+		m.logger.Error("Failed to register image informers", zap.Error(err))
+		return
+	}
+
 	// Start the shared cache and block until it has synced before serving, so
 	// managers never read from a cold cache.
 	go func() {
@@ -135,6 +189,10 @@ func (m *kubevimManager) Start(ctx context.Context) {
 		return
 	}
 
+	if err := m.cdiImageMgr.EnsureStorageProfiles(ctx); err != nil {
+		m.logger.Error("Failed to set the configured CDI StorageProfile clone strategies", zap.Error(err))
+		return
+	}
 	if m.cfg != nil && m.cfg.Network != nil {
 		if err := m.networkMgr.EnsureManagementNetwork(ctx, m.cfg.Network.ManagementNetwork); err != nil {
 			m.logger.Error("Failed to ensure kube-vim-managed management network", zap.Error(err))
@@ -152,6 +210,20 @@ func (m *kubevimManager) Start(ctx context.Context) {
 			errCh <- fmt.Errorf("start telemetry server: %w", err)
 		}
 	}()
+	if m.localImageServer != nil {
+		go func() {
+			if err := m.localImageServer.Start(ctx); err != nil {
+				errCh <- fmt.Errorf("start local image server: %w", err)
+			}
+		}()
+	}
+	if m.pmMgr != nil {
+		go func() {
+			if err := m.pmMgr.Start(ctx); err != nil {
+				errCh <- fmt.Errorf("start performance manager: %w", err)
+			}
+		}()
+	}
 	go func() {
 		select {
 		case err := <-errCh:
@@ -168,54 +240,59 @@ func (m *kubevimManager) Start(ctx context.Context) {
 	m.logger.Info("Kubevim manager shutdown completed")
 }
 
+// initImageManager creates the CDI image manager and, when image catalogs are
+// configured, merges their images in. The catalog names are part of the
+// stable catalog image ids and must not change.
 func (m *kubevimManager) initImageManager(cfg *config.ImageConfig, k8sCfg *config.K8sConfig) error {
 	if cfg == nil {
 		return &apperrors.ErrInvalidArgument{Field: "imageConfig", Reason: "cannot be nil"}
 	}
-	var err error
-	m.imageMgr, err = cdiimmage.NewCDIImageManager(m.cluster.GetClient(), m.cluster.GetAPIReader(), cfg, k8sCfg)
+	cdiMgr, err := cdiimmage.NewCDIImageManager(m.cluster.GetClient(), m.cluster.GetAPIReader(), cfg, k8sCfg, m.logger.Named("image.cdi"))
 	if err != nil {
 		return fmt.Errorf("initialize kubevirt cdi image manager: %w", err)
 	}
-	return nil
-	/*
-		cdiCtrl, err := image.NewCdiController(k8sConfig)
+	m.cdiImageMgr = cdiMgr
+	var catalogs []imagecomposite.Catalog
+	if cfg.Glance != nil {
+		glanceMgr, err := glance.NewGlanceImageManager(cfg.Glance)
 		if err != nil {
-			return fmt.Errorf("initialize kubevirt cdi controller: %w", err)
+			return fmt.Errorf("initialize Glance image manager: %w", err)
 		}
-		if cfg.Http != nil {
-			m.imageMgr, err = http_im.NewHttpImageManager(cdiCtrl, cfg.Http)
-			if err != nil {
-				return fmt.Errorf("initialize HTTP image manager: %w", err)
-			}
-			return nil
-		}
-		if cfg.Local != nil {
-			m.imageMgr, err = local.NewLocalImageManager(cfg.Local)
-			if err != nil {
-				return fmt.Errorf("initialize Local image manager: %w", err)
-			}
-			return nil
+		catalogs = append(catalogs, imagecomposite.Catalog{Name: "glance", Catalog: glanceMgr})
+	}
+	if cfg.Http != nil {
+		httpMgr, err := http_im.NewHttpImageManager(cfg.Http)
+		if err != nil {
+			return fmt.Errorf("initialize HTTP image manager: %w", err)
 		}
-		if cfg.Glance != nil {
-			m.imageMgr, err = glance.NewGlanceImageManager(cfg.Glance)
-			if err != nil {
-				return fmt.Errorf("initialize Glance image manager: %w", err)
-			}
-			return nil
+		catalogs = append(catalogs, imagecomposite.Catalog{Name: "http", Catalog: httpMgr})
+	}
+	if cfg.Local != nil {
+		localMgr, err := local.NewLocalImageManager(cfg.Local, m.logger.Named("image.local"))
+		if err != nil {
+			return fmt.Errorf("initialize Local image manager: %w", err)
 		}
-		return &apperrors.ErrInvalidArgument{Field: "imageConfig", Reason: "no valid image manager configuration found (http, local, or glance required)"}
-	*/
+		m.localImageServer = localMgr
+		catalogs = append(catalogs, imagecomposite.Catalog{Name: "local", Catalog: localMgr})
+	}
+	if len(catalogs) == 0 {
+		m.imageMgr = cdiMgr
+		return nil
+	}
+	m.imageMgr = imagecomposite.NewManager(cdiMgr, catalogs...)
+	return nil
 }
 
 func (m *kubevimManager) initNetworkManager(k8sCfg *config.K8sConfig) error {
-	ovnMgr, err := kubeovn.NewKubeovnNetworkManager(m.cluster.GetClient(), m.cluster.GetAPIReader(), k8sCfg, m.logger.Named("network.ovn"))
-	if err != nil {
-		return fmt.Errorf("create kubeovn network manager: %w", err)
-	}
 	var sriovCfg *config.SriovNetworkConfig
+	var natCfg *config.NatConfig
 	if m.cfg.Network != nil {
 		sriovCfg = m.cfg.Network.Sriov
+		natCfg = m.cfg.Network.Nat
+	}
+	ovnMgr, err := kubeovn.NewKubeovnNetworkManager(m.cluster.GetClient(), m.cluster.GetAPIReader(), k8sCfg, natCfg, m.logger.Named("network.ovn"))
+	if err != nil {
+		return fmt.Errorf("create kubeovn network manager: %w", err)
 	}
 	sriovMgr, err := sriov.NewSriovNetworkManager(m.cluster.GetClient(), k8sCfg, sriovCfg, m.logger.Named("network.sriov"))
 	if err != nil {
@@ -252,12 +329,37 @@ func (m *kubevimManager) initTelemetryManager(cfg *config.MonitoringConfig) erro
 	return nil
 }
 
+func (m *kubevimManager) initPerformanceManager(cfg *config.MonitoringConfig, k8sCfg *config.K8sConfig) error {
+	if cfg == nil || cfg.Performance == nil || cfg.Performance.PrometheusUrl == nil || *cfg.Performance.PrometheusUrl == "" {
+		return nil
+	}
+	pmCfg := cfg.Performance
+	queryTimeout, err := time.ParseDuration(*pmCfg.QueryTimeout)
+	if err != nil {
+		return &apperrors.ErrInvalidArgument{Field: "monitoring.performance.queryTimeout", Reason: err.Error()}
+	}
+	thresholdInterval, err := time.ParseDuration(*pmCfg.ThresholdInterval)
+	if err != nil || thresholdInterval <= 0 {
+		return &apperrors.ErrInvalidArgument{Field: "monitoring.performance.thresholdInterval", Reason: "must be a positive duration"}
+	}
+	promClient, err := promapi.NewClient(promapi.Config{Address: *pmCfg.PrometheusUrl})
+	if err != nil {
+		return fmt.Errorf("create prometheus client: %w", err)
+	}
+	if pmCfg.ConfigMap == nil || *pmCfg.ConfigMap == "" {
+		return &apperrors.ErrInvalidArgument{Field: "monitoring.performance.configMap", Reason: "cannot be empty"}
+	}
+	store := pm.NewConfigMapStore(m.cluster.GetClient(), m.cluster.GetAPIReader(), *k8sCfg.Namespace, *pmCfg.ConfigMap)
+	m.pmMgr = pm.NewManager(m.logger.Named("Performance"), promv1.NewAPI(promClient), store, queryTimeout, thresholdInterval)
+	return nil
+}
+
 func (m *kubevimManager) initNorthboundServer(cfg *config.ServerConfig) error {
 	if cfg == nil {
 		return &apperrors.ErrInvalidArgument{Field: "ServiceConfig", Reason: "cannot be nil"}
 	}
 	var err error
-	m.nbServer, err = server.NewNorthboundServer(cfg, m.logger.Named("NorthboundServer"), m.telemetryMgr.MeterProvider(), m.imageMgr, m.networkMgr, m.flavourMgr, m.computeMgr)
+	m.nbServer, err = server.NewNorthboundServer(cfg, m.logger.Named("NorthboundServer"), m.telemetryMgr.MeterProvider(), m.imageMgr, m.networkMgr, m.flavourMgr, m.computeMgr, m.notificationHub, m.alarmMgr, m.pmMgr, m.orviSvc)
 	if err != nil {
 		return fmt.Errorf("initialize NorthboundServer: %w", err)
 	}
@@ -76,6 +76,24 @@ func (m *manager) DeleteNetwork(ctx context.Context, opts ...network.GetNetworkO
 	return err
 }
 
+func (m *manager) UpdateNetwork(ctx context.Context, data *vivnfm.VirtualNetworkData, opts ...network.GetNetworkOpt) (*vivnfm.VirtualNetwork, error) {
+	net, err := m.ovn.UpdateNetwork(ctx, data, opts...)
+	if err == nil {
+		return net, nil
+	}
+	if !isNotFound(err) {
+		return nil, err
+	}
+	net, sriovErr := m.sriov.UpdateNetwork(ctx, data, opts...)
+	if sriovErr == nil {
+		return net, nil
+	}
+	if !isNotFound(sriovErr) {
+		return nil, sriovErr
+	}
+	return nil, err
+}
+
 func (m *manager) CreateSubnet(ctx context.Context, name string, data *vivnfm.NetworkSubnetData) (*vivnfm.NetworkSubnet, error) {
 	if data.NetworkId != nil {
 		net, err := m.GetNetwork(ctx, network.GetNetworkByUid(data.NetworkId))
@@ -101,6 +119,10 @@ func (m *manager) DeleteSubnet(ctx context.Context, opts ...network.GetSubnetOpt
 	return m.ovn.DeleteSubnet(ctx, opts...)
 }
 
+func (m *manager) UpdateSubnet(ctx context.Context, data *vivnfm.NetworkSubnetData, opts ...network.GetSubnetOpt) (*vivnfm.NetworkSubnet, error) {
+	return m.ovn.UpdateSubnet(ctx, data, opts...)
+}
+
 func (m *manager) EnsureManagementNetwork(ctx context.Context, cfg *config.ManagementNetworkConfig) error {
 	return m.ovn.EnsureManagementNetwork(ctx, cfg)
 }
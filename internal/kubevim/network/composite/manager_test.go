@@ -149,3 +149,93 @@ func TestSubnetReadsAlwaysOvn(t *testing.T) {
 	require.NoError(t, m.DeleteSubnet(context.Background()))
 	require.NoError(t, m.EnsureManagementNetwork(context.Background(), nil))
 }
+
+func TestUpdateNetworkDispatch(t *testing.T) {
+	t.Parallel()
+	t.Run("ovn not-found falls through to sriov", func(t *testing.T) {
+		ovn, sriov, m := setup(t)
+		ovn.EXPECT().UpdateNetwork(gomock.Any(), gomock.Any()).Return(nil, notFound())
+		sriov.EXPECT().UpdateNetwork(gomock.Any(), gomock.Any()).Return(nil, apperrors.ErrUnsupported)
+		_, err := m.UpdateNetwork(context.Background(), &vivnfm.VirtualNetworkData{})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+
+	t.Run("ovn invalid argument short-circuits", func(t *testing.T) {
+		ovn, _, m := setup(t)
+		ovn.EXPECT().UpdateNetwork(gomock.Any(), gomock.Any()).Return(nil, &apperrors.ErrInvalidArgument{Field: "segmentationId"})
+		_, err := m.UpdateNetwork(context.Background(), &vivnfm.VirtualNetworkData{})
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+}
+
+// sgManager is an ovn backend that also manages security groups.
+type sgManager struct {
+	*networkmock.MockManager
+	*networkmock.MockSecurityGroupManager
+}
+
+func TestSecurityGroupDispatch(t *testing.T) {
+	t.Parallel()
+	t.Run("routes to the ovn backend", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		sg := networkmock.NewMockSecurityGroupManager(ctrl)
+		m := NewManager(sgManager{networkmock.NewMockManager(ctrl), sg}, networkmock.NewMockManager(ctrl))
+		sg.EXPECT().ListSecurityGroups(gomock.Any()).Return([]*network.SecurityGroup{{Name: "web"}}, nil)
+		sgs, err := m.(network.SecurityGroupManager).ListSecurityGroups(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, sgs, 1)
+	})
+
+	t.Run("unsupported without an ovn security group manager", func(t *testing.T) {
+		_, _, m := setup(t)
+		_, err := m.(network.SecurityGroupManager).ListSecurityGroups(context.Background())
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
+
+// vipManager is an ovn backend that also manages virtual IPs.
+type vipManager struct {
+	*networkmock.MockManager
+	*networkmock.MockVipManager
+}
+
+func TestVipDispatch(t *testing.T) {
+	t.Parallel()
+	t.Run("routes to the ovn backend", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		vip := networkmock.NewMockVipManager(ctrl)
+		m := NewManager(vipManager{networkmock.NewMockManager(ctrl), vip}, networkmock.NewMockManager(ctrl))
+		vip.EXPECT().DeleteVip(gomock.Any(), gomock.Any()).Return(nil)
+		require.NoError(t, m.(network.VipManager).DeleteVip(context.Background(), network.GetVipByName("vrrp")))
+	})
+
+	t.Run("unsupported without an ovn virtual IP manager", func(t *testing.T) {
+		_, _, m := setup(t)
+		_, err := m.(network.VipManager).ListVips(context.Background())
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
+
+// natManager is an ovn backend that also manages NAT gateways and floating IPs.
+type natManager struct {
+	*networkmock.MockManager
+	*networkmock.MockNatManager
+}
+
+func TestNatDispatch(t *testing.T) {
+	t.Parallel()
+	t.Run("routes to the ovn backend", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		nat := networkmock.NewMockNatManager(ctrl)
+		m := NewManager(natManager{networkmock.NewMockManager(ctrl), nat}, networkmock.NewMockManager(ctrl))
+		nat.EXPECT().DeleteFloatingIp(gomock.Any(), gomock.Any()).Return(nil)
+		require.NoError(t, m.(network.NatManager).DeleteFloatingIp(context.Background(), network.GetNatByName("web")))
+	})
+
+	t.Run("unsupported without an ovn NAT manager", func(t *testing.T) {
+		_, _, m := setup(t)
+		_, err := m.(network.NatManager).ListNatGateways(context.Background())
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
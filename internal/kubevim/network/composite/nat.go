@@ -0,0 +1,92 @@
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+)
+
+// NAT gateways and floating IPs are kube-ovn only: they connect overlay
+// networks to the external network.
+
+func (m *manager) natManager() (network.NatManager, error) {
+	natMgr, ok := m.ovn.(network.NatManager)
+	if !ok {
+		return nil, fmt.Errorf("NAT gateways and floating IPs with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	return natMgr, nil
+}
+
+func (m *manager) CreateNatGateway(ctx context.Context, gw *network.NatGateway) (*network.NatGateway, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.CreateNatGateway(ctx, gw)
+}
+
+func (m *manager) GetNatGateway(ctx context.Context, opts ...network.GetNatOpt) (*network.NatGateway, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.GetNatGateway(ctx, opts...)
+}
+
+func (m *manager) ListNatGateways(ctx context.Context) ([]*network.NatGateway, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.ListNatGateways(ctx)
+}
+
+func (m *manager) DeleteNatGateway(ctx context.Context, opts ...network.GetNatOpt) error {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return err
+	}
+	return natMgr.DeleteNatGateway(ctx, opts...)
+}
+
+func (m *manager) CreateFloatingIp(ctx context.Context, fip *network.FloatingIp) (*network.FloatingIp, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.CreateFloatingIp(ctx, fip)
+}
+
+func (m *manager) GetFloatingIp(ctx context.Context, opts ...network.GetNatOpt) (*network.FloatingIp, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.GetFloatingIp(ctx, opts...)
+}
+
+func (m *manager) ListFloatingIps(ctx context.Context) ([]*network.FloatingIp, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.ListFloatingIps(ctx)
+}
+
+func (m *manager) UpdateFloatingIp(ctx context.Context, fip *network.FloatingIp) (*network.FloatingIp, error) {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return nil, err
+	}
+	return natMgr.UpdateFloatingIp(ctx, fip)
+}
+
+func (m *manager) DeleteFloatingIp(ctx context.Context, opts ...network.GetNatOpt) error {
+	natMgr, err := m.natManager()
+	if err != nil {
+		return err
+	}
+	return natMgr.DeleteFloatingIp(ctx, opts...)
+}
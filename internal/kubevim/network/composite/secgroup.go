@@ -0,0 +1,59 @@
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+)
+
+// Security groups are kube-ovn only: SR-IOV vNICs bypass the OVN datapath.
+
+func (m *manager) securityGroupManager() (network.SecurityGroupManager, error) {
+	sgMgr, ok := m.ovn.(network.SecurityGroupManager)
+	if !ok {
+		return nil, fmt.Errorf("security groups with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	return sgMgr, nil
+}
+
+func (m *manager) CreateSecurityGroup(ctx context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+	sgMgr, err := m.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	return sgMgr.CreateSecurityGroup(ctx, sg)
+}
+
+func (m *manager) GetSecurityGroup(ctx context.Context, opts ...network.GetSecurityGroupOpt) (*network.SecurityGroup, error) {
+	sgMgr, err := m.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	return sgMgr.GetSecurityGroup(ctx, opts...)
+}
+
+func (m *manager) ListSecurityGroups(ctx context.Context) ([]*network.SecurityGroup, error) {
+	sgMgr, err := m.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	return sgMgr.ListSecurityGroups(ctx)
+}
+
+func (m *manager) UpdateSecurityGroup(ctx context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+	sgMgr, err := m.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	return sgMgr.UpdateSecurityGroup(ctx, sg)
+}
+
+func (m *manager) DeleteSecurityGroup(ctx context.Context, opts ...network.GetSecurityGroupOpt) error {
+	sgMgr, err := m.securityGroupManager()
+	if err != nil {
+		return err
+	}
+	return sgMgr.DeleteSecurityGroup(ctx, opts...)
+}
@@ -0,0 +1,51 @@
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+)
+
+// Virtual IPs are kube-ovn only: they are allowed address pairs of OVN ports.
+
+func (m *manager) vipManager() (network.VipManager, error) {
+	vipMgr, ok := m.ovn.(network.VipManager)
+	if !ok {
+		return nil, fmt.Errorf("virtual IPs with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	return vipMgr, nil
+}
+
+func (m *manager) CreateVip(ctx context.Context, vip *network.Vip) (*network.Vip, error) {
+	vipMgr, err := m.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	return vipMgr.CreateVip(ctx, vip)
+}
+
+func (m *manager) GetVip(ctx context.Context, opts ...network.GetVipOpt) (*network.Vip, error) {
+	vipMgr, err := m.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	return vipMgr.GetVip(ctx, opts...)
+}
+
+func (m *manager) ListVips(ctx context.Context) ([]*network.Vip, error) {
+	vipMgr, err := m.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	return vipMgr.ListVips(ctx)
+}
+
+func (m *manager) DeleteVip(ctx context.Context, opts ...network.GetVipOpt) error {
+	vipMgr, err := m.vipManager()
+	if err != nil {
+		return err
+	}
+	return vipMgr.DeleteVip(ctx, opts...)
+}
@@ -0,0 +1,118 @@
+package kubeovn
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// kubeovnSubnetLabel is set by kube-ovn on the IP objects of a subnet.
+	kubeovnSubnetLabel = "ovn.kubernetes.io/subnet"
+	// kubeovnVmPodType is the IP podType of the addresses kube-ovn keeps for a
+	// kubevirt VirtualMachine, whose podName is then the VirtualMachine name.
+	kubeovnVmPodType = "VirtualMachine"
+)
+
+// Returns the ids of the computes with a vNIC on the subnet, sorted. A compute
+// is attached when its VirtualMachine template references the subnet
+// NetworkAttachmentDefinition, or when kube-ovn holds an IP of the subnet for
+// it. VirtualMachines already being deleted are not reported. Both lists are
+// read through the uncached apiReader: IPs are cluster-scoped and unlabelled by
+// kube-vim, and a cascade re-checks right after terminating the computes.
+func (m *manager) subnetComputeIds(ctx context.Context, subnetName, netAttachName string) ([]*nfvcommon.Identifier, error) {
+	namespace := *m.k8sCfg.Namespace
+	vmList := &kubevirtv1.VirtualMachineList{}
+	if err := m.apiReader.List(ctx, vmList, client.InNamespace(namespace), client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return nil, fmt.Errorf("list kubevirt VirtualMachines: %w", err)
+	}
+	vmByName := make(map[string]*kubevirtv1.VirtualMachine, len(vmList.Items))
+	attached := make(map[types.UID]struct{})
+	for i := range vmList.Items {
+		vm := &vmList.Items[i]
+		if vm.DeletionTimestamp != nil {
+			continue
+		}
+		vmByName[vm.Name] = vm
+		if vmReferencesNetAttach(vm, namespace, netAttachName) {
+			attached[vm.UID] = struct{}{}
+		}
+	}
+
+	ipList := &kubeovnv1.IPList{}
+	if err := m.apiReader.List(ctx, ipList, client.MatchingLabels{kubeovnSubnetLabel: subnetName}); err != nil {
+		return nil, fmt.Errorf("list kubeovn IPs of subnet '%s': %w", subnetName, err)
+	}
+	for _, ip := range ipList.Items {
+		if ip.Spec.Subnet != subnetName || ip.Spec.Namespace != namespace {
+			continue
+		}
+		vmName := ip.Spec.PodName
+		if ip.Spec.PodType != kubeovnVmPodType {
+			// The IP of a virt-launcher pod: the pod names its VirtualMachine.
+			pod := &corev1.Pod{}
+			if err := m.client.Get(ctx, client.ObjectKey{Name: ip.Spec.PodName, Namespace: namespace}, pod); err != nil {
+				if k8s_errors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("get pod '%s' of kubeovn IP '%s': %w", ip.Spec.PodName, ip.Name, err)
+			}
+			vmName = pod.Labels[kubevirtv1.VirtualMachineNameLabel]
+		}
+		// IPs of pods that are not kube-vim computes do not block the deletion.
+		if vm, ok := vmByName[vmName]; ok {
+			attached[vm.UID] = struct{}{}
+		}
+	}
+
+	uids := slices.Sorted(maps.Keys(attached))
+	res := make([]*nfvcommon.Identifier, 0, len(uids))
+	for _, uid := range uids {
+		res = append(res, misc.UIDToIdentifier(uid))
+	}
+	return res, nil
+}
+
+func vmReferencesNetAttach(vm *kubevirtv1.VirtualMachine, namespace, netAttachName string) bool {
+	if vm.Spec.Template == nil {
+		return false
+	}
+	for _, net := range vm.Spec.Template.Spec.Networks {
+		if net.Multus == nil {
+			continue
+		}
+		if net.Multus.NetworkName == netAttachName || net.Multus.NetworkName == namespace+"/"+netAttachName {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns ErrInUse for the subnet when computes are attached to it.
+func (m *manager) checkSubnetNotInUse(ctx context.Context, subnet *vivnfm.NetworkSubnet) error {
+	subnetName := subnet.Metadata.Fields[network.K8sSubnetNameLabel]
+	computeIds, err := m.subnetComputeIds(ctx, subnetName, subnet.Metadata.Fields[network.K8sSubnetNetAttachNameLabel])
+	if err != nil {
+		return fmt.Errorf("get computes attached to subnet '%s': %w", subnetName, err)
+	}
+	if len(computeIds) > 0 {
+		return &network.ErrInUse{
+			Resource:   fmt.Sprintf("subnet '%s' (id: %s)", subnetName, subnet.ResourceId.GetValue()),
+			ComputeIds: computeIds,
+		}
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"sync"
 
 	netattv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
@@ -28,12 +30,18 @@ type manager struct {
 	client client.Client
 	// apiReader is uncached; used by the management-network reconciliation, which
 	// reads objects that may not yet carry the managed-by label (and so are not in
-	// the cache) before labelling them.
+	// the cache) before labelling them, and to read the vpcs to patch.
 	apiReader client.Reader
 	k8sCfg    *config.K8sConfig
+	// natCfg selects the backend of NAT gateways and floating IPs. Nil when they
+	// are disabled.
+	natCfg *config.NatConfig
+	// peeringMu serializes the peering changes, which allocate the peering links
+	// from the view of all the vpcs and patch the vpcs of both peers.
+	peeringMu sync.Mutex
 }
 
-func NewKubeovnNetworkManager(cl client.Client, apiReader client.Reader, k8sCfg *config.K8sConfig, logger *zap.Logger) (*manager, error) {
+func NewKubeovnNetworkManager(cl client.Client, apiReader client.Reader, k8sCfg *config.K8sConfig, natCfg *config.NatConfig, logger *zap.Logger) (*manager, error) {
 	if k8sCfg.Namespace == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "config k8s.Namespace", Reason: "can't be nil"}
 	}
@@ -45,6 +53,7 @@ func NewKubeovnNetworkManager(cl client.Client, apiReader client.Reader, k8sCfg
 		client:    cl,
 		apiReader: apiReader,
 		k8sCfg:    k8sCfg,
+		natCfg:    natCfg,
 	}, nil
 }
 
@@ -102,6 +111,13 @@ func (m *manager) createOverlayNetwork(ctx context.Context, name string, network
 		m.DeleteNetwork(ctx, network.GetNetworkByName(name))
 		return nil, fmt.Errorf("create vpc l3 attributes (resources cleaned up): %w", err)
 	}
+	if peerings, ok := networkData.Metadata.GetFields()[network.NetworkPeeringsMetadataKey]; ok {
+		vpc, err = m.patchVpcRouting(ctx, vpc, &nfvcommon.Metadata{Fields: map[string]string{network.NetworkPeeringsMetadataKey: peerings}})
+		if err != nil {
+			m.DeleteNetwork(ctx, network.GetNetworkByName(name))
+			return nil, fmt.Errorf("peer vpc '%s' (resources cleaned up): %w", name, err)
+		}
+	}
 
 	res, err := kubeovnVpcToNfvNetwork(vpc, subnetIds)
 	if err != nil {
@@ -178,7 +194,12 @@ func (m *manager) getOverlayNetwork(ctx context.Context, opts ...network.GetNetw
 	} else {
 		return nil, &apperrors.ErrInvalidArgument{Field: "network identifier", Reason: "either name or uid must be specified"}
 	}
+	return m.overlayNetwork(ctx, vpc)
+}
 
+// overlayNetwork returns the network of the vpc, with its subnets and floating
+// ips.
+func (m *manager) overlayNetwork(ctx context.Context, vpc *kubeovnv1.Vpc) (*vivnfm.VirtualNetwork, error) {
 	subnetIds := []*nfvcommon.Identifier{}
 	for _, subnetName := range vpc.Status.Subnets {
 		subnet, err := m.GetSubnet(ctx, network.GetSubnetByName(subnetName))
@@ -192,6 +213,11 @@ func (m *manager) getOverlayNetwork(ctx context.Context, opts ...network.GetNetw
 	if err != nil {
 		return nil, fmt.Errorf("convert kubeovn vpc '%s' (id: %s) to nfv VirtualNetwork: %w", vpc.Name, vpc.GetUID(), err)
 	}
+	fips, err := m.floatingIpsByVpc(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get floating ips of vpc '%s': %w", vpc.Name, err)
+	}
+	setNetworkFloatingIps(res, fips[vpc.Name])
 	return res, nil
 }
 
@@ -222,7 +248,11 @@ func (m *manager) getUnderlayNetwork(ctx context.Context, opts ...network.GetNet
 	} else {
 		return nil, &apperrors.ErrInvalidArgument{Field: "network identifier", Reason: "either name or uid must be specified"}
 	}
+	return m.underlayNetwork(ctx, vlan)
+}
 
+// underlayNetwork returns the network of the vlan, with its subnets.
+func (m *manager) underlayNetwork(ctx context.Context, vlan *kubeovnv1.Vlan) (*vivnfm.VirtualNetwork, error) {
 	subnetIds := []*nfvcommon.Identifier{}
 	for _, subnetName := range vlan.Status.Subnets {
 		subnet, err := m.GetSubnet(ctx, network.GetSubnetByName(subnetName))
@@ -270,6 +300,11 @@ func (m *manager) ListNetworks(ctx context.Context) ([]*vivnfm.VirtualNetwork, e
 		return ids, nil
 	}
 
+	fips, err := m.floatingIpsByVpc(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get floating ips: %w", err)
+	}
+
 	res := make([]*vivnfm.VirtualNetwork, 0, len(vpcList.Items)+len(vlanList.Items))
 	for idx := range vpcList.Items {
 		vpc := &vpcList.Items[idx]
@@ -281,6 +316,7 @@ func (m *manager) ListNetworks(ctx context.Context) ([]*vivnfm.VirtualNetwork, e
 		if err != nil {
 			return nil, fmt.Errorf("convert kubeovn vpc '%s' (id: %s) to nfv VirtualNetwork: %w", vpc.Name, vpc.GetUID(), err)
 		}
+		setNetworkFloatingIps(net, fips[vpc.Name])
 		res = append(res, net)
 	}
 	for idx := range vlanList.Items {
@@ -299,15 +335,52 @@ func (m *manager) ListNetworks(ctx context.Context) ([]*vivnfm.VirtualNetwork, e
 }
 
 // Delete the network and all aquired resource (subnets, NetworkAttachmentDefinitions, etc.)
-// It will delete the network ONLY if it was created by the kube-vim.
+// It will delete the network ONLY if it was created by the kube-vim. Nothing is
+// deleted while computes are attached to any of the network subnets.
 func (m *manager) DeleteNetwork(ctx context.Context, opts ...network.GetNetworkOpt) error {
 	net, err := m.GetNetwork(ctx, opts...)
 	if err != nil {
 		return fmt.Errorf("get network: %w", err)
 	}
+	if net.NetworkType == nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY {
+		if err := m.checkNetworkHasNoNatGateway(ctx, *net.NetworkResourceName); err != nil {
+			return err
+		}
+	}
+	subnets := make([]*vivnfm.NetworkSubnet, 0, len(net.SubnetId))
+	var computeIds []*nfvcommon.Identifier
 	for _, subnetId := range net.SubnetId {
-		if err := m.DeleteSubnet(ctx, network.GetSubnetByUid(subnetId)); err != nil {
-			return fmt.Errorf("delete network subnet with id '%s': %w", subnetId.Value, err)
+		subnet, err := m.GetSubnet(ctx, network.GetSubnetByUid(subnetId))
+		if err != nil {
+			return fmt.Errorf("get network subnet with id '%s': %w", subnetId.Value, err)
+		}
+		err = m.checkSubnetNotInUse(ctx, subnet)
+		var inUseErr *network.ErrInUse
+		if errors.As(err, &inUseErr) {
+			for _, id := range inUseErr.ComputeIds {
+				if !slices.ContainsFunc(computeIds, func(c *nfvcommon.Identifier) bool { return c.GetValue() == id.GetValue() }) {
+					computeIds = append(computeIds, id)
+				}
+			}
+		} else if err != nil {
+			return err
+		}
+		subnets = append(subnets, subnet)
+	}
+	if len(computeIds) > 0 {
+		return &network.ErrInUse{
+			Resource:   fmt.Sprintf("network '%s' (id: %s)", net.GetNetworkResourceName(), net.NetworkResourceId.GetValue()),
+			ComputeIds: computeIds,
+		}
+	}
+	if net.NetworkType == nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY {
+		if err := m.unpeerVpc(ctx, *net.NetworkResourceName); err != nil {
+			return err
+		}
+	}
+	for _, subnet := range subnets {
+		if err := m.deleteSubnet(ctx, subnet); err != nil {
+			return fmt.Errorf("delete network subnet with id '%s': %w", subnet.ResourceId.GetValue(), err)
 		}
 	}
 	if net.NetworkType == nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY {
@@ -326,6 +399,46 @@ func (m *manager) DeleteNetwork(ctx context.Context, opts ...network.GetNetworkO
 	return nil
 }
 
+// Applies the metadata of the network data to the kubeovn Vpc or Vlan of the
+// network. The metadata is kept in the object annotations.
+func (m *manager) UpdateNetwork(ctx context.Context, networkData *vivnfm.VirtualNetworkData, opts ...network.GetNetworkOpt) (*vivnfm.VirtualNetwork, error) {
+	net, err := m.GetNetwork(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("get network: %w", err)
+	}
+	if err := validateNetworkUpdate(net, networkData); err != nil {
+		return nil, fmt.Errorf("update network '%s': %w", net.GetNetworkResourceName(), err)
+	}
+	name := net.GetNetworkResourceName()
+	switch net.NetworkType {
+	case nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY:
+		vpc := &kubeovnv1.Vpc{}
+		if err := m.client.Get(ctx, client.ObjectKey{Name: name}, vpc); err != nil {
+			return nil, fmt.Errorf("get kubeovn vpc '%s': %w", name, err)
+		}
+		vpc, err = m.patchVpcRouting(ctx, vpc, networkData.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("update network '%s': %w", name, err)
+		}
+		return m.overlayNetwork(ctx, vpc)
+	case nfvcommon.NetworkType_NETWORK_TYPE_UNDERLAY:
+		if err := checkNoVpcRouting(networkData.Metadata); err != nil {
+			return nil, fmt.Errorf("update network '%s': %w", name, err)
+		}
+		vlan := &kubeovnv1.Vlan{}
+		if err := m.client.Get(ctx, client.ObjectKey{Name: name}, vlan); err != nil {
+			return nil, fmt.Errorf("get kubeovn vlan '%s': %w", name, err)
+		}
+		base := vlan.DeepCopy()
+		vlan.Annotations = mergeMetadataAnnotations(vlan.Annotations, networkData.Metadata)
+		if err := m.client.Patch(ctx, vlan, client.MergeFrom(base)); err != nil {
+			return nil, fmt.Errorf("patch kubeovn vlan '%s' (id: %s): %w", name, vlan.GetUID(), err)
+		}
+		return m.underlayNetwork(ctx, vlan)
+	}
+	return nil, fmt.Errorf("unsupported network type '%s': %w", net.NetworkType, apperrors.ErrUnsupported)
+}
+
 // Creates the kubeovn subnet from the specified vivnfm.NetworkSubnetData.
 // If the subnet creation (or convertion) fails all resources (eg. Subnet, multus netowrkAttachmentDefinitions are cleared)
 func (m *manager) CreateSubnet(ctx context.Context, name string, subnetData *vivnfm.NetworkSubnetData) (*vivnfm.NetworkSubnet, error) {
@@ -373,6 +486,9 @@ func (m *manager) CreateSubnet(ctx context.Context, name string, subnetData *viv
 			Config: formatNetAttachConfig(netAttachName, *m.k8sCfg.Namespace),
 		},
 	}
+	if err := m.checkSubnetPeering(ctx, subnet); err != nil {
+		return nil, fmt.Errorf("create kubeovn subnet '%s': %w", subnet.GetName(), err)
+	}
 	if err := m.client.Create(ctx, nad); err != nil {
 		return nil, fmt.Errorf("create multus network-attachment-definition for subnet '%s': %w", subnet.GetName(), err)
 	}
@@ -396,6 +512,10 @@ func (m *manager) CreateSubnet(ctx context.Context, name string, subnetData *viv
 		m.DeleteSubnet(ctx, network.GetSubnetByUid(misc.UIDToIdentifier(subnet.GetUID())))
 		return nil, fmt.Errorf("convert created kubeovn subnet '%s' (id: %s) to vivnfm.NetworkSubnet (subnet will be deleted): %w", subnet.GetName(), subnet.GetUID(), err)
 	}
+	if err := m.routeSubnetsToPeers(ctx, subnet.Spec.Vpc); err != nil {
+		m.DeleteSubnet(ctx, network.GetSubnetByUid(misc.UIDToIdentifier(subnet.GetUID())))
+		return nil, fmt.Errorf("route subnet '%s' from the network peers (subnet will be deleted): %w", subnet.GetName(), err)
+	}
 	return nfvSubnet, nil
 }
 
@@ -473,11 +593,20 @@ func (m *manager) ListSubnets(ctx context.Context) ([]*vivnfm.NetworkSubnet, err
 	return res, nil
 }
 
+// Deletes the subnet and its NetworkAttachmentDefinition, refusing while
+// computes are attached to it.
 func (m *manager) DeleteSubnet(ctx context.Context, opts ...network.GetSubnetOpt) error {
 	subnet, err := m.GetSubnet(ctx, opts...)
 	if err != nil {
 		return fmt.Errorf("get subnet: %w", err)
 	}
+	if err := m.checkSubnetNotInUse(ctx, subnet); err != nil {
+		return err
+	}
+	return m.deleteSubnet(ctx, subnet)
+}
+
+func (m *manager) deleteSubnet(ctx context.Context, subnet *vivnfm.NetworkSubnet) error {
 	netAttachName := subnet.Metadata.Fields[network.K8sSubnetNetAttachNameLabel]
 	// The only way to get name from the vivnfm.NetworkSubnet resource is to get it by label.
 	subnetName := subnet.Metadata.Fields[network.K8sSubnetNameLabel]
@@ -491,5 +620,36 @@ func (m *manager) DeleteSubnet(ctx context.Context, opts ...network.GetSubnetOpt
 	if err := m.client.Delete(ctx, nad); err != nil {
 		return fmt.Errorf("delete multus NetworkAttachmentDefinition '%s' for subnet '%s': %w", netAttachName, subnet.ResourceId.Value, err)
 	}
+	if subnet.Metadata.Fields[network.K8sNetworkTypeLabel] == nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY.String() {
+		if err := m.routeSubnetsToPeers(ctx, subnet.Metadata.Fields[network.K8sNetworkNameLabel]); err != nil {
+			return fmt.Errorf("unroute subnet '%s' from the network peers: %w", subnetName, err)
+		}
+	}
 	return nil
 }
+
+// Patches the kubeovn subnet with the fields set in the subnet data. The cidr is
+// never mutated: VMs might already have IPs from it.
+func (m *manager) UpdateSubnet(ctx context.Context, subnetData *vivnfm.NetworkSubnetData, opts ...network.GetSubnetOpt) (*vivnfm.NetworkSubnet, error) {
+	current, err := m.GetSubnet(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("get subnet: %w", err)
+	}
+	subnetName := current.Metadata.Fields[network.K8sSubnetNameLabel]
+	subnet := &kubeovnv1.Subnet{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: subnetName}, subnet); err != nil {
+		return nil, fmt.Errorf("get kubeovn subnet '%s': %w", subnetName, err)
+	}
+	base := subnet.DeepCopy()
+	if err := applyNfvSubnetUpdate(subnet, subnetData); err != nil {
+		return nil, fmt.Errorf("update kubeovn subnet '%s' from NetworkSubnetData: %w", subnetName, err)
+	}
+	if err := m.client.Patch(ctx, subnet, client.MergeFrom(base)); err != nil {
+		return nil, fmt.Errorf("patch kubeovn subnet '%s' (id: %s): %w", subnetName, subnet.GetUID(), err)
+	}
+	res, err := nfvNetworkSubnetFromKubeovnSubnet(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("convert kubeovn subnet '%s' (id: %s) to nfv NetworkSubnet: %w", subnet.Name, subnet.GetUID(), err)
+	}
+	return res, nil
+}
@@ -3,6 +3,7 @@ package kubeovn
 import (
 	"context"
 	"testing"
+	"time"
 
 	netattv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
@@ -14,8 +15,10 @@ import (
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -25,7 +28,7 @@ func newManager(t *testing.T, objs ...client.Object) (*manager, client.Client) {
 	t.Helper()
 	cl := k8stest.NewClient(t, objs...)
 	ns := testNamespace
-	m, err := NewKubeovnNetworkManager(cl, cl, &config.K8sConfig{Namespace: &ns}, nil)
+	m, err := NewKubeovnNetworkManager(cl, cl, &config.K8sConfig{Namespace: &ns}, nil, nil)
 	require.NoError(t, err)
 	return m, cl
 }
@@ -165,6 +168,73 @@ func TestDeleteNetwork(t *testing.T) {
 		var target *apperrors.ErrNotFound
 		assert.ErrorAs(t, err, &target)
 	})
+
+	t.Run("a subnet in use keeps the whole network", func(t *testing.T) {
+		m, cl := newManager(t, seedVpc("net1", "sub1", "sub2"), seedSubnet("sub1"), seedSubnet("sub2"),
+			seedVm("vm1", formatNetAttachName("sub2")))
+		err := m.DeleteNetwork(context.Background(), network.GetNetworkByName("net1"))
+		var inUseErr *network.ErrInUse
+		require.ErrorAs(t, err, &inUseErr)
+		assert.Equal(t, []*nfvcommon.Identifier{k8stest.ID("uid-vm1")}, inUseErr.ComputeIds)
+		assert.Contains(t, err.Error(), "network 'net1'")
+		assert.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "sub1"}, &kubeovnv1.Subnet{}), "unused subnet should be kept")
+		assert.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "net1"}, &kubeovnv1.Vpc{}), "vpc should be kept")
+	})
+}
+
+func TestUpdateNetwork(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	t.Run("metadata is merged into the vpc annotations", func(t *testing.T) {
+		vpc := seedVpc("net1")
+		vpc.Annotations = map[string]string{"owner": "vnf-a", "tier": "gold"}
+		m, cl := newManager(t, vpc)
+		got, err := m.UpdateNetwork(ctx, &vivnfm.VirtualNetworkData{
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{"owner": "vnf-b", "tier": "", "zone": "a"}},
+		}, network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		want := map[string]string{"owner": "vnf-b", "zone": "a"}
+		assert.Equal(t, want, got.GetMetadata().GetFields())
+
+		stored := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, stored))
+		assert.Equal(t, want, stored.Annotations)
+	})
+
+	t.Run("underlay keeps its segmentation", func(t *testing.T) {
+		m, _ := newManager(t, seedVlan("vl1", 100))
+		segmentationId := uint64(100)
+		got, err := m.UpdateNetwork(ctx, &vivnfm.VirtualNetworkData{
+			SegmentationId: &segmentationId,
+			Metadata:       &nfvcommon.Metadata{Fields: map[string]string{"owner": "vnf-a"}},
+		}, network.GetNetworkByName("vl1"))
+		require.NoError(t, err)
+		assert.Equal(t, "vnf-a", got.GetMetadata().GetFields()["owner"])
+	})
+
+	t.Run("immutable changes are rejected", func(t *testing.T) {
+		m, _ := newManager(t, seedVlan("vl1", 100))
+		overlay := nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY
+		provider := "other"
+		segmentationId := uint64(200)
+		for name, data := range map[string]*vivnfm.VirtualNetworkData{
+			"network type":      {NetworkType: &overlay},
+			"provider network":  {ProviderNetwork: &provider},
+			"segmentation id":   {SegmentationId: &segmentationId},
+			"layer3 attributes": {Layer3Attributes: []*vivnfm.NetworkSubnetData{{}}},
+		} {
+			_, err := m.UpdateNetwork(ctx, data, network.GetNetworkByName("vl1"))
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+
+	t.Run("missing network surfaces not found", func(t *testing.T) {
+		m, _ := newManager(t)
+		_, err := m.UpdateNetwork(ctx, &vivnfm.VirtualNetworkData{}, network.GetNetworkByName("nope"))
+		var target *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &target)
+	})
 }
 
 func TestGetSubnet(t *testing.T) {
@@ -207,16 +277,168 @@ func TestListSubnets(t *testing.T) {
 	assert.Len(t, got, 2)
 }
 
+func seedVm(name string, netAttachNames ...string) *kubevirtv1.VirtualMachine {
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: managedMeta(name),
+		Spec:       kubevirtv1.VirtualMachineSpec{Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{}},
+	}
+	vm.Namespace = testNamespace
+	for _, nad := range netAttachNames {
+		vm.Spec.Template.Spec.Networks = append(vm.Spec.Template.Spec.Networks, kubevirtv1.Network{
+			Name:          nad,
+			NetworkSource: kubevirtv1.NetworkSource{Multus: &kubevirtv1.MultusNetwork{NetworkName: nad}},
+		})
+	}
+	return vm
+}
+
+func seedIp(name, subnet, podName, podType string) *kubeovnv1.IP {
+	return &kubeovnv1.IP{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{kubeovnSubnetLabel: subnet}},
+		Spec:       kubeovnv1.IPSpec{Subnet: subnet, Namespace: testNamespace, PodName: podName, PodType: podType},
+	}
+}
+
 func TestDeleteSubnet(t *testing.T) {
 	t.Parallel()
+	ctx := context.Background()
 	nad := &netattv1.NetworkAttachmentDefinition{
 		ObjectMeta: metav1.ObjectMeta{Name: formatNetAttachName("sub1"), Namespace: testNamespace},
 	}
-	m, cl := newManager(t, seedSubnet("sub1"), nad)
-	require.NoError(t, m.DeleteSubnet(context.Background(), network.GetSubnetByName("sub1")))
+	t.Run("deletes the subnet and its netattach", func(t *testing.T) {
+		m, cl := newManager(t, seedSubnet("sub1"), nad.DeepCopy(), seedVm("vm-other", formatNetAttachName("sub2")))
+		require.NoError(t, m.DeleteSubnet(ctx, network.GetSubnetByName("sub1")))
+
+		err := cl.Get(ctx, client.ObjectKey{Name: "sub1"}, &kubeovnv1.Subnet{})
+		assert.True(t, apierrors.IsNotFound(err), "subnet should be gone")
+		err = cl.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: formatNetAttachName("sub1")}, &netattv1.NetworkAttachmentDefinition{})
+		assert.True(t, apierrors.IsNotFound(err), "netattach should be gone")
+	})
+
+	t.Run("attached computes block the deletion", func(t *testing.T) {
+		launcher := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "virt-launcher-vm3-abcde",
+			Namespace: testNamespace,
+			Labels:    map[string]string{kubevirtv1.VirtualMachineNameLabel: "vm3"},
+		}}
+		deleting := seedVm("vm4", formatNetAttachName("sub1"))
+		deleting.Finalizers = []string{"kubevirt.io/virtualMachineControllerFinalize"}
+		deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		m, cl := newManager(t, seedSubnet("sub1"), nad.DeepCopy(),
+			seedVm("vm1", testNamespace+"/"+formatNetAttachName("sub1")),
+			seedVm("vm2"),
+			seedVm("vm3"),
+			deleting,
+			launcher,
+			seedIp("vm2.ip", "sub1", "vm2", "VirtualMachine"),
+			seedIp("vm3.ip", "sub1", launcher.Name, ""),
+			seedIp("other.ip", "sub1", "not-a-compute", ""),
+		)
+		err := m.DeleteSubnet(ctx, network.GetSubnetByName("sub1"))
+		var inUseErr *network.ErrInUse
+		require.ErrorAs(t, err, &inUseErr)
+		assert.Equal(t, []*nfvcommon.Identifier{k8stest.ID("uid-vm1"), k8stest.ID("uid-vm2"), k8stest.ID("uid-vm3")}, inUseErr.ComputeIds)
+		var preconditionErr *apperrors.ErrFailedPrecondition
+		assert.ErrorAs(t, err, &preconditionErr)
+		assert.Contains(t, err.Error(), "uid-vm1, uid-vm2, uid-vm3")
+
+		assert.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, &kubeovnv1.Subnet{}), "subnet should be kept")
+	})
+}
+
+func TestUpdateSubnet(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	t.Run("gateway, DHCP, pool and options are patched", func(t *testing.T) {
+		sub := seedSubnet("sub1")
+		sub.Spec.GatewayType = "distributed"
+		sub.Spec.NatOutgoing = true
+		sub.Spec.ExcludeIps = []string{"10.0.0.1"}
+		m, cl := newManager(t, sub)
+		dhcp := false
+		got, err := m.UpdateSubnet(ctx, &vivnfm.NetworkSubnetData{
+			Cidr:          &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24"},
+			GatewayIp:     &nfvcommon.IPAddress{Ip: "10.0.0.254"},
+			IsDhcpEnabled: &dhcp,
+			AddressPool:   addressPool("10.0.0.100", "10.0.0.200"),
+			Metadata:      &nfvcommon.Metadata{Fields: map[string]string{network.SubnetNatOutgoingMetadataKey: "false", network.SubnetMtuMetadataKey: "1400"}},
+		}, network.GetSubnetByUid(&nfvcommon.Identifier{Value: "uid-sub1"}))
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.254", got.GatewayIp.GetIp())
+		assert.False(t, got.IsDhcpEnabled)
+		assert.Equal(t, "false", got.Metadata.GetFields()[network.SubnetNatOutgoingMetadataKey])
+		assert.Equal(t, "1400", got.Metadata.GetFields()[network.SubnetMtuMetadataKey])
+
+		stored := &kubeovnv1.Subnet{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, stored))
+		assert.Equal(t, "10.0.0.0/24", stored.Spec.CIDRBlock)
+		assert.Equal(t, "10.0.0.254", stored.Spec.Gateway)
+		assert.False(t, stored.Spec.NatOutgoing)
+		assert.Equal(t, uint32(1400), stored.Spec.Mtu)
+		assert.Equal(t, []string{"10.0.0.1..10.0.0.99", "10.0.0.201..10.0.0.254"}, stored.Spec.ExcludeIps, "the old gateway exclusion is dropped")
+	})
+
+	t.Run("unset fields are kept", func(t *testing.T) {
+		sub := seedSubnet("sub1")
+		sub.Spec.ExcludeIps = []string{"10.0.0.1", "10.0.0.2..10.0.0.20"}
+		m, cl := newManager(t, sub)
+		_, err := m.UpdateSubnet(ctx, &vivnfm.NetworkSubnetData{
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{network.SubnetDnsServersMetadataKey: "10.0.0.53"}},
+		}, network.GetSubnetByName("sub1"))
+		require.NoError(t, err)
+
+		stored := &kubeovnv1.Subnet{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, stored))
+		assert.Equal(t, "10.0.0.1", stored.Spec.Gateway)
+		assert.True(t, stored.Spec.EnableDHCP)
+		assert.Equal(t, []string{"10.0.0.1", "10.0.0.2..10.0.0.20"}, stored.Spec.ExcludeIps)
+		assert.Contains(t, stored.Spec.DHCPv4Options, "dns_server=10.0.0.53")
+		assert.False(t, stored.Spec.NatOutgoing, "the current nat-outgoing is kept")
+	})
+
+	t.Run("bandwidth is set and reset", func(t *testing.T) {
+		m, cl := newManager(t, seedSubnet("sub1"))
+		got, err := m.UpdateSubnet(ctx, &vivnfm.NetworkSubnetData{
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{network.SubnetBandwidthMetadataKey: "100"}},
+		}, network.GetSubnetByName("sub1"))
+		require.NoError(t, err)
+		assert.Equal(t, "100", got.Metadata.GetFields()[network.SubnetBandwidthMetadataKey])
+		stored := &kubeovnv1.Subnet{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, stored))
+		assert.Equal(t, "100", stored.Annotations[network.SubnetBandwidthMetadataKey])
 
-	err := cl.Get(context.Background(), client.ObjectKey{Name: "sub1"}, &kubeovnv1.Subnet{})
-	assert.True(t, apierrors.IsNotFound(err), "subnet should be gone")
-	err = cl.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: formatNetAttachName("sub1")}, &netattv1.NetworkAttachmentDefinition{})
-	assert.True(t, apierrors.IsNotFound(err), "netattach should be gone")
+		_, err = m.UpdateSubnet(ctx, &vivnfm.NetworkSubnetData{
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{network.SubnetMtuMetadataKey: "1400"}},
+		}, network.GetSubnetByName("sub1"))
+		require.NoError(t, err)
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, stored))
+		assert.Equal(t, "100", stored.Annotations[network.SubnetBandwidthMetadataKey], "the current bandwidth is kept")
+
+		got, err = m.UpdateSubnet(ctx, &vivnfm.NetworkSubnetData{
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{network.SubnetBandwidthMetadataKey: ""}},
+		}, network.GetSubnetByName("sub1"))
+		require.NoError(t, err)
+		assert.NotContains(t, got.Metadata.GetFields(), network.SubnetBandwidthMetadataKey)
+		stored = &kubeovnv1.Subnet{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, stored))
+		assert.NotContains(t, stored.Annotations, network.SubnetBandwidthMetadataKey)
+	})
+
+	t.Run("immutable changes are rejected", func(t *testing.T) {
+		m, cl := newManager(t, seedSubnet("sub1"))
+		v6 := nfvcommon.IPVersion_IPV6
+		for name, data := range map[string]*vivnfm.NetworkSubnetData{
+			"cidr":       {Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.1.0.0/24"}},
+			"ip version": {IpVersion: &v6},
+			"network":    {NetworkId: &nfvcommon.Identifier{Value: "other-net"}},
+			"gateway":    {GatewayIp: &nfvcommon.IPAddress{Ip: "10.1.0.1"}},
+		} {
+			_, err := m.UpdateSubnet(ctx, data, network.GetSubnetByName("sub1"))
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+		stored := &kubeovnv1.Subnet{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "sub1"}, stored))
+		assert.Equal(t, seedSubnet("sub1").Spec, stored.Spec, "a rejected update leaves the subnet untouched")
+	})
 }
@@ -0,0 +1,892 @@
+package kubeovn
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	"go.uber.org/zap"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// A NAT gateway is the VpcNatGateway of its name with the iptables backend, and
+// the OvnEip of its name with the ovn one. Its external address is the
+// IptablesEIP or OvnEip of its name, and the SNAT rule of a subnet is named
+// <gateway>-<subnet>. A floating IP is the IptablesEIP or OvnEip of its name,
+// bound by the IptablesFIPRule or OvnFip of its name.
+const (
+	// natGatewayNameLabel names the NAT gateway an object is part of.
+	natGatewayNameLabel = "network.kubevim.kubenfv.io/nat-gateway-name"
+	// floatingIpNameLabel names the floating IP an object is part of.
+	floatingIpNameLabel = "network.kubevim.kubenfv.io/floating-ip-name"
+	// floatingIpGatewayLabel names the NAT gateway of a floating IP.
+	floatingIpGatewayLabel = "network.kubevim.kubenfv.io/floating-ip-nat-gateway"
+
+	kubeovnEipTypeNat = "nat"
+	defaultRouteCidr  = "0.0.0.0/0"
+)
+
+func (m *manager) natBackend() (config.NatConfigBackend, error) {
+	if m.natCfg == nil || m.natCfg.Backend == nil || *m.natCfg.Backend == "" {
+		return "", fmt.Errorf("NAT gateways and floating IPs with network.nat.backend unset: %w", apperrors.ErrUnsupported)
+	}
+	return *m.natCfg.Backend, nil
+}
+
+func (m *manager) CreateNatGateway(ctx context.Context, gw *network.NatGateway) (*network.NatGateway, error) {
+	backend, err := m.natBackend()
+	if err != nil {
+		return nil, err
+	}
+	if gw == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "nat gateway", Reason: "cannot be nil"}
+	}
+	if gw.Name == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "cannot be empty"}
+	}
+	if gw.NetworkId.GetValue() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "networkId", Reason: "cannot be empty"}
+	}
+	net, err := m.getOverlayNetwork(ctx, network.GetNetworkByUid(gw.NetworkId))
+	if err != nil {
+		return nil, fmt.Errorf("get overlay network '%s' of nat gateway '%s': %w", gw.NetworkId.GetValue(), gw.Name, err)
+	}
+	vpcName := net.GetNetworkResourceName()
+	existing, err := m.listNatGatewayObjects(ctx, client.MatchingLabels{network.K8sNetworkNameLabel: vpcName})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, &apperrors.ErrFailedPrecondition{
+			Resource: fmt.Sprintf("network '%s' (id: %s)", vpcName, gw.NetworkId.GetValue()),
+			Reason:   fmt.Sprintf("already has nat gateway '%s'", existing[0].GetName()),
+		}
+	}
+	snatSubnets := make([]*kubeovnv1.Subnet, 0, len(gw.SnatSubnetIds))
+	for _, subnetId := range gw.SnatSubnetIds {
+		subnet, err := m.networkKubeovnSubnet(ctx, subnetId, vpcName, "snatSubnetIds")
+		if err != nil {
+			return nil, err
+		}
+		snatSubnets = append(snatSubnets, subnet)
+	}
+
+	switch backend {
+	case config.Iptables:
+		err = m.createIptablesNatGateway(ctx, gw, vpcName, snatSubnets)
+	case config.Ovn:
+		err = m.createOvnNatGateway(ctx, gw, vpcName, snatSubnets)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create nat gateway '%s': %w", gw.Name, err)
+	}
+	return m.GetNatGateway(ctx, network.GetNatByName(gw.Name))
+}
+
+// Runs the gateway as a VpcNatGateway pod attached to the lan subnet and routes
+// the default traffic of the vpc to it.
+func (m *manager) createIptablesNatGateway(ctx context.Context, gw *network.NatGateway, vpcName string, snatSubnets []*kubeovnv1.Subnet) (err error) {
+	if gw.SubnetId.GetValue() == "" {
+		return &apperrors.ErrInvalidArgument{Field: "subnetId", Reason: "is required by the iptables nat backend"}
+	}
+	lanSubnet, err := m.networkKubeovnSubnet(ctx, gw.SubnetId, vpcName, "subnetId")
+	if err != nil {
+		return err
+	}
+	lanIp, err := netip.ParseAddr(gw.LanIpAddress)
+	if err != nil || !lanIp.Is4() {
+		return &apperrors.ErrInvalidArgument{Field: "lanIpAddress", Reason: fmt.Sprintf("'%s' is not an IPv4 address", gw.LanIpAddress)}
+	}
+	if prefix, ok := subnetPrefix4(lanSubnet); !ok || !prefix.Contains(lanIp) {
+		return &apperrors.ErrInvalidArgument{Field: "lanIpAddress", Reason: fmt.Sprintf("'%s' is not in subnet '%s' cidr '%s'", gw.LanIpAddress, lanSubnet.Name, lanSubnet.Spec.CIDRBlock)}
+	}
+	snatCidrs := make([]netip.Prefix, 0, len(snatSubnets))
+	for _, subnet := range snatSubnets {
+		prefix, ok := subnetPrefix4(subnet)
+		if !ok {
+			return &apperrors.ErrInvalidArgument{Field: "snatSubnetIds", Reason: fmt.Sprintf("subnet '%s' has no IPv4 cidr", subnet.Name)}
+		}
+		snatCidrs = append(snatCidrs, prefix)
+	}
+	if err := m.patchVpc(ctx, vpcName, func(vpc *kubeovnv1.Vpc) error {
+		if slices.ContainsFunc(vpc.Spec.StaticRoutes, func(r *kubeovnv1.StaticRoute) bool { return r.CIDR == defaultRouteCidr }) {
+			return &apperrors.ErrFailedPrecondition{Resource: fmt.Sprintf("network '%s'", vpcName), Reason: "already has a default route"}
+		}
+		vpc.Spec.StaticRoutes = append(vpc.Spec.StaticRoutes, &kubeovnv1.StaticRoute{
+			Policy:    kubeovnv1.PolicyDst,
+			CIDR:      defaultRouteCidr,
+			NextHopIP: lanIp.String(),
+		})
+		return nil
+	}); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			m.cleanupNatGateway(ctx, gw.Name, vpcName, lanIp.String())
+		}
+	}()
+	gwLabels := map[string]string{natGatewayNameLabel: gw.Name}
+	natGw := &kubeovnv1.VpcNatGateway{
+		ObjectMeta: natObjectMeta(gw.Name, vpcName, gwLabels),
+		Spec: kubeovnv1.VpcNatGatewaySpec{
+			Vpc:             vpcName,
+			Subnet:          lanSubnet.Name,
+			LanIP:           lanIp.String(),
+			ExternalSubnets: []string{*m.natCfg.ExternalSubnet},
+		},
+	}
+	if err := m.client.Create(ctx, natGw); err != nil {
+		return fmt.Errorf("create kube-ovn VpcNatGateway '%s': %w", natGw.Name, err)
+	}
+	eip := &kubeovnv1.IptablesEIP{
+		ObjectMeta: natObjectMeta(gw.Name, vpcName, gwLabels),
+		Spec: kubeovnv1.IptablesEIPSpec{
+			NatGwDp:        gw.Name,
+			ExternalSubnet: *m.natCfg.ExternalSubnet,
+		},
+	}
+	if err := m.client.Create(ctx, eip); err != nil {
+		return fmt.Errorf("create kube-ovn IptablesEIP '%s': %w", eip.Name, err)
+	}
+	for idx, subnet := range snatSubnets {
+		rule := &kubeovnv1.IptablesSnatRule{
+			ObjectMeta: natObjectMeta(gw.Name+"-"+subnet.Name, vpcName, map[string]string{natGatewayNameLabel: gw.Name, network.K8sSubnetNameLabel: subnet.Name}),
+			Spec: kubeovnv1.IptablesSnatRuleSpec{
+				EIP:          eip.Name,
+				InternalCIDR: snatCidrs[idx].String(),
+			},
+		}
+		if err := m.client.Create(ctx, rule); err != nil {
+			return fmt.Errorf("create kube-ovn IptablesSnatRule '%s': %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// Connects the vpc logical router to the external subnet and allocates the
+// external address of the gateway on it.
+func (m *manager) createOvnNatGateway(ctx context.Context, gw *network.NatGateway, vpcName string, snatSubnets []*kubeovnv1.Subnet) (err error) {
+	if gw.SubnetId.GetValue() != "" {
+		return &apperrors.ErrInvalidArgument{Field: "subnetId", Reason: "is only used by the iptables nat backend"}
+	}
+	if gw.LanIpAddress != "" {
+		return &apperrors.ErrInvalidArgument{Field: "lanIpAddress", Reason: "is only used by the iptables nat backend"}
+	}
+	if err := m.patchVpc(ctx, vpcName, func(vpc *kubeovnv1.Vpc) error {
+		vpc.Spec.EnableExternal = true
+		return nil
+	}); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			m.cleanupNatGateway(ctx, gw.Name, vpcName, "")
+		}
+	}()
+	gwLabels := map[string]string{natGatewayNameLabel: gw.Name}
+	eip := &kubeovnv1.OvnEip{
+		ObjectMeta: natObjectMeta(gw.Name, vpcName, gwLabels),
+		Spec: kubeovnv1.OvnEipSpec{
+			ExternalSubnet: *m.natCfg.ExternalSubnet,
+			Type:           kubeovnEipTypeNat,
+		},
+	}
+	if err := m.client.Create(ctx, eip); err != nil {
+		return fmt.Errorf("create kube-ovn OvnEip '%s': %w", eip.Name, err)
+	}
+	for _, subnet := range snatSubnets {
+		rule := &kubeovnv1.OvnSnatRule{
+			ObjectMeta: natObjectMeta(gw.Name+"-"+subnet.Name, vpcName, map[string]string{natGatewayNameLabel: gw.Name, network.K8sSubnetNameLabel: subnet.Name}),
+			Spec: kubeovnv1.OvnSnatRuleSpec{
+				OvnEip:    eip.Name,
+				VpcSubnet: subnet.Name,
+			},
+		}
+		if err := m.client.Create(ctx, rule); err != nil {
+			return fmt.Errorf("create kube-ovn OvnSnatRule '%s': %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *manager) GetNatGateway(ctx context.Context, opts ...network.GetNatOpt) (*network.NatGateway, error) {
+	if _, err := m.natBackend(); err != nil {
+		return nil, err
+	}
+	gw, err := m.getNatGatewayObject(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.nfvNatGateway(ctx, gw)
+}
+
+func (m *manager) ListNatGateways(ctx context.Context) ([]*network.NatGateway, error) {
+	if _, err := m.natBackend(); err != nil {
+		return nil, err
+	}
+	gws, err := m.listNatGatewayObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*network.NatGateway, 0, len(gws))
+	for _, obj := range gws {
+		gw, err := m.nfvNatGateway(ctx, obj)
+		if err != nil {
+			return nil, fmt.Errorf("convert nat gateway '%s': %w", obj.GetName(), err)
+		}
+		res = append(res, gw)
+	}
+	return res, nil
+}
+
+// Deletes the NAT gateway, refusing while floating IPs are allocated through
+// it.
+func (m *manager) DeleteNatGateway(ctx context.Context, opts ...network.GetNatOpt) error {
+	if _, err := m.natBackend(); err != nil {
+		return err
+	}
+	gw, err := m.getNatGatewayObject(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	fips, err := m.listFloatingIpObjects(ctx, client.MatchingLabels{floatingIpGatewayLabel: gw.GetName()})
+	if err != nil {
+		return err
+	}
+	if len(fips) > 0 {
+		names := make([]string, 0, len(fips))
+		for _, fip := range fips {
+			names = append(names, fip.GetName())
+		}
+		return &apperrors.ErrFailedPrecondition{
+			Resource: fmt.Sprintf("nat gateway '%s' (id: %s)", gw.GetName(), gw.GetUID()),
+			Reason:   fmt.Sprintf("has %d floating IP(s): %s", len(names), strings.Join(names, ", ")),
+		}
+	}
+	var lanIp string
+	if natGw, ok := gw.(*kubeovnv1.VpcNatGateway); ok {
+		lanIp = natGw.Spec.LanIP
+	}
+	if err := m.deleteNatGatewayObjects(ctx, gw.GetName(), gw.GetLabels()[network.K8sNetworkNameLabel], lanIp); err != nil {
+		return fmt.Errorf("delete nat gateway '%s': %w", gw.GetName(), err)
+	}
+	return nil
+}
+
+// Refuses the deletion of the network with the vpc while a NAT gateway connects
+// it, as deleting the vpc would leave the NAT objects behind.
+func (m *manager) checkNetworkHasNoNatGateway(ctx context.Context, vpcName string) error {
+	if _, err := m.natBackend(); err != nil {
+		return nil
+	}
+	gws, err := m.listNatGatewayObjects(ctx, client.MatchingLabels{network.K8sNetworkNameLabel: vpcName})
+	if err != nil {
+		return fmt.Errorf("list nat gateways of network '%s': %w", vpcName, err)
+	}
+	if len(gws) > 0 {
+		return &apperrors.ErrFailedPrecondition{
+			Resource: fmt.Sprintf("network '%s'", vpcName),
+			Reason:   fmt.Sprintf("connected by nat gateway '%s'", gws[0].GetName()),
+		}
+	}
+	return nil
+}
+
+// Deletes what was created of a NAT gateway whose creation failed.
+func (m *manager) cleanupNatGateway(ctx context.Context, name, vpcName, lanIp string) {
+	if err := m.deleteNatGatewayObjects(context.WithoutCancel(ctx), name, vpcName, lanIp); err != nil {
+		m.logger.Warn("clean up objects of failed nat gateway", zap.String("name", name), zap.Error(err))
+	}
+}
+
+// Deletes the objects of the NAT gateway that exist and disconnects the vpc
+// from the external network.
+func (m *manager) deleteNatGatewayObjects(ctx context.Context, name, vpcName, lanIp string) error {
+	selector := client.MatchingLabels{natGatewayNameLabel: name}
+	switch *m.natCfg.Backend {
+	case config.Iptables:
+		for _, list := range []client.ObjectList{&kubeovnv1.IptablesSnatRuleList{}, &kubeovnv1.IptablesEIPList{}, &kubeovnv1.VpcNatGatewayList{}} {
+			if err := m.deleteNatObjects(ctx, list, selector); err != nil {
+				return err
+			}
+		}
+		return m.patchVpc(ctx, vpcName, func(vpc *kubeovnv1.Vpc) error {
+			vpc.Spec.StaticRoutes = slices.DeleteFunc(vpc.Spec.StaticRoutes, func(r *kubeovnv1.StaticRoute) bool {
+				return r.CIDR == defaultRouteCidr && r.NextHopIP == lanIp
+			})
+			return nil
+		})
+	case config.Ovn:
+		for _, list := range []client.ObjectList{&kubeovnv1.OvnSnatRuleList{}, &kubeovnv1.OvnEipList{}} {
+			if err := m.deleteNatObjects(ctx, list, selector); err != nil {
+				return err
+			}
+		}
+		return m.patchVpc(ctx, vpcName, func(vpc *kubeovnv1.Vpc) error {
+			vpc.Spec.EnableExternal = false
+			return nil
+		})
+	}
+	return nil
+}
+
+func (m *manager) CreateFloatingIp(ctx context.Context, fip *network.FloatingIp) (*network.FloatingIp, error) {
+	backend, err := m.natBackend()
+	if err != nil {
+		return nil, err
+	}
+	if fip == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "floating ip", Reason: "cannot be nil"}
+	}
+	if fip.Name == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "cannot be empty"}
+	}
+	if fip.NatGatewayId.GetValue() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "natGatewayId", Reason: "cannot be empty"}
+	}
+	gw, err := m.getNatGatewayObject(ctx, network.GetNatByUid(fip.NatGatewayId))
+	if err != nil {
+		return nil, fmt.Errorf("get nat gateway '%s' of floating ip '%s': %w", fip.NatGatewayId.GetValue(), fip.Name, err)
+	}
+	var ip string
+	if fip.IpAddress != "" {
+		addr, err := netip.ParseAddr(fip.IpAddress)
+		if err != nil || !addr.Is4() {
+			return nil, &apperrors.ErrInvalidArgument{Field: "ipAddress", Reason: fmt.Sprintf("'%s' is not an IPv4 address", fip.IpAddress)}
+		}
+		ip = addr.String()
+	}
+	vpcName := gw.GetLabels()[network.K8sNetworkNameLabel]
+	if fip.PortIpAddress != "" {
+		if err := m.validateFloatingIpPort(ctx, vpcName, fip.PortIpAddress); err != nil {
+			return nil, err
+		}
+	}
+	meta := natObjectMeta(fip.Name, vpcName, map[string]string{floatingIpNameLabel: fip.Name, floatingIpGatewayLabel: gw.GetName()})
+	var eip client.Object
+	switch backend {
+	case config.Iptables:
+		eip = &kubeovnv1.IptablesEIP{
+			ObjectMeta: meta,
+			Spec: kubeovnv1.IptablesEIPSpec{
+				V4ip:           ip,
+				NatGwDp:        gw.GetName(),
+				ExternalSubnet: *m.natCfg.ExternalSubnet,
+			},
+		}
+	case config.Ovn:
+		eip = &kubeovnv1.OvnEip{
+			ObjectMeta: meta,
+			Spec: kubeovnv1.OvnEipSpec{
+				ExternalSubnet: *m.natCfg.ExternalSubnet,
+				V4Ip:           ip,
+				Type:           kubeovnEipTypeNat,
+			},
+		}
+	}
+	if err := m.client.Create(ctx, eip); err != nil {
+		return nil, fmt.Errorf("create kube-ovn eip '%s': %w", fip.Name, err)
+	}
+	if fip.PortIpAddress != "" {
+		if err := m.client.Create(ctx, m.floatingIpBinding(eip, fip.PortIpAddress)); err != nil {
+			if cleanupErr := m.client.Delete(context.WithoutCancel(ctx), eip); cleanupErr != nil {
+				m.logger.Warn("clean up eip of failed floating ip", zap.String("name", fip.Name), zap.Error(cleanupErr))
+			}
+			return nil, fmt.Errorf("bind floating ip '%s' to '%s': %w", fip.Name, fip.PortIpAddress, err)
+		}
+	}
+	return m.nfvFloatingIp(ctx, eip)
+}
+
+func (m *manager) GetFloatingIp(ctx context.Context, opts ...network.GetNatOpt) (*network.FloatingIp, error) {
+	if _, err := m.natBackend(); err != nil {
+		return nil, err
+	}
+	eip, err := m.getFloatingIpObject(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.nfvFloatingIp(ctx, eip)
+}
+
+func (m *manager) ListFloatingIps(ctx context.Context) ([]*network.FloatingIp, error) {
+	if _, err := m.natBackend(); err != nil {
+		return nil, err
+	}
+	eips, err := m.listFloatingIpObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*network.FloatingIp, 0, len(eips))
+	for _, eip := range eips {
+		fip, err := m.nfvFloatingIp(ctx, eip)
+		if err != nil {
+			return nil, fmt.Errorf("convert floating ip '%s': %w", eip.GetName(), err)
+		}
+		res = append(res, fip)
+	}
+	return res, nil
+}
+
+// Binds the floating IP to the port address of the request, replacing its
+// current binding, or unbinds it when the port address is empty.
+func (m *manager) UpdateFloatingIp(ctx context.Context, fip *network.FloatingIp) (*network.FloatingIp, error) {
+	if _, err := m.natBackend(); err != nil {
+		return nil, err
+	}
+	if fip == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "floating ip", Reason: "cannot be nil"}
+	}
+	if fip.FloatingIpId.GetValue() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "floatingIpId", Reason: "cannot be empty"}
+	}
+	eip, err := m.getFloatingIpObject(ctx, network.GetNatByUid(fip.FloatingIpId))
+	if err != nil {
+		return nil, err
+	}
+	if fip.Name != "" && fip.Name != eip.GetName() {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "is immutable"}
+	}
+	if fip.NatGatewayId.GetValue() != "" || fip.IpAddress != "" {
+		current, err := m.nfvFloatingIp(ctx, eip)
+		if err != nil {
+			return nil, err
+		}
+		if fip.NatGatewayId.GetValue() != "" && fip.NatGatewayId.GetValue() != current.NatGatewayId.GetValue() {
+			return nil, &apperrors.ErrInvalidArgument{Field: "natGatewayId", Reason: "is immutable"}
+		}
+		if fip.IpAddress != "" && fip.IpAddress != current.IpAddress {
+			return nil, &apperrors.ErrInvalidArgument{Field: "ipAddress", Reason: "is immutable"}
+		}
+	}
+	if fip.PortIpAddress != "" {
+		if err := m.validateFloatingIpPort(ctx, eip.GetLabels()[network.K8sNetworkNameLabel], fip.PortIpAddress); err != nil {
+			return nil, err
+		}
+	}
+	binding := m.floatingIpBinding(eip, "")
+	err = m.client.Get(ctx, client.ObjectKey{Name: eip.GetName()}, binding)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return nil, fmt.Errorf("get binding of floating ip '%s': %w", eip.GetName(), err)
+	}
+	bound := err == nil
+	switch {
+	case !bound && fip.PortIpAddress != "":
+		if err := m.client.Create(ctx, m.floatingIpBinding(eip, fip.PortIpAddress)); err != nil {
+			return nil, fmt.Errorf("bind floating ip '%s' to '%s': %w", eip.GetName(), fip.PortIpAddress, err)
+		}
+	case bound && fip.PortIpAddress == "":
+		if err := m.client.Delete(ctx, binding); err != nil {
+			return nil, fmt.Errorf("unbind floating ip '%s': %w", eip.GetName(), err)
+		}
+	case bound:
+		base := binding.DeepCopyObject().(client.Object)
+		setFloatingIpBindingPort(binding, fip.PortIpAddress)
+		if err := m.client.Patch(ctx, binding, client.MergeFrom(base)); err != nil {
+			return nil, fmt.Errorf("bind floating ip '%s' to '%s': %w", eip.GetName(), fip.PortIpAddress, err)
+		}
+	}
+	return m.nfvFloatingIp(ctx, eip)
+}
+
+func (m *manager) DeleteFloatingIp(ctx context.Context, opts ...network.GetNatOpt) error {
+	if _, err := m.natBackend(); err != nil {
+		return err
+	}
+	eip, err := m.getFloatingIpObject(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	if err := client.IgnoreNotFound(m.client.Delete(ctx, m.floatingIpBinding(eip, ""))); err != nil {
+		return fmt.Errorf("unbind floating ip '%s': %w", eip.GetName(), err)
+	}
+	if err := m.client.Delete(ctx, eip); err != nil {
+		return fmt.Errorf("delete floating ip '%s': %w", eip.GetName(), err)
+	}
+	return nil
+}
+
+// Returns the IptablesFIPRule or OvnFip binding the floating IP eip to the port
+// address.
+func (m *manager) floatingIpBinding(eip client.Object, portIp string) client.Object {
+	meta := v1.ObjectMeta{Name: eip.GetName(), Labels: maps.Clone(eip.GetLabels())}
+	if _, ok := eip.(*kubeovnv1.IptablesEIP); ok {
+		return &kubeovnv1.IptablesFIPRule{
+			ObjectMeta: meta,
+			Spec:       kubeovnv1.IptablesFIPRuleSpec{EIP: eip.GetName(), InternalIP: portIp},
+		}
+	}
+	return &kubeovnv1.OvnFip{
+		ObjectMeta: meta,
+		Spec: kubeovnv1.OvnFipSpec{
+			OvnEip: eip.GetName(),
+			Vpc:    eip.GetLabels()[network.K8sNetworkNameLabel],
+			V4Ip:   portIp,
+		},
+	}
+}
+
+func setFloatingIpBindingPort(binding client.Object, portIp string) {
+	switch b := binding.(type) {
+	case *kubeovnv1.IptablesFIPRule:
+		b.Spec.InternalIP = portIp
+	case *kubeovnv1.OvnFip:
+		b.Spec.V4Ip = portIp
+	}
+}
+
+// Checks that the port address is an IPv4 address in a subnet of the vpc.
+func (m *manager) validateFloatingIpPort(ctx context.Context, vpcName, portIp string) error {
+	addr, err := netip.ParseAddr(portIp)
+	if err != nil || !addr.Is4() {
+		return &apperrors.ErrInvalidArgument{Field: "portIpAddress", Reason: fmt.Sprintf("'%s' is not an IPv4 address", portIp)}
+	}
+	vpc := &kubeovnv1.Vpc{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: vpcName}, vpc); err != nil {
+		return fmt.Errorf("get kubeovn vpc '%s': %w", vpcName, err)
+	}
+	for _, subnetName := range vpc.Status.Subnets {
+		subnet := &kubeovnv1.Subnet{}
+		if err := m.client.Get(ctx, client.ObjectKey{Name: subnetName}, subnet); err != nil {
+			return fmt.Errorf("get kubeovn subnet '%s': %w", subnetName, err)
+		}
+		if prefix, ok := subnetPrefix4(subnet); ok && prefix.Contains(addr) {
+			return nil
+		}
+	}
+	return &apperrors.ErrInvalidArgument{Field: "portIpAddress", Reason: fmt.Sprintf("'%s' is not in a subnet of network '%s'", portIp, vpcName)}
+}
+
+// Returns the kube-ovn Subnet with the id, checking that it is a subnet of the
+// vpc.
+func (m *manager) networkKubeovnSubnet(ctx context.Context, subnetId *nfvcommon.Identifier, vpcName, field string) (*kubeovnv1.Subnet, error) {
+	subnet, err := m.GetSubnet(ctx, network.GetSubnetByUid(subnetId))
+	if err != nil {
+		return nil, fmt.Errorf("get subnet '%s': %w", subnetId.GetValue(), err)
+	}
+	subnetName := subnet.Metadata.Fields[network.K8sSubnetNameLabel]
+	kubeovnSub := &kubeovnv1.Subnet{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: subnetName}, kubeovnSub); err != nil {
+		return nil, fmt.Errorf("get kubeovn subnet '%s': %w", subnetName, err)
+	}
+	if kubeovnSub.Spec.Vpc != vpcName {
+		return nil, &apperrors.ErrInvalidArgument{Field: field, Reason: fmt.Sprintf("subnet '%s' is not in network '%s'", subnetName, vpcName)}
+	}
+	return kubeovnSub, nil
+}
+
+// patchVpc applies mutate to the current vpc. The vpc is read uncached, and
+// read and mutated again when a concurrent update conflicts with the patch.
+func (m *manager) patchVpc(ctx context.Context, vpcName string, mutate func(*kubeovnv1.Vpc) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		vpc := &kubeovnv1.Vpc{}
+		if err := m.apiReader.Get(ctx, client.ObjectKey{Name: vpcName}, vpc); err != nil {
+			return fmt.Errorf("get kubeovn vpc '%s': %w", vpcName, err)
+		}
+		base := vpc.DeepCopy()
+		if err := mutate(vpc); err != nil {
+			return err
+		}
+		if err := m.client.Patch(ctx, vpc, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+			return fmt.Errorf("patch kubeovn vpc '%s': %w", vpcName, err)
+		}
+		return nil
+	})
+}
+
+func (m *manager) getNatGatewayObject(ctx context.Context, opts ...network.GetNatOpt) (client.Object, error) {
+	if *m.natCfg.Backend == config.Iptables {
+		return m.getNatObject(ctx, "nat gateway", &kubeovnv1.VpcNatGateway{}, &kubeovnv1.VpcNatGatewayList{}, natGatewayNameLabel, opts...)
+	}
+	return m.getNatObject(ctx, "nat gateway", &kubeovnv1.OvnEip{}, &kubeovnv1.OvnEipList{}, natGatewayNameLabel, opts...)
+}
+
+func (m *manager) getFloatingIpObject(ctx context.Context, opts ...network.GetNatOpt) (client.Object, error) {
+	if *m.natCfg.Backend == config.Iptables {
+		return m.getNatObject(ctx, "floating ip", &kubeovnv1.IptablesEIP{}, &kubeovnv1.IptablesEIPList{}, floatingIpNameLabel, opts...)
+	}
+	return m.getNatObject(ctx, "floating ip", &kubeovnv1.OvnEip{}, &kubeovnv1.OvnEipList{}, floatingIpNameLabel, opts...)
+}
+
+// Gets into obj the managed object with the name or uid of the options, that
+// carries the label.
+func (m *manager) getNatObject(ctx context.Context, entity string, obj client.Object, list client.ObjectList, label string, opts ...network.GetNatOpt) (client.Object, error) {
+	cfg := network.ApplyGetNatOpts(opts...)
+	name := cfg.Name
+	if name == "" {
+		if cfg.Uid == nil || cfg.Uid.Value == "" {
+			return nil, &apperrors.ErrInvalidArgument{Field: entity + " identifier", Reason: "either name or uid must be specified"}
+		}
+		items, err := m.listNatObjects(ctx, list, client.HasLabels{label})
+		if err != nil {
+			return nil, err
+		}
+		uid := misc.IdentifierToUID(cfg.Uid)
+		idx := slices.IndexFunc(items, func(o client.Object) bool { return o.GetUID() == uid })
+		if idx < 0 {
+			return nil, &apperrors.ErrNotFound{Entity: entity, Identifier: cfg.Uid.GetValue()}
+		}
+		return items[idx], nil
+	}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: name}, obj); err != nil {
+		return nil, fmt.Errorf("get %s '%s': %w", entity, name, err)
+	}
+	if !misc.IsObjectManagedByKubeNfv(obj) {
+		return nil, &apperrors.ErrK8sObjectNotManagedByKubeNfv{ObjectType: entity, ObjectName: obj.GetName(), ObjectId: string(obj.GetUID())}
+	}
+	if _, ok := obj.GetLabels()[label]; !ok {
+		return nil, &apperrors.ErrNotFound{Entity: entity, Identifier: name}
+	}
+	return obj, nil
+}
+
+func (m *manager) listNatGatewayObjects(ctx context.Context, opts ...client.ListOption) ([]client.Object, error) {
+	var list client.ObjectList = &kubeovnv1.OvnEipList{}
+	if *m.natCfg.Backend == config.Iptables {
+		list = &kubeovnv1.VpcNatGatewayList{}
+	}
+	return m.listNatObjects(ctx, list, append(opts, client.HasLabels{natGatewayNameLabel})...)
+}
+
+func (m *manager) listFloatingIpObjects(ctx context.Context, opts ...client.ListOption) ([]client.Object, error) {
+	var list client.ObjectList = &kubeovnv1.OvnEipList{}
+	if *m.natCfg.Backend == config.Iptables {
+		list = &kubeovnv1.IptablesEIPList{}
+	}
+	return m.listNatObjects(ctx, list, append(opts, client.HasLabels{floatingIpNameLabel})...)
+}
+
+// Lists the managed objects of the list kind matching the options.
+func (m *manager) listNatObjects(ctx context.Context, list client.ObjectList, opts ...client.ListOption) ([]client.Object, error) {
+	opts = append(opts, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName})
+	if err := m.client.List(ctx, list, opts...); err != nil {
+		return nil, fmt.Errorf("list kube-ovn %T: %w", list, err)
+	}
+	items, err := apimeta.ExtractList(list)
+	if err != nil {
+		return nil, fmt.Errorf("extract kube-ovn %T: %w", list, err)
+	}
+	res := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(client.Object); ok {
+			res = append(res, obj)
+		}
+	}
+	return res, nil
+}
+
+func (m *manager) deleteNatObjects(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	objs, err := m.listNatObjects(ctx, list, opts...)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := client.IgnoreNotFound(m.client.Delete(ctx, obj)); err != nil {
+			return fmt.Errorf("delete kube-ovn %T '%s': %w", obj, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func (m *manager) nfvNatGateway(ctx context.Context, obj client.Object) (*network.NatGateway, error) {
+	if !misc.IsObjectInstantiated(obj) {
+		return nil, &apperrors.ErrK8sObjectNotInstantiated{ObjectType: "nat gateway", Identifier: obj.GetName()}
+	}
+	vpcName := obj.GetLabels()[network.K8sNetworkNameLabel]
+	vpc := &kubeovnv1.Vpc{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: vpcName}, vpc); err != nil {
+		return nil, fmt.Errorf("get kubeovn vpc '%s' of nat gateway '%s': %w", vpcName, obj.GetName(), err)
+	}
+	gw := &network.NatGateway{
+		NatGatewayId: misc.UIDToIdentifier(obj.GetUID()),
+		Name:         obj.GetName(),
+		NetworkId:    misc.UIDToIdentifier(vpc.UID),
+	}
+	var rules client.ObjectList = &kubeovnv1.OvnSnatRuleList{}
+	if *m.natCfg.Backend == config.Iptables {
+		rules = &kubeovnv1.IptablesSnatRuleList{}
+	}
+	snatRules, err := m.listNatObjects(ctx, rules, client.MatchingLabels{natGatewayNameLabel: obj.GetName()})
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range snatRules {
+		subnetId, err := m.kubeovnSubnetId(ctx, rule.GetLabels()[network.K8sSubnetNameLabel])
+		if err != nil {
+			return nil, err
+		}
+		gw.SnatSubnetIds = append(gw.SnatSubnetIds, subnetId)
+	}
+	switch o := obj.(type) {
+	case *kubeovnv1.VpcNatGateway:
+		if gw.SubnetId, err = m.kubeovnSubnetId(ctx, o.Spec.Subnet); err != nil {
+			return nil, err
+		}
+		gw.LanIpAddress = o.Spec.LanIP
+		eip := &kubeovnv1.IptablesEIP{}
+		if err := m.client.Get(ctx, client.ObjectKey{Name: o.Name}, eip); err != nil {
+			return nil, fmt.Errorf("get kube-ovn IptablesEIP '%s': %w", o.Name, err)
+		}
+		gw.ExternalIpAddress = iptablesEipAddress(eip)
+	case *kubeovnv1.OvnEip:
+		gw.ExternalIpAddress = ovnEipAddress(o)
+	}
+	return gw, nil
+}
+
+func (m *manager) nfvFloatingIp(ctx context.Context, eip client.Object) (*network.FloatingIp, error) {
+	if !misc.IsObjectInstantiated(eip) {
+		return nil, &apperrors.ErrK8sObjectNotInstantiated{ObjectType: "floating ip", Identifier: eip.GetName()}
+	}
+	gwName := eip.GetLabels()[floatingIpGatewayLabel]
+	gw, err := m.getNatGatewayObject(ctx, network.GetNatByName(gwName))
+	if err != nil {
+		return nil, fmt.Errorf("get nat gateway '%s' of floating ip '%s': %w", gwName, eip.GetName(), err)
+	}
+	fip := &network.FloatingIp{
+		FloatingIpId: misc.UIDToIdentifier(eip.GetUID()),
+		Name:         eip.GetName(),
+		NatGatewayId: misc.UIDToIdentifier(gw.GetUID()),
+	}
+	binding := m.floatingIpBinding(eip, "")
+	if err := m.client.Get(ctx, client.ObjectKey{Name: eip.GetName()}, binding); err != nil && !k8s_errors.IsNotFound(err) {
+		return nil, fmt.Errorf("get binding of floating ip '%s': %w", eip.GetName(), err)
+	}
+	switch e := eip.(type) {
+	case *kubeovnv1.IptablesEIP:
+		fip.IpAddress = iptablesEipAddress(e)
+		fip.PortIpAddress = binding.(*kubeovnv1.IptablesFIPRule).Spec.InternalIP
+	case *kubeovnv1.OvnEip:
+		fip.IpAddress = ovnEipAddress(e)
+		fip.PortIpAddress = binding.(*kubeovnv1.OvnFip).Spec.V4Ip
+	}
+	return fip, nil
+}
+
+// Returns the <port address>=<external address> floating IPs bound in each vpc,
+// or nil when NAT is disabled.
+func (m *manager) floatingIpsByVpc(ctx context.Context) (map[string][]string, error) {
+	if _, err := m.natBackend(); err != nil {
+		return nil, nil
+	}
+	res := map[string][]string{}
+	switch *m.natCfg.Backend {
+	case config.Iptables:
+		rules := &kubeovnv1.IptablesFIPRuleList{}
+		if err := m.client.List(ctx, rules, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+			return nil, fmt.Errorf("list kube-ovn IptablesFIPRules: %w", err)
+		}
+		for idx := range rules.Items {
+			rule := &rules.Items[idx]
+			external := rule.Status.V4ip
+			if external == "" {
+				eip := &kubeovnv1.IptablesEIP{}
+				if err := m.client.Get(ctx, client.ObjectKey{Name: rule.Spec.EIP}, eip); err != nil {
+					return nil, fmt.Errorf("get kube-ovn IptablesEIP '%s': %w", rule.Spec.EIP, err)
+				}
+				external = iptablesEipAddress(eip)
+			}
+			vpcName := rule.Labels[network.K8sNetworkNameLabel]
+			res[vpcName] = append(res[vpcName], rule.Spec.InternalIP+"="+external)
+		}
+	case config.Ovn:
+		fips := &kubeovnv1.OvnFipList{}
+		if err := m.client.List(ctx, fips, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+			return nil, fmt.Errorf("list kube-ovn OvnFips: %w", err)
+		}
+		for idx := range fips.Items {
+			fip := &fips.Items[idx]
+			external := fip.Status.V4Eip
+			if external == "" {
+				eip := &kubeovnv1.OvnEip{}
+				if err := m.client.Get(ctx, client.ObjectKey{Name: fip.Spec.OvnEip}, eip); err != nil {
+					return nil, fmt.Errorf("get kube-ovn OvnEip '%s': %w", fip.Spec.OvnEip, err)
+				}
+				external = ovnEipAddress(eip)
+			}
+			res[fip.Spec.Vpc] = append(res[fip.Spec.Vpc], fip.Spec.V4Ip+"="+external)
+		}
+	}
+	for _, pairs := range res {
+		slices.Sort(pairs)
+	}
+	return res, nil
+}
+
+// Reports the floating IPs bound to the ports of the network in its metadata.
+func setNetworkFloatingIps(net *vivnfm.VirtualNetwork, fips []string) {
+	if len(fips) == 0 {
+		return
+	}
+	if net.Metadata == nil {
+		net.Metadata = &nfvcommon.Metadata{}
+	}
+	if net.Metadata.Fields == nil {
+		net.Metadata.Fields = map[string]string{}
+	}
+	net.Metadata.Fields[network.NetworkFloatingIpsMetadataKey] = strings.Join(fips, ",")
+}
+
+func (m *manager) kubeovnSubnetId(ctx context.Context, subnetName string) (*nfvcommon.Identifier, error) {
+	subnet := &kubeovnv1.Subnet{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: subnetName}, subnet); err != nil {
+		return nil, fmt.Errorf("get kubeovn subnet '%s': %w", subnetName, err)
+	}
+	return misc.UIDToIdentifier(subnet.UID), nil
+}
+
+func natObjectMeta(name, vpcName string, labels map[string]string) v1.ObjectMeta {
+	meta := v1.ObjectMeta{
+		Name: name,
+		Labels: map[string]string{
+			common.K8sManagedByLabel:    common.KubeNfvName,
+			network.K8sNetworkNameLabel: vpcName,
+		},
+	}
+	maps.Copy(meta.Labels, labels)
+	return meta
+}
+
+// Returns the IPv4 cidr of the subnet.
+func subnetPrefix4(subnet *kubeovnv1.Subnet) (netip.Prefix, bool) {
+	prefixes, err := parseSubnetCidrs(subnet.Spec.CIDRBlock)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	idx := slices.IndexFunc(prefixes, func(p netip.Prefix) bool { return p.Addr().Is4() })
+	if idx < 0 {
+		return netip.Prefix{}, false
+	}
+	return prefixes[idx], true
+}
+
+// kube-ovn reports the external address once it allocated it.
+func iptablesEipAddress(eip *kubeovnv1.IptablesEIP) string {
+	if eip.Status.IP != "" {
+		return eip.Status.IP
+	}
+	return eip.Spec.V4ip
+}
+
+func ovnEipAddress(eip *kubeovnv1.OvnEip) string {
+	if eip.Status.V4Ip != "" {
+		return eip.Status.V4Ip
+	}
+	return eip.Spec.V4Ip
+}
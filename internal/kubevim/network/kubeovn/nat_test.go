@@ -0,0 +1,223 @@
+package kubeovn
+
+import (
+	"context"
+	"testing"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newNatManager(t *testing.T, backend config.NatConfigBackend, objs ...client.Object) (*manager, client.Client) {
+	t.Helper()
+	m, cl := newManager(t, objs...)
+	m.natCfg = &config.NatConfig{Backend: &backend, ExternalSubnet: k8stest.Ptr("external")}
+	return m, cl
+}
+
+func seedVpcSubnet(name, vpc string) *kubeovnv1.Subnet {
+	subnet := seedSubnet(name)
+	subnet.Spec.Vpc = vpc
+	return subnet
+}
+
+func natSeeds() []client.Object {
+	return []client.Object{
+		seedVpc("net1", "sub1"),
+		seedVpcSubnet("sub1", "net1"),
+		seedVpc("net2", "sub2"),
+		seedVpcSubnet("sub2", "net2"),
+	}
+}
+
+func TestNatDisabled(t *testing.T) {
+	t.Parallel()
+	m, _ := newManager(t, natSeeds()...)
+	_, err := m.CreateNatGateway(context.Background(), &network.NatGateway{Name: "gw", NetworkId: k8stest.ID("uid-net1")})
+	assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	_, err = m.ListFloatingIps(context.Background())
+	assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+
+	net, err := m.GetNetwork(context.Background(), network.GetNetworkByName("net1"))
+	require.NoError(t, err)
+	assert.NotContains(t, net.GetMetadata().GetFields(), network.NetworkFloatingIpsMetadataKey)
+}
+
+func TestIptablesNat(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	m, cl := newNatManager(t, config.Iptables, natSeeds()...)
+
+	gw, err := m.CreateNatGateway(ctx, &network.NatGateway{
+		Name:          "gw",
+		NetworkId:     k8stest.ID("uid-net1"),
+		SubnetId:      k8stest.ID("uid-sub1"),
+		LanIpAddress:  "10.0.0.254",
+		SnatSubnetIds: []*nfvcommon.Identifier{k8stest.ID("uid-sub1")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &network.NatGateway{
+		NatGatewayId:  k8stest.ID("uid-gw"),
+		Name:          "gw",
+		NetworkId:     k8stest.ID("uid-net1"),
+		SubnetId:      k8stest.ID("uid-sub1"),
+		LanIpAddress:  "10.0.0.254",
+		SnatSubnetIds: []*nfvcommon.Identifier{k8stest.ID("uid-sub1")},
+	}, gw)
+
+	natGw := &kubeovnv1.VpcNatGateway{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "gw"}, natGw))
+	assert.Equal(t, kubeovnv1.VpcNatGatewaySpec{Vpc: "net1", Subnet: "sub1", LanIP: "10.0.0.254", ExternalSubnets: []string{"external"}}, natGw.Spec)
+	eip := &kubeovnv1.IptablesEIP{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "gw"}, eip))
+	assert.Equal(t, kubeovnv1.IptablesEIPSpec{NatGwDp: "gw", ExternalSubnet: "external"}, eip.Spec)
+	snat := &kubeovnv1.IptablesSnatRule{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "gw-sub1"}, snat))
+	assert.Equal(t, kubeovnv1.IptablesSnatRuleSpec{EIP: "gw", InternalCIDR: "10.0.0.0/24"}, snat.Spec)
+	vpc := &kubeovnv1.Vpc{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+	assert.Equal(t, []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "0.0.0.0/0", NextHopIP: "10.0.0.254"}}, vpc.Spec.StaticRoutes)
+
+	_, err = m.CreateNatGateway(ctx, &network.NatGateway{Name: "gw2", NetworkId: k8stest.ID("uid-net1"), SubnetId: k8stest.ID("uid-sub1"), LanIpAddress: "10.0.0.253"})
+	var precondition *apperrors.ErrFailedPrecondition
+	assert.ErrorAs(t, err, &precondition, "one gateway per network")
+
+	fip, err := m.CreateFloatingIp(ctx, &network.FloatingIp{Name: "web", NatGatewayId: k8stest.ID("uid-gw"), IpAddress: "172.16.0.10", PortIpAddress: "10.0.0.5"})
+	require.NoError(t, err)
+	assert.Equal(t, &network.FloatingIp{
+		FloatingIpId:  k8stest.ID("uid-web"),
+		Name:          "web",
+		NatGatewayId:  k8stest.ID("uid-gw"),
+		IpAddress:     "172.16.0.10",
+		PortIpAddress: "10.0.0.5",
+	}, fip)
+	rule := &kubeovnv1.IptablesFIPRule{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "web"}, rule))
+	assert.Equal(t, kubeovnv1.IptablesFIPRuleSpec{EIP: "web", InternalIP: "10.0.0.5"}, rule.Spec)
+
+	gws, err := m.ListNatGateways(ctx)
+	require.NoError(t, err)
+	assert.Len(t, gws, 1, "floating ip eips are not gateways")
+	net, err := m.GetNetwork(ctx, network.GetNetworkByName("net1"))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5=172.16.0.10", net.GetMetadata().GetFields()[network.NetworkFloatingIpsMetadataKey])
+	net, err = m.UpdateNetwork(ctx, routingData(map[string]string{"tier": "web"}), network.GetNetworkByName("net1"))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5=172.16.0.10", net.GetMetadata().GetFields()[network.NetworkFloatingIpsMetadataKey], "update reports the network as get")
+
+	err = m.DeleteNatGateway(ctx, network.GetNatByUid(k8stest.ID("uid-gw")))
+	assert.ErrorAs(t, err, &precondition, "gateway with floating ips")
+
+	fip, err = m.UpdateFloatingIp(ctx, &network.FloatingIp{FloatingIpId: k8stest.ID("uid-web")})
+	require.NoError(t, err)
+	assert.Empty(t, fip.PortIpAddress)
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Name: "web"}, &kubeovnv1.IptablesFIPRule{})))
+
+	require.NoError(t, m.DeleteFloatingIp(ctx, network.GetNatByUid(k8stest.ID("uid-web"))))
+	require.NoError(t, m.DeleteNatGateway(ctx, network.GetNatByUid(k8stest.ID("uid-gw"))))
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Name: "gw"}, &kubeovnv1.VpcNatGateway{})))
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Name: "gw"}, &kubeovnv1.IptablesEIP{})))
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Name: "gw-sub1"}, &kubeovnv1.IptablesSnatRule{})))
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+	assert.Empty(t, vpc.Spec.StaticRoutes)
+}
+
+func TestOvnNat(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	m, cl := newNatManager(t, config.Ovn, natSeeds()...)
+
+	gw, err := m.CreateNatGateway(ctx, &network.NatGateway{Name: "gw", NetworkId: k8stest.ID("uid-net1"), SnatSubnetIds: []*nfvcommon.Identifier{k8stest.ID("uid-sub1")}})
+	require.NoError(t, err)
+	assert.Equal(t, k8stest.ID("uid-gw"), gw.NatGatewayId)
+	vpc := &kubeovnv1.Vpc{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+	assert.True(t, vpc.Spec.EnableExternal)
+	eip := &kubeovnv1.OvnEip{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "gw"}, eip))
+	assert.Equal(t, kubeovnv1.OvnEipSpec{ExternalSubnet: "external", Type: "nat"}, eip.Spec)
+	snat := &kubeovnv1.OvnSnatRule{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "gw-sub1"}, snat))
+	assert.Equal(t, kubeovnv1.OvnSnatRuleSpec{OvnEip: "gw", VpcSubnet: "sub1"}, snat.Spec)
+
+	_, err = m.CreateFloatingIp(ctx, &network.FloatingIp{Name: "web", NatGatewayId: k8stest.ID("uid-gw"), PortIpAddress: "10.0.0.5"})
+	require.NoError(t, err)
+	ovnFip := &kubeovnv1.OvnFip{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "web"}, ovnFip))
+	assert.Equal(t, kubeovnv1.OvnFipSpec{OvnEip: "web", Vpc: "net1", V4Ip: "10.0.0.5"}, ovnFip.Spec)
+
+	fip, err := m.UpdateFloatingIp(ctx, &network.FloatingIp{FloatingIpId: k8stest.ID("uid-web"), PortIpAddress: "10.0.0.6"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.6", fip.PortIpAddress)
+	fips, err := m.ListFloatingIps(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*network.FloatingIp{fip}, fips)
+
+	err = m.DeleteNetwork(ctx, network.GetNetworkByName("net1"))
+	var precondition *apperrors.ErrFailedPrecondition
+	assert.ErrorAs(t, err, &precondition, "network connected by a gateway")
+
+	require.NoError(t, m.DeleteFloatingIp(ctx, network.GetNatByName("web")))
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Name: "web"}, &kubeovnv1.OvnFip{})))
+	require.NoError(t, m.DeleteNatGateway(ctx, network.GetNatByName("gw")))
+	require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+	assert.False(t, vpc.Spec.EnableExternal)
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKey{Name: "gw"}, &kubeovnv1.OvnEip{})))
+}
+
+func TestNatInvalidRequests(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("iptables gateways", func(t *testing.T) {
+		m, cl := newNatManager(t, config.Iptables, natSeeds()...)
+		for name, gw := range map[string]*network.NatGateway{
+			"no name":              {NetworkId: k8stest.ID("uid-net1"), SubnetId: k8stest.ID("uid-sub1"), LanIpAddress: "10.0.0.254"},
+			"no network":           {Name: "gw", SubnetId: k8stest.ID("uid-sub1"), LanIpAddress: "10.0.0.254"},
+			"no lan subnet":        {Name: "gw", NetworkId: k8stest.ID("uid-net1"), LanIpAddress: "10.0.0.254"},
+			"lan subnet elsewhere": {Name: "gw", NetworkId: k8stest.ID("uid-net1"), SubnetId: k8stest.ID("uid-sub2"), LanIpAddress: "10.0.0.254"},
+			"lan ip not in cidr":   {Name: "gw", NetworkId: k8stest.ID("uid-net1"), SubnetId: k8stest.ID("uid-sub1"), LanIpAddress: "10.1.0.254"},
+			"snat subnet elsewhere": {Name: "gw", NetworkId: k8stest.ID("uid-net1"), SubnetId: k8stest.ID("uid-sub1"), LanIpAddress: "10.0.0.254",
+				SnatSubnetIds: []*nfvcommon.Identifier{k8stest.ID("uid-sub2")}},
+		} {
+			_, err := m.CreateNatGateway(ctx, gw)
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+		vpc := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+		assert.Empty(t, vpc.Spec.StaticRoutes, "rejected requests change nothing")
+	})
+
+	t.Run("ovn gateways take no lan attachment", func(t *testing.T) {
+		m, _ := newNatManager(t, config.Ovn, natSeeds()...)
+		_, err := m.CreateNatGateway(ctx, &network.NatGateway{Name: "gw", NetworkId: k8stest.ID("uid-net1"), LanIpAddress: "10.0.0.254"})
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+
+	t.Run("floating ips", func(t *testing.T) {
+		m, _ := newNatManager(t, config.Ovn, natSeeds()...)
+		_, err := m.CreateNatGateway(ctx, &network.NatGateway{Name: "gw", NetworkId: k8stest.ID("uid-net1")})
+		require.NoError(t, err)
+		for name, fip := range map[string]*network.FloatingIp{
+			"no name":             {NatGatewayId: k8stest.ID("uid-gw")},
+			"no gateway":          {Name: "web"},
+			"ipv6 address":        {Name: "web", NatGatewayId: k8stest.ID("uid-gw"), IpAddress: "fd00::10"},
+			"port not in network": {Name: "web", NatGatewayId: k8stest.ID("uid-gw"), PortIpAddress: "10.1.0.5"},
+			"port not an address": {Name: "web", NatGatewayId: k8stest.ID("uid-gw"), PortIpAddress: "web"},
+		} {
+			_, err := m.CreateFloatingIp(ctx, fip)
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+}
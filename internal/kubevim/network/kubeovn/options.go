@@ -0,0 +1,343 @@
+package kubeovn
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+)
+
+const (
+	gatewayTypeDistributed = "distributed"
+	gatewayTypeCentralized = "centralized"
+
+	// The kube-ovn defaults for the DHCP options it generates itself. The
+	// options of a subnet replace them as a whole, so they are repeated here.
+	dhcpLeaseTime = 3600
+	dhcpServerId  = "169.254.0.254"
+
+	minMtu     = 68
+	minIpv6Mtu = 1280
+	maxMtu     = 65535
+)
+
+// hostRoute is a static route pushed to the VMs of a subnet.
+type hostRoute struct {
+	destination netip.Prefix
+	nexthop     netip.Addr
+}
+
+// kubeovnSubnetOptionsFromNfv applies the subnet options carried by the
+// NetworkSubnetData metadata to the kube-ovn subnet: the outgoing NAT, the
+// gateway type and the MTU of the subnet, the DNS servers and host routes
+// announced by DHCP, and the vNIC bandwidth. Without any of the DHCP options the
+// kube-ovn generated DHCP options are kept. kube-ovn has no subnet bandwidth,
+// so it is kept as a subnet annotation applied to the vNICs when they are
+// created.
+func kubeovnSubnetOptionsFromNfv(sub *kubeovnv1.Subnet, prefixes []netip.Prefix, metadata *nfvcommon.Metadata) error {
+	fields := metadata.GetFields()
+
+	if value, ok := fields[network.SubnetBandwidthMetadataKey]; ok {
+		bandwidth, err := parseSubnetBandwidth(value)
+		if err != nil {
+			return err
+		}
+		if sub.Annotations == nil {
+			sub.Annotations = map[string]string{}
+		}
+		sub.Annotations[network.SubnetBandwidthMetadataKey] = strconv.FormatUint(bandwidth, 10)
+	}
+
+	sub.Spec.NatOutgoing = true
+	if value, ok := fields[network.SubnetNatOutgoingMetadataKey]; ok {
+		natOutgoing, err := strconv.ParseBool(value)
+		if err != nil {
+			return &apperrors.ErrInvalidArgument{Field: network.SubnetNatOutgoingMetadataKey, Reason: fmt.Sprintf("must be true or false: %s", value)}
+		}
+		sub.Spec.NatOutgoing = natOutgoing
+	}
+
+	sub.Spec.GatewayType = gatewayTypeDistributed
+	if value, ok := fields[network.SubnetGatewayTypeMetadataKey]; ok {
+		if value != gatewayTypeDistributed && value != gatewayTypeCentralized {
+			return &apperrors.ErrInvalidArgument{
+				Field:  network.SubnetGatewayTypeMetadataKey,
+				Reason: fmt.Sprintf("must be %s or %s: %s", gatewayTypeDistributed, gatewayTypeCentralized, value),
+			}
+		}
+		sub.Spec.GatewayType = value
+	}
+	gatewayNode := strings.Join(network.SplitDualStack(fields[network.SubnetGatewayNodeMetadataKey]), ",")
+	if sub.Spec.GatewayType == gatewayTypeCentralized && gatewayNode == "" {
+		return &apperrors.ErrInvalidArgument{Field: network.SubnetGatewayNodeMetadataKey, Reason: "must be specified for the centralized gateway"}
+	}
+	if sub.Spec.GatewayType == gatewayTypeDistributed && gatewayNode != "" {
+		return &apperrors.ErrInvalidArgument{Field: network.SubnetGatewayNodeMetadataKey, Reason: "applies to the centralized gateway only"}
+	}
+	sub.Spec.GatewayNode = gatewayNode
+
+	var mtu int
+	if value, ok := fields[network.SubnetMtuMetadataKey]; ok {
+		var err error
+		if mtu, err = parseSubnetMtu(value, prefixes); err != nil {
+			return err
+		}
+		sub.Spec.Mtu = uint32(mtu)
+	}
+	dnsServers, err := parseDnsServers(fields[network.SubnetDnsServersMetadataKey], prefixes)
+	if err != nil {
+		return err
+	}
+	routes, err := parseHostRoutes(fields[network.SubnetHostRoutesMetadataKey], prefixes)
+	if err != nil {
+		return err
+	}
+	if !sub.Spec.EnableDHCP {
+		if len(dnsServers) > 0 {
+			return &apperrors.ErrInvalidArgument{Field: network.SubnetDnsServersMetadataKey, Reason: "requires DHCP to be enabled on the subnet"}
+		}
+		if len(routes) > 0 {
+			return &apperrors.ErrInvalidArgument{Field: network.SubnetHostRoutesMetadataKey, Reason: "requires DHCP to be enabled on the subnet"}
+		}
+		return nil
+	}
+	if mtu == 0 && len(dnsServers) == 0 && len(routes) == 0 {
+		return nil
+	}
+
+	serverMac := formatDhcpServerMac(sub.Name)
+	if v4, ok := prefixOfFamily(prefixes, netip.IPv4Unspecified()); ok {
+		router := v4.Masked().Addr().Next()
+		for _, gw := range network.SplitDualStack(sub.Spec.Gateway) {
+			if addr, err := netip.ParseAddr(gw); err == nil && addr.Is4() {
+				router = addr
+			}
+		}
+		options := []string{
+			fmt.Sprintf("lease_time=%d", dhcpLeaseTime),
+			"router=" + router.String(),
+			"server_id=" + dhcpServerId,
+			"server_mac=" + serverMac,
+		}
+		if mtu != 0 {
+			options = append(options, fmt.Sprintf("mtu=%d", mtu))
+		}
+		if servers := dnsServersOfFamily(dnsServers, true); len(servers) > 0 {
+			options = append(options, "dns_server="+formatDhcpOptionList(servers))
+		}
+		if len(routes) > 0 {
+			options = append(options, "classless_static_route="+formatClasslessStaticRoutes(routes, router))
+		}
+		sub.Spec.DHCPv4Options = strings.Join(options, ",")
+	}
+	if servers := dnsServersOfFamily(dnsServers, false); len(servers) > 0 {
+		sub.Spec.DHCPv6Options = strings.Join([]string{
+			"server_id=" + serverMac,
+			"dns_server=" + formatDhcpOptionList(servers),
+		}, ",")
+	}
+	if sub.Spec.EnableIPv6RA && mtu != 0 {
+		sub.Spec.IPv6RAConfigs += fmt.Sprintf(",mtu=%d", mtu)
+	}
+	return nil
+}
+
+// nfvSubnetOptionsFromKubeovn adds the subnet options of the kube-ovn subnet to
+// the NetworkSubnet metadata fields. The default route that accompanies the
+// host routes is not reported.
+func nfvSubnetOptionsFromKubeovn(sub *kubeovnv1.Subnet, fields map[string]string) {
+	if bandwidth, ok := sub.Annotations[network.SubnetBandwidthMetadataKey]; ok {
+		fields[network.SubnetBandwidthMetadataKey] = bandwidth
+	}
+	spec := &sub.Spec
+	fields[network.SubnetNatOutgoingMetadataKey] = strconv.FormatBool(spec.NatOutgoing)
+	if spec.GatewayType != "" {
+		fields[network.SubnetGatewayTypeMetadataKey] = spec.GatewayType
+	}
+	if spec.GatewayNode != "" {
+		fields[network.SubnetGatewayNodeMetadataKey] = spec.GatewayNode
+	}
+	if spec.Mtu != 0 {
+		fields[network.SubnetMtuMetadataKey] = strconv.FormatUint(uint64(spec.Mtu), 10)
+	}
+	v4Options := parseDhcpOptions(spec.DHCPv4Options)
+	var dnsServers []string
+	for _, options := range []map[string]string{v4Options, parseDhcpOptions(spec.DHCPv6Options)} {
+		if servers, ok := options["dns_server"]; ok {
+			dnsServers = append(dnsServers, parseDhcpOptionList(servers)...)
+		}
+	}
+	if len(dnsServers) > 0 {
+		fields[network.SubnetDnsServersMetadataKey] = strings.Join(dnsServers, ",")
+	}
+	if value, ok := v4Options["classless_static_route"]; ok {
+		list := parseDhcpOptionList(value)
+		routes := make([]string, 0, len(list)/2)
+		for i := 0; i+1 < len(list); i += 2 {
+			if list[i] == "0.0.0.0/0" && list[i+1] == v4Options["router"] {
+				continue
+			}
+			routes = append(routes, list[i]+"="+list[i+1])
+		}
+		if len(routes) > 0 {
+			fields[network.SubnetHostRoutesMetadataKey] = strings.Join(routes, ",")
+		}
+	}
+}
+
+// parseSubnetMtu parses the MTU of the subnet. The MTU of a subnet with an IPv6
+// cidr cannot be lower than the IPv6 minimum.
+func parseSubnetMtu(value string, prefixes []netip.Prefix) (int, error) {
+	mtu, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, &apperrors.ErrInvalidArgument{Field: network.SubnetMtuMetadataKey, Reason: fmt.Sprintf("must be a number: %s", value)}
+	}
+	minimum := minMtu
+	if _, ok := prefixOfFamily(prefixes, netip.IPv6Unspecified()); ok {
+		minimum = minIpv6Mtu
+	}
+	if mtu < minimum || mtu > maxMtu {
+		return 0, &apperrors.ErrInvalidArgument{Field: network.SubnetMtuMetadataKey, Reason: fmt.Sprintf("must be within %d-%d: %d", minimum, maxMtu, mtu)}
+	}
+	return mtu, nil
+}
+
+// parseSubnetBandwidth parses the vNIC bandwidth of the subnet. kube-ovn rates
+// are whole Mbps.
+func parseSubnetBandwidth(value string) (uint64, error) {
+	bandwidth, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	if err != nil || bandwidth == 0 {
+		return 0, &apperrors.ErrInvalidArgument{Field: network.SubnetBandwidthMetadataKey, Reason: fmt.Sprintf("must be a positive whole number of Mbps: %s", value)}
+	}
+	return bandwidth, nil
+}
+
+// parseDnsServers parses the comma-separated DNS servers. Each server must be
+// of a family of the subnet, since it is announced by the DHCP of that family.
+func parseDnsServers(value string, prefixes []netip.Prefix) ([]netip.Addr, error) {
+	var servers []netip.Addr
+	for _, part := range network.SplitDualStack(value) {
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.SubnetDnsServersMetadataKey, Reason: fmt.Sprintf("invalid ip address: %s", part)}
+		}
+		addr = addr.Unmap()
+		if _, ok := prefixOfFamily(prefixes, addr); !ok {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.SubnetDnsServersMetadataKey, Reason: fmt.Sprintf("%s is not of an address family of cidr %s", addr, joinPrefixes(prefixes))}
+		}
+		servers = append(servers, addr)
+	}
+	return servers, nil
+}
+
+// parseHostRoutes parses the comma-separated <destination>=<nexthop> routes.
+// Routes are announced by DHCPv4 only, so both must be IPv4 and the nexthop must
+// be within the IPv4 cidr of the subnet.
+func parseHostRoutes(value string, prefixes []netip.Prefix) ([]hostRoute, error) {
+	var routes []hostRoute
+	for _, part := range network.SplitDualStack(value) {
+		dst, via, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.SubnetHostRoutesMetadataKey, Reason: fmt.Sprintf("must be <destination>=<nexthop>: %s", part)}
+		}
+		destination, err := netip.ParsePrefix(strings.TrimSpace(dst))
+		if err != nil || !destination.Addr().Is4() {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.SubnetHostRoutesMetadataKey, Reason: fmt.Sprintf("destination must be an IPv4 cidr: %s", dst)}
+		}
+		nexthop, err := netip.ParseAddr(strings.TrimSpace(via))
+		if err != nil || !nexthop.Is4() {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.SubnetHostRoutesMetadataKey, Reason: fmt.Sprintf("nexthop must be an IPv4 address: %s", via)}
+		}
+		if v4, ok := prefixOfFamily(prefixes, nexthop); !ok || !v4.Contains(nexthop) {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.SubnetHostRoutesMetadataKey, Reason: fmt.Sprintf("nexthop %s is not within cidr %s", nexthop, joinPrefixes(prefixes))}
+		}
+		routes = append(routes, hostRoute{destination: destination.Masked(), nexthop: nexthop})
+	}
+	return routes, nil
+}
+
+func dnsServersOfFamily(servers []netip.Addr, is4 bool) []string {
+	var res []string
+	for _, server := range servers {
+		if server.Is4() == is4 {
+			res = append(res, server.String())
+		}
+	}
+	return res
+}
+
+// formatClasslessStaticRoutes returns the OVN classless_static_route option of
+// the routes. DHCP clients ignore the router option when classless routes are
+// announced (RFC 3442), so the default route via the router is added unless the
+// routes have one.
+func formatClasslessStaticRoutes(routes []hostRoute, router netip.Addr) string {
+	list := make([]string, 0, 2*len(routes)+2)
+	hasDefault := false
+	for _, route := range routes {
+		hasDefault = hasDefault || route.destination.Bits() == 0
+		list = append(list, route.destination.String(), route.nexthop.String())
+	}
+	if !hasDefault {
+		list = append(list, "0.0.0.0/0", router.String())
+	}
+	return "{" + strings.Join(list, ",") + "}"
+}
+
+// formatDhcpOptionList returns the OVN notation of a DHCP option value list.
+func formatDhcpOptionList(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return "{" + strings.Join(values, ",") + "}"
+}
+
+// parseDhcpOptionList splits the OVN DHCP option value list.
+func parseDhcpOptionList(value string) []string {
+	return network.SplitDualStack(strings.Trim(strings.TrimSpace(value), "{}"))
+}
+
+// parseDhcpOptions parses the kube-ovn DHCP options. The comma-separated values
+// of the {} lists do not separate options.
+func parseDhcpOptions(options string) map[string]string {
+	res := map[string]string{}
+	depth, start := 0, 0
+	for i := 0; i <= len(options); i++ {
+		if i < len(options) {
+			switch options[i] {
+			case '{':
+				depth++
+				continue
+			case '}':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if key, value, ok := strings.Cut(options[start:i], "="); ok {
+			res[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		start = i + 1
+	}
+	return res
+}
+
+// formatDhcpServerMac returns the locally administered MAC address of the DHCP
+// server of the subnet. It is derived from the subnet name so that the options
+// are stable across conversions.
+func formatDhcpServerMac(subnetName string) string {
+	sum := sha256.Sum256([]byte(subnetName))
+	mac := net.HardwareAddr{0x0a, sum[0], sum[1], sum[2], sum[3], sum[4]}
+	return mac.String()
+}
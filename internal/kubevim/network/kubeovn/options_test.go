@@ -0,0 +1,143 @@
+package kubeovn
+
+import (
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func subnetDataWithOptions(cidr string, fields map[string]string) *vivnfm.NetworkSubnetData {
+	return &vivnfm.NetworkSubnetData{
+		Cidr:     &nfvcommon.IPSubnetCIDR{Cidr: cidr},
+		Metadata: &nfvcommon.Metadata{Fields: fields},
+	}
+}
+
+func TestKubeovnSubnetOptions(t *testing.T) {
+	t.Run("defaults keep the kube-ovn DHCP options", func(t *testing.T) {
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", subnetDataWithOptions("10.0.0.0/24", nil))
+		require.NoError(t, err)
+		assert.True(t, sub.Spec.NatOutgoing)
+		assert.Equal(t, "distributed", sub.Spec.GatewayType)
+		assert.Empty(t, sub.Spec.DHCPv4Options)
+		assert.Empty(t, sub.Spec.DHCPv6Options)
+		assert.Zero(t, sub.Spec.Mtu)
+
+		sub.ObjectMeta = managedMeta("sub1")
+		got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+		require.NoError(t, err)
+		assert.Equal(t, "true", got.Metadata.GetFields()[network.SubnetNatOutgoingMetadataKey])
+		assert.Equal(t, "distributed", got.Metadata.GetFields()[network.SubnetGatewayTypeMetadataKey])
+		assert.NotContains(t, got.Metadata.GetFields(), network.SubnetDnsServersMetadataKey)
+	})
+
+	t.Run("DNS servers, host routes and MTU are pushed by DHCP", func(t *testing.T) {
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", subnetDataWithOptions("10.0.0.0/24", map[string]string{
+			network.SubnetDnsServersMetadataKey:  "10.0.0.53, 8.8.8.8",
+			network.SubnetHostRoutesMetadataKey:  "192.168.0.0/16=10.0.0.254,172.16.1.1/24=10.0.0.253",
+			network.SubnetMtuMetadataKey:         "1400",
+			network.SubnetNatOutgoingMetadataKey: "false",
+		}))
+		require.NoError(t, err)
+		mac := formatDhcpServerMac("sub1")
+		assert.Equal(t, "lease_time=3600,router=10.0.0.1,server_id=169.254.0.254,server_mac="+mac+
+			",mtu=1400,dns_server={10.0.0.53,8.8.8.8}"+
+			",classless_static_route={192.168.0.0/16,10.0.0.254,172.16.1.0/24,10.0.0.253,0.0.0.0/0,10.0.0.1}", sub.Spec.DHCPv4Options)
+		assert.Equal(t, uint32(1400), sub.Spec.Mtu)
+		assert.False(t, sub.Spec.NatOutgoing)
+
+		sub.ObjectMeta = managedMeta("sub1")
+		got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+		require.NoError(t, err)
+		fields := got.Metadata.GetFields()
+		assert.Equal(t, "10.0.0.53,8.8.8.8", fields[network.SubnetDnsServersMetadataKey])
+		assert.Equal(t, "192.168.0.0/16=10.0.0.254,172.16.1.0/24=10.0.0.253", fields[network.SubnetHostRoutesMetadataKey], "the added default route is not reported")
+		assert.Equal(t, "1400", fields[network.SubnetMtuMetadataKey])
+		assert.Equal(t, "false", fields[network.SubnetNatOutgoingMetadataKey])
+	})
+
+	t.Run("dual-stack DNS servers use the DHCP of their family", func(t *testing.T) {
+		data := subnetDataWithOptions("10.0.0.0/24,fd00::/64", map[string]string{
+			network.SubnetDnsServersMetadataKey:  "fd00::53,10.0.0.53",
+			network.SubnetHostRoutesMetadataKey:  "0.0.0.0/0=10.0.0.254",
+			network.SubnetMtuMetadataKey:         "1400",
+			network.SubnetGatewayTypeMetadataKey: "centralized",
+			network.SubnetGatewayNodeMetadataKey: "node1, node2",
+		})
+		data.GatewayIp = &nfvcommon.IPAddress{Ip: "10.0.0.10,fd00::1"}
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", data)
+		require.NoError(t, err)
+		assert.Contains(t, sub.Spec.DHCPv4Options, "router=10.0.0.10")
+		assert.Contains(t, sub.Spec.DHCPv4Options, "dns_server=10.0.0.53,")
+		assert.Contains(t, sub.Spec.DHCPv4Options, "classless_static_route={0.0.0.0/0,10.0.0.254}", "the routes have a default route")
+		assert.Equal(t, "server_id="+formatDhcpServerMac("sub1")+",dns_server=fd00::53", sub.Spec.DHCPv6Options)
+		assert.Contains(t, sub.Spec.IPv6RAConfigs, ",mtu=1400")
+		assert.Equal(t, "centralized", sub.Spec.GatewayType)
+		assert.Equal(t, "node1,node2", sub.Spec.GatewayNode)
+
+		sub.ObjectMeta = managedMeta("sub1")
+		got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+		require.NoError(t, err)
+		fields := got.Metadata.GetFields()
+		assert.Equal(t, "10.0.0.53,fd00::53", fields[network.SubnetDnsServersMetadataKey])
+		assert.Equal(t, "0.0.0.0/0=10.0.0.254", fields[network.SubnetHostRoutesMetadataKey])
+		assert.Equal(t, "node1,node2", fields[network.SubnetGatewayNodeMetadataKey])
+	})
+
+	t.Run("bandwidth is kept as an annotation", func(t *testing.T) {
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", subnetDataWithOptions("10.0.0.0/24", map[string]string{
+			network.SubnetBandwidthMetadataKey: " 100",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "100", sub.Annotations[network.SubnetBandwidthMetadataKey])
+		assert.Empty(t, sub.Spec.DHCPv4Options)
+
+		sub.ObjectMeta = managedMeta("sub1")
+		sub.Annotations = map[string]string{network.SubnetBandwidthMetadataKey: "100"}
+		got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+		require.NoError(t, err)
+		assert.Equal(t, "100", got.Metadata.GetFields()[network.SubnetBandwidthMetadataKey])
+	})
+
+	t.Run("invalid options are rejected", func(t *testing.T) {
+		dhcp := false
+		noDhcp := subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetDnsServersMetadataKey: "10.0.0.53"})
+		noDhcp.IsDhcpEnabled = &dhcp
+		for name, data := range map[string]*vivnfm.NetworkSubnetData{
+			"nat outgoing":              subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetNatOutgoingMetadataKey: "yes please"}),
+			"gateway type":              subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetGatewayTypeMetadataKey: "shared"}),
+			"centralized without nodes": subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetGatewayTypeMetadataKey: "centralized"}),
+			"distributed with nodes":    subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetGatewayNodeMetadataKey: "node1"}),
+			"mtu not a number":          subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetMtuMetadataKey: "jumbo"}),
+			"mtu too low for ipv6":      subnetDataWithOptions("fd00::/64", map[string]string{network.SubnetMtuMetadataKey: "1200"}),
+			"dns server of no family":   subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetDnsServersMetadataKey: "fd00::53"}),
+			"dns server without dhcp":   noDhcp,
+			"route without nexthop":     subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetHostRoutesMetadataKey: "192.168.0.0/16"}),
+			"ipv6 route":                subnetDataWithOptions("10.0.0.0/24,fd00::/64", map[string]string{network.SubnetHostRoutesMetadataKey: "fd01::/64=fd00::1"}),
+			"nexthop outside the cidr":  subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetHostRoutesMetadataKey: "192.168.0.0/16=10.1.0.1"}),
+			"zero bandwidth":            subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetBandwidthMetadataKey: "0"}),
+			"fractional bandwidth":      subnetDataWithOptions("10.0.0.0/24", map[string]string{network.SubnetBandwidthMetadataKey: "2.5"}),
+		} {
+			_, err := kubeovnSubnetFromNfvSubnetData("sub1", data)
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+}
+
+func TestParseDhcpOptions(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"lease_time":             "3600",
+		"dns_server":             "{8.8.8.8, 1.1.1.1}",
+		"classless_static_route": "{10.1.0.0/16,10.0.0.254}",
+		"mtu":                    "1400",
+	}, parseDhcpOptions("lease_time=3600, dns_server={8.8.8.8, 1.1.1.1},classless_static_route={10.1.0.0/16,10.0.0.254},mtu=1400"))
+	assert.Empty(t, parseDhcpOptions(""))
+	assert.Equal(t, []string{"8.8.8.8", "1.1.1.1"}, parseDhcpOptionList("{8.8.8.8, 1.1.1.1}"))
+	assert.Equal(t, []string{"8.8.8.8"}, parseDhcpOptionList("8.8.8.8"))
+}
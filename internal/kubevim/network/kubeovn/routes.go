@@ -0,0 +1,728 @@
+package kubeovn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	maxPolicyRoutePriority = 32767
+)
+
+// peeringLinkPool is the link-local range the /30 links connecting peered vpc
+// routers are allocated from. The vpc that requested the peering takes the first
+// address of the link, its peer the second.
+var peeringLinkPool = netip.MustParsePrefix("169.254.64.0/18")
+
+// vpcStaticRoute routes the traffic to the destination cidr to the nexthop.
+type vpcStaticRoute struct {
+	destination netip.Prefix
+	nexthop     netip.Addr
+}
+
+func (r vpcStaticRoute) String() string {
+	return r.destination.String() + "=" + r.nexthop.String()
+}
+
+func (r vpcStaticRoute) kubeovn() *kubeovnv1.StaticRoute {
+	return &kubeovnv1.StaticRoute{Policy: kubeovnv1.PolicyDst, CIDR: r.destination.String(), NextHopIP: r.nexthop.String()}
+}
+
+// vpcPolicyRoute reroutes the traffic from the source cidr, and to the
+// destination cidr when it is valid, to the nexthop.
+type vpcPolicyRoute struct {
+	priority    int
+	source      netip.Prefix
+	destination netip.Prefix
+	nexthop     netip.Addr
+}
+
+func (r vpcPolicyRoute) String() string {
+	res := strconv.Itoa(r.priority) + ":" + r.source.String()
+	if r.destination.IsValid() {
+		res += ">" + r.destination.String()
+	}
+	return res + "=" + r.nexthop.String()
+}
+
+func (r vpcPolicyRoute) kubeovn() *kubeovnv1.PolicyRoute {
+	family := "ip4"
+	if r.source.Addr().Is6() {
+		family = "ip6"
+	}
+	match := fmt.Sprintf("%s.src == %s", family, r.source)
+	if r.destination.IsValid() {
+		match += fmt.Sprintf(" && %s.dst == %s", family, r.destination)
+	}
+	return &kubeovnv1.PolicyRoute{
+		Priority:  r.priority,
+		Match:     match,
+		Action:    kubeovnv1.PolicyRouteActionReroute,
+		NextHopIP: r.nexthop.String(),
+	}
+}
+
+// checkNoVpcRouting rejects the routing metadata keys for the networks that are
+// not backed by a vpc router.
+func checkNoVpcRouting(metadata *nfvcommon.Metadata) error {
+	for _, key := range []string{network.NetworkStaticRoutesMetadataKey, network.NetworkPolicyRoutesMetadataKey, network.NetworkPeeringsMetadataKey} {
+		if _, ok := metadata.GetFields()[key]; ok {
+			return &apperrors.ErrInvalidArgument{Field: key, Reason: "applies to overlay networks only"}
+		}
+	}
+	return nil
+}
+
+// patchVpcRouting merges the network metadata into the vpc annotations and
+// applies the routes and peerings it carries to the vpc and to its peers. When
+// a peer cannot be patched, the vpc and the peers already patched are rolled
+// back.
+func (m *manager) patchVpcRouting(ctx context.Context, vpc *kubeovnv1.Vpc, metadata *nfvcommon.Metadata) (*kubeovnv1.Vpc, error) {
+	m.peeringMu.Lock()
+	defer m.peeringMu.Unlock()
+	var base *kubeovnv1.Vpc
+	var state *peeringState
+	var added, removed []string
+	key := client.ObjectKeyFromObject(vpc)
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// The vpc is read again on a conflict, with the peers.
+		vpc = &kubeovnv1.Vpc{}
+		if err := m.apiReader.Get(ctx, key, vpc); err != nil {
+			return fmt.Errorf("get kubeovn vpc '%s': %w", key.Name, err)
+		}
+		base = vpc.DeepCopy()
+		vpc.Annotations = mergeMetadataAnnotations(vpc.Annotations, metadata)
+		if err := applyVpcStaticRoutes(vpc, base.Annotations, metadata); err != nil {
+			return err
+		}
+		if err := applyVpcPolicyRoutes(vpc, base.Annotations, metadata); err != nil {
+			return err
+		}
+		// The peerings are reported from the vpc spec, so that both peers report
+		// them.
+		delete(vpc.Annotations, network.NetworkPeeringsMetadataKey)
+		if value, ok := metadata.GetFields()[network.NetworkPeeringsMetadataKey]; ok {
+			var err error
+			if state, err = m.getPeeringState(ctx); err != nil {
+				return err
+			}
+			if added, removed, err = state.applyPeerings(vpc, value); err != nil {
+				return err
+			}
+		}
+		if err := m.client.Patch(ctx, vpc, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+			return fmt.Errorf("patch kubeovn vpc '%s' (id: %s): %w", vpc.Name, vpc.GetUID(), err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return vpc, nil
+	}
+	patched := vpc.DeepCopy()
+	var unpeered, peered []string
+	rollback := func(err error) (*kubeovnv1.Vpc, error) {
+		if rbErr := m.rollbackVpcRouting(ctx, base, patched, state, unpeered, peered); rbErr != nil {
+			m.logger.Error("Failed to roll back the routing of kubeovn vpc", zap.String("vpc", vpc.Name), zap.Error(rbErr))
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w (network '%s' rolled back)", err, vpc.Name)
+	}
+	for _, peer := range removed {
+		if err := m.patchVpc(ctx, peer, func(peerVpc *kubeovnv1.Vpc) error {
+			removeVpcPeering(peerVpc, vpc.Name)
+			return nil
+		}); err != nil {
+			return rollback(fmt.Errorf("remove peering from network '%s': %w", peer, err))
+		}
+		unpeered = append(unpeered, peer)
+	}
+	for _, peer := range added {
+		if err := m.addVpcPeering(ctx, peer, patched); err != nil {
+			return rollback(fmt.Errorf("add peering to network '%s': %w", peer, err))
+		}
+		peered = append(peered, peer)
+	}
+	if err := m.syncPeeringRoutes(ctx, state, append([]string{vpc.Name}, added...)...); err != nil {
+		return rollback(err)
+	}
+	if err := m.client.Get(ctx, client.ObjectKeyFromObject(vpc), vpc); err != nil {
+		return nil, fmt.Errorf("get kubeovn vpc '%s': %w", vpc.Name, err)
+	}
+	return vpc, nil
+}
+
+// addVpcPeering peers the peer vpc back with the vpc, over the other end of
+// the link of the vpc peering.
+func (m *manager) addVpcPeering(ctx context.Context, peer string, vpc *kubeovnv1.Vpc) error {
+	idx := slices.IndexFunc(vpc.Spec.VpcPeerings, func(p *kubeovnv1.VpcPeering) bool { return p.RemoteVpc == peer })
+	link := netip.MustParsePrefix(vpc.Spec.VpcPeerings[idx].LocalConnectIP)
+	return m.patchVpc(ctx, peer, func(peerVpc *kubeovnv1.Vpc) error {
+		removeVpcPeering(peerVpc, vpc.Name)
+		peerVpc.Spec.VpcPeerings = append(peerVpc.Spec.VpcPeerings, &kubeovnv1.VpcPeering{
+			RemoteVpc:      vpc.Name,
+			LocalConnectIP: netip.PrefixFrom(peeringLinkRemote(link), link.Bits()).String(),
+		})
+		return nil
+	})
+}
+
+// rollbackVpcRouting restores the routing of the vpc patched by patchVpcRouting
+// from base, and the peerings of the peers that were unpeered or peered. The
+// static routes added to the vpc since, other than the peering routes, are
+// kept.
+func (m *manager) rollbackVpcRouting(ctx context.Context, base, patched *kubeovnv1.Vpc, state *peeringState, unpeered, peered []string) error {
+	ctx = context.WithoutCancel(ctx)
+	var errs []error
+	for _, peer := range peered {
+		if err := m.patchVpc(ctx, peer, func(peerVpc *kubeovnv1.Vpc) error {
+			removeVpcPeering(peerVpc, base.Name)
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("remove peering from network '%s': %w", peer, err))
+		}
+	}
+	if err := m.patchVpc(ctx, base.Name, func(vpc *kubeovnv1.Vpc) error {
+		routes := slices.Clone(base.Spec.StaticRoutes)
+		for _, route := range vpc.Spec.StaticRoutes {
+			isRoute := func(r *kubeovnv1.StaticRoute) bool { return equality.Semantic.DeepEqual(r, route) }
+			if nexthop, err := netip.ParseAddr(route.NextHopIP); err == nil && peeringLinkPool.Contains(nexthop) {
+				continue
+			}
+			if !slices.ContainsFunc(base.Spec.StaticRoutes, isRoute) && !slices.ContainsFunc(patched.Spec.StaticRoutes, isRoute) {
+				routes = append(routes, route)
+			}
+		}
+		vpc.Annotations = base.Annotations
+		vpc.Spec.StaticRoutes = routes
+		vpc.Spec.PolicyRoutes = base.Spec.PolicyRoutes
+		vpc.Spec.VpcPeerings = base.Spec.VpcPeerings
+		return nil
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	for _, peer := range unpeered {
+		if err := m.addVpcPeering(ctx, peer, base); err != nil {
+			errs = append(errs, fmt.Errorf("add peering to network '%s': %w", peer, err))
+		}
+	}
+	if len(unpeered) > 0 {
+		errs = append(errs, m.syncPeeringRoutes(ctx, state, unpeered...))
+	}
+	return errors.Join(errs...)
+}
+
+// applyVpcStaticRoutes replaces the static routes the previous metadata of the
+// vpc requested with the routes of the metadata. The other static routes of
+// the vpc, held by NAT gateways or peerings, are kept, and a requested route
+// cannot take their destination.
+func applyVpcStaticRoutes(vpc *kubeovnv1.Vpc, annotations map[string]string, metadata *nfvcommon.Metadata) error {
+	value, ok := metadata.GetFields()[network.NetworkStaticRoutesMetadataKey]
+	if !ok {
+		return nil
+	}
+	routes, err := parseVpcStaticRoutes(value)
+	if err != nil {
+		return err
+	}
+	// The previous routes were validated when requested.
+	previous, _ := parseVpcStaticRoutes(annotations[network.NetworkStaticRoutesMetadataKey])
+	vpc.Spec.StaticRoutes = slices.DeleteFunc(vpc.Spec.StaticRoutes, func(r *kubeovnv1.StaticRoute) bool {
+		return slices.ContainsFunc(previous, func(p vpcStaticRoute) bool { return equality.Semantic.DeepEqual(r, p.kubeovn()) })
+	})
+	for _, route := range routes {
+		for _, held := range vpc.Spec.StaticRoutes {
+			if prefix, err := netip.ParsePrefix(held.CIDR); err == nil && prefix.Masked() == route.destination {
+				return &apperrors.ErrFailedPrecondition{
+					Resource: fmt.Sprintf("network '%s'", vpc.Name),
+					Reason:   fmt.Sprintf("the route to %s via %s is held by a NAT gateway or a peering", held.CIDR, held.NextHopIP),
+				}
+			}
+		}
+	}
+	formatted := make([]string, 0, len(routes))
+	for _, route := range routes {
+		vpc.Spec.StaticRoutes = append(vpc.Spec.StaticRoutes, route.kubeovn())
+		formatted = append(formatted, route.String())
+	}
+	setRoutingAnnotation(vpc, network.NetworkStaticRoutesMetadataKey, formatted)
+	return nil
+}
+
+// applyVpcPolicyRoutes replaces the policy routes the previous metadata of the
+// vpc requested with the routes of the metadata, keeping the others.
+func applyVpcPolicyRoutes(vpc *kubeovnv1.Vpc, annotations map[string]string, metadata *nfvcommon.Metadata) error {
+	value, ok := metadata.GetFields()[network.NetworkPolicyRoutesMetadataKey]
+	if !ok {
+		return nil
+	}
+	routes, err := parseVpcPolicyRoutes(value)
+	if err != nil {
+		return err
+	}
+	previous, _ := parseVpcPolicyRoutes(annotations[network.NetworkPolicyRoutesMetadataKey])
+	vpc.Spec.PolicyRoutes = slices.DeleteFunc(vpc.Spec.PolicyRoutes, func(r *kubeovnv1.PolicyRoute) bool {
+		return slices.ContainsFunc(previous, func(p vpcPolicyRoute) bool { return equality.Semantic.DeepEqual(r, p.kubeovn()) })
+	})
+	formatted := make([]string, 0, len(routes))
+	for _, route := range routes {
+		policy := route.kubeovn()
+		if slices.ContainsFunc(vpc.Spec.PolicyRoutes, func(r *kubeovnv1.PolicyRoute) bool { return r.Priority == policy.Priority && r.Match == policy.Match }) {
+			return &apperrors.ErrFailedPrecondition{
+				Resource: fmt.Sprintf("network '%s'", vpc.Name),
+				Reason:   fmt.Sprintf("already has a policy route with priority %d matching '%s'", policy.Priority, policy.Match),
+			}
+		}
+		vpc.Spec.PolicyRoutes = append(vpc.Spec.PolicyRoutes, policy)
+		formatted = append(formatted, route.String())
+	}
+	setRoutingAnnotation(vpc, network.NetworkPolicyRoutesMetadataKey, formatted)
+	return nil
+}
+
+// setRoutingAnnotation keeps the requested routes in their canonical form, so
+// that the queried network reports them as applied.
+func setRoutingAnnotation(vpc *kubeovnv1.Vpc, key string, routes []string) {
+	if len(routes) == 0 {
+		delete(vpc.Annotations, key)
+		return
+	}
+	if vpc.Annotations == nil {
+		vpc.Annotations = map[string]string{}
+	}
+	vpc.Annotations[key] = strings.Join(routes, ",")
+}
+
+// parseVpcStaticRoutes parses the comma-separated <destination>=<nexthop>
+// routes. The destination and the nexthop must be of the same family, and each
+// destination is routed once.
+func parseVpcStaticRoutes(value string) ([]vpcStaticRoute, error) {
+	var routes []vpcStaticRoute
+	for _, part := range network.SplitDualStack(value) {
+		dst, via, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.NetworkStaticRoutesMetadataKey, Reason: fmt.Sprintf("must be <destination>=<nexthop>: %s", part)}
+		}
+		destination, err := netip.ParsePrefix(strings.TrimSpace(dst))
+		if err != nil {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.NetworkStaticRoutesMetadataKey, Reason: fmt.Sprintf("invalid destination cidr: %s", dst)}
+		}
+		nexthop, err := parseRouteNexthop(network.NetworkStaticRoutesMetadataKey, via, destination)
+		if err != nil {
+			return nil, err
+		}
+		route := vpcStaticRoute{destination: destination.Masked(), nexthop: nexthop}
+		if slices.ContainsFunc(routes, func(r vpcStaticRoute) bool { return r.destination == route.destination }) {
+			return nil, &apperrors.ErrInvalidArgument{Field: network.NetworkStaticRoutesMetadataKey, Reason: fmt.Sprintf("destination %s is routed more than once", route.destination)}
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// parseVpcPolicyRoutes parses the comma-separated
+// <priority>:<source>[><destination>]=<nexthop> routes.
+func parseVpcPolicyRoutes(value string) ([]vpcPolicyRoute, error) {
+	invalid := func(reason string) error {
+		return &apperrors.ErrInvalidArgument{Field: network.NetworkPolicyRoutesMetadataKey, Reason: reason}
+	}
+	var routes []vpcPolicyRoute
+	for _, part := range network.SplitDualStack(value) {
+		prio, rest, ok := strings.Cut(part, ":")
+		match, via, ok2 := strings.Cut(rest, "=")
+		if !ok || !ok2 {
+			return nil, invalid(fmt.Sprintf("must be <priority>:<source>[><destination>]=<nexthop>: %s", part))
+		}
+		priority, err := strconv.Atoi(strings.TrimSpace(prio))
+		if err != nil || priority < 0 || priority > maxPolicyRoutePriority {
+			return nil, invalid(fmt.Sprintf("priority must be a number within 0-%d: %s", maxPolicyRoutePriority, prio))
+		}
+		src, dst, hasDst := strings.Cut(match, ">")
+		source, err := netip.ParsePrefix(strings.TrimSpace(src))
+		if err != nil {
+			return nil, invalid(fmt.Sprintf("invalid source cidr: %s", src))
+		}
+		route := vpcPolicyRoute{priority: priority, source: source.Masked()}
+		if hasDst {
+			destination, err := netip.ParsePrefix(strings.TrimSpace(dst))
+			if err != nil || destination.Addr().Is4() != source.Addr().Is4() {
+				return nil, invalid(fmt.Sprintf("destination must be a cidr of the source family: %s", dst))
+			}
+			route.destination = destination.Masked()
+		}
+		if route.nexthop, err = parseRouteNexthop(network.NetworkPolicyRoutesMetadataKey, via, source); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(routes, func(r vpcPolicyRoute) bool {
+			return r.priority == route.priority && r.source == route.source && r.destination == route.destination
+		}) {
+			return nil, invalid(fmt.Sprintf("traffic of %s is matched more than once with priority %d", part, priority))
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// parseRouteNexthop parses the nexthop of a route to or from the cidr. The
+// peering links are reserved to the peering routes.
+func parseRouteNexthop(field, value string, cidr netip.Prefix) (netip.Addr, error) {
+	nexthop, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil || nexthop.Is4() != cidr.Addr().Is4() {
+		return netip.Addr{}, &apperrors.ErrInvalidArgument{Field: field, Reason: fmt.Sprintf("nexthop must be an address of the %s family: %s", cidr, value)}
+	}
+	if peeringLinkPool.Contains(nexthop) {
+		return netip.Addr{}, &apperrors.ErrInvalidArgument{Field: field, Reason: fmt.Sprintf("nexthop %s is within the peering links range %s", nexthop, peeringLinkPool)}
+	}
+	return nexthop, nil
+}
+
+// peeringState is the view of the kube-vim vpcs the peerings are validated
+// and routed with.
+type peeringState struct {
+	vpcs map[string]*kubeovnv1.Vpc
+	// cidrs holds the IPv4 cidrs of the subnets of each vpc. Peered vpc routers
+	// are connected over IPv4, so only they are routed.
+	cidrs map[string][]netip.Prefix
+}
+
+func (m *manager) getPeeringState(ctx context.Context) (*peeringState, error) {
+	managed := client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}
+	// The links are allocated from the vpcs read uncached, so that they are not
+	// given twice while the cache lags the previous peering.
+	vpcList := &kubeovnv1.VpcList{}
+	if err := m.apiReader.List(ctx, vpcList, managed); err != nil {
+		return nil, fmt.Errorf("list kubeovn vpcs: %w", err)
+	}
+	subnetList := &kubeovnv1.SubnetList{}
+	if err := m.client.List(ctx, subnetList, managed); err != nil {
+		return nil, fmt.Errorf("list kubeovn subnets: %w", err)
+	}
+	res := &peeringState{
+		vpcs:  make(map[string]*kubeovnv1.Vpc, len(vpcList.Items)),
+		cidrs: map[string][]netip.Prefix{},
+	}
+	for idx := range vpcList.Items {
+		res.vpcs[vpcList.Items[idx].Name] = &vpcList.Items[idx]
+	}
+	for idx := range subnetList.Items {
+		subnet := &subnetList.Items[idx]
+		if prefix, ok := subnetPrefix4(subnet); ok && subnet.Spec.Vpc != "" {
+			res.cidrs[subnet.Spec.Vpc] = append(res.cidrs[subnet.Spec.Vpc], prefix.Masked())
+		}
+	}
+	return res, nil
+}
+
+// peers returns the names of the vpcs peered with the vpc.
+func (s *peeringState) peers(vpcName string) []string {
+	vpc, ok := s.vpcs[vpcName]
+	if !ok {
+		return nil
+	}
+	return vpcPeerNames(vpc)
+}
+
+// applyPeerings peers the vpc with the comma-separated names or ids of the
+// networks of the value, and only them. It returns the networks peered and
+// unpeered, whose vpcs are still to be patched.
+func (s *peeringState) applyPeerings(vpc *kubeovnv1.Vpc, value string) (added, removed []string, err error) {
+	var requested []string
+	for _, ref := range network.SplitDualStack(value) {
+		peer, err := s.resolveVpc(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		if peer.Name == vpc.Name {
+			return nil, nil, &apperrors.ErrInvalidArgument{Field: network.NetworkPeeringsMetadataKey, Reason: "a network cannot peer with itself"}
+		}
+		if !slices.Contains(requested, peer.Name) {
+			requested = append(requested, peer.Name)
+		}
+	}
+	for _, peer := range vpcPeerNames(vpc) {
+		if !slices.Contains(requested, peer) {
+			removeVpcPeering(vpc, peer)
+			removed = append(removed, peer)
+		}
+	}
+	current := vpcPeerNames(vpc)
+	for _, peer := range requested {
+		if !slices.Contains(current, peer) {
+			added = append(added, peer)
+		}
+	}
+	if len(added) == 0 {
+		s.vpcs[vpc.Name] = vpc
+		return added, removed, nil
+	}
+	if err := s.checkPeeringCidrs(network.NetworkPeeringsMetadataKey, vpc.Name, requested); err != nil {
+		return nil, nil, err
+	}
+	for _, peer := range added {
+		peerPeers := append(slices.DeleteFunc(s.peers(peer), func(p string) bool { return p == vpc.Name }), vpc.Name)
+		if err := s.checkPeeringCidrs(network.NetworkPeeringsMetadataKey, peer, peerPeers); err != nil {
+			return nil, nil, err
+		}
+		if err := checkStaticRoutesAvoid(vpc, s.cidrs[peer]); err != nil {
+			return nil, nil, err
+		}
+		if err := checkStaticRoutesAvoid(s.vpcs[peer], s.cidrs[vpc.Name]); err != nil {
+			return nil, nil, err
+		}
+		link, err := s.allocateLink(vpc)
+		if err != nil {
+			return nil, nil, err
+		}
+		vpc.Spec.VpcPeerings = append(vpc.Spec.VpcPeerings, &kubeovnv1.VpcPeering{
+			RemoteVpc:      peer,
+			LocalConnectIP: netip.PrefixFrom(link.Addr().Next(), link.Bits()).String(),
+		})
+	}
+	s.vpcs[vpc.Name] = vpc
+	return added, removed, nil
+}
+
+// resolveVpc returns the kube-vim vpc of the network name or id.
+func (s *peeringState) resolveVpc(ref string) (*kubeovnv1.Vpc, error) {
+	if vpc, ok := s.vpcs[ref]; ok {
+		return vpc, nil
+	}
+	for _, vpc := range s.vpcs {
+		if string(vpc.GetUID()) == ref {
+			return vpc, nil
+		}
+	}
+	return nil, &apperrors.ErrInvalidArgument{Field: network.NetworkPeeringsMetadataKey, Reason: fmt.Sprintf("'%s' is not an overlay network", ref)}
+}
+
+// checkPeeringCidrs checks that the vpc router can route to each of the peers:
+// the cidrs of the vpc and of the peers must not overlap.
+func (s *peeringState) checkPeeringCidrs(field, vpcName string, peers []string) error {
+	owners := append([]string{vpcName}, peers...)
+	for i, owner := range owners {
+		for _, other := range owners[i+1:] {
+			for _, prefix := range s.cidrs[owner] {
+				for _, otherPrefix := range s.cidrs[other] {
+					if prefix.Overlaps(otherPrefix) {
+						return &apperrors.ErrInvalidArgument{
+							Field:  field,
+							Reason: fmt.Sprintf("cidr %s of network '%s' overlaps cidr %s of network '%s', routed by network '%s'", prefix, owner, otherPrefix, other, vpcName),
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkStaticRoutesAvoid checks that no static route of the vpc, other than
+// its peering routes, overlaps the cidrs the vpc is to route to a new peer.
+func checkStaticRoutesAvoid(vpc *kubeovnv1.Vpc, cidrs []netip.Prefix) error {
+	for _, route := range vpc.Spec.StaticRoutes {
+		nexthop, err := netip.ParseAddr(route.NextHopIP)
+		if err == nil && peeringLinkPool.Contains(nexthop) {
+			continue
+		}
+		destination, err := netip.ParsePrefix(route.CIDR)
+		if err != nil {
+			continue
+		}
+		for _, prefix := range cidrs {
+			if destination.Overlaps(prefix) && destination.Bits() > 0 {
+				return &apperrors.ErrInvalidArgument{
+					Field:  network.NetworkPeeringsMetadataKey,
+					Reason: fmt.Sprintf("the route of network '%s' to %s via %s overlaps peer cidr %s", vpc.Name, route.CIDR, route.NextHopIP, prefix),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// allocateLink returns the first /30 link of the pool no vpc peering uses. The
+// links given to the vpc are reserved in the state.
+func (s *peeringState) allocateLink(vpc *kubeovnv1.Vpc) (netip.Prefix, error) {
+	used := map[netip.Prefix]bool{}
+	for _, v := range s.vpcs {
+		if v.Name == vpc.Name {
+			continue
+		}
+		for _, peering := range v.Spec.VpcPeerings {
+			if prefix, err := netip.ParsePrefix(peering.LocalConnectIP); err == nil {
+				used[prefix.Masked()] = true
+			}
+		}
+	}
+	for _, peering := range vpc.Spec.VpcPeerings {
+		if prefix, err := netip.ParsePrefix(peering.LocalConnectIP); err == nil {
+			used[prefix.Masked()] = true
+		}
+	}
+	for addr := peeringLinkPool.Addr(); peeringLinkPool.Contains(addr); {
+		link := netip.PrefixFrom(addr, 30)
+		if !used[link] {
+			return link, nil
+		}
+		for range 4 {
+			addr = addr.Next()
+		}
+	}
+	return netip.Prefix{}, &apperrors.ErrFailedPrecondition{Resource: "peering links " + peeringLinkPool.String(), Reason: "exhausted"}
+}
+
+// syncPeeringRoutes routes the cidrs of the peers of each vpc via the peering
+// links, replacing the routes to the previous cidrs of the peers.
+func (m *manager) syncPeeringRoutes(ctx context.Context, state *peeringState, vpcNames ...string) error {
+	for _, vpcName := range vpcNames {
+		if err := m.patchVpc(ctx, vpcName, func(vpc *kubeovnv1.Vpc) error {
+			for _, peering := range vpc.Spec.VpcPeerings {
+				link, err := netip.ParsePrefix(peering.LocalConnectIP)
+				if err != nil {
+					return fmt.Errorf("parse peering link of network '%s' to network '%s': %w", vpc.Name, peering.RemoteVpc, err)
+				}
+				remote := peeringLinkRemote(link).String()
+				vpc.Spec.StaticRoutes = slices.DeleteFunc(vpc.Spec.StaticRoutes, func(r *kubeovnv1.StaticRoute) bool { return r.NextHopIP == remote })
+				for _, prefix := range state.cidrs[peering.RemoteVpc] {
+					vpc.Spec.StaticRoutes = append(vpc.Spec.StaticRoutes, &kubeovnv1.StaticRoute{Policy: kubeovnv1.PolicyDst, CIDR: prefix.String(), NextHopIP: remote})
+				}
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("route peers of network '%s': %w", vpcName, err)
+		}
+	}
+	return nil
+}
+
+// unpeerVpc removes the peerings of the vpc from its peers, before the vpc is
+// deleted.
+func (m *manager) unpeerVpc(ctx context.Context, vpcName string) error {
+	m.peeringMu.Lock()
+	defer m.peeringMu.Unlock()
+	vpc := &kubeovnv1.Vpc{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: vpcName}, vpc); err != nil {
+		return fmt.Errorf("get kubeovn vpc '%s': %w", vpcName, err)
+	}
+	for _, peer := range vpcPeerNames(vpc) {
+		if err := m.patchVpc(ctx, peer, func(peerVpc *kubeovnv1.Vpc) error {
+			removeVpcPeering(peerVpc, vpcName)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("remove peering from network '%s': %w", peer, err)
+		}
+	}
+	return nil
+}
+
+// removeVpcPeering removes the peering with the remote vpc and the routes via
+// its link.
+func removeVpcPeering(vpc *kubeovnv1.Vpc, remoteVpc string) {
+	vpc.Spec.VpcPeerings = slices.DeleteFunc(vpc.Spec.VpcPeerings, func(p *kubeovnv1.VpcPeering) bool {
+		if p.RemoteVpc != remoteVpc {
+			return false
+		}
+		if link, err := netip.ParsePrefix(p.LocalConnectIP); err == nil {
+			remote := peeringLinkRemote(link).String()
+			vpc.Spec.StaticRoutes = slices.DeleteFunc(vpc.Spec.StaticRoutes, func(r *kubeovnv1.StaticRoute) bool { return r.NextHopIP == remote })
+		}
+		return true
+	})
+}
+
+func vpcPeerNames(vpc *kubeovnv1.Vpc) []string {
+	res := make([]string, 0, len(vpc.Spec.VpcPeerings))
+	for _, peering := range vpc.Spec.VpcPeerings {
+		res = append(res, peering.RemoteVpc)
+	}
+	return res
+}
+
+// peeringLinkRemote returns the address of the other end of the /30 link.
+func peeringLinkRemote(link netip.Prefix) netip.Addr {
+	first := link.Masked().Addr().Next()
+	if link.Addr() == first {
+		return first.Next()
+	}
+	return first
+}
+
+// setNetworkPeerings reports the networks peered with the vpc under the
+// peerings metadata key.
+func setNetworkPeerings(fields map[string]string, vpc *kubeovnv1.Vpc) {
+	peers := vpcPeerNames(vpc)
+	if len(peers) == 0 {
+		delete(fields, network.NetworkPeeringsMetadataKey)
+		return
+	}
+	slices.Sort(peers)
+	fields[network.NetworkPeeringsMetadataKey] = strings.Join(peers, ",")
+}
+
+// checkSubnetPeering checks that the peers of the vpc of the new subnet can
+// route to its cidr too.
+func (m *manager) checkSubnetPeering(ctx context.Context, subnet *kubeovnv1.Subnet) error {
+	prefix, ok := subnetPrefix4(subnet)
+	if !ok || !m.vpcHasPeerings(ctx, subnet.Spec.Vpc) {
+		return nil
+	}
+	state, err := m.getPeeringState(ctx)
+	if err != nil {
+		return err
+	}
+	state.cidrs[subnet.Spec.Vpc] = append(state.cidrs[subnet.Spec.Vpc], prefix.Masked())
+	if err := state.checkPeeringCidrs("cidr", subnet.Spec.Vpc, state.peers(subnet.Spec.Vpc)); err != nil {
+		return err
+	}
+	for _, peer := range state.peers(subnet.Spec.Vpc) {
+		if err := state.checkPeeringCidrs("cidr", peer, state.peers(peer)); err != nil {
+			return err
+		}
+		if err := checkStaticRoutesAvoid(state.vpcs[peer], []netip.Prefix{prefix.Masked()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeSubnetsToPeers routes the current cidrs of the vpc from its peers, after
+// a subnet of the vpc was created or deleted.
+func (m *manager) routeSubnetsToPeers(ctx context.Context, vpcName string) error {
+	m.peeringMu.Lock()
+	defer m.peeringMu.Unlock()
+	if !m.vpcHasPeerings(ctx, vpcName) {
+		return nil
+	}
+	state, err := m.getPeeringState(ctx)
+	if err != nil {
+		return err
+	}
+	return m.syncPeeringRoutes(ctx, state, state.peers(vpcName)...)
+}
+
+func (m *manager) vpcHasPeerings(ctx context.Context, vpcName string) bool {
+	if vpcName == "" {
+		return false
+	}
+	vpc := &kubeovnv1.Vpc{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: vpcName}, vpc); err != nil {
+		return false
+	}
+	return len(vpc.Spec.VpcPeerings) > 0
+}
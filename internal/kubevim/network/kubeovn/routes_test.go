@@ -0,0 +1,300 @@
+package kubeovn
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	netattv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func routingData(fields map[string]string) *vivnfm.VirtualNetworkData {
+	return &vivnfm.VirtualNetworkData{Metadata: &nfvcommon.Metadata{Fields: fields}}
+}
+
+// peeringSeeds returns the vpcs net1, net2 and net3 with a subnet each, net3
+// overlapping net1.
+func peeringSeeds() []client.Object {
+	sub2 := seedVpcSubnet("sub2", "net2")
+	sub2.Spec.CIDRBlock = "10.1.0.0/24"
+	return []client.Object{
+		seedVpc("net1", "sub1"),
+		seedVpcSubnet("sub1", "net1"),
+		seedVpc("net2", "sub2"),
+		sub2,
+		seedVpc("net3", "sub3"),
+		seedVpcSubnet("sub3", "net3"),
+	}
+}
+
+// newPatchingManager is newManager with the vpc patches going through patch.
+func newPatchingManager(t *testing.T, patch func(ctx context.Context, cl client.WithWatch, vpc *kubeovnv1.Vpc, p client.Patch) error, objs ...client.Object) (*manager, client.Client) {
+	t.Helper()
+	scheme, err := k8s.BuildScheme()
+	require.NoError(t, err)
+	funcs := k8stest.AssignMetaOnCreate
+	funcs.Patch = func(ctx context.Context, cl client.WithWatch, obj client.Object, p client.Patch, opts ...client.PatchOption) error {
+		if vpc, ok := obj.(*kubeovnv1.Vpc); ok {
+			return patch(ctx, cl, vpc, p)
+		}
+		return cl.Patch(ctx, obj, p, opts...)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(funcs).Build()
+	ns := testNamespace
+	m, err := NewKubeovnNetworkManager(cl, cl, &config.K8sConfig{Namespace: &ns}, nil, nil)
+	require.NoError(t, err)
+	return m, cl
+}
+
+func TestVpcRoutes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("routes are applied and reported", func(t *testing.T) {
+		m, cl := newManager(t)
+		got, err := m.CreateNetwork(ctx, "net1", routingData(map[string]string{
+			network.NetworkStaticRoutesMetadataKey: "192.168.0.0/16 = 10.0.0.10, fd10::/64=fd00::10",
+			network.NetworkPolicyRoutesMetadataKey: "31000:10.0.1.0/24>0.0.0.0/0=10.0.0.20",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "192.168.0.0/16=10.0.0.10,fd10::/64=fd00::10", got.GetMetadata().GetFields()[network.NetworkStaticRoutesMetadataKey])
+		assert.Equal(t, "31000:10.0.1.0/24>0.0.0.0/0=10.0.0.20", got.GetMetadata().GetFields()[network.NetworkPolicyRoutesMetadataKey])
+
+		vpc := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+		assert.Equal(t, []*kubeovnv1.StaticRoute{
+			{Policy: kubeovnv1.PolicyDst, CIDR: "192.168.0.0/16", NextHopIP: "10.0.0.10"},
+			{Policy: kubeovnv1.PolicyDst, CIDR: "fd10::/64", NextHopIP: "fd00::10"},
+		}, vpc.Spec.StaticRoutes)
+		assert.Equal(t, []*kubeovnv1.PolicyRoute{{
+			Priority:  31000,
+			Match:     "ip4.src == 10.0.1.0/24 && ip4.dst == 0.0.0.0/0",
+			Action:    kubeovnv1.PolicyRouteActionReroute,
+			NextHopIP: "10.0.0.20",
+		}}, vpc.Spec.PolicyRoutes)
+
+		got, err = m.UpdateNetwork(ctx, routingData(map[string]string{
+			network.NetworkStaticRoutesMetadataKey: "172.16.0.0/12=10.0.0.11",
+			network.NetworkPolicyRoutesMetadataKey: "",
+		}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		assert.Equal(t, "172.16.0.0/12=10.0.0.11", got.GetMetadata().GetFields()[network.NetworkStaticRoutesMetadataKey])
+		assert.NotContains(t, got.GetMetadata().GetFields(), network.NetworkPolicyRoutesMetadataKey)
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+		assert.Equal(t, []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "172.16.0.0/12", NextHopIP: "10.0.0.11"}}, vpc.Spec.StaticRoutes)
+		assert.Empty(t, vpc.Spec.PolicyRoutes)
+	})
+
+	t.Run("the NAT gateway default route is kept", func(t *testing.T) {
+		vpc := seedVpc("net1")
+		natRoute := &kubeovnv1.StaticRoute{Policy: kubeovnv1.PolicyDst, CIDR: "0.0.0.0/0", NextHopIP: "10.0.0.254"}
+		vpc.Spec.StaticRoutes = []*kubeovnv1.StaticRoute{natRoute}
+		m, cl := newManager(t, vpc)
+
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkStaticRoutesMetadataKey: "0.0.0.0/0=10.0.0.1"}), network.GetNetworkByName("net1"))
+		var precondition *apperrors.ErrFailedPrecondition
+		assert.ErrorAs(t, err, &precondition)
+
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkStaticRoutesMetadataKey: "192.168.0.0/16=10.0.0.10"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkStaticRoutesMetadataKey: ""}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, vpc))
+		assert.Equal(t, []*kubeovnv1.StaticRoute{natRoute}, vpc.Spec.StaticRoutes)
+	})
+
+	t.Run("invalid routes are rejected", func(t *testing.T) {
+		m, _ := newManager(t, seedVpc("net1"), seedVlan("vl1", 100))
+		for name, fields := range map[string]map[string]string{
+			"static route format":        {network.NetworkStaticRoutesMetadataKey: "192.168.0.0/16"},
+			"static route family":        {network.NetworkStaticRoutesMetadataKey: "192.168.0.0/16=fd00::1"},
+			"static route duplicate":     {network.NetworkStaticRoutesMetadataKey: "192.168.0.0/16=10.0.0.1,192.168.0.0/16=10.0.0.2"},
+			"static route via peering":   {network.NetworkStaticRoutesMetadataKey: "192.168.0.0/16=169.254.64.2"},
+			"policy route priority":      {network.NetworkPolicyRoutesMetadataKey: "40000:10.0.1.0/24=10.0.0.20"},
+			"policy route format":        {network.NetworkPolicyRoutesMetadataKey: "10.0.1.0/24=10.0.0.20"},
+			"policy route mixed family":  {network.NetworkPolicyRoutesMetadataKey: "100:10.0.1.0/24>fd00::/64=10.0.0.20"},
+			"peering with itself":        {network.NetworkPeeringsMetadataKey: "net1"},
+			"peering with an underlay":   {network.NetworkPeeringsMetadataKey: "vl1"},
+			"peering with a missing one": {network.NetworkPeeringsMetadataKey: "nope"},
+		} {
+			_, err := m.UpdateNetwork(ctx, routingData(fields), network.GetNetworkByName("net1"))
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net1"}), network.GetNetworkByName("vl1"))
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target, "underlay networks have no router")
+	})
+}
+
+func TestVpcPeering(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("peers both vpcs and routes their cidrs", func(t *testing.T) {
+		m, cl := newManager(t, peeringSeeds()...)
+		got, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "uid-net2"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		assert.Equal(t, "net2", got.GetMetadata().GetFields()[network.NetworkPeeringsMetadataKey])
+
+		net1, net2 := &kubeovnv1.Vpc{}, &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, net1))
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		assert.Equal(t, []*kubeovnv1.VpcPeering{{RemoteVpc: "net2", LocalConnectIP: "169.254.64.1/30"}}, net1.Spec.VpcPeerings)
+		assert.Equal(t, []*kubeovnv1.VpcPeering{{RemoteVpc: "net1", LocalConnectIP: "169.254.64.2/30"}}, net2.Spec.VpcPeerings)
+		assert.Equal(t, []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "10.1.0.0/24", NextHopIP: "169.254.64.2"}}, net1.Spec.StaticRoutes)
+		assert.Equal(t, []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "10.0.0.0/24", NextHopIP: "169.254.64.1"}}, net2.Spec.StaticRoutes)
+		assert.NotContains(t, net1.Annotations, network.NetworkPeeringsMetadataKey)
+
+		peer, err := m.GetNetwork(ctx, network.GetNetworkByName("net2"))
+		require.NoError(t, err)
+		assert.Equal(t, "net1", peer.GetMetadata().GetFields()[network.NetworkPeeringsMetadataKey])
+
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2,net3"}), network.GetNetworkByName("net1"))
+		var invalid *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &invalid, "net3 overlaps net1")
+
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: ""}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, net1))
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		assert.Empty(t, net1.Spec.VpcPeerings)
+		assert.Empty(t, net1.Spec.StaticRoutes)
+		assert.Empty(t, net2.Spec.VpcPeerings)
+		assert.Empty(t, net2.Spec.StaticRoutes)
+	})
+
+	t.Run("peer cidrs must not overlap each other", func(t *testing.T) {
+		m, _ := newManager(t, peeringSeeds()...)
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net1"}), network.GetNetworkByName("net2"))
+		require.NoError(t, err)
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net3"}), network.GetNetworkByName("net2"))
+		require.NoError(t, err, "net1 is unpeered")
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net1,net3"}), network.GetNetworkByName("net2"))
+		var invalid *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &invalid, "net2 would route to both net1 and net3")
+	})
+
+	t.Run("subnets are routed from the peers", func(t *testing.T) {
+		m, cl := newManager(t, peeringSeeds()...)
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+
+		data := subnetDataWithOptions("10.2.0.0/24", nil)
+		data.NetworkId = &nfvcommon.Identifier{Value: "net2"}
+		_, err = m.CreateSubnet(ctx, "sub4", data)
+		require.NoError(t, err)
+		net1 := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, net1))
+		assert.ElementsMatch(t, []*kubeovnv1.StaticRoute{
+			{Policy: kubeovnv1.PolicyDst, CIDR: "10.1.0.0/24", NextHopIP: "169.254.64.2"},
+			{Policy: kubeovnv1.PolicyDst, CIDR: "10.2.0.0/24", NextHopIP: "169.254.64.2"},
+		}, net1.Spec.StaticRoutes)
+
+		data = subnetDataWithOptions("10.0.0.128/25", nil)
+		data.NetworkId = &nfvcommon.Identifier{Value: "net2"}
+		_, err = m.CreateSubnet(ctx, "sub5", data)
+		var invalid *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &invalid, "overlaps the net1 peer")
+
+		require.NoError(t, m.DeleteSubnet(ctx, network.GetSubnetByName("sub4")))
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, net1))
+		assert.Equal(t, []*kubeovnv1.StaticRoute{{Policy: kubeovnv1.PolicyDst, CIDR: "10.1.0.0/24", NextHopIP: "169.254.64.2"}}, net1.Spec.StaticRoutes)
+	})
+
+	t.Run("deleting a network unpeers it", func(t *testing.T) {
+		nad := &netattv1.NetworkAttachmentDefinition{ObjectMeta: metav1.ObjectMeta{Name: formatNetAttachName("sub1"), Namespace: testNamespace}}
+		m, cl := newManager(t, append(peeringSeeds(), nad)...)
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		require.NoError(t, m.DeleteNetwork(ctx, network.GetNetworkByName("net1")))
+		net2 := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		assert.Empty(t, net2.Spec.VpcPeerings)
+		assert.Empty(t, net2.Spec.StaticRoutes)
+	})
+
+	t.Run("links are not reused", func(t *testing.T) {
+		sub4 := seedVpcSubnet("sub4", "net4")
+		sub4.Spec.CIDRBlock = "10.3.0.0/24"
+		m, cl := newManager(t, append(peeringSeeds(), seedVpc("net4", "sub4"), sub4)...)
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2"}), network.GetNetworkByName("net4"))
+		require.NoError(t, err)
+		net2 := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		assert.Equal(t, []*kubeovnv1.VpcPeering{
+			{RemoteVpc: "net1", LocalConnectIP: "169.254.64.2/30"},
+			{RemoteVpc: "net4", LocalConnectIP: "169.254.64.6/30"},
+		}, net2.Spec.VpcPeerings)
+		assert.ElementsMatch(t, []*kubeovnv1.StaticRoute{
+			{Policy: kubeovnv1.PolicyDst, CIDR: "10.0.0.0/24", NextHopIP: "169.254.64.1"},
+			{Policy: kubeovnv1.PolicyDst, CIDR: "10.3.0.0/24", NextHopIP: "169.254.64.5"},
+		}, net2.Spec.StaticRoutes)
+	})
+
+	t.Run("failed peer patch rolls the peering back", func(t *testing.T) {
+		sub4 := seedVpcSubnet("sub4", "net4")
+		sub4.Spec.CIDRBlock = "10.3.0.0/24"
+		m, cl := newPatchingManager(t, func(ctx context.Context, cl client.WithWatch, vpc *kubeovnv1.Vpc, p client.Patch) error {
+			if vpc.Name == "net4" {
+				return errors.New("net4 is unavailable")
+			}
+			return cl.Patch(ctx, vpc, p)
+		}, append(peeringSeeds(), seedVpc("net4", "sub4"), sub4)...)
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		net1, net2 := &kubeovnv1.Vpc{}, &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, net1))
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		peered1, peered2 := net1.Spec, net2.Spec
+
+		_, err = m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net4"}), network.GetNetworkByName("net1"))
+		assert.ErrorContains(t, err, "net4 is unavailable")
+		assert.ErrorContains(t, err, "rolled back")
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net1"}, net1))
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		assert.Equal(t, peered1, net1.Spec)
+		assert.Equal(t, peered2.VpcPeerings, net2.Spec.VpcPeerings)
+		assert.Equal(t, peered2.StaticRoutes, net2.Spec.StaticRoutes)
+	})
+
+	t.Run("conflicting patches are retried", func(t *testing.T) {
+		conflicts := 0
+		m, cl := newPatchingManager(t, func(ctx context.Context, cl client.WithWatch, vpc *kubeovnv1.Vpc, p client.Patch) error {
+			if conflicts < 2 {
+				conflicts++
+				return apierrors.NewConflict(schema.GroupResource{Resource: "vpcs"}, vpc.Name, errors.New("modified"))
+			}
+			return cl.Patch(ctx, vpc, p)
+		}, peeringSeeds()...)
+		_, err := m.UpdateNetwork(ctx, routingData(map[string]string{network.NetworkPeeringsMetadataKey: "net2"}), network.GetNetworkByName("net1"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, conflicts)
+		net2 := &kubeovnv1.Vpc{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "net2"}, net2))
+		assert.Equal(t, []*kubeovnv1.VpcPeering{{RemoteVpc: "net1", LocalConnectIP: "169.254.64.2/30"}}, net2.Spec.VpcPeerings)
+	})
+}
+
+func TestPeeringLinkRemote(t *testing.T) {
+	assert.Equal(t, "169.254.64.2", peeringLinkRemote(netip.MustParsePrefix("169.254.64.1/30")).String())
+	assert.Equal(t, "169.254.64.1", peeringLinkRemote(netip.MustParsePrefix("169.254.64.2/30")).String())
+}
@@ -0,0 +1,291 @@
+package kubeovn
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// sgRulePriority is the kube-ovn priority (1-200) of every rule. All the rules
+	// allow traffic, so their relative priority does not matter.
+	sgRulePriority = 100
+	minPort        = 1
+	maxPort        = 65535
+
+	// kubeovnSecurityGroupsAnnotationSuffix ends the "<netattach>.<namespace>"
+	// annotation binding security groups to a vNIC.
+	kubeovnSecurityGroupsAnnotationSuffix = ".ovn.kubernetes.io/security_groups"
+)
+
+func (m *manager) CreateSecurityGroup(ctx context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+	kubeovnSg, err := kubeovnSecurityGroupFromNfv(sg)
+	if err != nil {
+		return nil, fmt.Errorf("convert security group to kube-ovn SecurityGroup: %w", err)
+	}
+	if err := m.client.Create(ctx, kubeovnSg); err != nil {
+		return nil, fmt.Errorf("create kube-ovn SecurityGroup '%s': %w", kubeovnSg.Name, err)
+	}
+	return nfvSecurityGroupFromKubeovn(kubeovnSg)
+}
+
+func (m *manager) GetSecurityGroup(ctx context.Context, opts ...network.GetSecurityGroupOpt) (*network.SecurityGroup, error) {
+	sg, err := m.getKubeovnSecurityGroup(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return nfvSecurityGroupFromKubeovn(sg)
+}
+
+func (m *manager) getKubeovnSecurityGroup(ctx context.Context, opts ...network.GetSecurityGroupOpt) (*kubeovnv1.SecurityGroup, error) {
+	cfg := network.ApplyGetSecurityGroupOpts(opts...)
+	if cfg.Name != "" {
+		sg := &kubeovnv1.SecurityGroup{}
+		if err := m.client.Get(ctx, client.ObjectKey{Name: cfg.Name}, sg); err != nil {
+			return nil, fmt.Errorf("get kube-ovn SecurityGroup '%s': %w", cfg.Name, err)
+		}
+		if !misc.IsObjectManagedByKubeNfv(sg) {
+			return nil, &apperrors.ErrK8sObjectNotManagedByKubeNfv{ObjectType: "SecurityGroup", ObjectName: sg.Name, ObjectId: string(sg.UID)}
+		}
+		return sg, nil
+	}
+	if cfg.Uid == nil || cfg.Uid.Value == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "security group identifier", Reason: "either name or uid must be specified"}
+	}
+	sgList := &kubeovnv1.SecurityGroupList{}
+	if err := m.client.List(ctx, sgList, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return nil, fmt.Errorf("list kube-ovn SecurityGroups for id '%s': %w", cfg.Uid.Value, err)
+	}
+	uid := misc.IdentifierToUID(cfg.Uid)
+	for idx := range sgList.Items {
+		if sgList.Items[idx].GetUID() == uid {
+			return &sgList.Items[idx], nil
+		}
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "kube-ovn SecurityGroup", Identifier: cfg.Uid.GetValue()}
+}
+
+func (m *manager) ListSecurityGroups(ctx context.Context) ([]*network.SecurityGroup, error) {
+	sgList := &kubeovnv1.SecurityGroupList{}
+	if err := m.client.List(ctx, sgList, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return nil, fmt.Errorf("list kube-ovn SecurityGroups: %w", err)
+	}
+	res := make([]*network.SecurityGroup, 0, len(sgList.Items))
+	for idx := range sgList.Items {
+		sg, err := nfvSecurityGroupFromKubeovn(&sgList.Items[idx])
+		if err != nil {
+			return nil, fmt.Errorf("convert kube-ovn SecurityGroup '%s': %w", sgList.Items[idx].Name, err)
+		}
+		res = append(res, sg)
+	}
+	return res, nil
+}
+
+// Replaces the rules of the kube-ovn SecurityGroup identified by the id, or else
+// the name, of the security group.
+func (m *manager) UpdateSecurityGroup(ctx context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+	if sg == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "security group", Reason: "cannot be nil"}
+	}
+	opts := []network.GetSecurityGroupOpt{network.GetSecurityGroupByName(sg.Name)}
+	if sg.SecurityGroupId.GetValue() != "" {
+		opts = []network.GetSecurityGroupOpt{network.GetSecurityGroupByUid(sg.SecurityGroupId)}
+	}
+	kubeovnSg, err := m.getKubeovnSecurityGroup(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if sg.Name != "" && sg.Name != kubeovnSg.Name {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "is immutable"}
+	}
+	if sg.Rules != nil {
+		ingress, egress, err := kubeovnSgRulesFromNfv(sg.Rules)
+		if err != nil {
+			return nil, err
+		}
+		base := kubeovnSg.DeepCopy()
+		kubeovnSg.Spec.IngressRules = ingress
+		kubeovnSg.Spec.EgressRules = egress
+		if err := m.client.Patch(ctx, kubeovnSg, client.MergeFrom(base)); err != nil {
+			return nil, fmt.Errorf("patch kube-ovn SecurityGroup '%s': %w", kubeovnSg.Name, err)
+		}
+	}
+	return nfvSecurityGroupFromKubeovn(kubeovnSg)
+}
+
+// Deletes the kube-ovn SecurityGroup, refusing while a compute has a vNIC bound
+// to it.
+func (m *manager) DeleteSecurityGroup(ctx context.Context, opts ...network.GetSecurityGroupOpt) error {
+	kubeovnSg, err := m.getKubeovnSecurityGroup(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	computeIds, err := m.securityGroupComputeIds(ctx, kubeovnSg.Name)
+	if err != nil {
+		return fmt.Errorf("get computes bound to security group '%s': %w", kubeovnSg.Name, err)
+	}
+	if len(computeIds) > 0 {
+		return &network.ErrInUse{
+			Resource:   fmt.Sprintf("security group '%s' (id: %s)", kubeovnSg.Name, kubeovnSg.UID),
+			ComputeIds: computeIds,
+		}
+	}
+	if err := m.client.Delete(ctx, &kubeovnv1.SecurityGroup{ObjectMeta: v1.ObjectMeta{Name: kubeovnSg.Name}}); err != nil {
+		return fmt.Errorf("delete kube-ovn SecurityGroup '%s': %w", kubeovnSg.Name, err)
+	}
+	return nil
+}
+
+// Returns the sorted ids of the computes whose VirtualMachine template binds a
+// vNIC to the security group.
+func (m *manager) securityGroupComputeIds(ctx context.Context, sgName string) ([]*nfvcommon.Identifier, error) {
+	vmList := &kubevirtv1.VirtualMachineList{}
+	if err := m.apiReader.List(ctx, vmList, client.InNamespace(*m.k8sCfg.Namespace), client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return nil, fmt.Errorf("list kubevirt VirtualMachines: %w", err)
+	}
+	bound := make(map[types.UID]struct{})
+	for _, vm := range vmList.Items {
+		if vm.DeletionTimestamp != nil || vm.Spec.Template == nil {
+			continue
+		}
+		for key, value := range vm.Spec.Template.ObjectMeta.Annotations {
+			if strings.HasSuffix(key, kubeovnSecurityGroupsAnnotationSuffix) && slices.Contains(strings.Split(value, ","), sgName) {
+				bound[vm.UID] = struct{}{}
+			}
+		}
+	}
+	res := make([]*nfvcommon.Identifier, 0, len(bound))
+	for _, uid := range slices.Sorted(maps.Keys(bound)) {
+		res = append(res, misc.UIDToIdentifier(uid))
+	}
+	return res, nil
+}
+
+func kubeovnSecurityGroupFromNfv(sg *network.SecurityGroup) (*kubeovnv1.SecurityGroup, error) {
+	if sg == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "security group", Reason: "cannot be nil"}
+	}
+	if sg.Name == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "cannot be empty"}
+	}
+	ingress, egress, err := kubeovnSgRulesFromNfv(sg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeovnv1.SecurityGroup{
+		ObjectMeta: v1.ObjectMeta{
+			Name: sg.Name,
+			Labels: map[string]string{
+				common.K8sManagedByLabel: common.KubeNfvName,
+			},
+		},
+		Spec: kubeovnv1.SecurityGroupSpec{
+			IngressRules: ingress,
+			EgressRules:  egress,
+		},
+	}, nil
+}
+
+func kubeovnSgRulesFromNfv(rules []*network.SecurityGroupRule) (ingress, egress []kubeovnv1.SecurityGroupRule, err error) {
+	for idx, rule := range rules {
+		field := fmt.Sprintf("rules[%d]", idx)
+		if rule == nil {
+			return nil, nil, &apperrors.ErrInvalidArgument{Field: field, Reason: "cannot be null"}
+		}
+		protocol := rule.Protocol
+		if protocol == "" {
+			protocol = network.SecurityGroupRuleProtocolAll
+		}
+		res := kubeovnv1.SecurityGroupRule{
+			Protocol:   kubeovnv1.SgProtocol(protocol),
+			Priority:   sgRulePriority,
+			RemoteType: kubeovnv1.SgRemoteTypeAddress,
+			Policy:     kubeovnv1.SgPolicyAllow,
+		}
+		switch protocol {
+		case network.SecurityGroupRuleProtocolTcp, network.SecurityGroupRuleProtocolUdp:
+			res.PortRangeMin, res.PortRangeMax = int(rule.PortRangeMin), int(rule.PortRangeMax)
+			if res.PortRangeMin == 0 && res.PortRangeMax == 0 {
+				res.PortRangeMin, res.PortRangeMax = minPort, maxPort
+			} else if res.PortRangeMax == 0 {
+				res.PortRangeMax = res.PortRangeMin
+			}
+			if res.PortRangeMin < minPort || res.PortRangeMax > maxPort || res.PortRangeMin > res.PortRangeMax {
+				return nil, nil, &apperrors.ErrInvalidArgument{Field: field + " port range", Reason: fmt.Sprintf("must be within %d-%d with min <= max: %d-%d", minPort, maxPort, rule.PortRangeMin, rule.PortRangeMax)}
+			}
+		case network.SecurityGroupRuleProtocolAll, network.SecurityGroupRuleProtocolIcmp:
+			if rule.PortRangeMin != 0 || rule.PortRangeMax != 0 {
+				return nil, nil, &apperrors.ErrInvalidArgument{Field: field + " port range", Reason: fmt.Sprintf("only applies to tcp and udp, not %s", protocol)}
+			}
+		default:
+			return nil, nil, &apperrors.ErrInvalidArgument{Field: field + " protocol", Reason: fmt.Sprintf("must be one of all, tcp, udp, icmp: '%s'", rule.Protocol)}
+		}
+		prefix, perr := netip.ParsePrefix(rule.RemoteCidr)
+		if perr != nil {
+			return nil, nil, &apperrors.ErrInvalidArgument{Field: field + " remoteCidr", Reason: fmt.Sprintf("invalid CIDR: '%s'", rule.RemoteCidr)}
+		}
+		res.RemoteAddress = prefix.Masked().String()
+		res.IPVersion = "ipv4"
+		if prefix.Addr().Is6() {
+			res.IPVersion = "ipv6"
+		}
+		switch rule.Direction {
+		case network.SecurityGroupRuleIngress:
+			ingress = append(ingress, res)
+		case network.SecurityGroupRuleEgress:
+			egress = append(egress, res)
+		default:
+			return nil, nil, &apperrors.ErrInvalidArgument{Field: field + " direction", Reason: fmt.Sprintf("must be ingress or egress: '%s'", rule.Direction)}
+		}
+	}
+	return ingress, egress, nil
+}
+
+func nfvSecurityGroupFromKubeovn(sg *kubeovnv1.SecurityGroup) (*network.SecurityGroup, error) {
+	if !misc.IsObjectInstantiated(sg) {
+		return nil, &apperrors.ErrK8sObjectNotInstantiated{ObjectType: "SecurityGroup", Identifier: sg.Name}
+	}
+	rules := make([]*network.SecurityGroupRule, 0, len(sg.Spec.IngressRules)+len(sg.Spec.EgressRules))
+	for _, dir := range []struct {
+		direction string
+		rules     []kubeovnv1.SecurityGroupRule
+	}{
+		{network.SecurityGroupRuleIngress, sg.Spec.IngressRules},
+		{network.SecurityGroupRuleEgress, sg.Spec.EgressRules},
+	} {
+		for _, rule := range dir.rules {
+			res := &network.SecurityGroupRule{
+				Direction:  dir.direction,
+				Protocol:   string(rule.Protocol),
+				RemoteCidr: rule.RemoteAddress,
+			}
+			if res.Protocol == "" {
+				res.Protocol = network.SecurityGroupRuleProtocolAll
+			}
+			// The whole port range is reported as unset, like it was requested.
+			if rule.PortRangeMin != minPort || rule.PortRangeMax != maxPort {
+				res.PortRangeMin, res.PortRangeMax = int32(rule.PortRangeMin), int32(rule.PortRangeMax)
+			}
+			rules = append(rules, res)
+		}
+	}
+	return &network.SecurityGroup{
+		SecurityGroupId: misc.UIDToIdentifier(sg.UID),
+		Name:            sg.Name,
+		Rules:           rules,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package kubeovn
+
+import (
+	"context"
+	"testing"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func seedSecurityGroup(name string, ingress ...kubeovnv1.SecurityGroupRule) *kubeovnv1.SecurityGroup {
+	return &kubeovnv1.SecurityGroup{
+		ObjectMeta: managedMeta(name),
+		Spec:       kubeovnv1.SecurityGroupSpec{IngressRules: ingress},
+	}
+}
+
+func TestCreateSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("rules are converted to kube-ovn allow rules", func(t *testing.T) {
+		m, cl := newManager(t)
+		sg, err := m.CreateSecurityGroup(ctx, &network.SecurityGroup{Name: "web", Rules: []*network.SecurityGroupRule{
+			{Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolTcp, PortRangeMin: 443, RemoteCidr: "10.0.0.7/24"},
+			{Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolUdp, RemoteCidr: "fd00::/64"},
+			{Direction: network.SecurityGroupRuleEgress, RemoteCidr: "0.0.0.0/0"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "web", sg.Name)
+		assert.Equal(t, []*network.SecurityGroupRule{
+			{Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolTcp, PortRangeMin: 443, PortRangeMax: 443, RemoteCidr: "10.0.0.0/24"},
+			{Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolUdp, RemoteCidr: "fd00::/64"},
+			{Direction: network.SecurityGroupRuleEgress, Protocol: network.SecurityGroupRuleProtocolAll, RemoteCidr: "0.0.0.0/0"},
+		}, sg.Rules)
+
+		stored := &kubeovnv1.SecurityGroup{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "web"}, stored))
+		require.Len(t, stored.Spec.IngressRules, 2)
+		assert.Equal(t, kubeovnv1.SecurityGroupRule{
+			IPVersion:     "ipv4",
+			Protocol:      kubeovnv1.SgProtocol("tcp"),
+			Priority:      sgRulePriority,
+			RemoteType:    kubeovnv1.SgRemoteTypeAddress,
+			RemoteAddress: "10.0.0.0/24",
+			PortRangeMin:  443,
+			PortRangeMax:  443,
+			Policy:        kubeovnv1.SgPolicyAllow,
+		}, stored.Spec.IngressRules[0])
+		assert.Equal(t, "ipv6", stored.Spec.IngressRules[1].IPVersion)
+		assert.Equal(t, []int{minPort, maxPort}, []int{stored.Spec.IngressRules[1].PortRangeMin, stored.Spec.IngressRules[1].PortRangeMax})
+		require.Len(t, stored.Spec.EgressRules, 1)
+	})
+
+	t.Run("invalid rules are rejected", func(t *testing.T) {
+		m, _ := newManager(t)
+		for name, rule := range map[string]*network.SecurityGroupRule{
+			"no direction":        {RemoteCidr: "0.0.0.0/0"},
+			"unknown protocol":    {Direction: network.SecurityGroupRuleIngress, Protocol: "sctp", RemoteCidr: "0.0.0.0/0"},
+			"ports on icmp":       {Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolIcmp, PortRangeMin: 1, RemoteCidr: "0.0.0.0/0"},
+			"inverted port range": {Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolTcp, PortRangeMin: 90, PortRangeMax: 80, RemoteCidr: "0.0.0.0/0"},
+			"port out of range":   {Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolTcp, PortRangeMin: 70000, RemoteCidr: "0.0.0.0/0"},
+			"missing remote cidr": {Direction: network.SecurityGroupRuleEgress},
+			"invalid remote cidr": {Direction: network.SecurityGroupRuleEgress, RemoteCidr: "10.0.0.0"},
+		} {
+			_, err := m.CreateSecurityGroup(ctx, &network.SecurityGroup{Name: "bad", Rules: []*network.SecurityGroupRule{rule}})
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+		_, err := m.CreateSecurityGroup(ctx, &network.SecurityGroup{})
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target, "name is required")
+	})
+}
+
+func TestGetSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ssh := kubeovnv1.SecurityGroupRule{Protocol: "tcp", RemoteAddress: "0.0.0.0/0", PortRangeMin: 22, PortRangeMax: 22}
+
+	t.Run("by name and uid", func(t *testing.T) {
+		m, _ := newManager(t, seedSecurityGroup("ssh", ssh))
+		byName, err := m.GetSecurityGroup(ctx, network.GetSecurityGroupByName("ssh"))
+		require.NoError(t, err)
+		assert.Equal(t, []*network.SecurityGroupRule{
+			{Direction: network.SecurityGroupRuleIngress, Protocol: network.SecurityGroupRuleProtocolTcp, PortRangeMin: 22, PortRangeMax: 22, RemoteCidr: "0.0.0.0/0"},
+		}, byName.Rules)
+
+		byUid, err := m.GetSecurityGroup(ctx, network.GetSecurityGroupByUid(k8stest.ID("uid-ssh")))
+		require.NoError(t, err)
+		assert.Equal(t, byName, byUid)
+	})
+
+	t.Run("not managed or missing", func(t *testing.T) {
+		m, _ := newManager(t, &kubeovnv1.SecurityGroup{ObjectMeta: metav1.ObjectMeta{Name: "foreign", UID: "uid-foreign"}})
+		_, err := m.GetSecurityGroup(ctx, network.GetSecurityGroupByName("foreign"))
+		var notManaged *apperrors.ErrK8sObjectNotManagedByKubeNfv
+		assert.ErrorAs(t, err, &notManaged)
+
+		_, err = m.GetSecurityGroup(ctx, network.GetSecurityGroupByUid(k8stest.ID("uid-foreign")))
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestListSecurityGroups(t *testing.T) {
+	t.Parallel()
+	m, _ := newManager(t, seedSecurityGroup("sg1"), seedSecurityGroup("sg2"),
+		&kubeovnv1.SecurityGroup{ObjectMeta: metav1.ObjectMeta{Name: "foreign", UID: "uid-foreign"}})
+	sgs, err := m.ListSecurityGroups(context.Background())
+	require.NoError(t, err)
+	names := make([]string, 0, len(sgs))
+	for _, sg := range sgs {
+		names = append(names, sg.Name)
+	}
+	assert.ElementsMatch(t, []string{"sg1", "sg2"}, names)
+}
+
+func TestUpdateSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ssh := kubeovnv1.SecurityGroupRule{Protocol: "tcp", RemoteAddress: "0.0.0.0/0", PortRangeMin: 22, PortRangeMax: 22}
+
+	t.Run("replaces the rules", func(t *testing.T) {
+		m, cl := newManager(t, seedSecurityGroup("ssh", ssh))
+		sg, err := m.UpdateSecurityGroup(ctx, &network.SecurityGroup{SecurityGroupId: k8stest.ID("uid-ssh"), Rules: []*network.SecurityGroupRule{
+			{Direction: network.SecurityGroupRuleEgress, Protocol: network.SecurityGroupRuleProtocolIcmp, RemoteCidr: "10.0.0.0/8"},
+		}})
+		require.NoError(t, err)
+		assert.Len(t, sg.Rules, 1)
+
+		stored := &kubeovnv1.SecurityGroup{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "ssh"}, stored))
+		assert.Empty(t, stored.Spec.IngressRules)
+		require.Len(t, stored.Spec.EgressRules, 1)
+		assert.Equal(t, kubeovnv1.SgProtocol("icmp"), stored.Spec.EgressRules[0].Protocol)
+	})
+
+	t.Run("nil rules keep the rules, an empty list clears them", func(t *testing.T) {
+		m, _ := newManager(t, seedSecurityGroup("ssh", ssh))
+		sg, err := m.UpdateSecurityGroup(ctx, &network.SecurityGroup{Name: "ssh"})
+		require.NoError(t, err)
+		assert.Len(t, sg.Rules, 1)
+
+		sg, err = m.UpdateSecurityGroup(ctx, &network.SecurityGroup{Name: "ssh", Rules: []*network.SecurityGroupRule{}})
+		require.NoError(t, err)
+		assert.Empty(t, sg.Rules)
+	})
+
+	t.Run("name is immutable", func(t *testing.T) {
+		m, _ := newManager(t, seedSecurityGroup("ssh", ssh))
+		_, err := m.UpdateSecurityGroup(ctx, &network.SecurityGroup{SecurityGroupId: k8stest.ID("uid-ssh"), Name: "renamed"})
+		var target *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &target)
+	})
+}
+
+func TestDeleteSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bound := func(name, groups string) client.Object {
+		vm := seedVm(name, formatNetAttachName("sub1"))
+		vm.Spec.Template.ObjectMeta.Annotations = map[string]string{
+			formatNetAttachName("sub1") + "." + testNamespace + kubeovnSecurityGroupsAnnotationSuffix: groups,
+		}
+		return vm
+	}
+
+	t.Run("deletes an unused group", func(t *testing.T) {
+		m, cl := newManager(t, seedSecurityGroup("web"), bound("vm1", "web-admin"))
+		require.NoError(t, m.DeleteSecurityGroup(ctx, network.GetSecurityGroupByUid(k8stest.ID("uid-web"))))
+		err := cl.Get(ctx, client.ObjectKey{Name: "web"}, &kubeovnv1.SecurityGroup{})
+		assert.True(t, apierrors.IsNotFound(err), "security group should be gone")
+	})
+
+	t.Run("bound vNICs block the deletion", func(t *testing.T) {
+		m, cl := newManager(t, seedSecurityGroup("web"), bound("vm2", "ssh,web"), bound("vm1", "web"))
+		err := m.DeleteSecurityGroup(ctx, network.GetSecurityGroupByName("web"))
+		var inUseErr *network.ErrInUse
+		require.ErrorAs(t, err, &inUseErr)
+		assert.Equal(t, []*nfvcommon.Identifier{k8stest.ID("uid-vm1"), k8stest.ID("uid-vm2")}, inUseErr.ComputeIds)
+		assert.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "web"}, &kubeovnv1.SecurityGroup{}), "security group should be kept")
+	})
+}
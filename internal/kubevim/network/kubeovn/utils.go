@@ -2,7 +2,11 @@ package kubeovn
 
 import (
 	"fmt"
+	"maps"
 	"net"
+	"net/netip"
+	"slices"
+	"strings"
 
 	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
@@ -14,6 +18,13 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// IPv6 address modes announced in the router advertisements of a subnet.
+const (
+	ipv6AddressModeSlaac           = "slaac"
+	ipv6AddressModeDhcpv6Stateful  = "dhcpv6_stateful"
+	ipv6AddressModeDhcpv6Stateless = "dhcpv6_stateless"
+)
+
 func kubeovnVpcFromNfvNetworkData(name string, nfvNet *vivnfm.VirtualNetworkData) (*kubeovnv1.Vpc, error) {
 	if len(name) == 0 {
 		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "cannot be empty"}
@@ -27,9 +38,18 @@ func kubeovnVpcFromNfvNetworkData(name string, nfvNet *vivnfm.VirtualNetworkData
 			Labels: map[string]string{
 				common.K8sManagedByLabel: common.KubeNfvName,
 			},
+			Annotations: mergeMetadataAnnotations(nil, nfvNet.Metadata),
 		},
 		Spec: kubeovnv1.VpcSpec{},
 	}
+	if err := applyVpcStaticRoutes(res, nil, nfvNet.Metadata); err != nil {
+		return nil, err
+	}
+	if err := applyVpcPolicyRoutes(res, nil, nfvNet.Metadata); err != nil {
+		return nil, err
+	}
+	// The peerings are applied once the vpc and its subnets exist.
+	delete(res.Annotations, network.NetworkPeeringsMetadataKey)
 	return res, nil
 }
 
@@ -63,9 +83,24 @@ func kubeovnVpcToNfvNetwork(vpc *kubeovnv1.Vpc, subnetIds []*nfvcommon.Identifie
 		NetworkType:         nfvcommon.NetworkType_NETWORK_TYPE_OVERLAY,
 		IsShared:            false,
 		OperationalState:    nfvcommon.OperationalState_ENABLED,
+		Metadata:            nfvVpcMetadata(vpc),
 	}, nil
 }
 
+// nfvVpcMetadata returns the network metadata kept in the vpc annotations, with
+// the peerings of the vpc.
+func nfvVpcMetadata(vpc *kubeovnv1.Vpc) *nfvcommon.Metadata {
+	res := nfvNetworkMetadataFromAnnotations(vpc.Annotations)
+	if len(vpc.Spec.VpcPeerings) == 0 {
+		return res
+	}
+	if res == nil {
+		res = &nfvcommon.Metadata{Fields: map[string]string{}}
+	}
+	setNetworkPeerings(res.Fields, vpc)
+	return res
+}
+
 func kubeovnVlanToNfvNetwork(vlan *kubeovnv1.Vlan, subnetIds []*nfvcommon.Identifier) (*vivnfm.VirtualNetwork, error) {
 	if vlan == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "vlan", Reason: "cannot be nil"}
@@ -100,6 +135,7 @@ func kubeovnVlanToNfvNetwork(vlan *kubeovnv1.Vlan, subnetIds []*nfvcommon.Identi
 		ProviderNetwork:     &vlan.Spec.Provider,
 		SegmentationId:      &segmentationId,
 		OperationalState:    nfvcommon.OperationalState_ENABLED,
+		Metadata:            nfvNetworkMetadataFromAnnotations(vlan.Annotations),
 	}, nil
 
 }
@@ -117,6 +153,9 @@ func kubeovnVlanFromNfvNetworkData(name string, nfvNet *vivnfm.VirtualNetworkDat
 	if nfvNet.ProviderNetwork == nil || *nfvNet.ProviderNetwork == "" {
 		return nil, &apperrors.ErrInvalidArgument{Field: "providerNetwork", Reason: "cannot be empty for underlay networks"}
 	}
+	if err := checkNoVpcRouting(nfvNet.Metadata); err != nil {
+		return nil, err
+	}
 	vlanId := 0
 	if nfvNet.SegmentationId != nil {
 		vlanId = int(*nfvNet.SegmentationId)
@@ -128,6 +167,7 @@ func kubeovnVlanFromNfvNetworkData(name string, nfvNet *vivnfm.VirtualNetworkDat
 			Labels: map[string]string{
 				common.K8sManagedByLabel: common.KubeNfvName,
 			},
+			Annotations: mergeMetadataAnnotations(nil, nfvNet.Metadata),
 		},
 		Spec: kubeovnv1.VlanSpec{
 			ID:       vlanId,
@@ -137,11 +177,59 @@ func kubeovnVlanFromNfvNetworkData(name string, nfvNet *vivnfm.VirtualNetworkDat
 	return res, nil
 }
 
+// mergeMetadataAnnotations returns the annotations with the network metadata
+// applied. A key with an empty value removes the annotation.
+func mergeMetadataAnnotations(annotations map[string]string, metadata *nfvcommon.Metadata) map[string]string {
+	res := maps.Clone(annotations)
+	for key, value := range metadata.GetFields() {
+		if res == nil {
+			res = map[string]string{}
+		}
+		if value == "" {
+			delete(res, key)
+			continue
+		}
+		res[key] = value
+	}
+	return res
+}
+
+// nfvNetworkMetadataFromAnnotations returns the network metadata kept in the
+// annotations of the kubeovn Vpc or Vlan, or nil when there is none.
+func nfvNetworkMetadataFromAnnotations(annotations map[string]string) *nfvcommon.Metadata {
+	if len(annotations) == 0 {
+		return nil
+	}
+	return &nfvcommon.Metadata{Fields: maps.Clone(annotations)}
+}
+
+// validateNetworkUpdate checks that the network data changes no immutable field
+// of the network. Only the metadata of a network can be updated; its subnets
+// are updated as subnet resources.
+func validateNetworkUpdate(net *vivnfm.VirtualNetwork, nfvNet *vivnfm.VirtualNetworkData) error {
+	if nfvNet == nil {
+		return &apperrors.ErrInvalidArgument{Field: "network data", Reason: "cannot be nil"}
+	}
+	if nfvNet.NetworkType != nil && *nfvNet.NetworkType != net.NetworkType {
+		return &apperrors.ErrInvalidArgument{Field: "networkType", Reason: fmt.Sprintf("is immutable: network type is %s", net.NetworkType)}
+	}
+	if nfvNet.ProviderNetwork != nil && *nfvNet.ProviderNetwork != net.GetProviderNetwork() {
+		return &apperrors.ErrInvalidArgument{Field: "providerNetwork", Reason: fmt.Sprintf("is immutable: provider network is '%s'", net.GetProviderNetwork())}
+	}
+	if nfvNet.SegmentationId != nil && (net.SegmentationId == nil || *nfvNet.SegmentationId != *net.SegmentationId) {
+		return &apperrors.ErrInvalidArgument{Field: "segmentationId", Reason: fmt.Sprintf("is immutable: segmentation id is %d", net.GetSegmentationId())}
+	}
+	if len(nfvNet.Layer3Attributes) > 0 {
+		return &apperrors.ErrInvalidArgument{Field: "layer3Attributes", Reason: "subnets are updated with the subnet resource type"}
+	}
+	return nil
+}
+
 // Returns kubeovn IP version string representation of the nfvcommon.IPVersion enum or
 // error if it is contains unexpected data.
 //
-// Kubeovn IPVersion string MUST be one of the: IPv4, IPv6 or Dual
-// Note(dmalovan): Dual IPVersion is not yet supported.
+// Kubeovn IPVersion string MUST be one of the: IPv4, IPv6 or Dual. Dual is never
+// returned: a dual-stack subnet is recognized from its cidr.
 func kubeovnIpVersionFromNfv(ipVersion *nfvcommon.IPVersion) (string, error) {
 	if ipVersion == nil {
 		return "", &apperrors.ErrInvalidArgument{Field: "ip version", Reason: "not specified"}
@@ -159,24 +247,139 @@ func kubeovnIpVersionFromNfv(ipVersion *nfvcommon.IPVersion) (string, error) {
 // Returns vivnfm.IpVersion enum representation of the kubeovn IP version string or
 // error if it is contains unexpected data.
 //
-// Kubeovn IPVersion string MUST be one of the: IPv4, IPv6 or Dual
-// Note(dmalovan): Dual IPVersion is not yet supported.
+// Kubeovn IPVersion string MUST be one of the: IPv4, IPv6 or Dual. The ETSI IP
+// version has no dual-stack value, so a Dual subnet reports IPv4, the family
+// kube-ovn lists first.
 func nfvIpversionFromKubeovn(ipVersion string) (*nfvcommon.IPVersion, error) {
 	if ipVersion == "" {
 		return nil, &apperrors.ErrInvalidArgument{Field: "ip version", Reason: "not specified"}
 	}
 	switch ipVersion {
-	case "IPv4":
+	case kubeovnv1.ProtocolIPv4, kubeovnv1.ProtocolDual:
 		return nfvcommon.IPVersion_IPV4.Enum(), nil
-	case "IPv6":
+	case kubeovnv1.ProtocolIPv6:
 		return nfvcommon.IPVersion_IPV6.Enum(), nil
 	default:
 		return nil, fmt.Errorf("unsupported ip version '%s': %w", ipVersion, apperrors.ErrUnsupported)
 	}
 }
 
+// parseSubnetCidrs parses the CIDR of a subnet: a single CIDR or a comma-joined
+// IPv4 and IPv6 CIDR pair. The pair is returned IPv4 first, as kube-ovn expects
+// it.
+func parseSubnetCidrs(cidr string) ([]netip.Prefix, error) {
+	parts := network.SplitDualStack(cidr)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "cidr", Reason: fmt.Sprintf("must be a CIDR or an IPv4,IPv6 CIDR pair: '%s'", cidr)}
+	}
+	prefixes := make([]netip.Prefix, 0, len(parts))
+	for _, part := range parts {
+		if _, _, err := net.ParseCIDR(part); err != nil {
+			return nil, fmt.Errorf("parse cidr '%s': %w", part, err)
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr '%s': %w", part, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	if len(prefixes) == 2 {
+		if prefixes[0].Addr().Is4() == prefixes[1].Addr().Is4() {
+			return nil, &apperrors.ErrInvalidArgument{Field: "cidr", Reason: fmt.Sprintf("dual-stack cidr must hold one IPv4 and one IPv6 CIDR: '%s'", cidr)}
+		}
+		if prefixes[1].Addr().Is4() {
+			prefixes[0], prefixes[1] = prefixes[1], prefixes[0]
+		}
+	}
+	return prefixes, nil
+}
+
+// kubeovnProtocolFromPrefixes returns the kube-ovn protocol of the subnet CIDRs.
+func kubeovnProtocolFromPrefixes(prefixes []netip.Prefix) string {
+	if len(prefixes) == 2 {
+		return kubeovnv1.ProtocolDual
+	}
+	if prefixes[0].Addr().Is4() {
+		return kubeovnv1.ProtocolIPv4
+	}
+	return kubeovnv1.ProtocolIPv6
+}
+
+// prefixOfFamily returns the prefix of the address family of addr.
+func prefixOfFamily(prefixes []netip.Prefix, addr netip.Addr) (netip.Prefix, bool) {
+	for _, prefix := range prefixes {
+		if prefix.Addr().Is4() == addr.Is4() {
+			return prefix, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+func joinPrefixes(prefixes []netip.Prefix) string {
+	parts := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		parts = append(parts, prefix.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// kubeovnGatewayFromNfv returns the kube-ovn gateway of the subnet: one address
+// within each CIDR, in the order of the CIDRs.
+func kubeovnGatewayFromNfv(gateway string, prefixes []netip.Prefix) (string, error) {
+	parts := network.SplitDualStack(gateway)
+	if len(parts) != len(prefixes) {
+		return "", &apperrors.ErrInvalidArgument{Field: "gateway ip", Reason: fmt.Sprintf("must hold one address per cidr: %s", gateway)}
+	}
+	ordered := make([]string, len(prefixes))
+	for _, part := range parts {
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return "", &apperrors.ErrInvalidArgument{Field: "gateway ip", Reason: fmt.Sprintf("invalid format: %s", gateway)}
+		}
+		addr = addr.Unmap()
+		idx := slices.IndexFunc(prefixes, func(p netip.Prefix) bool { return p.Addr().Is4() == addr.Is4() })
+		if idx < 0 || !prefixes[idx].Contains(addr) || ordered[idx] != "" {
+			return "", &apperrors.ErrInvalidArgument{Field: "gateway ip", Reason: fmt.Sprintf("%s is not within cidr %s", part, joinPrefixes(prefixes))}
+		}
+		ordered[idx] = addr.String()
+	}
+	return strings.Join(ordered, ","), nil
+}
+
+// kubeovnIpv6RaConfigs returns the kube-ovn ipv6RAConfigs announcing the IPv6
+// address mode of the subnet.
+func kubeovnIpv6RaConfigs(metadata *nfvcommon.Metadata) (string, error) {
+	mode := ipv6AddressModeDhcpv6Stateful
+	if m, ok := metadata.GetFields()[network.SubnetIpv6AddressModeMetadataKey]; ok {
+		mode = m
+	}
+	if !slices.Contains([]string{ipv6AddressModeSlaac, ipv6AddressModeDhcpv6Stateful, ipv6AddressModeDhcpv6Stateless}, mode) {
+		return "", &apperrors.ErrInvalidArgument{
+			Field:  network.SubnetIpv6AddressModeMetadataKey,
+			Reason: fmt.Sprintf("must be one of %s, %s or %s: %s", ipv6AddressModeSlaac, ipv6AddressModeDhcpv6Stateful, ipv6AddressModeDhcpv6Stateless, mode),
+		}
+	}
+	return fmt.Sprintf("address_mode=%s,max_interval=30,min_interval=5,send_periodic=true", mode), nil
+}
+
+// nfvIpv6AddressModeFromKubeovn returns the address_mode of the kube-ovn
+// ipv6RAConfigs, or "" when IPv6 router advertisements are disabled.
+func nfvIpv6AddressModeFromKubeovn(spec *kubeovnv1.SubnetSpec) string {
+	if !spec.EnableIPv6RA {
+		return ""
+	}
+	for _, option := range strings.Split(spec.IPv6RAConfigs, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(option), "="); ok && key == "address_mode" {
+			return value
+		}
+	}
+	return ipv6AddressModeDhcpv6Stateful
+}
+
 // Returns the kubeovn Subnet k8s object or error if convertation from the
-// NetworkSubnetData structure failed.
+// NetworkSubnetData structure failed. A comma-joined IPv4 and IPv6 cidr (and
+// gateway) makes a dual-stack subnet; the ip version, when set, must be one of
+// the cidr families. The subnet options are read from the metadata.
 func kubeovnSubnetFromNfvSubnetData(name string, nfvSubnet *vivnfm.NetworkSubnetData) (*kubeovnv1.Subnet, error) {
 	if len(name) == 0 {
 		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "cannot be empty"}
@@ -184,10 +387,6 @@ func kubeovnSubnetFromNfvSubnetData(name string, nfvSubnet *vivnfm.NetworkSubnet
 	if nfvSubnet == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "subnet data", Reason: "cannot be nil"}
 	}
-	ipProto, err := kubeovnIpVersionFromNfv(nfvSubnet.IpVersion)
-	if err != nil {
-		return nil, fmt.Errorf("convert nfv IPVersion: %w", err)
-	}
 	var enableDhcp bool
 	if nfvSubnet.IsDhcpEnabled == nil || *nfvSubnet.IsDhcpEnabled == true {
 		enableDhcp = true
@@ -197,8 +396,19 @@ func kubeovnSubnetFromNfvSubnetData(name string, nfvSubnet *vivnfm.NetworkSubnet
 	if nfvSubnet.Cidr == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "cidr", Reason: "must be specified for NetworkSubnetData"}
 	}
-	if _, _, err := net.ParseCIDR(nfvSubnet.Cidr.GetCidr()); err != nil {
-		return nil, fmt.Errorf("parse cidr '%s': %w", nfvSubnet.Cidr.GetCidr(), err)
+	prefixes, err := parseSubnetCidrs(nfvSubnet.Cidr.GetCidr())
+	if err != nil {
+		return nil, err
+	}
+	ipProto := kubeovnProtocolFromPrefixes(prefixes)
+	if nfvSubnet.IpVersion != nil {
+		version, err := kubeovnIpVersionFromNfv(nfvSubnet.IpVersion)
+		if err != nil {
+			return nil, fmt.Errorf("convert nfv IPVersion: %w", err)
+		}
+		if version != ipProto && ipProto != kubeovnv1.ProtocolDual {
+			return nil, &apperrors.ErrInvalidArgument{Field: "ip version", Reason: fmt.Sprintf("%s does not match cidr %s", version, nfvSubnet.Cidr.GetCidr())}
+		}
 	}
 
 	sub := &kubeovnv1.Subnet{
@@ -212,28 +422,162 @@ func kubeovnSubnetFromNfvSubnetData(name string, nfvSubnet *vivnfm.NetworkSubnet
 		Spec: kubeovnv1.SubnetSpec{
 			Protocol:   ipProto,
 			EnableDHCP: enableDhcp,
-			CIDRBlock:  nfvSubnet.Cidr.GetCidr(),
+			CIDRBlock:  joinPrefixes(prefixes),
 			Default:    false,
-			// Only distributed gateway currently supported
-			GatewayType: "distributed",
-			// Enable outgoing NAT by default
-			NatOutgoing: true,
 		},
 	}
 	if nfvSubnet.GatewayIp != nil {
-		if ip := net.ParseIP(nfvSubnet.GatewayIp.GetIp()); ip == nil {
-			return nil, &apperrors.ErrInvalidArgument{Field: "gateway ip", Reason: fmt.Sprintf("invalid format: %s", nfvSubnet.GatewayIp.GetIp())}
+		if sub.Spec.Gateway, err = kubeovnGatewayFromNfv(nfvSubnet.GatewayIp.GetIp(), prefixes); err != nil {
+			return nil, err
+		}
+	}
+	if ipProto != kubeovnv1.ProtocolIPv4 && enableDhcp {
+		raConfigs, err := kubeovnIpv6RaConfigs(nfvSubnet.Metadata)
+		if err != nil {
+			return nil, err
 		}
-		sub.Spec.Gateway = nfvSubnet.GatewayIp.GetIp()
+		sub.Spec.EnableIPv6RA = true
+		sub.Spec.IPv6RAConfigs = raConfigs
+	}
+	if err := kubeovnSubnetOptionsFromNfv(sub, prefixes, nfvSubnet.Metadata); err != nil {
+		return nil, err
 	}
 	if nfvSubnet.AddressPool != nil {
-		// Not yet supported
+		excludeIps, err := kubeovnExcludeIpsFromAddressPool(prefixes, nfvSubnet.AddressPool)
+		if err != nil {
+			return nil, fmt.Errorf("convert address pool: %w", err)
+		}
+		sub.Spec.ExcludeIps = excludeIps
 	}
 	return sub, nil
 }
 
+// applyNfvSubnetUpdate applies the fields set in the NetworkSubnetData to the
+// kubeovn Subnet. The cidr, ip version and network are immutable and may only
+// be repeated. The subnet options metadata is merged into the current options,
+// a key with an empty value resets the option. A new address pool replaces the
+// excludeIps of its address family.
+func applyNfvSubnetUpdate(sub *kubeovnv1.Subnet, nfvSubnet *vivnfm.NetworkSubnetData) error {
+	if nfvSubnet == nil {
+		return &apperrors.ErrInvalidArgument{Field: "subnet data", Reason: "cannot be nil"}
+	}
+	prefixes, err := parseSubnetCidrs(sub.Spec.CIDRBlock)
+	if err != nil {
+		return fmt.Errorf("parse subnet cidr: %w", err)
+	}
+	if nfvSubnet.Cidr != nil {
+		requested, err := parseSubnetCidrs(nfvSubnet.Cidr.GetCidr())
+		if err != nil {
+			return err
+		}
+		if joinPrefixes(requested) != joinPrefixes(prefixes) {
+			return &apperrors.ErrInvalidArgument{Field: "cidr", Reason: fmt.Sprintf("is immutable: subnet cidr is %s", sub.Spec.CIDRBlock)}
+		}
+	}
+	if nfvSubnet.IpVersion != nil {
+		version, err := kubeovnIpVersionFromNfv(nfvSubnet.IpVersion)
+		if err != nil {
+			return fmt.Errorf("convert nfv IPVersion: %w", err)
+		}
+		if version != sub.Spec.Protocol && sub.Spec.Protocol != kubeovnv1.ProtocolDual {
+			return &apperrors.ErrInvalidArgument{Field: "ip version", Reason: fmt.Sprintf("is immutable: subnet protocol is %s", sub.Spec.Protocol)}
+		}
+	}
+	if networkId := nfvSubnet.NetworkId.GetValue(); networkId != "" &&
+		networkId != sub.Labels[network.K8sNetworkIdLabel] && networkId != sub.Labels[network.K8sNetworkNameLabel] {
+		return &apperrors.ErrInvalidArgument{Field: "networkId", Reason: fmt.Sprintf("is immutable: subnet belongs to network '%s'", sub.Labels[network.K8sNetworkIdLabel])}
+	}
+
+	fields := map[string]string{}
+	if mode := nfvIpv6AddressModeFromKubeovn(&sub.Spec); mode != "" {
+		fields[network.SubnetIpv6AddressModeMetadataKey] = mode
+	}
+	nfvSubnetOptionsFromKubeovn(sub, fields)
+	for key, value := range nfvSubnet.Metadata.GetFields() {
+		if value == "" {
+			delete(fields, key)
+			continue
+		}
+		fields[key] = value
+	}
+	gateway := sub.Spec.Gateway
+	if nfvSubnet.GatewayIp != nil {
+		gateway = nfvSubnet.GatewayIp.GetIp()
+	}
+	enableDhcp := sub.Spec.EnableDHCP
+	if nfvSubnet.IsDhcpEnabled != nil {
+		enableDhcp = *nfvSubnet.IsDhcpEnabled
+	}
+	data := &vivnfm.NetworkSubnetData{
+		Cidr:          &nfvcommon.IPSubnetCIDR{Cidr: sub.Spec.CIDRBlock},
+		IsDhcpEnabled: &enableDhcp,
+		AddressPool:   nfvSubnet.AddressPool,
+		Metadata:      &nfvcommon.Metadata{Fields: fields},
+	}
+	if gateway != "" {
+		data.GatewayIp = &nfvcommon.IPAddress{Ip: gateway}
+	}
+	desired, err := kubeovnSubnetFromNfvSubnetData(sub.Name, data)
+	if err != nil {
+		return err
+	}
+
+	// kube-ovn excludes the gateway itself; the exclusion of a replaced gateway
+	// is dropped so that the address returns to the pool.
+	oldGateways := network.SplitDualStack(sub.Spec.Gateway)
+	newGateways := network.SplitDualStack(desired.Spec.Gateway)
+	excludeIps := make([]string, 0, len(sub.Spec.ExcludeIps))
+	for _, excludeIp := range sub.Spec.ExcludeIps {
+		if slices.Contains(oldGateways, excludeIp) && !slices.Contains(newGateways, excludeIp) {
+			continue
+		}
+		if nfvSubnet.AddressPool != nil && excludeIpFamilyIs4(excludeIp) == addressPoolIs4(nfvSubnet.AddressPool) {
+			continue
+		}
+		excludeIps = append(excludeIps, excludeIp)
+	}
+	sub.Spec.ExcludeIps = append(excludeIps, desired.Spec.ExcludeIps...)
+
+	if desired.Spec.Gateway != "" {
+		sub.Spec.Gateway = desired.Spec.Gateway
+	}
+	sub.Spec.EnableDHCP = desired.Spec.EnableDHCP
+	sub.Spec.EnableIPv6RA = desired.Spec.EnableIPv6RA
+	sub.Spec.IPv6RAConfigs = desired.Spec.IPv6RAConfigs
+	sub.Spec.DHCPv4Options = desired.Spec.DHCPv4Options
+	sub.Spec.DHCPv6Options = desired.Spec.DHCPv6Options
+	sub.Spec.NatOutgoing = desired.Spec.NatOutgoing
+	sub.Spec.GatewayType = desired.Spec.GatewayType
+	sub.Spec.GatewayNode = desired.Spec.GatewayNode
+	sub.Spec.Mtu = desired.Spec.Mtu
+	if bandwidth, ok := desired.Annotations[network.SubnetBandwidthMetadataKey]; ok {
+		if sub.Annotations == nil {
+			sub.Annotations = map[string]string{}
+		}
+		sub.Annotations[network.SubnetBandwidthMetadataKey] = bandwidth
+	} else {
+		delete(sub.Annotations, network.SubnetBandwidthMetadataKey)
+	}
+	return nil
+}
+
+// excludeIpFamilyIs4 reports whether the kube-ovn excludeIps entry is an IPv4
+// address or range.
+func excludeIpFamilyIs4(excludeIp string) bool {
+	first, _, _ := strings.Cut(excludeIp, "..")
+	addr, err := netip.ParseAddr(strings.TrimSpace(first))
+	return err == nil && addr.Unmap().Is4()
+}
+
+func addressPoolIs4(pool *nfvcommon.IPAddressPool) bool {
+	addr, err := netip.ParseAddr(pool.GetStartIP().GetIp())
+	return err == nil && addr.Unmap().Is4()
+}
+
 // Converts the instantiated kubeovn Subnet resource to the vivnfm.NetworkSubnet.
-// TODO(dmalovan): Add address pool if it is exists
+// The address pools are reconstructed from the subnet excludeIps. A dual-stack
+// subnet reports its comma-joined cidr and gateway. The subnet options are
+// reported as metadata.
 func nfvNetworkSubnetFromKubeovnSubnet(kubeovnSub *kubeovnv1.Subnet) (*vivnfm.NetworkSubnet, error) {
 	if kubeovnSub == nil {
 		return nil, &apperrors.ErrInvalidArgument{Field: "subnet", Reason: "cannot be nil"}
@@ -259,6 +603,14 @@ func nfvNetworkSubnetFromKubeovnSubnet(kubeovnSub *kubeovnv1.Subnet) (*vivnfm.Ne
 	if err != nil {
 		return nil, fmt.Errorf("convert ip protocol from kubeovn resource spec: %w", err)
 	}
+	fields := maps.Clone(kubeovnSub.Labels)
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	if mode := nfvIpv6AddressModeFromKubeovn(&kubeovnSub.Spec); mode != "" {
+		fields[network.SubnetIpv6AddressModeMetadataKey] = mode
+	}
+	nfvSubnetOptionsFromKubeovn(kubeovnSub, fields)
 	return &vivnfm.NetworkSubnet{
 		ResourceId: misc.UIDToIdentifier(kubeovnSub.UID),
 		NetworkId:  optNetworkId,
@@ -270,12 +622,153 @@ func nfvNetworkSubnetFromKubeovnSubnet(kubeovnSub *kubeovnv1.Subnet) (*vivnfm.Ne
 			Cidr: kubeovnSub.Spec.CIDRBlock,
 		},
 		IsDhcpEnabled: kubeovnSub.Spec.EnableDHCP,
+		AddressPool:   nfvAddressPoolsFromKubeovnSubnet(kubeovnSub),
 		Metadata: &nfvcommon.Metadata{
-			Fields: kubeovnSub.Labels,
+			Fields: fields,
 		},
 	}, nil
 }
 
+// kubeovnExcludeIpsFromAddressPool returns the kube-ovn excludeIps that keep
+// the addresses of the cidr outside of the pool from dynamic allocation. The
+// pool applies to the cidr of its address family; the other family of a
+// dual-stack subnet is left whole. The network address (and the IPv4 broadcast
+// address) is never allocated, so it is left out of the ranges.
+func kubeovnExcludeIpsFromAddressPool(prefixes []netip.Prefix, pool *nfvcommon.IPAddressPool) ([]string, error) {
+	start, err := netip.ParseAddr(pool.GetStartIP().GetIp())
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "address pool start ip", Reason: fmt.Sprintf("invalid format: %s", pool.GetStartIP().GetIp())}
+	}
+	end, err := netip.ParseAddr(pool.GetEndIP().GetIp())
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "address pool end ip", Reason: fmt.Sprintf("invalid format: %s", pool.GetEndIP().GetIp())}
+	}
+	start, end = start.Unmap(), end.Unmap()
+	prefix, ok := prefixOfFamily(prefixes, start)
+	if !ok || !prefix.Contains(start) || !prefix.Contains(end) {
+		return nil, &apperrors.ErrInvalidArgument{Field: "address pool", Reason: fmt.Sprintf("range %s-%s is not within cidr %s", start, end, joinPrefixes(prefixes))}
+	}
+	first, last := allocatableRange(prefix)
+	if start.Compare(first) < 0 || end.Compare(last) > 0 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "address pool", Reason: fmt.Sprintf("range %s-%s must be within the allocatable range %s-%s", start, end, first, last)}
+	}
+	if start.Compare(end) > 0 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "address pool", Reason: fmt.Sprintf("start ip %s is after end ip %s", start, end)}
+	}
+	excludeIps := make([]string, 0, 2)
+	if start != first {
+		excludeIps = append(excludeIps, formatKubeovnIpRange(first, start.Prev()))
+	}
+	if end != last {
+		excludeIps = append(excludeIps, formatKubeovnIpRange(end.Next(), last))
+	}
+	return excludeIps, nil
+}
+
+// nfvAddressPoolsFromKubeovnSubnet returns the ranges of the subnet cidr that
+// kube-ovn allocates from, i.e. the complement of the subnet excludeIps, for
+// each address family with exclusions. The gateway, which kube-ovn adds to
+// excludeIps on its own, does not split a pool. A subnet without exclusions has
+// no address pool.
+func nfvAddressPoolsFromKubeovnSubnet(kubeovnSub *kubeovnv1.Subnet) []*nfvcommon.IPAddressPool {
+	prefixes, err := parseSubnetCidrs(kubeovnSub.Spec.CIDRBlock)
+	if err != nil {
+		return nil
+	}
+	gateways := network.SplitDualStack(kubeovnSub.Spec.Gateway)
+	var pools []*nfvcommon.IPAddressPool
+	for _, prefix := range prefixes {
+		pools = append(pools, addressPoolsOfPrefix(prefix, kubeovnSub.Spec.ExcludeIps, gateways)...)
+	}
+	return pools
+}
+
+// addressPoolsOfPrefix returns the complement of the excludeIps of the prefix
+// family within the allocatable range of the prefix.
+func addressPoolsOfPrefix(prefix netip.Prefix, excludeIps []string, gateways []string) []*nfvcommon.IPAddressPool {
+	type ipRange struct{ start, end netip.Addr }
+	excluded := make([]ipRange, 0, len(excludeIps))
+	for _, excludeIp := range excludeIps {
+		if slices.Contains(gateways, excludeIp) {
+			continue
+		}
+		startStr, endStr, isRange := strings.Cut(excludeIp, "..")
+		if !isRange {
+			endStr = startStr
+		}
+		start, err := netip.ParseAddr(startStr)
+		if err != nil || start.BitLen() != prefix.Addr().BitLen() {
+			continue
+		}
+		end, err := netip.ParseAddr(endStr)
+		if err != nil || end.BitLen() != prefix.Addr().BitLen() || start.Compare(end) > 0 {
+			continue
+		}
+		excluded = append(excluded, ipRange{start: start, end: end})
+	}
+	if len(excluded) == 0 {
+		return nil
+	}
+	slices.SortFunc(excluded, func(a, b ipRange) int { return a.start.Compare(b.start) })
+
+	var pools []*nfvcommon.IPAddressPool
+	next, last := allocatableRange(prefix)
+	for _, r := range excluded {
+		if r.start.Compare(next) > 0 && next.Compare(last) <= 0 {
+			poolEnd := r.start.Prev()
+			if poolEnd.Compare(last) > 0 {
+				poolEnd = last
+			}
+			pools = append(pools, &nfvcommon.IPAddressPool{
+				StartIP: &nfvcommon.IPAddress{Ip: next.String()},
+				EndIP:   &nfvcommon.IPAddress{Ip: poolEnd.String()},
+			})
+		}
+		if r.end.Compare(next) >= 0 {
+			next = r.end.Next()
+			if !next.IsValid() {
+				return pools
+			}
+		}
+	}
+	if next.Compare(last) <= 0 {
+		pools = append(pools, &nfvcommon.IPAddressPool{
+			StartIP: &nfvcommon.IPAddress{Ip: next.String()},
+			EndIP:   &nfvcommon.IPAddress{Ip: last.String()},
+		})
+	}
+	return pools
+}
+
+// allocatableRange returns the first and the last address of the prefix that can
+// be allocated: the network address is skipped, and so is the broadcast address
+// of an IPv4 prefix.
+func allocatableRange(prefix netip.Prefix) (netip.Addr, netip.Addr) {
+	prefix = prefix.Masked()
+	first := prefix.Addr()
+	last := first
+	for i := prefix.Bits(); i < first.BitLen(); i++ {
+		b := last.AsSlice()
+		b[i/8] |= 0x80 >> (i % 8)
+		last, _ = netip.AddrFromSlice(b)
+	}
+	if first.Is4() && prefix.Bits() < 31 {
+		last = last.Prev()
+	}
+	if prefix.Bits() < first.BitLen()-1 {
+		first = first.Next()
+	}
+	return first, last
+}
+
+// formatKubeovnIpRange returns the kube-ovn excludeIps notation of the range.
+func formatKubeovnIpRange(start, end netip.Addr) string {
+	if start == end {
+		return start.String()
+	}
+	return start.String() + ".." + end.String()
+}
+
 func formatSubnetName(networkName string, subnetName string) string {
 	return fmt.Sprintf("%s-subnet-%s", networkName, subnetName)
 }
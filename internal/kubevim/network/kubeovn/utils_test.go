@@ -8,7 +8,9 @@ import (
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
 	common "github.com/kube-nfv/kube-vim/internal/config"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -155,8 +157,13 @@ func TestKubeovnIpVersionErrors(t *testing.T) {
 	_, err = nfvIpversionFromKubeovn("")
 	assert.Error(t, err)
 
-	_, err = nfvIpversionFromKubeovn("Dual")
+	_, err = nfvIpversionFromKubeovn("IPv5")
 	assert.Error(t, err)
+
+	// The ETSI IP version has no dual-stack value: a Dual subnet reports IPv4.
+	dual, err := nfvIpversionFromKubeovn("Dual")
+	require.NoError(t, err)
+	assert.Equal(t, nfvcommon.IPVersion_IPV4, *dual)
 }
 
 func TestKubeovnSubnetFromNfvSubnetData(t *testing.T) {
@@ -205,6 +212,170 @@ func TestKubeovnSubnetFromNfvSubnetData(t *testing.T) {
 	})
 }
 
+func TestKubeovnDualStackSubnet(t *testing.T) {
+	v4 := nfvcommon.IPVersion_IPV4
+	v6 := nfvcommon.IPVersion_IPV6
+
+	t.Run("dual-stack subnet with router advertisements", func(t *testing.T) {
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{
+			Cidr:        &nfvcommon.IPSubnetCIDR{Cidr: "fd00::/64, 10.0.0.0/24"},
+			GatewayIp:   &nfvcommon.IPAddress{Ip: "fd00::1,10.0.0.1"},
+			AddressPool: addressPool("fd00::100", "fd00::ffff"),
+			Metadata:    &nfvcommon.Metadata{Fields: map[string]string{network.SubnetIpv6AddressModeMetadataKey: "dhcpv6_stateless"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Dual", sub.Spec.Protocol)
+		assert.Equal(t, "10.0.0.0/24,fd00::/64", sub.Spec.CIDRBlock, "IPv4 first")
+		assert.Equal(t, "10.0.0.1,fd00::1", sub.Spec.Gateway)
+		assert.True(t, sub.Spec.EnableIPv6RA)
+		assert.Equal(t, "address_mode=dhcpv6_stateless,max_interval=30,min_interval=5,send_periodic=true", sub.Spec.IPv6RAConfigs)
+		assert.Equal(t, []string{"fd00::1..fd00::ff", "fd00::1:0..fd00::ffff:ffff:ffff:ffff"}, sub.Spec.ExcludeIps, "the pool applies to its family")
+
+		sub.ObjectMeta = managedMeta("sub1")
+		sub.Spec.ExcludeIps = append(sub.Spec.ExcludeIps, "10.0.0.1", "fd00::1")
+		got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+		require.NoError(t, err)
+		assert.Equal(t, nfvcommon.IPVersion_IPV4, got.IpVersion)
+		assert.Equal(t, "10.0.0.0/24,fd00::/64", got.Cidr.GetCidr())
+		assert.Equal(t, "10.0.0.1,fd00::1", got.GatewayIp.GetIp())
+		assert.Equal(t, "dhcpv6_stateless", got.Metadata.GetFields()[network.SubnetIpv6AddressModeMetadataKey])
+		assert.NotContains(t, sub.Labels, network.SubnetIpv6AddressModeMetadataKey, "the subnet labels are not modified")
+		assert.Equal(t, []*nfvcommon.IPAddressPool{addressPool("fd00::100", "fd00::ffff")}, got.AddressPool)
+	})
+
+	t.Run("ipv6-only subnet defaults to stateful DHCPv6", func(t *testing.T) {
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{
+			IpVersion: &v6,
+			Cidr:      &nfvcommon.IPSubnetCIDR{Cidr: "fd00::/64"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "IPv6", sub.Spec.Protocol)
+		assert.Contains(t, sub.Spec.IPv6RAConfigs, "address_mode=dhcpv6_stateful")
+
+		dhcp := false
+		sub, err = kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{
+			Cidr:          &nfvcommon.IPSubnetCIDR{Cidr: "fd00::/64"},
+			IsDhcpEnabled: &dhcp,
+		})
+		require.NoError(t, err)
+		assert.False(t, sub.Spec.EnableIPv6RA, "no router advertisements without DHCP")
+	})
+
+	t.Run("ip version is derived from the cidr", func(t *testing.T) {
+		sub, err := kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24"}})
+		require.NoError(t, err)
+		assert.Equal(t, "IPv4", sub.Spec.Protocol)
+		assert.False(t, sub.Spec.EnableIPv6RA)
+
+		sub, err = kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{IpVersion: &v6, Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}})
+		require.NoError(t, err)
+		assert.Equal(t, "Dual", sub.Spec.Protocol)
+	})
+
+	t.Run("invalid dual-stack data is rejected", func(t *testing.T) {
+		for name, data := range map[string]*vivnfm.NetworkSubnetData{
+			"ip version mismatch":       {IpVersion: &v4, Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "fd00::/64"}},
+			"two cidrs of one family":   {Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,10.1.0.0/24"}},
+			"three cidrs":               {Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64,fd01::/64"}},
+			"gateway of one family":     {Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}, GatewayIp: &nfvcommon.IPAddress{Ip: "10.0.0.1"}},
+			"gateway outside the cidr":  {Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}, GatewayIp: &nfvcommon.IPAddress{Ip: "10.0.0.1,fd01::1"}},
+			"unknown ipv6 address mode": {Cidr: &nfvcommon.IPSubnetCIDR{Cidr: "fd00::/64"}, Metadata: &nfvcommon.Metadata{Fields: map[string]string{network.SubnetIpv6AddressModeMetadataKey: "manual"}}},
+		} {
+			_, err := kubeovnSubnetFromNfvSubnetData("sub1", data)
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+}
+
+func addressPool(start, end string) *nfvcommon.IPAddressPool {
+	return &nfvcommon.IPAddressPool{StartIP: &nfvcommon.IPAddress{Ip: start}, EndIP: &nfvcommon.IPAddress{Ip: end}}
+}
+
+func TestKubeovnSubnetAddressPool(t *testing.T) {
+	v4 := nfvcommon.IPVersion_IPV4
+	v6 := nfvcommon.IPVersion_IPV6
+	tests := []struct {
+		name       string
+		ipVersion  *nfvcommon.IPVersion
+		cidr       string
+		pool       *nfvcommon.IPAddressPool
+		excludeIps []string
+	}{
+		{"first addresses kept out", &v4, "10.0.0.0/24", addressPool("10.0.0.21", "10.0.0.254"), []string{"10.0.0.1..10.0.0.20"}},
+		{"last addresses kept out", &v4, "10.0.0.0/24", addressPool("10.0.0.1", "10.0.0.99"), []string{"10.0.0.100..10.0.0.254"}},
+		{"both ends kept out", &v4, "10.0.0.0/24", addressPool("10.0.0.3", "10.0.0.253"), []string{"10.0.0.1..10.0.0.2", "10.0.0.254"}},
+		{"whole range", &v4, "10.0.0.0/24", addressPool("10.0.0.1", "10.0.0.254"), []string{}},
+		{"ipv6", &v6, "fd00::/120", addressPool("fd00::10", "fd00::ff"), []string{"fd00::1..fd00::f"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{
+				IpVersion:   tt.ipVersion,
+				Cidr:        &nfvcommon.IPSubnetCIDR{Cidr: tt.cidr},
+				AddressPool: tt.pool,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.excludeIps, sub.Spec.ExcludeIps)
+
+			// The pool is reconstructed from excludeIps, with the gateway kube-ovn adds.
+			sub.ObjectMeta = managedMeta("sub1")
+			sub.Spec.Gateway = "10.0.0.1"
+			if *tt.ipVersion == v6 {
+				sub.Spec.Gateway = "fd00::1"
+			}
+			sub.Spec.ExcludeIps = append(sub.Spec.ExcludeIps, sub.Spec.Gateway)
+			got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+			require.NoError(t, err)
+			if len(tt.excludeIps) == 0 {
+				assert.Empty(t, got.AddressPool, "a subnet without exclusions has no pool")
+				return
+			}
+			require.Len(t, got.AddressPool, 1)
+			assert.Equal(t, tt.pool.GetStartIP().GetIp(), got.AddressPool[0].GetStartIP().GetIp())
+			assert.Equal(t, tt.pool.GetEndIP().GetIp(), got.AddressPool[0].GetEndIP().GetIp())
+		})
+	}
+
+	t.Run("invalid pools are rejected", func(t *testing.T) {
+		for name, pool := range map[string]*nfvcommon.IPAddressPool{
+			"bad start":           addressPool("bad", "10.0.0.20"),
+			"missing end":         {StartIP: &nfvcommon.IPAddress{Ip: "10.0.0.1"}},
+			"outside the cidr":    addressPool("10.0.0.10", "10.0.1.10"),
+			"network address":     addressPool("10.0.0.0", "10.0.0.10"),
+			"broadcast address":   addressPool("10.0.0.10", "10.0.0.255"),
+			"start after the end": addressPool("10.0.0.20", "10.0.0.10"),
+		} {
+			_, err := kubeovnSubnetFromNfvSubnetData("sub1", &vivnfm.NetworkSubnetData{
+				IpVersion:   &v4,
+				Cidr:        &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24"},
+				AddressPool: pool,
+			})
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+
+	t.Run("several exclusions give several pools", func(t *testing.T) {
+		sub := &kubeovnv1.Subnet{
+			ObjectMeta: managedMeta("sub1"),
+			Spec: kubeovnv1.SubnetSpec{
+				Protocol:   "IPv4",
+				CIDRBlock:  "10.0.0.0/24",
+				Gateway:    "10.0.0.1",
+				ExcludeIps: []string{"10.0.0.1", "10.0.0.100..10.0.0.199", "10.0.0.50", "10.0.0.250..10.0.0.255", "not-an-ip"},
+			},
+		}
+		got, err := nfvNetworkSubnetFromKubeovnSubnet(sub)
+		require.NoError(t, err)
+		assert.Equal(t, []*nfvcommon.IPAddressPool{
+			addressPool("10.0.0.1", "10.0.0.49"),
+			addressPool("10.0.0.51", "10.0.0.99"),
+			addressPool("10.0.0.200", "10.0.0.249"),
+		}, got.AddressPool)
+	})
+}
+
 func TestNfvNetworkSubnetFromKubeovnSubnet(t *testing.T) {
 	t.Run("valid", func(t *testing.T) {
 		sub := &kubeovnv1.Subnet{
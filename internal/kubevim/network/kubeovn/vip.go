@@ -0,0 +1,245 @@
+package kubeovn
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (m *manager) CreateVip(ctx context.Context, vip *network.Vip) (*network.Vip, error) {
+	if vip == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "vip", Reason: "cannot be nil"}
+	}
+	if vip.Name == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "name", Reason: "cannot be empty"}
+	}
+	if vip.SubnetId.GetValue() == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "subnetId", Reason: "cannot be empty"}
+	}
+	subnet, err := m.GetSubnet(ctx, network.GetSubnetByUid(vip.SubnetId))
+	if err != nil {
+		return nil, fmt.Errorf("get subnet '%s' of vip '%s': %w", vip.SubnetId.GetValue(), vip.Name, err)
+	}
+	subnetName := subnet.Metadata.Fields[network.K8sSubnetNameLabel]
+	kubeovnSub := &kubeovnv1.Subnet{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: subnetName}, kubeovnSub); err != nil {
+		return nil, fmt.Errorf("get kubeovn subnet '%s': %w", subnetName, err)
+	}
+	prefixes, err := parseSubnetCidrs(kubeovnSub.Spec.CIDRBlock)
+	if err != nil {
+		return nil, fmt.Errorf("parse cidr of kubeovn subnet '%s': %w", subnetName, err)
+	}
+	kubeovnVip := &kubeovnv1.Vip{
+		ObjectMeta: v1.ObjectMeta{
+			Name: vip.Name,
+			Labels: map[string]string{
+				common.K8sManagedByLabel: common.KubeNfvName,
+			},
+		},
+		Spec: kubeovnv1.VipSpec{
+			Namespace: *m.k8sCfg.Namespace,
+			Subnet:    subnetName,
+		},
+	}
+	for _, ipStr := range vip.IpAddresses {
+		ip, err := netip.ParseAddr(ipStr)
+		if err != nil {
+			return nil, &apperrors.ErrInvalidArgument{Field: "ipAddresses", Reason: fmt.Sprintf("invalid IP address: '%s'", ipStr)}
+		}
+		prefix, ok := prefixOfFamily(prefixes, ip)
+		if !ok || !prefix.Contains(ip) {
+			return nil, &apperrors.ErrInvalidArgument{Field: "ipAddresses", Reason: fmt.Sprintf("'%s' is not in subnet '%s' cidr '%s'", ipStr, subnetName, kubeovnSub.Spec.CIDRBlock)}
+		}
+		family := &kubeovnVip.Spec.V4ip
+		if ip.Is6() {
+			family = &kubeovnVip.Spec.V6ip
+		}
+		if *family != "" {
+			return nil, &apperrors.ErrInvalidArgument{Field: "ipAddresses", Reason: "at most one address per IP family"}
+		}
+		*family = ip.String()
+	}
+	if err := m.client.Create(ctx, kubeovnVip); err != nil {
+		return nil, fmt.Errorf("create kube-ovn Vip '%s': %w", kubeovnVip.Name, err)
+	}
+	return nfvVipFromKubeovn(kubeovnVip, subnet.ResourceId)
+}
+
+func (m *manager) GetVip(ctx context.Context, opts ...network.GetVipOpt) (*network.Vip, error) {
+	vip, err := m.getKubeovnVip(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.nfvVipFromKubeovn(ctx, vip)
+}
+
+func (m *manager) getKubeovnVip(ctx context.Context, opts ...network.GetVipOpt) (*kubeovnv1.Vip, error) {
+	cfg := network.ApplyGetVipOpts(opts...)
+	if cfg.Name != "" {
+		vip := &kubeovnv1.Vip{}
+		if err := m.client.Get(ctx, client.ObjectKey{Name: cfg.Name}, vip); err != nil {
+			return nil, fmt.Errorf("get kube-ovn Vip '%s': %w", cfg.Name, err)
+		}
+		if !misc.IsObjectManagedByKubeNfv(vip) {
+			return nil, &apperrors.ErrK8sObjectNotManagedByKubeNfv{ObjectType: "Vip", ObjectName: vip.Name, ObjectId: string(vip.UID)}
+		}
+		return vip, nil
+	}
+	if cfg.Uid == nil || cfg.Uid.Value == "" {
+		return nil, &apperrors.ErrInvalidArgument{Field: "vip identifier", Reason: "either name or uid must be specified"}
+	}
+	vipList := &kubeovnv1.VipList{}
+	if err := m.client.List(ctx, vipList, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return nil, fmt.Errorf("list kube-ovn Vips for id '%s': %w", cfg.Uid.Value, err)
+	}
+	uid := misc.IdentifierToUID(cfg.Uid)
+	for idx := range vipList.Items {
+		if vipList.Items[idx].GetUID() == uid {
+			return &vipList.Items[idx], nil
+		}
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "kube-ovn Vip", Identifier: cfg.Uid.GetValue()}
+}
+
+func (m *manager) ListVips(ctx context.Context) ([]*network.Vip, error) {
+	vipList := &kubeovnv1.VipList{}
+	if err := m.client.List(ctx, vipList, client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return nil, fmt.Errorf("list kube-ovn Vips: %w", err)
+	}
+	res := make([]*network.Vip, 0, len(vipList.Items))
+	for idx := range vipList.Items {
+		vip, err := m.nfvVipFromKubeovn(ctx, &vipList.Items[idx])
+		if err != nil {
+			return nil, fmt.Errorf("convert kube-ovn Vip '%s': %w", vipList.Items[idx].Name, err)
+		}
+		res = append(res, vip)
+	}
+	return res, nil
+}
+
+// Detaches the kube-ovn Vip from every vNIC, then deletes it.
+func (m *manager) DeleteVip(ctx context.Context, opts ...network.GetVipOpt) error {
+	vip, err := m.getKubeovnVip(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	if err := m.detachVip(ctx, vip.Name); err != nil {
+		return fmt.Errorf("detach kube-ovn Vip '%s' from vNICs: %w", vip.Name, err)
+	}
+	if err := m.client.Delete(ctx, &kubeovnv1.Vip{ObjectMeta: v1.ObjectMeta{Name: vip.Name}}); err != nil {
+		return fmt.Errorf("delete kube-ovn Vip '%s': %w", vip.Name, err)
+	}
+	return nil
+}
+
+// Removes the vip from the aaps annotations of the VirtualMachine templates,
+// so that it is not re-attached on restart, and of their virt-launcher pods,
+// so that kube-ovn drops it from the running vNICs.
+func (m *manager) detachVip(ctx context.Context, vipName string) error {
+	namespace := *m.k8sCfg.Namespace
+	vmList := &kubevirtv1.VirtualMachineList{}
+	if err := m.apiReader.List(ctx, vmList, client.InNamespace(namespace), client.MatchingLabels{common.K8sManagedByLabel: common.KubeNfvName}); err != nil {
+		return fmt.Errorf("list kubevirt VirtualMachines: %w", err)
+	}
+	for idx := range vmList.Items {
+		vm := &vmList.Items[idx]
+		if vm.Spec.Template == nil {
+			continue
+		}
+		base := vm.DeepCopy()
+		if !removeVipFromAnnotations(vm.Spec.Template.ObjectMeta.Annotations, vipName) {
+			continue
+		}
+		if err := m.client.Patch(ctx, vm, client.MergeFrom(base)); err != nil {
+			return fmt.Errorf("patch kubevirt VirtualMachine '%s': %w", vm.Name, err)
+		}
+		podList := &corev1.PodList{}
+		if err := m.apiReader.List(ctx, podList, client.InNamespace(namespace), client.MatchingLabels{kubevirtv1.VirtualMachineNameLabel: vm.Name}); err != nil {
+			return fmt.Errorf("list virt-launcher pods of VirtualMachine '%s': %w", vm.Name, err)
+		}
+		for podIdx := range podList.Items {
+			pod := &podList.Items[podIdx]
+			podBase := pod.DeepCopy()
+			if !removeVipFromAnnotations(pod.Annotations, vipName) {
+				continue
+			}
+			if err := m.client.Patch(ctx, pod, client.MergeFrom(podBase)); err != nil {
+				return fmt.Errorf("patch virt-launcher pod '%s': %w", pod.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Removes the vip from every aaps annotation, deleting the annotations left
+// empty, and reports whether any changed.
+func removeVipFromAnnotations(annotations map[string]string, vipName string) bool {
+	changed := false
+	for key, value := range annotations {
+		if !strings.HasSuffix(key, network.KubeovnAapsAnnotationSuffix) {
+			continue
+		}
+		vips := strings.Split(value, ",")
+		kept := slices.DeleteFunc(slices.Clone(vips), func(v string) bool { return v == vipName })
+		if len(kept) == len(vips) {
+			continue
+		}
+		changed = true
+		if len(kept) == 0 {
+			delete(annotations, key)
+		} else {
+			annotations[key] = strings.Join(kept, ",")
+		}
+	}
+	return changed
+}
+
+// Converts the kube-ovn Vip, resolving the id of its subnet.
+func (m *manager) nfvVipFromKubeovn(ctx context.Context, vip *kubeovnv1.Vip) (*network.Vip, error) {
+	subnet := &kubeovnv1.Subnet{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: vip.Spec.Subnet}, subnet); err != nil {
+		return nil, fmt.Errorf("get kubeovn subnet '%s' of Vip '%s': %w", vip.Spec.Subnet, vip.Name, err)
+	}
+	return nfvVipFromKubeovn(vip, misc.UIDToIdentifier(subnet.UID))
+}
+
+func nfvVipFromKubeovn(vip *kubeovnv1.Vip, subnetId *nfvcommon.Identifier) (*network.Vip, error) {
+	if !misc.IsObjectInstantiated(vip) {
+		return nil, &apperrors.ErrK8sObjectNotInstantiated{ObjectType: "Vip", Identifier: vip.Name}
+	}
+	// kube-ovn reports the reserved addresses once it allocated them.
+	v4ip, v6ip := vip.Status.V4ip, vip.Status.V6ip
+	if v4ip == "" && v6ip == "" {
+		v4ip, v6ip = vip.Spec.V4ip, vip.Spec.V6ip
+	}
+	var ips []string
+	for _, ip := range []string{v4ip, v6ip} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	mac := vip.Status.Mac
+	if mac == "" {
+		mac = vip.Spec.MacAddress
+	}
+	return &network.Vip{
+		VipId:       misc.UIDToIdentifier(vip.UID),
+		Name:        vip.Name,
+		SubnetId:    subnetId,
+		IpAddresses: ips,
+		MacAddress:  mac,
+	}, nil
+}
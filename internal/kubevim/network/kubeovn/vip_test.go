@@ -0,0 +1,126 @@
+package kubeovn
+
+import (
+	"context"
+	"testing"
+
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func seedVip(name, subnet string) *kubeovnv1.Vip {
+	return &kubeovnv1.Vip{
+		ObjectMeta: managedMeta(name),
+		Spec:       kubeovnv1.VipSpec{Namespace: testNamespace, Subnet: subnet},
+		Status:     kubeovnv1.VipStatus{V4ip: "10.0.0.100", Mac: "00:00:00:aa:bb:cc"},
+	}
+}
+
+func TestCreateVip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("reserves the requested address in the subnet", func(t *testing.T) {
+		m, cl := newManager(t, seedSubnet("sub1"))
+		vip, err := m.CreateVip(ctx, &network.Vip{Name: "vrrp", SubnetId: k8stest.ID("uid-sub1"), IpAddresses: []string{"10.0.0.100"}})
+		require.NoError(t, err)
+		assert.Equal(t, &network.Vip{
+			VipId:       k8stest.ID("uid-vrrp"),
+			Name:        "vrrp",
+			SubnetId:    k8stest.ID("uid-sub1"),
+			IpAddresses: []string{"10.0.0.100"},
+		}, vip)
+
+		stored := &kubeovnv1.Vip{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "vrrp"}, stored))
+		assert.Equal(t, kubeovnv1.VipSpec{Namespace: testNamespace, Subnet: "sub1", V4ip: "10.0.0.100"}, stored.Spec)
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		m, _ := newManager(t, seedSubnet("sub1"))
+		for name, vip := range map[string]*network.Vip{
+			"no name":              {SubnetId: k8stest.ID("uid-sub1")},
+			"no subnet":            {Name: "vrrp"},
+			"invalid address":      {Name: "vrrp", SubnetId: k8stest.ID("uid-sub1"), IpAddresses: []string{"10.0.0"}},
+			"address not in cidr":  {Name: "vrrp", SubnetId: k8stest.ID("uid-sub1"), IpAddresses: []string{"10.1.0.100"}},
+			"family not in subnet": {Name: "vrrp", SubnetId: k8stest.ID("uid-sub1"), IpAddresses: []string{"fd00::100"}},
+			"two of a family":      {Name: "vrrp", SubnetId: k8stest.ID("uid-sub1"), IpAddresses: []string{"10.0.0.100", "10.0.0.101"}},
+		} {
+			_, err := m.CreateVip(ctx, vip)
+			var target *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &target, name)
+		}
+	})
+}
+
+func TestGetVip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	m, _ := newManager(t, seedSubnet("sub1"), seedVip("vrrp", "sub1"),
+		&kubeovnv1.Vip{ObjectMeta: metav1.ObjectMeta{Name: "foreign", UID: "uid-foreign"}})
+
+	byName, err := m.GetVip(ctx, network.GetVipByName("vrrp"))
+	require.NoError(t, err)
+	assert.Equal(t, &network.Vip{
+		VipId:       k8stest.ID("uid-vrrp"),
+		Name:        "vrrp",
+		SubnetId:    k8stest.ID("uid-sub1"),
+		IpAddresses: []string{"10.0.0.100"},
+		MacAddress:  "00:00:00:aa:bb:cc",
+	}, byName)
+	byUid, err := m.GetVip(ctx, network.GetVipByUid(k8stest.ID("uid-vrrp")))
+	require.NoError(t, err)
+	assert.Equal(t, byName, byUid)
+
+	_, err = m.GetVip(ctx, network.GetVipByName("foreign"))
+	var notManaged *apperrors.ErrK8sObjectNotManagedByKubeNfv
+	assert.ErrorAs(t, err, &notManaged)
+
+	vips, err := m.ListVips(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*network.Vip{byName}, vips)
+}
+
+func TestDeleteVip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	aaps := formatNetAttachName("sub1") + "." + testNamespace + network.KubeovnAapsAnnotationSuffix
+	attached := func(name, vips string) *kubevirtv1.VirtualMachine {
+		vm := seedVm(name, formatNetAttachName("sub1"))
+		vm.Spec.Template.ObjectMeta.Annotations = map[string]string{aaps: vips}
+		return vm
+	}
+	launcher := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "virt-launcher-vm1-abcde",
+		Namespace:   testNamespace,
+		Labels:      map[string]string{kubevirtv1.VirtualMachineNameLabel: "vm1"},
+		Annotations: map[string]string{aaps: "vrrp"},
+	}}
+	m, cl := newManager(t, seedSubnet("sub1"), seedVip("vrrp", "sub1"),
+		attached("vm1", "vrrp"), attached("vm2", "other,vrrp"), attached("vm3", "other"), launcher)
+
+	require.NoError(t, m.DeleteVip(ctx, network.GetVipByUid(k8stest.ID("uid-vrrp"))))
+
+	err := cl.Get(ctx, client.ObjectKey{Name: "vrrp"}, &kubeovnv1.Vip{})
+	assert.True(t, apierrors.IsNotFound(err), "vip should be gone")
+	annotationsOf := func(name string) map[string]string {
+		vm := &kubevirtv1.VirtualMachine{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: name}, vm))
+		return vm.Spec.Template.ObjectMeta.Annotations
+	}
+	assert.NotContains(t, annotationsOf("vm1"), aaps)
+	assert.Equal(t, "other", annotationsOf("vm2")[aaps])
+	assert.Equal(t, "other", annotationsOf("vm3")[aaps])
+	pod := &corev1.Pod{}
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(launcher), pod))
+	assert.NotContains(t, pod.Annotations, aaps)
+}
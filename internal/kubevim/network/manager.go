@@ -3,10 +3,12 @@ package network
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
 	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 )
 
 const (
@@ -19,18 +21,103 @@ const (
 	K8sSubnetNetAttachNameLabel  = "network.kubevim.kubenfv.io/subnet-netattach-name"
 )
 
+const (
+	// SubnetIpv6AddressModeMetadataKey is the subnet metadata key selecting how the
+	// VMs of an IPv6 or dual-stack subnet get their IPv6 address: slaac,
+	// dhcpv6_stateful (the default) or dhcpv6_stateless.
+	SubnetIpv6AddressModeMetadataKey = "network.kubevim.kubenfv.io/ipv6-address-mode"
+	// SubnetDnsServersMetadataKey is the subnet metadata key holding the
+	// comma-separated DNS servers pushed to the VMs by DHCP.
+	SubnetDnsServersMetadataKey = "network.kubevim.kubenfv.io/dns-servers"
+	// SubnetHostRoutesMetadataKey is the subnet metadata key holding the
+	// comma-separated <destination>=<nexthop> static routes pushed to the VMs
+	// by DHCP.
+	SubnetHostRoutesMetadataKey = "network.kubevim.kubenfv.io/host-routes"
+	// SubnetMtuMetadataKey is the subnet metadata key holding the MTU of the
+	// subnet, also pushed to the VMs by DHCP.
+	SubnetMtuMetadataKey = "network.kubevim.kubenfv.io/mtu"
+	// SubnetNatOutgoingMetadataKey is the subnet metadata key enabling
+	// ("true", the default) or disabling the NAT of the outgoing traffic.
+	SubnetNatOutgoingMetadataKey = "network.kubevim.kubenfv.io/nat-outgoing"
+	// SubnetGatewayTypeMetadataKey is the subnet metadata key selecting the
+	// distributed (the default) or centralized subnet gateway.
+	SubnetGatewayTypeMetadataKey = "network.kubevim.kubenfv.io/gateway-type"
+	// SubnetGatewayNodeMetadataKey is the subnet metadata key holding the
+	// comma-separated nodes of a centralized gateway.
+	SubnetGatewayNodeMetadataKey = "network.kubevim.kubenfv.io/gateway-node"
+	// SubnetBandwidthMetadataKey is the subnet metadata key holding the default
+	// and maximum bandwidth, in Mbps, of each vNIC of the subnet.
+	SubnetBandwidthMetadataKey = "network.kubevim.kubenfv.io/bandwidth"
+
+	// NetworkStaticRoutesMetadataKey is the overlay network metadata key holding
+	// the comma-separated <destination>=<nexthop> static routes of the network
+	// router.
+	NetworkStaticRoutesMetadataKey = "network.kubevim.kubenfv.io/static-routes"
+	// NetworkPolicyRoutesMetadataKey is the overlay network metadata key holding
+	// the comma-separated <priority>:<source>[><destination>]=<nexthop> policy
+	// routes of the network router, rerouting the traffic from the source cidr
+	// (to the destination cidr) to the nexthop.
+	NetworkPolicyRoutesMetadataKey = "network.kubevim.kubenfv.io/policy-routes"
+	// NetworkPeeringsMetadataKey is the overlay network metadata key holding the
+	// comma-separated names or ids of the overlay networks peered with the
+	// network.
+	NetworkPeeringsMetadataKey = "network.kubevim.kubenfv.io/peerings"
+
+	// UpdateResourceIdMetadataKey is the request metadata holding the id of the
+	// network or subnet an update applies to.
+	UpdateResourceIdMetadataKey = "kubevim-network-resource-id"
+	// DeleteCascadeMetadataKey ("true") terminates the computes attached to a
+	// network or subnet before deleting it.
+	DeleteCascadeMetadataKey = "kubevim-delete-cascade"
+)
+
+// ErrInUse is returned when deleting a network or subnet that computes still
+// have vNICs on. It unwraps to ErrFailedPrecondition.
+type ErrInUse struct {
+	Resource   string
+	ComputeIds []*nfvcommon.Identifier
+}
+
+func (e *ErrInUse) Error() string {
+	return e.Unwrap().Error()
+}
+
+func (e *ErrInUse) Unwrap() error {
+	ids := make([]string, 0, len(e.ComputeIds))
+	for _, id := range e.ComputeIds {
+		ids = append(ids, id.GetValue())
+	}
+	return &apperrors.ErrFailedPrecondition{
+		Resource: e.Resource,
+		Reason:   fmt.Sprintf("in use by %d compute(s): %s", len(ids), strings.Join(ids, ", ")),
+	}
+}
+
 //go:generate go run go.uber.org/mock/mockgen -source=manager.go -destination=mock/mock_manager.go -package=mock
 
 type Manager interface {
 	CreateNetwork(context.Context, string /*name*/, *vivnfm.VirtualNetworkData) (*vivnfm.VirtualNetwork, error)
 	GetNetwork(context.Context, ...GetNetworkOpt) (*vivnfm.VirtualNetwork, error)
 	ListNetworks(context.Context) ([]*vivnfm.VirtualNetwork, error)
+	// DeleteNetwork deletes the network and its subnets. It fails with ErrInUse,
+	// deleting nothing, while computes are attached to any of them.
 	DeleteNetwork(context.Context, ...GetNetworkOpt) error
+	// UpdateNetwork applies the mutable fields of the network data to an
+	// existing network: its metadata. Changing an immutable field, like the
+	// network type or segmentation id, fails with ErrInvalidArgument.
+	UpdateNetwork(context.Context, *vivnfm.VirtualNetworkData, ...GetNetworkOpt) (*vivnfm.VirtualNetwork, error)
 
 	CreateSubnet(context.Context, string /*name*/, *vivnfm.NetworkSubnetData) (*vivnfm.NetworkSubnet, error)
 	GetSubnet(context.Context, ...GetSubnetOpt) (*vivnfm.NetworkSubnet, error)
 	ListSubnets(context.Context) ([]*vivnfm.NetworkSubnet, error)
+	// DeleteSubnet deletes the subnet. It fails with ErrInUse while computes
+	// are attached to it.
 	DeleteSubnet(context.Context, ...GetSubnetOpt) error
+	// UpdateSubnet applies the fields set in the subnet data to an existing
+	// subnet: gateway, DHCP, address pool and the subnet options metadata. The
+	// cidr, ip version and network of a subnet are immutable; changing them
+	// fails with ErrInvalidArgument.
+	UpdateSubnet(context.Context, *vivnfm.NetworkSubnetData, ...GetSubnetOpt) (*vivnfm.NetworkSubnet, error)
 
 	// EnsureManagementNetwork is idempotent: it creates the Vpc + Subnet +
 	// NetworkAttachmentDefinition described by cfg if they do not exist, and
@@ -194,3 +194,43 @@ func (mr *MockManagerMockRecorder) ListSubnets(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubnets", reflect.TypeOf((*MockManager)(nil).ListSubnets), arg0)
 }
+
+// UpdateNetwork mocks base method.
+func (m *MockManager) UpdateNetwork(arg0 context.Context, arg1 *vivnfm.VirtualNetworkData, arg2 ...network.GetNetworkOpt) (*vivnfm.VirtualNetwork, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateNetwork", varargs...)
+	ret0, _ := ret[0].(*vivnfm.VirtualNetwork)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNetwork indicates an expected call of UpdateNetwork.
+func (mr *MockManagerMockRecorder) UpdateNetwork(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNetwork", reflect.TypeOf((*MockManager)(nil).UpdateNetwork), varargs...)
+}
+
+// UpdateSubnet mocks base method.
+func (m *MockManager) UpdateSubnet(arg0 context.Context, arg1 *vivnfm.NetworkSubnetData, arg2 ...network.GetSubnetOpt) (*vivnfm.NetworkSubnet, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateSubnet", varargs...)
+	ret0, _ := ret[0].(*vivnfm.NetworkSubnet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSubnet indicates an expected call of UpdateSubnet.
+func (mr *MockManagerMockRecorder) UpdateSubnet(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubnet", reflect.TypeOf((*MockManager)(nil).UpdateSubnet), varargs...)
+}
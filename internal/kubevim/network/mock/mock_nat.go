@@ -0,0 +1,195 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: nat.go
+//
+// Generated by this command:
+//
+//	mockgen -source=nat.go -destination=mock/mock_nat.go -package=mock
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	network "github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNatManager is a mock of NatManager interface.
+type MockNatManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockNatManagerMockRecorder
+	isgomock struct{}
+}
+
+// MockNatManagerMockRecorder is the mock recorder for MockNatManager.
+type MockNatManagerMockRecorder struct {
+	mock *MockNatManager
+}
+
+// NewMockNatManager creates a new mock instance.
+func NewMockNatManager(ctrl *gomock.Controller) *MockNatManager {
+	mock := &MockNatManager{ctrl: ctrl}
+	mock.recorder = &MockNatManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNatManager) EXPECT() *MockNatManagerMockRecorder {
+	return m.recorder
+}
+
+// CreateFloatingIp mocks base method.
+func (m *MockNatManager) CreateFloatingIp(arg0 context.Context, arg1 *network.FloatingIp) (*network.FloatingIp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFloatingIp", arg0, arg1)
+	ret0, _ := ret[0].(*network.FloatingIp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFloatingIp indicates an expected call of CreateFloatingIp.
+func (mr *MockNatManagerMockRecorder) CreateFloatingIp(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFloatingIp", reflect.TypeOf((*MockNatManager)(nil).CreateFloatingIp), arg0, arg1)
+}
+
+// CreateNatGateway mocks base method.
+func (m *MockNatManager) CreateNatGateway(arg0 context.Context, arg1 *network.NatGateway) (*network.NatGateway, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNatGateway", arg0, arg1)
+	ret0, _ := ret[0].(*network.NatGateway)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNatGateway indicates an expected call of CreateNatGateway.
+func (mr *MockNatManagerMockRecorder) CreateNatGateway(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNatGateway", reflect.TypeOf((*MockNatManager)(nil).CreateNatGateway), arg0, arg1)
+}
+
+// DeleteFloatingIp mocks base method.
+func (m *MockNatManager) DeleteFloatingIp(arg0 context.Context, arg1 ...network.GetNatOpt) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteFloatingIp", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFloatingIp indicates an expected call of DeleteFloatingIp.
+func (mr *MockNatManagerMockRecorder) DeleteFloatingIp(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFloatingIp", reflect.TypeOf((*MockNatManager)(nil).DeleteFloatingIp), varargs...)
+}
+
+// DeleteNatGateway mocks base method.
+func (m *MockNatManager) DeleteNatGateway(arg0 context.Context, arg1 ...network.GetNatOpt) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteNatGateway", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteNatGateway indicates an expected call of DeleteNatGateway.
+func (mr *MockNatManagerMockRecorder) DeleteNatGateway(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNatGateway", reflect.TypeOf((*MockNatManager)(nil).DeleteNatGateway), varargs...)
+}
+
+// GetFloatingIp mocks base method.
+func (m *MockNatManager) GetFloatingIp(arg0 context.Context, arg1 ...network.GetNatOpt) (*network.FloatingIp, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetFloatingIp", varargs...)
+	ret0, _ := ret[0].(*network.FloatingIp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFloatingIp indicates an expected call of GetFloatingIp.
+func (mr *MockNatManagerMockRecorder) GetFloatingIp(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFloatingIp", reflect.TypeOf((*MockNatManager)(nil).GetFloatingIp), varargs...)
+}
+
+// GetNatGateway mocks base method.
+func (m *MockNatManager) GetNatGateway(arg0 context.Context, arg1 ...network.GetNatOpt) (*network.NatGateway, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetNatGateway", varargs...)
+	ret0, _ := ret[0].(*network.NatGateway)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNatGateway indicates an expected call of GetNatGateway.
+func (mr *MockNatManagerMockRecorder) GetNatGateway(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNatGateway", reflect.TypeOf((*MockNatManager)(nil).GetNatGateway), varargs...)
+}
+
+// ListFloatingIps mocks base method.
+func (m *MockNatManager) ListFloatingIps(arg0 context.Context) ([]*network.FloatingIp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFloatingIps", arg0)
+	ret0, _ := ret[0].([]*network.FloatingIp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFloatingIps indicates an expected call of ListFloatingIps.
+func (mr *MockNatManagerMockRecorder) ListFloatingIps(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFloatingIps", reflect.TypeOf((*MockNatManager)(nil).ListFloatingIps), arg0)
+}
+
+// ListNatGateways mocks base method.
+func (m *MockNatManager) ListNatGateways(arg0 context.Context) ([]*network.NatGateway, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNatGateways", arg0)
+	ret0, _ := ret[0].([]*network.NatGateway)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNatGateways indicates an expected call of ListNatGateways.
+func (mr *MockNatManagerMockRecorder) ListNatGateways(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNatGateways", reflect.TypeOf((*MockNatManager)(nil).ListNatGateways), arg0)
+}
+
+// UpdateFloatingIp mocks base method.
+func (m *MockNatManager) UpdateFloatingIp(arg0 context.Context, arg1 *network.FloatingIp) (*network.FloatingIp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFloatingIp", arg0, arg1)
+	ret0, _ := ret[0].(*network.FloatingIp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateFloatingIp indicates an expected call of UpdateFloatingIp.
+func (mr *MockNatManagerMockRecorder) UpdateFloatingIp(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFloatingIp", reflect.TypeOf((*MockNatManager)(nil).UpdateFloatingIp), arg0, arg1)
+}
@@ -0,0 +1,126 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: secgroup.go
+//
+// Generated by this command:
+//
+//	mockgen -source=secgroup.go -destination=mock/mock_secgroup.go -package=mock
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	network "github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSecurityGroupManager is a mock of SecurityGroupManager interface.
+type MockSecurityGroupManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecurityGroupManagerMockRecorder
+	isgomock struct{}
+}
+
+// MockSecurityGroupManagerMockRecorder is the mock recorder for MockSecurityGroupManager.
+type MockSecurityGroupManagerMockRecorder struct {
+	mock *MockSecurityGroupManager
+}
+
+// NewMockSecurityGroupManager creates a new mock instance.
+func NewMockSecurityGroupManager(ctrl *gomock.Controller) *MockSecurityGroupManager {
+	mock := &MockSecurityGroupManager{ctrl: ctrl}
+	mock.recorder = &MockSecurityGroupManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecurityGroupManager) EXPECT() *MockSecurityGroupManagerMockRecorder {
+	return m.recorder
+}
+
+// CreateSecurityGroup mocks base method.
+func (m *MockSecurityGroupManager) CreateSecurityGroup(arg0 context.Context, arg1 *network.SecurityGroup) (*network.SecurityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSecurityGroup", arg0, arg1)
+	ret0, _ := ret[0].(*network.SecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSecurityGroup indicates an expected call of CreateSecurityGroup.
+func (mr *MockSecurityGroupManagerMockRecorder) CreateSecurityGroup(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSecurityGroup", reflect.TypeOf((*MockSecurityGroupManager)(nil).CreateSecurityGroup), arg0, arg1)
+}
+
+// DeleteSecurityGroup mocks base method.
+func (m *MockSecurityGroupManager) DeleteSecurityGroup(arg0 context.Context, arg1 ...network.GetSecurityGroupOpt) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteSecurityGroup", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSecurityGroup indicates an expected call of DeleteSecurityGroup.
+func (mr *MockSecurityGroupManagerMockRecorder) DeleteSecurityGroup(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecurityGroup", reflect.TypeOf((*MockSecurityGroupManager)(nil).DeleteSecurityGroup), varargs...)
+}
+
+// GetSecurityGroup mocks base method.
+func (m *MockSecurityGroupManager) GetSecurityGroup(arg0 context.Context, arg1 ...network.GetSecurityGroupOpt) (*network.SecurityGroup, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetSecurityGroup", varargs...)
+	ret0, _ := ret[0].(*network.SecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecurityGroup indicates an expected call of GetSecurityGroup.
+func (mr *MockSecurityGroupManagerMockRecorder) GetSecurityGroup(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecurityGroup", reflect.TypeOf((*MockSecurityGroupManager)(nil).GetSecurityGroup), varargs...)
+}
+
+// ListSecurityGroups mocks base method.
+func (m *MockSecurityGroupManager) ListSecurityGroups(arg0 context.Context) ([]*network.SecurityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSecurityGroups", arg0)
+	ret0, _ := ret[0].([]*network.SecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSecurityGroups indicates an expected call of ListSecurityGroups.
+func (mr *MockSecurityGroupManagerMockRecorder) ListSecurityGroups(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecurityGroups", reflect.TypeOf((*MockSecurityGroupManager)(nil).ListSecurityGroups), arg0)
+}
+
+// UpdateSecurityGroup mocks base method.
+func (m *MockSecurityGroupManager) UpdateSecurityGroup(arg0 context.Context, arg1 *network.SecurityGroup) (*network.SecurityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSecurityGroup", arg0, arg1)
+	ret0, _ := ret[0].(*network.SecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSecurityGroup indicates an expected call of UpdateSecurityGroup.
+func (mr *MockSecurityGroupManagerMockRecorder) UpdateSecurityGroup(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSecurityGroup", reflect.TypeOf((*MockSecurityGroupManager)(nil).UpdateSecurityGroup), arg0, arg1)
+}
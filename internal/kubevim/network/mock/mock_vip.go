@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: vip.go
+//
+// Generated by this command:
+//
+//	mockgen -source=vip.go -destination=mock/mock_vip.go -package=mock
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	network "github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockVipManager is a mock of VipManager interface.
+type MockVipManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockVipManagerMockRecorder
+	isgomock struct{}
+}
+
+// MockVipManagerMockRecorder is the mock recorder for MockVipManager.
+type MockVipManagerMockRecorder struct {
+	mock *MockVipManager
+}
+
+// NewMockVipManager creates a new mock instance.
+func NewMockVipManager(ctrl *gomock.Controller) *MockVipManager {
+	mock := &MockVipManager{ctrl: ctrl}
+	mock.recorder = &MockVipManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVipManager) EXPECT() *MockVipManagerMockRecorder {
+	return m.recorder
+}
+
+// CreateVip mocks base method.
+func (m *MockVipManager) CreateVip(arg0 context.Context, arg1 *network.Vip) (*network.Vip, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVip", arg0, arg1)
+	ret0, _ := ret[0].(*network.Vip)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVip indicates an expected call of CreateVip.
+func (mr *MockVipManagerMockRecorder) CreateVip(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVip", reflect.TypeOf((*MockVipManager)(nil).CreateVip), arg0, arg1)
+}
+
+// DeleteVip mocks base method.
+func (m *MockVipManager) DeleteVip(arg0 context.Context, arg1 ...network.GetVipOpt) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteVip", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVip indicates an expected call of DeleteVip.
+func (mr *MockVipManagerMockRecorder) DeleteVip(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVip", reflect.TypeOf((*MockVipManager)(nil).DeleteVip), varargs...)
+}
+
+// GetVip mocks base method.
+func (m *MockVipManager) GetVip(arg0 context.Context, arg1 ...network.GetVipOpt) (*network.Vip, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetVip", varargs...)
+	ret0, _ := ret[0].(*network.Vip)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVip indicates an expected call of GetVip.
+func (mr *MockVipManagerMockRecorder) GetVip(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVip", reflect.TypeOf((*MockVipManager)(nil).GetVip), varargs...)
+}
+
+// ListVips mocks base method.
+func (m *MockVipManager) ListVips(arg0 context.Context) ([]*network.Vip, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVips", arg0)
+	ret0, _ := ret[0].([]*network.Vip)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVips indicates an expected call of ListVips.
+func (mr *MockVipManagerMockRecorder) ListVips(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVips", reflect.TypeOf((*MockVipManager)(nil).ListVips), arg0)
+}
@@ -0,0 +1,90 @@
+package network
+
+import (
+	"context"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+)
+
+// NetworkFloatingIpsMetadataKey is the overlay network metadata key reporting
+// the comma-separated <port address>=<external address> floating IPs bound to
+// the ports of the network.
+const NetworkFloatingIpsMetadataKey = "network.kubevim.kubenfv.io/floating-ips"
+
+// NatGateway connects an overlay network to the external network. The traffic
+// the subnets of SnatSubnetIds send out is source NATed to ExternalIpAddress.
+// kube-vim-api has no NAT gateway message yet, so the type is kube-vim's own
+// and travels as JSON.
+type NatGateway struct {
+	NatGatewayId *nfvcommon.Identifier `json:"natGatewayId,omitempty"`
+	Name         string                `json:"name,omitempty"`
+	// NetworkId is the overlay network the gateway connects.
+	NetworkId *nfvcommon.Identifier `json:"networkId,omitempty"`
+	// SubnetId and LanIpAddress are the subnet and address the gateway is
+	// attached to the network with. Only the iptables NAT backend, which runs
+	// the gateway as a pod, uses them, and requires both.
+	SubnetId     *nfvcommon.Identifier `json:"subnetId,omitempty"`
+	LanIpAddress string                `json:"lanIpAddress,omitempty"`
+	// SnatSubnetIds are the subnets of the network whose outgoing traffic is
+	// source NATed.
+	SnatSubnetIds []*nfvcommon.Identifier `json:"snatSubnetIds,omitempty"`
+	// ExternalIpAddress is the external address allocated to the gateway.
+	ExternalIpAddress string `json:"externalIpAddress,omitempty"`
+}
+
+// FloatingIp is an external IPv4 address allocated through a NAT gateway, that
+// is NATed to and from the port address it is bound to.
+// kube-vim-api has no floating IP message yet, so the type is kube-vim's own
+// and travels as JSON.
+type FloatingIp struct {
+	FloatingIpId *nfvcommon.Identifier `json:"floatingIpId,omitempty"`
+	Name         string                `json:"name,omitempty"`
+	NatGatewayId *nfvcommon.Identifier `json:"natGatewayId,omitempty"`
+	// IpAddress requests the external address. Unset, it is allocated.
+	IpAddress string `json:"ipAddress,omitempty"`
+	// PortIpAddress is the address, in a subnet of the gateway network, of the
+	// port the floating IP is bound to. Empty, the floating IP is unbound.
+	PortIpAddress string `json:"portIpAddress,omitempty"`
+}
+
+//go:generate go run go.uber.org/mock/mockgen -source=nat.go -destination=mock/mock_nat.go -package=mock
+
+// NatManager is implemented by network managers that can connect overlay
+// networks to the external network.
+type NatManager interface {
+	CreateNatGateway(context.Context, *NatGateway) (*NatGateway, error)
+	GetNatGateway(context.Context, ...GetNatOpt) (*NatGateway, error)
+	ListNatGateways(context.Context) ([]*NatGateway, error)
+	// DeleteNatGateway deletes the gateway. It fails with ErrFailedPrecondition
+	// while floating IPs are allocated through it.
+	DeleteNatGateway(context.Context, ...GetNatOpt) error
+
+	CreateFloatingIp(context.Context, *FloatingIp) (*FloatingIp, error)
+	GetFloatingIp(context.Context, ...GetNatOpt) (*FloatingIp, error)
+	ListFloatingIps(context.Context) ([]*FloatingIp, error)
+	// UpdateFloatingIp binds the floating IP with the FloatingIpId to the
+	// PortIpAddress, or unbinds it when PortIpAddress is empty.
+	UpdateFloatingIp(context.Context, *FloatingIp) (*FloatingIp, error)
+	DeleteFloatingIp(context.Context, ...GetNatOpt) error
+}
+
+// GetNatOpt identifies a NAT gateway or a floating IP.
+type GetNatOpt func(*getNatOpts)
+type getNatOpts struct {
+	Name string
+	Uid  *nfvcommon.Identifier
+}
+
+func GetNatByName(name string) GetNatOpt {
+	return func(gno *getNatOpts) { gno.Name = name }
+}
+func GetNatByUid(uid *nfvcommon.Identifier) GetNatOpt {
+	return func(gno *getNatOpts) { gno.Uid = uid }
+}
+func ApplyGetNatOpts(gno ...GetNatOpt) *getNatOpts {
+	res := &getNatOpts{}
+	for _, opt := range gno {
+		opt(res)
+	}
+	return res
+}
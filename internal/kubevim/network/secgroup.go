@@ -0,0 +1,85 @@
+package network
+
+import (
+	"context"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+)
+
+// Directions of a security group rule.
+const (
+	SecurityGroupRuleIngress = "ingress"
+	SecurityGroupRuleEgress  = "egress"
+)
+
+// Protocols of a security group rule.
+const (
+	SecurityGroupRuleProtocolAll  = "all"
+	SecurityGroupRuleProtocolTcp  = "tcp"
+	SecurityGroupRuleProtocolUdp  = "udp"
+	SecurityGroupRuleProtocolIcmp = "icmp"
+)
+
+// SecurityGroup is a named set of allow rules for the traffic of the vNICs it is
+// bound to. Traffic matched by no rule of the groups of a vNIC is dropped.
+// kube-vim-api has no security group message yet, so the type is kube-vim's own
+// and travels as JSON.
+type SecurityGroup struct {
+	SecurityGroupId *nfvcommon.Identifier `json:"securityGroupId,omitempty"`
+	Name            string                `json:"name,omitempty"`
+	// Rules of the group. In an update, nil keeps the current rules and an
+	// empty list removes them all.
+	Rules []*SecurityGroupRule `json:"rules"`
+}
+
+// SecurityGroupRule allows the traffic of one direction, protocol and port range
+// from (ingress) or to (egress) a remote CIDR.
+type SecurityGroupRule struct {
+	// Direction is ingress or egress.
+	Direction string `json:"direction"`
+	// Protocol is all (the default), tcp, udp or icmp.
+	Protocol string `json:"protocol,omitempty"`
+	// PortRangeMin and PortRangeMax bound the tcp or udp destination ports. Unset,
+	// the rule matches every port.
+	PortRangeMin int32 `json:"portRangeMin,omitempty"`
+	PortRangeMax int32 `json:"portRangeMax,omitempty"`
+	// RemoteCidr is the IPv4 or IPv6 CIDR of the peers. Its family is the family
+	// of the rule.
+	RemoteCidr string `json:"remoteCidr"`
+}
+
+//go:generate go run go.uber.org/mock/mockgen -source=secgroup.go -destination=mock/mock_secgroup.go -package=mock
+
+// SecurityGroupManager is implemented by network managers that can manage
+// security groups.
+type SecurityGroupManager interface {
+	CreateSecurityGroup(context.Context, *SecurityGroup) (*SecurityGroup, error)
+	GetSecurityGroup(context.Context, ...GetSecurityGroupOpt) (*SecurityGroup, error)
+	ListSecurityGroups(context.Context) ([]*SecurityGroup, error)
+	// UpdateSecurityGroup replaces the rules of an existing group. The name of a
+	// group is immutable.
+	UpdateSecurityGroup(context.Context, *SecurityGroup) (*SecurityGroup, error)
+	// DeleteSecurityGroup deletes the group. It fails with ErrInUse while vNICs
+	// are bound to it.
+	DeleteSecurityGroup(context.Context, ...GetSecurityGroupOpt) error
+}
+
+type GetSecurityGroupOpt func(*getSecurityGroupOpts)
+type getSecurityGroupOpts struct {
+	Name string
+	Uid  *nfvcommon.Identifier
+}
+
+func GetSecurityGroupByName(name string) GetSecurityGroupOpt {
+	return func(gso *getSecurityGroupOpts) { gso.Name = name }
+}
+func GetSecurityGroupByUid(uid *nfvcommon.Identifier) GetSecurityGroupOpt {
+	return func(gso *getSecurityGroupOpts) { gso.Uid = uid }
+}
+func ApplyGetSecurityGroupOpts(gso ...GetSecurityGroupOpt) *getSecurityGroupOpts {
+	res := &getSecurityGroupOpts{}
+	for _, opt := range gso {
+		opt(res)
+	}
+	return res
+}
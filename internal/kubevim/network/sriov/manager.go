@@ -167,6 +167,13 @@ func (m *manager) DeleteNetwork(ctx context.Context, opts ...network.GetNetworkO
 	return nil
 }
 
+func (m *manager) UpdateNetwork(ctx context.Context, _ *vivnfm.VirtualNetworkData, opts ...network.GetNetworkOpt) (*vivnfm.VirtualNetwork, error) {
+	if _, err := m.GetNetwork(ctx, opts...); err != nil {
+		return nil, fmt.Errorf("get SR-IOV network: %w", err)
+	}
+	return nil, fmt.Errorf("SR-IOV network update: %w", apperrors.ErrUnsupported)
+}
+
 func (m *manager) CreateSubnet(_ context.Context, _ string, _ *vivnfm.NetworkSubnetData) (*vivnfm.NetworkSubnet, error) {
 	return nil, fmt.Errorf("SR-IOV networks do not support subnets: %w", apperrors.ErrUnsupported)
 }
@@ -183,6 +190,10 @@ func (m *manager) DeleteSubnet(_ context.Context, _ ...network.GetSubnetOpt) err
 	return fmt.Errorf("SR-IOV networks do not support subnets: %w", apperrors.ErrUnsupported)
 }
 
+func (m *manager) UpdateSubnet(_ context.Context, _ *vivnfm.NetworkSubnetData, _ ...network.GetSubnetOpt) (*vivnfm.NetworkSubnet, error) {
+	return nil, fmt.Errorf("SR-IOV networks do not support subnets: %w", apperrors.ErrUnsupported)
+}
+
 func (m *manager) EnsureManagementNetwork(_ context.Context, _ *config.ManagementNetworkConfig) error {
 	// SR-IOV backend has no management-network concept.
 	return nil
@@ -2,21 +2,85 @@ package network
 
 import (
 	"net"
+	"net/netip"
+	"strings"
 
 	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
 )
 
+// SplitDualStack splits a comma-joined dual-stack value, e.g. the CIDR
+// "10.0.0.0/24,fd00::/64" or the address pair "10.0.0.5,fd00::5", into its
+// trimmed, non-empty parts.
+func SplitDualStack(value string) []string {
+	res := make([]string, 0, 2)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			res = append(res, part)
+		}
+	}
+	return res
+}
+
+// IpBelongsToCidr reports whether every address of ip is within one of the
+// CIDRs of cidr. Both may be comma-joined dual-stack values.
 func IpBelongsToCidr(ip *nfvcommon.IPAddress, cidr *nfvcommon.IPSubnetCIDR) bool {
 	if ip == nil || cidr == nil {
 		return false
 	}
-	parsedIP := net.ParseIP(ip.Ip)
-	if parsedIP == nil {
+	ipNets := make([]*net.IPNet, 0, 2)
+	for _, c := range SplitDualStack(cidr.Cidr) {
+		_, ipNet, err := net.ParseCIDR(c)
+		if err != nil {
+			return false
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	ips := SplitDualStack(ip.Ip)
+	if len(ips) == 0 || len(ipNets) == 0 {
+		return false
+	}
+	for _, ipStr := range ips {
+		parsedIP := net.ParseIP(ipStr)
+		if parsedIP == nil {
+			return false
+		}
+		contained := false
+		for _, ipNet := range ipNets {
+			if ipNet.Contains(parsedIP) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			return false
+		}
+	}
+	return true
+}
+
+// IpBelongsToAddressPools reports whether ip is within any of the address pools.
+// Pool bounds are inclusive.
+func IpBelongsToAddressPools(ip *nfvcommon.IPAddress, pools []*nfvcommon.IPAddressPool) bool {
+	if ip == nil {
 		return false
 	}
-	_, ipNet, err := net.ParseCIDR(cidr.Cidr)
+	addr, err := netip.ParseAddr(ip.Ip)
 	if err != nil {
 		return false
 	}
-	return ipNet.Contains(parsedIP)
+	addr = addr.Unmap()
+	for _, pool := range pools {
+		start, err := netip.ParseAddr(pool.GetStartIP().GetIp())
+		if err != nil {
+			continue
+		}
+		end, err := netip.ParseAddr(pool.GetEndIP().GetIp())
+		if err != nil {
+			continue
+		}
+		if start.Unmap().Compare(addr) <= 0 && addr.Compare(end.Unmap()) <= 0 {
+			return true
+		}
+	}
+	return false
 }
@@ -22,6 +22,11 @@ func TestIpBelongsToCidr(t *testing.T) {
 		{"nil cidr", &nfvcommon.IPAddress{Ip: "10.0.0.5"}, nil, false},
 		{"invalid ip", &nfvcommon.IPAddress{Ip: "not-an-ip"}, &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24"}, false},
 		{"invalid cidr", &nfvcommon.IPAddress{Ip: "10.0.0.5"}, &nfvcommon.IPSubnetCIDR{Cidr: "not-a-cidr"}, false},
+		{"dual-stack cidr ipv4", &nfvcommon.IPAddress{Ip: "10.0.0.5"}, &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}, true},
+		{"dual-stack cidr ipv6", &nfvcommon.IPAddress{Ip: "fd00::5"}, &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}, true},
+		{"address pair inside", &nfvcommon.IPAddress{Ip: "10.0.0.5, fd00::5"}, &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}, true},
+		{"address pair half outside", &nfvcommon.IPAddress{Ip: "10.0.0.5,fd01::5"}, &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24,fd00::/64"}, false},
+		{"address pair on single-stack cidr", &nfvcommon.IPAddress{Ip: "10.0.0.5,fd00::5"}, &nfvcommon.IPSubnetCIDR{Cidr: "10.0.0.0/24"}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -29,3 +34,38 @@ func TestIpBelongsToCidr(t *testing.T) {
 		})
 	}
 }
+
+func TestIpBelongsToAddressPools(t *testing.T) {
+	pool := func(start, end string) *nfvcommon.IPAddressPool {
+		return &nfvcommon.IPAddressPool{StartIP: &nfvcommon.IPAddress{Ip: start}, EndIP: &nfvcommon.IPAddress{Ip: end}}
+	}
+	pools := []*nfvcommon.IPAddressPool{pool("10.0.0.21", "10.0.0.100"), pool("10.0.0.200", "10.0.0.200")}
+	tests := []struct {
+		name  string
+		ip    *nfvcommon.IPAddress
+		pools []*nfvcommon.IPAddressPool
+		want  bool
+	}{
+		{"pool start", &nfvcommon.IPAddress{Ip: "10.0.0.21"}, pools, true},
+		{"pool end", &nfvcommon.IPAddress{Ip: "10.0.0.100"}, pools, true},
+		{"single address pool", &nfvcommon.IPAddress{Ip: "10.0.0.200"}, pools, true},
+		{"before the pools", &nfvcommon.IPAddress{Ip: "10.0.0.20"}, pools, false},
+		{"between the pools", &nfvcommon.IPAddress{Ip: "10.0.0.150"}, pools, false},
+		{"ipv6 inside", &nfvcommon.IPAddress{Ip: "2001:db8::10"}, []*nfvcommon.IPAddressPool{pool("2001:db8::1", "2001:db8::ff")}, true},
+		{"other family", &nfvcommon.IPAddress{Ip: "2001:db8::10"}, pools, false},
+		{"no pools", &nfvcommon.IPAddress{Ip: "10.0.0.50"}, nil, false},
+		{"nil ip", nil, pools, false},
+		{"invalid ip", &nfvcommon.IPAddress{Ip: "not-an-ip"}, pools, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IpBelongsToAddressPools(tt.ip, tt.pools))
+		})
+	}
+}
+
+func TestSplitDualStack(t *testing.T) {
+	assert.Equal(t, []string{"10.0.0.0/24", "fd00::/64"}, SplitDualStack("10.0.0.0/24, fd00::/64"))
+	assert.Equal(t, []string{"10.0.0.5"}, SplitDualStack("10.0.0.5"))
+	assert.Empty(t, SplitDualStack(" , "))
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"context"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+)
+
+// Vip is a virtual IP reserved in a subnet, that the vNICs of an active/standby
+// VNF pair attached to it may all send and receive traffic for as an allowed
+// address pair. kube-vim-api has no virtual IP message yet, so the type is
+// kube-vim's own and travels as JSON.
+type Vip struct {
+	VipId *nfvcommon.Identifier `json:"vipId,omitempty"`
+	Name  string                `json:"name,omitempty"`
+	// SubnetId is the subnet the address is reserved in.
+	SubnetId *nfvcommon.Identifier `json:"subnetId,omitempty"`
+	// IpAddresses requests at most one address per family of the subnet. Unset,
+	// the addresses are allocated. A queried VIP reports the reserved addresses.
+	IpAddresses []string `json:"ipAddresses,omitempty"`
+	MacAddress  string   `json:"macAddress,omitempty"`
+}
+
+// KubeovnAapsAnnotationSuffix ends the kube-ovn annotation of the
+// VirtualMachine template attaching virtual IPs to the vNIC of a provider
+// ("<netattach>.<namespace>") as allowed address pairs. The compute manager
+// sets it and the kube-ovn network manager detaches deleted VIPs from it.
+const KubeovnAapsAnnotationSuffix = ".ovn.kubernetes.io/aaps"
+
+//go:generate go run go.uber.org/mock/mockgen -source=vip.go -destination=mock/mock_vip.go -package=mock
+
+// VipManager is implemented by network managers that can manage virtual IPs.
+type VipManager interface {
+	CreateVip(context.Context, *Vip) (*Vip, error)
+	GetVip(context.Context, ...GetVipOpt) (*Vip, error)
+	ListVips(context.Context) ([]*Vip, error)
+	// DeleteVip detaches the virtual IP from every vNIC and deletes it.
+	DeleteVip(context.Context, ...GetVipOpt) error
+}
+
+type GetVipOpt func(*getVipOpts)
+type getVipOpts struct {
+	Name string
+	Uid  *nfvcommon.Identifier
+}
+
+func GetVipByName(name string) GetVipOpt {
+	return func(gvo *getVipOpts) { gvo.Name = name }
+}
+func GetVipByUid(uid *nfvcommon.Identifier) GetVipOpt {
+	return func(gvo *getVipOpts) { gvo.Uid = uid }
+}
+func ApplyGetVipOpts(gvo ...GetVipOpt) *getVipOpts {
+	res := &getVipOpts{}
+	for _, opt := range gvo {
+		opt(res)
+	}
+	return res
+}
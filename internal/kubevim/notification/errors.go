@@ -0,0 +1,49 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	// Register the notification module error converter
+	apperrors.RegisterErrorConverter(&NotificationErrorConverter{})
+}
+
+// ErrVersionExpired indicates that a resume version is no longer in the hub
+// backlog (too old, or issued by a previous kube-vim process). The client has to
+// re-list the resources and subscribe without a version.
+type ErrVersionExpired struct {
+	Version string
+}
+
+func (e *ErrVersionExpired) Error() string {
+	return fmt.Sprintf("version '%s' expired, re-list and subscribe again", e.Version)
+}
+
+// NotificationErrorConverter implements the ErrorConverter interface for notification module errors
+type NotificationErrorConverter struct{}
+
+// ConvertToGrpcError converts notification module specific errors to gRPC status errors
+func (c *NotificationErrorConverter) ConvertToGrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var versionExpired *ErrVersionExpired
+	if errors.As(err, &versionExpired) {
+		return status.Error(codes.OutOfRange, err.Error())
+	}
+
+	if errors.Is(err, event.ErrSlowSubscriber) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	// Return nil if this is not a notification module error (let main handler deal with it)
+	return nil
+}
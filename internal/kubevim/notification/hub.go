@@ -0,0 +1,208 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	filter "github.com/kube-nfv/query-filter"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBacklogSize bounds how many past events the hub keeps for resume.
+	DefaultBacklogSize = 1024
+
+	// subscriberBufferSize is the per-subscriber channel depth. A subscriber that
+	// falls further behind is dropped with ErrSlowSubscriber; it resumes from its
+	// last version marker, so nothing is lost as long as the backlog covers it.
+	subscriberBufferSize = 256
+)
+
+// Hub assigns version markers to published events, keeps a bounded backlog for
+// resume and fans events out to subscribers. It holds no resource state; the
+// cache informers feeding it (see RegisterInformers) are the source of truth.
+type Hub struct {
+	logger *zap.Logger
+	epoch  string
+
+	mu          sync.Mutex
+	seq         uint64
+	backlog     []event.Event
+	backlogSize int
+	subscribers map[*Subscription]struct{}
+}
+
+// SubscribeOptions selects what a subscription receives.
+type SubscribeOptions struct {
+	// Filter is a SOL 013 attribute filter ("filter=...") evaluated against
+	// Event. Empty matches everything.
+	Filter string
+	// FromVersion resumes after the event with this version marker. Empty
+	// starts with the next published event.
+	FromVersion string
+}
+
+// Subscription is a live event stream. Events is closed when the subscription
+// ends; Err then reports why (nil for a regular Close or context cancellation).
+type Subscription struct {
+	hub    *Hub
+	filter string
+	events chan event.Event
+	// done is closed together with events so the context watcher exits when
+	// the subscription ends on its own (Close, slow subscriber).
+	done chan struct{}
+	err  error
+}
+
+func NewHub(logger *zap.Logger, backlogSize int) *Hub {
+	if backlogSize <= 0 {
+		backlogSize = DefaultBacklogSize
+	}
+	return &Hub{
+		logger:      logger,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		backlog:     make([]event.Event, 0, backlogSize),
+		backlogSize: backlogSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish stamps each event with the next version marker, appends it to the
+// backlog and delivers it to every subscriber whose filter matches. It never
+// blocks on a subscriber.
+func (h *Hub) Publish(events ...event.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ev := range events {
+		h.seq++
+		ev.Version = formatVersion(h.epoch, h.seq)
+		if ev.Timestamp.IsZero() {
+			ev.Timestamp = time.Now()
+		}
+		if len(h.backlog) == h.backlogSize {
+			h.backlog = append(h.backlog[:0], h.backlog[1:]...)
+		}
+		h.backlog = append(h.backlog, ev)
+
+		for sub := range h.subscribers {
+			if !matches(sub.filter, ev) {
+				continue
+			}
+			select {
+			case sub.events <- ev:
+			default:
+				h.logger.Warn("drop slow notification subscriber", zap.String("lastVersion", ev.Version))
+				h.closeLocked(sub, event.ErrSlowSubscriber)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscription. With FromVersion set, backlog events after
+// that version are replayed first; a version that is no longer covered by the
+// backlog (or comes from another kube-vim process) returns ErrVersionExpired.
+// The subscription is closed when ctx is done.
+func (h *Hub) Subscribe(ctx context.Context, opts SubscribeOptions) (*Subscription, error) {
+	if _, err := filter.FilterList([]event.Event{{}}, opts.Filter); err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	replay, err := h.replayLocked(opts)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{
+		hub:    h,
+		filter: opts.Filter,
+		events: make(chan event.Event, subscriberBufferSize+len(replay)),
+		done:   make(chan struct{}),
+	}
+	for _, ev := range replay {
+		sub.events <- ev
+	}
+	h.subscribers[sub] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// replayLocked returns the backlog events after opts.FromVersion that match
+// opts.Filter. h.mu must be held.
+func (h *Hub) replayLocked(opts SubscribeOptions) ([]event.Event, error) {
+	if opts.FromVersion == "" {
+		return nil, nil
+	}
+	epoch, seq, err := parseVersion(opts.FromVersion)
+	if err != nil {
+		return nil, err
+	}
+	if epoch != h.epoch {
+		return nil, &ErrVersionExpired{Version: opts.FromVersion}
+	}
+	if seq > h.seq {
+		return nil, &apperrors.ErrInvalidArgument{Field: "version", Reason: fmt.Sprintf("version '%s' was never issued", opts.FromVersion)}
+	}
+	// The backlog holds seq range [h.seq-len+1, h.seq]. Resuming after seq needs
+	// seq+1 to still be in it (or nothing to have been published since).
+	oldest := h.seq - uint64(len(h.backlog)) + 1
+	if seq+1 < oldest {
+		return nil, &ErrVersionExpired{Version: opts.FromVersion}
+	}
+	replay := make([]event.Event, 0, h.seq-seq)
+	for _, ev := range h.backlog[seq+1-oldest:] {
+		if matches(opts.Filter, ev) {
+			replay = append(replay, ev)
+		}
+	}
+	return replay, nil
+}
+
+// Events returns the event stream. It is closed when the subscription ends.
+func (s *Subscription) Events() <-chan event.Event { return s.events }
+
+// Err reports why the subscription ended. Only valid once Events is closed.
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.closeLocked(s, nil)
+}
+
+func (h *Hub) closeLocked(sub *Subscription, err error) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+	close(sub.done)
+}
+
+// matches evaluates a subscription filter against a single event. The filter was
+// validated on subscribe, so an evaluation error is treated as no match.
+func matches(filterStr string, ev event.Event) bool {
+	if filterStr == "" {
+		return true
+	}
+	res, err := filter.FilterList([]event.Event{ev}, filterStr)
+	return err == nil && len(res) == 1
+}
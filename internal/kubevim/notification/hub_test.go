@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func computeEvent(id string, ct event.ChangeType) event.Event {
+	return event.Event{ChangeType: ct, ResourceType: event.ResourceTypeCompute, ResourceId: id, ResourceName: "vm-" + id}
+}
+
+// receive drains n events or fails the test after a short timeout.
+func receive(t *testing.T, sub *Subscription, n int) []event.Event {
+	t.Helper()
+	res := make([]event.Event, 0, n)
+	for len(res) < n {
+		select {
+		case ev, ok := <-sub.Events():
+			require.True(t, ok, "subscription closed after %d of %d events", len(res), n)
+			res = append(res, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for events", "got %d of %d", len(res), n)
+		}
+	}
+	return res
+}
+
+func TestHubPublishSubscribe(t *testing.T) {
+	h := NewHub(zap.NewNop(), 0)
+	sub, err := h.Subscribe(t.Context(), SubscribeOptions{})
+	require.NoError(t, err)
+
+	h.Publish(computeEvent("a", event.ChangeTypeCreated), computeEvent("a", event.ChangeTypeDeleted))
+	got := receive(t, sub, 2)
+	assert.Equal(t, event.ChangeTypeCreated, got[0].ChangeType)
+	assert.Equal(t, event.ChangeTypeDeleted, got[1].ChangeType)
+	assert.Equal(t, formatVersion(h.epoch, 1), got[0].Version)
+	assert.Equal(t, formatVersion(h.epoch, 2), got[1].Version)
+	assert.False(t, got[0].Timestamp.IsZero())
+}
+
+func TestHubFilter(t *testing.T) {
+	h := NewHub(zap.NewNop(), 0)
+	sub, err := h.Subscribe(t.Context(), SubscribeOptions{Filter: "filter=(eq,resourceType,COMPUTE);(eq,changeType,STATE_CHANGED)"})
+	require.NoError(t, err)
+
+	h.Publish(
+		computeEvent("a", event.ChangeTypeCreated),
+		event.Event{ChangeType: event.ChangeTypeStateChanged, ResourceType: event.ResourceTypeImage, ResourceId: "img"},
+		computeEvent("a", event.ChangeTypeStateChanged),
+	)
+	got := receive(t, sub, 1)
+	assert.Equal(t, "a", got[0].ResourceId)
+	assert.Equal(t, event.ChangeTypeStateChanged, got[0].ChangeType)
+	assert.Empty(t, sub.Events())
+}
+
+func TestHubSubscribeInvalidFilter(t *testing.T) {
+	h := NewHub(zap.NewNop(), 0)
+	for _, f := range []string{"filter=(eq,resourceType", "filter=(eq,noSuchField,x)"} {
+		_, err := h.Subscribe(t.Context(), SubscribeOptions{Filter: f})
+		var invalid *apperrors.ErrInvalidArgument
+		assert.ErrorAs(t, err, &invalid, f)
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	h := NewHub(zap.NewNop(), 3)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		h.Publish(computeEvent(id, event.ChangeTypeCreated))
+	}
+	// Backlog now holds b, c, d (seq 2..4).
+
+	t.Run("replays after version", func(t *testing.T) {
+		sub, err := h.Subscribe(t.Context(), SubscribeOptions{FromVersion: formatVersion(h.epoch, 2)})
+		require.NoError(t, err)
+		got := receive(t, sub, 2)
+		assert.Equal(t, "c", got[0].ResourceId)
+		assert.Equal(t, "d", got[1].ResourceId)
+	})
+	t.Run("oldest retained boundary", func(t *testing.T) {
+		sub, err := h.Subscribe(t.Context(), SubscribeOptions{FromVersion: formatVersion(h.epoch, 1)})
+		require.NoError(t, err)
+		assert.Len(t, receive(t, sub, 3), 3)
+	})
+	t.Run("latest version replays nothing", func(t *testing.T) {
+		sub, err := h.Subscribe(t.Context(), SubscribeOptions{FromVersion: formatVersion(h.epoch, 4)})
+		require.NoError(t, err)
+		assert.Empty(t, sub.Events())
+	})
+	t.Run("replay honours filter", func(t *testing.T) {
+		sub, err := h.Subscribe(t.Context(), SubscribeOptions{FromVersion: formatVersion(h.epoch, 1), Filter: "filter=(eq,resourceId,c)"})
+		require.NoError(t, err)
+		got := receive(t, sub, 1)
+		assert.Equal(t, "c", got[0].ResourceId)
+		assert.Empty(t, sub.Events())
+	})
+
+	expired := []struct {
+		name    string
+		version string
+	}{
+		{"evicted from backlog", formatVersion(h.epoch, 0)},
+		{"previous process", formatVersion("other", 3)},
+	}
+	for _, tt := range expired {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Subscribe(t.Context(), SubscribeOptions{FromVersion: tt.version})
+			var expiredErr *ErrVersionExpired
+			assert.ErrorAs(t, err, &expiredErr)
+		})
+	}
+
+	invalid := []struct {
+		name    string
+		version string
+	}{
+		{"malformed", "nope"},
+		{"not a number", h.epoch + "-x"},
+		{"never issued", formatVersion(h.epoch, 5)},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Subscribe(t.Context(), SubscribeOptions{FromVersion: tt.version})
+			var invalidErr *apperrors.ErrInvalidArgument
+			assert.ErrorAs(t, err, &invalidErr)
+		})
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	h := NewHub(zap.NewNop(), 0)
+	sub, err := h.Subscribe(t.Context(), SubscribeOptions{})
+	require.NoError(t, err)
+
+	for i := 0; i <= subscriberBufferSize; i++ {
+		h.Publish(computeEvent("a", event.ChangeTypeModified))
+	}
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, subscriberBufferSize, received)
+	assert.ErrorIs(t, sub.Err(), event.ErrSlowSubscriber)
+}
+
+func TestHubSubscriptionClosedOnContextDone(t *testing.T) {
+	h := NewHub(zap.NewNop(), 0)
+	ctx, cancel := context.WithCancel(t.Context())
+	sub, err := h.Subscribe(ctx, SubscribeOptions{})
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case _, ok := <-sub.Events():
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		require.FailNow(t, "subscription not closed on context cancellation")
+	}
+	assert.NoError(t, sub.Err())
+	// Closing again and publishing afterwards must not panic.
+	sub.Close()
+	h.Publish(computeEvent("a", event.ChangeTypeCreated))
+}
+
+func TestHubCloseReleasesContextWatcher(t *testing.T) {
+	h := NewHub(zap.NewNop(), 0)
+	before := runtime.NumGoroutine()
+	subs := make([]*Subscription, 0, 10)
+	for range 10 {
+		sub, err := h.Subscribe(t.Context(), SubscribeOptions{})
+		require.NoError(t, err)
+		subs = append(subs, sub)
+	}
+	for _, sub := range subs {
+		sub.Close()
+	}
+	// The subscriptions' context outlives them; their watchers must still exit.
+	// Polled by hand: assert.Eventually runs the condition on goroutines of its own.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
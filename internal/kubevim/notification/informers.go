@@ -0,0 +1,281 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	netattv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/misc"
+	corev1 "k8s.io/api/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// translateFunc maps a watch event to notifications. oldObj is nil on add and
+// newObj is nil on delete. Objects not managed by kube-nfv never get here on the
+// label-scoped cache, but translators still ignore them defensively.
+type translateFunc func(oldObj, newObj client.Object) []event.Event
+
+// RegisterInformers attaches the hub to the shared cache informers. It must be
+// called before the cache is started; the initial list replay is not published,
+// only changes observed after it.
+func (h *Hub) RegisterInformers(ctx context.Context, informers cache.Informers) error {
+	watches := []struct {
+		obj       client.Object
+		translate translateFunc
+	}{
+		{&kubevirtv1.VirtualMachine{}, translateVirtualMachine},
+		{&kubevirtv1.VirtualMachineInstance{}, translateVirtualMachineInstance},
+		{&kubeovnv1.Vpc{}, translateNetwork},
+		{&kubeovnv1.Vlan{}, translateNetwork},
+		{&kubeovnv1.Subnet{}, translateSubnet},
+		{&netattv1.NetworkAttachmentDefinition{}, translateNetAttach},
+		{&cdiv1beta1.VolumeImportSource{}, translateVolumeImportSource},
+		{&cdiv1beta1.DataVolume{}, translateDataVolume},
+	}
+	for _, w := range watches {
+		inf, err := informers.GetInformer(ctx, w.obj)
+		if err != nil {
+			return fmt.Errorf("get informer for %T: %w", w.obj, err)
+		}
+		if _, err := inf.AddEventHandler(h.eventHandler(w.translate)); err != nil {
+			return fmt.Errorf("add event handler for %T: %w", w.obj, err)
+		}
+	}
+	return nil
+}
+
+func (h *Hub) eventHandler(translate translateFunc) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj any, isInInitialList bool) {
+			if isInInitialList {
+				return
+			}
+			if o, ok := obj.(client.Object); ok {
+				h.Publish(translate(nil, o)...)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			o, okOld := oldObj.(client.Object)
+			n, okNew := newObj.(client.Object)
+			if okOld && okNew {
+				h.Publish(translate(o, n)...)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if o, ok := obj.(client.Object); ok {
+				h.Publish(translate(o, nil)...)
+			}
+		},
+	}
+}
+
+// lifecycleEvent reports created/deleted, and modified when the spec generation
+// (or labels, which carry kube-vim bookkeeping) changed. Status-only updates are
+// not reported as MODIFIED; translators that track state report them separately.
+func lifecycleEvent(rt event.ResourceType, oldObj, newObj client.Object) []event.Event {
+	switch {
+	case oldObj == nil && newObj != nil:
+		return []event.Event{resourceEvent(event.ChangeTypeCreated, rt, newObj)}
+	case oldObj != nil && newObj == nil:
+		return []event.Event{resourceEvent(event.ChangeTypeDeleted, rt, oldObj)}
+	case oldObj != nil && newObj != nil:
+		if oldObj.GetGeneration() != newObj.GetGeneration() || !labelsEqual(oldObj.GetLabels(), newObj.GetLabels()) {
+			return []event.Event{resourceEvent(event.ChangeTypeModified, rt, newObj)}
+		}
+	}
+	return nil
+}
+
+func resourceEvent(ct event.ChangeType, rt event.ResourceType, obj client.Object) event.Event {
+	return event.Event{
+		ChangeType:   ct,
+		ResourceType: rt,
+		ResourceId:   misc.UIDToIdentifier(obj.GetUID()).GetValue(),
+		ResourceName: obj.GetName(),
+	}
+}
+
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func managed(objs ...client.Object) bool {
+	for _, o := range objs {
+		if o != nil && !misc.IsObjectManagedByKubeNfv(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func translateVirtualMachine(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) {
+		return nil
+	}
+	return lifecycleEvent(event.ResourceTypeCompute, oldObj, newObj)
+}
+
+// translateVirtualMachineInstance reports compute running-state transitions. The
+// VMI is the runtime half of a compute; its events are attributed to the owning
+// VirtualMachine, whose UID is the ETSI compute id.
+func translateVirtualMachineInstance(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) {
+		return nil
+	}
+	prevState, state := "", ""
+	var vmi *kubevirtv1.VirtualMachineInstance
+	if oldObj != nil {
+		vmi = oldObj.(*kubevirtv1.VirtualMachineInstance)
+		prevState = vmiRunningState(vmi).String()
+	}
+	if newObj != nil {
+		vmi = newObj.(*kubevirtv1.VirtualMachineInstance)
+		state = vmiRunningState(vmi).String()
+	} else {
+		// The VMI is gone while the VM may remain (stopped or crashed and not
+		// restarted), so the compute is no longer running.
+		state = nfvcommon.ComputeRunningState_STOPPED.String()
+	}
+	if prevState == state {
+		return nil
+	}
+	computeId, computeName := "", vmi.GetName()
+	for _, ref := range vmi.GetOwnerReferences() {
+		if ref.Kind == "VirtualMachine" {
+			computeId, computeName = string(ref.UID), ref.Name
+			break
+		}
+	}
+	if computeId == "" {
+		return nil
+	}
+	return []event.Event{{
+		ChangeType:    event.ChangeTypeStateChanged,
+		ResourceType:  event.ResourceTypeCompute,
+		ResourceId:    computeId,
+		ResourceName:  computeName,
+		State:         state,
+		PreviousState: prevState,
+	}}
+}
+
+// vmiRunningState maps a VMI to the ETSI ComputeRunningState from the VMI alone.
+// The full mapping in the compute manager also looks at the VM run strategy;
+// that is not needed to notice a crash or a (re)start.
+func vmiRunningState(vmi *kubevirtv1.VirtualMachineInstance) nfvcommon.ComputeRunningState {
+	if vmi.GetDeletionTimestamp() != nil {
+		return nfvcommon.ComputeRunningState_TERMINATING
+	}
+	for _, cond := range vmi.Status.Conditions {
+		if cond.Type == kubevirtv1.VirtualMachineInstancePaused && cond.Status == corev1.ConditionTrue {
+			return nfvcommon.ComputeRunningState_PAUSED
+		}
+	}
+	switch vmi.Status.Phase {
+	case kubevirtv1.VmPhaseUnset, kubevirtv1.Pending, kubevirtv1.Scheduling, kubevirtv1.Scheduled:
+		return nfvcommon.ComputeRunningState_STARTING
+	case kubevirtv1.Running:
+		return nfvcommon.ComputeRunningState_RUNNING
+	case kubevirtv1.Succeeded:
+		return nfvcommon.ComputeRunningState_STOPPED
+	case kubevirtv1.Failed:
+		return nfvcommon.ComputeRunningState_FAILED
+	default:
+		return nfvcommon.ComputeRunningState_UNKNOWN
+	}
+}
+
+func translateNetwork(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) {
+		return nil
+	}
+	return lifecycleEvent(event.ResourceTypeNetwork, oldObj, newObj)
+}
+
+func translateSubnet(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) {
+		return nil
+	}
+	return lifecycleEvent(event.ResourceTypeSubnet, oldObj, newObj)
+}
+
+// translateNetAttach reports SR-IOV networks, which are backed by a
+// NetworkAttachmentDefinition alone. NADs of kube-OVN subnets are an
+// implementation detail of the subnet and already covered by its Subnet events.
+func translateNetAttach(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) {
+		return nil
+	}
+	obj := newObj
+	if obj == nil {
+		obj = oldObj
+	}
+	if obj.GetLabels()[network.K8sNetworkTypeLabel] != nfvcommon.NetworkType_NETWORK_TYPE_SRIOV.String() {
+		return nil
+	}
+	return lifecycleEvent(event.ResourceTypeNetwork, oldObj, newObj)
+}
+
+// translateVolumeImportSource reports image creation and deletion. The
+// VolumeImportSource UID is the image id and it exists for lazily downloaded
+// images too, which have no DataVolume.
+func translateVolumeImportSource(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) {
+		return nil
+	}
+	return lifecycleEvent(event.ResourceTypeImage, oldObj, newObj)
+}
+
+// translateDataVolume reports image download progress as DataVolume phase
+// transitions. Compute boot volumes carry the image id label as well, but are
+// cloned from the image PVC rather than populated from its VolumeImportSource;
+// those are ignored.
+func translateDataVolume(oldObj, newObj client.Object) []event.Event {
+	if !managed(oldObj, newObj) || newObj == nil {
+		return nil
+	}
+	dv := newObj.(*cdiv1beta1.DataVolume)
+	imageId, ok := dv.GetLabels()[image.K8sImageIdLabel]
+	if !ok || imageId == "" {
+		return nil
+	}
+	if dv.Spec.Storage == nil || dv.Spec.Storage.DataSourceRef == nil || dv.Spec.Storage.DataSourceRef.Kind != cdi.CDIVolumeImportSourceKind {
+		return nil
+	}
+	prevPhase := ""
+	if oldObj != nil {
+		prevPhase = string(oldObj.(*cdiv1beta1.DataVolume).Status.Phase)
+	}
+	if prevPhase == string(dv.Status.Phase) {
+		return nil
+	}
+	return []event.Event{{
+		ChangeType:    event.ChangeTypeStateChanged,
+		ResourceType:  event.ResourceTypeImage,
+		ResourceId:    imageId,
+		ResourceName:  dv.GetName(),
+		State:         string(dv.Status.Phase),
+		PreviousState: prevPhase,
+	}}
+}
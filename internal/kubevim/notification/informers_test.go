@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"testing"
+
+	netattv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	kubeovnv1 "github.com/kube-nfv/kube-vim-api/kube-ovn-api/pkg/apis/kubeovn/v1"
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim/internal/k8s"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image/cdi"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+)
+
+func vmi(phase kubevirtv1.VirtualMachineInstancePhase) *kubevirtv1.VirtualMachineInstance {
+	meta := k8stest.ManagedMeta("vm1")
+	meta.OwnerReferences = []metav1.OwnerReference{{Kind: "VirtualMachine", Name: "vm1", UID: "vm-uid"}}
+	return &kubevirtv1.VirtualMachineInstance{ObjectMeta: meta, Status: kubevirtv1.VirtualMachineInstanceStatus{Phase: phase}}
+}
+
+func imageDv(phase cdiv1beta1.DataVolumePhase, sourceKind string) *cdiv1beta1.DataVolume {
+	meta := k8stest.ManagedMeta("img")
+	meta.Labels[image.K8sImageIdLabel] = "img-uid"
+	return &cdiv1beta1.DataVolume{
+		ObjectMeta: meta,
+		Spec: cdiv1beta1.DataVolumeSpec{Storage: &cdiv1beta1.StorageSpec{
+			DataSourceRef: &corev1.TypedObjectReference{Kind: sourceKind, Name: "img"},
+		}},
+		Status: cdiv1beta1.DataVolumeStatus{Phase: phase},
+	}
+}
+
+func TestTranslateVirtualMachineInstance(t *testing.T) {
+	paused := vmi(kubevirtv1.Running)
+	paused.Status.Conditions = []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstancePaused, Status: corev1.ConditionTrue}}
+	orphan := vmi(kubevirtv1.Running)
+	orphan.OwnerReferences = nil
+	unmanaged := vmi(kubevirtv1.Running)
+	unmanaged.Labels = nil
+
+	tests := []struct {
+		name      string
+		old, new  *kubevirtv1.VirtualMachineInstance
+		wantState string
+		wantPrev  string
+		wantNone  bool
+	}{
+		{name: "started", old: vmi(kubevirtv1.Scheduled), new: vmi(kubevirtv1.Running), wantPrev: "STARTING", wantState: "RUNNING"},
+		{name: "crashed", old: vmi(kubevirtv1.Running), new: vmi(kubevirtv1.Failed), wantPrev: "RUNNING", wantState: "FAILED"},
+		{name: "paused", old: vmi(kubevirtv1.Running), new: paused, wantPrev: "RUNNING", wantState: "PAUSED"},
+		{name: "created", new: vmi(kubevirtv1.Pending), wantPrev: "", wantState: "STARTING"},
+		{name: "vmi removed", old: vmi(kubevirtv1.Running), wantPrev: "RUNNING", wantState: "STOPPED"},
+		{name: "phase within same state", old: vmi(kubevirtv1.Pending), new: vmi(kubevirtv1.Scheduling), wantNone: true},
+		{name: "no owning vm", old: vmi(kubevirtv1.Scheduled), new: orphan, wantNone: true},
+		{name: "not managed", old: vmi(kubevirtv1.Scheduled), new: unmanaged, wantNone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Pass untyped nils: a typed nil pointer is a non-nil client.Object.
+			var evs []event.Event
+			switch {
+			case tt.old == nil:
+				evs = translateVirtualMachineInstance(nil, tt.new)
+			case tt.new == nil:
+				evs = translateVirtualMachineInstance(tt.old, nil)
+			default:
+				evs = translateVirtualMachineInstance(tt.old, tt.new)
+			}
+			if tt.wantNone {
+				assert.Empty(t, evs)
+				return
+			}
+			require.Len(t, evs, 1)
+			assert.Equal(t, event.ChangeTypeStateChanged, evs[0].ChangeType)
+			assert.Equal(t, event.ResourceTypeCompute, evs[0].ResourceType)
+			assert.Equal(t, "vm-uid", evs[0].ResourceId)
+			assert.Equal(t, "vm1", evs[0].ResourceName)
+			assert.Equal(t, tt.wantState, evs[0].State)
+			assert.Equal(t, tt.wantPrev, evs[0].PreviousState)
+		})
+	}
+}
+
+func TestTranslateLifecycle(t *testing.T) {
+	vpc := &kubeovnv1.Vpc{ObjectMeta: k8stest.ManagedMeta("net")}
+	vpc.Generation = 1
+	specChanged := vpc.DeepCopy()
+	specChanged.Generation = 2
+	statusOnly := vpc.DeepCopy()
+	statusOnly.ResourceVersion = "2"
+	relabelled := vpc.DeepCopy()
+	relabelled.Labels[network.K8sNetworkIdLabel] = "x"
+
+	assert.Equal(t, []event.Event{{ChangeType: event.ChangeTypeCreated, ResourceType: event.ResourceTypeNetwork, ResourceId: "uid-net", ResourceName: "net"}},
+		translateNetwork(nil, vpc))
+	assert.Equal(t, event.ChangeTypeDeleted, translateNetwork(vpc, nil)[0].ChangeType)
+	assert.Equal(t, event.ChangeTypeModified, translateNetwork(vpc, specChanged)[0].ChangeType)
+	assert.Equal(t, event.ChangeTypeModified, translateNetwork(vpc, relabelled)[0].ChangeType)
+	assert.Empty(t, translateNetwork(vpc, statusOnly))
+}
+
+func TestTranslateNetAttach(t *testing.T) {
+	sriov := &netattv1.NetworkAttachmentDefinition{ObjectMeta: k8stest.ManagedMeta("sriov-net")}
+	sriov.Labels[network.K8sNetworkTypeLabel] = nfvcommon.NetworkType_NETWORK_TYPE_SRIOV.String()
+	ovn := &netattv1.NetworkAttachmentDefinition{ObjectMeta: k8stest.ManagedMeta("subnet-netattach")}
+
+	evs := translateNetAttach(nil, sriov)
+	require.Len(t, evs, 1)
+	assert.Equal(t, event.ResourceTypeNetwork, evs[0].ResourceType)
+	assert.Equal(t, "uid-sriov-net", evs[0].ResourceId)
+	assert.Empty(t, translateNetAttach(nil, ovn))
+}
+
+func TestTranslateDataVolume(t *testing.T) {
+	evs := translateDataVolume(imageDv(cdiv1beta1.ImportScheduled, cdi.CDIVolumeImportSourceKind), imageDv(cdiv1beta1.ImportInProgress, cdi.CDIVolumeImportSourceKind))
+	require.Len(t, evs, 1)
+	assert.Equal(t, event.Event{
+		ChangeType:    event.ChangeTypeStateChanged,
+		ResourceType:  event.ResourceTypeImage,
+		ResourceId:    "img-uid",
+		ResourceName:  "img",
+		State:         "ImportInProgress",
+		PreviousState: "ImportScheduled",
+	}, evs[0])
+
+	assert.Empty(t, translateDataVolume(imageDv(cdiv1beta1.ImportInProgress, cdi.CDIVolumeImportSourceKind), imageDv(cdiv1beta1.ImportInProgress, cdi.CDIVolumeImportSourceKind)), "no phase change")
+	assert.Empty(t, translateDataVolume(nil, imageDv(cdiv1beta1.CloneScheduled, "PersistentVolumeClaim")), "compute boot volume")
+	assert.Empty(t, translateDataVolume(imageDv(cdiv1beta1.Succeeded, cdi.CDIVolumeImportSourceKind), nil), "deletion is reported by the VolumeImportSource")
+}
+
+// Events flow from the cache informers to subscribers, and the initial list
+// replay of an (re)started cache is not published.
+func TestRegisterInformers(t *testing.T) {
+	scheme, err := k8s.BuildScheme()
+	require.NoError(t, err)
+	informers := &informertest.FakeInformers{Scheme: scheme}
+	h := NewHub(zap.NewNop(), 0)
+	require.NoError(t, h.RegisterInformers(t.Context(), informers))
+	sub, err := h.Subscribe(t.Context(), SubscribeOptions{})
+	require.NoError(t, err)
+
+	subnetInf, err := informers.FakeInformerFor(t.Context(), &kubeovnv1.Subnet{})
+	require.NoError(t, err)
+	subnet := &kubeovnv1.Subnet{ObjectMeta: k8stest.ManagedMeta("sub")}
+	subnetInf.Add(subnet)
+	subnetInf.Delete(subnet)
+
+	vmiInf, err := informers.FakeInformerFor(t.Context(), &kubevirtv1.VirtualMachineInstance{})
+	require.NoError(t, err)
+	vmiInf.Update(vmi(kubevirtv1.Running), vmi(kubevirtv1.Failed))
+
+	got := receive(t, sub, 3)
+	assert.Equal(t, event.ResourceTypeSubnet, got[0].ResourceType)
+	assert.Equal(t, event.ChangeTypeCreated, got[0].ChangeType)
+	assert.Equal(t, event.ChangeTypeDeleted, got[1].ChangeType)
+	assert.Equal(t, "FAILED", got[2].State)
+}
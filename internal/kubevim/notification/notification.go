@@ -0,0 +1,32 @@
+// Package notification turns watch events on the shared cluster cache into
+// ETSI virtualised resource change notifications (IFA 006 §7.3.5/§7.4.5/§7.5.5)
+// and fans them out to subscribers. Events carry a version marker so a client
+// that reconnects can resume where it left off instead of re-listing.
+package notification
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+)
+
+// formatVersion builds the "<epoch>-<seq>" version marker. The epoch is unique
+// per hub (i.e. per kube-vim process), so a marker from a previous run is
+// recognised as expired rather than silently matched against a reset sequence.
+func formatVersion(epoch string, seq uint64) string {
+	return epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+func parseVersion(version string) (string, uint64, error) {
+	epoch, seqStr, ok := strings.Cut(version, "-")
+	if !ok || epoch == "" {
+		return "", 0, &apperrors.ErrInvalidArgument{Field: "version", Reason: fmt.Sprintf("malformed version marker '%s'", version)}
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return "", 0, &apperrors.ErrInvalidArgument{Field: "version", Reason: fmt.Sprintf("malformed version marker '%s'", version)}
+	}
+	return epoch, seq, nil
+}
@@ -0,0 +1,415 @@
+package orvi
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/flavour"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	filter "github.com/kube-nfv/query-filter"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultZoneId is the zone of the nodes without a zone label.
+	DefaultZoneId = "default"
+
+	// vmCountQuotaResource is the ResourceQuota object count of KubeVirt VMs.
+	vmCountQuotaResource corev1.ResourceName = "count/virtualmachines.kubevirt.io"
+
+	mebibyte = 1 << 20
+)
+
+// Service serves the Or-Vi operations. Nodes, virt-launcher pods and VMs are
+// read from the shared cache; ResourceQuotas are not cached and are read with
+// the APIReader.
+type Service struct {
+	imageMgr   image.Manager
+	flavourMgr flavour.Manager
+	networkMgr network.Manager
+	reader     client.Reader
+	apiReader  client.Reader
+	namespace  string
+	// nodeSelector restricts the nodes computes can land on, as configured in
+	// compute.nodeSelector.
+	nodeSelector map[string]string
+}
+
+func NewService(
+	imageMgr image.Manager,
+	flavourMgr flavour.Manager,
+	networkMgr network.Manager,
+	reader, apiReader client.Reader,
+	k8sCfg *config.K8sConfig,
+	computeCfg *config.ComputeConfig) (*Service, error) {
+	if imageMgr == nil || flavourMgr == nil || networkMgr == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "managers", Reason: "image, flavour and network managers are required"}
+	}
+	if reader == nil || apiReader == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "reader", Reason: "cannot be nil"}
+	}
+	if k8sCfg == nil || k8sCfg.Namespace == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "k8sConfig.namespace", Reason: "cannot be empty"}
+	}
+	s := &Service{
+		imageMgr:   imageMgr,
+		flavourMgr: flavourMgr,
+		networkMgr: networkMgr,
+		reader:     reader,
+		apiReader:  apiReader,
+		namespace:  *k8sCfg.Namespace,
+	}
+	if computeCfg != nil && computeCfg.NodeSelector != nil {
+		s.nodeSelector = *computeCfg.NodeSelector
+	}
+	return s, nil
+}
+
+// AddImage adds a software image to the image repository and returns its
+// information. The image becomes usable once its download completed.
+func (s *Service) AddImage(ctx context.Context, req *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error) {
+	res, err := s.imageMgr.DownloadImage(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("add image: %w", err)
+	}
+	info, err := s.imageMgr.GetImage(ctx, res.GetImageId())
+	if err != nil {
+		return nil, fmt.Errorf("get added image '%s': %w", res.GetImageId().GetValue(), err)
+	}
+	return info, nil
+}
+
+// QueryImages returns the software images matching the SOL 013 filter.
+func (s *Service) QueryImages(ctx context.Context, filterStr string) ([]*vivnfm.SoftwareImageInformation, error) {
+	res, err := s.imageMgr.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query images: %w", err)
+	}
+	filtered, err := filter.FilterList(res, filterStr)
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	return filtered, nil
+}
+
+// QueryImage returns a software image.
+func (s *Service) QueryImage(ctx context.Context, imageId *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	return s.imageMgr.GetImage(ctx, imageId)
+}
+
+// UpdateImage updates the attributes of a software image (see image.Updater)
+// and returns its information.
+func (s *Service) UpdateImage(ctx context.Context, imageId *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	updater, ok := s.imageMgr.(image.Updater)
+	if !ok {
+		return nil, fmt.Errorf("update image '%s' with the configured image manager: %w", imageId.GetValue(), apperrors.ErrUnsupported)
+	}
+	info, err := updater.UpdateImage(ctx, imageId, md)
+	if err != nil {
+		return nil, fmt.Errorf("update image '%s': %w", imageId.GetValue(), err)
+	}
+	return info, nil
+}
+
+// DeleteImage deletes a software image from the image repository. An image in
+// use by a compute is only deleted with force.
+func (s *Service) DeleteImage(ctx context.Context, imageId *nfvcommon.Identifier, force bool) error {
+	deleter, ok := s.imageMgr.(image.Deleter)
+	if !ok {
+		return fmt.Errorf("delete image '%s' with the configured image manager: %w", imageId.GetValue(), apperrors.ErrUnsupported)
+	}
+	if err := deleter.DeleteImage(ctx, imageId, force); err != nil {
+		return fmt.Errorf("delete image '%s': %w", imageId.GetValue(), err)
+	}
+	return nil
+}
+
+// QueryResourceZones returns the resource zones matching the SOL 013 filter,
+// sorted by id.
+func (s *Service) QueryResourceZones(ctx context.Context, filterStr string) ([]ResourceZone, error) {
+	nodes, err := s.computeNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zones := map[string]*ResourceZone{}
+	for i := range nodes {
+		id := zoneOf(&nodes[i])
+		z, ok := zones[id]
+		if !ok {
+			z = &ResourceZone{ZoneId: id, ZoneName: id, ZoneState: ZoneStateUnavailable, Hosts: []string{}}
+			zones[id] = z
+		}
+		z.Hosts = append(z.Hosts, nodes[i].Name)
+		if nodeReady(&nodes[i]) {
+			z.ZoneState = ZoneStateAvailable
+		}
+	}
+	res := make([]ResourceZone, 0, len(zones))
+	for _, z := range zones {
+		slices.Sort(z.Hosts)
+		res = append(res, *z)
+	}
+	slices.SortFunc(res, func(a, b ResourceZone) int { return strings.Compare(a.ZoneId, b.ZoneId) })
+	if filterStr == "" {
+		return res, nil
+	}
+	filtered, err := filter.FilterList(res, filterStr)
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	return filtered, nil
+}
+
+// QueryComputeCapacity returns the compute capacity of a zone, or of all zones
+// when zoneId is empty. Total capacity is the allocatable CPU and memory of the
+// Ready, schedulable compute nodes; allocated capacity is what the kube-vim pods
+// on them request. Workloads of other namespaces are not accounted for. When
+// flavourId is set the capacity is also given in instances of that flavour.
+func (s *Service) QueryComputeCapacity(ctx context.Context, zoneId string, flavourId *nfvcommon.Identifier) (*ComputeCapacity, error) {
+	nodes, err := s.computeNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if zoneId != "" && !slices.ContainsFunc(nodes, func(n corev1.Node) bool { return zoneOf(&n) == zoneId }) {
+		return nil, &apperrors.ErrNotFound{Entity: "resource zone", Identifier: zoneId}
+	}
+	pods := &corev1.PodList{}
+	if err := s.reader.List(ctx, pods, client.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	allocated := map[string]resources{}
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if p.Spec.NodeName == "" || p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		allocated[p.Spec.NodeName] = allocated[p.Spec.NodeName].add(podRequests(p))
+	}
+
+	var flavourRes *resources
+	res := &ComputeCapacity{ZoneId: zoneId}
+	if flavourId != nil {
+		flav, err := s.flavourMgr.GetFlavour(ctx, flavourId)
+		if err != nil {
+			return nil, fmt.Errorf("get flavour '%s': %w", flavourId.GetValue(), err)
+		}
+		flavourRes, err = flavourResources(flav)
+		if err != nil {
+			return nil, err
+		}
+		instances, err := s.countFlavourInstances(ctx, flavourId.GetValue())
+		if err != nil {
+			return nil, err
+		}
+		res.ComputeFlavourId = flavourId.GetValue()
+		res.Instances = &CapacityInformation{AllocatedCapacity: instances}
+	}
+
+	var total, used resources
+	for i := range nodes {
+		n := &nodes[i]
+		if (zoneId != "" && zoneOf(n) != zoneId) || !nodeReady(n) {
+			continue
+		}
+		nodeTotal := resources{
+			milliCpu:    n.Status.Allocatable.Cpu().MilliValue(),
+			memoryBytes: n.Status.Allocatable.Memory().Value(),
+		}
+		nodeUsed := allocated[n.Name]
+		total = total.add(nodeTotal)
+		used = used.add(nodeUsed)
+		if flavourRes != nil {
+			res.Instances.TotalCapacity += nodeTotal.fits(*flavourRes)
+			res.Instances.AvailableCapacity += nodeTotal.sub(nodeUsed).fits(*flavourRes)
+		}
+	}
+	res.VCpu = capacity(total.milliCpu/1000, used.milliCpu/1000)
+	res.VirtualMemory = capacity(total.memoryBytes/mebibyte, used.memoryBytes/mebibyte)
+	return res, nil
+}
+
+// QueryComputeQuota returns the compute quota of the kube-vim namespace. Limits
+// come from its ResourceQuotas (requests.cpu or cpu, requests.memory or memory,
+// count/virtualmachines.kubevirt.io), the tightest one winning. Usage is what
+// the kube-vim pods request and the number of VMs.
+func (s *Service) QueryComputeQuota(ctx context.Context) (*VirtualComputeQuota, error) {
+	quotas := &corev1.ResourceQuotaList{}
+	if err := s.apiReader.List(ctx, quotas, client.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("list resource quotas: %w", err)
+	}
+	pods := &corev1.PodList{}
+	if err := s.reader.List(ctx, pods, client.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	var used resources
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		used = used.add(podRequests(p))
+	}
+	vms := &kubevirtv1.VirtualMachineList{}
+	if err := s.reader.List(ctx, vms, client.InNamespace(s.namespace)); err != nil {
+		return nil, fmt.Errorf("list virtual machines: %w", err)
+	}
+
+	res := &VirtualComputeQuota{
+		ResourceGroupId: s.namespace,
+		NumVCPUs:        QuotaValue{Used: used.milliCpu / 1000},
+		NumVcInstances:  QuotaValue{Used: int64(len(vms.Items))},
+		VirtualMemSize:  QuotaValue{Used: used.memoryBytes / mebibyte},
+	}
+	for _, q := range quotas.Items {
+		hard := q.Spec.Hard
+		if v, ok := firstQuantity(hard, corev1.ResourceRequestsCPU, corev1.ResourceCPU); ok {
+			res.NumVCPUs.Limit = minLimit(res.NumVCPUs.Limit, v.MilliValue()/1000)
+		}
+		if v, ok := firstQuantity(hard, corev1.ResourceRequestsMemory, corev1.ResourceMemory); ok {
+			res.VirtualMemSize.Limit = minLimit(res.VirtualMemSize.Limit, v.Value()/mebibyte)
+		}
+		if v, ok := hard[vmCountQuotaResource]; ok {
+			res.NumVcInstances.Limit = minLimit(res.NumVcInstances.Limit, v.Value())
+		}
+	}
+	return res, nil
+}
+
+// QueryNetworkQuota returns the number of networks and subnets kube-vim manages.
+func (s *Service) QueryNetworkQuota(ctx context.Context) (*VirtualNetworkQuota, error) {
+	networks, err := s.networkMgr.ListNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list networks: %w", err)
+	}
+	subnets, err := s.networkMgr.ListSubnets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list subnets: %w", err)
+	}
+	return &VirtualNetworkQuota{
+		ResourceGroupId: s.namespace,
+		NumNetworks:     QuotaValue{Used: int64(len(networks))},
+		NumSubnets:      QuotaValue{Used: int64(len(subnets))},
+	}, nil
+}
+
+// computeNodes lists the schedulable nodes matching compute.nodeSelector.
+func (s *Service) computeNodes(ctx context.Context) ([]corev1.Node, error) {
+	nodes := &corev1.NodeList{}
+	if err := s.reader.List(ctx, nodes, client.MatchingLabels(s.nodeSelector)); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	res := make([]corev1.Node, 0, len(nodes.Items))
+	for _, n := range nodes.Items {
+		if n.Spec.Unschedulable {
+			continue
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+func (s *Service) countFlavourInstances(ctx context.Context, flavourId string) (int64, error) {
+	vms := &kubevirtv1.VirtualMachineList{}
+	if err := s.reader.List(ctx, vms, client.InNamespace(s.namespace), client.MatchingLabels{
+		flavour.K8sFlavourIdLabel: flavourId,
+		common.K8sManagedByLabel:  common.KubeNfvName,
+	}); err != nil {
+		return 0, fmt.Errorf("list virtual machines of flavour '%s': %w", flavourId, err)
+	}
+	return int64(len(vms.Items)), nil
+}
+
+func zoneOf(n *corev1.Node) string {
+	if z := n.Labels[corev1.LabelTopologyZone]; z != "" {
+		return z
+	}
+	return DefaultZoneId
+}
+
+func nodeReady(n *corev1.Node) bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// resources is an amount of CPU and memory.
+type resources struct {
+	milliCpu    int64
+	memoryBytes int64
+}
+
+func (r resources) add(o resources) resources {
+	return resources{milliCpu: r.milliCpu + o.milliCpu, memoryBytes: r.memoryBytes + o.memoryBytes}
+}
+
+func (r resources) sub(o resources) resources {
+	return resources{milliCpu: max(r.milliCpu-o.milliCpu, 0), memoryBytes: max(r.memoryBytes-o.memoryBytes, 0)}
+}
+
+// fits returns how many times o fits into r.
+func (r resources) fits(o resources) int64 {
+	return min(r.milliCpu/o.milliCpu, r.memoryBytes/o.memoryBytes)
+}
+
+// podRequests returns the CPU and memory the scheduler accounts for a pod: the
+// container requests plus the pod overhead (set by KubeVirt on virt-launcher
+// pods).
+func podRequests(p *corev1.Pod) resources {
+	var r resources
+	for _, c := range p.Spec.Containers {
+		r.milliCpu += c.Resources.Requests.Cpu().MilliValue()
+		r.memoryBytes += c.Resources.Requests.Memory().Value()
+	}
+	r.milliCpu += p.Spec.Overhead.Cpu().MilliValue()
+	r.memoryBytes += p.Spec.Overhead.Memory().Value()
+	return r
+}
+
+func flavourResources(f *vivnfm.VirtualComputeFlavour) (*resources, error) {
+	if f.GetVirtualCpu().GetNumVirtualCpu() == 0 || f.GetVirtualMemory().GetVirtualMemSize() == nil || f.GetVirtualMemory().GetVirtualMemSize().IsZero() {
+		return nil, &apperrors.ErrInvalidArgument{Field: "flavour", Reason: "flavour has no vCPU or memory size"}
+	}
+	return &resources{
+		milliCpu:    int64(f.GetVirtualCpu().GetNumVirtualCpu()) * 1000,
+		memoryBytes: f.GetVirtualMemory().GetVirtualMemSize().Value(),
+	}, nil
+}
+
+func capacity(total, allocated int64) CapacityInformation {
+	return CapacityInformation{
+		TotalCapacity:     total,
+		AllocatedCapacity: allocated,
+		AvailableCapacity: max(total-allocated, 0),
+	}
+}
+
+func firstQuantity(list corev1.ResourceList, names ...corev1.ResourceName) (resource.Quantity, bool) {
+	for _, n := range names {
+		if v, ok := list[n]; ok {
+			return v, true
+		}
+	}
+	return resource.Quantity{}, false
+}
+
+func minLimit(current *int64, v int64) *int64 {
+	if current != nil && *current <= v {
+		return current
+	}
+	return &v
+}
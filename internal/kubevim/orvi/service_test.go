@@ -0,0 +1,299 @@
+package orvi
+
+import (
+	"context"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/flavour"
+	flavourmock "github.com/kube-nfv/kube-vim/internal/kubevim/flavour/mock"
+	imagemock "github.com/kube-nfv/kube-vim/internal/kubevim/image/mock"
+	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// imageManagerMock satisfies image.Manager: DownloadImage returns downloadId,
+// the ETSI query surface is a gomock.
+type imageManagerMock struct {
+	admin.UnimplementedAdminServer
+	*imagemock.MockNfvImageManager
+	downloadId string
+}
+
+func (m *imageManagerMock) DownloadImage(context.Context, *admin.DownloadImageRequest) (*admin.DownloadImageResponse, error) {
+	return &admin.DownloadImageResponse{ImageId: k8stest.ID(m.downloadId)}, nil
+}
+
+// deletingImageManager adds image.Deleter to imageManagerMock.
+type deletingImageManager struct {
+	*imageManagerMock
+	deleted *nfvcommon.Identifier
+	force   bool
+}
+
+func (m *deletingImageManager) DeleteImage(_ context.Context, id *nfvcommon.Identifier, force bool) error {
+	m.deleted, m.force = id, force
+	return nil
+}
+
+// updatingImageManager adds image.Updater to imageManagerMock.
+type updatingImageManager struct {
+	*imageManagerMock
+	updated *admin.ImageMetadata
+}
+
+func (m *updatingImageManager) UpdateImage(_ context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	m.updated = md
+	return &vivnfm.SoftwareImageInformation{SoftwareImageId: id, Version: md.Version}, nil
+}
+
+type mocks struct {
+	image   *imageManagerMock
+	flavour *flavourmock.MockManager
+	network *networkmock.MockManager
+}
+
+func newService(t *testing.T, computeCfg *config.ComputeConfig, objs ...client.Object) (*Service, mocks) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	m := mocks{
+		image:   &imageManagerMock{MockNfvImageManager: imagemock.NewMockNfvImageManager(ctrl)},
+		flavour: flavourmock.NewMockManager(ctrl),
+		network: networkmock.NewMockManager(ctrl),
+	}
+	c := k8stest.NewClient(t, objs...)
+	s, err := NewService(m.image, m.flavour, m.network, c, c,
+		&config.K8sConfig{Namespace: k8stest.Ptr(k8stest.TestNamespace)}, computeCfg)
+	require.NoError(t, err)
+	return s, m
+}
+
+func testNode(name, zone string, ready corev1.ConditionStatus, cpu, memory string) *corev1.Node {
+	n := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubevim": "compute"}},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+		},
+	}
+	if zone != "" {
+		n.Labels[corev1.LabelTopologyZone] = zone
+	}
+	return n
+}
+
+func testPod(name, node, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: k8stest.TestNamespace},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Name: "compute",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+			Overhead: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func testVM(name, flavourId string) *kubevirtv1.VirtualMachine {
+	meta := k8stest.ManagedMeta(name)
+	meta.Namespace = k8stest.TestNamespace
+	meta.Labels[flavour.K8sFlavourIdLabel] = flavourId
+	return &kubevirtv1.VirtualMachine{ObjectMeta: meta}
+}
+
+func TestAddImage(t *testing.T) {
+	s, m := newService(t, nil)
+	m.image.downloadId = "img1"
+	img := &vivnfm.SoftwareImageInformation{SoftwareImageId: k8stest.ID("img1"), Name: "cirros"}
+	m.image.EXPECT().GetImage(gomock.Any(), k8stest.ID("img1")).Return(img, nil)
+
+	res, err := s.AddImage(t.Context(), &admin.DownloadImageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, img, res)
+}
+
+func TestQueryImages(t *testing.T) {
+	s, m := newService(t, nil)
+	m.image.EXPECT().ListImages(gomock.Any()).Return([]*vivnfm.SoftwareImageInformation{{Name: "a"}, {Name: "b"}}, nil).Times(2)
+
+	res, err := s.QueryImages(t.Context(), "filter=(eq,name,b)")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "b", res[0].Name)
+
+	_, err = s.QueryImages(t.Context(), "filter=(eq,name")
+	var invalid *apperrors.ErrInvalidArgument
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestUpdateImage(t *testing.T) {
+	version := "2.0"
+	md := &admin.ImageMetadata{Version: &version}
+
+	t.Run("delegates to the image manager", func(t *testing.T) {
+		s, m := newService(t, nil)
+		updater := &updatingImageManager{imageManagerMock: m.image}
+		s.imageMgr = updater
+		res, err := s.UpdateImage(t.Context(), k8stest.ID("img1"), md)
+		require.NoError(t, err)
+		assert.Equal(t, "2.0", res.GetVersion())
+		assert.Equal(t, md, updater.updated)
+	})
+
+	t.Run("image manager without update support", func(t *testing.T) {
+		s, _ := newService(t, nil)
+		_, err := s.UpdateImage(t.Context(), k8stest.ID("img1"), md)
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
+
+func TestDeleteImage(t *testing.T) {
+	t.Run("delegates to the image manager", func(t *testing.T) {
+		s, m := newService(t, nil)
+		deleter := &deletingImageManager{imageManagerMock: m.image}
+		s.imageMgr = deleter
+		require.NoError(t, s.DeleteImage(t.Context(), k8stest.ID("img1"), true))
+		assert.Equal(t, "img1", deleter.deleted.GetValue())
+		assert.True(t, deleter.force)
+	})
+
+	t.Run("image manager without delete support", func(t *testing.T) {
+		s, _ := newService(t, nil)
+		assert.ErrorIs(t, s.DeleteImage(t.Context(), k8stest.ID("img1"), false), apperrors.ErrUnsupported)
+	})
+}
+
+func TestQueryResourceZones(t *testing.T) {
+	cordoned := testNode("cordoned", "zone-a", corev1.ConditionTrue, "8", "16Gi")
+	cordoned.Spec.Unschedulable = true
+	other := testNode("other", "zone-a", corev1.ConditionTrue, "8", "16Gi")
+	other.Labels = map[string]string{corev1.LabelTopologyZone: "zone-a"}
+	s, _ := newService(t, &config.ComputeConfig{NodeSelector: &map[string]string{"kubevim": "compute"}},
+		testNode("n1", "zone-a", corev1.ConditionTrue, "8", "16Gi"),
+		testNode("n2", "zone-b", corev1.ConditionFalse, "8", "16Gi"),
+		testNode("n3", "", corev1.ConditionTrue, "8", "16Gi"),
+		cordoned, other)
+
+	zones, err := s.QueryResourceZones(t.Context(), "")
+	require.NoError(t, err)
+	assert.Equal(t, []ResourceZone{
+		{ZoneId: DefaultZoneId, ZoneName: DefaultZoneId, ZoneState: ZoneStateAvailable, Hosts: []string{"n3"}},
+		{ZoneId: "zone-a", ZoneName: "zone-a", ZoneState: ZoneStateAvailable, Hosts: []string{"n1"}},
+		{ZoneId: "zone-b", ZoneName: "zone-b", ZoneState: ZoneStateUnavailable, Hosts: []string{"n2"}},
+	}, zones)
+
+	zones, err = s.QueryResourceZones(t.Context(), "filter=(eq,zoneState,UNAVAILABLE)")
+	require.NoError(t, err)
+	require.Len(t, zones, 1)
+	assert.Equal(t, "zone-b", zones[0].ZoneId)
+}
+
+func TestQueryComputeCapacity(t *testing.T) {
+	objs := []client.Object{
+		testNode("n1", "zone-a", corev1.ConditionTrue, "8", "16Gi"),
+		testNode("n2", "zone-a", corev1.ConditionTrue, "4", "8Gi"),
+		testNode("n3", "zone-b", corev1.ConditionTrue, "16", "32Gi"),
+		testNode("down", "zone-a", corev1.ConditionFalse, "64", "128Gi"),
+		testPod("vm1", "n1", "2", "3840Mi"),
+		testPod("vm2", "n2", "4", "7936Mi"),
+		testVM("vm1", "small"),
+		testVM("vm2", "large"),
+	}
+	small := &vivnfm.VirtualComputeFlavour{
+		VirtualCpu:    &vivnfm.VirtualCpuData{NumVirtualCpu: 2},
+		VirtualMemory: &vivnfm.VirtualMemoryData{VirtualMemSize: k8stest.Ptr(resource.MustParse("4Gi"))},
+	}
+
+	t.Run("zone", func(t *testing.T) {
+		s, _ := newService(t, nil, objs...)
+		res, err := s.QueryComputeCapacity(t.Context(), "zone-a", nil)
+		require.NoError(t, err)
+		assert.Equal(t, CapacityInformation{TotalCapacity: 12, AllocatedCapacity: 6, AvailableCapacity: 6}, res.VCpu)
+		// 24Gi total; 4Gi + 8Gi requested including the pod overhead.
+		assert.Equal(t, CapacityInformation{TotalCapacity: 24576, AllocatedCapacity: 12288, AvailableCapacity: 12288}, res.VirtualMemory)
+		assert.Nil(t, res.Instances)
+	})
+
+	t.Run("flavour instances", func(t *testing.T) {
+		s, m := newService(t, nil, objs...)
+		m.flavour.EXPECT().GetFlavour(gomock.Any(), k8stest.ID("small")).Return(small, nil)
+		res, err := s.QueryComputeCapacity(t.Context(), "zone-a", k8stest.ID("small"))
+		require.NoError(t, err)
+		require.NotNil(t, res.Instances)
+		// n1 holds 4 (8 vCPU, 16Gi) with room for 3 more; n2 holds 2 and is full.
+		assert.Equal(t, CapacityInformation{TotalCapacity: 6, AllocatedCapacity: 1, AvailableCapacity: 3}, *res.Instances)
+	})
+
+	t.Run("all zones", func(t *testing.T) {
+		s, _ := newService(t, nil, objs...)
+		res, err := s.QueryComputeCapacity(t.Context(), "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(28), res.VCpu.TotalCapacity)
+	})
+
+	t.Run("unknown zone", func(t *testing.T) {
+		s, _ := newService(t, nil, objs...)
+		_, err := s.QueryComputeCapacity(t.Context(), "zone-x", nil)
+		var notFound *apperrors.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestQueryComputeQuota(t *testing.T) {
+	quota := func(name string, hard corev1.ResourceList) *corev1.ResourceQuota {
+		return &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: k8stest.TestNamespace},
+			Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+		}
+	}
+	s, _ := newService(t, nil,
+		testPod("vm1", "n1", "2", "3840Mi"),
+		testVM("vm1", "small"),
+		quota("compute", corev1.ResourceList{
+			corev1.ResourceRequestsCPU:    resource.MustParse("32"),
+			corev1.ResourceRequestsMemory: resource.MustParse("64Gi"),
+		}),
+		quota("tight", corev1.ResourceList{
+			corev1.ResourceCPU:   resource.MustParse("16"),
+			vmCountQuotaResource: resource.MustParse("10"),
+		}),
+	)
+	res, err := s.QueryComputeQuota(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, k8stest.TestNamespace, res.ResourceGroupId)
+	assert.Equal(t, QuotaValue{Limit: k8stest.Ptr(int64(16)), Used: 2}, res.NumVCPUs)
+	assert.Equal(t, QuotaValue{Limit: k8stest.Ptr(int64(65536)), Used: 4096}, res.VirtualMemSize)
+	assert.Equal(t, QuotaValue{Limit: k8stest.Ptr(int64(10)), Used: 1}, res.NumVcInstances)
+}
+
+func TestQueryNetworkQuota(t *testing.T) {
+	s, m := newService(t, nil)
+	m.network.EXPECT().ListNetworks(gomock.Any()).Return([]*vivnfm.VirtualNetwork{{}, {}}, nil)
+	m.network.EXPECT().ListSubnets(gomock.Any()).Return([]*vivnfm.NetworkSubnet{{}, {}, {}}, nil)
+	res, err := s.QueryNetworkQuota(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, QuotaValue{Used: 2}, res.NumNetworks)
+	assert.Equal(t, QuotaValue{Used: 3}, res.NumSubnets)
+	assert.Nil(t, res.NumSubnets.Limit)
+}
@@ -0,0 +1,70 @@
+// Package orvi implements the operations of the IFA 005 Or-Vi reference point
+// that the NFVO uses and Vi-Vnfm does not cover: software image management,
+// resource capacity, resource zones and quotas. It sits on top of the image,
+// flavour and network managers and reads node and quota state from Kubernetes.
+package orvi
+
+// ZoneState tells whether a resource zone can host new computes.
+type ZoneState string
+
+const (
+	ZoneStateAvailable   ZoneState = "AVAILABLE"
+	ZoneStateUnavailable ZoneState = "UNAVAILABLE"
+)
+
+// ResourceZone is the IFA 005 §8.3.2 resource zone. Kube-vim maps a zone to the
+// nodes sharing a topology.kubernetes.io/zone label; nodes without the label
+// form DefaultZoneId.
+type ResourceZone struct {
+	ZoneId    string    `json:"zoneId"`
+	ZoneName  string    `json:"zoneName"`
+	ZoneState ZoneState `json:"zoneState"`
+	// Hosts lists the nodes of the zone eligible for computes.
+	Hosts []string `json:"hosts"`
+}
+
+// CapacityInformation is the IFA 005 §8.3.4 capacity of one resource type.
+// Kube-vim has no reservations, so ReservedCapacity is always 0.
+type CapacityInformation struct {
+	AvailableCapacity int64 `json:"availableCapacity"`
+	ReservedCapacity  int64 `json:"reservedCapacity"`
+	TotalCapacity     int64 `json:"totalCapacity"`
+	AllocatedCapacity int64 `json:"allocatedCapacity"`
+}
+
+// ComputeCapacity is the compute capacity of a zone, or of all zones when
+// ZoneId is empty. VCpu is in vCPUs and VirtualMemory in MiB. Instances is the
+// capacity in instances of ComputeFlavourId and is only set when a flavour was
+// given.
+type ComputeCapacity struct {
+	ZoneId           string               `json:"zoneId,omitempty"`
+	VCpu             CapacityInformation  `json:"vCpu"`
+	VirtualMemory    CapacityInformation  `json:"virtualMemory"`
+	ComputeFlavourId string               `json:"computeFlavourId,omitempty"`
+	Instances        *CapacityInformation `json:"instances,omitempty"`
+}
+
+// QuotaValue is the limit and usage of one quota. Limit is nil when no limit is
+// configured.
+type QuotaValue struct {
+	Limit *int64 `json:"limit,omitempty"`
+	Used  int64  `json:"used"`
+}
+
+// VirtualComputeQuota is the IFA 005 §8.9.2 compute quota of the kube-vim
+// resource group (its namespace). VirtualMemSize is in MiB.
+type VirtualComputeQuota struct {
+	ResourceGroupId string     `json:"resourceGroupId"`
+	NumVCPUs        QuotaValue `json:"numVCPUs"`
+	NumVcInstances  QuotaValue `json:"numVcInstances"`
+	VirtualMemSize  QuotaValue `json:"virtualMemSize"`
+}
+
+// VirtualNetworkQuota is the IFA 005 §8.9.3 network quota. kube-OVN networks
+// and subnets are cluster-scoped and cannot be limited by a ResourceQuota, so
+// only usage is reported.
+type VirtualNetworkQuota struct {
+	ResourceGroupId string     `json:"resourceGroupId"`
+	NumNetworks     QuotaValue `json:"numNetworks"`
+	NumSubnets      QuotaValue `json:"numSubnets"`
+}
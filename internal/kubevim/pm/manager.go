@@ -0,0 +1,630 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	filter "github.com/kube-nfv/query-filter"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"go.uber.org/zap"
+)
+
+const (
+	// subscriberBufferSize is the per-subscriber channel depth. A subscriber
+	// that falls further behind is dropped with event.ErrSlowSubscriber.
+	subscriberBufferSize = 256
+	// maxReportsPerJob is how many reports a PM job keeps. Older reports are
+	// discarded; consumers fetch a report when notified it is available.
+	maxReportsPerJob = 8
+	// maxValuesPerReport bounds ReportingPeriod / CollectionPeriod, well below
+	// the Prometheus limit of points per range query series.
+	maxValuesPerReport = 1000
+)
+
+// Manager runs PM jobs and evaluates thresholds against a Prometheus-compatible
+// endpoint. Jobs and thresholds are written to the Store, when one is set, and
+// reloaded by Start. Reports and threshold crossing state are held in memory
+// only: a restarted job produces its next report one reporting period later.
+type Manager struct {
+	logger *zap.Logger
+	prom   promv1.API
+	// store persists jobs and thresholds. nil keeps them in memory only.
+	store Store
+	// queryTimeout bounds every Prometheus query.
+	queryTimeout time.Duration
+	// thresholdInterval is how often thresholds are evaluated. It is also the
+	// window the metric is computed over.
+	thresholdInterval time.Duration
+
+	mu sync.Mutex
+	// ctx is the Start context jobs run in; nil until Start is called.
+	ctx         context.Context
+	jobs        map[string]*job
+	thresholds  map[string]*threshold
+	subscribers map[*Subscription]struct{}
+}
+
+type job struct {
+	PmJob
+	created time.Time
+	reports []*PerformanceReport
+	cancel  context.CancelFunc
+}
+
+type threshold struct {
+	Threshold
+	created time.Time
+	// above records the measured objects currently above the threshold.
+	above map[measuredObject]struct{}
+}
+
+type measuredObject struct {
+	objectInstanceId    string
+	subObjectInstanceId string
+}
+
+// Subscription is a live PM notification stream. Events is closed when the
+// subscription ends; Err then reports why (nil for a regular Close or context
+// cancellation).
+type Subscription struct {
+	manager *Manager
+	filter  string
+	events  chan Notification
+	err     error
+	// done is closed with events, releasing the context watcher.
+	done chan struct{}
+}
+
+func NewManager(logger *zap.Logger, prom promv1.API, store Store, queryTimeout, thresholdInterval time.Duration) *Manager {
+	return &Manager{
+		logger:            logger,
+		prom:              prom,
+		store:             store,
+		queryTimeout:      queryTimeout,
+		thresholdInterval: thresholdInterval,
+		jobs:              make(map[string]*job),
+		thresholds:        make(map[string]*threshold),
+		subscribers:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Start reloads the persisted jobs and thresholds, then runs the PM jobs and
+// evaluates the thresholds until ctx is done. Jobs created before Start begin
+// collecting once it is called.
+func (m *Manager) Start(ctx context.Context) error {
+	var jobs []JobRecord
+	var thresholds []ThresholdRecord
+	if m.store != nil {
+		var err error
+		if jobs, thresholds, err = m.store.Load(ctx); err != nil {
+			return fmt.Errorf("load pm jobs and thresholds: %w", err)
+		}
+	}
+	m.mu.Lock()
+	for _, rec := range jobs {
+		spec := rec.PmJob
+		spec.Reports = nil
+		m.jobs[spec.PmJobId] = &job{PmJob: spec, created: rec.Created}
+	}
+	for _, rec := range thresholds {
+		m.thresholds[rec.Threshold.ThresholdId] = &threshold{
+			Threshold: rec.Threshold,
+			created:   rec.Created,
+			above:     make(map[measuredObject]struct{}),
+		}
+	}
+	if len(jobs) > 0 || len(thresholds) > 0 {
+		m.logger.Info("pm jobs and thresholds reloaded", zap.Int("pmJobs", len(jobs)), zap.Int("thresholds", len(thresholds)))
+	}
+	m.ctx = ctx
+	for _, j := range m.jobs {
+		m.startJobLocked(j)
+	}
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(m.thresholdInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			m.evaluateThresholds(ctx, now)
+		}
+	}
+}
+
+// CreatePmJob validates the request, persists the job and starts collecting.
+// The first report is produced one reporting period after creation.
+func (m *Manager) CreatePmJob(ctx context.Context, req *PmJobRequest) (*PmJob, error) {
+	if req == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "request", Reason: "cannot be nil"}
+	}
+	if err := validateObjects(req.ObjectType, req.ObjectInstanceIds); err != nil {
+		return nil, err
+	}
+	if len(req.PerformanceMetrics) == 0 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "performanceMetrics", Reason: "at least one metric is required"}
+	}
+	for _, metric := range req.PerformanceMetrics {
+		if err := validateMetric("performanceMetrics", metric); err != nil {
+			return nil, err
+		}
+	}
+	if req.CollectionPeriod <= 0 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "collectionPeriod", Reason: "must be positive"}
+	}
+	if req.ReportingPeriod < req.CollectionPeriod || req.ReportingPeriod%req.CollectionPeriod != 0 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "reportingPeriod", Reason: "must be a multiple of collectionPeriod"}
+	}
+	if req.ReportingPeriod/req.CollectionPeriod > maxValuesPerReport {
+		return nil, &apperrors.ErrInvalidArgument{Field: "reportingPeriod", Reason: fmt.Sprintf("at most %d collection periods per report", maxValuesPerReport)}
+	}
+
+	j := &job{
+		PmJob: PmJob{
+			PmJobId:            uuid.NewString(),
+			ObjectType:         req.ObjectType,
+			ObjectInstanceIds:  slices.Clone(req.ObjectInstanceIds),
+			PerformanceMetrics: slices.Clone(req.PerformanceMetrics),
+			CollectionPeriod:   req.CollectionPeriod,
+			ReportingPeriod:    req.ReportingPeriod,
+		},
+		created: time.Now(),
+	}
+	if m.store != nil {
+		if err := m.store.PutJob(ctx, JobRecord{PmJob: j.PmJob, Created: j.created}); err != nil {
+			return nil, fmt.Errorf("persist pm job '%s': %w", j.PmJobId, err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[j.PmJobId] = j
+	if m.ctx != nil {
+		m.startJobLocked(j)
+	}
+	m.logger.Info("pm job created", zap.String("pmJobId", j.PmJobId), zap.Strings("objectInstanceIds", j.ObjectInstanceIds))
+	res := j.snapshot()
+	return &res, nil
+}
+
+// QueryPmJobs returns the PM jobs matching the SOL 013 filter, oldest first.
+func (m *Manager) QueryPmJobs(filterStr string) ([]PmJob, error) {
+	m.mu.Lock()
+	jobs := make([]*job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	slices.SortFunc(jobs, func(a, b *job) int { return a.created.Compare(b.created) })
+	res := make([]PmJob, 0, len(jobs))
+	for _, j := range jobs {
+		res = append(res, j.snapshot())
+	}
+	m.mu.Unlock()
+	return filterList(res, filterStr)
+}
+
+// DeletePmJob stops a PM job and discards its reports.
+func (m *Manager) DeletePmJob(ctx context.Context, pmJobId string) error {
+	m.mu.Lock()
+	_, ok := m.jobs[pmJobId]
+	m.mu.Unlock()
+	if !ok {
+		return &apperrors.ErrNotFound{Entity: "pm job", Identifier: pmJobId}
+	}
+	if m.store != nil {
+		if err := m.store.DeleteJob(ctx, pmJobId); err != nil {
+			return fmt.Errorf("delete persisted pm job '%s': %w", pmJobId, err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[pmJobId]
+	if !ok {
+		// Deleted concurrently.
+		return nil
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	delete(m.jobs, pmJobId)
+	m.logger.Info("pm job deleted", zap.String("pmJobId", pmJobId))
+	return nil
+}
+
+// GetPerformanceReport returns a report of a PM job.
+func (m *Manager) GetPerformanceReport(pmJobId, reportId string) (*PerformanceReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[pmJobId]
+	if !ok {
+		return nil, &apperrors.ErrNotFound{Entity: "pm job", Identifier: pmJobId}
+	}
+	for _, r := range j.reports {
+		if r.ReportId == reportId {
+			return r, nil
+		}
+	}
+	return nil, &apperrors.ErrNotFound{Entity: "performance report", Identifier: reportId}
+}
+
+// CreateThreshold validates the request, persists the threshold and starts
+// evaluating it on the next evaluation interval.
+func (m *Manager) CreateThreshold(ctx context.Context, req *ThresholdRequest) (*Threshold, error) {
+	if req == nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "request", Reason: "cannot be nil"}
+	}
+	if err := validateObjects(req.ObjectType, req.ObjectInstanceIds); err != nil {
+		return nil, err
+	}
+	if err := validateMetric("performanceMetric", req.PerformanceMetric); err != nil {
+		return nil, err
+	}
+	if req.Hysteresis < 0 {
+		return nil, &apperrors.ErrInvalidArgument{Field: "hysteresis", Reason: "cannot be negative"}
+	}
+	t := &threshold{
+		Threshold: Threshold{
+			ThresholdId:       uuid.NewString(),
+			ObjectType:        req.ObjectType,
+			ObjectInstanceIds: slices.Clone(req.ObjectInstanceIds),
+			PerformanceMetric: req.PerformanceMetric,
+			ThresholdValue:    req.ThresholdValue,
+			Hysteresis:        req.Hysteresis,
+		},
+		created: time.Now(),
+		above:   make(map[measuredObject]struct{}),
+	}
+	if m.store != nil {
+		if err := m.store.PutThreshold(ctx, ThresholdRecord{Threshold: t.Threshold, Created: t.created}); err != nil {
+			return nil, fmt.Errorf("persist threshold '%s': %w", t.ThresholdId, err)
+		}
+	}
+	m.mu.Lock()
+	m.thresholds[t.ThresholdId] = t
+	m.mu.Unlock()
+	m.logger.Info("threshold created", zap.String("thresholdId", t.ThresholdId), zap.String("performanceMetric", string(t.PerformanceMetric)))
+	res := t.Threshold
+	return &res, nil
+}
+
+// QueryThresholds returns the thresholds matching the SOL 013 filter, oldest first.
+func (m *Manager) QueryThresholds(filterStr string) ([]Threshold, error) {
+	m.mu.Lock()
+	thresholds := make([]*threshold, 0, len(m.thresholds))
+	for _, t := range m.thresholds {
+		thresholds = append(thresholds, t)
+	}
+	m.mu.Unlock()
+	slices.SortFunc(thresholds, func(a, b *threshold) int { return a.created.Compare(b.created) })
+	res := make([]Threshold, 0, len(thresholds))
+	for _, t := range thresholds {
+		res = append(res, t.Threshold)
+	}
+	return filterList(res, filterStr)
+}
+
+// DeleteThreshold stops evaluating a threshold.
+func (m *Manager) DeleteThreshold(ctx context.Context, thresholdId string) error {
+	m.mu.Lock()
+	_, ok := m.thresholds[thresholdId]
+	m.mu.Unlock()
+	if !ok {
+		return &apperrors.ErrNotFound{Entity: "threshold", Identifier: thresholdId}
+	}
+	if m.store != nil {
+		if err := m.store.DeleteThreshold(ctx, thresholdId); err != nil {
+			return fmt.Errorf("delete persisted threshold '%s': %w", thresholdId, err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.thresholds, thresholdId)
+	m.logger.Info("threshold deleted", zap.String("thresholdId", thresholdId))
+	return nil
+}
+
+// Subscribe registers a PM notification stream. The filter is evaluated against
+// the Notification. The subscription is closed when ctx is done.
+func (m *Manager) Subscribe(ctx context.Context, filterStr string) (*Subscription, error) {
+	if _, err := filter.FilterList([]Notification{{}}, filterStr); err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	sub := &Subscription{
+		manager: m,
+		filter:  filterStr,
+		events:  make(chan Notification, subscriberBufferSize),
+		done:    make(chan struct{}),
+	}
+	m.mu.Lock()
+	m.subscribers[sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// Events returns the notification stream. It is closed when the subscription ends.
+func (s *Subscription) Events() <-chan Notification { return s.events }
+
+// Err reports why the subscription ended. Only valid once Events is closed.
+func (s *Subscription) Err() error {
+	s.manager.mu.Lock()
+	defer s.manager.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.manager.mu.Lock()
+	defer s.manager.mu.Unlock()
+	s.manager.closeLocked(s, nil)
+}
+
+func (m *Manager) closeLocked(sub *Subscription, err error) {
+	if _, ok := m.subscribers[sub]; !ok {
+		return
+	}
+	delete(m.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+	close(sub.done)
+}
+
+// startJobLocked runs the collection loop of a job. m.mu must be held and
+// m.ctx set.
+func (m *Manager) startJobLocked(j *job) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	j.cancel = cancel
+	spec := j.PmJob
+	spec.Reports = nil
+	go func() {
+		ticker := time.NewTicker(time.Duration(spec.ReportingPeriod) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				report, err := m.collect(ctx, &spec, now)
+				if err != nil {
+					m.logger.Warn("collect performance report", zap.String("pmJobId", spec.PmJobId), zap.Error(err))
+					continue
+				}
+				m.addReport(report)
+			}
+		}
+	}()
+}
+
+// collect builds the report of the reporting period ending at end: one value
+// per collection period for every metric and measured object.
+func (m *Manager) collect(ctx context.Context, spec *PmJob, end time.Time) (*PerformanceReport, error) {
+	collection := time.Duration(spec.CollectionPeriod) * time.Second
+	reporting := time.Duration(spec.ReportingPeriod) * time.Second
+	r := promv1.Range{Start: end.Add(collection - reporting), End: end, Step: collection}
+
+	report := &PerformanceReport{ReportId: uuid.NewString(), PmJobId: spec.PmJobId, Entries: []ReportEntry{}}
+	for _, metric := range spec.PerformanceMetrics {
+		query, err := buildQuery(metric, spec.ObjectInstanceIds, collection)
+		if err != nil {
+			return nil, err
+		}
+		qctx, cancel := context.WithTimeout(ctx, m.queryTimeout)
+		value, warnings, err := m.prom.QueryRange(qctx, query, r)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("query metric '%s': %w", metric, err)
+		}
+		if len(warnings) > 0 {
+			m.logger.Debug("prometheus query warnings", zap.String("performanceMetric", string(metric)), zap.Strings("warnings", warnings))
+		}
+		measurements, err := parseResult(value)
+		if err != nil {
+			return nil, fmt.Errorf("query metric '%s': %w", metric, err)
+		}
+		for _, ms := range measurements {
+			report.Entries = append(report.Entries, ReportEntry{
+				ObjectType:          spec.ObjectType,
+				ObjectInstanceId:    ms.objectInstanceId,
+				SubObjectInstanceId: ms.subObjectInstanceId,
+				PerformanceMetric:   metric,
+				PerformanceValues:   ms.values,
+			})
+		}
+	}
+	return report, nil
+}
+
+// addReport stores a report, discarding the oldest one past maxReportsPerJob,
+// and notifies that it is available. A report of a job deleted while it was
+// being collected is dropped.
+func (m *Manager) addReport(report *PerformanceReport) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[report.PmJobId]
+	if !ok {
+		return
+	}
+	j.reports = append(j.reports, report)
+	if len(j.reports) > maxReportsPerJob {
+		j.reports = slices.Delete(j.reports, 0, len(j.reports)-maxReportsPerJob)
+	}
+	m.notifyLocked(Notification{
+		NotificationType: PerformanceInformationAvailableType,
+		TimeStamp:        time.Now(),
+		ObjectType:       j.ObjectType,
+		PmJobId:          j.PmJobId,
+		ReportId:         report.ReportId,
+	})
+}
+
+// evaluateThresholds queries the metric of every threshold at now and notifies
+// the crossings. A failed query skips that threshold until the next interval.
+func (m *Manager) evaluateThresholds(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	specs := make([]Threshold, 0, len(m.thresholds))
+	for _, t := range m.thresholds {
+		specs = append(specs, t.Threshold)
+	}
+	m.mu.Unlock()
+
+	for _, spec := range specs {
+		query, err := buildQuery(spec.PerformanceMetric, spec.ObjectInstanceIds, m.thresholdInterval)
+		if err != nil {
+			m.logger.Warn("build threshold query", zap.String("thresholdId", spec.ThresholdId), zap.Error(err))
+			continue
+		}
+		qctx, cancel := context.WithTimeout(ctx, m.queryTimeout)
+		value, _, err := m.prom.Query(qctx, query, now)
+		cancel()
+		if err != nil {
+			m.logger.Warn("evaluate threshold", zap.String("thresholdId", spec.ThresholdId), zap.Error(err))
+			continue
+		}
+		measurements, err := parseResult(value)
+		if err != nil {
+			m.logger.Warn("evaluate threshold", zap.String("thresholdId", spec.ThresholdId), zap.Error(err))
+			continue
+		}
+		m.applyThreshold(spec.ThresholdId, measurements)
+	}
+}
+
+// applyThreshold updates the crossing state of a threshold from the latest
+// measurements and notifies every crossing. Measured objects start below the
+// threshold, so a value already above it on the first evaluation notifies UP.
+// An object missing from the measurements (e.g. a deleted compute) forgets its
+// state.
+func (m *Manager) applyThreshold(thresholdId string, measurements []measurement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.thresholds[thresholdId]
+	if !ok {
+		return
+	}
+	above := make(map[measuredObject]struct{}, len(t.above))
+	for _, ms := range measurements {
+		if len(ms.values) == 0 {
+			continue
+		}
+		obj := measuredObject{objectInstanceId: ms.objectInstanceId, subObjectInstanceId: ms.subObjectInstanceId}
+		value := ms.values[len(ms.values)-1]
+		_, wasAbove := t.above[obj]
+		isAbove, direction := cross(wasAbove, value.Value, t.ThresholdValue, t.Hysteresis)
+		if isAbove {
+			above[obj] = struct{}{}
+		}
+		if direction == "" {
+			continue
+		}
+		m.logger.Info("threshold crossed",
+			zap.String("thresholdId", t.ThresholdId),
+			zap.String("objectInstanceId", obj.objectInstanceId),
+			zap.String("direction", string(direction)),
+			zap.Float64("value", value.Value))
+		m.notifyLocked(Notification{
+			NotificationType:    ThresholdCrossedType,
+			TimeStamp:           value.TimeStamp,
+			ObjectType:          t.ObjectType,
+			ThresholdId:         t.ThresholdId,
+			CrossingDirection:   direction,
+			ObjectInstanceId:    obj.objectInstanceId,
+			SubObjectInstanceId: obj.subObjectInstanceId,
+			PerformanceMetric:   t.PerformanceMetric,
+			PerformanceValue:    value.Value,
+		})
+	}
+	t.above = above
+}
+
+// cross returns whether a measured object is above the threshold after value
+// and the crossing to notify, if any. Within the hysteresis band the previous
+// state is kept.
+func cross(above bool, value, threshold, hysteresis float64) (bool, CrossingDirection) {
+	switch {
+	case !above && value > threshold+hysteresis:
+		return true, CrossingUp
+	case above && value < threshold-hysteresis:
+		return false, CrossingDown
+	}
+	return above, ""
+}
+
+// notifyLocked fans a notification out to matching subscribers without
+// blocking. m.mu must be held.
+func (m *Manager) notifyLocked(n Notification) {
+	for sub := range m.subscribers {
+		if !matches(sub.filter, n) {
+			continue
+		}
+		select {
+		case sub.events <- n:
+		default:
+			m.logger.Warn("drop slow pm subscriber", zap.String("notificationType", string(n.NotificationType)))
+			m.closeLocked(sub, event.ErrSlowSubscriber)
+		}
+	}
+}
+
+func (j *job) snapshot() PmJob {
+	res := j.PmJob
+	res.Reports = make([]string, 0, len(j.reports))
+	for _, r := range j.reports {
+		res.Reports = append(res.Reports, r.ReportId)
+	}
+	return res
+}
+
+func validateObjects(objectType event.ResourceType, ids []string) error {
+	if objectType != event.ResourceTypeCompute {
+		return &apperrors.ErrInvalidArgument{Field: "objectType", Reason: fmt.Sprintf("only '%s' is supported", event.ResourceTypeCompute)}
+	}
+	if len(ids) == 0 {
+		return &apperrors.ErrInvalidArgument{Field: "objectInstanceIds", Reason: "at least one object instance id is required"}
+	}
+	if slices.Contains(ids, "") {
+		return &apperrors.ErrInvalidArgument{Field: "objectInstanceIds", Reason: "cannot contain an empty id"}
+	}
+	return nil
+}
+
+func validateMetric(field string, metric PerformanceMetric) error {
+	if _, ok := metrics[metric]; !ok {
+		return &apperrors.ErrInvalidArgument{Field: field, Reason: fmt.Sprintf("unsupported metric '%s', supported: %v", metric, SupportedMetrics())}
+	}
+	return nil
+}
+
+func filterList[T any](list []T, filterStr string) ([]T, error) {
+	if filterStr == "" {
+		return list, nil
+	}
+	res, err := filter.FilterList(list, filterStr)
+	if err != nil {
+		return nil, &apperrors.ErrInvalidArgument{Field: "filter", Reason: err.Error()}
+	}
+	return res, nil
+}
+
+// matches evaluates a subscription filter against a notification. The filter
+// was validated on subscribe, so an evaluation error is treated as no match.
+func matches(filterStr string, n Notification) bool {
+	if filterStr == "" {
+		return true
+	}
+	res, err := filter.FilterList([]Notification{n}, filterStr)
+	return err == nil && len(res) == 1
+}
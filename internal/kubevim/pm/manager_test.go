@@ -0,0 +1,329 @@
+package pm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakePrometheus serves canned query results and records the queries it got.
+type fakePrometheus struct {
+	mu      sync.Mutex
+	queries []string
+	// result is the JSON "data" of the next answer.
+	result string
+}
+
+func (p *fakePrometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queries = append(p.queries, r.Form.Get("query"))
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success","data":%s}`, p.result)
+}
+
+func (p *fakePrometheus) setResult(result string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = result
+}
+
+func newTestManager(t *testing.T, store Store) (*Manager, *fakePrometheus) {
+	prom := &fakePrometheus{result: `{"resultType":"vector","result":[]}`}
+	srv := httptest.NewServer(prom)
+	t.Cleanup(srv.Close)
+	c, err := api.NewClient(api.Config{Address: srv.URL})
+	require.NoError(t, err)
+	return NewManager(zap.NewNop(), promv1.NewAPI(c), store, time.Second, time.Minute), prom
+}
+
+func jobRequest() *PmJobRequest {
+	return &PmJobRequest{
+		ObjectType:         event.ResourceTypeCompute,
+		ObjectInstanceIds:  []string{"vm-a"},
+		PerformanceMetrics: []PerformanceMetric{MetricVCpuUsageMean, MetricVNetByteIncoming},
+		CollectionPeriod:   60,
+		ReportingPeriod:    300,
+	}
+}
+
+func receive(t *testing.T, sub *Subscription) Notification {
+	t.Helper()
+	select {
+	case n := <-sub.Events():
+		return n
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for pm notification")
+	}
+	return Notification{}
+}
+
+func TestCreatePmJobValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*PmJobRequest)
+		field  string
+	}{
+		{"network object", func(r *PmJobRequest) { r.ObjectType = event.ResourceTypeNetwork }, "objectType"},
+		{"no objects", func(r *PmJobRequest) { r.ObjectInstanceIds = nil }, "objectInstanceIds"},
+		{"empty object id", func(r *PmJobRequest) { r.ObjectInstanceIds = []string{""} }, "objectInstanceIds"},
+		{"no metrics", func(r *PmJobRequest) { r.PerformanceMetrics = nil }, "performanceMetrics"},
+		{"unknown metric", func(r *PmJobRequest) { r.PerformanceMetrics = []PerformanceMetric{"CpuTemperature"} }, "performanceMetrics"},
+		{"no collection period", func(r *PmJobRequest) { r.CollectionPeriod = 0 }, "collectionPeriod"},
+		{"reporting not a multiple", func(r *PmJobRequest) { r.ReportingPeriod = 90 }, "reportingPeriod"},
+		{"too many values", func(r *PmJobRequest) { r.CollectionPeriod, r.ReportingPeriod = 1, 3600 }, "reportingPeriod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestManager(t, nil)
+			req := jobRequest()
+			tt.modify(req)
+			_, err := m.CreatePmJob(t.Context(), req)
+			var invalid *apperrors.ErrInvalidArgument
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.field, invalid.Field)
+		})
+	}
+}
+
+func TestPmJobLifecycle(t *testing.T) {
+	m, prom := newTestManager(t, nil)
+	sub, err := m.Subscribe(t.Context(), "filter=(eq,notificationType,PerformanceInformationAvailableNotification)")
+	require.NoError(t, err)
+
+	j, err := m.CreatePmJob(t.Context(), jobRequest())
+	require.NoError(t, err)
+	jobs, err := m.QueryPmJobs(fmt.Sprintf("filter=(eq,pmJobId,%s)", j.PmJobId))
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Empty(t, jobs[0].Reports)
+
+	prom.setResult(`{"resultType":"matrix","result":[
+		{"metric":{"compute_id":"vm-a","vnic_id":"eth0"},"values":[[1700000000,"10"],[1700000060,"20"]]}
+	]}`)
+	end := time.Unix(1700000060, 0)
+	report, err := m.collect(t.Context(), j, end)
+	require.NoError(t, err)
+	require.Len(t, prom.queries, 2, "one range query per metric")
+	assert.Contains(t, prom.queries[0], `compute_id=~"vm-a"`)
+	require.Len(t, report.Entries, 2)
+	assert.Equal(t, MetricVCpuUsageMean, report.Entries[0].PerformanceMetric)
+	assert.Equal(t, "eth0", report.Entries[1].SubObjectInstanceId)
+	assert.Equal(t, 20.0, report.Entries[1].PerformanceValues[1].Value)
+
+	m.addReport(report)
+	available := receive(t, sub)
+	assert.Equal(t, j.PmJobId, available.PmJobId)
+	assert.Equal(t, report.ReportId, available.ReportId)
+
+	got, err := m.GetPerformanceReport(j.PmJobId, report.ReportId)
+	require.NoError(t, err)
+	assert.Equal(t, report, got)
+	jobs, err = m.QueryPmJobs("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{report.ReportId}, jobs[0].Reports)
+
+	var notFound *apperrors.ErrNotFound
+	_, err = m.GetPerformanceReport(j.PmJobId, "missing")
+	assert.ErrorAs(t, err, &notFound)
+
+	require.NoError(t, m.DeletePmJob(t.Context(), j.PmJobId))
+	assert.ErrorAs(t, m.DeletePmJob(t.Context(), j.PmJobId), &notFound)
+	_, err = m.GetPerformanceReport(j.PmJobId, report.ReportId)
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestPmJobKeepsLatestReports(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	j, err := m.CreatePmJob(t.Context(), jobRequest())
+	require.NoError(t, err)
+	var last string
+	for i := 0; i < maxReportsPerJob+2; i++ {
+		last = fmt.Sprintf("r%d", i)
+		m.addReport(&PerformanceReport{ReportId: last, PmJobId: j.PmJobId})
+	}
+	jobs, err := m.QueryPmJobs("")
+	require.NoError(t, err)
+	require.Len(t, jobs[0].Reports, maxReportsPerJob)
+	assert.Equal(t, "r2", jobs[0].Reports[0])
+	assert.Equal(t, last, jobs[0].Reports[maxReportsPerJob-1])
+}
+
+func TestCross(t *testing.T) {
+	tests := []struct {
+		name      string
+		above     bool
+		value     float64
+		wantAbove bool
+		want      CrossingDirection
+	}{
+		{"rises above", false, 91, true, CrossingUp},
+		{"within band from below", false, 85, false, ""},
+		{"within band from above", true, 75, true, ""},
+		{"falls below", true, 69, false, CrossingDown},
+		{"stays above", true, 95, true, ""},
+		{"stays below", false, 10, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			above, dir := cross(tt.above, tt.value, 80, 10)
+			assert.Equal(t, tt.wantAbove, above)
+			assert.Equal(t, tt.want, dir)
+		})
+	}
+}
+
+func TestThresholdEvaluation(t *testing.T) {
+	m, prom := newTestManager(t, nil)
+	_, err := m.CreateThreshold(t.Context(), &ThresholdRequest{
+		ObjectType:        event.ResourceTypeCompute,
+		ObjectInstanceIds: []string{"vm-a"},
+		PerformanceMetric: MetricVCpuUsageMean,
+		ThresholdValue:    80,
+		Hysteresis:        5,
+	})
+	require.NoError(t, err)
+	sub, err := m.Subscribe(t.Context(), "")
+	require.NoError(t, err)
+
+	evaluate := func(value string) {
+		prom.setResult(fmt.Sprintf(`{"resultType":"vector","result":[{"metric":{"compute_id":"vm-a"},"value":[1700000000,%q]}]}`, value))
+		m.evaluateThresholds(t.Context(), time.Unix(1700000000, 0))
+	}
+
+	evaluate("50")
+	assert.Empty(t, sub.Events())
+
+	evaluate("90")
+	up := receive(t, sub)
+	assert.Equal(t, ThresholdCrossedType, up.NotificationType)
+	assert.Equal(t, CrossingUp, up.CrossingDirection)
+	assert.Equal(t, "vm-a", up.ObjectInstanceId)
+	assert.Equal(t, 90.0, up.PerformanceValue)
+
+	evaluate("78")
+	assert.Empty(t, sub.Events(), "within hysteresis")
+
+	evaluate("70")
+	down := receive(t, sub)
+	assert.Equal(t, CrossingDown, down.CrossingDirection)
+}
+
+func TestThresholdLifecycle(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	_, err := m.CreateThreshold(t.Context(), &ThresholdRequest{
+		ObjectType:        event.ResourceTypeCompute,
+		ObjectInstanceIds: []string{"vm-a"},
+		PerformanceMetric: MetricVCpuUsageMean,
+		Hysteresis:        -1,
+	})
+	var invalid *apperrors.ErrInvalidArgument
+	assert.ErrorAs(t, err, &invalid)
+
+	th, err := m.CreateThreshold(t.Context(), &ThresholdRequest{
+		ObjectType:        event.ResourceTypeCompute,
+		ObjectInstanceIds: []string{"vm-a"},
+		PerformanceMetric: MetricVMemoryUsageMean,
+		ThresholdValue:    90,
+	})
+	require.NoError(t, err)
+	thresholds, err := m.QueryThresholds("filter=(eq,performanceMetric,VMemoryUsageMean)")
+	require.NoError(t, err)
+	require.Len(t, thresholds, 1)
+	assert.Equal(t, th.ThresholdId, thresholds[0].ThresholdId)
+
+	require.NoError(t, m.DeleteThreshold(t.Context(), th.ThresholdId))
+	var notFound *apperrors.ErrNotFound
+	assert.ErrorAs(t, m.DeleteThreshold(t.Context(), th.ThresholdId), &notFound)
+}
+
+func TestSubscribeInvalidFilter(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	_, err := m.Subscribe(t.Context(), "filter=(eq,notificationType")
+	var invalid *apperrors.ErrInvalidArgument
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestManagerReloadsPersistedState(t *testing.T) {
+	c := k8stest.NewClient(t)
+	store := NewConfigMapStore(c, c, k8stest.TestNamespace, "kube-vim-pm")
+	m, _ := newTestManager(t, store)
+	j, err := m.CreatePmJob(t.Context(), jobRequest())
+	require.NoError(t, err)
+	kept, err := m.CreateThreshold(t.Context(), &ThresholdRequest{
+		ObjectType:        event.ResourceTypeCompute,
+		ObjectInstanceIds: []string{"vm-a"},
+		PerformanceMetric: MetricVCpuUsageMean,
+		ThresholdValue:    80,
+	})
+	require.NoError(t, err)
+	deleted, err := m.CreateThreshold(t.Context(), &ThresholdRequest{
+		ObjectType:        event.ResourceTypeCompute,
+		ObjectInstanceIds: []string{"vm-b"},
+		PerformanceMetric: MetricVCpuUsageMean,
+		ThresholdValue:    80,
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.DeleteThreshold(t.Context(), deleted.ThresholdId))
+
+	restarted, _ := newTestManager(t, store)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- restarted.Start(ctx) }()
+	require.Eventually(t, func() bool {
+		jobs, err := restarted.QueryPmJobs("")
+		return err == nil && len(jobs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	jobs, err := restarted.QueryPmJobs("")
+	require.NoError(t, err)
+	assert.Equal(t, j.PmJobId, jobs[0].PmJobId)
+	assert.Equal(t, j.PerformanceMetrics, jobs[0].PerformanceMetrics)
+	assert.Empty(t, jobs[0].Reports)
+	thresholds, err := restarted.QueryThresholds("")
+	require.NoError(t, err)
+	assert.Equal(t, []Threshold{*kept}, thresholds)
+
+	require.NoError(t, restarted.DeletePmJob(t.Context(), j.PmJobId))
+	jobRecords, _, err := store.Load(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, jobRecords)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestManagerCloseReleasesContextWatcher(t *testing.T) {
+	m, _ := newTestManager(t, nil)
+	before := runtime.NumGoroutine()
+	subs := make([]*Subscription, 0, 10)
+	for range 10 {
+		sub, err := m.Subscribe(t.Context(), "")
+		require.NoError(t, err)
+		subs = append(subs, sub)
+	}
+	for _, sub := range subs {
+		sub.Close()
+	}
+	// Polled by hand: assert.Eventually runs the condition on goroutines of its own.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -0,0 +1,140 @@
+// Package pm implements virtualised resource performance management (IFA 005/006
+// §7.7 / §8.7): PM jobs that periodically collect IFA 027 metrics of computes
+// into performance reports, and thresholds that notify when a metric crosses a
+// value. kube-vim does not collect counters itself; it queries a
+// Prometheus-compatible endpoint and joins the backend series (KubeVirt, SR-IOV
+// VF exporter) to ETSI ids through the kubevim_*_info correlation metrics.
+package pm
+
+import (
+	"time"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+)
+
+// PerformanceMetric is the IFA 027 name of a collected measurement.
+type PerformanceMetric string
+
+const (
+	// MetricVCpuUsageMean is the mean usage of the compute vCPUs over the
+	// collection period, in percent.
+	MetricVCpuUsageMean PerformanceMetric = "VCpuUsageMean"
+	// MetricVMemoryUsageMean is the mean memory used by the guest over the
+	// collection period, in percent of the memory available to it.
+	MetricVMemoryUsageMean PerformanceMetric = "VMemoryUsageMean"
+	// MetricVNetByteIncoming is the number of bytes received by a vNIC during the
+	// collection period.
+	MetricVNetByteIncoming PerformanceMetric = "VNetByteIncoming"
+	// MetricVNetByteOutgoing is the number of bytes sent by a vNIC during the
+	// collection period.
+	MetricVNetByteOutgoing PerformanceMetric = "VNetByteOutgoing"
+	// MetricVNetPacketIncoming is the number of packets received by a vNIC
+	// during the collection period.
+	MetricVNetPacketIncoming PerformanceMetric = "VNetPacketIncoming"
+	// MetricVNetPacketOutgoing is the number of packets sent by a vNIC during
+	// the collection period.
+	MetricVNetPacketOutgoing PerformanceMetric = "VNetPacketOutgoing"
+)
+
+// PmJobRequest is the IFA 005 §7.7.2 CreatePmJob input. Periods are in seconds.
+type PmJobRequest struct {
+	ObjectType         event.ResourceType  `json:"objectType"`
+	ObjectInstanceIds  []string            `json:"objectInstanceIds"`
+	PerformanceMetrics []PerformanceMetric `json:"performanceMetrics"`
+	// CollectionPeriod is the granularity of the collected values.
+	CollectionPeriod int32 `json:"collectionPeriod"`
+	// ReportingPeriod is how often a performance report is produced. It is a
+	// multiple of CollectionPeriod; every report holds ReportingPeriod /
+	// CollectionPeriod values per measured object.
+	ReportingPeriod int32 `json:"reportingPeriod"`
+}
+
+// PmJob is the IFA 005 §8.7.2 PM job.
+type PmJob struct {
+	PmJobId            string              `json:"pmJobId"`
+	ObjectType         event.ResourceType  `json:"objectType"`
+	ObjectInstanceIds  []string            `json:"objectInstanceIds"`
+	PerformanceMetrics []PerformanceMetric `json:"performanceMetrics"`
+	CollectionPeriod   int32               `json:"collectionPeriod"`
+	ReportingPeriod    int32               `json:"reportingPeriod"`
+	// Reports lists the ids of the reports still available, oldest first.
+	Reports []string `json:"reports"`
+}
+
+// PerformanceReport is the IFA 005 §8.7.5 performance report of a PM job.
+type PerformanceReport struct {
+	ReportId string        `json:"reportId"`
+	PmJobId  string        `json:"pmJobId"`
+	Entries  []ReportEntry `json:"entries"`
+}
+
+// ReportEntry holds the values collected for one metric of one measured object.
+// SubObjectInstanceId is the vNIC id for per-vNIC metrics.
+type ReportEntry struct {
+	ObjectType          event.ResourceType `json:"objectType"`
+	ObjectInstanceId    string             `json:"objectInstanceId"`
+	SubObjectInstanceId string             `json:"subObjectInstanceId,omitempty"`
+	PerformanceMetric   PerformanceMetric  `json:"performanceMetric"`
+	PerformanceValues   []PerformanceValue `json:"performanceValues"`
+}
+
+// PerformanceValue is one collected value and the end of its collection period.
+type PerformanceValue struct {
+	TimeStamp time.Time `json:"timeStamp"`
+	Value     float64   `json:"value"`
+}
+
+// ThresholdRequest is the IFA 005 §7.7.7 CreateThreshold input.
+type ThresholdRequest struct {
+	ObjectType        event.ResourceType `json:"objectType"`
+	ObjectInstanceIds []string           `json:"objectInstanceIds"`
+	PerformanceMetric PerformanceMetric  `json:"performanceMetric"`
+	ThresholdValue    float64            `json:"thresholdValue"`
+	// Hysteresis keeps a value oscillating around ThresholdValue from notifying
+	// on every evaluation: an UP crossing needs a value above ThresholdValue +
+	// Hysteresis, a DOWN crossing one below ThresholdValue - Hysteresis.
+	Hysteresis float64 `json:"hysteresis"`
+}
+
+// Threshold is the IFA 005 §8.7.6 threshold.
+type Threshold struct {
+	ThresholdId       string             `json:"thresholdId"`
+	ObjectType        event.ResourceType `json:"objectType"`
+	ObjectInstanceIds []string           `json:"objectInstanceIds"`
+	PerformanceMetric PerformanceMetric  `json:"performanceMetric"`
+	ThresholdValue    float64            `json:"thresholdValue"`
+	Hysteresis        float64            `json:"hysteresis"`
+}
+
+// NotificationType is the IFA 005 §8.7.3/§8.7.4 PM notification kind.
+type NotificationType string
+
+const (
+	PerformanceInformationAvailableType NotificationType = "PerformanceInformationAvailableNotification"
+	ThresholdCrossedType                NotificationType = "ThresholdCrossedNotification"
+)
+
+// CrossingDirection tells whether a threshold was crossed upwards or downwards.
+type CrossingDirection string
+
+const (
+	CrossingUp   CrossingDirection = "UP"
+	CrossingDown CrossingDirection = "DOWN"
+)
+
+// Notification is a PM notification. PerformanceInformationAvailable carries
+// the job and report ids; ThresholdCrossed carries the threshold, the measured
+// object and the value that crossed it.
+type Notification struct {
+	NotificationType    NotificationType   `json:"notificationType"`
+	TimeStamp           time.Time          `json:"timeStamp"`
+	ObjectType          event.ResourceType `json:"objectType"`
+	PmJobId             string             `json:"pmJobId,omitempty"`
+	ReportId            string             `json:"reportId,omitempty"`
+	ThresholdId         string             `json:"thresholdId,omitempty"`
+	CrossingDirection   CrossingDirection  `json:"crossingDirection,omitempty"`
+	ObjectInstanceId    string             `json:"objectInstanceId,omitempty"`
+	SubObjectInstanceId string             `json:"subObjectInstanceId,omitempty"`
+	PerformanceMetric   PerformanceMetric  `json:"performanceMetric,omitempty"`
+	PerformanceValue    float64            `json:"performanceValue,omitempty"`
+}
@@ -0,0 +1,155 @@
+package pm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Labels the correlation joins attach to every measured series.
+const (
+	computeIdLabel = "compute_id"
+	vnicIdLabel    = "vnic_id"
+)
+
+// metricDef describes how a metric is computed from the backend series.
+type metricDef struct {
+	// perVnic metrics are measured per vNIC (the report sub-object) rather than
+	// per compute.
+	perVnic bool
+	// compute is the backend expression over a window, aggregated by the
+	// KubeVirt VMI name. Only set for per-compute metrics.
+	compute string
+	// bridge and sriov are the per-vNIC backend expressions for bridge/virtio
+	// vNICs (by VMI name and interface) and SR-IOV VFs (by PCI address).
+	bridge string
+	sriov  string
+}
+
+// metrics is the catalogue of supported IFA 027 metrics. %[1]s is the window.
+var metrics = map[PerformanceMetric]metricDef{
+	MetricVCpuUsageMean: {
+		compute: `100 * avg by (name) (rate(kubevirt_vmi_vcpu_seconds_total{state="running"}[%[1]s]))`,
+	},
+	MetricVMemoryUsageMean: {
+		compute: `100 * avg by (name) (avg_over_time(kubevirt_vmi_memory_used_bytes[%[1]s])) / avg by (name) (avg_over_time(kubevirt_vmi_memory_available_bytes[%[1]s]))`,
+	},
+	MetricVNetByteIncoming: {
+		perVnic: true,
+		bridge:  `sum by (name, interface) (increase(kubevirt_vmi_network_receive_bytes_total[%[1]s]))`,
+		sriov:   `sum by (pciAddr) (increase(sriov_vf_rx_bytes[%[1]s]))`,
+	},
+	MetricVNetByteOutgoing: {
+		perVnic: true,
+		bridge:  `sum by (name, interface) (increase(kubevirt_vmi_network_transmit_bytes_total[%[1]s]))`,
+		sriov:   `sum by (pciAddr) (increase(sriov_vf_tx_bytes[%[1]s]))`,
+	},
+	MetricVNetPacketIncoming: {
+		perVnic: true,
+		bridge:  `sum by (name, interface) (increase(kubevirt_vmi_network_receive_packets_total[%[1]s]))`,
+		sriov:   `sum by (pciAddr) (increase(sriov_vf_rx_packets[%[1]s]))`,
+	},
+	MetricVNetPacketOutgoing: {
+		perVnic: true,
+		bridge:  `sum by (name, interface) (increase(kubevirt_vmi_network_transmit_packets_total[%[1]s]))`,
+		sriov:   `sum by (pciAddr) (increase(sriov_vf_tx_packets[%[1]s]))`,
+	},
+}
+
+// SupportedMetrics returns the metrics PM jobs and thresholds accept, sorted.
+func SupportedMetrics() []PerformanceMetric {
+	res := make([]PerformanceMetric, 0, len(metrics))
+	for m := range metrics {
+		res = append(res, m)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// buildQuery returns the PromQL computing metric over window for the given
+// computes. The backend series are joined to kubevim_compute_info or
+// kubevim_vnic_info, so every result series carries compute_id (and vnic_id for
+// per-vNIC metrics). The info side is reduced with max so a label change on the
+// info metric cannot produce a many-to-many match.
+func buildQuery(metric PerformanceMetric, computeIds []string, window time.Duration) (string, error) {
+	def, ok := metrics[metric]
+	if !ok {
+		return "", fmt.Errorf("unsupported performance metric '%s'", metric)
+	}
+	ids := idMatcher(computeIds)
+	w := model.Duration(window).String()
+	if !def.perVnic {
+		return fmt.Sprintf(`(%s) * on(name) group_left(compute_id) max by (name, compute_id) (label_replace(kubevim_compute_info{compute_id=~%s}, "name", "$1", "compute_name", "(.+)"))`,
+			fmt.Sprintf(def.compute, w), ids), nil
+	}
+	bridge := fmt.Sprintf(`(%s) * on(name, interface) group_left(compute_id, vnic_id) max by (name, interface, compute_id, vnic_id) (label_replace(label_replace(kubevim_vnic_info{type="TYPE_VIRTUAL_NIC_BRIDGE", compute_id=~%s}, "name", "$1", "compute_name", "(.+)"), "interface", "$1", "vnic_id", "(.+)"))`,
+		fmt.Sprintf(def.bridge, w), ids)
+	sriov := fmt.Sprintf(`(%s) * on(pciAddr) group_left(compute_id, vnic_id) max by (pciAddr, compute_id, vnic_id) (label_replace(kubevim_vnic_info{type="TYPE_VIRTUAL_NIC_SRIOV", compute_id=~%s}, "pciAddr", "$1", "pci_address", "(.+)"))`,
+		fmt.Sprintf(def.sriov, w), ids)
+	return bridge + " or " + sriov, nil
+}
+
+// idMatcher returns a quoted PromQL regex matching exactly the given ids.
+func idMatcher(ids []string) string {
+	quoted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		quoted = append(quoted, regexp.QuoteMeta(id))
+	}
+	return strconv.Quote(strings.Join(quoted, "|"))
+}
+
+// measurement is one value of a measured object read from a query result.
+type measurement struct {
+	objectInstanceId    string
+	subObjectInstanceId string
+	values              []PerformanceValue
+}
+
+// parseResult converts an instant (vector) or range (matrix) query result into
+// measurements. Series without a compute_id, which cannot be attributed, are
+// skipped.
+func parseResult(value model.Value) ([]measurement, error) {
+	var res []measurement
+	switch v := value.(type) {
+	case model.Vector:
+		for _, s := range v {
+			if m, ok := newMeasurement(s.Metric); ok {
+				m.values = []PerformanceValue{{TimeStamp: s.Timestamp.Time().UTC(), Value: float64(s.Value)}}
+				res = append(res, m)
+			}
+		}
+	case model.Matrix:
+		for _, s := range v {
+			m, ok := newMeasurement(s.Metric)
+			if !ok {
+				continue
+			}
+			for _, p := range s.Values {
+				m.values = append(m.values, PerformanceValue{TimeStamp: p.Timestamp.Time().UTC(), Value: float64(p.Value)})
+			}
+			res = append(res, m)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected prometheus result type '%s'", value.Type())
+	}
+	slices.SortFunc(res, func(a, b measurement) int {
+		if c := strings.Compare(a.objectInstanceId, b.objectInstanceId); c != 0 {
+			return c
+		}
+		return strings.Compare(a.subObjectInstanceId, b.subObjectInstanceId)
+	})
+	return res, nil
+}
+
+func newMeasurement(labels model.Metric) (measurement, bool) {
+	id := string(labels[computeIdLabel])
+	if id == "" {
+		return measurement{}, false
+	}
+	return measurement{objectInstanceId: id, subObjectInstanceId: string(labels[vnicIdLabel])}, true
+}
@@ -0,0 +1,81 @@
+package pm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		metric   PerformanceMetric
+		contains []string
+	}{
+		{
+			name:   "per compute",
+			metric: MetricVCpuUsageMean,
+			contains: []string{
+				`rate(kubevirt_vmi_vcpu_seconds_total{state="running"}[1m])`,
+				`on(name) group_left(compute_id)`,
+				`kubevim_compute_info{compute_id=~"vm-a|vm-b"}`,
+			},
+		},
+		{
+			name:   "per vnic",
+			metric: MetricVNetByteIncoming,
+			contains: []string{
+				`increase(kubevirt_vmi_network_receive_bytes_total[1m])`,
+				`kubevim_vnic_info{type="TYPE_VIRTUAL_NIC_BRIDGE", compute_id=~"vm-a|vm-b"}`,
+				`) or (`,
+				`increase(sriov_vf_rx_bytes[1m])`,
+				`"pciAddr", "$1", "pci_address", "(.+)"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := buildQuery(tt.metric, []string{"vm-a", "vm-b"}, time.Minute)
+			require.NoError(t, err)
+			for _, s := range tt.contains {
+				assert.Contains(t, q, s)
+			}
+		})
+	}
+
+	_, err := buildQuery("NoSuchMetric", []string{"vm-a"}, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestIdMatcher(t *testing.T) {
+	assert.Equal(t, `"a\\.b|c\""`, idMatcher([]string{"a.b", `c"`}))
+}
+
+func TestParseResult(t *testing.T) {
+	ts := model.TimeFromUnix(1700000000)
+	vector := model.Vector{
+		{Metric: model.Metric{computeIdLabel: "vm-b"}, Value: 2, Timestamp: ts},
+		{Metric: model.Metric{computeIdLabel: "vm-a", vnicIdLabel: "eth0"}, Value: 1, Timestamp: ts},
+		{Metric: model.Metric{"name": "unattributed"}, Value: 3, Timestamp: ts},
+	}
+	res, err := parseResult(vector)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "vm-a", res[0].objectInstanceId)
+	assert.Equal(t, "eth0", res[0].subObjectInstanceId)
+	assert.Equal(t, []PerformanceValue{{TimeStamp: ts.Time().UTC(), Value: 1}}, res[0].values)
+
+	matrix := model.Matrix{
+		{Metric: model.Metric{computeIdLabel: "vm-a"}, Values: []model.SamplePair{{Timestamp: ts, Value: 1}, {Timestamp: ts.Add(time.Minute), Value: 2}}},
+	}
+	res, err = parseResult(matrix)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Len(t, res[0].values, 2)
+
+	_, err = parseResult(&model.Scalar{Value: 1, Timestamp: ts})
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package pm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	common "github.com/kube-nfv/kube-vim/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	jobKeyPrefix       = "job."
+	thresholdKeyPrefix = "threshold."
+)
+
+// JobRecord is a persisted PM job. Its reports are not persisted.
+type JobRecord struct {
+	PmJob   PmJob     `json:"pmJob"`
+	Created time.Time `json:"created"`
+}
+
+// ThresholdRecord is a persisted threshold. Its crossing state is not
+// persisted.
+type ThresholdRecord struct {
+	Threshold Threshold `json:"threshold"`
+	Created   time.Time `json:"created"`
+}
+
+// Store persists PM jobs and thresholds across kube-vim restarts.
+type Store interface {
+	Load(ctx context.Context) ([]JobRecord, []ThresholdRecord, error)
+	PutJob(ctx context.Context, j JobRecord) error
+	DeleteJob(ctx context.Context, pmJobId string) error
+	PutThreshold(ctx context.Context, t ThresholdRecord) error
+	DeleteThreshold(ctx context.Context, thresholdId string) error
+}
+
+// configMapStore keeps every PM job and threshold as a JSON entry of a single
+// ConfigMap, keyed by "job.<id>" and "threshold.<id>". It reads through the
+// uncached reader, so an entry is visible as soon as it is written.
+type configMapStore struct {
+	client    client.Client
+	reader    client.Reader
+	namespace string
+	name      string
+}
+
+func NewConfigMapStore(c client.Client, reader client.Reader, namespace, name string) *configMapStore {
+	return &configMapStore{
+		client:    c,
+		reader:    reader,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s *configMapStore) Load(ctx context.Context) ([]JobRecord, []ThresholdRecord, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("get pm configmap '%s/%s': %w", s.namespace, s.name, err)
+	}
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var jobs []JobRecord
+	var thresholds []ThresholdRecord
+	for _, key := range keys {
+		switch {
+		case strings.HasPrefix(key, jobKeyPrefix):
+			j := JobRecord{}
+			if err := json.Unmarshal([]byte(cm.Data[key]), &j); err != nil {
+				return nil, nil, fmt.Errorf("decode pm job '%s': %w", strings.TrimPrefix(key, jobKeyPrefix), err)
+			}
+			jobs = append(jobs, j)
+		case strings.HasPrefix(key, thresholdKeyPrefix):
+			t := ThresholdRecord{}
+			if err := json.Unmarshal([]byte(cm.Data[key]), &t); err != nil {
+				return nil, nil, fmt.Errorf("decode threshold '%s': %w", strings.TrimPrefix(key, thresholdKeyPrefix), err)
+			}
+			thresholds = append(thresholds, t)
+		}
+	}
+	return jobs, thresholds, nil
+}
+
+func (s *configMapStore) PutJob(ctx context.Context, j JobRecord) error {
+	raw, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("encode pm job '%s': %w", j.PmJob.PmJobId, err)
+	}
+	return s.update(ctx, func(data map[string]string) { data[jobKeyPrefix+j.PmJob.PmJobId] = string(raw) })
+}
+
+func (s *configMapStore) DeleteJob(ctx context.Context, pmJobId string) error {
+	return s.update(ctx, func(data map[string]string) { delete(data, jobKeyPrefix+pmJobId) })
+}
+
+func (s *configMapStore) PutThreshold(ctx context.Context, t ThresholdRecord) error {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("encode threshold '%s': %w", t.Threshold.ThresholdId, err)
+	}
+	return s.update(ctx, func(data map[string]string) { data[thresholdKeyPrefix+t.Threshold.ThresholdId] = string(raw) })
+}
+
+func (s *configMapStore) DeleteThreshold(ctx context.Context, thresholdId string) error {
+	return s.update(ctx, func(data map[string]string) { delete(data, thresholdKeyPrefix+thresholdId) })
+}
+
+func (s *configMapStore) update(ctx context.Context, mutate func(map[string]string)) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.name}, cm); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return err
+			}
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      s.name,
+					Namespace: s.namespace,
+					Labels: map[string]string{
+						common.K8sManagedByLabel: common.KubeNfvName,
+					},
+				},
+				Data: map[string]string{},
+			}
+			mutate(cm.Data)
+			return s.client.Create(ctx, cm)
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		mutate(cm.Data)
+		return s.client.Update(ctx, cm)
+	})
+	if err != nil {
+		return fmt.Errorf("update pm configmap '%s/%s': %w", s.namespace, s.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// The admin service of kube-vim-api has no DeleteImage RPC yet. Until it does,
+// kube-vim serves it from the hand-written Images service below. The request is
+// the image id, and the force flag travels as image.DeleteForceMetadataKey
+// request metadata.
+const (
+	ImagesServiceName                 = "admin.kubevim.kubenfv.api.pb.admin.Images"
+	Images_DeleteImage_FullMethodName = "/" + ImagesServiceName + "/DeleteImage"
+)
+
+// ImagesServer is the server API of the Images service.
+type ImagesServer interface {
+	DeleteImage(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+}
+
+var imagesServiceDesc = grpc.ServiceDesc{
+	ServiceName: ImagesServiceName,
+	HandlerType: (*ImagesServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "DeleteImage",
+		Handler:    interim.UnaryHandler(Images_DeleteImage_FullMethodName, ImagesServer.DeleteImage),
+	}},
+}
+
+func RegisterImagesServer(s grpc.ServiceRegistrar, srv ImagesServer) {
+	s.RegisterService(&imagesServiceDesc, srv)
+}
+
+// DeleteImage calls the Images DeleteImage RPC over cc.
+func DeleteImage(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier, force bool) error {
+	if force {
+		ctx = metadata.AppendToOutgoingContext(ctx, image.DeleteForceMetadataKey, "true")
+	}
+	return cc.Invoke(ctx, Images_DeleteImage_FullMethodName, id, new(emptypb.Empty))
+}
+
+// DeleteImage deletes an image, refusing while a compute uses it unless the
+// force metadata is set.
+func (s *AdminServer) DeleteImage(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	deleter, ok := s.ImageMgr.(image.Deleter)
+	if !ok {
+		return nil, fmt.Errorf("image deletion with the configured image manager: %w", apperrors.ErrUnsupported)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	force := false
+	if v := md.Get(image.DeleteForceMetadataKey); len(v) > 0 {
+		force = v[0] == "true"
+	}
+	if err := deleter.DeleteImage(ctx, id, force); err != nil {
+		return nil, fmt.Errorf("delete image '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -2,11 +2,13 @@ package admin
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type AdminServer struct {
@@ -16,13 +18,53 @@ type AdminServer struct {
 }
 
 func (s *AdminServer) DownloadImage(ctx context.Context, req *admin.DownloadImageRequest) (*admin.DownloadImageResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DownloadImage not implemented")
+	res, err := s.ImageMgr.DownloadImage(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("download image '%s': %w", req.GetMetadata().GetName(), err)
+	}
+	return res, nil
 }
 
 func (s *AdminServer) GetImageDownloadStatus(ctx context.Context, req *admin.GetImageDownloadStatusRequest) (*admin.GetImageDownloadStatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetImageDownloadStatus not implemented")
+	res, err := s.ImageMgr.GetImageDownloadStatus(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("get download status of image '%s': %w", req.GetImageId().GetValue(), err)
+	}
+	return res, nil
 }
 
+// SetupImageUploadProxy registers an image to be uploaded and returns where to
+// upload it. The SetupImageUploadProxy messages carry no fields yet, so the
+// image name, storage size and class are read from the request metadata, and
+// the image id, upload URL and token are returned as response header metadata
+// (see the image.Upload*MetadataKey constants).
 func (s *AdminServer) SetupImageUploadProxy(ctx context.Context, req *admin.SetupImageUploadProxyRequest) (*admin.SetupImageUploadProxyResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SetupImageUploadProxy not implemented")
+	uploader, ok := s.ImageMgr.(image.Uploader)
+	if !ok {
+		return nil, fmt.Errorf("image upload with the configured image manager: %w", apperrors.ErrUnsupported)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	uploadReq := &image.UploadRequest{
+		Name:         first(image.UploadImageNameMetadataKey),
+		StorageSize:  first(image.UploadStorageSizeMetadataKey),
+		StorageClass: first(image.UploadStorageClassMetadataKey),
+	}
+	target, err := uploader.SetupImageUpload(ctx, uploadReq)
+	if err != nil {
+		return nil, fmt.Errorf("setup upload of image '%s': %w", uploadReq.Name, err)
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(
+		image.UploadImageIdMetadataKey, target.ImageId.GetValue(),
+		image.UploadUrlMetadataKey, target.Url,
+		image.UploadTokenMetadataKey, target.Token,
+	)); err != nil {
+		return nil, fmt.Errorf("set upload target header: %w", err)
+	}
+	return &admin.SetupImageUploadProxyResponse{}, nil
 }
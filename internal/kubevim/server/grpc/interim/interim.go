@@ -0,0 +1,92 @@
+// Package interim holds the helpers of the hand-written gRPC services kube-vim
+// serves until kube-vim-api has the messages and RPCs they stand in for.
+package interim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// UnaryHandler returns the method handler of an interim service, implemented
+// by S, calling rpc with the decoded request.
+func UnaryHandler[S any, Req any, PReq interface {
+	*Req
+	proto.Message
+}, Res proto.Message](fullMethod string, rpc func(S, context.Context, PReq) (Res, error)) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := PReq(new(Req))
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return rpc(srv.(S), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		handler := func(ctx context.Context, req any) (any, error) {
+			return rpc(srv.(S), ctx, req.(PReq))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+// ServerStreamHandler returns the stream handler of a server-streaming method
+// of an interim service, implemented by S, calling rpc with the decoded request.
+func ServerStreamHandler[S any, Req any, PReq interface {
+	*Req
+	proto.Message
+}, Res any](rpc func(S, PReq, grpc.ServerStreamingServer[Res]) error) grpc.StreamHandler {
+	return func(srv any, stream grpc.ServerStream) error {
+		in := PReq(new(Req))
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		return rpc(srv.(S), in, &grpc.GenericServerStream[Req, Res]{ServerStream: stream})
+	}
+}
+
+// NewServerStream opens the server-streaming method desc of an interim service
+// over cc and sends it the request.
+func NewServerStream[Req any, Res any](ctx context.Context, cc grpc.ClientConnInterface, desc *grpc.StreamDesc, fullMethod string, req *Req) (grpc.ServerStreamingClient[Res], error) {
+	stream, err := cc.NewStream(ctx, desc, fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Req, Res]{ClientStream: stream}
+	if err := x.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := x.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// DecodeStruct decodes the JSON form of st into v, rejecting unknown fields.
+func DecodeStruct(st *structpb.Struct, v any) error {
+	raw, err := protojson.Marshal(st)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// EncodeStruct returns the JSON form of v as a Struct.
+func EncodeStruct(v any) (*structpb.Struct, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	st := &structpb.Struct{}
+	if err := protojson.Unmarshal(raw, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
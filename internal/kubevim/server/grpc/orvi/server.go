@@ -0,0 +1,416 @@
+package orvi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/orvi"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no Or-Vi service yet. Until it does, kube-vim serves the
+// operations of orvi.Service from the hand-written OrVi service below.
+//
+// The software image operations reuse the kube-vim-api messages: AddImage
+// takes the admin DownloadImageRequest and QueryImage the image Identifier,
+// and both answer the SoftwareImageInformation. QueryImages takes a
+// structpb.Struct with an optional SOL 013 filter under filter and answers a
+// QueryImagesResponse. UpdateImage takes the softwareImageId and, under
+// metadata, the JSON form of the admin ImageMetadata; DeleteImage takes the
+// softwareImageId and an optional force.
+//
+// QueryResourceZones takes the optional filter too and answers with the zones
+// under zones. QueryComputeCapacity takes the optional zoneId and
+// computeFlavourId. Results travel as the JSON form of the orvi types in a
+// structpb.Struct.
+const (
+	OrViServiceName                          = "kubenvf.kubevim.api.pb.or_vi.OrVi"
+	OrVi_AddImage_FullMethodName             = "/" + OrViServiceName + "/AddImage"
+	OrVi_QueryImages_FullMethodName          = "/" + OrViServiceName + "/QueryImages"
+	OrVi_QueryImage_FullMethodName           = "/" + OrViServiceName + "/QueryImage"
+	OrVi_UpdateImage_FullMethodName          = "/" + OrViServiceName + "/UpdateImage"
+	OrVi_DeleteImage_FullMethodName          = "/" + OrViServiceName + "/DeleteImage"
+	OrVi_QueryResourceZones_FullMethodName   = "/" + OrViServiceName + "/QueryResourceZones"
+	OrVi_QueryComputeCapacity_FullMethodName = "/" + OrViServiceName + "/QueryComputeCapacity"
+	OrVi_QueryComputeQuota_FullMethodName    = "/" + OrViServiceName + "/QueryComputeQuota"
+	OrVi_QueryNetworkQuota_FullMethodName    = "/" + OrViServiceName + "/QueryNetworkQuota"
+)
+
+// Service serves the Or-Vi operations (see orvi.Service).
+type Service interface {
+	AddImage(ctx context.Context, req *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error)
+	QueryImages(ctx context.Context, filter string) ([]*vivnfm.SoftwareImageInformation, error)
+	QueryImage(ctx context.Context, imageId *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error)
+	UpdateImage(ctx context.Context, imageId *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error)
+	DeleteImage(ctx context.Context, imageId *nfvcommon.Identifier, force bool) error
+	QueryResourceZones(ctx context.Context, filter string) ([]orvi.ResourceZone, error)
+	QueryComputeCapacity(ctx context.Context, zoneId string, flavourId *nfvcommon.Identifier) (*orvi.ComputeCapacity, error)
+	QueryComputeQuota(ctx context.Context) (*orvi.VirtualComputeQuota, error)
+	QueryNetworkQuota(ctx context.Context) (*orvi.VirtualNetworkQuota, error)
+}
+
+// OrViServer is the server API of the OrVi service.
+type OrViServer interface {
+	AddImage(context.Context, *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error)
+	QueryImages(context.Context, *structpb.Struct) (*vivnfm.QueryImagesResponse, error)
+	QueryImage(context.Context, *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error)
+	UpdateImage(context.Context, *structpb.Struct) (*vivnfm.SoftwareImageInformation, error)
+	DeleteImage(context.Context, *structpb.Struct) (*emptypb.Empty, error)
+	QueryResourceZones(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryComputeCapacity(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryComputeQuota(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	QueryNetworkQuota(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+}
+
+// Server implements OrViServer over an orvi.Service.
+type Server struct {
+	// Svc serves the operations. nil disables them.
+	Svc Service
+}
+
+var orViServiceDesc = grpc.ServiceDesc{
+	ServiceName: OrViServiceName,
+	HandlerType: (*OrViServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddImage",
+			Handler:    interim.UnaryHandler(OrVi_AddImage_FullMethodName, OrViServer.AddImage),
+		},
+		{
+			MethodName: "QueryImages",
+			Handler:    interim.UnaryHandler(OrVi_QueryImages_FullMethodName, OrViServer.QueryImages),
+		},
+		{
+			MethodName: "QueryImage",
+			Handler:    interim.UnaryHandler(OrVi_QueryImage_FullMethodName, OrViServer.QueryImage),
+		},
+		{
+			MethodName: "UpdateImage",
+			Handler:    interim.UnaryHandler(OrVi_UpdateImage_FullMethodName, OrViServer.UpdateImage),
+		},
+		{
+			MethodName: "DeleteImage",
+			Handler:    interim.UnaryHandler(OrVi_DeleteImage_FullMethodName, OrViServer.DeleteImage),
+		},
+		{
+			MethodName: "QueryResourceZones",
+			Handler:    interim.UnaryHandler(OrVi_QueryResourceZones_FullMethodName, OrViServer.QueryResourceZones),
+		},
+		{
+			MethodName: "QueryComputeCapacity",
+			Handler:    interim.UnaryHandler(OrVi_QueryComputeCapacity_FullMethodName, OrViServer.QueryComputeCapacity),
+		},
+		{
+			MethodName: "QueryComputeQuota",
+			Handler:    interim.UnaryHandler(OrVi_QueryComputeQuota_FullMethodName, OrViServer.QueryComputeQuota),
+		},
+		{
+			MethodName: "QueryNetworkQuota",
+			Handler:    interim.UnaryHandler(OrVi_QueryNetworkQuota_FullMethodName, OrViServer.QueryNetworkQuota),
+		},
+	},
+}
+
+func RegisterOrViServer(s grpc.ServiceRegistrar, srv OrViServer) {
+	s.RegisterService(&orViServiceDesc, srv)
+}
+
+// AddImage calls the OrVi AddImage RPC over cc.
+func AddImage(ctx context.Context, cc grpc.ClientConnInterface, req *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error) {
+	res := new(vivnfm.SoftwareImageInformation)
+	if err := cc.Invoke(ctx, OrVi_AddImage_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryImages calls the OrVi QueryImages RPC over cc.
+func QueryImages(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*vivnfm.QueryImagesResponse, error) {
+	res := new(vivnfm.QueryImagesResponse)
+	if err := cc.Invoke(ctx, OrVi_QueryImages_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryImage calls the OrVi QueryImage RPC over cc.
+func QueryImage(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	res := new(vivnfm.SoftwareImageInformation)
+	if err := cc.Invoke(ctx, OrVi_QueryImage_FullMethodName, id, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateImage calls the OrVi UpdateImage RPC over cc.
+func UpdateImage(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	raw, err := protojson.Marshal(md)
+	if err != nil {
+		return nil, fmt.Errorf("encode image metadata: %w", err)
+	}
+	metadata := &structpb.Struct{}
+	if err := protojson.Unmarshal(raw, metadata); err != nil {
+		return nil, fmt.Errorf("encode image metadata: %w", err)
+	}
+	req := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"softwareImageId": structpb.NewStringValue(id.GetValue()),
+		"metadata":        structpb.NewStructValue(metadata),
+	}}
+	res := new(vivnfm.SoftwareImageInformation)
+	if err := cc.Invoke(ctx, OrVi_UpdateImage_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteImage calls the OrVi DeleteImage RPC over cc.
+func DeleteImage(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier, force bool) error {
+	req := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"softwareImageId": structpb.NewStringValue(id.GetValue()),
+		"force":           structpb.NewBoolValue(force),
+	}}
+	return cc.Invoke(ctx, OrVi_DeleteImage_FullMethodName, req, new(emptypb.Empty))
+}
+
+// QueryResourceZones calls the OrVi QueryResourceZones RPC over cc.
+func QueryResourceZones(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, OrVi_QueryResourceZones_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryComputeCapacity calls the OrVi QueryComputeCapacity RPC over cc.
+func QueryComputeCapacity(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, OrVi_QueryComputeCapacity_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryComputeQuota calls the OrVi QueryComputeQuota RPC over cc.
+func QueryComputeQuota(ctx context.Context, cc grpc.ClientConnInterface) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, OrVi_QueryComputeQuota_FullMethodName, &emptypb.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryNetworkQuota calls the OrVi QueryNetworkQuota RPC over cc.
+func QueryNetworkQuota(ctx context.Context, cc grpc.ClientConnInterface) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, OrVi_QueryNetworkQuota_FullMethodName, &emptypb.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// filterRequest is the decoded QueryImages and QueryResourceZones request.
+type filterRequest struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+// updateImageRequest is the decoded UpdateImage request. Metadata is the JSON
+// form of the admin ImageMetadata.
+type updateImageRequest struct {
+	SoftwareImageId string          `json:"softwareImageId"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+}
+
+// deleteImageRequest is the decoded DeleteImage request.
+type deleteImageRequest struct {
+	SoftwareImageId string `json:"softwareImageId"`
+	Force           bool   `json:"force,omitempty"`
+}
+
+// computeCapacityRequest is the decoded QueryComputeCapacity request.
+type computeCapacityRequest struct {
+	ZoneId           string `json:"zoneId,omitempty"`
+	ComputeFlavourId string `json:"computeFlavourId,omitempty"`
+}
+
+// AddImage adds a software image and returns its information.
+func (s *Server) AddImage(ctx context.Context, req *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	return svc.AddImage(ctx, req)
+}
+
+// QueryImages returns the software images matching the request filter.
+func (s *Server) QueryImages(ctx context.Context, req *structpb.Struct) (*vivnfm.QueryImagesResponse, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	in := &filterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query images request: %v", err)
+	}
+	images, err := svc.QueryImages(ctx, in.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return &vivnfm.QueryImagesResponse{SoftwareImagesInformation: images}, nil
+}
+
+// QueryImage returns a software image.
+func (s *Server) QueryImage(ctx context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	return svc.QueryImage(ctx, id)
+}
+
+// UpdateImage updates the attributes of a software image and returns its
+// information.
+func (s *Server) UpdateImage(ctx context.Context, req *structpb.Struct) (*vivnfm.SoftwareImageInformation, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	in := &updateImageRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode update image request: %v", err)
+	}
+	if in.SoftwareImageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "decode update image request: softwareImageId is required")
+	}
+	md := &admin.ImageMetadata{}
+	if len(in.Metadata) > 0 {
+		if err := protojson.Unmarshal(in.Metadata, md); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "decode update image request metadata: %v", err)
+		}
+	}
+	return svc.UpdateImage(ctx, &nfvcommon.Identifier{Value: in.SoftwareImageId}, md)
+}
+
+// DeleteImage deletes a software image. An image in use by a compute is only
+// deleted with force.
+func (s *Server) DeleteImage(ctx context.Context, req *structpb.Struct) (*emptypb.Empty, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	in := &deleteImageRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode delete image request: %v", err)
+	}
+	if in.SoftwareImageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "decode delete image request: softwareImageId is required")
+	}
+	if err := svc.DeleteImage(ctx, &nfvcommon.Identifier{Value: in.SoftwareImageId}, in.Force); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// QueryResourceZones returns the resource zones matching the request filter.
+func (s *Server) QueryResourceZones(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	in := &filterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query resource zones request: %v", err)
+	}
+	zones, err := svc.QueryResourceZones(ctx, in.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("query resource zones: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(zones))}
+	for i := range zones {
+		st, err := interim.EncodeStruct(&zones[i])
+		if err != nil {
+			return nil, fmt.Errorf("encode resource zone '%s': %w", zones[i].ZoneId, err)
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"zones": structpb.NewListValue(list),
+	}}, nil
+}
+
+// QueryComputeCapacity returns the compute capacity of the requested zone, or
+// of all zones, optionally in instances of the requested flavour.
+func (s *Server) QueryComputeCapacity(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	in := &computeCapacityRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query compute capacity request: %v", err)
+	}
+	var flavourId *nfvcommon.Identifier
+	if in.ComputeFlavourId != "" {
+		flavourId = &nfvcommon.Identifier{Value: in.ComputeFlavourId}
+	}
+	capacity, err := svc.QueryComputeCapacity(ctx, in.ZoneId, flavourId)
+	if err != nil {
+		return nil, fmt.Errorf("query compute capacity: %w", err)
+	}
+	st, err := interim.EncodeStruct(capacity)
+	if err != nil {
+		return nil, fmt.Errorf("encode compute capacity: %w", err)
+	}
+	return st, nil
+}
+
+// QueryComputeQuota returns the compute quota of the kube-vim resource group.
+func (s *Server) QueryComputeQuota(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	quota, err := svc.QueryComputeQuota(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query compute quota: %w", err)
+	}
+	st, err := interim.EncodeStruct(quota)
+	if err != nil {
+		return nil, fmt.Errorf("encode compute quota: %w", err)
+	}
+	return st, nil
+}
+
+// QueryNetworkQuota returns the network quota of the kube-vim resource group.
+func (s *Server) QueryNetworkQuota(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	svc, err := s.service()
+	if err != nil {
+		return nil, err
+	}
+	quota, err := svc.QueryNetworkQuota(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query network quota: %w", err)
+	}
+	st, err := interim.EncodeStruct(quota)
+	if err != nil {
+		return nil, fmt.Errorf("encode network quota: %w", err)
+	}
+	return st, nil
+}
+
+func (s *Server) service() (Service, error) {
+	if s.Svc == nil {
+		return nil, fmt.Errorf("or-vi: %w", apperrors.ErrUnsupported)
+	}
+	return s.Svc, nil
+}
@@ -0,0 +1,187 @@
+package orvi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	"github.com/kube-nfv/kube-vim-api/pkg/apis/admin"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/orvi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeService answers fixed images, zones, capacity and quotas and records
+// the arguments it got.
+type fakeService struct {
+	filter    string
+	zoneId    string
+	flavourId *nfvcommon.Identifier
+	imageId   *nfvcommon.Identifier
+	metadata  *admin.ImageMetadata
+	force     bool
+}
+
+func (f *fakeService) AddImage(_ context.Context, req *admin.DownloadImageRequest) (*vivnfm.SoftwareImageInformation, error) {
+	return &vivnfm.SoftwareImageInformation{SoftwareImageId: k8stest.ID("img1"), Name: req.GetMetadata().GetName()}, nil
+}
+
+func (f *fakeService) QueryImages(_ context.Context, filter string) ([]*vivnfm.SoftwareImageInformation, error) {
+	f.filter = filter
+	return []*vivnfm.SoftwareImageInformation{{SoftwareImageId: k8stest.ID("img1"), Name: "cirros"}}, nil
+}
+
+func (f *fakeService) QueryImage(_ context.Context, id *nfvcommon.Identifier) (*vivnfm.SoftwareImageInformation, error) {
+	if id.GetValue() != "img1" {
+		return nil, &apperrors.ErrNotFound{Entity: "software image", Identifier: id.GetValue()}
+	}
+	return &vivnfm.SoftwareImageInformation{SoftwareImageId: id, Name: "cirros"}, nil
+}
+
+func (f *fakeService) UpdateImage(_ context.Context, id *nfvcommon.Identifier, md *admin.ImageMetadata) (*vivnfm.SoftwareImageInformation, error) {
+	f.imageId, f.metadata = id, md
+	return &vivnfm.SoftwareImageInformation{SoftwareImageId: id, Name: "cirros", Version: md.Version}, nil
+}
+
+func (f *fakeService) DeleteImage(_ context.Context, id *nfvcommon.Identifier, force bool) error {
+	f.imageId, f.force = id, force
+	return nil
+}
+
+func (f *fakeService) QueryResourceZones(_ context.Context, filter string) ([]orvi.ResourceZone, error) {
+	f.filter = filter
+	return []orvi.ResourceZone{{ZoneId: "zone-a", ZoneName: "zone-a", ZoneState: orvi.ZoneStateAvailable, Hosts: []string{"node-1"}}}, nil
+}
+
+func (f *fakeService) QueryComputeCapacity(_ context.Context, zoneId string, flavourId *nfvcommon.Identifier) (*orvi.ComputeCapacity, error) {
+	f.zoneId, f.flavourId = zoneId, flavourId
+	return &orvi.ComputeCapacity{ZoneId: zoneId, VCpu: orvi.CapacityInformation{TotalCapacity: 8, AvailableCapacity: 6, AllocatedCapacity: 2}}, nil
+}
+
+func (f *fakeService) QueryComputeQuota(context.Context) (*orvi.VirtualComputeQuota, error) {
+	return &orvi.VirtualComputeQuota{ResourceGroupId: k8stest.TestNamespace, NumVCPUs: orvi.QuotaValue{Limit: k8stest.Ptr[int64](16), Used: 2}}, nil
+}
+
+func (f *fakeService) QueryNetworkQuota(context.Context) (*orvi.VirtualNetworkQuota, error) {
+	return &orvi.VirtualNetworkQuota{ResourceGroupId: k8stest.TestNamespace, NumNetworks: orvi.QuotaValue{Used: 3}}, nil
+}
+
+func newConn(t *testing.T, srv OrViServer) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	RegisterOrViServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestOrVi(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("images", func(t *testing.T) {
+		svc := &fakeService{}
+		conn := newConn(t, &Server{Svc: svc})
+		added, err := AddImage(ctx, conn, &admin.DownloadImageRequest{Metadata: &admin.ImageMetadata{Name: "cirros"}})
+		require.NoError(t, err)
+		assert.Equal(t, "cirros", added.GetName())
+
+		req, err := structpb.NewStruct(map[string]any{"filter": "filter=(eq,name,cirros)"})
+		require.NoError(t, err)
+		list, err := QueryImages(ctx, conn, req)
+		require.NoError(t, err)
+		assert.Len(t, list.GetSoftwareImagesInformation(), 1)
+		assert.Equal(t, "filter=(eq,name,cirros)", svc.filter)
+
+		img, err := QueryImage(ctx, conn, k8stest.ID("img1"))
+		require.NoError(t, err)
+		assert.Equal(t, "cirros", img.GetName())
+
+		version := "2.0"
+		updated, err := UpdateImage(ctx, conn, k8stest.ID("img1"), &admin.ImageMetadata{
+			Version:  &version,
+			Metadata: &nfvcommon.Metadata{Fields: map[string]string{"image.kubevim.kubenfv.io/min-disk": "20Gi"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "2.0", updated.GetVersion())
+		assert.Equal(t, "img1", svc.imageId.GetValue())
+		assert.Equal(t, "20Gi", svc.metadata.GetMetadata().GetFields()["image.kubevim.kubenfv.io/min-disk"])
+
+		require.NoError(t, DeleteImage(ctx, conn, k8stest.ID("img1"), true))
+		assert.True(t, svc.force)
+
+		unknown, err := structpb.NewStruct(map[string]any{"softwareImageId": "img1", "metadata": map[string]any{"size": "1Gi"}})
+		require.NoError(t, err)
+		_, err = (&Server{Svc: svc}).UpdateImage(ctx, unknown)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = (&Server{Svc: svc}).DeleteImage(ctx, &structpb.Struct{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("resource zones pass the filter", func(t *testing.T) {
+		svc := &fakeService{}
+		req, err := structpb.NewStruct(map[string]any{"filter": "filter=(eq,zoneState,AVAILABLE)"})
+		require.NoError(t, err)
+		resp, err := QueryResourceZones(ctx, newConn(t, &Server{Svc: svc}), req)
+		require.NoError(t, err)
+		zones := resp.GetFields()["zones"].GetListValue().GetValues()
+		require.Len(t, zones, 1)
+		assert.Equal(t, "zone-a", zones[0].GetStructValue().GetFields()["zoneId"].GetStringValue())
+		assert.Equal(t, "filter=(eq,zoneState,AVAILABLE)", svc.filter)
+	})
+
+	t.Run("compute capacity of a zone and flavour", func(t *testing.T) {
+		svc := &fakeService{}
+		conn := newConn(t, &Server{Svc: svc})
+		req, err := structpb.NewStruct(map[string]any{"zoneId": "zone-a", "computeFlavourId": "small"})
+		require.NoError(t, err)
+		resp, err := QueryComputeCapacity(ctx, conn, req)
+		require.NoError(t, err)
+		assert.Equal(t, 6.0, resp.GetFields()["vCpu"].GetStructValue().GetFields()["availableCapacity"].GetNumberValue())
+		assert.Equal(t, "zone-a", svc.zoneId)
+		assert.Equal(t, "small", svc.flavourId.GetValue())
+
+		_, err = QueryComputeCapacity(ctx, conn, &structpb.Struct{})
+		require.NoError(t, err)
+		assert.Empty(t, svc.zoneId)
+		assert.Nil(t, svc.flavourId)
+
+		unknown, err := structpb.NewStruct(map[string]any{"zone": "zone-a"})
+		require.NoError(t, err)
+		_, err = QueryComputeCapacity(ctx, conn, unknown)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("quotas", func(t *testing.T) {
+		conn := newConn(t, &Server{Svc: &fakeService{}})
+		compute, err := QueryComputeQuota(ctx, conn)
+		require.NoError(t, err)
+		assert.Equal(t, 16.0, compute.GetFields()["numVCPUs"].GetStructValue().GetFields()["limit"].GetNumberValue())
+		network, err := QueryNetworkQuota(ctx, conn)
+		require.NoError(t, err)
+		assert.Equal(t, 3.0, network.GetFields()["numNetworks"].GetStructValue().GetFields()["used"].GetNumberValue())
+	})
+
+	t.Run("without a service", func(t *testing.T) {
+		_, err := (&Server{}).QueryComputeQuota(ctx, nil)
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
@@ -0,0 +1,194 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/alarm"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no fault management messages or RPCs yet. Until it does,
+// kube-vim serves them from the hand-written Alarms service below. QueryAlarms
+// and SubscribeAlarms take a structpb.Struct with an optional SOL 013 filter
+// under filter. An alarm.Alarm travels as its JSON form in a structpb.Struct, a
+// query of them as a structpb.Struct with their list under alarms, and a
+// streamed alarm.Notification as its JSON form.
+const (
+	AlarmsServiceName                      = "kubenvf.kubevim.api.pb.vi_vnfm.Alarms"
+	Alarms_QueryAlarms_FullMethodName      = "/" + AlarmsServiceName + "/QueryAlarms"
+	Alarms_AcknowledgeAlarm_FullMethodName = "/" + AlarmsServiceName + "/AcknowledgeAlarm"
+	Alarms_SubscribeAlarms_FullMethodName  = "/" + AlarmsServiceName + "/SubscribeAlarms"
+)
+
+// AlarmStream is a live alarm notification stream. Events is closed when the
+// stream ends; Err then reports why (nil when it was closed or its context is
+// done).
+type AlarmStream interface {
+	Events() <-chan alarm.Notification
+	Err() error
+	Close()
+}
+
+// AlarmManager keeps the active alarms (see alarm.Manager).
+type AlarmManager interface {
+	QueryAlarms(filter string) ([]alarm.Alarm, error)
+	Acknowledge(alarmId string) (*alarm.Alarm, error)
+	Subscribe(ctx context.Context, filter string) (AlarmStream, error)
+}
+
+// AlarmsServer is the server API of the Alarms service.
+type AlarmsServer interface {
+	QueryAlarms(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	AcknowledgeAlarm(context.Context, *nfvcommon.Identifier) (*structpb.Struct, error)
+	SubscribeAlarms(*structpb.Struct, grpc.ServerStreamingServer[structpb.Struct]) error
+}
+
+var alarmsServiceDesc = grpc.ServiceDesc{
+	ServiceName: AlarmsServiceName,
+	HandlerType: (*AlarmsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryAlarms",
+			Handler:    interim.UnaryHandler(Alarms_QueryAlarms_FullMethodName, AlarmsServer.QueryAlarms),
+		},
+		{
+			MethodName: "AcknowledgeAlarm",
+			Handler:    interim.UnaryHandler(Alarms_AcknowledgeAlarm_FullMethodName, AlarmsServer.AcknowledgeAlarm),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeAlarms",
+			Handler:       interim.ServerStreamHandler(AlarmsServer.SubscribeAlarms),
+			ServerStreams: true,
+		},
+	},
+}
+
+func RegisterAlarmsServer(s grpc.ServiceRegistrar, srv AlarmsServer) {
+	s.RegisterService(&alarmsServiceDesc, srv)
+}
+
+// QueryAlarms calls the Alarms QueryAlarms RPC over cc.
+func QueryAlarms(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Alarms_QueryAlarms_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AcknowledgeAlarm calls the Alarms AcknowledgeAlarm RPC over cc.
+func AcknowledgeAlarm(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Alarms_AcknowledgeAlarm_FullMethodName, id, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SubscribeAlarms calls the Alarms SubscribeAlarms RPC over cc.
+func SubscribeAlarms(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (grpc.ServerStreamingClient[structpb.Struct], error) {
+	return interim.NewServerStream[structpb.Struct, structpb.Struct](ctx, cc, &alarmsServiceDesc.Streams[0], Alarms_SubscribeAlarms_FullMethodName, req)
+}
+
+// alarmFilterRequest is the decoded QueryAlarms and SubscribeAlarms request.
+type alarmFilterRequest struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+// QueryAlarms returns the active alarms matching the request filter.
+func (s *ViVnfmServer) QueryAlarms(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	alarmMgr, err := s.alarmManager()
+	if err != nil {
+		return nil, err
+	}
+	in := &alarmFilterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query alarms request: %v", err)
+	}
+	alarms, err := alarmMgr.QueryAlarms(in.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("query alarms: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(alarms))}
+	for i := range alarms {
+		st, err := alarmToStruct(&alarms[i])
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"alarms": structpb.NewListValue(list),
+	}}, nil
+}
+
+// AcknowledgeAlarm acknowledges the active alarm with the id.
+func (s *ViVnfmServer) AcknowledgeAlarm(ctx context.Context, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	alarmMgr, err := s.alarmManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "alarmId can't be empty")
+	}
+	a, err := alarmMgr.Acknowledge(id.GetValue())
+	if err != nil {
+		return nil, fmt.Errorf("acknowledge alarm '%s': %w", id.GetValue(), err)
+	}
+	return alarmToStruct(a)
+}
+
+// SubscribeAlarms streams the alarm notifications matching the request filter
+// until the client goes away or the subscription is dropped.
+func (s *ViVnfmServer) SubscribeAlarms(req *structpb.Struct, stream grpc.ServerStreamingServer[structpb.Struct]) error {
+	alarmMgr, err := s.alarmManager()
+	if err != nil {
+		return err
+	}
+	in := &alarmFilterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "decode subscribe alarms request: %v", err)
+	}
+	sub, err := alarmMgr.Subscribe(stream.Context(), in.Filter)
+	if err != nil {
+		return fmt.Errorf("subscribe to alarms: %w", err)
+	}
+	defer sub.Close()
+	for n := range sub.Events() {
+		st, err := interim.EncodeStruct(n)
+		if err != nil {
+			return fmt.Errorf("encode notification of alarm '%s': %w", n.Alarm.AlarmId, err)
+		}
+		if err := stream.Send(st); err != nil {
+			return err
+		}
+	}
+	if err := sub.Err(); err != nil {
+		return fmt.Errorf("alarm subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *ViVnfmServer) alarmManager() (AlarmManager, error) {
+	if s.AlarmMgr == nil {
+		return nil, fmt.Errorf("alarms: %w", apperrors.ErrUnsupported)
+	}
+	return s.AlarmMgr, nil
+}
+
+func alarmToStruct(a *alarm.Alarm) (*structpb.Struct, error) {
+	st, err := interim.EncodeStruct(a)
+	if err != nil {
+		return nil, fmt.Errorf("encode alarm '%s': %w", a.AlarmId, err)
+	}
+	return st, nil
+}
@@ -0,0 +1,120 @@
+package vivnfm
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/alarm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeAlarmManager serves fixed alarms and replays fixed notifications.
+type fakeAlarmManager struct {
+	alarms        []alarm.Alarm
+	notifications []alarm.Notification
+	filter        string
+}
+
+type fakeAlarmStream struct {
+	events chan alarm.Notification
+}
+
+func (s *fakeAlarmStream) Events() <-chan alarm.Notification { return s.events }
+func (s *fakeAlarmStream) Err() error                        { return nil }
+func (s *fakeAlarmStream) Close()                            {}
+
+func (f *fakeAlarmManager) QueryAlarms(filter string) ([]alarm.Alarm, error) {
+	f.filter = filter
+	return f.alarms, nil
+}
+
+func (f *fakeAlarmManager) Acknowledge(alarmId string) (*alarm.Alarm, error) {
+	for _, a := range f.alarms {
+		if a.AlarmId == alarmId {
+			a.AckState = alarm.AckStateAcknowledged
+			return &a, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "alarm not found")
+}
+
+func (f *fakeAlarmManager) Subscribe(_ context.Context, filter string) (AlarmStream, error) {
+	f.filter = filter
+	ch := make(chan alarm.Notification, len(f.notifications))
+	for _, n := range f.notifications {
+		ch <- n
+	}
+	close(ch)
+	return &fakeAlarmStream{events: ch}, nil
+}
+
+func newAlarmsConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterAlarmsServer(gs, s) })
+}
+
+func TestAlarms(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	active := alarm.Alarm{AlarmId: "a1", ManagedObjectId: "uid-vm", AckState: alarm.AckStateUnacknowledged, PerceivedSeverity: alarm.SeverityCritical}
+
+	t.Run("query passes the filter and lists the alarms", func(t *testing.T) {
+		s, _ := newServer(t)
+		fake := &fakeAlarmManager{alarms: []alarm.Alarm{active}}
+		s.AlarmMgr = fake
+		req, err := structpb.NewStruct(map[string]any{"filter": "(eq,perceivedSeverity,CRITICAL)"})
+		require.NoError(t, err)
+		resp, err := QueryAlarms(ctx, newAlarmsConn(t, s), req)
+		require.NoError(t, err)
+		alarms := resp.GetFields()["alarms"].GetListValue().GetValues()
+		require.Len(t, alarms, 1)
+		assert.Equal(t, "a1", alarms[0].GetStructValue().GetFields()["alarmId"].GetStringValue())
+		assert.Equal(t, "(eq,perceivedSeverity,CRITICAL)", fake.filter)
+	})
+
+	t.Run("acknowledge returns the acknowledged alarm", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.AlarmMgr = &fakeAlarmManager{alarms: []alarm.Alarm{active}}
+		conn := newAlarmsConn(t, s)
+		resp, err := AcknowledgeAlarm(ctx, conn, k8stest.ID("a1"))
+		require.NoError(t, err)
+		assert.Equal(t, string(alarm.AckStateAcknowledged), resp.GetFields()["ackState"].GetStringValue())
+
+		_, err = AcknowledgeAlarm(ctx, conn, k8stest.ID("missing"))
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("subscribe streams the notifications", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.AlarmMgr = &fakeAlarmManager{notifications: []alarm.Notification{
+			{NotificationType: alarm.AlarmNotificationType, Alarm: active},
+			{NotificationType: alarm.AlarmClearedNotificationType, Alarm: active},
+		}}
+		stream, err := SubscribeAlarms(ctx, newAlarmsConn(t, s), &structpb.Struct{})
+		require.NoError(t, err)
+		var types []string
+		for {
+			st, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			types = append(types, st.GetFields()["notificationType"].GetStringValue())
+		}
+		assert.Equal(t, []string{string(alarm.AlarmNotificationType), string(alarm.AlarmClearedNotificationType)}, types)
+	})
+
+	t.Run("without an alarm manager", func(t *testing.T) {
+		s, _ := newServer(t)
+		_, err := s.QueryAlarms(ctx, &structpb.Struct{})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
@@ -0,0 +1,365 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no NAT gateway or floating IP messages or RPCs yet. Until it
+// does, kube-vim serves them from the hand-written Nat service below. A
+// network.NatGateway or network.FloatingIp travels as its JSON form in a
+// structpb.Struct, and a query of them as a structpb.Struct with their list
+// under natGateways or floatingIps.
+const (
+	NatServiceName                      = "kubenvf.kubevim.api.pb.vi_vnfm.Nat"
+	Nat_CreateNatGateway_FullMethodName = "/" + NatServiceName + "/CreateNatGateway"
+	Nat_QueryNatGateways_FullMethodName = "/" + NatServiceName + "/QueryNatGateways"
+	Nat_QueryNatGateway_FullMethodName  = "/" + NatServiceName + "/QueryNatGateway"
+	Nat_DeleteNatGateway_FullMethodName = "/" + NatServiceName + "/DeleteNatGateway"
+	Nat_CreateFloatingIp_FullMethodName = "/" + NatServiceName + "/CreateFloatingIp"
+	Nat_QueryFloatingIps_FullMethodName = "/" + NatServiceName + "/QueryFloatingIps"
+	Nat_QueryFloatingIp_FullMethodName  = "/" + NatServiceName + "/QueryFloatingIp"
+	Nat_UpdateFloatingIp_FullMethodName = "/" + NatServiceName + "/UpdateFloatingIp"
+	Nat_DeleteFloatingIp_FullMethodName = "/" + NatServiceName + "/DeleteFloatingIp"
+)
+
+// NatServer is the server API of the Nat service.
+type NatServer interface {
+	CreateNatGateway(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryNatGateways(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	QueryNatGateway(context.Context, *nfvcommon.Identifier) (*structpb.Struct, error)
+	DeleteNatGateway(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+	CreateFloatingIp(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryFloatingIps(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	QueryFloatingIp(context.Context, *nfvcommon.Identifier) (*structpb.Struct, error)
+	// UpdateFloatingIp binds the floating IP whose floatingIpId is set in the
+	// request to its portIpAddress, or unbinds it when that is empty.
+	UpdateFloatingIp(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DeleteFloatingIp(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+}
+
+var natServiceDesc = grpc.ServiceDesc{
+	ServiceName: NatServiceName,
+	HandlerType: (*NatServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateNatGateway",
+			Handler:    interim.UnaryHandler(Nat_CreateNatGateway_FullMethodName, NatServer.CreateNatGateway),
+		},
+		{
+			MethodName: "QueryNatGateways",
+			Handler:    interim.UnaryHandler(Nat_QueryNatGateways_FullMethodName, NatServer.QueryNatGateways),
+		},
+		{
+			MethodName: "QueryNatGateway",
+			Handler:    interim.UnaryHandler(Nat_QueryNatGateway_FullMethodName, NatServer.QueryNatGateway),
+		},
+		{
+			MethodName: "DeleteNatGateway",
+			Handler:    interim.UnaryHandler(Nat_DeleteNatGateway_FullMethodName, NatServer.DeleteNatGateway),
+		},
+		{
+			MethodName: "CreateFloatingIp",
+			Handler:    interim.UnaryHandler(Nat_CreateFloatingIp_FullMethodName, NatServer.CreateFloatingIp),
+		},
+		{
+			MethodName: "QueryFloatingIps",
+			Handler:    interim.UnaryHandler(Nat_QueryFloatingIps_FullMethodName, NatServer.QueryFloatingIps),
+		},
+		{
+			MethodName: "QueryFloatingIp",
+			Handler:    interim.UnaryHandler(Nat_QueryFloatingIp_FullMethodName, NatServer.QueryFloatingIp),
+		},
+		{
+			MethodName: "UpdateFloatingIp",
+			Handler:    interim.UnaryHandler(Nat_UpdateFloatingIp_FullMethodName, NatServer.UpdateFloatingIp),
+		},
+		{
+			MethodName: "DeleteFloatingIp",
+			Handler:    interim.UnaryHandler(Nat_DeleteFloatingIp_FullMethodName, NatServer.DeleteFloatingIp),
+		},
+	},
+}
+
+func RegisterNatServer(s grpc.ServiceRegistrar, srv NatServer) {
+	s.RegisterService(&natServiceDesc, srv)
+}
+
+// CreateNatGateway calls the Nat CreateNatGateway RPC over cc.
+func CreateNatGateway(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_CreateNatGateway_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryNatGateways calls the Nat QueryNatGateways RPC over cc.
+func QueryNatGateways(ctx context.Context, cc grpc.ClientConnInterface) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_QueryNatGateways_FullMethodName, &emptypb.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryNatGateway calls the Nat QueryNatGateway RPC over cc.
+func QueryNatGateway(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_QueryNatGateway_FullMethodName, id, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteNatGateway calls the Nat DeleteNatGateway RPC over cc.
+func DeleteNatGateway(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) error {
+	return cc.Invoke(ctx, Nat_DeleteNatGateway_FullMethodName, id, new(emptypb.Empty))
+}
+
+// CreateFloatingIp calls the Nat CreateFloatingIp RPC over cc.
+func CreateFloatingIp(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_CreateFloatingIp_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryFloatingIps calls the Nat QueryFloatingIps RPC over cc.
+func QueryFloatingIps(ctx context.Context, cc grpc.ClientConnInterface) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_QueryFloatingIps_FullMethodName, &emptypb.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryFloatingIp calls the Nat QueryFloatingIp RPC over cc.
+func QueryFloatingIp(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_QueryFloatingIp_FullMethodName, id, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateFloatingIp calls the Nat UpdateFloatingIp RPC over cc.
+func UpdateFloatingIp(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Nat_UpdateFloatingIp_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteFloatingIp calls the Nat DeleteFloatingIp RPC over cc.
+func DeleteFloatingIp(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) error {
+	return cc.Invoke(ctx, Nat_DeleteFloatingIp_FullMethodName, id, new(emptypb.Empty))
+}
+
+// CreateNatGateway connects an overlay network to the external network.
+func (s *ViVnfmServer) CreateNatGateway(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	gw := &network.NatGateway{}
+	if err := interim.DecodeStruct(req, gw); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode nat gateway: %v", err)
+	}
+	res, err := natMgr.CreateNatGateway(ctx, gw)
+	if err != nil {
+		return nil, fmt.Errorf("create nat gateway '%s': %w", gw.Name, err)
+	}
+	return natGatewayToStruct(res)
+}
+
+// QueryNatGateways returns every NAT gateway.
+func (s *ViVnfmServer) QueryNatGateways(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	gws, err := natMgr.ListNatGateways(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query nat gateways: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(gws))}
+	for _, gw := range gws {
+		st, err := natGatewayToStruct(gw)
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"natGateways": structpb.NewListValue(list),
+	}}, nil
+}
+
+// QueryNatGateway returns the NAT gateway with the id.
+func (s *ViVnfmServer) QueryNatGateway(ctx context.Context, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "natGatewayId can't be empty")
+	}
+	gw, err := natMgr.GetNatGateway(ctx, network.GetNatByUid(id))
+	if err != nil {
+		return nil, fmt.Errorf("query nat gateway '%s': %w", id.GetValue(), err)
+	}
+	return natGatewayToStruct(gw)
+}
+
+// DeleteNatGateway deletes the NAT gateway with the id. It fails while floating
+// IPs are allocated through the gateway.
+func (s *ViVnfmServer) DeleteNatGateway(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "natGatewayId can't be empty")
+	}
+	if err := natMgr.DeleteNatGateway(ctx, network.GetNatByUid(id)); err != nil {
+		return nil, fmt.Errorf("delete nat gateway '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// CreateFloatingIp allocates a floating IP through a NAT gateway.
+func (s *ViVnfmServer) CreateFloatingIp(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	fip, err := floatingIpFromStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := natMgr.CreateFloatingIp(ctx, fip)
+	if err != nil {
+		return nil, fmt.Errorf("create floating ip '%s': %w", fip.Name, err)
+	}
+	return floatingIpToStruct(res)
+}
+
+// QueryFloatingIps returns every floating IP.
+func (s *ViVnfmServer) QueryFloatingIps(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	fips, err := natMgr.ListFloatingIps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query floating ips: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(fips))}
+	for _, fip := range fips {
+		st, err := floatingIpToStruct(fip)
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"floatingIps": structpb.NewListValue(list),
+	}}, nil
+}
+
+// QueryFloatingIp returns the floating IP with the id.
+func (s *ViVnfmServer) QueryFloatingIp(ctx context.Context, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "floatingIpId can't be empty")
+	}
+	fip, err := natMgr.GetFloatingIp(ctx, network.GetNatByUid(id))
+	if err != nil {
+		return nil, fmt.Errorf("query floating ip '%s': %w", id.GetValue(), err)
+	}
+	return floatingIpToStruct(fip)
+}
+
+// UpdateFloatingIp binds the floating IP identified by the request floatingIpId
+// to the request portIpAddress, or unbinds it when that is empty.
+func (s *ViVnfmServer) UpdateFloatingIp(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	fip, err := floatingIpFromStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	if fip.FloatingIpId.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "floatingIpId can't be empty")
+	}
+	res, err := natMgr.UpdateFloatingIp(ctx, fip)
+	if err != nil {
+		return nil, fmt.Errorf("update floating ip '%s': %w", fip.FloatingIpId.GetValue(), err)
+	}
+	return floatingIpToStruct(res)
+}
+
+// DeleteFloatingIp unbinds and releases the floating IP with the id.
+func (s *ViVnfmServer) DeleteFloatingIp(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	natMgr, err := s.natManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "floatingIpId can't be empty")
+	}
+	if err := natMgr.DeleteFloatingIp(ctx, network.GetNatByUid(id)); err != nil {
+		return nil, fmt.Errorf("delete floating ip '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ViVnfmServer) natManager() (network.NatManager, error) {
+	natMgr, ok := s.NetworkMgr.(network.NatManager)
+	if !ok {
+		return nil, fmt.Errorf("NAT gateways and floating IPs with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	return natMgr, nil
+}
+
+func floatingIpFromStruct(st *structpb.Struct) (*network.FloatingIp, error) {
+	fip := &network.FloatingIp{}
+	if err := interim.DecodeStruct(st, fip); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode floating ip: %v", err)
+	}
+	return fip, nil
+}
+
+func natGatewayToStruct(gw *network.NatGateway) (*structpb.Struct, error) {
+	st, err := interim.EncodeStruct(gw)
+	if err != nil {
+		return nil, fmt.Errorf("encode nat gateway '%s': %w", gw.Name, err)
+	}
+	return st, nil
+}
+
+func floatingIpToStruct(fip *network.FloatingIp) (*structpb.Struct, error) {
+	st, err := interim.EncodeStruct(fip)
+	if err != nil {
+		return nil, fmt.Errorf("encode floating ip '%s': %w", fip.Name, err)
+	}
+	return st, nil
+}
@@ -0,0 +1,166 @@
+package vivnfm
+
+import (
+	"context"
+	"testing"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// natNetworkManager is a network manager that also manages NAT.
+type natNetworkManager struct {
+	*networkmock.MockManager
+	*networkmock.MockNatManager
+}
+
+func newNatServer(t *testing.T) (*ViVnfmServer, *networkmock.MockNatManager) {
+	t.Helper()
+	s, m := newServer(t)
+	natm := networkmock.NewMockNatManager(gomock.NewController(t))
+	s.NetworkMgr = natNetworkManager{m.network, natm}
+	return s, natm
+}
+
+func TestCreateNatGateway(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("decodes the gateway and encodes the result", func(t *testing.T) {
+		s, natm := newNatServer(t)
+		natm.EXPECT().CreateNatGateway(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, gw *network.NatGateway) (*network.NatGateway, error) {
+				assert.Equal(t, "gw", gw.Name)
+				assert.Equal(t, "uid-net1", gw.NetworkId.GetValue())
+				require.Len(t, gw.SnatSubnetIds, 1)
+				assert.Equal(t, "uid-sub1", gw.SnatSubnetIds[0].GetValue())
+				gw.NatGatewayId = k8stest.ID("uid-gw")
+				gw.ExternalIpAddress = "172.18.0.10"
+				return gw, nil
+			})
+		req, err := structpb.NewStruct(map[string]any{
+			"name":          "gw",
+			"networkId":     map[string]any{"value": "uid-net1"},
+			"snatSubnetIds": []any{map[string]any{"value": "uid-sub1"}},
+		})
+		require.NoError(t, err)
+		resp, err := CreateNatGateway(ctx, newNatConn(t, s), req)
+		require.NoError(t, err)
+		assert.Equal(t, "uid-gw", resp.GetFields()["natGatewayId"].GetStructValue().GetFields()["value"].GetStringValue())
+		assert.Equal(t, "172.18.0.10", resp.GetFields()["externalIpAddress"].GetStringValue())
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		s, _ := newNatServer(t)
+		req, err := structpb.NewStruct(map[string]any{"name": "gw", "externalIp": "172.18.0.10"})
+		require.NoError(t, err)
+		_, err = s.CreateNatGateway(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestNatQueries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s, natm := newNatServer(t)
+	natm.EXPECT().ListNatGateways(gomock.Any()).Return([]*network.NatGateway{{Name: "gw"}}, nil)
+	natm.EXPECT().GetNatGateway(gomock.Any(), gomock.Any()).Return(&network.NatGateway{Name: "gw"}, nil)
+	natm.EXPECT().ListFloatingIps(gomock.Any()).Return([]*network.FloatingIp{{Name: "fip1"}, {Name: "fip2"}}, nil)
+	natm.EXPECT().GetFloatingIp(gomock.Any(), gomock.Any()).Return(&network.FloatingIp{Name: "fip1"}, nil)
+	conn := newNatConn(t, s)
+
+	gws, err := QueryNatGateways(ctx, conn)
+	require.NoError(t, err)
+	assert.Len(t, gws.GetFields()["natGateways"].GetListValue().GetValues(), 1)
+
+	gw, err := QueryNatGateway(ctx, conn, k8stest.ID("uid-gw"))
+	require.NoError(t, err)
+	assert.Equal(t, "gw", gw.GetFields()["name"].GetStringValue())
+
+	fips, err := QueryFloatingIps(ctx, conn)
+	require.NoError(t, err)
+	assert.Len(t, fips.GetFields()["floatingIps"].GetListValue().GetValues(), 2)
+
+	fip, err := QueryFloatingIp(ctx, conn, k8stest.ID("uid-fip1"))
+	require.NoError(t, err)
+	assert.Equal(t, "fip1", fip.GetFields()["name"].GetStringValue())
+}
+
+func TestUpdateFloatingIp(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("binds the floating ip", func(t *testing.T) {
+		s, natm := newNatServer(t)
+		natm.EXPECT().UpdateFloatingIp(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, fip *network.FloatingIp) (*network.FloatingIp, error) {
+				assert.Equal(t, "uid-fip1", fip.FloatingIpId.GetValue())
+				assert.Equal(t, "10.0.0.5", fip.PortIpAddress)
+				fip.Name = "fip1"
+				return fip, nil
+			})
+		req, err := structpb.NewStruct(map[string]any{
+			"floatingIpId":  map[string]any{"value": "uid-fip1"},
+			"portIpAddress": "10.0.0.5",
+		})
+		require.NoError(t, err)
+		resp, err := UpdateFloatingIp(ctx, newNatConn(t, s), req)
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.5", resp.GetFields()["portIpAddress"].GetStringValue())
+	})
+
+	t.Run("floatingIpId is required", func(t *testing.T) {
+		s, _ := newNatServer(t)
+		req, err := structpb.NewStruct(map[string]any{"portIpAddress": "10.0.0.5"})
+		require.NoError(t, err)
+		_, err = s.UpdateFloatingIp(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestNatDeletes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("deletes by id", func(t *testing.T) {
+		s, natm := newNatServer(t)
+		natm.EXPECT().DeleteFloatingIp(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, opts ...network.GetNatOpt) error {
+				assert.Equal(t, "uid-fip1", network.ApplyGetNatOpts(opts...).Uid.GetValue())
+				return nil
+			})
+		natm.EXPECT().DeleteNatGateway(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, opts ...network.GetNatOpt) error {
+				assert.Equal(t, "uid-gw", network.ApplyGetNatOpts(opts...).Uid.GetValue())
+				return nil
+			})
+		conn := newNatConn(t, s)
+		require.NoError(t, DeleteFloatingIp(ctx, conn, k8stest.ID("uid-fip1")))
+		require.NoError(t, DeleteNatGateway(ctx, conn, k8stest.ID("uid-gw")))
+	})
+
+	t.Run("gateway in use", func(t *testing.T) {
+		s, natm := newNatServer(t)
+		natm.EXPECT().DeleteNatGateway(gomock.Any(), gomock.Any()).Return(&apperrors.ErrFailedPrecondition{Resource: "nat gateway 'gw'", Reason: "floating ips allocated"})
+		_, err := s.DeleteNatGateway(ctx, k8stest.ID("uid-gw"))
+		var precondition *apperrors.ErrFailedPrecondition
+		assert.ErrorAs(t, err, &precondition)
+	})
+
+	t.Run("unsupported network manager", func(t *testing.T) {
+		s, _ := newServer(t)
+		_, err := s.DeleteNatGateway(ctx, k8stest.ID("uid-gw"))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+		_, err = s.QueryFloatingIps(ctx, &emptypb.Empty{})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
@@ -0,0 +1,104 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The ViVnfm service of kube-vim-api has no UpdateVirtualisedNetworkResource RPC
+// yet. Until it does, kube-vim serves it from the hand-written Networks service
+// below. The request reuses AllocateNetworkRequest: networkResourceType selects
+// typeNetworkData or typeSubnetData, and the id of the updated resource travels
+// as network.UpdateResourceIdMetadataKey request metadata.
+const (
+	NetworksServiceName                                      = "kubenvf.kubevim.api.pb.vi_vnfm.Networks"
+	Networks_UpdateVirtualisedNetworkResource_FullMethodName = "/" + NetworksServiceName + "/UpdateVirtualisedNetworkResource"
+)
+
+// NetworksServer is the server API of the Networks service.
+type NetworksServer interface {
+	UpdateVirtualisedNetworkResource(context.Context, *vivnfm.AllocateNetworkRequest) (*vivnfm.AllocateNetworkResponse, error)
+}
+
+var networksServiceDesc = grpc.ServiceDesc{
+	ServiceName: NetworksServiceName,
+	HandlerType: (*NetworksServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "UpdateVirtualisedNetworkResource",
+		Handler:    interim.UnaryHandler(Networks_UpdateVirtualisedNetworkResource_FullMethodName, NetworksServer.UpdateVirtualisedNetworkResource),
+	}},
+}
+
+func RegisterNetworksServer(s grpc.ServiceRegistrar, srv NetworksServer) {
+	s.RegisterService(&networksServiceDesc, srv)
+}
+
+// UpdateVirtualisedNetworkResource calls the Networks UpdateVirtualisedNetworkResource
+// RPC over cc for the network resource id.
+func UpdateVirtualisedNetworkResource(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier, req *vivnfm.AllocateNetworkRequest) (*vivnfm.AllocateNetworkResponse, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, network.UpdateResourceIdMetadataKey, id.GetValue())
+	res := new(vivnfm.AllocateNetworkResponse)
+	if err := cc.Invoke(ctx, Networks_UpdateVirtualisedNetworkResource_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// TerminateVirtualisedNetworkResource calls the ViVnfm
+// TerminateVirtualisedNetworkResource RPC over cc. With cascade, the computes
+// attached to the network resource are terminated before it is deleted.
+func TerminateVirtualisedNetworkResource(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier, cascade bool) (*vivnfm.TerminateNetworkResponse, error) {
+	if cascade {
+		ctx = metadata.AppendToOutgoingContext(ctx, network.DeleteCascadeMetadataKey, "true")
+	}
+	return vivnfm.NewViVnfmClient(cc).TerminateVirtualisedNetworkResource(ctx, &vivnfm.TerminateNetworkRequest{NetworkResourceId: id})
+}
+
+// UpdateVirtualisedNetworkResource updates the network or subnet identified by
+// the request metadata in place.
+func (s *ViVnfmServer) UpdateVirtualisedNetworkResource(ctx context.Context, req *vivnfm.AllocateNetworkRequest) (*vivnfm.AllocateNetworkResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "updateNetworkRequest can't be empty")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	ids := md.Get(network.UpdateResourceIdMetadataKey)
+	if len(ids) == 0 || ids[0] == "" {
+		return nil, status.Error(codes.InvalidArgument, "networkResourceId can't be empty")
+	}
+	id := &nfvcommon.Identifier{Value: ids[0]}
+	switch req.NetworkResourceType {
+	case nfvcommon.NetworkResourceType_NETWORK:
+		if req.TypeNetworkData == nil {
+			return nil, status.Error(codes.InvalidArgument, "field typeNetworkData can't be empty with Network resource type")
+		}
+		net, err := s.NetworkMgr.UpdateNetwork(ctx, req.TypeNetworkData, network.GetNetworkByUid(id))
+		if err != nil {
+			return nil, fmt.Errorf("update network '%s': %w", id.GetValue(), err)
+		}
+		return &vivnfm.AllocateNetworkResponse{
+			NetworkData: net,
+		}, nil
+	case nfvcommon.NetworkResourceType_SUBNET:
+		if req.TypeSubnetData == nil {
+			return nil, status.Error(codes.InvalidArgument, "field TypeSubnetData can't be empty with Subnet resource type")
+		}
+		subnet, err := s.NetworkMgr.UpdateSubnet(ctx, req.TypeSubnetData, network.GetSubnetByUid(id))
+		if err != nil {
+			return nil, fmt.Errorf("update subnet '%s': %w", id.GetValue(), err)
+		}
+		return &vivnfm.AllocateNetworkResponse{
+			SubnetData: subnet,
+		}, nil
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported NetworkResourceType: %s", req.NetworkResourceType.String())
+	}
+}
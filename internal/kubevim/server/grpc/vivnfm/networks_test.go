@@ -0,0 +1,104 @@
+package vivnfm
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newNetworksConn serves the Networks service of s over an in-memory listener.
+func newNetworksConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterNetworksServer(gs, s) })
+}
+
+// newSecurityGroupsConn serves the SecurityGroups service of s over an
+// in-memory listener.
+func newSecurityGroupsConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterSecurityGroupsServer(gs, s) })
+}
+
+// newVipsConn serves the Vips service of s over an in-memory listener.
+func newVipsConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterVipsServer(gs, s) })
+}
+
+// newNatConn serves the Nat service of s over an in-memory listener.
+func newNatConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterNatServer(gs, s) })
+}
+
+func newConn(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestUpdateVirtualisedNetworkResource(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("network update is addressed by the metadata id", func(t *testing.T) {
+		s, m := newServer(t)
+		m.network.EXPECT().UpdateNetwork(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, data *vivnfm.VirtualNetworkData, opts ...network.GetNetworkOpt) (*vivnfm.VirtualNetwork, error) {
+				assert.Equal(t, "net-1", network.ApplyGetNetworkOpts(opts...).Uid.GetValue())
+				return &vivnfm.VirtualNetwork{NetworkResourceId: k8stest.ID("net-1"), Metadata: data.Metadata}, nil
+			})
+		resp, err := UpdateVirtualisedNetworkResource(ctx, newNetworksConn(t, s), k8stest.ID("net-1"), &vivnfm.AllocateNetworkRequest{
+			NetworkResourceType: nfvcommon.NetworkResourceType_NETWORK,
+			TypeNetworkData:     &vivnfm.VirtualNetworkData{Metadata: &nfvcommon.Metadata{Fields: map[string]string{"owner": "vnf-a"}}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "vnf-a", resp.GetNetworkData().GetMetadata().GetFields()["owner"])
+	})
+
+	t.Run("subnet update", func(t *testing.T) {
+		s, m := newServer(t)
+		m.network.EXPECT().UpdateSubnet(gomock.Any(), gomock.Any(), gomock.Any()).Return(&vivnfm.NetworkSubnet{ResourceId: k8stest.ID("sub-1")}, nil)
+		resp, err := UpdateVirtualisedNetworkResource(ctx, newNetworksConn(t, s), k8stest.ID("sub-1"), &vivnfm.AllocateNetworkRequest{
+			NetworkResourceType: nfvcommon.NetworkResourceType_SUBNET,
+			TypeSubnetData:      &vivnfm.NetworkSubnetData{},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "sub-1", resp.GetSubnetData().GetResourceId().GetValue())
+	})
+
+	t.Run("missing id or data is rejected", func(t *testing.T) {
+		s, _ := newServer(t)
+		_, err := s.UpdateVirtualisedNetworkResource(ctx, &vivnfm.AllocateNetworkRequest{NetworkResourceType: nfvcommon.NetworkResourceType_NETWORK})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = UpdateVirtualisedNetworkResource(ctx, newNetworksConn(t, s), k8stest.ID("sub-1"), &vivnfm.AllocateNetworkRequest{
+			NetworkResourceType: nfvcommon.NetworkResourceType_SUBNET,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
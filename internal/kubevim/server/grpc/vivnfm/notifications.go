@@ -0,0 +1,119 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no notification messages or RPCs yet. Until it does,
+// kube-vim serves the virtualised resource change notifications from the
+// hand-written Notifications service below. Subscribe takes a structpb.Struct
+// with an optional SOL 013 filter under filter and streams every matching
+// event.Event as its JSON form in a structpb.Struct. A client resuming after a
+// reconnect passes the version of the last event it received as
+// NotificationVersionMetadataKey request metadata.
+const (
+	NotificationsServiceName               = "kubenvf.kubevim.api.pb.vi_vnfm.Notifications"
+	Notifications_Subscribe_FullMethodName = "/" + NotificationsServiceName + "/Subscribe"
+
+	// NotificationVersionMetadataKey is the request metadata holding the
+	// version marker to resume a subscription after.
+	NotificationVersionMetadataKey = "kubevim-notification-version"
+)
+
+// EventStream is a live stream of change events. Events is closed when the
+// stream ends; Err then reports why (nil when it was closed or its context is
+// done).
+type EventStream interface {
+	Events() <-chan event.Event
+	Err() error
+	Close()
+}
+
+// NotificationSource opens change event streams. A fromVersion that can no
+// longer be resumed fails with an error the notification module converts to
+// codes.OutOfRange; the client then re-lists and subscribes without it.
+type NotificationSource interface {
+	Subscribe(ctx context.Context, filter string, fromVersion string) (EventStream, error)
+}
+
+// NotificationsServer is the server API of the Notifications service.
+type NotificationsServer interface {
+	Subscribe(*structpb.Struct, grpc.ServerStreamingServer[structpb.Struct]) error
+}
+
+var notificationsServiceDesc = grpc.ServiceDesc{
+	ServiceName: NotificationsServiceName,
+	HandlerType: (*NotificationsServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       interim.ServerStreamHandler(NotificationsServer.Subscribe),
+			ServerStreams: true,
+		},
+	},
+}
+
+func RegisterNotificationsServer(s grpc.ServiceRegistrar, srv NotificationsServer) {
+	s.RegisterService(&notificationsServiceDesc, srv)
+}
+
+// SubscribeNotifications calls the Notifications Subscribe RPC over cc. A
+// non-empty fromVersion resumes after the event with that version.
+func SubscribeNotifications(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct, fromVersion string) (grpc.ServerStreamingClient[structpb.Struct], error) {
+	if fromVersion != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, NotificationVersionMetadataKey, fromVersion)
+	}
+	return interim.NewServerStream[structpb.Struct, structpb.Struct](ctx, cc, &notificationsServiceDesc.Streams[0], Notifications_Subscribe_FullMethodName, req)
+}
+
+// subscribeRequest is the decoded Subscribe request.
+type subscribeRequest struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+// Subscribe streams the change events matching the request filter until the
+// client goes away or the subscription is dropped.
+func (s *ViVnfmServer) Subscribe(req *structpb.Struct, stream grpc.ServerStreamingServer[structpb.Struct]) error {
+	if s.Notifications == nil {
+		return fmt.Errorf("notifications: %w", apperrors.ErrUnsupported)
+	}
+	in := &subscribeRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "decode subscribe request: %v", err)
+	}
+	ctx := stream.Context()
+	var fromVersion string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if versions := md.Get(NotificationVersionMetadataKey); len(versions) > 0 {
+			fromVersion = versions[0]
+		}
+	}
+	sub, err := s.Notifications.Subscribe(ctx, in.Filter, fromVersion)
+	if err != nil {
+		return fmt.Errorf("subscribe to notifications: %w", err)
+	}
+	defer sub.Close()
+	for ev := range sub.Events() {
+		st, err := interim.EncodeStruct(ev)
+		if err != nil {
+			return fmt.Errorf("encode notification '%s': %w", ev.Version, err)
+		}
+		if err := stream.Send(st); err != nil {
+			return err
+		}
+	}
+	if err := sub.Err(); err != nil {
+		return fmt.Errorf("notification subscription: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package vivnfm
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/kube-nfv/kube-vim/internal/kubevim/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeEventStream replays a fixed list of events and ends with err.
+type fakeEventStream struct {
+	events chan event.Event
+	err    error
+	closed bool
+}
+
+func (s *fakeEventStream) Events() <-chan event.Event { return s.events }
+func (s *fakeEventStream) Err() error                 { return s.err }
+func (s *fakeEventStream) Close()                     { s.closed = true }
+
+// fakeNotificationSource records the subscribe arguments and returns stream.
+type fakeNotificationSource struct {
+	filter, fromVersion string
+	stream              *fakeEventStream
+	err                 error
+}
+
+func (f *fakeNotificationSource) Subscribe(_ context.Context, filter string, fromVersion string) (EventStream, error) {
+	f.filter, f.fromVersion = filter, fromVersion
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.stream, nil
+}
+
+func newEventStream(err error, events ...event.Event) *fakeEventStream {
+	ch := make(chan event.Event, len(events))
+	for _, ev := range events {
+		ch <- ev
+	}
+	close(ch)
+	return &fakeEventStream{events: ch, err: err}
+}
+
+func newNotificationsConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterNotificationsServer(gs, s) })
+}
+
+func TestSubscribeNotifications(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("streams events and passes the filter and resume version", func(t *testing.T) {
+		s, _ := newServer(t)
+		src := &fakeNotificationSource{stream: newEventStream(nil,
+			event.Event{Version: "e-2", ChangeType: event.ChangeTypeCreated, ResourceType: event.ResourceTypeCompute, ResourceId: "uid-vm"},
+			event.Event{Version: "e-3", ChangeType: event.ChangeTypeDeleted, ResourceType: event.ResourceTypeCompute, ResourceId: "uid-vm"},
+		)}
+		s.Notifications = src
+		req, err := structpb.NewStruct(map[string]any{"filter": "(eq,resourceType,COMPUTE)"})
+		require.NoError(t, err)
+
+		stream, err := SubscribeNotifications(ctx, newNotificationsConn(t, s), req, "e-1")
+		require.NoError(t, err)
+		var versions []string
+		for {
+			st, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			versions = append(versions, st.GetFields()["version"].GetStringValue())
+		}
+		assert.Equal(t, []string{"e-2", "e-3"}, versions)
+		assert.Equal(t, "(eq,resourceType,COMPUTE)", src.filter)
+		assert.Equal(t, "e-1", src.fromVersion)
+		assert.True(t, src.stream.closed)
+	})
+
+	t.Run("subscribe error ends the stream", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.Notifications = &fakeNotificationSource{err: status.Error(codes.OutOfRange, "expired")}
+		stream, err := SubscribeNotifications(ctx, newNotificationsConn(t, s), &structpb.Struct{}, "old-1")
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("subscription error is reported after the events", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.Notifications = &fakeNotificationSource{stream: newEventStream(status.Error(codes.ResourceExhausted, "slow"),
+			event.Event{Version: "e-1"},
+		)}
+		stream, err := SubscribeNotifications(ctx, newNotificationsConn(t, s), &structpb.Struct{}, "")
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("unknown request fields are rejected", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.Notifications = &fakeNotificationSource{}
+		req, err := structpb.NewStruct(map[string]any{"resourceType": "COMPUTE"})
+		require.NoError(t, err)
+		stream, err := SubscribeNotifications(ctx, newNotificationsConn(t, s), req, "")
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,368 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/pm"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no performance management messages or RPCs yet. Until it
+// does, kube-vim serves them from the hand-written Performance service below.
+// A pm.PmJobRequest, pm.ThresholdRequest and their results travel as their JSON
+// form in a structpb.Struct. The query RPCs take a structpb.Struct with an
+// optional SOL 013 filter under filter and answer with the list under pmJobs or
+// thresholds. QueryPerformanceReport takes the pmJobId and reportId, and a
+// streamed pm.Notification travels as its JSON form.
+const (
+	PerformanceServiceName                            = "kubenvf.kubevim.api.pb.vi_vnfm.Performance"
+	Performance_CreatePmJob_FullMethodName            = "/" + PerformanceServiceName + "/CreatePmJob"
+	Performance_QueryPmJobs_FullMethodName            = "/" + PerformanceServiceName + "/QueryPmJobs"
+	Performance_DeletePmJob_FullMethodName            = "/" + PerformanceServiceName + "/DeletePmJob"
+	Performance_QueryPerformanceReport_FullMethodName = "/" + PerformanceServiceName + "/QueryPerformanceReport"
+	Performance_CreateThreshold_FullMethodName        = "/" + PerformanceServiceName + "/CreateThreshold"
+	Performance_QueryThresholds_FullMethodName        = "/" + PerformanceServiceName + "/QueryThresholds"
+	Performance_DeleteThreshold_FullMethodName        = "/" + PerformanceServiceName + "/DeleteThreshold"
+	Performance_SubscribePerformance_FullMethodName   = "/" + PerformanceServiceName + "/SubscribePerformance"
+)
+
+// PmStream is a live PM notification stream. Events is closed when the stream
+// ends; Err then reports why (nil when it was closed or its context is done).
+type PmStream interface {
+	Events() <-chan pm.Notification
+	Err() error
+	Close()
+}
+
+// PmManager runs the PM jobs and thresholds (see pm.Manager).
+type PmManager interface {
+	CreatePmJob(ctx context.Context, req *pm.PmJobRequest) (*pm.PmJob, error)
+	QueryPmJobs(filter string) ([]pm.PmJob, error)
+	DeletePmJob(ctx context.Context, pmJobId string) error
+	GetPerformanceReport(pmJobId, reportId string) (*pm.PerformanceReport, error)
+	CreateThreshold(ctx context.Context, req *pm.ThresholdRequest) (*pm.Threshold, error)
+	QueryThresholds(filter string) ([]pm.Threshold, error)
+	DeleteThreshold(ctx context.Context, thresholdId string) error
+	Subscribe(ctx context.Context, filter string) (PmStream, error)
+}
+
+// PerformanceServer is the server API of the Performance service.
+type PerformanceServer interface {
+	CreatePmJob(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryPmJobs(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DeletePmJob(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+	QueryPerformanceReport(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	CreateThreshold(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryThresholds(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DeleteThreshold(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+	SubscribePerformance(*structpb.Struct, grpc.ServerStreamingServer[structpb.Struct]) error
+}
+
+var performanceServiceDesc = grpc.ServiceDesc{
+	ServiceName: PerformanceServiceName,
+	HandlerType: (*PerformanceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreatePmJob",
+			Handler:    interim.UnaryHandler(Performance_CreatePmJob_FullMethodName, PerformanceServer.CreatePmJob),
+		},
+		{
+			MethodName: "QueryPmJobs",
+			Handler:    interim.UnaryHandler(Performance_QueryPmJobs_FullMethodName, PerformanceServer.QueryPmJobs),
+		},
+		{
+			MethodName: "DeletePmJob",
+			Handler:    interim.UnaryHandler(Performance_DeletePmJob_FullMethodName, PerformanceServer.DeletePmJob),
+		},
+		{
+			MethodName: "QueryPerformanceReport",
+			Handler:    interim.UnaryHandler(Performance_QueryPerformanceReport_FullMethodName, PerformanceServer.QueryPerformanceReport),
+		},
+		{
+			MethodName: "CreateThreshold",
+			Handler:    interim.UnaryHandler(Performance_CreateThreshold_FullMethodName, PerformanceServer.CreateThreshold),
+		},
+		{
+			MethodName: "QueryThresholds",
+			Handler:    interim.UnaryHandler(Performance_QueryThresholds_FullMethodName, PerformanceServer.QueryThresholds),
+		},
+		{
+			MethodName: "DeleteThreshold",
+			Handler:    interim.UnaryHandler(Performance_DeleteThreshold_FullMethodName, PerformanceServer.DeleteThreshold),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribePerformance",
+			Handler:       interim.ServerStreamHandler(PerformanceServer.SubscribePerformance),
+			ServerStreams: true,
+		},
+	},
+}
+
+func RegisterPerformanceServer(s grpc.ServiceRegistrar, srv PerformanceServer) {
+	s.RegisterService(&performanceServiceDesc, srv)
+}
+
+// CreatePmJob calls the Performance CreatePmJob RPC over cc.
+func CreatePmJob(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Performance_CreatePmJob_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryPmJobs calls the Performance QueryPmJobs RPC over cc.
+func QueryPmJobs(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Performance_QueryPmJobs_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeletePmJob calls the Performance DeletePmJob RPC over cc.
+func DeletePmJob(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) error {
+	return cc.Invoke(ctx, Performance_DeletePmJob_FullMethodName, id, new(emptypb.Empty))
+}
+
+// QueryPerformanceReport calls the Performance QueryPerformanceReport RPC over cc.
+func QueryPerformanceReport(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Performance_QueryPerformanceReport_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateThreshold calls the Performance CreateThreshold RPC over cc.
+func CreateThreshold(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Performance_CreateThreshold_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryThresholds calls the Performance QueryThresholds RPC over cc.
+func QueryThresholds(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Performance_QueryThresholds_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteThreshold calls the Performance DeleteThreshold RPC over cc.
+func DeleteThreshold(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) error {
+	return cc.Invoke(ctx, Performance_DeleteThreshold_FullMethodName, id, new(emptypb.Empty))
+}
+
+// SubscribePerformance calls the Performance SubscribePerformance RPC over cc.
+func SubscribePerformance(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (grpc.ServerStreamingClient[structpb.Struct], error) {
+	return interim.NewServerStream[structpb.Struct, structpb.Struct](ctx, cc, &performanceServiceDesc.Streams[0], Performance_SubscribePerformance_FullMethodName, req)
+}
+
+// pmFilterRequest is the decoded query and subscribe request.
+type pmFilterRequest struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+// performanceReportRequest is the decoded QueryPerformanceReport request.
+type performanceReportRequest struct {
+	PmJobId  string `json:"pmJobId"`
+	ReportId string `json:"reportId"`
+}
+
+// CreatePmJob creates a PM job collecting the requested metrics.
+func (s *ViVnfmServer) CreatePmJob(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	in := &pm.PmJobRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode create pm job request: %v", err)
+	}
+	j, err := pmMgr.CreatePmJob(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("create pm job: %w", err)
+	}
+	return encodePm(j, "pm job", j.PmJobId)
+}
+
+// QueryPmJobs returns the PM jobs matching the request filter.
+func (s *ViVnfmServer) QueryPmJobs(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	in := &pmFilterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query pm jobs request: %v", err)
+	}
+	jobs, err := pmMgr.QueryPmJobs(in.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("query pm jobs: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(jobs))}
+	for i := range jobs {
+		st, err := encodePm(&jobs[i], "pm job", jobs[i].PmJobId)
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"pmJobs": structpb.NewListValue(list),
+	}}, nil
+}
+
+// DeletePmJob stops the PM job with the id and discards its reports.
+func (s *ViVnfmServer) DeletePmJob(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "pmJobId can't be empty")
+	}
+	if err := pmMgr.DeletePmJob(ctx, id.GetValue()); err != nil {
+		return nil, fmt.Errorf("delete pm job '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// QueryPerformanceReport returns a report of a PM job.
+func (s *ViVnfmServer) QueryPerformanceReport(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	in := &performanceReportRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query performance report request: %v", err)
+	}
+	if in.PmJobId == "" || in.ReportId == "" {
+		return nil, status.Error(codes.InvalidArgument, "pmJobId and reportId can't be empty")
+	}
+	report, err := pmMgr.GetPerformanceReport(in.PmJobId, in.ReportId)
+	if err != nil {
+		return nil, fmt.Errorf("query performance report '%s' of pm job '%s': %w", in.ReportId, in.PmJobId, err)
+	}
+	return encodePm(report, "performance report", report.ReportId)
+}
+
+// CreateThreshold creates a threshold on a metric.
+func (s *ViVnfmServer) CreateThreshold(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	in := &pm.ThresholdRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode create threshold request: %v", err)
+	}
+	t, err := pmMgr.CreateThreshold(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("create threshold: %w", err)
+	}
+	return encodePm(t, "threshold", t.ThresholdId)
+}
+
+// QueryThresholds returns the thresholds matching the request filter.
+func (s *ViVnfmServer) QueryThresholds(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	in := &pmFilterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode query thresholds request: %v", err)
+	}
+	thresholds, err := pmMgr.QueryThresholds(in.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("query thresholds: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(thresholds))}
+	for i := range thresholds {
+		st, err := encodePm(&thresholds[i], "threshold", thresholds[i].ThresholdId)
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"thresholds": structpb.NewListValue(list),
+	}}, nil
+}
+
+// DeleteThreshold stops evaluating the threshold with the id.
+func (s *ViVnfmServer) DeleteThreshold(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "thresholdId can't be empty")
+	}
+	if err := pmMgr.DeleteThreshold(ctx, id.GetValue()); err != nil {
+		return nil, fmt.Errorf("delete threshold '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// SubscribePerformance streams the PM notifications matching the request
+// filter until the client goes away or the subscription is dropped.
+func (s *ViVnfmServer) SubscribePerformance(req *structpb.Struct, stream grpc.ServerStreamingServer[structpb.Struct]) error {
+	pmMgr, err := s.pmManager()
+	if err != nil {
+		return err
+	}
+	in := &pmFilterRequest{}
+	if err := interim.DecodeStruct(req, in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "decode subscribe performance request: %v", err)
+	}
+	sub, err := pmMgr.Subscribe(stream.Context(), in.Filter)
+	if err != nil {
+		return fmt.Errorf("subscribe to pm notifications: %w", err)
+	}
+	defer sub.Close()
+	for n := range sub.Events() {
+		st, err := interim.EncodeStruct(n)
+		if err != nil {
+			return fmt.Errorf("encode pm notification: %w", err)
+		}
+		if err := stream.Send(st); err != nil {
+			return err
+		}
+	}
+	if err := sub.Err(); err != nil {
+		return fmt.Errorf("pm subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *ViVnfmServer) pmManager() (PmManager, error) {
+	if s.PmMgr == nil {
+		return nil, fmt.Errorf("performance management: %w", apperrors.ErrUnsupported)
+	}
+	return s.PmMgr, nil
+}
+
+func encodePm(v any, entity, id string) (*structpb.Struct, error) {
+	st, err := interim.EncodeStruct(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s '%s': %w", entity, id, err)
+	}
+	return st, nil
+}
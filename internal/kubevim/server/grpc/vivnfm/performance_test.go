@@ -0,0 +1,130 @@
+package vivnfm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// testPmManager serves the Performance service from an in-memory pm.Manager
+// that is never started, so no Prometheus query is made.
+type testPmManager struct {
+	*pm.Manager
+}
+
+func (m testPmManager) Subscribe(ctx context.Context, filter string) (PmStream, error) {
+	sub, err := m.Manager.Subscribe(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func newPerformanceConn(t *testing.T, s *ViVnfmServer) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, func(gs *grpc.Server) { RegisterPerformanceServer(gs, s) })
+}
+
+func TestPerformance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("pm job lifecycle", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.PmMgr = testPmManager{Manager: pm.NewManager(zap.NewNop(), nil, nil, time.Second, time.Minute)}
+		conn := newPerformanceConn(t, s)
+
+		req, err := structpb.NewStruct(map[string]any{
+			"objectType":         "COMPUTE",
+			"objectInstanceIds":  []any{"vm-a"},
+			"performanceMetrics": []any{"VCpuUsageMean"},
+			"collectionPeriod":   60,
+			"reportingPeriod":    300,
+		})
+		require.NoError(t, err)
+		job, err := CreatePmJob(ctx, conn, req)
+		require.NoError(t, err)
+		jobId := job.GetFields()["pmJobId"].GetStringValue()
+		require.NotEmpty(t, jobId)
+
+		query, err := structpb.NewStruct(map[string]any{"filter": "filter=(eq,pmJobId," + jobId + ")"})
+		require.NoError(t, err)
+		resp, err := QueryPmJobs(ctx, conn, query)
+		require.NoError(t, err)
+		require.Len(t, resp.GetFields()["pmJobs"].GetListValue().GetValues(), 1)
+
+		reportReq, err := structpb.NewStruct(map[string]any{"pmJobId": jobId, "reportId": "missing"})
+		require.NoError(t, err)
+		var notFound *apperrors.ErrNotFound
+		_, err = s.QueryPerformanceReport(ctx, reportReq)
+		assert.ErrorAs(t, err, &notFound)
+
+		require.NoError(t, DeletePmJob(ctx, conn, k8stest.ID(jobId)))
+		_, err = s.DeletePmJob(ctx, k8stest.ID(jobId))
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("threshold lifecycle", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.PmMgr = testPmManager{Manager: pm.NewManager(zap.NewNop(), nil, nil, time.Second, time.Minute)}
+		conn := newPerformanceConn(t, s)
+
+		req, err := structpb.NewStruct(map[string]any{
+			"objectType":        "COMPUTE",
+			"objectInstanceIds": []any{"vm-a"},
+			"performanceMetric": "VMemoryUsageMean",
+			"thresholdValue":    90,
+		})
+		require.NoError(t, err)
+		th, err := CreateThreshold(ctx, conn, req)
+		require.NoError(t, err)
+		thresholdId := th.GetFields()["thresholdId"].GetStringValue()
+
+		resp, err := QueryThresholds(ctx, conn, &structpb.Struct{})
+		require.NoError(t, err)
+		thresholds := resp.GetFields()["thresholds"].GetListValue().GetValues()
+		require.Len(t, thresholds, 1)
+		assert.Equal(t, thresholdId, thresholds[0].GetStructValue().GetFields()["thresholdId"].GetStringValue())
+
+		require.NoError(t, DeleteThreshold(ctx, conn, k8stest.ID(thresholdId)))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		s, _ := newServer(t)
+		s.PmMgr = testPmManager{Manager: pm.NewManager(zap.NewNop(), nil, nil, time.Second, time.Minute)}
+		conn := newPerformanceConn(t, s)
+
+		unknown, err := structpb.NewStruct(map[string]any{"objectType": "COMPUTE", "unknown": true})
+		require.NoError(t, err)
+		_, err = CreatePmJob(ctx, conn, unknown)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		network, err := structpb.NewStruct(map[string]any{
+			"objectType":        "NETWORK",
+			"objectInstanceIds": []any{"net-a"},
+			"performanceMetric": "VCpuUsageMean",
+		})
+		require.NoError(t, err)
+		var invalid *apperrors.ErrInvalidArgument
+		_, err = s.CreateThreshold(ctx, network)
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, "objectType", invalid.Field)
+	})
+
+	t.Run("without a pm manager", func(t *testing.T) {
+		s, _ := newServer(t)
+		_, err := s.QueryPmJobs(ctx, &structpb.Struct{})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
@@ -0,0 +1,229 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no security group messages or RPCs yet. Until it does,
+// kube-vim serves them from the hand-written SecurityGroups service below. A
+// network.SecurityGroup travels as its JSON form in a structpb.Struct, and a
+// query of them as a structpb.Struct with their list under securityGroups.
+const (
+	SecurityGroupsServiceName                         = "kubenvf.kubevim.api.pb.vi_vnfm.SecurityGroups"
+	SecurityGroups_CreateSecurityGroup_FullMethodName = "/" + SecurityGroupsServiceName + "/CreateSecurityGroup"
+	SecurityGroups_QuerySecurityGroups_FullMethodName = "/" + SecurityGroupsServiceName + "/QuerySecurityGroups"
+	SecurityGroups_QuerySecurityGroup_FullMethodName  = "/" + SecurityGroupsServiceName + "/QuerySecurityGroup"
+	SecurityGroups_UpdateSecurityGroup_FullMethodName = "/" + SecurityGroupsServiceName + "/UpdateSecurityGroup"
+	SecurityGroups_DeleteSecurityGroup_FullMethodName = "/" + SecurityGroupsServiceName + "/DeleteSecurityGroup"
+)
+
+// SecurityGroupsServer is the server API of the SecurityGroups service.
+type SecurityGroupsServer interface {
+	CreateSecurityGroup(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QuerySecurityGroups(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	QuerySecurityGroup(context.Context, *nfvcommon.Identifier) (*structpb.Struct, error)
+	// UpdateSecurityGroup replaces the rules of the group whose securityGroupId
+	// is set in the request.
+	UpdateSecurityGroup(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	DeleteSecurityGroup(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+}
+
+var securityGroupsServiceDesc = grpc.ServiceDesc{
+	ServiceName: SecurityGroupsServiceName,
+	HandlerType: (*SecurityGroupsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateSecurityGroup",
+			Handler:    interim.UnaryHandler(SecurityGroups_CreateSecurityGroup_FullMethodName, SecurityGroupsServer.CreateSecurityGroup),
+		},
+		{
+			MethodName: "QuerySecurityGroups",
+			Handler:    interim.UnaryHandler(SecurityGroups_QuerySecurityGroups_FullMethodName, SecurityGroupsServer.QuerySecurityGroups),
+		},
+		{
+			MethodName: "QuerySecurityGroup",
+			Handler:    interim.UnaryHandler(SecurityGroups_QuerySecurityGroup_FullMethodName, SecurityGroupsServer.QuerySecurityGroup),
+		},
+		{
+			MethodName: "UpdateSecurityGroup",
+			Handler:    interim.UnaryHandler(SecurityGroups_UpdateSecurityGroup_FullMethodName, SecurityGroupsServer.UpdateSecurityGroup),
+		},
+		{
+			MethodName: "DeleteSecurityGroup",
+			Handler:    interim.UnaryHandler(SecurityGroups_DeleteSecurityGroup_FullMethodName, SecurityGroupsServer.DeleteSecurityGroup),
+		},
+	},
+}
+
+func RegisterSecurityGroupsServer(s grpc.ServiceRegistrar, srv SecurityGroupsServer) {
+	s.RegisterService(&securityGroupsServiceDesc, srv)
+}
+
+// CreateSecurityGroup calls the SecurityGroups CreateSecurityGroup RPC over cc.
+func CreateSecurityGroup(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, SecurityGroups_CreateSecurityGroup_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QuerySecurityGroups calls the SecurityGroups QuerySecurityGroups RPC over cc.
+func QuerySecurityGroups(ctx context.Context, cc grpc.ClientConnInterface) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, SecurityGroups_QuerySecurityGroups_FullMethodName, &emptypb.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QuerySecurityGroup calls the SecurityGroups QuerySecurityGroup RPC over cc.
+func QuerySecurityGroup(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, SecurityGroups_QuerySecurityGroup_FullMethodName, id, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateSecurityGroup calls the SecurityGroups UpdateSecurityGroup RPC over cc.
+func UpdateSecurityGroup(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, SecurityGroups_UpdateSecurityGroup_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteSecurityGroup calls the SecurityGroups DeleteSecurityGroup RPC over cc.
+func DeleteSecurityGroup(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) error {
+	return cc.Invoke(ctx, SecurityGroups_DeleteSecurityGroup_FullMethodName, id, new(emptypb.Empty))
+}
+
+// CreateSecurityGroup creates a security group with its rules.
+func (s *ViVnfmServer) CreateSecurityGroup(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	sgm, err := s.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	sg, err := securityGroupFromStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := sgm.CreateSecurityGroup(ctx, sg)
+	if err != nil {
+		return nil, fmt.Errorf("create security group '%s': %w", sg.Name, err)
+	}
+	return securityGroupToStruct(res)
+}
+
+// QuerySecurityGroups returns every security group.
+func (s *ViVnfmServer) QuerySecurityGroups(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	sgm, err := s.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	sgs, err := sgm.ListSecurityGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query security groups: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(sgs))}
+	for _, sg := range sgs {
+		st, err := securityGroupToStruct(sg)
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"securityGroups": structpb.NewListValue(list),
+	}}, nil
+}
+
+// QuerySecurityGroup returns the security group with the id.
+func (s *ViVnfmServer) QuerySecurityGroup(ctx context.Context, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	sgm, err := s.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "securityGroupId can't be empty")
+	}
+	sg, err := sgm.GetSecurityGroup(ctx, network.GetSecurityGroupByUid(id))
+	if err != nil {
+		return nil, fmt.Errorf("query security group '%s': %w", id.GetValue(), err)
+	}
+	return securityGroupToStruct(sg)
+}
+
+// UpdateSecurityGroup replaces the rules of the security group identified by
+// the request securityGroupId.
+func (s *ViVnfmServer) UpdateSecurityGroup(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	sgm, err := s.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	sg, err := securityGroupFromStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	if sg.SecurityGroupId.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "securityGroupId can't be empty")
+	}
+	res, err := sgm.UpdateSecurityGroup(ctx, sg)
+	if err != nil {
+		return nil, fmt.Errorf("update security group '%s': %w", sg.SecurityGroupId.GetValue(), err)
+	}
+	return securityGroupToStruct(res)
+}
+
+// DeleteSecurityGroup deletes the security group with the id. It fails while
+// vNICs are bound to the group.
+func (s *ViVnfmServer) DeleteSecurityGroup(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	sgm, err := s.securityGroupManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "securityGroupId can't be empty")
+	}
+	if err := sgm.DeleteSecurityGroup(ctx, network.GetSecurityGroupByUid(id)); err != nil {
+		return nil, fmt.Errorf("delete security group '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ViVnfmServer) securityGroupManager() (network.SecurityGroupManager, error) {
+	sgm, ok := s.NetworkMgr.(network.SecurityGroupManager)
+	if !ok {
+		return nil, fmt.Errorf("security groups with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	return sgm, nil
+}
+
+func securityGroupFromStruct(st *structpb.Struct) (*network.SecurityGroup, error) {
+	sg := &network.SecurityGroup{}
+	if err := interim.DecodeStruct(st, sg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode security group: %v", err)
+	}
+	return sg, nil
+}
+
+func securityGroupToStruct(sg *network.SecurityGroup) (*structpb.Struct, error) {
+	st, err := interim.EncodeStruct(sg)
+	if err != nil {
+		return nil, fmt.Errorf("encode security group '%s': %w", sg.Name, err)
+	}
+	return st, nil
+}
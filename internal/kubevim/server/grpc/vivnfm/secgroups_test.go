@@ -0,0 +1,134 @@
+package vivnfm
+
+import (
+	"context"
+	"testing"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// sgNetworkManager is a network manager that also manages security groups.
+type sgNetworkManager struct {
+	*networkmock.MockManager
+	*networkmock.MockSecurityGroupManager
+}
+
+func newSecurityGroupServer(t *testing.T) (*ViVnfmServer, *networkmock.MockSecurityGroupManager) {
+	t.Helper()
+	s, m := newServer(t)
+	sgm := networkmock.NewMockSecurityGroupManager(gomock.NewController(t))
+	s.NetworkMgr = sgNetworkManager{m.network, sgm}
+	return s, sgm
+}
+
+func TestCreateSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("decodes the group and encodes the result", func(t *testing.T) {
+		s, sgm := newSecurityGroupServer(t)
+		sgm.EXPECT().CreateSecurityGroup(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+				assert.Equal(t, "web", sg.Name)
+				require.Len(t, sg.Rules, 1)
+				assert.Equal(t, int32(443), sg.Rules[0].PortRangeMin)
+				sg.SecurityGroupId = k8stest.ID("uid-web")
+				return sg, nil
+			})
+		req, err := structpb.NewStruct(map[string]any{
+			"name":  "web",
+			"rules": []any{map[string]any{"direction": "ingress", "protocol": "tcp", "portRangeMin": 443, "remoteCidr": "0.0.0.0/0"}},
+		})
+		require.NoError(t, err)
+		resp, err := CreateSecurityGroup(ctx, newSecurityGroupsConn(t, s), req)
+		require.NoError(t, err)
+		assert.Equal(t, "uid-web", resp.GetFields()["securityGroupId"].GetStructValue().GetFields()["value"].GetStringValue())
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		s, _ := newSecurityGroupServer(t)
+		req, err := structpb.NewStruct(map[string]any{"name": "web", "ports": "22"})
+		require.NoError(t, err)
+		_, err = s.CreateSecurityGroup(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestSecurityGroupQueries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s, sgm := newSecurityGroupServer(t)
+	sgm.EXPECT().ListSecurityGroups(gomock.Any()).Return([]*network.SecurityGroup{{Name: "web"}, {Name: "ssh"}}, nil)
+	sgm.EXPECT().GetSecurityGroup(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, opts ...network.GetSecurityGroupOpt) (*network.SecurityGroup, error) {
+			return &network.SecurityGroup{SecurityGroupId: network.ApplyGetSecurityGroupOpts(opts...).Uid, Name: "ssh"}, nil
+		})
+	conn := newSecurityGroupsConn(t, s)
+
+	list, err := QuerySecurityGroups(ctx, conn)
+	require.NoError(t, err)
+	assert.Len(t, list.GetFields()["securityGroups"].GetListValue().GetValues(), 2)
+
+	sg, err := QuerySecurityGroup(ctx, conn, k8stest.ID("uid-ssh"))
+	require.NoError(t, err)
+	assert.Equal(t, "ssh", sg.GetFields()["name"].GetStringValue())
+}
+
+func TestUpdateSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("id is required", func(t *testing.T) {
+		s, _ := newSecurityGroupServer(t)
+		req, err := structpb.NewStruct(map[string]any{"rules": []any{}})
+		require.NoError(t, err)
+		_, err = s.UpdateSecurityGroup(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("empty rules clear the group", func(t *testing.T) {
+		s, sgm := newSecurityGroupServer(t)
+		sgm.EXPECT().UpdateSecurityGroup(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, sg *network.SecurityGroup) (*network.SecurityGroup, error) {
+				assert.Equal(t, "uid-web", sg.SecurityGroupId.GetValue())
+				assert.NotNil(t, sg.Rules)
+				assert.Empty(t, sg.Rules)
+				return sg, nil
+			})
+		req, err := structpb.NewStruct(map[string]any{"securityGroupId": map[string]any{"value": "uid-web"}, "rules": []any{}})
+		require.NoError(t, err)
+		_, err = s.UpdateSecurityGroup(ctx, req)
+		require.NoError(t, err)
+	})
+}
+
+func TestDeleteSecurityGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("in use", func(t *testing.T) {
+		s, sgm := newSecurityGroupServer(t)
+		sgm.EXPECT().DeleteSecurityGroup(gomock.Any(), gomock.Any()).Return(&network.ErrInUse{Resource: "security group 'web'", ComputeIds: nil})
+		_, err := s.DeleteSecurityGroup(ctx, k8stest.ID("uid-web"))
+		var precondition *apperrors.ErrFailedPrecondition
+		assert.ErrorAs(t, err, &precondition)
+	})
+
+	t.Run("unsupported network manager", func(t *testing.T) {
+		s, _ := newServer(t)
+		_, err := s.DeleteSecurityGroup(ctx, k8stest.ID("uid-web"))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+		_, err = s.QuerySecurityGroups(ctx, &emptypb.Empty{})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
 	filter "github.com/kube-nfv/query-filter"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 )
@@ -25,6 +26,12 @@ type ViVnfmServer struct {
 	FlavourMgr flavour.Manager
 	NetworkMgr network.Manager
 	ComputeMgr compute.Manager
+	// Notifications serves the Notifications service. nil disables it.
+	Notifications NotificationSource
+	// AlarmMgr serves the Alarms service. nil disables it.
+	AlarmMgr AlarmManager
+	// PmMgr serves the Performance service. nil disables it.
+	PmMgr PmManager
 }
 
 func (s *ViVnfmServer) QueryImages(ctx context.Context, req *vivnfm.QueryImagesRequest) (*vivnfm.QueryImagesResponse, error) {
@@ -170,27 +177,54 @@ func (s *ViVnfmServer) QueryVirtualisedNetworkResource(ctx context.Context, req
 	}
 }
 
+// TerminateVirtualisedNetworkResource deletes a network or a subnet. While
+// computes are attached to it the deletion fails with FailedPrecondition, unless
+// the network.DeleteCascadeMetadataKey request metadata is set: the attached
+// computes are then terminated first.
 func (s *ViVnfmServer) TerminateVirtualisedNetworkResource(ctx context.Context, req *vivnfm.TerminateNetworkRequest) (*vivnfm.TerminateNetworkResponse, error) {
-	err := s.NetworkMgr.DeleteNetwork(ctx, network.GetNetworkByUid(req.NetworkResourceId))
+	md, _ := metadata.FromIncomingContext(ctx)
+	cascade := false
+	if v := md.Get(network.DeleteCascadeMetadataKey); len(v) > 0 {
+		cascade = v[0] == "true"
+	}
+	err := s.deleteNetworkResource(ctx, req.NetworkResourceId)
+	var inUseErr *network.ErrInUse
+	if cascade && errors.As(err, &inUseErr) {
+		for _, computeId := range inUseErr.ComputeIds {
+			err := s.ComputeMgr.DeleteComputeResource(ctx, compute.GetComputeByUid(computeId))
+			var computeNotFoundErr *apperrors.ErrNotFound
+			if err != nil && !errors.As(err, &computeNotFoundErr) && !k8s_errors.IsNotFound(err) {
+				return nil, fmt.Errorf("terminate compute '%s' attached to network resource '%s': %w", computeId.GetValue(), req.NetworkResourceId.GetValue(), err)
+			}
+		}
+		err = s.deleteNetworkResource(ctx, req.NetworkResourceId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &vivnfm.TerminateNetworkResponse{
+		NetworkResourceId: req.NetworkResourceId,
+	}, nil
+}
+
+// Deletes the network with the id, or the subnet if no network has it.
+func (s *ViVnfmServer) deleteNetworkResource(ctx context.Context, id *nfvcommon.Identifier) error {
+	err := s.NetworkMgr.DeleteNetwork(ctx, network.GetNetworkByUid(id))
 	if err == nil {
-		return &vivnfm.TerminateNetworkResponse{
-			NetworkResourceId: req.NetworkResourceId,
-		}, nil
+		return nil
 	}
 	var networkNotFoundErr *apperrors.ErrNotFound
 	if !errors.As(err, &networkNotFoundErr) && !k8s_errors.IsNotFound(err) {
-		return nil, fmt.Errorf("delete network '%s': %w", req.NetworkResourceId.GetValue(), err)
+		return fmt.Errorf("delete network '%s': %w", id.GetValue(), err)
 	}
-	err = s.NetworkMgr.DeleteSubnet(ctx, network.GetSubnetByUid(req.NetworkResourceId))
+	err = s.NetworkMgr.DeleteSubnet(ctx, network.GetSubnetByUid(id))
 	if err == nil {
-		return &vivnfm.TerminateNetworkResponse{
-			NetworkResourceId: req.NetworkResourceId,
-		}, nil
+		return nil
 	}
 	var subnetNotFoundErr *apperrors.ErrNotFound
 	if errors.As(err, &subnetNotFoundErr) || k8s_errors.IsNotFound(err) {
-		return nil, fmt.Errorf("network resource '%s' not found in networks or subnets: %w", req.NetworkResourceId.GetValue(), err)
+		return fmt.Errorf("network resource '%s' not found in networks or subnets: %w", id.GetValue(), err)
 	} else {
-		return nil, fmt.Errorf("delete subnet '%s': %w", req.NetworkResourceId.GetValue(), err)
+		return fmt.Errorf("delete subnet '%s': %w", id.GetValue(), err)
 	}
 }
@@ -13,11 +13,13 @@ import (
 	computemock "github.com/kube-nfv/kube-vim/internal/kubevim/compute/mock"
 	flavourmock "github.com/kube-nfv/kube-vim/internal/kubevim/flavour/mock"
 	imagemock "github.com/kube-nfv/kube-vim/internal/kubevim/image/mock"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
 	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -274,4 +276,38 @@ func TestTerminateVirtualisedNetworkResource(t *testing.T) {
 		_, err := s.TerminateVirtualisedNetworkResource(context.Background(), &vivnfm.TerminateNetworkRequest{NetworkResourceId: k8stest.ID("r1")})
 		require.Error(t, err)
 	})
+
+	inUse := &network.ErrInUse{Resource: "network 'net1'", ComputeIds: []*nfvcommon.Identifier{k8stest.ID("c1"), k8stest.ID("c2")}}
+
+	t.Run("attached computes fail the precondition", func(t *testing.T) {
+		s, m := newServer(t) // computes must not be terminated without cascade
+		m.network.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).Return(inUse)
+		_, err := s.TerminateVirtualisedNetworkResource(context.Background(), &vivnfm.TerminateNetworkRequest{NetworkResourceId: k8stest.ID("r1")})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(apperrors.ToGRPCError(err)))
+		assert.Contains(t, err.Error(), "c1, c2")
+	})
+
+	t.Run("cascade terminates the attached computes first", func(t *testing.T) {
+		s, m := newServer(t)
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(network.DeleteCascadeMetadataKey, "true"))
+		gomock.InOrder(
+			m.network.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).Return(inUse),
+			m.compute.EXPECT().DeleteComputeResource(gomock.Any(), gomock.Any()).Return(nil),
+			m.compute.EXPECT().DeleteComputeResource(gomock.Any(), gomock.Any()).Return(&apperrors.ErrNotFound{Entity: "compute"}),
+			m.network.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).Return(nil),
+		)
+		resp, err := s.TerminateVirtualisedNetworkResource(ctx, &vivnfm.TerminateNetworkRequest{NetworkResourceId: k8stest.ID("r1")})
+		require.NoError(t, err)
+		assert.Equal(t, "r1", resp.NetworkResourceId.GetValue())
+	})
+
+	t.Run("cascade stops when a compute fails to terminate", func(t *testing.T) {
+		s, m := newServer(t)
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(network.DeleteCascadeMetadataKey, "true"))
+		m.network.EXPECT().DeleteNetwork(gomock.Any(), gomock.Any()).Return(&apperrors.ErrNotFound{Entity: "network"})
+		m.network.EXPECT().DeleteSubnet(gomock.Any(), gomock.Any()).Return(inUse)
+		m.compute.EXPECT().DeleteComputeResource(gomock.Any(), gomock.Any()).Return(errors.New("boom"))
+		_, err := s.TerminateVirtualisedNetworkResource(ctx, &vivnfm.TerminateNetworkRequest{NetworkResourceId: k8stest.ID("r1")})
+		require.ErrorContains(t, err, "terminate compute 'c1'")
+	})
 }
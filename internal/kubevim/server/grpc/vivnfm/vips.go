@@ -0,0 +1,182 @@
+package vivnfm
+
+import (
+	"context"
+	"fmt"
+
+	nfvcommon "github.com/kube-nfv/kube-vim-api/pkg/apis"
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/interim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// kube-vim-api has no virtual IP messages or RPCs yet. Until it does, kube-vim
+// serves them from the hand-written Vips service below. A network.Vip travels
+// as its JSON form in a structpb.Struct, and a query of them as a
+// structpb.Struct with their list under vips.
+const (
+	VipsServiceName               = "kubenvf.kubevim.api.pb.vi_vnfm.Vips"
+	Vips_CreateVip_FullMethodName = "/" + VipsServiceName + "/CreateVip"
+	Vips_QueryVips_FullMethodName = "/" + VipsServiceName + "/QueryVips"
+	Vips_QueryVip_FullMethodName  = "/" + VipsServiceName + "/QueryVip"
+	Vips_DeleteVip_FullMethodName = "/" + VipsServiceName + "/DeleteVip"
+)
+
+// VipsServer is the server API of the Vips service.
+type VipsServer interface {
+	CreateVip(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	QueryVips(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	QueryVip(context.Context, *nfvcommon.Identifier) (*structpb.Struct, error)
+	DeleteVip(context.Context, *nfvcommon.Identifier) (*emptypb.Empty, error)
+}
+
+var vipsServiceDesc = grpc.ServiceDesc{
+	ServiceName: VipsServiceName,
+	HandlerType: (*VipsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateVip",
+			Handler:    interim.UnaryHandler(Vips_CreateVip_FullMethodName, VipsServer.CreateVip),
+		},
+		{
+			MethodName: "QueryVips",
+			Handler:    interim.UnaryHandler(Vips_QueryVips_FullMethodName, VipsServer.QueryVips),
+		},
+		{
+			MethodName: "QueryVip",
+			Handler:    interim.UnaryHandler(Vips_QueryVip_FullMethodName, VipsServer.QueryVip),
+		},
+		{
+			MethodName: "DeleteVip",
+			Handler:    interim.UnaryHandler(Vips_DeleteVip_FullMethodName, VipsServer.DeleteVip),
+		},
+	},
+}
+
+func RegisterVipsServer(s grpc.ServiceRegistrar, srv VipsServer) {
+	s.RegisterService(&vipsServiceDesc, srv)
+}
+
+// CreateVip calls the Vips CreateVip RPC over cc.
+func CreateVip(ctx context.Context, cc grpc.ClientConnInterface, req *structpb.Struct) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Vips_CreateVip_FullMethodName, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryVips calls the Vips QueryVips RPC over cc.
+func QueryVips(ctx context.Context, cc grpc.ClientConnInterface) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Vips_QueryVips_FullMethodName, &emptypb.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QueryVip calls the Vips QueryVip RPC over cc.
+func QueryVip(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	res := new(structpb.Struct)
+	if err := cc.Invoke(ctx, Vips_QueryVip_FullMethodName, id, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteVip calls the Vips DeleteVip RPC over cc.
+func DeleteVip(ctx context.Context, cc grpc.ClientConnInterface, id *nfvcommon.Identifier) error {
+	return cc.Invoke(ctx, Vips_DeleteVip_FullMethodName, id, new(emptypb.Empty))
+}
+
+// CreateVip reserves a virtual IP in a subnet.
+func (s *ViVnfmServer) CreateVip(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	vipMgr, err := s.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	vip := &network.Vip{}
+	if err := interim.DecodeStruct(req, vip); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode vip: %v", err)
+	}
+	res, err := vipMgr.CreateVip(ctx, vip)
+	if err != nil {
+		return nil, fmt.Errorf("create vip '%s': %w", vip.Name, err)
+	}
+	return vipToStruct(res)
+}
+
+// QueryVips returns every virtual IP.
+func (s *ViVnfmServer) QueryVips(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	vipMgr, err := s.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	vips, err := vipMgr.ListVips(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query vips: %w", err)
+	}
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(vips))}
+	for _, vip := range vips {
+		st, err := vipToStruct(vip)
+		if err != nil {
+			return nil, err
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(st))
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"vips": structpb.NewListValue(list),
+	}}, nil
+}
+
+// QueryVip returns the virtual IP with the id.
+func (s *ViVnfmServer) QueryVip(ctx context.Context, id *nfvcommon.Identifier) (*structpb.Struct, error) {
+	vipMgr, err := s.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "vipId can't be empty")
+	}
+	vip, err := vipMgr.GetVip(ctx, network.GetVipByUid(id))
+	if err != nil {
+		return nil, fmt.Errorf("query vip '%s': %w", id.GetValue(), err)
+	}
+	return vipToStruct(vip)
+}
+
+// DeleteVip detaches the virtual IP with the id from every vNIC and deletes it.
+func (s *ViVnfmServer) DeleteVip(ctx context.Context, id *nfvcommon.Identifier) (*emptypb.Empty, error) {
+	vipMgr, err := s.vipManager()
+	if err != nil {
+		return nil, err
+	}
+	if id.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "vipId can't be empty")
+	}
+	if err := vipMgr.DeleteVip(ctx, network.GetVipByUid(id)); err != nil {
+		return nil, fmt.Errorf("delete vip '%s': %w", id.GetValue(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ViVnfmServer) vipManager() (network.VipManager, error) {
+	vipMgr, ok := s.NetworkMgr.(network.VipManager)
+	if !ok {
+		return nil, fmt.Errorf("virtual IPs with the configured network manager: %w", apperrors.ErrUnsupported)
+	}
+	return vipMgr, nil
+}
+
+func vipToStruct(vip *network.Vip) (*structpb.Struct, error) {
+	st, err := interim.EncodeStruct(vip)
+	if err != nil {
+		return nil, fmt.Errorf("encode vip '%s': %w", vip.Name, err)
+	}
+	return st, nil
+}
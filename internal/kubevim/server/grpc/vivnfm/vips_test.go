@@ -0,0 +1,103 @@
+package vivnfm
+
+import (
+	"context"
+	"testing"
+
+	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/k8s/k8stest"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	networkmock "github.com/kube-nfv/kube-vim/internal/kubevim/network/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// vipNetworkManager is a network manager that also manages virtual IPs.
+type vipNetworkManager struct {
+	*networkmock.MockManager
+	*networkmock.MockVipManager
+}
+
+func newVipServer(t *testing.T) (*ViVnfmServer, *networkmock.MockVipManager) {
+	t.Helper()
+	s, m := newServer(t)
+	vipm := networkmock.NewMockVipManager(gomock.NewController(t))
+	s.NetworkMgr = vipNetworkManager{m.network, vipm}
+	return s, vipm
+}
+
+func TestCreateVip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("decodes the vip and encodes the result", func(t *testing.T) {
+		s, vipm := newVipServer(t)
+		vipm.EXPECT().CreateVip(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, vip *network.Vip) (*network.Vip, error) {
+				assert.Equal(t, "vrrp", vip.Name)
+				assert.Equal(t, "uid-sub1", vip.SubnetId.GetValue())
+				vip.VipId = k8stest.ID("uid-vrrp")
+				vip.IpAddresses = []string{"10.0.0.100"}
+				return vip, nil
+			})
+		req, err := structpb.NewStruct(map[string]any{"name": "vrrp", "subnetId": map[string]any{"value": "uid-sub1"}})
+		require.NoError(t, err)
+		resp, err := CreateVip(ctx, newVipsConn(t, s), req)
+		require.NoError(t, err)
+		assert.Equal(t, "uid-vrrp", resp.GetFields()["vipId"].GetStructValue().GetFields()["value"].GetStringValue())
+		assert.Equal(t, "10.0.0.100", resp.GetFields()["ipAddresses"].GetListValue().GetValues()[0].GetStringValue())
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		s, _ := newVipServer(t)
+		req, err := structpb.NewStruct(map[string]any{"name": "vrrp", "ip": "10.0.0.100"})
+		require.NoError(t, err)
+		_, err = s.CreateVip(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestVipQueries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s, vipm := newVipServer(t)
+	vipm.EXPECT().ListVips(gomock.Any()).Return([]*network.Vip{{Name: "vrrp"}}, nil)
+	vipm.EXPECT().GetVip(gomock.Any(), gomock.Any()).Return(&network.Vip{Name: "vrrp"}, nil)
+	conn := newVipsConn(t, s)
+
+	list, err := QueryVips(ctx, conn)
+	require.NoError(t, err)
+	assert.Len(t, list.GetFields()["vips"].GetListValue().GetValues(), 1)
+
+	vip, err := QueryVip(ctx, conn, k8stest.ID("uid-vrrp"))
+	require.NoError(t, err)
+	assert.Equal(t, "vrrp", vip.GetFields()["name"].GetStringValue())
+}
+
+func TestDeleteVip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("deletes by id", func(t *testing.T) {
+		s, vipm := newVipServer(t)
+		vipm.EXPECT().DeleteVip(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, opts ...network.GetVipOpt) error {
+				assert.Equal(t, "uid-vrrp", network.ApplyGetVipOpts(opts...).Uid.GetValue())
+				return nil
+			})
+		require.NoError(t, DeleteVip(ctx, newVipsConn(t, s), k8stest.ID("uid-vrrp")))
+	})
+
+	t.Run("unsupported network manager", func(t *testing.T) {
+		s, _ := newServer(t)
+		_, err := s.DeleteVip(ctx, k8stest.ID("uid-vrrp"))
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+		_, err = s.QueryVips(ctx, &emptypb.Empty{})
+		assert.ErrorIs(t, err, apperrors.ErrUnsupported)
+	})
+}
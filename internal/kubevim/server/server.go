@@ -11,10 +11,16 @@ import (
 	vivnfm "github.com/kube-nfv/kube-vim-api/pkg/apis/vivnfm"
 	config "github.com/kube-nfv/kube-vim/internal/config/kubevim"
 	apperrors "github.com/kube-nfv/kube-vim/internal/errors"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/alarm"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/compute"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/flavour"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/image"
 	"github.com/kube-nfv/kube-vim/internal/kubevim/network"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/notification"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/orvi"
+	"github.com/kube-nfv/kube-vim/internal/kubevim/pm"
+	adminserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/admin"
+	orviserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/orvi"
 	vivnfmserver "github.com/kube-nfv/kube-vim/internal/kubevim/server/grpc/vivnfm"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel/metric"
@@ -43,7 +49,11 @@ func NewNorthboundServer(
 	imageMgr image.Manager,
 	networkManager network.Manager,
 	flavourManager flavour.Manager,
-	computeManager compute.Manager) (*NorthboundServer, error) {
+	computeManager compute.Manager,
+	notificationHub *notification.Hub,
+	alarmMgr *alarm.Manager,
+	pmMgr *pm.Manager,
+	orviSvc *orvi.Service) (*NorthboundServer, error) {
 	// TODO: Add Security
 	opts := []grpc.ServerOption{
 		// Emits per-RPC RED metrics through meterProvider (a no-op provider when
@@ -61,6 +71,14 @@ func NewNorthboundServer(
 				return loggingInterceptor(ctx, req, info, handler, log)
 			},
 		),
+		grpc.ChainStreamInterceptor(
+			func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				return errorConversionStreamInterceptor(srv, ss, info, handler)
+			},
+			func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				return loggingStreamInterceptor(srv, ss, info, handler, log)
+			},
+		),
 	}
 	if cfg.Tls != nil {
 		creds, err := credentials.NewServerTLSFromFile(*cfg.Tls.Cert, *cfg.Tls.Key)
@@ -72,13 +90,39 @@ func NewNorthboundServer(
 		log.Warn("No TLS configuration specified. Kubevim gRPC server will launch unsecure!")
 	}
 	server := grpc.NewServer(opts...)
-	vivnfm.RegisterViVnfmServer(server, &vivnfmserver.ViVnfmServer{
+	vivnfmSrv := &vivnfmserver.ViVnfmServer{
 		ImageMgr:   imageMgr,
 		NetworkMgr: networkManager,
 		FlavourMgr: flavourManager,
 		ComputeMgr: computeManager,
-	})
-	admin.RegisterAdminServer(server, imageMgr)
+	}
+	if notificationHub != nil {
+		vivnfmSrv.Notifications = hubNotificationSource{hub: notificationHub}
+	}
+	if alarmMgr != nil {
+		vivnfmSrv.AlarmMgr = alarmManager{Manager: alarmMgr}
+	}
+	if pmMgr != nil {
+		vivnfmSrv.PmMgr = pmManager{Manager: pmMgr}
+	}
+	vivnfm.RegisterViVnfmServer(server, vivnfmSrv)
+	vivnfmserver.RegisterNetworksServer(server, vivnfmSrv)
+	vivnfmserver.RegisterSecurityGroupsServer(server, vivnfmSrv)
+	vivnfmserver.RegisterVipsServer(server, vivnfmSrv)
+	vivnfmserver.RegisterNatServer(server, vivnfmSrv)
+	vivnfmserver.RegisterNotificationsServer(server, vivnfmSrv)
+	vivnfmserver.RegisterAlarmsServer(server, vivnfmSrv)
+	vivnfmserver.RegisterPerformanceServer(server, vivnfmSrv)
+	adminSrv := &adminserver.AdminServer{
+		ImageMgr: imageMgr,
+	}
+	admin.RegisterAdminServer(server, adminSrv)
+	adminserver.RegisterImagesServer(server, adminSrv)
+	orviSrv := &orviserver.Server{}
+	if orviSvc != nil {
+		orviSrv.Svc = orviSvc
+	}
+	orviserver.RegisterOrViServer(server, orviSrv)
 	reflection.Register(server)
 	return &NorthboundServer{
 		server: server,
@@ -134,6 +178,86 @@ func errorConversionInterceptor(ctx context.Context, req any, info *grpc.UnarySe
 	return resp, nil
 }
 
+// errorConversionStreamInterceptor converts application errors ending a stream
+// to gRPC status errors
+func errorConversionStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		if grpcErr := apperrors.ToGRPCError(err); grpcErr != nil {
+			return grpcErr
+		}
+		return err
+	}
+	return nil
+}
+
+func loggingStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler, log *zap.Logger) error {
+	var clientIP string
+	if p, ok := peer.FromContext(ss.Context()); ok {
+		if addr, ok := p.Addr.(*net.TCPAddr); ok {
+			clientIP = addr.IP.String()
+		}
+	}
+	log.Info("New incoming stream", zap.String("Request", info.FullMethod), zap.String("IP", clientIP))
+	start := time.Now()
+	err := handler(srv, ss)
+	duration := time.Since(start)
+	if err != nil {
+		log.Error(
+			"Stream failed",
+			zap.String("Request", info.FullMethod),
+			zap.String("IP", clientIP),
+			zap.Duration("Duration", duration),
+			zap.Error(err))
+	} else {
+		log.Info(
+			"Stream completed",
+			zap.String("Request", info.FullMethod),
+			zap.String("IP", clientIP),
+			zap.Duration("Duration", duration))
+	}
+	return err
+}
+
+// hubNotificationSource serves the Notifications service from the
+// notification hub.
+type hubNotificationSource struct {
+	hub *notification.Hub
+}
+
+func (s hubNotificationSource) Subscribe(ctx context.Context, filter string, fromVersion string) (vivnfmserver.EventStream, error) {
+	sub, err := s.hub.Subscribe(ctx, notification.SubscribeOptions{Filter: filter, FromVersion: fromVersion})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// alarmManager serves the Alarms service from the alarm manager.
+type alarmManager struct {
+	*alarm.Manager
+}
+
+func (m alarmManager) Subscribe(ctx context.Context, filter string) (vivnfmserver.AlarmStream, error) {
+	sub, err := m.Manager.Subscribe(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// pmManager serves the Performance service from the PM manager.
+type pmManager struct {
+	*pm.Manager
+}
+
+func (m pmManager) Subscribe(ctx context.Context, filter string) (vivnfmserver.PmStream, error) {
+	sub, err := m.Manager.Subscribe(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
 func loggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler, log *zap.Logger) (resp any, err error) {
 	// Retrieve the client IP address
 	var clientIP string
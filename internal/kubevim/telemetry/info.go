@@ -104,6 +104,8 @@ func observeComputeInfo(ctx context.Context, obs metric.Observer, logger *zap.Lo
 				// pci_address is the host VF/pass-through address for host-PCI vNICs
 				// (SR-IOV); empty for virtio/bridge vNICs.
 				attribute.String("pci_address", nic.GetMetadata().GetFields()[compute.VnicHostPciAddressMetadataKey]),
+				// bandwidth is the vNIC rate limit in Mbps; 0 when it is not limited.
+				attribute.String("bandwidth", strconv.FormatFloat(float64(nic.GetBandwidth()), 'f', -1, 32)),
 			))
 		}
 	}